@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// runBudget is the --max-total-time of the run. Once the deadline is reached no
+// more go test commands are started, and the commands which are still running
+// are stopped after the grace period.
+type runBudget struct {
+	total    time.Duration
+	grace    time.Duration
+	deadline time.Time
+	timer    *time.Timer
+	stopped  atomic.Bool
+}
+
+// newRunBudget returns nil when --max-total-time is not set. stop is called
+// when the grace period after the deadline ends.
+func newRunBudget(opts *options, stop func()) *runBudget {
+	if opts.maxTotalTime == 0 {
+		return nil
+	}
+	b := &runBudget{
+		total:    opts.maxTotalTime,
+		grace:    opts.maxTotalTimeGrace,
+		deadline: time.Now().Add(opts.maxTotalTime),
+	}
+	b.timer = time.AfterFunc(opts.maxTotalTime+opts.maxTotalTimeGrace, func() {
+		log.Warnf("stopping go test, the run exceeded --max-total-time=%v by the grace period of %v",
+			b.total, b.grace)
+		b.stopped.Store(true)
+		stop()
+	})
+	return b
+}
+
+// Deadline returns the time after which no more go test commands are
+// started, or the zero value when there is no budget.
+func (b *runBudget) Deadline() time.Time {
+	if b == nil {
+		return time.Time{}
+	}
+	return b.deadline
+}
+
+// Exceeded returns true once the deadline is reached.
+func (b *runBudget) Exceeded() bool {
+	return b != nil && !time.Now().Before(b.deadline)
+}
+
+func (b *runBudget) Stop() {
+	if b == nil {
+		return
+	}
+	b.timer.Stop()
+}
+
+// exceededError prints the packages which were not tested, or did not finish,
+// because the run exceeded the budget, and returns an error. Returns nil when
+// the budget was not exceeded, or every package was tested.
+func (b *runBudget) exceededError(opts *options, exec *testjson.Execution, notStarted []string) error {
+	if !b.Exceeded() {
+		return nil
+	}
+	var unfinished []string
+	if b.stopped.Load() {
+		for _, name := range exec.Packages() {
+			if exec.Package(name).Result() == "" {
+				unfinished = append(unfinished, name)
+			}
+		}
+	}
+	if len(notStarted) == 0 && len(unfinished) == 0 {
+		return nil
+	}
+
+	sort.Strings(notStarted)
+	for _, section := range []struct {
+		format string
+		pkgs   []string
+	}{
+		{format: "%d packages were not tested because the run exceeded --max-total-time=%v:\n", pkgs: notStarted},
+		{format: "%d packages were stopped before they finished because the run exceeded --max-total-time=%v:\n", pkgs: unfinished},
+	} {
+		if len(section.pkgs) == 0 {
+			continue
+		}
+		fmt.Fprintf(opts.stderr, section.format, len(section.pkgs), b.total)
+		for _, pkg := range section.pkgs {
+			fmt.Fprintf(opts.stderr, "    %v\n", pkg)
+		}
+	}
+	return fmt.Errorf("the run exceeded --max-total-time=%v", b.total)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestGoTestGroups_Deadline(t *testing.T) {
+	var started []string
+	reset := patchStartGoTestFn(func(args []string) *proc {
+		started = append(started, args[len(args)-1])
+		return &proc{cmd: fakeWaiter{}, stdout: strings.NewReader(""), stderr: strings.NewReader("")}
+	})
+	defer reset()
+
+	groups := &goTestGroups{
+		groups: []testGroup{
+			{packages: []string{"example.com/a"}},
+			{packages: []string{"example.com/b"}},
+		},
+		parallel: 1,
+		deadline: time.Now().Add(-time.Second),
+	}
+	goTestProc := groups.Start(context.Background(), &options{})
+	go func() { _, _ = io.Copy(io.Discard, goTestProc.stderr) }()
+	_, _ = io.Copy(io.Discard, goTestProc.stdout)
+	assert.NilError(t, goTestProc.cmd.Wait())
+
+	assert.Assert(t, started == nil)
+	assert.DeepEqual(t, groups.NotStarted(), []string{"example.com/a", "example.com/b"})
+}
+
+func TestRunBudget_ExceededError(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Package": "example.com/done", "Action": "run", "Test": "TestOne"}
+{"Package": "example.com/done", "Action": "pass", "Test": "TestOne"}
+{"Package": "example.com/done", "Action": "pass"}
+{"Package": "example.com/slow", "Action": "run", "Test": "TestSlow"}
+`),
+	})
+	assert.NilError(t, err)
+
+	budget := &runBudget{total: time.Minute, deadline: time.Now().Add(-time.Second)}
+	budget.stopped.Store(true)
+	out := new(bytes.Buffer)
+	opts := &options{stderr: out}
+
+	err = budget.exceededError(opts, exec, []string{"example.com/z", "example.com/b"})
+	assert.Error(t, err, "the run exceeded --max-total-time=1m0s")
+	assert.Equal(t, out.String(), `2 packages were not tested because the run exceeded --max-total-time=1m0s:
+    example.com/b
+    example.com/z
+1 packages were stopped before they finished because the run exceeded --max-total-time=1m0s:
+    example.com/slow
+`)
+
+	t.Run("within budget", func(t *testing.T) {
+		budget := &runBudget{total: time.Minute, deadline: time.Now().Add(time.Minute)}
+		assert.NilError(t, budget.exceededError(opts, exec, nil))
+
+		var none *runBudget
+		assert.NilError(t, none.exceededError(opts, exec, nil))
+	})
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dnephin/pflag"
+)
+
+// RunExecBinary runs the exec-binary command, which runs a test binary
+// compiled with 'go test -c' using test2json, so that the output can be
+// formatted, reported, and the failed tests run again like the output of
+// go test.
+func RunExecBinary(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	binOpts := &execBinaryOptions{test2json: &commandValue{}, exec: &commandValue{}}
+	setupExecBinaryFlags(flags, binOpts)
+	flags.Usage = func() {
+		execBinaryUsage(os.Stdout, name, flags)
+	}
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		execBinaryUsage(os.Stderr, name, flags)
+		return err
+	}
+	applyUserConfig(flags)
+	if err := setExecBinaryArgs(opts, binOpts, flags); err != nil {
+		execBinaryUsage(os.Stderr, name, flags)
+		return err
+	}
+	setupOptions(opts)
+	if opts.watch {
+		return fmt.Errorf("--watch can not be used with %v", name)
+	}
+	return run(opts)
+}
+
+type execBinaryOptions struct {
+	pkg       string
+	test2json *commandValue
+	exec      *commandValue
+}
+
+func setupExecBinaryFlags(flags *pflag.FlagSet, binOpts *execBinaryOptions) {
+	// test flags must come after --, so that they are not parsed as flags of
+	// gotestsum
+	flags.SetInterspersed(true)
+	flags.StringVar(&binOpts.pkg, "package", "",
+		"name of the package tested by the binary, the default is the name of the binary without .test")
+	flags.Var(binOpts.test2json, "test2json",
+		"command used to convert the output of the binary, the default is 'go tool test2json', or test2json when go is not found")
+	flags.Var(binOpts.exec, "exec",
+		"run the test binary using this command, like go test -exec")
+}
+
+// setExecBinaryArgs sets the command to run the test binary, which is the
+// first positional arg, with the test flags after --.
+func setExecBinaryArgs(opts *options, binOpts *execBinaryOptions, flags *pflag.FlagSet) error {
+	positional := flags.Args()
+	var testFlags []string
+	if dash := flags.ArgsLenAtDash(); dash >= 0 {
+		testFlags = positional[dash:]
+		positional = positional[:dash]
+	}
+	switch len(positional) {
+	case 0:
+		return fmt.Errorf("the path to a test binary is required")
+	case 1:
+	default:
+		return fmt.Errorf("only one test binary can be run, got %v", strings.Join(positional, " "))
+	}
+	binary := positional[0]
+	pkg := binOpts.pkg
+	if pkg == "" {
+		pkg = packageFromTestBinary(binary)
+	}
+	// a name without a separator would be looked up in the PATH
+	if !strings.ContainsRune(binary, filepath.Separator) && !strings.ContainsRune(binary, '/') {
+		binary = "." + string(filepath.Separator) + binary
+	}
+
+	opts.rawCommand = true
+	opts.execBinary = true
+	opts.args = append(test2jsonCommand(binOpts.test2json.Value()), "-t", "-p", pkg)
+	opts.args = append(opts.args, binOpts.exec.Value()...)
+	opts.args = append(opts.args, binary, "-test.v")
+	opts.args = append(opts.args, testFlags...)
+	return nil
+}
+
+// packageFromTestBinary returns the name of the package of a binary named
+// like the ones created by 'go test -c', which are named pkg.test.
+func packageFromTestBinary(binary string) string {
+	name := filepath.Base(binary)
+	name = strings.TrimSuffix(name, ".exe")
+	return strings.TrimSuffix(name, ".test")
+}
+
+// lookPathFn is a shim for testing
+var lookPathFn = exec.LookPath
+
+// test2jsonCommand returns the command used to run test2json. The standalone
+// test2json binary is used when go is not installed, for example on the
+// device which runs a cross-compiled test binary.
+func test2jsonCommand(command []string) []string {
+	if len(command) > 0 {
+		return append([]string{}, command...)
+	}
+	if _, err := lookPathFn("go"); err != nil {
+		return []string{"test2json"}
+	}
+	return []string{"go", "tool", "test2json"}
+}
+
+func execBinaryUsage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] TEST_BINARY [-- test flags]
+
+Run a test binary compiled with 'go test -c', and print the results like
+gotestsum. The output of the binary is converted with test2json, so all the
+flags of gotestsum, like --junitfile and --rerun-fails, can be used. The failed
+tests are run again with the same binary.
+
+The test flags after -- are passed to the test binary, and must use the
+-test. prefix, for example -test.run.
+
+Example:
+
+    GOOS=linux GOARCH=arm64 go test -c -o pkg.test ./pkg
+    %[1]s --package=example.com/pkg --rerun-fails ./pkg.test -- -test.short
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func patchLookPathFn(t *testing.T, found bool) {
+	orig := lookPathFn
+	lookPathFn = func(file string) (string, error) {
+		if !found {
+			return "", errors.New("not found")
+		}
+		return "/usr/bin/" + file, nil
+	}
+	t.Cleanup(func() { lookPathFn = orig })
+}
+
+func TestSetExecBinaryArgs(t *testing.T) {
+	type testCase struct {
+		name     string
+		args     []string
+		goFound  bool
+		expected []string
+	}
+	fn := func(t *testing.T, tc testCase) {
+		patchLookPathFn(t, tc.goFound)
+		flags, opts := setupFlags("gotestsum exec-binary")
+		binOpts := &execBinaryOptions{test2json: &commandValue{}, exec: &commandValue{}}
+		setupExecBinaryFlags(flags, binOpts)
+		assert.NilError(t, flags.Parse(tc.args))
+
+		assert.NilError(t, setExecBinaryArgs(opts, binOpts, flags))
+		assert.Assert(t, opts.rawCommand)
+		assert.Assert(t, opts.execBinary)
+		assert.DeepEqual(t, opts.args, tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name:    "package from binary name",
+			args:    []string{"./bin/store.test", "--", "-test.run=TestOpen"},
+			goFound: true,
+			expected: []string{
+				"go", "tool", "test2json", "-t", "-p", "store",
+				"./bin/store.test", "-test.v", "-test.run=TestOpen",
+			},
+		},
+		{
+			name: "without go",
+			args: []string{"--package=example.com/store", "store.test"},
+			expected: []string{
+				"test2json", "-t", "-p", "example.com/store", "./store.test", "-test.v",
+			},
+		},
+		{
+			name:    "with exec and test2json",
+			args:    []string{"--exec=qemu-aarch64 -L /usr/aarch64", "--test2json=/opt/test2json", "./store.test"},
+			goFound: true,
+			expected: []string{
+				"/opt/test2json", "-t", "-p", "store",
+				"qemu-aarch64", "-L", "/usr/aarch64", "./store.test", "-test.v",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fn(t, tc)
+		})
+	}
+}
+
+func TestSetExecBinaryArgs_Errors(t *testing.T) {
+	for _, args := range [][]string{
+		{"--", "-test.run=TestOpen"},
+		{"./a.test", "./b.test"},
+	} {
+		flags, opts := setupFlags("gotestsum exec-binary")
+		binOpts := &execBinaryOptions{test2json: &commandValue{}, exec: &commandValue{}}
+		setupExecBinaryFlags(flags, binOpts)
+		assert.NilError(t, flags.Parse(args))
+		assert.ErrorContains(t, setExecBinaryArgs(opts, binOpts, flags), "test binary")
+	}
+}
+
+func TestRun_ExecBinary_RerunFails(t *testing.T) {
+	patchLookPathFn(t, true)
+	jsonFailed := `{"Package": "store", "Action": "run"}
+{"Package": "store", "Test": "TestOpen", "Action": "run"}
+{"Package": "store", "Test": "TestOpen", "Action": "fail"}
+{"Package": "store", "Action": "fail"}
+`
+	jsonPassed := `{"Package": "store", "Action": "run"}
+{"Package": "store", "Test": "TestOpen", "Action": "run"}
+{"Package": "store", "Test": "TestOpen", "Action": "pass"}
+{"Package": "store", "Action": "pass"}
+`
+	var calls [][]string
+	reset := patchStartGoTestFn(func(args []string) *proc {
+		calls = append(calls, args)
+		if len(calls) == 1 {
+			return &proc{
+				cmd:    fakeWaiter{result: newExitCode("failed", 1)},
+				stdout: strings.NewReader(jsonFailed),
+				stderr: bytes.NewReader(nil),
+			}
+		}
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(jsonPassed),
+			stderr: bytes.NewReader(nil),
+		}
+	})
+	defer reset()
+
+	flags, opts := setupFlags("gotestsum exec-binary")
+	binOpts := &execBinaryOptions{test2json: &commandValue{}, exec: &commandValue{}}
+	setupExecBinaryFlags(flags, binOpts)
+	assert.NilError(t, flags.Parse([]string{"--rerun-fails", "./store.test", "--", "-test.short"}))
+	assert.NilError(t, setExecBinaryArgs(opts, binOpts, flags))
+	opts.stdout = new(bytes.Buffer)
+	opts.stderr = os.Stderr
+
+	assert.NilError(t, run(opts))
+	command := []string{"go", "tool", "test2json", "-t", "-p", "store", "./store.test", "-test.v", "-test.short"}
+	assert.DeepEqual(t, calls, [][]string{
+		command,
+		append(command, "-test.run=^TestOpen$"),
+	})
+}
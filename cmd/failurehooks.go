@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// failureHooks runs the --on-test-failure-exec command for each test which
+// fails. The commands run one at a time in a separate goroutine, so that
+// reading the output of go test is not blocked while a command runs. The
+// queue of failed tests has no limit, so a slow command never blocks the
+// scanner.
+//
+// The output of each command is buffered, and written to stdout and stderr by
+// writeOutput, which is called from the event handler. The output is never
+// written at the same time as the output of the formatter.
+type failureHooks struct {
+	command []string
+	stdout  io.Writer
+	stderr  io.Writer
+	// dir contains the files with the output of the failed tests.
+	dir  string
+	done chan struct{}
+	// wake is sent a value when a test is queued, or the queue is closed.
+	wake chan struct{}
+
+	mu     sync.Mutex
+	queue  []failedTest
+	closed bool
+	// outputs are the stdout and stderr of the commands which finished, and
+	// were not written yet.
+	outputs []hookOutput
+}
+
+type hookOutput struct {
+	stdout []byte
+	stderr []byte
+}
+
+type failedTest struct {
+	pkg     string
+	name    string
+	attempt int
+	output  []string
+}
+
+// newFailureHooks returns nil when --on-test-failure-exec is not set.
+func newFailureHooks(opts *options) (*failureHooks, error) {
+	command := opts.onTestFailureExecCmd.Value()
+	if len(command) == 0 {
+		return nil, nil
+	}
+	dir, err := os.MkdirTemp("", "gotestsum-failures-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory for test output: %w", err)
+	}
+	h := &failureHooks{
+		command: command,
+		stdout:  opts.stdout,
+		stderr:  opts.stderr,
+		dir:     dir,
+		done:    make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+	}
+	go h.runCommands()
+	return h, nil
+}
+
+// Event queues the command for a test which failed, and writes the output of
+// any commands which finished. All other events, and events received after
+// Wait, are ignored.
+func (h *failureHooks) Event(event testjson.TestEvent, execution *testjson.Execution) {
+	if h == nil {
+		return
+	}
+	h.writeOutput()
+	if event.PackageEvent() || event.Action != testjson.ActionFail {
+		return
+	}
+	pkg := execution.Package(event.Package)
+	if pkg == nil {
+		return
+	}
+	tc := pkg.LastFailedByName(event.Test)
+	test := failedTest{
+		pkg:     event.Package,
+		name:    event.Test,
+		attempt: event.RunID + 1,
+		output:  execution.OutputLines(tc),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.queue = append(h.queue, test)
+	h.notify()
+}
+
+// notify wakes runCommands without blocking. h.mu must be held.
+func (h *failureHooks) notify() {
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the next failed test from the queue, and false once the queue
+// is closed and empty.
+func (h *failureHooks) next() (failedTest, bool) {
+	for {
+		h.mu.Lock()
+		if len(h.queue) > 0 {
+			test := h.queue[0]
+			h.queue = h.queue[1:]
+			h.mu.Unlock()
+			return test, true
+		}
+		closed := h.closed
+		h.mu.Unlock()
+		if closed {
+			return failedTest{}, false
+		}
+		<-h.wake
+	}
+}
+
+func (h *failureHooks) runCommands() {
+	defer close(h.done)
+	for i := 1; ; i++ {
+		test, ok := h.next()
+		if !ok {
+			return
+		}
+		output, err := h.run(test, filepath.Join(h.dir, fmt.Sprintf("%d.txt", i)))
+		h.mu.Lock()
+		h.outputs = append(h.outputs, output)
+		h.mu.Unlock()
+		if err != nil {
+			log.Warnf("--on-test-failure-exec command failed for %v.%v: %v", test.pkg, test.name, err)
+		}
+	}
+}
+
+// writeOutput writes the output of the commands which finished.
+func (h *failureHooks) writeOutput() {
+	h.mu.Lock()
+	outputs := h.outputs
+	h.outputs = nil
+	h.mu.Unlock()
+	for _, output := range outputs {
+		_, _ = h.stdout.Write(output.stdout)
+		_, _ = h.stderr.Write(output.stderr)
+	}
+}
+
+func (h *failureHooks) run(test failedTest, outputFile string) (hookOutput, error) {
+	if err := os.WriteFile(outputFile, []byte(strings.Join(test.output, "")), 0o644); err != nil {
+		return hookOutput{}, err
+	}
+	defer os.Remove(outputFile) //nolint:errcheck
+
+	log.Debugf("exec: %s", h.command)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(h.command[0], h.command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(
+		os.Environ(),
+		"TEST_NAME="+test.name,
+		"PACKAGE="+test.pkg,
+		"OUTPUT_FILE="+outputFile,
+		fmt.Sprintf("TEST_ATTEMPT=%d", test.attempt),
+	)
+	err := cmd.Run()
+	return hookOutput{stdout: stdout.Bytes(), stderr: stderr.Bytes()}, err
+}
+
+// Wait for the commands of every failed test to finish, and write their
+// output. Calling Wait more than once is a no-op.
+func (h *failureHooks) Wait() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	h.closed = true
+	h.notify()
+	h.mu.Unlock()
+
+	<-h.done
+	h.writeOutput()
+	_ = os.RemoveAll(h.dir)
+}
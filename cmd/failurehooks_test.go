@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestFailureHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+	command := &commandValue{}
+	assert.NilError(t, command.Set(
+		`sh -c 'echo "$PACKAGE $TEST_NAME $TEST_ATTEMPT"; cat "$OUTPUT_FILE"'`))
+	stdout := new(bytes.Buffer)
+	opts := &options{
+		format:               "none",
+		onTestFailureExecCmd: command,
+		stdout:               stdout,
+		stderr:               os.Stderr,
+	}
+	handler, err := newEventHandler(opts)
+	assert.NilError(t, err)
+	defer handler.Close() //nolint:errcheck
+
+	input := `{"Action":"run","Package":"example.com/pkg","Test":"TestOk"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOk"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestBroken","Output":"=== RUN   TestBroken\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestBroken","Output":"    broken_test.go:9: connection refused\n"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestBroken","Output":"--- FAIL: TestBroken (0.10s)\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestBroken","Elapsed":0.1}
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.2}
+`
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  strings.NewReader(input),
+		Stderr:  strings.NewReader(""),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	opts.failureHooks.Wait()
+	opts.failureHooks.Wait()
+
+	expected := `example.com/pkg TestBroken 1
+=== RUN   TestBroken
+    broken_test.go:9: connection refused
+--- FAIL: TestBroken (0.10s)
+`
+	assert.Equal(t, stdout.String(), expected)
+	_, err = os.Stat(opts.failureHooks.dir)
+	assert.Assert(t, os.IsNotExist(err), "output files should be removed")
+}
+
+func TestFailureHooks_NotSet(t *testing.T) {
+	hooks, err := newFailureHooks(&options{onTestFailureExecCmd: &commandValue{}, stdout: io.Discard})
+	assert.NilError(t, err)
+	assert.Assert(t, hooks == nil)
+	// a nil failureHooks is a no-op
+	hooks.Event(testjson.TestEvent{Action: testjson.ActionFail, Test: "TestOne"}, nil)
+	hooks.Wait()
+}
+
+func TestFailureHooks_EventDoesNotBlock(t *testing.T) {
+	stdout := new(bytes.Buffer)
+	// runCommands is not started, so none of the queued commands run
+	hooks := &failureHooks{
+		command: []string{"true"},
+		stdout:  stdout,
+		stderr:  io.Discard,
+		done:    make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestOne"}
+`),
+	})
+	assert.NilError(t, err)
+
+	event := testjson.TestEvent{Action: testjson.ActionFail, Package: "example.com/pkg", Test: "TestOne"}
+	for i := 0; i < 500; i++ {
+		hooks.Event(event, exec)
+	}
+	assert.Equal(t, len(hooks.queue), 500)
+
+	// the output of a finished command is written by the next event
+	hooks.outputs = []hookOutput{{stdout: []byte("captured logs\n")}}
+	hooks.Event(testjson.TestEvent{Action: testjson.ActionPass, Package: "example.com/pkg"}, exec)
+	assert.Equal(t, stdout.String(), "captured logs\n")
+}
@@ -3,12 +3,18 @@ package cmd
 import (
 	"encoding/csv"
 	"fmt"
+	"math/rand/v2"
+	"os"
 	"path"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dnephin/pflag"
 	"github.com/google/shlex"
 	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/log"
 	"gotest.tools/gotestsum/testjson"
 )
 
@@ -140,3 +146,353 @@ func truthyFlag(s string) bool {
 	}
 	return false
 }
+
+// percentValue is a flag.Value which accepts a percentage, with or without a
+// trailing '%', and stores it as a fraction.
+type percentValue struct {
+	original string
+	value    float64
+}
+
+func (p *percentValue) String() string {
+	return p.original
+}
+
+func (p *percentValue) Set(raw string) error {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(raw), "%"), 64)
+	if err != nil {
+		return fmt.Errorf("invalid percentage %q", raw)
+	}
+	if v < 0 {
+		return fmt.Errorf("percentage must not be negative")
+	}
+	p.original = raw
+	p.value = v / 100
+	return nil
+}
+
+func (p *percentValue) Type() string {
+	return "percent"
+}
+
+// Value returns the percentage as a fraction, or 0 if the value was not set.
+func (p *percentValue) Value() float64 {
+	if p == nil {
+		return 0
+	}
+	return p.value
+}
+
+// partitionValue is a flag.Value which accepts a partition as INDEX/TOTAL,
+// where INDEX is from 1 to TOTAL.
+type partitionValue struct {
+	index int
+	total int
+}
+
+func (p *partitionValue) String() string {
+	if p.total == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", p.index, p.total)
+}
+
+func (p *partitionValue) Set(raw string) error {
+	index, total, ok := strings.Cut(raw, "/")
+	if !ok {
+		return fmt.Errorf("partition must be INDEX/TOTAL, for example 1/4")
+	}
+	var err error
+	if p.index, err = strconv.Atoi(strings.TrimSpace(index)); err != nil {
+		return fmt.Errorf("invalid partition index %q", index)
+	}
+	if p.total, err = strconv.Atoi(strings.TrimSpace(total)); err != nil {
+		return fmt.Errorf("invalid partition total %q", total)
+	}
+	if p.total < 1 || p.index < 1 || p.index > p.total {
+		return fmt.Errorf("partition index must be from 1 to the total number of partitions")
+	}
+	return nil
+}
+
+func (p *partitionValue) Type() string {
+	return "partition"
+}
+
+// junitPropertiesValue is a flag.Value which accepts a junit property as
+// name=value. When the value is only a name, the value of the property is read
+// from the environment variable with that name.
+type junitPropertiesValue []junitxml.JUnitProperty
+
+func (p *junitPropertiesValue) String() string {
+	if p == nil {
+		return ""
+	}
+	values := make([]string, len(*p))
+	for i, prop := range *p {
+		values[i] = prop.Name + "=" + prop.Value
+	}
+	return strings.Join(values, ",")
+}
+
+func (p *junitPropertiesValue) Set(raw string) error {
+	name, value, ok := strings.Cut(raw, "=")
+	if name == "" {
+		return fmt.Errorf("property name must not be empty")
+	}
+	if !ok {
+		value, ok = os.LookupEnv(name)
+		if !ok {
+			log.Warnf("junit property %v not set, environment variable is not set", name)
+			return nil
+		}
+	}
+	*p = append(*p, junitxml.JUnitProperty{Name: name, Value: value})
+	return nil
+}
+
+func (p *junitPropertiesValue) Type() string {
+	return "name=value"
+}
+
+// timestampValue is a flag.Value which accepts an RFC3339 timestamp.
+type timestampValue struct {
+	value time.Time
+}
+
+func (t *timestampValue) String() string {
+	if t == nil || t.value.IsZero() {
+		return ""
+	}
+	return t.value.Format(time.RFC3339)
+}
+
+func (t *timestampValue) Set(raw string) error {
+	v, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q, must be RFC3339 format", raw)
+	}
+	t.value = v
+	return nil
+}
+
+func (t *timestampValue) Type() string {
+	return "timestamp"
+}
+
+// Value returns the timestamp, or the zero value if it was not set.
+func (t *timestampValue) Value() time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.value
+}
+
+// regexpSlice is a flag.Value which compiles each value as a regular
+// expression. Unlike stringSlice the value is not split on whitespace.
+type regexpSlice []*regexp.Regexp
+
+func (s *regexpSlice) String() string {
+	if s == nil {
+		return ""
+	}
+	values := make([]string, len(*s))
+	for i, re := range *s {
+		values[i] = re.String()
+	}
+	return strings.Join(values, ",")
+}
+
+func (s *regexpSlice) Set(raw string) error {
+	re, err := regexp.Compile(raw)
+	if err != nil {
+		return err
+	}
+	*s = append(*s, re)
+	return nil
+}
+
+func (s *regexpSlice) Type() string {
+	return "regexp"
+}
+
+var junitSuiteGranularityValues = "package, test, single"
+
+type junitSuiteGranularityValue struct {
+	value junitxml.SuiteGranularity
+}
+
+func (f *junitSuiteGranularityValue) Set(val string) error {
+	switch v := junitxml.SuiteGranularity(val); v {
+	case junitxml.SuitePerPackage, junitxml.SuitePerTest, junitxml.SingleSuite:
+		f.value = v
+		return nil
+	}
+	return fmt.Errorf("invalid value: %v, must be one of: "+junitSuiteGranularityValues, val)
+}
+
+func (f *junitSuiteGranularityValue) Type() string {
+	return "granularity"
+}
+
+func (f *junitSuiteGranularityValue) String() string {
+	if f.value == "" {
+		return string(junitxml.SuitePerPackage)
+	}
+	return string(f.value)
+}
+
+func (f *junitSuiteGranularityValue) Value() junitxml.SuiteGranularity {
+	return f.value
+}
+
+var junitSubtestNamingValues = "slash, dot, classname"
+
+type junitSubtestNamingValue struct {
+	value junitxml.SubtestNaming
+}
+
+func (f *junitSubtestNamingValue) Set(val string) error {
+	switch v := junitxml.SubtestNaming(val); v {
+	case junitxml.SubtestSlash, junitxml.SubtestDot, junitxml.SubtestClassname:
+		f.value = v
+		return nil
+	}
+	return fmt.Errorf("invalid value: %v, must be one of: "+junitSubtestNamingValues, val)
+}
+
+func (f *junitSubtestNamingValue) Type() string {
+	return "naming"
+}
+
+func (f *junitSubtestNamingValue) String() string {
+	if f.value == "" {
+		return string(junitxml.SubtestSlash)
+	}
+	return string(f.value)
+}
+
+func (f *junitSubtestNamingValue) Value() junitxml.SubtestNaming {
+	return f.value
+}
+
+// rerunDelayValue is a flag.Value which accepts the delay before each rerun of
+// failed tests. The value is either a duration, which is used as a fixed delay,
+// or exponential:DURATION, which doubles the delay after each attempt and adds
+// a random jitter.
+type rerunDelayValue struct {
+	original    string
+	base        time.Duration
+	exponential bool
+}
+
+func (d *rerunDelayValue) String() string {
+	if d == nil {
+		return ""
+	}
+	return d.original
+}
+
+func (d *rerunDelayValue) Set(raw string) error {
+	value, exponential := strings.CutPrefix(raw, "exponential:")
+	base, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid delay %q, must be a duration or exponential:DURATION", raw)
+	}
+	if base < 0 {
+		return fmt.Errorf("delay must not be negative")
+	}
+	d.original = raw
+	d.base = base
+	d.exponential = exponential
+	return nil
+}
+
+func (d *rerunDelayValue) Type() string {
+	return "delay"
+}
+
+// maxDelayShift limits the exponential delay to base * 2^maxDelayShift, so
+// that the delay can not overflow.
+const maxDelayShift = 16
+
+// Delay returns the time to wait before rerun attempt, where the first rerun
+// is attempt 0. An exponential delay is base * 2^attempt, of which the second
+// half is random, so that tests which are rerun at the same time by different
+// jobs do not all retry at the same time.
+func (d *rerunDelayValue) Delay(attempt int) time.Duration {
+	if d == nil || d.base == 0 {
+		return 0
+	}
+	if !d.exponential {
+		return d.base
+	}
+	delay := d.base << min(attempt, maxDelayShift)
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// Policies for packages which failed to build, or failed in TestMain, when
+// failed tests are rerun.
+const (
+	packageErrorsAbort = "abort"
+	packageErrorsSkip  = "skip"
+	packageErrorsRetry = "retry"
+)
+
+// defaultPackageErrorRetries is the number of times a package is retried by
+// the retry policy when no number is given.
+const defaultPackageErrorRetries = 2
+
+// packageErrorsValue is a flag.Value which accepts the policy for packages
+// which failed to build, or failed in TestMain, when failed tests are rerun.
+// The value is one of abort, skip, retry, or retry:N. The zero value is abort.
+type packageErrorsValue struct {
+	policy   string
+	attempts int
+}
+
+func (v *packageErrorsValue) String() string {
+	switch v.policy {
+	case "":
+		return packageErrorsAbort
+	case packageErrorsRetry:
+		return fmt.Sprintf("%v:%d", packageErrorsRetry, v.attempts)
+	default:
+		return v.policy
+	}
+}
+
+func (v *packageErrorsValue) Set(raw string) error {
+	policy, count, hasCount := strings.Cut(raw, ":")
+	switch {
+	case policy == packageErrorsRetry && hasCount:
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid number of retries %q, must be a positive integer", count)
+		}
+		v.attempts = n
+	case policy == packageErrorsRetry:
+		v.attempts = defaultPackageErrorRetries
+	case hasCount:
+		return fmt.Errorf("invalid policy %q, only retry accepts a number", raw)
+	case policy == packageErrorsAbort, policy == packageErrorsSkip:
+		v.attempts = 0
+	default:
+		return fmt.Errorf("invalid policy %q, must be one of: abort, skip, retry, retry:N", raw)
+	}
+	v.policy = policy
+	return nil
+}
+
+func (v *packageErrorsValue) Type() string {
+	return "policy"
+}
+
+// Value returns the policy, which defaults to abort.
+func (v *packageErrorsValue) Value() string {
+	if v.policy == "" {
+		return packageErrorsAbort
+	}
+	return v.policy
+}
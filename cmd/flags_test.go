@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
 )
@@ -33,3 +34,122 @@ func TestStringSlice(t *testing.T) {
 	assert.NilError(t, ss.Set(value))
 	assert.DeepEqual(t, v, []string{"one", "two", "three", "four", "five"})
 }
+
+func TestPercentValue(t *testing.T) {
+	t.Run("with percent sign", func(t *testing.T) {
+		value := &percentValue{}
+		assert.NilError(t, value.Set("50%"))
+		assert.Equal(t, value.Value(), 0.5)
+		assert.Equal(t, value.String(), "50%")
+	})
+	t.Run("without percent sign", func(t *testing.T) {
+		value := &percentValue{}
+		assert.NilError(t, value.Set("125"))
+		assert.Equal(t, value.Value(), 1.25)
+	})
+	t.Run("bad value", func(t *testing.T) {
+		value := &percentValue{}
+		assert.ErrorContains(t, value.Set("lots"), "invalid percentage")
+		assert.ErrorContains(t, value.Set("-5%"), "must not be negative")
+	})
+	t.Run("nil", func(t *testing.T) {
+		var value *percentValue
+		assert.Equal(t, value.Value(), 0.0)
+	})
+}
+
+func TestPartitionValue(t *testing.T) {
+	value := &partitionValue{}
+	assert.Equal(t, value.String(), "")
+	assert.NilError(t, value.Set("2/3"))
+	assert.Equal(t, *value, partitionValue{index: 2, total: 3})
+	assert.Equal(t, value.String(), "2/3")
+
+	assert.ErrorContains(t, value.Set("2"), "partition must be INDEX/TOTAL")
+	assert.ErrorContains(t, value.Set("a/3"), "invalid partition index")
+	assert.ErrorContains(t, value.Set("0/3"), "index must be from 1")
+	assert.ErrorContains(t, value.Set("4/3"), "index must be from 1")
+}
+
+func TestRegexpSlice(t *testing.T) {
+	value := &regexpSlice{}
+	assert.NilError(t, value.Set(`^\s+check\.go:\d+:`))
+	assert.NilError(t, value.Set("expected value"))
+	assert.Equal(t, len(*value), 2)
+	assert.Equal(t, value.String(), `^\s+check\.go:\d+:,expected value`)
+
+	assert.ErrorContains(t, value.Set("(unclosed"), "missing closing )")
+}
+
+func TestJUnitPropertiesValue(t *testing.T) {
+	t.Setenv("GIT_SHA", "abc123")
+	value := &junitPropertiesValue{}
+	assert.NilError(t, value.Set("ci.job=https://ci.example.com/job/1"))
+	assert.NilError(t, value.Set("GIT_SHA"))
+	assert.NilError(t, value.Set("GOTESTSUM_NOT_SET_IN_ENV"))
+	assert.NilError(t, value.Set("empty="))
+
+	expected := junitPropertiesValue{
+		{Name: "ci.job", Value: "https://ci.example.com/job/1"},
+		{Name: "GIT_SHA", Value: "abc123"},
+		{Name: "empty", Value: ""},
+	}
+	assert.DeepEqual(t, *value, expected)
+	assert.Equal(t, value.String(), "ci.job=https://ci.example.com/job/1,GIT_SHA=abc123,empty=")
+
+	assert.ErrorContains(t, value.Set("=value"), "name must not be empty")
+}
+
+func TestTimestampValue(t *testing.T) {
+	value := &timestampValue{}
+	assert.NilError(t, value.Set("2024-05-02T10:00:00Z"))
+	assert.Equal(t, value.Value(), time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC))
+	assert.Equal(t, value.String(), "2024-05-02T10:00:00Z")
+
+	assert.ErrorContains(t, value.Set("yesterday"), "must be RFC3339 format")
+
+	var unset *timestampValue
+	assert.Assert(t, unset.Value().IsZero())
+}
+
+func TestPackageErrorsValue(t *testing.T) {
+	value := &packageErrorsValue{}
+	assert.Equal(t, value.String(), "abort")
+	assert.Equal(t, value.Value(), packageErrorsAbort)
+
+	assert.NilError(t, value.Set("retry"))
+	assert.Equal(t, value.String(), "retry:2")
+	assert.Equal(t, value.Value(), packageErrorsRetry)
+
+	assert.NilError(t, value.Set("retry:5"))
+	assert.Equal(t, value.attempts, 5)
+
+	assert.NilError(t, value.Set("skip"))
+	assert.Equal(t, value.String(), "skip")
+	assert.Equal(t, value.attempts, 0)
+
+	assert.ErrorContains(t, value.Set("retry:0"), "must be a positive integer")
+	assert.ErrorContains(t, value.Set("skip:3"), "only retry accepts a number")
+	assert.ErrorContains(t, value.Set("ignore"), "must be one of: abort, skip, retry, retry:N")
+}
+
+func TestRerunDelayValue(t *testing.T) {
+	value := &rerunDelayValue{}
+	assert.NilError(t, value.Set("2s"))
+	assert.Equal(t, value.String(), "2s")
+	assert.Equal(t, value.Delay(0), 2*time.Second)
+	assert.Equal(t, value.Delay(3), 2*time.Second)
+
+	assert.NilError(t, value.Set("exponential:1s"))
+	assert.Equal(t, value.String(), "exponential:1s")
+	for attempt, maxDelay := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		delay := value.Delay(attempt)
+		assert.Assert(t, delay >= maxDelay/2 && delay <= maxDelay, "attempt %d: %v", attempt, delay)
+	}
+
+	assert.ErrorContains(t, value.Set("soon"), "must be a duration or exponential:DURATION")
+	assert.ErrorContains(t, value.Set("-1s"), "must not be negative")
+
+	var unset *rerunDelayValue
+	assert.Equal(t, unset.Delay(1), time.Duration(0))
+}
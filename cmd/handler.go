@@ -2,14 +2,33 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"time"
 
+	"gotest.tools/gotestsum/internal/allure"
+	"gotest.tools/gotestsum/internal/bes"
+	"gotest.tools/gotestsum/internal/circleci"
+	"gotest.tools/gotestsum/internal/datadog"
+	"gotest.tools/gotestsum/internal/formatexec"
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/internal/jsonfile"
 	"gotest.tools/gotestsum/internal/junitxml"
 	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/internal/notify"
+	"gotest.tools/gotestsum/internal/otlp"
+	"gotest.tools/gotestsum/internal/resultsexec"
+	"gotest.tools/gotestsum/internal/sonar"
+	"gotest.tools/gotestsum/internal/webhook"
+	"gotest.tools/gotestsum/internal/xunitxml"
 	"gotest.tools/gotestsum/testjson"
 )
 
@@ -19,8 +38,15 @@ type eventHandler struct {
 	jsonFile             writeSyncer
 	jsonFileTimingEvents writeSyncer
 	maxFails             int
+	results              *resultsexec.Process
+	formatExec           *formatexec.Process
+	failureHooks         *failureHooks
 }
 
+// errMaxFailsReached is returned by eventHandler.Event to stop the test run
+// when the number of failures reaches --max-fails.
+var errMaxFailsReached = errors.New("ending test run because max failures was reached")
+
 type writeSyncer interface {
 	io.WriteCloser
 	Sync() error
@@ -28,6 +54,12 @@ type writeSyncer interface {
 
 //nolint:errcheck
 func (h *eventHandler) Err(text string) error {
+	if h.formatExec != nil {
+		if err := h.formatExec.Stderr(text); err != nil {
+			return fmt.Errorf("failed to format stderr: %w", err)
+		}
+		return nil
+	}
 	h.err.WriteString(text)
 	h.err.WriteRune('\n')
 	h.err.Flush()
@@ -45,13 +77,18 @@ func (h *eventHandler) Event(event testjson.TestEvent, execution *testjson.Execu
 		}
 	}
 
+	if h.results != nil {
+		h.results.Event(event)
+	}
+	h.failureHooks.Event(event, execution)
+
 	err := h.formatter.Format(event, execution)
 	if err != nil {
 		return fmt.Errorf("failed to format event: %w", err)
 	}
 
 	if h.maxFails > 0 && len(execution.Failed()) >= h.maxFails {
-		return fmt.Errorf("ending test run because max failures was reached")
+		return errMaxFailsReached
 	}
 	return nil
 }
@@ -81,90 +118,333 @@ func (h *eventHandler) Flush() {
 	}
 }
 
+// closeFormatter removes the status line printed by the formatter, so that it
+// is not redrawn while the summary is printed. It is called when go test
+// exits, because a package which was stopped by --max-fails, a timeout, or a
+// signal never sends the event that would remove it from the status line.
+func (h *eventHandler) closeFormatter() {
+	if h.formatExec != nil {
+		// closed by finishRun after the end of the run is sent to the command
+		return
+	}
+	if c, ok := h.formatter.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.Warnf("failed to close the formatter: %v", err)
+		}
+	}
+}
+
 func (h *eventHandler) Close() error {
+	h.closeFormatter()
+	h.closeJSONFiles()
+	// the run_end message is written by finishRun, the command is only closed
+	// here when the run stopped before it finished.
+	if err := h.results.Close(); err != nil {
+		log.Warnf("--results-exec command failed: %v", err)
+	}
+	if err := h.formatExec.Close(); err != nil {
+		log.Warnf("--format command failed: %v", err)
+	}
+	h.failureHooks.Wait()
+	return nil
+}
+
+// closeJSONFiles closes the --jsonfile and --jsonfile-timing-events, so that
+// they are complete before they are read by the --post-run-command. A
+// compressed file is only complete once the end of the gzip stream is written.
+func (h *eventHandler) closeJSONFiles() {
 	if h.jsonFile != nil {
 		if err := h.jsonFile.Close(); err != nil {
 			log.Errorf("Failed to close JSON file: %v", err)
 		}
+		h.jsonFile = nil
 	}
 	if h.jsonFileTimingEvents != nil {
 		if err := h.jsonFileTimingEvents.Close(); err != nil {
 			log.Errorf("Failed to close JSON file: %v", err)
 		}
+		h.jsonFileTimingEvents = nil
 	}
-	return nil
 }
 
 var _ testjson.EventHandler = &eventHandler{}
 
 func newEventHandler(opts *options) (*eventHandler, error) {
-	formatter := testjson.NewEventFormatter(opts.stdout, opts.format, opts.formatOptions)
-	if formatter == nil {
-		return nil, fmt.Errorf("unknown format %s", opts.format)
+	if err := loadCustomIcons(&opts.formatOptions); err != nil {
+		return nil, err
 	}
 	handler := &eventHandler{
-		formatter: formatter,
-		err:       bufio.NewWriter(opts.stderr),
-		maxFails:  opts.maxFails,
+		err:      bufio.NewWriter(opts.stderr),
+		maxFails: opts.maxFails,
+	}
+	command, ok, err := formatexec.Command(opts.format)
+	switch {
+	case err != nil:
+		return nil, err
+	case ok:
+		log.Debugf("exec: %s", command)
+		handler.formatExec, err = formatexec.Start(command, goTestCmdArgs(opts, rerunOpts{}), formatexec.Config{
+			Stdout: opts.stdout,
+			Stderr: opts.stderr,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to start --format command: %w", err)
+		}
+		handler.formatter = handler.formatExec
+		opts.formatExec = handler.formatExec
+	default:
+		handler.formatter = testjson.NewEventFormatter(opts.stdout, opts.format, opts.formatOptions)
+		if handler.formatter == nil {
+			return nil, fmt.Errorf("unknown format %s", opts.format)
+		}
 	}
 
 	switch opts.format {
-	case "dots", "dots-v1", "dots-v2":
+	case "dots", "dots-v1", "dots-v2", "grid", "matrix":
+		if opts.formatOptions.Accessible {
+			// accessible mode uses the pkgname format instead
+			break
+		}
 		// Discard the error from the handler to prevent extra lines. The
 		// error will be printed in the summary.
 		handler.err = bufio.NewWriter(io.Discard)
 	}
 
-	var err error
 	if opts.jsonFile != "" {
-		_ = os.MkdirAll(filepath.Dir(opts.jsonFile), 0o755)
-		handler.jsonFile, err = os.Create(opts.jsonFile)
+		open := jsonfile.Create
+		if opts.jsonFileAppend {
+			open = jsonfile.Append
+		}
+		file, err := open(opts.jsonFile)
 		if err != nil {
 			return handler, fmt.Errorf("failed to create file: %w", err)
 		}
+		handler.jsonFile = file
 	}
 	if opts.jsonFileTimingEvents != "" {
-		_ = os.MkdirAll(filepath.Dir(opts.jsonFileTimingEvents), 0o755)
-		handler.jsonFileTimingEvents, err = os.Create(opts.jsonFileTimingEvents)
+		file, err := jsonfile.Create(opts.jsonFileTimingEvents)
 		if err != nil {
 			return handler, fmt.Errorf("failed to create file: %w", err)
 		}
+		handler.jsonFileTimingEvents = file
+	}
+	if command := opts.resultsExecCmd.Value(); len(command) > 0 {
+		log.Debugf("exec: %s", command)
+		handler.results, err = resultsexec.Start(command, goTestCmdArgs(opts, rerunOpts{}), resultsexec.Config{
+			Stdout: opts.stdout,
+			Stderr: opts.stderr,
+		})
+		if err != nil {
+			return handler, fmt.Errorf("failed to start --results-exec command: %w", err)
+		}
+		opts.resultsExec = handler.results
 	}
+	handler.failureHooks, err = newFailureHooks(opts)
+	if err != nil {
+		return handler, err
+	}
+	opts.failureHooks = handler.failureHooks
+	opts.closeJSONFiles = handler.closeJSONFiles
 	return handler, nil
 }
 
-func writeJUnitFile(opts *options, execution *testjson.Execution) error {
-	if opts.junitFile == "" {
+// publishAzurePipelinesResults prints the logging command which publishes the
+// JUnit file as the test results of the job, when the format is azure-pipelines.
+func publishAzurePipelinesResults(opts *options) error {
+	if opts.junitFile == "" || opts.format != "azure-pipelines" {
 		return nil
 	}
-	_ = os.MkdirAll(filepath.Dir(opts.junitFile), 0o755)
-	junitFile, err := os.Create(opts.junitFile)
+	filename, err := filepath.Abs(opts.junitFile)
 	if err != nil {
-		return fmt.Errorf("failed to open JUnit file: %v", err)
+		return err
 	}
-	defer func() {
-		if err := junitFile.Close(); err != nil {
-			log.Errorf("Failed to close JUnit file: %v", err)
-		}
-	}()
+	return testjson.PrintAzurePipelinesPublish(opts.stdout, filename)
+}
+
+// gitLabJUnitFile returns the path of the JUnit file when the format is gitlab.
+// The paths of artifacts:reports:junit are relative to projectDir, the
+// CI_PROJECT_DIR of the job, so a relative path is resolved from projectDir,
+// even when gotestsum is run from a subdirectory of the project.
+func gitLabJUnitFile(filename, projectDir string) string {
+	if filename == "" || projectDir == "" || filepath.IsAbs(filename) {
+		return filename
+	}
+	return filepath.Join(projectDir, filename)
+}
 
-	return junitxml.Write(junitFile, execution, junitxml.Config{
+func writeJUnitFile(opts *options, execution *testjson.Execution) error {
+	if opts.junitFile == "" {
+		return nil
+	}
+	return writeReportFile(opts.junitFile, func(out io.Writer) error {
+		return junitxml.Write(out, execution, junitConfig(opts))
+	})
+}
+
+func junitConfig(opts *options) junitxml.Config {
+	return junitxml.Config{
 		ProjectName:             opts.junitProjectName,
 		FormatTestSuiteName:     opts.junitTestSuiteNameFormat.Value(),
 		FormatTestCaseClassname: opts.junitTestCaseClassnameFormat.Value(),
 		HideEmptyPackages:       opts.junitHideEmptyPackages,
 		HideSkippedTests:        opts.junitHideSkippedTests,
+		IncludeOutput:           opts.junitIncludeOutput,
+		MaxSystemOutBytes:       opts.junitMaxSystemOutBytes,
+		MaxOutputBytes:          opts.junitMaxOutputBytes,
+		MaxTotalOutputBytes:     opts.junitMaxTotalOutputBytes,
+		Properties:              opts.junitProperties,
+		Hostname:                opts.junitHostname,
+		Timestamp:               opts.junitTimestamp.Value(),
+		FlakyFailures:           opts.junitFlakyFailures,
+		Quarantined:             opts.quarantine.Contains,
+		SuiteGranularity:        opts.junitSuiteGranularity.Value(),
+		SubtestNaming:           opts.junitSubtestNaming.Value(),
+	}
+}
+
+func writeXUnitFile(opts *options, execution *testjson.Execution) error {
+	if opts.xunitFile == "" {
+		return nil
+	}
+	return writeReportFile(opts.xunitFile, func(out io.Writer) error {
+		return xunitxml.Write(out, execution, xunitxml.Config{
+			HideEmptyPackages: opts.junitHideEmptyPackages,
+			IncludeOutput:     opts.junitIncludeOutput,
+		})
 	})
 }
 
-func postRunHook(opts *options, execution *testjson.Execution) error {
+func writeSonarFile(opts *options, execution *testjson.Execution) error {
+	if opts.sonarFile == "" {
+		return nil
+	}
+	return writeReportFile(opts.sonarFile, func(out io.Writer) error {
+		return sonar.Write(out, execution, sonar.Config{})
+	})
+}
+
+// writeReportFile writes a report to a temporary file, and renames it to path,
+// so that path always contains a complete report, even if gotestsum is killed
+// while the report is written.
+func writeReportFile(path string, write func(out io.Writer) error) error {
+	_ = os.MkdirAll(filepath.Dir(path), 0o755)
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %v: %w", path, err)
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func writeCircleCITimingsFile(opts *options, execution *testjson.Execution) error {
+	if opts.circleCITimingsFile == "" {
+		return nil
+	}
+	_ = os.MkdirAll(filepath.Dir(opts.circleCITimingsFile), 0o755)
+	timingsFile, err := os.Create(opts.circleCITimingsFile)
+	if err != nil {
+		return fmt.Errorf("failed to open CircleCI timings file: %v", err)
+	}
+	defer func() {
+		if err := timingsFile.Close(); err != nil {
+			log.Errorf("Failed to close CircleCI timings file: %v", err)
+		}
+	}()
+
+	return circleci.Write(timingsFile, execution, circleci.Config{})
+}
+
+func writeBESFile(opts *options, execution *testjson.Execution, exitErr error) error {
+	if opts.besJSONFile == "" {
+		return nil
+	}
+	_ = os.MkdirAll(filepath.Dir(opts.besJSONFile), 0o755)
+	besFile, err := os.Create(opts.besJSONFile)
+	if err != nil {
+		return fmt.Errorf("failed to open build event file: %v", err)
+	}
+	defer func() {
+		if err := besFile.Close(); err != nil {
+			log.Errorf("Failed to close build event file: %v", err)
+		}
+	}()
+
+	return bes.Write(besFile, execution, bes.Config{
+		Version:   version,
+		Command:   goTestCmdArgs(opts, rerunOpts{}),
+		JUnitFile: opts.junitFile,
+		ExitCode:  ExitCodeWithDefault(exitErr),
+	})
+}
+
+// appendHistory appends the result of every test in the run to the
+// --history-file.
+func appendHistory(opts *options, execution *testjson.Execution) error {
+	if opts.historyFile == "" {
+		return nil
+	}
+	records := history.NewRecords(execution, history.RunInfoFromEnv(os.Getenv))
+	return history.Append(opts.historyFile, records)
+}
+
+func writeAllureResults(opts *options, execution *testjson.Execution) error {
+	if opts.allureResultsDir == "" {
+		return nil
+	}
+	return allure.Write(opts.allureResultsDir, execution)
+}
+
+// endFormatExec writes the run_end message to the --format=exec command, and
+// waits for the command to exit, so that its output is printed before the
+// summary. A command which fails does not fail the run.
+func endFormatExec(opts *options, execution *testjson.Execution, exitErr error) {
+	if opts.formatExec == nil {
+		return
+	}
+	_ = opts.formatExec.RunEnd(execution, ExitCodeWithDefault(exitErr))
+	if err := opts.formatExec.Close(); err != nil {
+		log.Warnf("--format command failed: %v", err)
+	}
+	opts.formatExec = nil
+}
+
+// endResultsExec writes the run_end message to the --results-exec command, and
+// waits for the command to exit. A command which fails does not fail the run.
+func endResultsExec(opts *options, execution *testjson.Execution, exitErr error) {
+	if opts.resultsExec == nil {
+		return
+	}
+	opts.resultsExec.RunEnd(execution, ExitCodeWithDefault(exitErr))
+	if err := opts.resultsExec.Close(); err != nil {
+		log.Warnf("--results-exec command failed: %v", err)
+	}
+	opts.resultsExec = nil
+}
+
+func postRunHook(opts *options, execution *testjson.Execution, exitErr error) error {
 	command := opts.postRunHookCmd.Value()
 	if len(command) == 0 {
 		return nil
 	}
 	log.Debugf("exec: %s", command)
 
+	summary := newRunSummary(opts, execution, exitErr)
+	raw, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
 	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(append(raw, '\n'))
 	cmd.Stdout = opts.stdout
 	cmd.Stderr = opts.stderr
 	cmd.Env = append(
@@ -172,11 +452,152 @@ func postRunHook(opts *options, execution *testjson.Execution) error {
 		"GOTESTSUM_JSONFILE="+opts.jsonFile,
 		"GOTESTSUM_JSONFILE_TIMING_EVENTS="+opts.jsonFileTimingEvents,
 		"GOTESTSUM_JUNITFILE="+opts.junitFile,
+		"GOTESTSUM_XUNITFILE="+opts.xunitFile,
+		"GOTESTSUM_SONARFILE="+opts.sonarFile,
+		"GOTESTSUM_COVERPROFILE="+summary.Reports.CoverProfile,
+		"GOTESTSUM_STATUS="+summary.Status,
+		fmt.Sprintf("GOTESTSUM_EXIT_CODE=%d", summary.ExitCode),
 		fmt.Sprintf("GOTESTSUM_ELAPSED=%.3fs", execution.Elapsed().Seconds()),
 		fmt.Sprintf("TESTS_TOTAL=%d", execution.Total()),
 		fmt.Sprintf("TESTS_FAILED=%d", len(execution.Failed())),
 		fmt.Sprintf("TESTS_SKIPPED=%d", len(execution.Skipped())),
 		fmt.Sprintf("TESTS_ERRORS=%d", len(execution.Errors())),
+		fmt.Sprintf("TESTS_FLAKY=%d", summary.Totals.Flaky),
 	)
 	return cmd.Run()
 }
+
+// sendNotification sends a desktop notification with the results of the run.
+// A notification which fails to send does not fail the run.
+func sendNotification(opts *options, execution *testjson.Execution) {
+	if !opts.notify {
+		return
+	}
+	if err := notifySendFn(newRunNotification(execution)); err != nil {
+		log.Warnf("failed to send notification: %v", err)
+	}
+}
+
+var notifySendFn = notify.Send
+
+func newRunNotification(execution *testjson.Execution) notify.Notification {
+	failed := len(execution.Failed())
+	skipped := len(execution.Skipped())
+	errs := len(execution.Errors())
+
+	title := "Passed"
+	switch {
+	case errs > 0:
+		title = "Errored"
+	case failed > 0:
+		title = "Failed"
+	case skipped > 0:
+		title = "Passed with skipped"
+	}
+
+	msg := fmt.Sprintf("%d tests run in %v", execution.Total(),
+		execution.Elapsed().Round(time.Millisecond))
+	if errs > 0 {
+		msg += fmt.Sprintf(", %d errors", errs)
+	}
+	if failed > 0 {
+		msg += fmt.Sprintf(", %d failed", failed)
+	}
+	if skipped > 0 {
+		msg += fmt.Sprintf(", %d skipped", skipped)
+	}
+	return notify.Notification{Title: title, Message: msg}
+}
+
+// postWebhook posts a summary of the results of the run to --notify-webhook.
+// A webhook which fails does not fail the run.
+func postWebhook(opts *options, execution *testjson.Execution, exitErr error) {
+	if opts.notifyWebhook == "" {
+		return
+	}
+	summary := webhook.NewSummary(execution, webhook.JobURL(os.Getenv))
+	if summary.Passed() && exitErr != nil {
+		summary.Status = "errored"
+	}
+	if opts.notifyWebhookOnFailure && summary.Passed() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+	format := webhook.Format(opts.notifyWebhookFormat)
+	if err := webhookPostFn(ctx, http.DefaultClient, opts.notifyWebhook, format, summary); err != nil {
+		log.Warnf("failed to post to webhook: %v", err)
+	}
+}
+
+// uploadTimeout is the timeout of requests which send the results of a run
+// to a service.
+const uploadTimeout = 30 * time.Second
+
+var webhookPostFn = webhook.Post
+
+// exportTrace exports an OpenTelemetry trace of the run. A trace which fails to
+// export does not fail the run.
+func exportTrace(opts *options, execution *testjson.Execution) {
+	if !opts.otlpTraces {
+		return
+	}
+	cfg, err := otlp.ConfigFromEnv(os.Getenv)
+	if err != nil {
+		log.Warnf("failed to export trace: %v", err)
+		return
+	}
+	cfg.Trace.Version = version
+	if err := otlpExportFn(context.Background(), http.DefaultClient, execution, cfg); err != nil {
+		log.Warnf("failed to export trace to %v: %v", cfg.Endpoint, err)
+	}
+}
+
+var otlpExportFn = otlp.Export
+
+// sendToDatadog sends the results of the run to Datadog CI Visibility. Results
+// which fail to send do not fail the run.
+func sendToDatadog(opts *options, execution *testjson.Execution) {
+	if !opts.datadog {
+		return
+	}
+	intake, err := datadog.IntakeFromEnv(os.Getenv)
+	if err != nil {
+		log.Warnf("failed to send test results to Datadog: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+	payload := datadog.NewPayload(execution, intake.Config)
+	if err := datadogSendFn(ctx, http.DefaultClient, intake.URL, intake.APIKey, payload); err != nil {
+		log.Warnf("failed to send test results to Datadog: %v", err)
+	}
+}
+
+var datadogSendFn = datadog.Send
+
+// loadCustomIcons reads the icon set from the file named by --format-icons,
+// when the value is not the name of a built-in icon set.
+func loadCustomIcons(formatOpts *testjson.FormatOptions) error {
+	name := formatOpts.Icons
+	if name == "" || formatOpts.CustomIcons != nil || testjson.IsBuiltinIconSet(name) {
+		return nil
+	}
+	raw, err := os.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("--format-icons %v is not a built-in icon set or a file: %w", name, err)
+	}
+
+	// color defaults to true when it is not set in the file
+	icons := testjson.IconSet{Color: true}
+	if err := json.Unmarshal(raw, &icons); err != nil {
+		return fmt.Errorf("failed to parse icons from %v: %w", name, err)
+	}
+	if icons.Pass == "" || icons.Skip == "" || icons.Fail == "" {
+		return fmt.Errorf("icons in %v must set pass, skip, and fail", name)
+	}
+	formatOpts.CustomIcons = &icons
+	return nil
+}
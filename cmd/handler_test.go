@@ -2,14 +2,24 @@ package cmd
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 
+	"gotest.tools/gotestsum/internal/datadog"
+	"gotest.tools/gotestsum/internal/jsonfile"
 	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/notify"
+	"gotest.tools/gotestsum/internal/otlp"
 	"gotest.tools/gotestsum/internal/text"
+	"gotest.tools/gotestsum/internal/webhook"
 	"gotest.tools/gotestsum/testjson"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/assert/cmp"
@@ -35,7 +45,7 @@ func TestPostRunHook(t *testing.T) {
 	t.Setenv("GOTESTSUM_FORMAT_ICONS", "default")
 
 	exec := newExecFromTestData(t)
-	err = postRunHook(opts, exec)
+	err = postRunHook(opts, exec, exitError{num: 1})
 	assert.NilError(t, err)
 
 	actual := text.ProcessLines(t, buf, func(line string) string {
@@ -49,6 +59,173 @@ func TestPostRunHook(t *testing.T) {
 	golden.Assert(t, actual, "post-run-hook-expected")
 }
 
+func TestSendNotification(t *testing.T) {
+	var sent []notify.Notification
+	orig := notifySendFn
+	notifySendFn = func(n notify.Notification) error {
+		sent = append(sent, n)
+		return nil
+	}
+	t.Cleanup(func() { notifySendFn = orig })
+
+	exec := newExecFromTestData(t)
+	sendNotification(&options{}, exec)
+	assert.Equal(t, len(sent), 0)
+
+	sendNotification(&options{notify: true}, exec)
+	assert.Equal(t, len(sent), 1)
+	assert.Equal(t, sent[0].Title, "Failed")
+	assert.Assert(t, strings.HasPrefix(sent[0].Message, "59 tests run in "), sent[0].Message)
+	assert.Assert(t, strings.HasSuffix(sent[0].Message, ", 13 failed, 5 skipped"), sent[0].Message)
+}
+
+func TestPostWebhook(t *testing.T) {
+	var posted []webhook.Summary
+	orig := webhookPostFn
+	webhookPostFn = func(_ context.Context, _ *http.Client, url string, format webhook.Format, s webhook.Summary) error {
+		assert.Equal(t, url, "https://hooks.example.com/abc")
+		assert.Equal(t, format, webhook.FormatSlack)
+		posted = append(posted, s)
+		return nil
+	}
+	t.Cleanup(func() { webhookPostFn = orig })
+
+	opts := &options{
+		notifyWebhook:       "https://hooks.example.com/abc",
+		notifyWebhookFormat: "slack",
+	}
+	exec := newExecFromTestData(t)
+	postWebhook(opts, exec, nil)
+	assert.Equal(t, len(posted), 1)
+	assert.Equal(t, posted[0].Status, "failed")
+	assert.Equal(t, posted[0].Total, 59)
+	assert.Equal(t, posted[0].Failed, 13)
+
+	opts.notifyWebhookOnFailure = true
+	passed := &testjson.Execution{}
+	postWebhook(opts, passed, nil)
+	assert.Equal(t, len(posted), 1, "passed run should not be posted")
+
+	postWebhook(opts, passed, errors.New("exit status 2"))
+	assert.Equal(t, len(posted), 2)
+	assert.Equal(t, posted[1].Status, "errored")
+}
+
+func TestExportTrace(t *testing.T) {
+	var exported []otlp.ExporterConfig
+	orig := otlpExportFn
+	otlpExportFn = func(_ context.Context, _ *http.Client, _ *testjson.Execution, cfg otlp.ExporterConfig) error {
+		exported = append(exported, cfg)
+		return nil
+	}
+	t.Cleanup(func() { otlpExportFn = orig })
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "https://otlp.example.com")
+	t.Setenv("OTEL_SERVICE_NAME", "unit-tests")
+
+	exec := newExecFromTestData(t)
+	exportTrace(&options{}, exec)
+	assert.Equal(t, len(exported), 0)
+
+	exportTrace(&options{otlpTraces: true}, exec)
+	assert.Equal(t, len(exported), 1)
+	assert.Equal(t, exported[0].Endpoint, "https://otlp.example.com/v1/traces")
+}
+
+func TestSendToDatadog(t *testing.T) {
+	var sent []datadog.Payload
+	orig := datadogSendFn
+	datadogSendFn = func(_ context.Context, _ *http.Client, url, apiKey string, payload datadog.Payload) error {
+		assert.Equal(t, url, "https://citestcycle-intake.datadoghq.com/api/v2/citestcycle")
+		assert.Equal(t, apiKey, "the-key")
+		sent = append(sent, payload)
+		return nil
+	}
+	t.Cleanup(func() { datadogSendFn = orig })
+	t.Setenv("DD_API_KEY", "the-key")
+	t.Setenv("DD_SITE", "")
+	t.Setenv("DD_CIVISIBILITY_AGENTLESS_URL", "")
+
+	exec := newExecFromTestData(t)
+	sendToDatadog(&options{}, exec)
+	assert.Equal(t, len(sent), 0)
+
+	sendToDatadog(&options{datadog: true}, exec)
+	assert.Equal(t, len(sent), 1)
+}
+
+func TestResultsExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+	out := filepath.Join(t.TempDir(), "results.jsonl")
+	command := &commandValue{}
+	assert.NilError(t, command.Set("sh -c 'cat > "+out+"'"))
+	opts := &options{
+		format:         "standard-quiet",
+		resultsExecCmd: command,
+		stdout:         io.Discard,
+		stderr:         io.Discard,
+	}
+	handler, err := newEventHandler(opts)
+	assert.NilError(t, err)
+	defer handler.Close() //nolint:errcheck
+
+	f, err := os.Open("../testjson/testdata/input/go-test-json.out")
+	assert.NilError(t, err)
+	defer f.Close() //nolint:errcheck
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  f,
+		Stderr:  strings.NewReader(""),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	endResultsExec(opts, exec, exitError{num: 1})
+
+	raw, err := os.ReadFile(out)
+	assert.NilError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	// run_start, a test_done for every test, and run_end
+	assert.Equal(t, len(lines), 2+exec.Total())
+	assert.Assert(t, cmp.Contains(lines[0], `"type":"run_start"`))
+	assert.Assert(t, cmp.Contains(lines[len(lines)-1], `"exit_code":1`))
+}
+
+func TestFormatExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+	stdout := new(bytes.Buffer)
+	opts := &options{
+		format:      `exec:sh -c 'grep -c "\"type\":\"event\""'`,
+		stdout:      stdout,
+		stderr:      io.Discard,
+		hideSummary: newHideSummaryValue(),
+	}
+	handler, err := newEventHandler(opts)
+	assert.NilError(t, err)
+	defer handler.Close() //nolint:errcheck
+
+	input, err := os.ReadFile("../testjson/testdata/input/go-test-json.out")
+	assert.NilError(t, err)
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  bytes.NewReader(input),
+		Stderr:  strings.NewReader(""),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, finishRun(opts, exec, nil))
+
+	// the output of the command is printed before the summary
+	first, summary, _ := strings.Cut(stdout.String(), "\n")
+	assert.Equal(t, first, strconv.Itoa(bytes.Count(input, []byte("\n"))))
+	assert.Assert(t, cmp.Contains(summary, "DONE"))
+}
+
+func TestFormatExec_InvalidCommand(t *testing.T) {
+	_, err := newEventHandler(&options{format: "exec:", stdout: io.Discard, stderr: io.Discard})
+	assert.Error(t, err, "a command is required after exec:")
+}
+
 func newExecFromTestData(t *testing.T) *testjson.Execution {
 	t.Helper()
 	f, err := os.Open("../testjson/testdata/input/go-test-json.out")
@@ -105,6 +282,34 @@ func TestEventHandler_Event_MaxFails(t *testing.T) {
 	assert.Error(t, err, "ending test run because max failures was reached")
 }
 
+type closerFormatter struct {
+	closed bool
+}
+
+func (f *closerFormatter) Format(testjson.TestEvent, *testjson.Execution) error {
+	return nil
+}
+
+func (f *closerFormatter) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestEventHandler_CloseFormatter_WithUnfinishedPackage(t *testing.T) {
+	format := &closerFormatter{}
+	handler := &eventHandler{formatter: format, maxFails: 2}
+
+	source := golden.Get(t, "../../testjson/testdata/input/go-test-json.out")
+	_, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  bytes.NewReader(source),
+		Handler: handler,
+	})
+	assert.ErrorIs(t, err, errMaxFailsReached)
+
+	handler.closeFormatter()
+	assert.Assert(t, format.closed)
+}
+
 func TestNewEventHandler_CreatesDirectory(t *testing.T) {
 	dir := fs.NewDir(t, t.Name())
 	jsonFile := filepath.Join(dir.Path(), "new-path", "log.json")
@@ -121,6 +326,37 @@ func TestNewEventHandler_CreatesDirectory(t *testing.T) {
 	assert.NilError(t, err)
 }
 
+func TestNewEventHandler_CompressedJSONFile(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	jsonFile := filepath.Join(dir.Path(), "log.json.gz")
+
+	opts := &options{
+		stdout:   new(bytes.Buffer),
+		stderr:   new(bytes.Buffer),
+		format:   "testname",
+		jsonFile: jsonFile,
+	}
+	handler, err := newEventHandler(opts)
+	assert.NilError(t, err)
+
+	source := golden.Get(t, "../../testjson/testdata/input/go-test-json.out")
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  bytes.NewReader(source),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	// finishRun closes the file before the post run command
+	opts.closeJSONFiles()
+	assert.NilError(t, handler.Close())
+
+	in, err := jsonfile.Open(jsonFile)
+	assert.NilError(t, err)
+	defer in.Close() //nolint:errcheck
+	out, err := io.ReadAll(in)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), string(source))
+}
+
 func TestWriteJunitFile_CreatesDirectory(t *testing.T) {
 	dir := fs.NewDir(t, t.Name())
 	junitFile := filepath.Join(dir.Path(), "new-path", "junit.xml")
@@ -138,6 +374,30 @@ func TestWriteJunitFile_CreatesDirectory(t *testing.T) {
 	assert.NilError(t, err)
 }
 
+func TestPublishAzurePipelinesResults(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	junitFile := filepath.Join(dir.Path(), "junit.xml")
+
+	out := new(bytes.Buffer)
+	opts := &options{junitFile: junitFile, format: "testname", stdout: out}
+	assert.NilError(t, publishAzurePipelinesResults(opts))
+	assert.Equal(t, out.String(), "")
+
+	opts.format = "azure-pipelines"
+	assert.NilError(t, publishAzurePipelinesResults(opts))
+	expected := "##vso[results.publish type=JUnit;resultFiles=" + junitFile + ";]\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestGitLabJUnitFile(t *testing.T) {
+	assert.Equal(t, gitLabJUnitFile("", "/builds/project"), "")
+	assert.Equal(t, gitLabJUnitFile("junit.xml", ""), "junit.xml")
+	assert.Equal(t, gitLabJUnitFile("out/junit.xml", "/builds/project"),
+		filepath.Join("/builds/project", "out/junit.xml"))
+	abs := filepath.Join(t.TempDir(), "junit.xml")
+	assert.Equal(t, gitLabJUnitFile(abs, "/builds/project"), abs)
+}
+
 func TestScanTestOutput_TestTimeoutPanicRace(t *testing.T) {
 	run := func(t *testing.T, name string) {
 		format := testjson.NewEventFormatter(io.Discard, "testname", testjson.FormatOptions{})
@@ -222,3 +482,32 @@ func TestEventHandler_SetupFail(t *testing.T) {
 	actual := text.ProcessLines(t, out, text.OpRemoveSummaryLineElapsedTime)
 	golden.Assert(t, actual, "expected/setup-fail-expected")
 }
+
+func TestLoadCustomIcons(t *testing.T) {
+	t.Run("built-in icon set", func(t *testing.T) {
+		formatOpts := testjson.FormatOptions{Icons: "ascii"}
+		assert.NilError(t, loadCustomIcons(&formatOpts))
+		assert.Assert(t, formatOpts.CustomIcons == nil)
+	})
+
+	t.Run("from file", func(t *testing.T) {
+		file := fs.NewFile(t, "icons", fs.WithContent(`{"pass": "ok", "skip": "--", "fail": "NO"}`))
+		formatOpts := testjson.FormatOptions{Icons: file.Path()}
+		assert.NilError(t, loadCustomIcons(&formatOpts))
+		expected := &testjson.IconSet{Pass: "ok", Skip: "--", Fail: "NO", Color: true}
+		assert.DeepEqual(t, formatOpts.CustomIcons, expected)
+	})
+
+	t.Run("missing icons", func(t *testing.T) {
+		file := fs.NewFile(t, "icons", fs.WithContent(`{"pass": "ok", "color": false}`))
+		formatOpts := testjson.FormatOptions{Icons: file.Path()}
+		err := loadCustomIcons(&formatOpts)
+		assert.ErrorContains(t, err, "must set pass, skip, and fail")
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		formatOpts := testjson.FormatOptions{Icons: "sparkles"}
+		err := loadCustomIcons(&formatOpts)
+		assert.ErrorContains(t, err, "--format-icons sparkles is not a built-in icon set or a file")
+	})
+}
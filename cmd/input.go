@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// inputValue is a flag.Value which accepts a file of test2json events, with
+// an optional label. The flag may be used more than once.
+type inputValue []inputSource
+
+type inputSource struct {
+	// label is added to the name of every package read from the file, so that
+	// the same package tested on different platforms is reported separately.
+	label string
+	// path is the file to read, which may be a named pipe, or - for stdin.
+	path string
+}
+
+func (v *inputValue) String() string {
+	if v == nil {
+		return ""
+	}
+	values := make([]string, len(*v))
+	for i, source := range *v {
+		values[i] = source.path
+		if source.label != "" {
+			values[i] = source.label + "=" + source.path
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+func (v *inputValue) Set(raw string) error {
+	source := inputSource{path: raw}
+	if label, path, ok := strings.Cut(raw, "="); ok && !strings.ContainsAny(label, `/\`) {
+		source = inputSource{label: label, path: path}
+	}
+	if source.path == "" {
+		return errors.New("file must not be empty")
+	}
+	*v = append(*v, source)
+	return nil
+}
+
+func (v *inputValue) Type() string {
+	return "[label=]file"
+}
+
+// ExitErr returns an error with exit code 1 when a package read from the
+// inputs failed, because there is no go test command to return the exit code.
+func (v inputValue) ExitErr(exec *testjson.Execution, exitErr error) error {
+	if len(v) == 0 || exitErr != nil {
+		return exitErr
+	}
+	for _, pkg := range exec.Packages() {
+		if exec.Package(pkg).Result() == testjson.ActionFail {
+			return exitError{num: 1}
+		}
+	}
+	return nil
+}
+
+// startInputs returns a proc which reads the events from every input at the
+// same time. Each line is written to stdout once it has been read completely,
+// so the events of an input are never split, and are kept in the order they
+// were read from the input. The packages in the Execution are sorted by name,
+// so the summary and reports do not depend on the order of the lines.
+func startInputs(ctx context.Context, inputs inputValue) *proc {
+	r, w := io.Pipe()
+	waiter := &inputWaiter{ctx: ctx, done: make(chan struct{})}
+
+	var wg sync.WaitGroup
+	for _, source := range inputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := copyInput(w, source); err != nil {
+				waiter.setErr(err)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		_ = w.Close()
+		close(waiter.done)
+	}()
+	go func() {
+		// reading from a named pipe blocks until it is closed by the writer
+		select {
+		case <-ctx.Done():
+			_ = w.Close()
+		case <-waiter.done:
+		}
+	}()
+	return &proc{cmd: waiter, stdout: r, stderr: bytes.NewReader(nil)}
+}
+
+func copyInput(out *io.PipeWriter, source inputSource) error {
+	in := io.Reader(os.Stdin)
+	if source.path != "-" {
+		// opening a named pipe blocks until it is opened by the writer
+		f, err := os.Open(source.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close() //nolint:errcheck
+		in = f
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if line[len(line)-1] != '\n' {
+				line = append(line, '\n')
+			}
+			if _, err := out.Write(labelEvent(line, source.label)); err != nil {
+				// the run was stopped
+				return nil
+			}
+		}
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+	}
+}
+
+// labelEvent adds the label to the package of a test2json event. Lines which
+// are not events are returned unchanged.
+func labelEvent(line []byte, label string) []byte {
+	if label == "" || !bytes.HasPrefix(line, []byte("{")) {
+		return line
+	}
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal(line, &event); err != nil {
+		return line
+	}
+	for _, key := range []string{"Package", "ImportPath"} {
+		var name string
+		if err := json.Unmarshal(event[key], &name); err != nil || name == "" {
+			continue
+		}
+		event[key], _ = json.Marshal(name + " [" + label + "]")
+	}
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return line
+	}
+	return append(raw, '\n')
+}
+
+type inputWaiter struct {
+	ctx  context.Context
+	done chan struct{}
+	mu   sync.Mutex
+	err  error
+}
+
+func (w *inputWaiter) setErr(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// Wait returns the first error from reading an input. It does not wait for
+// the inputs which are still open when the run is stopped.
+func (w *inputWaiter) Wait() error {
+	select {
+	case <-w.done:
+	case <-w.ctx.Done():
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestInputValue(t *testing.T) {
+	var value inputValue
+	assert.NilError(t, value.Set("linux=out/linux.json"))
+	assert.NilError(t, value.Set("./a=b/darwin.json"))
+	assert.NilError(t, value.Set("-"))
+	assert.DeepEqual(t, value, inputValue{
+		{label: "linux", path: "out/linux.json"},
+		{path: "./a=b/darwin.json"},
+		{path: "-"},
+	}, cmpInputSource)
+	assert.Equal(t, value.String(), "linux=out/linux.json,./a=b/darwin.json,-")
+
+	assert.ErrorContains(t, value.Set("linux="), "file must not be empty")
+}
+
+var cmpInputSource = cmp.AllowUnexported(inputSource{})
+
+func TestLabelEvent(t *testing.T) {
+	line := []byte(`{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}` + "\n")
+	assert.Equal(t, string(labelEvent(line, "")), string(line))
+	assert.Equal(t, string(labelEvent(line, "linux")),
+		`{"Action":"run","Package":"example.com/pkg [linux]","Test":"TestOne"}`+"\n")
+
+	build := []byte(`{"ImportPath":"example.com/pkg [example.com/pkg.test]","Action":"build-fail"}` + "\n")
+	assert.Equal(t, string(labelEvent(build, "linux")),
+		`{"Action":"build-fail","ImportPath":"example.com/pkg [example.com/pkg.test] [linux]"}`+"\n")
+
+	text := []byte("panic: not an event\n")
+	assert.Equal(t, string(labelEvent(text, "linux")), string(text))
+}
+
+func TestRun_Input(t *testing.T) {
+	events := `{"Action":"start","Package":"example.com/pkg"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/pkg"}
+`
+	failed := `{"Action":"start","Package":"example.com/pkg"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"fail","Package":"example.com/pkg"}`
+	dir := fs.NewDir(t, "input",
+		fs.WithFile("linux.json", events),
+		fs.WithFile("windows.json", failed))
+
+	out := new(bytes.Buffer)
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse([]string{
+		"--format=testname",
+		"--input=linux=" + dir.Join("linux.json"),
+		"--input=windows=" + dir.Join("windows.json"),
+	}))
+	opts.stdout = out
+	opts.stderr = new(bytes.Buffer)
+
+	err := run(opts)
+	exec := opts.execution
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.DeepEqual(t, exec.Packages(), []string{
+		"example.com/pkg [linux]",
+		"example.com/pkg [windows]",
+	})
+	assert.Equal(t, exec.Total(), 2)
+	assert.Equal(t, len(exec.Failed()), 1)
+	assert.Equal(t, exec.Failed()[0].Package, "example.com/pkg [windows]")
+}
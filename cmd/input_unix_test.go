@@ -0,0 +1,51 @@
+//go:build unix
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestRun_Input_NamedPipes(t *testing.T) {
+	dir := fs.NewDir(t, "input")
+	paths := []string{dir.Join("one"), dir.Join("two")}
+	for _, path := range paths {
+		assert.NilError(t, syscall.Mkfifo(path, 0o600))
+	}
+
+	// the events of each pipe are written after the other pipe is opened, so
+	// the pipes must be read at the same time
+	go func() {
+		one, err := os.OpenFile(paths[0], os.O_WRONLY, 0)
+		assert.Check(t, err)
+		two, err := os.OpenFile(paths[1], os.O_WRONLY, 0)
+		assert.Check(t, err)
+		for _, f := range []*os.File{one, two} {
+			pkg := filepath.Base(f.Name())
+			_, err := f.WriteString(`{"Action":"run","Package":"` + pkg + `","Test":"TestOne"}` + "\n" +
+				`{"Action":"pass","Package":"` + pkg + `","Test":"TestOne"}` + "\n" +
+				`{"Action":"pass","Package":"` + pkg + `"}` + "\n")
+			assert.Check(t, err)
+		}
+		assert.Check(t, one.Close())
+		assert.Check(t, two.Close())
+	}()
+
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse([]string{"--input=" + paths[0], "--input=" + paths[1]}))
+	opts.stdout = new(bytes.Buffer)
+	opts.stderr = new(bytes.Buffer)
+
+	err := run(opts)
+	exec := opts.execution
+	assert.NilError(t, err)
+	assert.DeepEqual(t, exec.Packages(), []string{"one", "two"})
+	assert.Equal(t, exec.Total(), 2)
+}
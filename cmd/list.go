@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// RunList runs the list command, which prints the tests, benchmarks, fuzz
+// tests, and examples in each package as JSON.
+func RunList(name string, args []string) error {
+	flags, opts := setupListFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		listUsage(os.Stderr, name, flags)
+		return err
+	}
+	setListArgs(opts, flags)
+	setupLogging(opts)
+	return runList(opts)
+}
+
+// setListArgs adds the positional args to the packages, and sets the go test
+// args to the args after --.
+func setListArgs(opts *options, flags *pflag.FlagSet) {
+	positional := flags.Args()
+	if dash := flags.ArgsLenAtDash(); dash >= 0 {
+		opts.args = positional[dash:]
+		positional = positional[:dash]
+	}
+	opts.packages = append(opts.packages, positional...)
+}
+
+func setupListFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{stdout: os.Stdout, stderr: os.Stderr}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		listUsage(os.Stdout, name, flags)
+	}
+	flags.Var((*stringSlice)(&opts.packages), "packages",
+		"space separated list of packages to list")
+	flags.StringVar(&opts.onlyAffected, "only-affected", "",
+		"only list the packages with files that changed since this git ref, and the packages which import them")
+	flags.Lookup("only-affected").NoOptDefVal = defaultAffectedRef
+	flags.Var(&opts.partition, "partition",
+		"only list the packages in partition INDEX/TOTAL, using the package times from the --history-file to balance the partitions")
+	flags.StringVar(&opts.historyFile, "history-file",
+		lookEnvWithDefault("GOTESTSUM_HISTORY_FILE", ""),
+		"file of previous runs, used to balance the --partition")
+	flags.BoolVar(&opts.debug, "debug", false, "enabled debug logging")
+	return flags, opts
+}
+
+func listUsage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] [packages...] [-- go test flags]
+
+Print the tests, benchmarks, fuzz tests, and examples in each package, using
+'go test -list'. Each package is printed as a JSON object on a single line.
+The packages are selected with the same flags as the gotestsum command. The
+default is ./...
+
+The go test flags after -- are used to build the tests, for example -tags.
+
+Example:
+
+    %[1]s --only-affected ./... -- -tags=integration
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+// listedPackageTests is the JSON printed for each package by the list command.
+type listedPackageTests struct {
+	Package    string   `json:"package"`
+	Tests      []string `json:"tests"`
+	Benchmarks []string `json:"benchmarks"`
+	Fuzz       []string `json:"fuzz"`
+	Examples   []string `json:"examples"`
+}
+
+var listedNamePattern = regexp.MustCompile(`^(Test|Benchmark|Fuzz|Example)\w*$`)
+
+// goTestListFn is a shim for testing
+var goTestListFn = goTestList
+
+func runList(opts *options) error {
+	if opts.onlyAffected != "" {
+		selection, err := onlyAffectedPackages(opts)
+		if err != nil {
+			return err
+		}
+		printSkippedPackages(opts, selection)
+		if len(selection.packages) == 0 {
+			return nil
+		}
+		opts.packages = selection.packages
+	}
+	if opts.partition.total > 0 {
+		ok, err := applyPartition(opts)
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	// the -tags flag may include packages which are otherwise excluded
+	patterns := append(slices.Clone(listBuildFlags(opts.args)), cmdArgPackageList(opts, rerunOpts{}, "./...")...)
+	pkgs, err := goListPackagesFn(patterns)
+	if err != nil || len(pkgs) == 0 {
+		return err
+	}
+	log.Debugf("listing the tests in %d packages", len(pkgs))
+	names, err := goTestListFn(pkgs, opts.args, listedNamePattern)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(opts.stdout)
+	for _, pkg := range pkgs {
+		if err := enc.Encode(newListedPackageTests(pkg, names[pkg])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newListedPackageTests(pkg string, names []string) listedPackageTests {
+	result := listedPackageTests{
+		Package:    pkg,
+		Tests:      []string{},
+		Benchmarks: []string{},
+		Fuzz:       []string{},
+		Examples:   []string{},
+	}
+	for _, name := range names {
+		switch {
+		case strings.HasPrefix(name, "Benchmark"):
+			result.Benchmarks = append(result.Benchmarks, name)
+		case strings.HasPrefix(name, "Fuzz"):
+			result.Fuzz = append(result.Fuzz, name)
+		case strings.HasPrefix(name, "Example"):
+			result.Examples = append(result.Examples, name)
+		default:
+			result.Tests = append(result.Tests, name)
+		}
+	}
+	return result
+}
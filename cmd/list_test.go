@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRunList(t *testing.T) {
+	origPackages := goListPackagesFn
+	goListPackagesFn = func(patterns []string) ([]string, error) {
+		assert.DeepEqual(t, patterns, []string{"-tags", "integration", "./api/...", "./store"})
+		return []string{"example.com/api", "example.com/api/v2", "example.com/store"}, nil
+	}
+	t.Cleanup(func() { goListPackagesFn = origPackages })
+
+	origList := goTestListFn
+	goTestListFn = func(pkgs []string, buildFlags []string, pattern *regexp.Regexp) (map[string][]string, error) {
+		assert.DeepEqual(t, buildFlags, []string{"-tags", "integration", "-count=1"})
+		assert.Equal(t, pattern, listedNamePattern)
+		out := []byte(`TestGet
+TestPut
+BenchmarkGet
+FuzzDecode
+ExampleClient
+ok  	example.com/api	0.002s
+?   	example.com/api/v2	[no test files]
+TestOpen
+ok  	example.com/store	(cached)
+`)
+		return parseTestList(out, pattern), nil
+	}
+	t.Cleanup(func() { goTestListFn = origList })
+
+	flags, opts := setupListFlags("gotestsum list")
+	assert.NilError(t, flags.Parse([]string{
+		"--packages=./api/...", "./store", "--", "-tags", "integration", "-count=1",
+	}))
+	setListArgs(opts, flags)
+	out := new(bytes.Buffer)
+	opts.stdout = out
+
+	assert.NilError(t, runList(opts))
+	expected := `{"package":"example.com/api","tests":["TestGet","TestPut"],"benchmarks":["BenchmarkGet"],"fuzz":["FuzzDecode"],"examples":["ExampleClient"]}
+{"package":"example.com/api/v2","tests":[],"benchmarks":[],"fuzz":[],"examples":[]}
+{"package":"example.com/store","tests":["TestOpen"],"benchmarks":[],"fuzz":[],"examples":[]}
+`
+	assert.Equal(t, out.String(), expected)
+}
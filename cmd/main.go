@@ -8,14 +8,25 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"regexp"
+	"runtime"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/dnephin/pflag"
 	"github.com/fatih/color"
+	"gotest.tools/gotestsum/internal/coverprofile"
+	"gotest.tools/gotestsum/internal/experiment"
+	"gotest.tools/gotestsum/internal/formatexec"
 	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/internal/quarantine"
+	"gotest.tools/gotestsum/internal/resultsexec"
+	"gotest.tools/gotestsum/internal/verbose"
+	"gotest.tools/gotestsum/internal/webhook"
 	"gotest.tools/gotestsum/testjson"
 )
 
@@ -30,8 +41,9 @@ func Run(name string, args []string) error {
 		usage(os.Stderr, name, flags)
 		return err
 	}
+	applyUserConfig(flags)
 	opts.args = flags.Args()
-	setupLogging(opts)
+	setupOptions(opts)
 
 	switch {
 	case opts.version:
@@ -50,12 +62,76 @@ func Run(name string, args []string) error {
 	return run(opts)
 }
 
+// runContextMu serialises calls to RunContext, because the log level, the
+// writer of the log, and the color setting are global.
+var runContextMu sync.Mutex
+
+// RunContext runs gotestsum with args, in the same way as Run, and returns the
+// Execution of the run, so that a Go program can inspect the results. The
+// output is written to stdout and stderr. The user config is not applied, so
+// that the run only depends on args. The run stops when ctx is cancelled.
+//
+// The Execution is nil when no tests were run. The error is an ExitCoder when
+// the tests failed.
+//
+// Unlike Run, RunContext does not handle os.Interrupt, the caller stops the
+// run by cancelling ctx. The log level, the writer of the log, and
+// color.NoColor are set for the duration of the run, and restored when it
+// returns, so only one RunContext runs at a time.
+func RunContext(
+	ctx context.Context,
+	name string,
+	args []string,
+	stdout, stderr io.Writer,
+) (*testjson.Execution, error) {
+	runContextMu.Lock()
+	defer runContextMu.Unlock()
+	defer restoreGlobals(log.GetLevel(), log.SetOutput(stderr), color.NoColor)
+	ctx = withoutSignalHandler(ctx)
+
+	flags, opts := setupFlags(name)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	opts.args = flags.Args()
+	opts.stdout = stdout
+	opts.stderr = stderr
+	setupOptions(opts)
+
+	switch {
+	case opts.version:
+		return nil, fmt.Errorf("--version can not be used with RunContext")
+	case opts.watch:
+		return nil, fmt.Errorf("--watch can not be used with RunContext")
+	case opts.sandboxPackages:
+		// the -exec of go test must be the gotestsum executable
+		return nil, fmt.Errorf("--sandbox-packages can not be used with RunContext")
+	}
+	err := runContext(ctx, opts)
+	return opts.execution, err
+}
+
+// restoreGlobals restores the global state changed by RunContext.
+func restoreGlobals(level log.Level, out io.Writer, noColor bool) {
+	log.SetLevel(level)
+	log.SetOutput(out)
+	color.NoColor = noColor
+}
+
 func setupFlags(name string) (*pflag.FlagSet, *options) {
 	opts := &options{
 		hideSummary:                  newHideSummaryValue(),
 		junitTestCaseClassnameFormat: &junitFieldFormatValue{},
 		junitTestSuiteNameFormat:     &junitFieldFormatValue{},
 		postRunHookCmd:               &commandValue{},
+		resultsExecCmd:               &commandValue{},
+		onTestFailureExecCmd:         &commandValue{},
+		watchPreRunCmd:               &commandValue{},
+		watchDebugCmd:                &commandValue{},
+		durationRegressionThreshold:  &percentValue{},
+		rerunFailsDelay:              &rerunDelayValue{},
 		stdout:                       color.Output,
 		stderr:                       color.Error,
 	}
@@ -79,31 +155,103 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 	flags.BoolVar(&opts.rawCommand, "raw-command", false,
 		"don't prepend 'go test -json' to the 'go test' command")
 	flags.BoolVar(&opts.ignoreNonJSONOutputLines, "ignore-non-json-output-lines", false,
-		"write non-JSON 'go test' output lines to stderr instead of failing")
+		"write non-JSON 'go test' output lines to stderr, instead of adding them to the output of the last test")
 	flags.Lookup("ignore-non-json-output-lines").Hidden = true
+	flags.StringVar(&opts.inputFormat, "input-format", "json",
+		"format of the output of the --raw-command, one of: json, text (the output of go test -v)")
+	flags.Var(&opts.input, "input",
+		"read test2json events from a file, named pipe, or - for stdin, instead of running go test, repeat to merge several files")
 	flags.StringVar(&opts.jsonFile, "jsonfile",
 		lookEnvWithDefault("GOTESTSUM_JSONFILE", ""),
-		"write all TestEvents to file")
+		"write all TestEvents to file, compressed with gzip when the file name ends with .gz")
 	flags.StringVar(&opts.jsonFileTimingEvents, "jsonfile-timing-events",
 		lookEnvWithDefault("GOTESTSUM_JSONFILE_TIMING_EVENTS", ""),
 		"write only the pass, skip, and fail TestEvents to the file")
+	flags.IntVar(&opts.formatOptions.MaxLineLength, "max-line-length", 0,
+		"truncate lines of test output longer than this number of characters, 0 for no limit")
+	flags.BoolVar(&opts.formatOptions.CollapseRepeatedOutput, "collapse-repeated-output", false,
+		"print consecutive identical lines of test output once, with the number of times they were repeated")
+	flags.BoolVarP(&opts.quiet, "quiet", "q", false,
+		"only print failures, and the summary when the run fails. Implies --format failures-only")
 	flags.BoolVar(&opts.noColor, "no-color", defaultNoColor(), "disable color output")
+	flags.BoolVar(&opts.formatOptions.Accessible, "accessible",
+		truthyFlag(lookEnvWithDefault("GOTESTSUM_ACCESSIBLE", "")),
+		"output for screen readers and dumb terminals: no color, icons, or rewritten lines")
 
 	flags.Var(opts.hideSummary, "no-summary",
 		"do not print summary of: "+testjson.SummarizeAll.String())
-	flags.Lookup("no-summary").Hidden = true
+	_ = flags.MarkDeprecated("no-summary", "use --hide-summary")
 	flags.Var(opts.hideSummary, "hide-summary",
 		"hide sections of the summary: "+testjson.SummarizeAll.String())
+	flags.IntVar(&opts.foldFailureOutput, "fold-failure-output", 0,
+		"in the summary, fold the output of failed tests with at least this many lines into a short headline")
+	flags.Var(&opts.foldFailurePatterns, "fold-failure-pattern",
+		"include lines matching this regexp in the headline of folded failures, may be repeated")
+	flags.IntVar(&opts.foldFailureTail, "fold-failure-tail", 5,
+		"number of lines from the end of the output to include in the headline of folded failures")
+	flags.Var(&opts.summarySection, "summary-section",
+		"add a section to the summary, one of: "+summarySectionNames()+
+			", or NAME=exec:COMMAND to print the stdout of a command, may be repeated")
 	flags.Var(opts.postRunHookCmd, "post-run-command",
 		"command to run after the tests have completed")
+	flags.Var(opts.resultsExecCmd, "results-exec",
+		"command which receives the results on stdin as a stream of JSON messages")
+	flags.Var(opts.onTestFailureExecCmd, "on-test-failure-exec",
+		"command to run for each test which fails, as soon as the test fails")
+	flags.BoolVar(&opts.notify, "notify",
+		truthyFlag(lookEnvWithDefault("GOTESTSUM_NOTIFY", "")),
+		"send a desktop notification with the results when the tests have completed")
+	flags.StringVar(&opts.notifyWebhook, "notify-webhook",
+		lookEnvWithDefault("GOTESTSUM_NOTIFY_WEBHOOK", ""),
+		"POST a summary of the results to this URL when the tests have completed")
+	flags.StringVar(&opts.notifyWebhookFormat, "notify-webhook-format",
+		lookEnvWithDefault("GOTESTSUM_NOTIFY_WEBHOOK_FORMAT", "json"),
+		"format of the summary posted to --notify-webhook, one of: json, slack")
+	flags.BoolVar(&opts.notifyWebhookOnFailure, "notify-webhook-on-failure", false,
+		"only POST to --notify-webhook when the run failed")
+	flags.BoolVar(&opts.datadog, "datadog",
+		truthyFlag(lookEnvWithDefault("GOTESTSUM_DATADOG", "")),
+		"send the results to Datadog CI Visibility, configured by the DD_* environment variables")
+	flags.BoolVar(&opts.otlpTraces, "otlp-traces",
+		truthyFlag(lookEnvWithDefault("GOTESTSUM_OTLP_TRACES", "")),
+		"export an OpenTelemetry trace of the run, configured by the OTEL_* environment variables")
 	flags.BoolVar(&opts.watch, "watch", false,
 		"watch go files, and run tests when a file is modified")
 	flags.BoolVar(&opts.watchClear, "watch-clear", false,
 		"in watch mode clear screen when rerun tests")
 	flags.BoolVar(&opts.watchChdir, "watch-chdir", false,
 		"in watch mode change the working directory to the directory with the modified file before running tests")
+	flags.BoolVar(&opts.watchAffected, "watch-affected", false,
+		"in watch mode also run tests in packages which import the package with the modified file")
+	flags.Var((*stringSlice)(&opts.watchIgnore), "watch-ignore",
+		"in watch mode ignore files and directories which match the glob patterns")
+	flags.Var((*stringSlice)(&opts.watchInclude), "watch-include",
+		"in watch mode also run tests when a file which matches the glob patterns is modified")
+	flags.BoolVar(&opts.watchFailureDiff, "watch-failure-diff", false,
+		"in watch mode print the failures which are new, still failing, or fixed since the previous run")
+	flags.BoolVar(&opts.watchStatusLine, "watch-status-line", true,
+		"in watch mode show the result of the last run and the keys in a status line at the bottom of the terminal")
+	flags.DurationVar(&opts.watchPoll, "watch-poll", 0,
+		"in watch mode check for modified files at this interval, instead of using file system notifications")
+	flags.DurationVar(&opts.watchDebounce, "watch-debounce", 0,
+		"in watch mode wait this long after a file is modified for more changes, before running tests")
+	flags.Var(opts.watchPreRunCmd, "watch-pre-run-command",
+		"in watch mode command to run before each test run, tests are not run if the command fails")
+	flags.Var(opts.watchDebugCmd, "watch-debug-command",
+		"in watch mode command used to debug tests, must accept the arguments of 'dlv test' (default 'dlv test')")
+	flags.BoolVar(&opts.sandboxTmpDir, "sandbox-tmpdir", false,
+		"run tests with TMPDIR set to a new directory, and warn about files left in the directory")
+	flags.BoolVar(&opts.sandboxPackages, "sandbox-packages", false,
+		"with --per-package run the tests of each package in a new directory with a copy of its testdata, and a new TMPDIR, and warn about files left in the directory")
 	flags.IntVar(&opts.maxFails, "max-fails", 0,
 		"end the test run after this number of failures")
+	flags.DurationVar(&opts.partialReportInterval, "partial-report-interval", 0,
+		"write the junit, xunit, and sonar files while the tests run, at most once every interval, so that a report exists if the run is killed")
+	flags.IntVar(&opts.maxTestOutputBytes, "max-test-output-bytes", 0,
+		"limit the output kept in memory for each test to this number of bytes, keeping the start and end, 0 for no limit")
+	flags.StringVar(&opts.quarantineFile, "quarantine-file",
+		lookEnvWithDefault("GOTESTSUM_QUARANTINE_FILE", ""),
+		"file with a list of flaky tests, which are run but do not fail the run")
 
 	flags.StringVar(&opts.junitFile, "junitfile",
 		lookEnvWithDefault("GOTESTSUM_JUNITFILE", ""),
@@ -121,6 +269,48 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 	flags.BoolVar(&opts.junitHideSkippedTests, "junitfile-hide-skipped-tests",
 		truthyFlag(lookEnvWithDefault("GOTESTSUM_JUNIT_HIDE_SKIPPED_TESTS", "")),
 		"omit skipped tests from the junit.xml file")
+	flags.BoolVar(&opts.junitIncludeOutput, "junitfile-system-out",
+		truthyFlag(lookEnvWithDefault("GOTESTSUM_JUNIT_SYSTEM_OUT", "")),
+		"include the output of passed tests in the junit.xml file as <system-out>")
+	flags.IntVar(&opts.junitMaxSystemOutBytes, "junitfile-system-out-max-bytes", 64*1024,
+		"truncate the <system-out> of each test to this number of bytes, 0 for no limit")
+	flags.IntVar(&opts.junitMaxOutputBytes, "junitfile-max-output-bytes", 0,
+		"truncate the output of each test in the junit.xml file to this number of bytes, keeping the start and end, 0 for no limit")
+	flags.IntVar(&opts.junitMaxTotalOutputBytes, "junitfile-max-total-output-bytes", 0,
+		"limit the output of all tests in the junit.xml file to this number of bytes, 0 for no limit")
+	flags.Var(&opts.junitProperties, "junitfile-property",
+		"add a property to each testsuite in the junit.xml file, may be repeated. A name without a value reads the value from the environment")
+	flags.StringVar(&opts.junitHostname, "junitfile-hostname",
+		lookEnvWithDefault("GOTESTSUM_JUNITFILE_HOSTNAME", ""),
+		"hostname attribute of each testsuite in the junit.xml file")
+	flags.Var(&opts.junitTimestamp, "junitfile-timestamp",
+		"RFC3339 timestamp of each testsuite in the junit.xml file, in place of the package start time")
+	flags.BoolVar(&opts.junitFlakyFailures, "junitfile-flaky-failures",
+		truthyFlag(lookEnvWithDefault("GOTESTSUM_JUNIT_FLAKY_FAILURES", "")),
+		"report failed attempts of tests which passed on rerun as <flakyFailure> of the passed test")
+	flags.Var(&opts.junitSuiteGranularity, "junitfile-testsuite-granularity",
+		"create a testsuite in the junit.xml file for each: "+junitSuiteGranularityValues)
+	flags.Var(&opts.junitSubtestNaming, "junitfile-subtest-name",
+		"format the name of subtests in the junit.xml file as: "+junitSubtestNamingValues)
+
+	flags.StringVar(&opts.xunitFile, "xunitfile",
+		lookEnvWithDefault("GOTESTSUM_XUNITFILE", ""),
+		"write an xUnit.net v2 XML file")
+	flags.StringVar(&opts.sonarFile, "sonarfile",
+		lookEnvWithDefault("GOTESTSUM_SONARFILE", ""),
+		"write a SonarQube generic test execution report")
+	flags.StringVar(&opts.circleCITimingsFile, "circleci-timings-file",
+		lookEnvWithDefault("GOTESTSUM_CIRCLECI_TIMINGS_FILE", ""),
+		"write a file with the time of each test, used by CircleCI to split tests by timings")
+	flags.StringVar(&opts.besJSONFile, "bes-json-file",
+		lookEnvWithDefault("GOTESTSUM_BES_JSON_FILE", ""),
+		"write the results as Bazel Build Event Protocol JSON events to this file")
+	flags.StringVar(&opts.historyFile, "history-file",
+		lookEnvWithDefault("GOTESTSUM_HISTORY_FILE", ""),
+		"append the result of every test to this file, to be queried by 'gotestsum tool history'")
+	flags.StringVar(&opts.allureResultsDir, "allure-results",
+		lookEnvWithDefault("GOTESTSUM_ALLURE_RESULTS", ""),
+		"write Allure result files to this directory")
 
 	flags.IntVar(&opts.rerunFailsMaxAttempts, "rerun-fails", 0,
 		"rerun failed tests until they all pass, or attempts exceeds maximum. Defaults to max 2 reruns when enabled")
@@ -131,10 +321,69 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 		"do not rerun any tests if the initial run has more than this number of failures")
 	flags.Var((*stringSlice)(&opts.packages), "packages",
 		"space separated list of package to test")
+	flags.StringVar(&opts.partitionBy, "partition-by", "package",
+		"split the --partition by package, or by test name to split large packages, one of: package, test")
+	flags.StringVar(&opts.onlyAffected, "only-affected", "",
+		"only test the packages with files that changed since this git ref, and the packages which import them")
+	flags.Lookup("only-affected").NoOptDefVal = defaultAffectedRef
+	flags.Var(&opts.partition, "partition",
+		"only test the packages in partition INDEX/TOTAL, using the package times from the --history-file to balance the partitions")
+	flags.BoolVar(&opts.pick, "pick", false,
+		"choose the tests to run from an interactive list of the tests in the packages")
+	flags.StringVar(&opts.runTestsFile, "run-tests-file", "",
+		"run only the tests listed in this file, one package and test name per line")
+	flags.StringVar(&opts.skipTestsFile, "skip-tests-file", "",
+		"skip the tests listed in this file, one package and test name per line")
+	flags.DurationVar(&opts.maxTotalTime, "max-total-time", 0,
+		"stop starting go test commands once the run has taken longer than this duration, and report the packages which were not tested")
+	flags.DurationVar(&opts.maxTotalTimeGrace, "max-total-time-grace", time.Minute,
+		"time to wait for running go test commands to finish after the --max-total-time, before they are stopped")
+	flags.BoolVar(&opts.perPackage, "per-package", false,
+		"run a separate go test command for each package, instead of one go test command for every package")
+	flags.IntVar(&opts.maxProcs, "max-procs", 0,
+		"number of --per-package go test commands to run at the same time, defaults to GOMAXPROCS")
+	flags.BoolVar(&opts.resume, "resume", false,
+		"record the packages which finish next to the --jsonfile, and when the run was interrupted only test the packages which did not finish")
+	flags.DurationVar(&opts.packageTimeout, "package-timeout", 0,
+		"send SIGQUIT to the test binary of a package which runs for longer than this duration, and continue with the other packages")
+	flags.DurationVar(&opts.rerunFailsMaxTime, "rerun-fails-max-time", 0,
+		"do not start another rerun once reruns have taken longer than this duration, 0 for no limit")
+	flags.IntVar(&opts.rerunFailsParallel, "rerun-fails-parallel", 1,
+		"number of packages to rerun at the same time, the output of each rerun is printed when it finishes")
+	flags.Var((*stringSlice)(&opts.rerunFailsExtraArgs), "rerun-fails-extra-args",
+		"space separated list of args added to the go test command of each rerun, for example -p=1")
+	flags.Var(&opts.rerunFailsIfOutput, "rerun-fails-if-output-matches",
+		"only rerun failed tests when the output of every failed test matches this regexp, may be repeated")
+	flags.Var(&opts.rerunFailsUnlessOutput, "rerun-fails-unless-output-matches",
+		"do not rerun failed tests when the output of any failed test matches this regexp, may be repeated")
+	flags.Var(opts.rerunFailsDelay, "rerun-fails-delay",
+		"wait before each rerun of failed tests, a duration or exponential:DURATION to double the delay after each attempt")
 	flags.StringVar(&opts.rerunFailsReportFile, "rerun-fails-report", "",
 		"write a report to the file, of the tests that were rerun")
+	flags.StringVar(&opts.rerunFailsReportFormat, "rerun-fails-report-format", "text",
+		"format of the --rerun-fails-report file, one of: text, json")
+	flags.StringVar(&opts.rerunFailsAttemptsDir, "rerun-fails-attempts-dir", "",
+		"write the jsonfile, output, and cover profile of each attempt to a directory named attempt-N in this directory")
 	flags.BoolVar(&opts.rerunFailsRunRootCases, "rerun-fails-run-root-test", false,
 		"rerun the entire root testcase when any of its subtests fail, instead of only the failed subtest")
+	flags.BoolVar(&opts.rerunFailsRunPackage, "rerun-fails-run-package", false,
+		"rerun all the tests in a package when any of its tests fail, instead of only the failed tests")
+	flags.Var(&opts.rerunFailsPkgErrors, "rerun-fails-package-errors",
+		"when a package fails to build or fails in TestMain: abort the rerun, skip the package, or retry[:N] the whole package N times")
+	flags.BoolVar(&opts.rerunFailsFailOnFlaky, "rerun-fails-fail-on-flaky", false,
+		fmt.Sprintf("exit with code %d when any test only passed when it was rerun", flakyExitCode))
+	flags.BoolVar(&opts.rerunFailsRace, "rerun-fails-race", false,
+		"add -race to the first rerun of failed tests, when the original run did not use -race")
+
+	flags.Var(opts.durationRegressionThreshold, "duration-regression-threshold",
+		"report tests which are slower than the median in --duration-regression-baseline, or the --history-file, by more than this percent")
+	flags.StringVar(&opts.durationRegressionBaseline, "duration-regression-baseline",
+		lookEnvWithDefault("GOTESTSUM_DURATION_REGRESSION_BASELINE", ""),
+		"glob pattern to match jsonfiles from previous runs used to compare test durations, instead of the --history-file")
+	flags.DurationVar(&opts.durationRegressionMinElapsed, "duration-regression-min-elapsed", 50*time.Millisecond,
+		"ignore duration regressions in tests with a median elapsed time less than this value")
+	flags.BoolVar(&opts.durationRegressionFail, "duration-regression-fail", false,
+		"exit with an error when any test exceeds --duration-regression-threshold")
 
 	flags.BoolVar(&opts.debug, "debug", false, "enabled debug logging")
 	flags.BoolVar(&opts.version, "version", false, "show version and exit")
@@ -156,26 +405,50 @@ Flags:
 Formats:
     dots                     print a character for each test
     dots-v2                  experimental dots format, one package per line
+    grid                     print a cell for each package, for very large runs
     pkgname                  print a line for each package
     pkgname-and-test-fails   print a line for each package and failed test output
     testname                 print a line for each test and package
+    failures-only            print only failed tests and packages, as they fail
+    stream                   print test output as it is received, prefixed with the test name
     testdox                  print a sentence for each test using gotestdox
     github-actions           testname format with github actions log grouping
     standard-quiet           standard go test format
     standard-verbose         standard go test -v format
+    tap                      TAP version 13, the summary is replaced by the plan
+    teamcity                 TeamCity service messages for each test
+    azure-pipelines          testname format with Azure Pipelines logging commands
+    gitlab                   testname format with a collapsible section for each package
+    exec:COMMAND             send the events as JSON messages to COMMAND, which prints them
 
 Format icons:
-    default                  the original unicode (✓, ∅, ✖)
-    hivis                    higher visibility unicode (✅, ➖, ❌)
+    default, unicode         the original unicode (✓, ∅, ✖)
+    hivis, emoji             higher visibility unicode (✅, ➖, ❌)
     text                     simple text characters (PASS, SKIP, FAIL)
-    codicons                 requires a font from https://www.nerdfonts.com/ (  )
+    ascii                    single ascii characters (+, -, x)
+    codicons, nerdfonts      requires a font from https://www.nerdfonts.com/ (  )
     octicons                 requires a font from https://www.nerdfonts.com/ (  )
     emoticons                requires a font from https://www.nerdfonts.com/ (󰇵 󰇶 󰇸)
+    PATH                     a JSON file with custom icons: {"pass": "✓", "skip": "-", "fail": "✖"}
 
 Commands:
+    %[1]s list           print the tests in each package as JSON
+    %[1]s exec-binary    run a test binary compiled with 'go test -c'
     %[1]s tool slowest   find or skip the slowest tests
     %[1]s help           print this help text
 `, name)
+	printExperiments(out)
+}
+
+func printExperiments(out io.Writer) {
+	experiments := experiment.All()
+	if len(experiments) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\nExperiments (enable with %v=name,name):\n", experiment.EnvVar)
+	for _, e := range experiments {
+		fmt.Fprintf(out, "    %-24s %v\n", e.Name, e.Description)
+	}
 }
 
 func lookEnvWithDefault(key, defValue string) string {
@@ -191,28 +464,110 @@ type options struct {
 	formatOptions                testjson.FormatOptions
 	debug                        bool
 	rawCommand                   bool
+	execBinary                   bool
+	inputFormat                  string
+	input                        inputValue
 	ignoreNonJSONOutputLines     bool
 	jsonFile                     string
 	jsonFileTimingEvents         string
 	junitFile                    string
 	postRunHookCmd               *commandValue
+	resultsExecCmd               *commandValue
+	onTestFailureExecCmd         *commandValue
+	notify                       bool
+	notifyWebhook                string
+	notifyWebhookFormat          string
+	notifyWebhookOnFailure       bool
+	otlpTraces                   bool
+	datadog                      bool
 	noColor                      bool
+	quiet                        bool
 	hideSummary                  *hideSummaryValue
+	foldFailureOutput            int
+	foldFailurePatterns          regexpSlice
+	foldFailureTail              int
+	summarySection               summarySectionValue
 	junitTestSuiteNameFormat     *junitFieldFormatValue
 	junitTestCaseClassnameFormat *junitFieldFormatValue
 	junitProjectName             string
 	junitHideEmptyPackages       bool
 	junitHideSkippedTests        bool
+	junitIncludeOutput           bool
+	junitMaxSystemOutBytes       int
+	junitMaxOutputBytes          int
+	junitMaxTotalOutputBytes     int
+	junitProperties              junitPropertiesValue
+	junitHostname                string
+	junitTimestamp               timestampValue
+	junitFlakyFailures           bool
+	allureResultsDir             string
+	xunitFile                    string
+	sonarFile                    string
+	circleCITimingsFile          string
+	besJSONFile                  string
+	historyFile                  string
+	junitSuiteGranularity        junitSuiteGranularityValue
+	junitSubtestNaming           junitSubtestNamingValue
 	rerunFailsMaxAttempts        int
 	rerunFailsMaxInitialFailures int
+	rerunFailsMaxTime            time.Duration
+	rerunFailsDelay              *rerunDelayValue
+	rerunFailsIfOutput           regexpSlice
+	rerunFailsUnlessOutput       regexpSlice
 	rerunFailsReportFile         string
+	rerunFailsReportFormat       string
+	rerunFailsAttemptsDir        string
 	rerunFailsRunRootCases       bool
+	rerunFailsRunPackage         bool
+	rerunFailsExtraArgs          []string
+	rerunFailsParallel           int
 	rerunFailsAbortOnDataRace    bool
+	rerunFailsPkgErrors          packageErrorsValue
+	rerunFailsFailOnFlaky        bool
+	rerunFailsRace               bool
+	durationRegressionThreshold  *percentValue
+	durationRegressionBaseline   string
+	durationRegressionMinElapsed time.Duration
+	durationRegressionFail       bool
 	packages                     []string
+	partition                    partitionValue
+	partitionBy                  string
+	onlyAffected                 string
+	pick                         bool
+	runTestsFile                 string
+	skipTestsFile                string
+	packageTimeout               time.Duration
+	perPackage                   bool
+	maxProcs                     int
+	maxTotalTime                 time.Duration
+	maxTotalTimeGrace            time.Duration
+	resume                       bool
+	jsonFileAppend               bool
 	watch                        bool
 	watchClear                   bool
 	watchChdir                   bool
+	watchAffected                bool
+	watchIgnore                  []string
+	watchInclude                 []string
+	watchStatusLine              bool
+	watchFailureDiff             bool
+	watchPoll                    time.Duration
+	watchDebounce                time.Duration
+	watchPreRunCmd               *commandValue
+	watchDebugCmd                *commandValue
+	sandboxTmpDir                bool
+	sandboxPackages              bool
+	packageSandboxes             *packageSandboxes
 	maxFails                     int
+	maxTestOutputBytes           int
+	partialReportInterval        time.Duration
+	quarantineFile               string
+	quarantine                   *quarantine.List
+	resultsExec                  *resultsexec.Process
+	formatExec                   *formatexec.Process
+	failureHooks                 *failureHooks
+	execution                    *testjson.Execution
+	closeJSONFiles               func()
 	version                      bool
 
 	// shims for testing
@@ -221,7 +576,8 @@ type options struct {
 }
 
 func (o options) Validate() error {
-	if o.rerunFailsMaxAttempts > 0 && len(o.args) > 0 && !o.rawCommand && len(o.packages) == 0 {
+	if o.rerunFailsMaxAttempts > 0 && len(o.args) > 0 && !o.rawCommand && len(o.packages) == 0 &&
+		o.runTestsFile == "" {
 		return fmt.Errorf(
 			"when go test args are used with --rerun-fails " +
 				"the list of packages to test must be specified by the --packages flag")
@@ -232,6 +588,133 @@ func (o options) Validate() error {
 		return fmt.Errorf("-(test.)failfast can not be used with --rerun-fails " +
 			"because not all test cases will run")
 	}
+	if len(o.rerunFailsExtraArgs) > 0 && o.rawCommand {
+		return fmt.Errorf("--rerun-fails-extra-args can not be used with --raw-command")
+	}
+	if len(o.input) > 0 {
+		switch {
+		case len(o.args) > 0 || o.rawCommand:
+			return fmt.Errorf("--input can not be used with a go test command")
+		case o.rerunFailsMaxAttempts > 0:
+			return fmt.Errorf("--rerun-fails can not be used with --input")
+		case o.watch:
+			return fmt.Errorf("--watch can not be used with --input")
+		case o.inputFormat == "text":
+			return fmt.Errorf("--input-format=text can not be used with --input")
+		}
+	}
+	switch o.inputFormat {
+	case "", "json":
+	case "text":
+		if !o.rawCommand {
+			return fmt.Errorf("--input-format=text requires --raw-command")
+		}
+	default:
+		return fmt.Errorf("--input-format must be one of: json, text")
+	}
+	if o.watchAffected && o.watchChdir {
+		return fmt.Errorf("--watch-affected can not be used with --watch-chdir")
+	}
+	if err := validateGlobs("--watch-ignore", o.watchIgnore); err != nil {
+		return err
+	}
+	if err := validateGlobs("--watch-include", o.watchInclude); err != nil {
+		return err
+	}
+	switch o.rerunFailsReportFormat {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("--rerun-fails-report-format must be one of: text, json")
+	}
+	switch webhook.Format(o.notifyWebhookFormat) {
+	case "", webhook.FormatJSON, webhook.FormatSlack:
+	default:
+		return fmt.Errorf("--notify-webhook-format must be one of: json, slack")
+	}
+	switch o.partitionBy {
+	case "", "package":
+	case "test":
+		if o.partition.total == 0 {
+			return fmt.Errorf("--partition-by=test requires --partition")
+		}
+		if start, _ := argIndex("run", o.args); start >= 0 {
+			return fmt.Errorf("-run can not be used with --partition-by=test")
+		}
+	default:
+		return fmt.Errorf("--partition-by must be one of: package, test")
+	}
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{name: "--only-affected", set: o.onlyAffected != ""},
+		{name: "--partition", set: o.partition.total > 0},
+		{name: "--pick", set: o.pick},
+		{name: "--skip-tests-file", set: o.skipTestsFile != "" && o.runTestsFile == ""},
+		{name: "--per-package", set: o.perPackage && o.runTestsFile == ""},
+		{name: "--resume", set: o.resume && o.runTestsFile == ""},
+	} {
+		switch {
+		case !flag.set:
+		case o.rawCommand:
+			return fmt.Errorf("%v can not be used with --raw-command", flag.name)
+		case len(o.input) > 0:
+			return fmt.Errorf("%v can not be used with --input", flag.name)
+		case o.watch:
+			return fmt.Errorf("%v can not be used with --watch", flag.name)
+		case len(o.args) > 0 && len(o.packages) == 0:
+			return fmt.Errorf("when go test args are used with %v "+
+				"the list of packages to test must be specified by the --packages flag", flag.name)
+		}
+	}
+	if o.pick {
+		if o.partition.total > 0 {
+			return fmt.Errorf("--pick can not be used with --partition")
+		}
+		if start, _ := argIndex("run", o.args); start >= 0 {
+			return fmt.Errorf("-run can not be used with --pick")
+		}
+	}
+	if o.runTestsFile != "" {
+		if err := validateRunTestsFile(o); err != nil {
+			return err
+		}
+	}
+	if o.skipTestsFile != "" {
+		if err := validateSkipTestsFile(o); err != nil {
+			return err
+		}
+	}
+	switch {
+	case o.resume && o.jsonFile == "":
+		return fmt.Errorf("--resume requires --jsonfile")
+	case o.partialReportInterval < 0:
+		return fmt.Errorf("--partial-report-interval must not be negative")
+	case o.maxTestOutputBytes < 0:
+		return fmt.Errorf("--max-test-output-bytes must not be negative")
+	case o.maxProcs < 0:
+		return fmt.Errorf("--max-procs must not be negative")
+	case o.maxProcs > 0 && !o.perPackage:
+		return fmt.Errorf("--max-procs requires --per-package")
+	case o.sandboxPackages && !o.perPackage:
+		return fmt.Errorf("--sandbox-packages requires --per-package")
+	case o.maxTotalTime < 0:
+		return fmt.Errorf("--max-total-time must not be negative")
+	case o.maxTotalTimeGrace < 0:
+		return fmt.Errorf("--max-total-time-grace must not be negative")
+	case o.maxTotalTime > 0 && o.watch:
+		return fmt.Errorf("--max-total-time can not be used with --watch")
+	case o.packageTimeout < 0:
+		return fmt.Errorf("--package-timeout must not be negative")
+	case o.packageTimeout > 0 && o.watch:
+		return fmt.Errorf("--package-timeout can not be used with --watch")
+	}
+	if start, _ := argIndex("exec", o.args); start >= 0 && o.sandboxPackages {
+		return fmt.Errorf("-exec can not be used with --sandbox-packages")
+	}
+	if o.rerunFailsRunPackage && o.rerunFailsRunRootCases {
+		return fmt.Errorf("--rerun-fails-run-package can not be used with --rerun-fails-run-root-test")
+	}
 	return nil
 }
 
@@ -269,22 +752,132 @@ func defaultNoColor() bool {
 	return color.NoColor
 }
 
+// setupOptions sets the options which depend on the value of other flags.
+func setupOptions(opts *options) {
+	if opts.quiet {
+		opts.format = "failures-only"
+	}
+	if opts.format == "gitlab" {
+		opts.junitFile = gitLabJUnitFile(opts.junitFile, os.Getenv("CI_PROJECT_DIR"))
+	}
+	setupLogging(opts)
+}
+
 func setupLogging(opts *options) {
 	if opts.debug {
 		log.SetLevel(log.DebugLevel)
 	}
-	color.NoColor = opts.noColor
+	color.NoColor = opts.noColor || opts.formatOptions.Accessible
 }
 
 func run(opts *options) error {
-	ctx, cancel := context.WithCancel(context.Background())
+	return runContext(context.Background(), opts)
+}
+
+func runContext(ctx context.Context, opts *options) error {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	if err := opts.Validate(); err != nil {
 		return err
 	}
+	budget := newRunBudget(opts, cancel)
+	defer budget.Stop()
+	if opts.maxFails > 0 || opts.maxTotalTime > 0 {
+		ctx = withProcessGroup(ctx)
+	}
+
+	if opts.onlyAffected != "" {
+		selection, err := onlyAffectedPackages(opts)
+		if err != nil {
+			return err
+		}
+		printSkippedPackages(opts, selection)
+		if len(selection.packages) == 0 {
+			return nil
+		}
+		opts.packages = selection.packages
+	}
+	if opts.partition.total > 0 {
+		ok, err := applyPartition(opts)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	if opts.pick {
+		ok, err := applyPick(opts)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	testGroups, err := selectTestGroups(opts)
+	if err != nil {
+		return err
+	}
+	if opts.perPackage {
+		if testGroups, err = perPackageGroups(opts, testGroups); err != nil {
+			return err
+		}
+	}
+
+	resume, err := newResume(opts)
+	if err != nil {
+		return err
+	}
+	defer resume.Close()
+	testGroups, ok, err := resume.SkipFinished(opts, testGroups)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		resume.Done()
+		return finishRun(opts, resume.Execution(), resume.ExitErr(resume.Execution(), nil))
+	}
 
-	goTestProc, err := startGoTestFn(ctx, "", goTestCmdArgs(opts, rerunOpts{}))
+	list, err := quarantine.Load(opts.quarantineFile)
+	if err != nil {
+		return err
+	}
+	opts.quarantine = list
+
+	sandbox, err := newTmpDirSandbox(opts)
+	if err != nil {
+		return err
+	}
+	defer sandbox.Close()
+	opts.packageSandboxes, err = newPackageSandboxes(opts)
+	if err != nil {
+		return err
+	}
+	defer opts.packageSandboxes.Close()
+
+	artifacts, err := newAttemptArtifacts(opts, 1)
+	if err != nil {
+		return err
+	}
+	defer artifacts.Close()
+
+	groups := &goTestGroups{groups: testGroups, parallel: maxProcs(opts), deadline: budget.Deadline()}
+	timeouts := newPackageTimeout(opts, groups.Pid)
+	defer timeouts.Close()
+	defer printTimedOutPackages(opts, timeouts)
+
+	if len(testGroups) > 0 && len(opts.input) == 0 {
+		groups.coverProfiles, err = newTempCoverProfiles(coverprofile.ArgValue(opts.args), "group")
+		if err != nil {
+			return err
+		}
+		defer groups.coverProfiles.Close()
+	}
+	var goTestProc *proc
+	switch {
+	case len(opts.input) > 0:
+		goTestProc = startInputs(ctx, opts.input)
+	case len(testGroups) > 0:
+		goTestProc = groups.Start(ctx, opts, sandbox.Env()...)
+	default:
+		goTestProc, err = startGoTestFn(ctx, "", goTestCmdArgs(opts, rerunOpts{}), sandbox.Env()...)
+	}
 	if err != nil {
 		return err
 	}
@@ -294,31 +887,58 @@ func run(opts *options) error {
 		return err
 	}
 	defer handler.Close() //nolint:errcheck
+	interrupted := func() bool {
+		return ctx.Err() != nil || atomic.LoadInt32(&goTestProc.signal) != 0
+	}
 	cfg := testjson.ScanConfig{
-		Stdout:                   goTestProc.stdout,
-		Stderr:                   goTestProc.stderr,
-		Handler:                  handler,
-		Stop:                     cancel,
-		IgnoreNonJSONOutputLines: opts.ignoreNonJSONOutputLines,
+		Stdout: goTestStdout(opts, goTestProc.stdout),
+		Stderr: goTestProc.stderr,
+		Handler: timeouts.Handler(artifacts.Handler(
+			resume.Handler(partialReportsHandler(opts, handler), handler.Flush, interrupted))),
+		Stop:                        cancel,
+		Execution:                   resume.Execution(),
+		IgnoreNonJSONOutputLines:    opts.ignoreNonJSONOutputLines,
+		AttributeNonJSONOutputLines: true,
+		KeepPassedOutput:            keepPassedOutput(opts),
+		MaxTestOutputBytes:          opts.maxTestOutputBytes,
+		CollapseRepeatedOutput:      opts.formatOptions.CollapseRepeatedOutput,
+	}
+	if opts.rawCommand {
+		// the output of a raw command may be a large stream of events
+		cfg.ParseWorkers = runtime.GOMAXPROCS(0)
 	}
 	exec, err := testjson.ScanTestOutput(cfg)
 	handler.Flush()
+	handler.closeFormatter()
+	if errors.Is(err, errMaxFailsReached) {
+		warnUnfinishedPackages(exec)
+	}
 	if err != nil {
 		return finishRun(opts, exec, err)
 	}
 
-	exitErr := goTestProc.cmd.Wait()
+	exitErr := opts.input.ExitErr(exec, goTestProc.cmd.Wait())
+	artifacts.MergeCoverProfile(coverprofile.ArgValue(opts.args))
 	if signum := atomic.LoadInt32(&goTestProc.signal); signum != 0 {
 		return finishRun(opts, exec, exitError{num: signalExitCode + int(signum)})
 	}
+	if err := budget.exceededError(opts, exec, groups.NotStarted()); err != nil {
+		return finishRun(opts, exec, err)
+	}
+	resume.Done()
+	exitErr = resume.ExitErr(exec, exitErr)
 	if exitErr == nil || opts.rerunFailsMaxAttempts == 0 {
 		return finishRun(opts, exec, exitErr)
 	}
-	if err := hasErrors(exitErr, exec, opts); err != nil {
+	if err := hasErrors(exitErr, exec, opts, packageErrors(exec)); err != nil {
 		return finishRun(opts, exec, err)
 	}
 
 	failed := len(rerunFailsFilter(opts)(exec.Failed()))
+	if failed == 0 && !hasPackageErrorPolicy(opts, exec) {
+		// all the failures are quarantined
+		return finishRun(opts, exec, exitErr)
+	}
 	if failed > opts.rerunFailsMaxInitialFailures {
 		err := fmt.Errorf(
 			"number of test failures (%d) exceeds maximum (%d) set by --rerun-fails-max-failures",
@@ -327,30 +947,138 @@ func run(opts *options) error {
 	}
 
 	cfg = testjson.ScanConfig{Execution: exec, Handler: handler}
-	exitErr = rerunFailed(ctx, opts, cfg)
+	exitErr = rerunFailed(ctx, opts, cfg, sandbox.Env()...)
 	handler.Flush()
+	handler.closeFormatter()
 	if err := writeRerunFailsReport(opts, exec); err != nil {
 		return err
 	}
 	return finishRun(opts, exec, exitErr)
 }
 
+// keepPassedOutput returns true when the output of tests which passed is
+// written to a report.
+func keepPassedOutput(opts *options) bool {
+	return opts.junitIncludeOutput || opts.allureResultsDir != "" || opts.xunitFile != ""
+}
+
+// goTestStdout returns the test2json events read from the stdout of the go
+// test command.
+func goTestStdout(opts *options, stdout io.Reader) io.Reader {
+	if opts.inputFormat == "text" {
+		return verbose.NewReader(stdout)
+	}
+	return stdout
+}
+
 func finishRun(opts *options, exec *testjson.Execution, exitErr error) error {
-	testjson.PrintSummary(opts.stdout, exec, opts.hideSummary.value)
+	opts.execution = exec
+	exitErr = quarantineExitErr(opts, exec, exitErr)
+	opts.failureHooks.Wait()
+	endFormatExec(opts, exec, exitErr)
+
+	switch {
+	case opts.format == "tap":
+		// the summary is replaced by the plan, so that stdout is a valid TAP stream
+		if err := testjson.PrintTAPPlan(opts.stdout, exec); err != nil {
+			return err
+		}
+	case !opts.quiet || !runPassed(exec, exitErr):
+		testjson.PrintSummaryWithConfig(opts.stdout, exec, testjson.SummaryConfig{
+			Sections:      opts.hideSummary.value,
+			FoldFailures:  opts.foldConfig(),
+			MaxLineLength: opts.formatOptions.MaxLineLength,
+			Quarantined:   opts.quarantine.Contains,
+			Extra:         opts.summarySection.sections(opts, exitErr),
+		})
+	}
+	if err := checkDurationRegressions(opts, exec); err != nil && exitErr == nil {
+		exitErr = err
+	}
+	if err := checkFlakyTests(opts, exec); err != nil && exitErr == nil {
+		exitErr = err
+	}
 
 	if err := writeJUnitFile(opts, exec); err != nil {
 		return fmt.Errorf("failed to write junit file: %w", err)
 	}
-	if err := postRunHook(opts, exec); err != nil {
+	if err := publishAzurePipelinesResults(opts); err != nil {
+		return err
+	}
+	if err := writeXUnitFile(opts, exec); err != nil {
+		return fmt.Errorf("failed to write xunit file: %w", err)
+	}
+	if err := writeSonarFile(opts, exec); err != nil {
+		return fmt.Errorf("failed to write sonar file: %w", err)
+	}
+	if err := writeCircleCITimingsFile(opts, exec); err != nil {
+		return fmt.Errorf("failed to write CircleCI timings file: %w", err)
+	}
+	if err := writeBESFile(opts, exec, exitErr); err != nil {
+		return fmt.Errorf("failed to write build event file: %w", err)
+	}
+	if err := appendHistory(opts, exec); err != nil {
+		return fmt.Errorf("failed to append to history file: %w", err)
+	}
+	if err := writeAllureResults(opts, exec); err != nil {
+		return fmt.Errorf("failed to write allure results: %w", err)
+	}
+	if opts.closeJSONFiles != nil {
+		opts.closeJSONFiles()
+	}
+	if err := postRunHook(opts, exec, exitErr); err != nil {
 		return fmt.Errorf("post run command failed: %w", err)
 	}
+	sendNotification(opts, exec)
+	postWebhook(opts, exec, exitErr)
+	exportTrace(opts, exec)
+	sendToDatadog(opts, exec)
+	endResultsExec(opts, exec, exitErr)
 	return exitErr
 }
 
+// warnUnfinishedPackages prints the packages which started, but did not finish,
+// because the run was stopped by --max-fails. Packages which had not started
+// are not known, so they can not be listed.
+func warnUnfinishedPackages(exec *testjson.Execution) {
+	var names []string
+	for _, name := range exec.Packages() {
+		if exec.Package(name).Result() == "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		log.Warnf("max failures reached, no more packages were tested")
+		return
+	}
+	log.Warnf("max failures reached, %d packages did not finish, and no more packages were tested:\n    %v",
+		len(names), strings.Join(names, "\n    "))
+}
+
+// runPassed returns true if the run had no failed tests, no errors, and go test
+// exited successfully.
+func runPassed(exec *testjson.Execution, exitErr error) bool {
+	return exitErr == nil && len(exec.Failed()) == 0 && len(exec.Errors()) == 0
+}
+
+func (o options) foldConfig() testjson.FoldConfig {
+	patterns := append([]*regexp.Regexp{}, o.foldFailurePatterns...)
+	return testjson.FoldConfig{
+		MinLines:  o.foldFailureOutput,
+		Patterns:  append(patterns, testjson.DefaultFoldPatterns...),
+		TailLines: o.foldFailureTail,
+	}
+}
+
 func goTestCmdArgs(opts *options, rerunOpts rerunOpts) []string {
 	if opts.rawCommand {
 		var result []string
 		result = append(result, opts.args...)
+		if opts.execBinary {
+			// a test binary only has the tests of one package, and does not
+			// accept a package as an argument
+			rerunOpts.pkg = ""
+		}
 		result = append(result, rerunOpts.Args()...)
 		return result
 	}
@@ -363,6 +1091,10 @@ func goTestCmdArgs(opts *options, rerunOpts rerunOpts) []string {
 		if rerunOpts.runFlag != "" {
 			result = append(result, rerunOpts.runFlag)
 		}
+		if rerunOpts.race {
+			result = append(result, "-race")
+		}
+		result = append(result, rerunOpts.extraArgs...)
 		return append(result, cmdArgPackageList(opts, rerunOpts, "./...")...)
 	}
 
@@ -380,6 +1112,10 @@ func goTestCmdArgs(opts *options, rerunOpts rerunOpts) []string {
 		result = append(result, rerunOpts.runFlag)
 	}
 
+	if rerunOpts.race {
+		result = append(result, "-race")
+	}
+
 	if rerunOpts.coverProfileArg != "" {
 		// Redirect -coverprofile to a temp file so reruns don't overwrite
 		// the original coverage data. The caller merges the results.
@@ -393,6 +1129,7 @@ func goTestCmdArgs(opts *options, rerunOpts rerunOpts) []string {
 
 	pkgArgIndex := findPkgArgPosition(args)
 	result = append(result, args[:pkgArgIndex]...)
+	result = append(result, rerunOpts.extraArgs...)
 	result = append(result, cmdArgPackageList(opts, rerunOpts)...)
 	result = append(result, args[pkgArgIndex:]...)
 	return result
@@ -449,6 +1186,8 @@ type proc struct {
 	cmd    waiter
 	stdout io.Reader
 	stderr io.Reader
+	// pid of the command, or 0 when the proc is not a single process.
+	pid int
 	// signal is atomically set to the signal value when a signal is received
 	// by newSignalHandler.
 	signal int32
@@ -458,7 +1197,9 @@ type waiter interface {
 	Wait() error
 }
 
-func startGoTest(ctx context.Context, dir string, args []string) (*proc, error) {
+// startGoTest starts the command in args in the directory dir. If env is not
+// empty the values are added to the environment of the current process.
+func startGoTest(ctx context.Context, dir string, args []string, env ...string) (*proc, error) {
 	if len(args) == 0 {
 		return nil, errors.New("missing command to run")
 	}
@@ -466,6 +1207,18 @@ func startGoTest(ctx context.Context, dir string, args []string) (*proc, error)
 	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
 	cmd.Stdin = os.Stdin
 	cmd.Dir = dir
+	group := usesProcessGroup(ctx)
+	if group {
+		setProcessGroup(cmd)
+		// a process group which is not in the foreground is stopped when it
+		// reads from the terminal.
+		if isTerminal(os.Stdin) {
+			cmd.Stdin = nil
+		}
+	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	p := proc{cmd: cmd}
 	log.Debugf("exec: %s", cmd.Args)
@@ -482,9 +1235,12 @@ func startGoTest(ctx context.Context, dir string, args []string) (*proc, error)
 		return nil, fmt.Errorf("failed to run %s: %w", strings.Join(cmd.Args, " "), err)
 	}
 	log.Debugf("go test pid: %d", cmd.Process.Pid)
+	p.pid = cmd.Process.Pid
 
 	ctx, cancel := context.WithCancel(ctx)
-	newSignalHandler(ctx, cmd.Process.Pid, &p)
+	if usesSignalHandler(ctx) {
+		newSignalHandler(ctx, cmd.Process.Pid, group, &p)
+	}
 	p.cmd = &cancelWaiter{cancel: cancel, wrapped: p.cmd}
 	return &p, nil
 }
@@ -528,7 +1284,43 @@ func (e exitError) ExitCode() int {
 // exit code value. This matches the behaviour of bash.
 const signalExitCode = 128
 
-func newSignalHandler(ctx context.Context, pid int, p *proc) {
+// processGroupKey is the context key set by withProcessGroup.
+type processGroupKey struct{}
+
+// withProcessGroup returns a context which starts go test in its own process
+// group, so that the test binaries are stopped along with go test when ctx is
+// cancelled. It is only used when the run may be stopped before go test exits,
+// because a process group which is not in the foreground of the terminal does
+// not receive Ctrl-Z or SIGHUP from the terminal.
+func withProcessGroup(ctx context.Context) context.Context {
+	return context.WithValue(ctx, processGroupKey{}, true)
+}
+
+func usesProcessGroup(ctx context.Context) bool {
+	group, _ := ctx.Value(processGroupKey{}).(bool)
+	return group
+}
+
+// signalHandlerKey is the context key set by withoutSignalHandler.
+type signalHandlerKey struct{}
+
+// withoutSignalHandler returns a context which starts go test without
+// handling os.Interrupt, for callers which stop the run by cancelling ctx.
+func withoutSignalHandler(ctx context.Context) context.Context {
+	return context.WithValue(ctx, signalHandlerKey{}, false)
+}
+
+func usesSignalHandler(ctx context.Context) bool {
+	handle, ok := ctx.Value(signalHandlerKey{}).(bool)
+	return !ok || handle
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func newSignalHandler(ctx context.Context, pid int, group bool, p *proc) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 
@@ -541,12 +1333,11 @@ func newSignalHandler(ctx context.Context, pid int, p *proc) {
 		case s := <-c:
 			atomic.StoreInt32(&p.signal, int32(s.(syscall.Signal)))
 
-			proc, err := os.FindProcess(pid)
-			if err != nil {
-				log.Errorf("failed to find pid of 'go test': %v", err)
-				return
+			signal := signalProcess
+			if group {
+				signal = signalProcessGroup
 			}
-			if err := proc.Signal(s); err != nil {
+			if err := signal(pid, s); err != nil {
 				log.Errorf("failed to interrupt 'go test': %v", err)
 				return
 			}
@@ -554,6 +1345,14 @@ func newSignalHandler(ctx context.Context, pid int, p *proc) {
 	}()
 }
 
+func signalProcess(pid int, sig os.Signal) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return proc.Signal(sig)
+}
+
 // cancelWaiter wraps a waiter to cancel the context after the wrapped
 // Wait exits.
 type cancelWaiter struct {
@@ -3,6 +3,7 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -15,6 +16,7 @@ import (
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/assert/cmp"
 	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
 	"gotest.tools/v3/golden"
 	"gotest.tools/v3/skip"
 )
@@ -93,6 +95,139 @@ func TestOptions_Validate_FromFlags(t *testing.T) {
 			args:     []string{"--rerun-fails", "--packages=./...", "--", "-test.failfast"},
 			expected: "-(test.)failfast can not be used with --rerun-fails",
 		},
+		{
+			name:     "rerun-fails-extra-args with raw-command",
+			args:     []string{"--rerun-fails", "--rerun-fails-extra-args=-p=1", "--raw-command", "--", "./test.sh"},
+			expected: "--rerun-fails-extra-args can not be used with --raw-command",
+		},
+		{
+			name:     "input-format text without raw-command",
+			args:     []string{"--input-format=text"},
+			expected: "--input-format=text requires --raw-command",
+		},
+		{
+			name:     "input with go test args",
+			args:     []string{"--input=out.json", "--", "-count=1"},
+			expected: "--input can not be used with a go test command",
+		},
+		{
+			name:     "input with rerun-fails",
+			args:     []string{"--input=out.json", "--rerun-fails"},
+			expected: "--rerun-fails can not be used with --input",
+		},
+		{
+			name:     "input with partition",
+			args:     []string{"--input=out.json", "--partition=1/2"},
+			expected: "--partition can not be used with --input",
+		},
+		{
+			name:     "input-format unknown",
+			args:     []string{"--input-format=xml", "--raw-command", "--", "./test.sh"},
+			expected: "--input-format must be one of: json, text",
+		},
+		{
+			name:     "rerun-fails-run-package with rerun-fails-run-root-test",
+			args:     []string{"--rerun-fails", "--rerun-fails-run-package", "--rerun-fails-run-root-test"},
+			expected: "--rerun-fails-run-package can not be used with --rerun-fails-run-root-test",
+		},
+		{
+			name:     "partition, go-test args, no packages flag",
+			args:     []string{"--partition=1/2", "--", "-race", "./..."},
+			expected: "the list of packages to test must be specified by the --packages flag",
+		},
+		{
+			name:     "partition with raw-command",
+			args:     []string{"--partition=1/2", "--raw-command", "--", "./test.sh"},
+			expected: "--partition can not be used with --raw-command",
+		},
+		{
+			name:     "only-affected with watch",
+			args:     []string{"--only-affected", "--watch"},
+			expected: "--only-affected can not be used with --watch",
+		},
+		{
+			name:     "partition-by test with run flag",
+			args:     []string{"--partition=1/2", "--partition-by=test", "--packages=./...", "--", "-run=TestA"},
+			expected: "-run can not be used with --partition-by=test",
+		},
+		{
+			name:     "partition-by test without partition",
+			args:     []string{"--partition-by=test"},
+			expected: "--partition-by=test requires --partition",
+		},
+		{
+			name:     "pick with run flag",
+			args:     []string{"--pick", "--packages=./...", "--", "-run=TestA"},
+			expected: "-run can not be used with --pick",
+		},
+		{
+			name:     "pick with partition",
+			args:     []string{"--pick", "--partition=1/2"},
+			expected: "--pick can not be used with --partition",
+		},
+		{
+			name:     "pick with watch",
+			args:     []string{"--pick", "--watch"},
+			expected: "--pick can not be used with --watch",
+		},
+		{
+			name:     "run-tests-file with packages",
+			args:     []string{"--run-tests-file=tests.txt", "--packages=./..."},
+			expected: "--run-tests-file can not be used with --packages",
+		},
+		{
+			name:     "run-tests-file with run flag",
+			args:     []string{"--run-tests-file=tests.txt", "--", "-run=TestA"},
+			expected: "-run can not be used with --run-tests-file",
+		},
+		{
+			name: "run-tests-file, rerun-fails, go-test args",
+			args: []string{"--run-tests-file=tests.txt", "--rerun-fails", "--", "-race"},
+		},
+		{
+			name:     "skip-tests-file with skip flag",
+			args:     []string{"--skip-tests-file=skip.txt", "--packages=./...", "--", "-skip=TestA"},
+			expected: "-skip can not be used with --skip-tests-file",
+		},
+		{
+			name:     "skip-tests-file, go-test args, no packages flag",
+			args:     []string{"--skip-tests-file=skip.txt", "--", "-race", "./..."},
+			expected: "the list of packages to test must be specified by the --packages flag",
+		},
+		{
+			name:     "package-timeout with watch",
+			args:     []string{"--package-timeout=1m", "--watch"},
+			expected: "--package-timeout can not be used with --watch",
+		},
+		{
+			name:     "max-procs without per-package",
+			args:     []string{"--max-procs=2"},
+			expected: "--max-procs requires --per-package",
+		},
+		{
+			name:     "per-package with raw-command",
+			args:     []string{"--per-package", "--raw-command", "--", "./test.sh"},
+			expected: "--per-package can not be used with --raw-command",
+		},
+		{
+			name:     "max-total-time with watch",
+			args:     []string{"--max-total-time=10m", "--watch"},
+			expected: "--max-total-time can not be used with --watch",
+		},
+		{
+			name:     "resume without jsonfile",
+			args:     []string{"--resume"},
+			expected: "--resume requires --jsonfile",
+		},
+		{
+			name:     "resume with raw-command",
+			args:     []string{"--resume", "--jsonfile=out.json", "--raw-command", "--", "./test.sh"},
+			expected: "--resume can not be used with --raw-command",
+		},
+		{
+			name: "partition, go-test args, with packages flag",
+			args: []string{"--partition=1/2", "--packages=./...", "--", "-race"},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -178,6 +313,40 @@ func TestGoTestCmdArgs(t *testing.T) {
 		},
 		expected: []string{"go", "test", "-json", "-run=TestOne|TestTwo", "./fails"},
 	})
+	run(t, "with args, with race rerunOpts", testCase{
+		opts: &options{
+			args: []string{"-tags=integration"},
+		},
+		rerunOpts: rerunOpts{
+			runFlag: "-run=TestOne",
+			pkg:     "./fails",
+			race:    true,
+		},
+		expected: []string{"go", "test", "-json", "-run=TestOne", "-race", "-tags=integration", "./fails"},
+	})
+	run(t, "no args, with extra args rerunOpts", testCase{
+		opts: &options{},
+		rerunOpts: rerunOpts{
+			runFlag:   "-run=TestOne",
+			pkg:       "./fails",
+			extraArgs: []string{"-count=1", "-p=1"},
+		},
+		expected: []string{"go", "test", "-json", "-run=TestOne", "-count=1", "-p=1", "./fails"},
+	})
+	run(t, "with args, with extra args rerunOpts", testCase{
+		opts: &options{
+			args: []string{"-timeout=2m", "-args", "-flag"},
+		},
+		rerunOpts: rerunOpts{
+			runFlag:   "-run=TestOne",
+			pkg:       "./fails",
+			extraArgs: []string{"-timeout=10m", "-v"},
+		},
+		expected: []string{
+			"go", "test", "-json", "-run=TestOne", "-timeout=2m", "-timeout=10m", "-v",
+			"./fails", "-args", "-flag",
+		},
+	})
 	run(t, "TEST_DIRECTORY env var, no args, with rerunOpts", testCase{
 		opts: &options{},
 		rerunOpts: rerunOpts{
@@ -602,3 +771,124 @@ func TestRun_JsonFileTimingEvents(t *testing.T) {
 	assert.NilError(t, err)
 	golden.Assert(t, string(raw), "expected-jsonfile-timing-events")
 }
+
+func TestRun_Quiet(t *testing.T) {
+	events := func(result testjson.Action) string {
+		buf := new(bytes.Buffer)
+		e := json.NewEncoder(buf)
+		for _, event := range []testjson.TestEvent{
+			{Action: "run", Package: "pkg", Test: "TestOne"},
+			{Action: "output", Package: "pkg", Test: "TestOne", Output: "    one_test.go:10: failed\n"},
+			{Action: result, Package: "pkg", Test: "TestOne"},
+			{Action: result, Package: "pkg"},
+		} {
+			assert.NilError(t, e.Encode(event))
+		}
+		return buf.String()
+	}
+
+	runQuiet := func(t *testing.T, input string) string {
+		t.Helper()
+		fh := fs.NewFile(t, "input", fs.WithContent(input))
+		stdout := new(bytes.Buffer)
+		_ = run(&options{
+			args:        []string{"cat", fh.Path()},
+			format:      "failures-only",
+			quiet:       true,
+			hideSummary: newHideSummaryValue(),
+			rawCommand:  true,
+
+			stdout: stdout,
+			stderr: io.Discard,
+		})
+		return stdout.String()
+	}
+
+	t.Run("pass", func(t *testing.T) {
+		assert.Equal(t, runQuiet(t, events(testjson.ActionPass)), "")
+	})
+	t.Run("fail", func(t *testing.T) {
+		out := runQuiet(t, events(testjson.ActionFail))
+		assert.Assert(t, cmp.Contains(out, "one_test.go:10: failed"))
+		assert.Assert(t, cmp.Contains(out, "DONE 1 tests, 1 failure"))
+	})
+}
+
+func TestRun_InputFormatText(t *testing.T) {
+	outputs := []string{`=== RUN   TestOne
+--- PASS: TestOne (0.00s)
+=== RUN   TestTwo
+    two_test.go:5: not this time
+--- FAIL: TestTwo (0.01s)
+FAIL
+FAIL	example.com/pkg	0.012s
+`, `=== RUN   TestTwo
+--- PASS: TestTwo (0.01s)
+PASS
+ok  	example.com/pkg	0.011s
+`}
+	var runs int
+	fn := func([]string) *proc {
+		out := outputs[runs]
+		runs++
+		var result error
+		if runs == 1 {
+			result = newExitCode("failed", 1)
+		}
+		return &proc{
+			cmd:    fakeWaiter{result: result},
+			stdout: strings.NewReader(out),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		rawCommand:                   true,
+		inputFormat:                  "text",
+		args:                         []string{"./test.sh"},
+		format:                       "testname",
+		rerunFailsMaxAttempts:        2,
+		rerunFailsMaxInitialFailures: 10,
+		stdout:                       out,
+		stderr:                       os.Stderr,
+		hideSummary:                  newHideSummaryValue(),
+	}
+	assert.NilError(t, run(opts))
+	assert.Equal(t, runs, 2)
+	assert.Assert(t, cmp.Contains(out.String(), "FAIL example.com/pkg.TestTwo"))
+	assert.Assert(t, cmp.Contains(out.String(), "PASS example.com/pkg.TestTwo (re-run 1)"))
+	assert.Assert(t, cmp.Contains(out.String(), "DONE 2 runs, 3 tests, 1 failure"))
+}
+
+func TestRun_NonJSONOutputLines(t *testing.T) {
+	stdout := `{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+liblegacy: failed to open device
+{"Package": "pkg", "Test": "TestOne", "Action": "fail"}
+{"Package": "pkg", "Action": "fail"}
+`
+	fn := func([]string) *proc {
+		return &proc{
+			cmd:    fakeWaiter{result: newExitCode("failed", 1)},
+			stdout: strings.NewReader(stdout),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	out := new(bytes.Buffer)
+	opts := &options{
+		rawCommand:  true,
+		args:        []string{"./test.test"},
+		format:      "testname",
+		stdout:      out,
+		stderr:      os.Stderr,
+		hideSummary: newHideSummaryValue(),
+	}
+	err := run(opts)
+	assert.Error(t, err, "failed")
+	assert.Assert(t, cmp.Contains(out.String(), "=== FAIL: pkg TestOne (0.00s)\nliblegacy: failed to open device\n"))
+}
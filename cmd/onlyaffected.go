@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// defaultAffectedRef is the git ref used by --only-affected when the flag is
+// used without a value.
+const defaultAffectedRef = "origin/HEAD"
+
+// gitChangedFilesFn is a shim for testing
+var gitChangedFilesFn = gitChangedFiles
+
+// gitChangedFiles returns the files which changed since the merge base of ref
+// and HEAD, including uncommitted and untracked files. The paths are relative
+// to the working directory, and files outside of the working directory are not
+// included.
+func gitChangedFiles(ref string) ([]string, error) {
+	base, err := gitOutput("merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	changed, err := gitOutput("diff", "--name-only", "--relative", strings.TrimSpace(base))
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := gitOutput("ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	return append(strings.Fields(changed), strings.Fields(untracked)...), nil
+}
+
+func gitOutput(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %v failed: %w\n%s", args[0], err, stderr)
+	}
+	return string(out), nil
+}
+
+// affectedSelection is the result of --only-affected.
+type affectedSelection struct {
+	// packages to test, as a path relative to the working directory with a
+	// ./ prefix.
+	packages []string
+	// skipped are the packages which are not affected by the changes.
+	skipped []string
+}
+
+// onlyAffectedPackages returns the packages in the module in the working
+// directory which contain a file that changed since the --only-affected ref,
+// and every package which imports one of them, directly or transitively. When
+// --packages is set only those packages are tested.
+func onlyAffectedPackages(opts *options) (affectedSelection, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return affectedSelection{}, err
+	}
+	files, err := gitChangedFilesFn(opts.onlyAffected)
+	if err != nil {
+		return affectedSelection{}, err
+	}
+	pkgs, err := goListFn(wd)
+	if err != nil {
+		return affectedSelection{}, err
+	}
+	affected := make(map[string]bool)
+	for _, pkg := range dependents(pkgs, changedPackages(wd, files, pkgs)) {
+		affected[pkg.ImportPath] = true
+	}
+	if len(opts.packages) > 0 {
+		selected, err := goListPackagesFn(opts.packages)
+		if err != nil {
+			return affectedSelection{}, err
+		}
+		pkgs = slices.DeleteFunc(pkgs, func(pkg listedPackage) bool {
+			return !slices.Contains(selected, pkg.ImportPath)
+		})
+	}
+
+	var result affectedSelection
+	for _, pkg := range pkgs {
+		dir := relativePackageDir(wd, pkg.Dir)
+		if affected[pkg.ImportPath] {
+			result.packages = append(result.packages, dir)
+			continue
+		}
+		result.skipped = append(result.skipped, dir)
+	}
+	sort.Strings(result.packages)
+	sort.Strings(result.skipped)
+	return result, nil
+}
+
+// changedPackages returns the import paths of the packages which contain one
+// of the changed files. A file belongs to the package in the closest parent
+// directory, so that a change to a file in testdata affects the package which
+// uses it. A change to go.mod or go.sum affects every package.
+func changedPackages(wd string, files []string, pkgs []listedPackage) map[string]bool {
+	byDir := make(map[string]string, len(pkgs))
+	for _, pkg := range pkgs {
+		byDir[pkg.Dir] = pkg.ImportPath
+	}
+
+	changed := make(map[string]bool)
+	for _, file := range files {
+		switch filepath.Base(file) {
+		case "go.mod", "go.sum", "go.work", "go.work.sum":
+			for _, pkg := range pkgs {
+				changed[pkg.ImportPath] = true
+			}
+			return changed
+		}
+
+		dir := filepath.Dir(filepath.Join(wd, filepath.FromSlash(file)))
+		for {
+			if importPath, ok := byDir[dir]; ok {
+				changed[importPath] = true
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir || !strings.HasPrefix(dir, wd) {
+				break
+			}
+			dir = parent
+		}
+	}
+	return changed
+}
+
+// printSkippedPackages prints the packages which are not tested because they
+// are not affected by the changes since the --only-affected ref.
+func printSkippedPackages(opts *options, selection affectedSelection) {
+	if len(selection.packages) == 0 {
+		fmt.Fprintf(opts.stderr, "No packages are affected by the changes since %v\n", opts.onlyAffected)
+		return
+	}
+	if len(selection.skipped) == 0 {
+		return
+	}
+	fmt.Fprintf(opts.stderr, "Skipped %d packages not affected by the changes since %v:\n",
+		len(selection.skipped), opts.onlyAffected)
+	for _, pkg := range selection.skipped {
+		fmt.Fprintf(opts.stderr, "    %v\n", pkg)
+	}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestOnlyAffectedPackages(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NilError(t, err)
+
+	patchGoListFn(t, []listedPackage{
+		{Dir: filepath.Join(wd, "store"), ImportPath: "example.com/app/store"},
+		{
+			Dir:        filepath.Join(wd, "api"),
+			ImportPath: "example.com/app/api",
+			Deps:       []string{"example.com/app/store"},
+		},
+		{
+			Dir:         filepath.Join(wd, "cli"),
+			ImportPath:  "example.com/app/cli",
+			TestImports: []string{"example.com/app/api"},
+		},
+		{Dir: filepath.Join(wd, "report"), ImportPath: "example.com/app/report"},
+		{Dir: filepath.Join(wd, "util"), ImportPath: "example.com/app/util"},
+	})
+	patchGitChangedFiles := func(t *testing.T, files ...string) {
+		orig := gitChangedFilesFn
+		gitChangedFilesFn = func(ref string) ([]string, error) {
+			assert.Equal(t, ref, "origin/main")
+			return files, nil
+		}
+		t.Cleanup(func() { gitChangedFilesFn = orig })
+	}
+
+	t.Run("changed package and its dependents", func(t *testing.T) {
+		patchGitChangedFiles(t, "store/store.go", "report/testdata/expected.txt", "docs/README.md")
+		actual, err := onlyAffectedPackages(&options{onlyAffected: "origin/main"})
+		assert.NilError(t, err)
+		expected := affectedSelection{
+			packages: []string{"./api", "./cli", "./report", "./store"},
+			skipped:  []string{"./util"},
+		}
+		assert.DeepEqual(t, actual, expected, cmpAffectedSelection)
+	})
+	t.Run("go.mod changed", func(t *testing.T) {
+		patchGitChangedFiles(t, "go.mod")
+		actual, err := onlyAffectedPackages(&options{onlyAffected: "origin/main"})
+		assert.NilError(t, err)
+		assert.Equal(t, len(actual.packages), 5)
+		assert.Equal(t, len(actual.skipped), 0)
+	})
+	t.Run("with packages flag", func(t *testing.T) {
+		patchGitChangedFiles(t, "store/store.go")
+		orig := goListPackagesFn
+		goListPackagesFn = func([]string) ([]string, error) {
+			return []string{"example.com/app/cli", "example.com/app/util"}, nil
+		}
+		t.Cleanup(func() { goListPackagesFn = orig })
+
+		opts := &options{onlyAffected: "origin/main", packages: []string{"./cli", "./util"}}
+		actual, err := onlyAffectedPackages(opts)
+		assert.NilError(t, err)
+		expected := affectedSelection{packages: []string{"./cli"}, skipped: []string{"./util"}}
+		assert.DeepEqual(t, actual, expected, cmpAffectedSelection)
+	})
+}
+
+var cmpAffectedSelection = cmp.AllowUnexported(affectedSelection{})
+
+func TestPrintSkippedPackages(t *testing.T) {
+	buf := new(bytes.Buffer)
+	opts := &options{onlyAffected: "origin/main", stderr: buf}
+	printSkippedPackages(opts, affectedSelection{
+		packages: []string{"./api"},
+		skipped:  []string{"./cli", "./util"},
+	})
+	expected := `Skipped 2 packages not affected by the changes since origin/main:
+    ./cli
+    ./util
+`
+	assert.Equal(t, buf.String(), expected)
+
+	buf.Reset()
+	printSkippedPackages(opts, affectedSelection{skipped: []string{"./cli"}})
+	assert.Equal(t, buf.String(), "No packages are affected by the changes since origin/main\n")
+}
+
+func TestGitChangedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := fs.NewDir(t, "only-affected",
+		fs.WithFile("go.mod", "module example.com/app\n"),
+		fs.WithDir("store", fs.WithFile("store.go", "package store\n")),
+		fs.WithDir("api", fs.WithFile("api.go", "package api\n")))
+	t.Chdir(dir.Path())
+	git := func(args ...string) {
+		t.Helper()
+		_, err := gitOutput(append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		assert.NilError(t, err)
+	}
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	git("branch", "base")
+
+	assert.NilError(t, os.WriteFile("store/store.go", []byte("package store\n\nvar X = 1\n"), 0o644))
+	assert.NilError(t, os.WriteFile("api/new.go", []byte("package api\n"), 0o644))
+
+	files, err := gitChangedFiles("base")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, files, []string{"store/store.go", "api/new.go"})
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// quitTestBinaryFn is a shim for testing
+var quitTestBinaryFn = quitTestBinary
+
+// packageTimeout sends SIGQUIT to the test binary of any package which runs
+// for longer than the --package-timeout. The test binary prints the stack of
+// every goroutine and exits, which fails the tests which were still running,
+// and go test continues with the other packages.
+type packageTimeout struct {
+	timeout time.Duration
+	done    chan struct{}
+	// parent returns the pid of the go test command which tests only pkg, or 0
+	// when the package is tested by a go test command with other packages.
+	parent func(pkg string) int
+
+	mu       sync.Mutex
+	started  map[string]time.Time
+	timedOut []string
+}
+
+// newPackageTimeout returns nil when --package-timeout is not set.
+func newPackageTimeout(opts *options, parent func(pkg string) int) *packageTimeout {
+	if opts.packageTimeout == 0 {
+		return nil
+	}
+	p := &packageTimeout{
+		timeout: opts.packageTimeout,
+		done:    make(chan struct{}),
+		parent:  parent,
+		started: make(map[string]time.Time),
+	}
+	go p.watch(min(opts.packageTimeout/4, time.Second))
+	return p
+}
+
+func (p *packageTimeout) watch(interval time.Duration) {
+	ticker := time.NewTicker(max(interval, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			p.check(now)
+		}
+	}
+}
+
+// check stops the test binary of every package which started more than the
+// timeout before now.
+func (p *packageTimeout) check(now time.Time) {
+	p.mu.Lock()
+	var expired []string
+	for pkg, start := range p.started {
+		if now.Sub(start) >= p.timeout {
+			expired = append(expired, pkg)
+			delete(p.started, pkg)
+		}
+	}
+	sort.Strings(expired)
+	p.timedOut = append(p.timedOut, expired...)
+	p.mu.Unlock()
+
+	for _, pkg := range expired {
+		var parent int
+		if p.parent != nil {
+			parent = p.parent(pkg)
+		}
+		if err := quitTestBinaryFn(testBinaryName(pkg), parent); err != nil {
+			log.Warnf("package %v did not finish within --package-timeout=%v, failed to stop it: %v",
+				pkg, p.timeout, err)
+			continue
+		}
+		log.Warnf("package %v did not finish within --package-timeout=%v, "+
+			"sent SIGQUIT to print the goroutines and stop it", pkg, p.timeout)
+	}
+}
+
+// Handler returns an EventHandler which records the start and end of each
+// package.
+func (p *packageTimeout) Handler(handler testjson.EventHandler) testjson.EventHandler {
+	if p == nil {
+		return handler
+	}
+	return &packageTimeoutHandler{EventHandler: handler, timeout: p}
+}
+
+// TimedOut returns the packages which were stopped because they exceeded the
+// timeout.
+func (p *packageTimeout) TimedOut() []string {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string{}, p.timedOut...)
+}
+
+func (p *packageTimeout) Close() {
+	if p == nil {
+		return
+	}
+	close(p.done)
+}
+
+type packageTimeoutHandler struct {
+	testjson.EventHandler
+	timeout *packageTimeout
+}
+
+func (h *packageTimeoutHandler) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	p := h.timeout
+	p.mu.Lock()
+	switch {
+	case event.PackageEvent() && event.Action.IsTerminal():
+		delete(p.started, event.Package)
+	case event.Action == testjson.ActionStart || event.Action == testjson.ActionRun:
+		// go before go1.20 does not send a start event for the package, so the
+		// package starts with its first test.
+		if _, ok := p.started[event.Package]; !ok {
+			p.started[event.Package] = time.Now()
+		}
+	}
+	p.mu.Unlock()
+	return h.EventHandler.Event(event, execution)
+}
+
+// testBinaryName returns the name of the test binary built by go test for the
+// package, which is the last element of the import path, without a major
+// version suffix.
+func testBinaryName(importPath string) string {
+	elem := path.Base(importPath)
+	if elem != importPath && isMajorVersion(elem) {
+		elem = path.Base(path.Dir(importPath))
+	}
+	return elem + ".test"
+}
+
+func isMajorVersion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' || elem[1] == '0' || elem == "v1" {
+		return false
+	}
+	for _, c := range elem[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// printTimedOutPackages prints the packages which were stopped by the
+// --package-timeout.
+func printTimedOutPackages(opts *options, timeout *packageTimeout) {
+	pkgs := timeout.TimedOut()
+	if len(pkgs) == 0 {
+		return
+	}
+	fmt.Fprintf(opts.stderr, "%d packages were stopped by --package-timeout=%v:\n",
+		len(pkgs), opts.packageTimeout)
+	for _, pkg := range pkgs {
+		fmt.Fprintf(opts.stderr, "    %v\n", pkg)
+	}
+}
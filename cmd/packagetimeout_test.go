@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestPackageTimeout(t *testing.T) {
+	var quit []string
+	orig := quitTestBinaryFn
+	quitTestBinaryFn = func(name string, parent int) error {
+		assert.Equal(t, parent, 0)
+		quit = append(quit, name)
+		return nil
+	}
+	t.Cleanup(func() { quitTestBinaryFn = orig })
+
+	timeout := &packageTimeout{
+		timeout: time.Minute,
+		done:    make(chan struct{}),
+		started: make(map[string]time.Time),
+	}
+	handler := timeout.Handler(noopHandler{})
+	for _, event := range []testjson.TestEvent{
+		{Package: "example.com/fast", Action: testjson.ActionStart},
+		{Package: "example.com/hang", Action: testjson.ActionStart},
+		{Package: "example.com/api/v2", Test: "TestOne", Action: testjson.ActionRun},
+		{Package: "example.com/fast", Action: testjson.ActionPass},
+	} {
+		assert.NilError(t, handler.Event(event, nil))
+	}
+
+	timeout.check(time.Now())
+	assert.Assert(t, quit == nil)
+
+	timeout.check(time.Now().Add(time.Minute))
+	assert.DeepEqual(t, quit, []string{"api.test", "hang.test"})
+	assert.DeepEqual(t, timeout.TimedOut(), []string{"example.com/api/v2", "example.com/hang"})
+
+	// packages are only stopped once
+	timeout.check(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, len(quit), 2)
+
+	out := new(bytes.Buffer)
+	printTimedOutPackages(&options{stderr: out, packageTimeout: time.Minute}, timeout)
+	assert.Equal(t, out.String(), `2 packages were stopped by --package-timeout=1m0s:
+    example.com/api/v2
+    example.com/hang
+`)
+}
+
+func TestPackageTimeout_PackagesWithTheSameName(t *testing.T) {
+	var calls []string
+	orig := quitTestBinaryFn
+	quitTestBinaryFn = func(name string, parent int) error {
+		calls = append(calls, fmt.Sprintf("%v %d", name, parent))
+		return nil
+	}
+	t.Cleanup(func() { quitTestBinaryFn = orig })
+
+	// with --per-package each package is tested by its own go test command
+	pids := map[string]int{"example.com/a/util": 101, "example.com/b/util": 102}
+	timeout := &packageTimeout{
+		timeout: time.Minute,
+		done:    make(chan struct{}),
+		parent:  func(pkg string) int { return pids[pkg] },
+		started: make(map[string]time.Time),
+	}
+	handler := timeout.Handler(noopHandler{})
+	for _, event := range []testjson.TestEvent{
+		{Package: "example.com/a/util", Action: testjson.ActionStart},
+		{Package: "example.com/b/util", Action: testjson.ActionStart},
+	} {
+		assert.NilError(t, handler.Event(event, nil))
+	}
+
+	timeout.check(time.Now().Add(time.Minute))
+	assert.DeepEqual(t, calls, []string{"util.test 101", "util.test 102"})
+}
+
+func TestTestBinaryName(t *testing.T) {
+	for importPath, expected := range map[string]string{
+		"example.com/app/store": "store.test",
+		"example.com/api/v2":    "api.test",
+		"example.com/api/v1":    "v1.test",
+		"gopkg.in/yaml.v3":      "yaml.v3.test",
+		"v2":                    "v2.test",
+	} {
+		assert.Equal(t, testBinaryName(importPath), expected, importPath)
+	}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"time"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// partialReportsHandler returns an EventHandler which writes the report files
+// each time a package finishes, at most once every --partial-report-interval,
+// so that a report of the packages which finished exists if gotestsum is killed
+// before the end of the run. The --jsonfile is always written as the events
+// are received, so it does not need to be written again.
+func partialReportsHandler(opts *options, handler testjson.EventHandler) testjson.EventHandler {
+	if opts.partialReportInterval == 0 {
+		return handler
+	}
+	if opts.junitFile == "" && opts.xunitFile == "" && opts.sonarFile == "" {
+		return handler
+	}
+	return &partialReports{EventHandler: handler, opts: opts}
+}
+
+type partialReports struct {
+	testjson.EventHandler
+	opts    *options
+	written time.Time
+}
+
+func (h *partialReports) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	if err := h.EventHandler.Event(event, execution); err != nil {
+		return err
+	}
+	if !event.PackageEvent() || !event.Action.IsTerminal() {
+		return nil
+	}
+	if time.Since(h.written) < h.opts.partialReportInterval {
+		return nil
+	}
+	h.written = time.Now()
+
+	for _, write := range []func(*options, *testjson.Execution) error{
+		writeJUnitFile,
+		writeXUnitFile,
+		writeSonarFile,
+	} {
+		if err := write(h.opts, execution); err != nil {
+			log.Warnf("failed to write partial report: %v", err)
+		}
+	}
+	return nil
+}
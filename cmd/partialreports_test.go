@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestPartialReportsHandler(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	junitFile := filepath.Join(dir.Path(), "junit.xml")
+	opts := &options{
+		junitFile:                    junitFile,
+		junitTestCaseClassnameFormat: &junitFieldFormatValue{},
+		junitTestSuiteNameFormat:     &junitFieldFormatValue{},
+		partialReportInterval:        time.Nanosecond,
+	}
+
+	var reports []string
+	handler := partialReportsHandler(opts, &eventHandler{
+		formatter: testjson.NewEventFormatter(io.Discard, "testname", testjson.FormatOptions{}),
+	})
+	handler = &readReportHandler{EventHandler: handler, path: junitFile, reports: &reports}
+
+	input := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestOne"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestOne","Elapsed":0.1}`,
+		`{"Action":"pass","Package":"example.com/a","Elapsed":0.2}`,
+		`{"Action":"run","Package":"example.com/b","Test":"TestTwo"}`,
+	}, "\n")
+	_, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  strings.NewReader(input),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+
+	// the report is written when package a ends, before package b finishes
+	assert.Equal(t, len(reports), 1)
+	assert.Assert(t, strings.Contains(reports[0], `name="example.com/a"`), reports[0])
+	assert.Assert(t, !strings.Contains(reports[0], "example.com/b"), reports[0])
+
+	_, err = os.Stat(junitFile + ".tmp")
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestPartialReportsHandler_Disabled(t *testing.T) {
+	handler := &eventHandler{}
+	opts := &options{junitFile: "junit.xml"}
+	assert.Equal(t, partialReportsHandler(opts, handler), testjson.EventHandler(handler))
+
+	opts = &options{partialReportInterval: time.Second}
+	assert.Equal(t, partialReportsHandler(opts, handler), testjson.EventHandler(handler))
+}
+
+// readReportHandler reads the report file after each package ends.
+type readReportHandler struct {
+	testjson.EventHandler
+	path    string
+	reports *[]string
+}
+
+func (h *readReportHandler) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	if err := h.EventHandler.Event(event, execution); err != nil {
+		return err
+	}
+	if event.PackageEvent() && event.Action.IsTerminal() {
+		raw, err := os.ReadFile(h.path)
+		if err != nil {
+			return err
+		}
+		*h.reports = append(*h.reports, string(bytes.TrimSpace(raw)))
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// goListPackagesFn is a shim for testing
+var goListPackagesFn = goListPackages
+
+func goListPackages(patterns []string) ([]string, error) {
+	cmd := exec.Command("go", append([]string{"list"}, patterns...)...)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list failed: %w\n%s", err, stderr)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// goListTestsFn is a shim for testing
+var goListTestsFn = goListTests
+
+// goListTests returns the names of the tests, fuzz tests, and examples in each
+// package, using 'go test -list'. Benchmarks are not included because they are
+// not selected by -run.
+func goListTests(pkgs []string, buildFlags []string) (map[string][]string, error) {
+	return goTestList(pkgs, buildFlags, listedTestPattern)
+}
+
+// goTestList returns the names listed by 'go test -list' in each package
+// which match pattern.
+func goTestList(pkgs []string, buildFlags []string, pattern *regexp.Regexp) (map[string][]string, error) {
+	args := append([]string{"test", "-list", "."}, buildFlags...)
+	cmd := exec.Command("go", append(args, pkgs...)...)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go test -list failed: %w\n%s%s", err, out, stderr)
+	}
+	return parseTestList(out, pattern), nil
+}
+
+var listedTestPattern = regexp.MustCompile(`^(Test|Fuzz|Example)\w*$`)
+
+// parseTestList parses the output of 'go test -list', where the names of the
+// tests in a package are followed by a line with the result of the package.
+// Only the names which match pattern are included.
+func parseTestList(out []byte, pattern *regexp.Regexp) map[string][]string {
+	result := make(map[string][]string)
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		switch {
+		case pattern.MatchString(line):
+			names = append(names, line)
+		case len(fields) >= 2 && (fields[0] == "ok" || fields[0] == "?"):
+			if len(names) > 0 {
+				result[fields[1]] = names
+			}
+			names = nil
+		}
+	}
+	return result
+}
+
+// applyPartition replaces the packages to test with the packages in the
+// --partition. With --partition-by=test a -run flag is added to the go test
+// args to select the tests in the partition. The time of each package or test
+// is read from the --history-file, so that the partitions take about the same
+// time to run. applyPartition returns false when the partition is empty.
+func applyPartition(opts *options) (bool, error) {
+	pkgs, err := goListPackagesFn(cmdArgPackageList(opts, rerunOpts{}, "./..."))
+	if err != nil {
+		return false, err
+	}
+
+	var runs []history.Run
+	if opts.historyFile != "" {
+		runs, err = history.Read(opts.historyFile)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			log.Debugf("history file %v does not exist, partitioning by number of %vs",
+				opts.historyFile, opts.partitionBy)
+		case err != nil:
+			return false, fmt.Errorf("failed to read history file: %w", err)
+		}
+	}
+
+	if opts.partitionBy == "test" {
+		tests, err := goListTestsFn(pkgs, listBuildFlags(opts.args))
+		if err != nil {
+			return false, err
+		}
+		selected, runFlag := partitionTests(tests, history.TestElapsed(runs), opts.partition)
+		if len(selected) == 0 {
+			log.Warnf("partition %v has no tests to run", opts.partition.String())
+			return false, nil
+		}
+		opts.packages = selected
+		opts.args = append(opts.args, runFlag)
+		return true, nil
+	}
+
+	selected := partitionPackages(pkgs, history.PackageElapsed(runs), opts.partition)
+	if len(selected) == 0 {
+		log.Warnf("partition %v has no packages to test", opts.partition.String())
+		return false, nil
+	}
+	opts.packages = selected
+	return true, nil
+}
+
+// listBuildFlags returns the -tags flag from the go test args, which changes
+// the tests listed by 'go test -list'.
+func listBuildFlags(args []string) []string {
+	start, end := argIndex("tags", args)
+	if start < 0 || end >= len(args) {
+		return nil
+	}
+	return args[start : end+1]
+}
+
+// partitionPackages returns the packages in partition p.
+func partitionPackages(pkgs []string, timing map[string]time.Duration, p partitionValue) []string {
+	partitions := partitionByTime(pkgs, timing, p.total)
+	result := partitions[p.index-1]
+	log.Debugf("partition %v has %d of %d packages, with an estimated time of %v",
+		p.String(), len(result.items), len(pkgs), result.total)
+	return result.items
+}
+
+// partitionTests returns the packages, and the -run flag, of the tests in
+// partition p. Tests are partitioned by name, so that a test name is only run
+// by one partition, even when there is a test with the same name in more than
+// one package. The time of a test name is the sum of the time of the tests with
+// that name in every package.
+func partitionTests(
+	tests map[string][]string,
+	timing map[string]map[string]time.Duration,
+	p partitionValue,
+) ([]string, string) {
+	var known []time.Duration
+	for pkg, names := range tests {
+		for _, name := range names {
+			if d, ok := timing[pkg][name]; ok {
+				known = append(known, d)
+			}
+		}
+	}
+	estimate := aggregate.Median(known)
+
+	byName := make(map[string]time.Duration)
+	pkgsByName := make(map[string][]string)
+	for pkg, names := range tests {
+		for _, name := range names {
+			d, ok := timing[pkg][name]
+			if !ok {
+				d = estimate
+			}
+			byName[name] += d
+			pkgsByName[name] = append(pkgsByName[name], pkg)
+		}
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+
+	partitions := partitionByTime(names, byName, p.total)
+	result := partitions[p.index-1]
+	log.Debugf("partition %v has %d of %d tests, with an estimated time of %v",
+		p.String(), len(result.items), len(names), result.total)
+	if len(result.items) == 0 {
+		return nil, ""
+	}
+
+	pkgSet := make(map[string]bool)
+	for _, name := range result.items {
+		for _, pkg := range pkgsByName[name] {
+			pkgSet[pkg] = true
+		}
+	}
+	pkgs := make([]string, 0, len(pkgSet))
+	for pkg := range pkgSet {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs, runFlagForTests(result.items)
+}
+
+// runFlagForTests returns a go test -run flag which matches exactly the top
+// level tests in names.
+func runFlagForTests(names []string) string {
+	return "-run=" + testsPattern(names)
+}
+
+// testsPattern returns a -run or -skip pattern which matches exactly the top
+// level tests in names.
+func testsPattern(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+type partition struct {
+	total time.Duration
+	items []string
+}
+
+// partitionByTime splits items into n partitions with about the same total
+// time. Items without a time in timing are assumed to take the median time of
+// the items which have one. The items in each partition are sorted by name.
+func partitionByTime(items []string, timing map[string]time.Duration, n int) []partition {
+	var known []time.Duration
+	for _, item := range items {
+		if d, ok := timing[item]; ok {
+			known = append(known, d)
+		}
+	}
+	estimate := aggregate.Median(known)
+	elapsed := func(item string) time.Duration {
+		if d, ok := timing[item]; ok {
+			return d
+		}
+		return estimate
+	}
+
+	sorted := append([]string{}, items...)
+	sort.Strings(sorted)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return elapsed(sorted[i]) > elapsed(sorted[j])
+	})
+
+	partitions := make([]partition, n)
+	for _, item := range sorted {
+		i := 0
+		for j := range partitions {
+			a, b := partitions[j], partitions[i]
+			if a.total < b.total || (a.total == b.total && len(a.items) < len(b.items)) {
+				i = j
+			}
+		}
+		partitions[i].total += elapsed(item)
+		partitions[i].items = append(partitions[i].items, item)
+	}
+	for _, p := range partitions {
+		sort.Strings(p.items)
+	}
+	return partitions
+}
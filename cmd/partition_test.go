@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestPartitionByTime(t *testing.T) {
+	pkgs := []string{"a", "b", "c", "d", "e", "f"}
+	timing := map[string]time.Duration{
+		"a": 10 * time.Second,
+		"b": 6 * time.Second,
+		"c": 5 * time.Second,
+		"d": 2 * time.Second,
+		"e": time.Second,
+	}
+	partitions := partitionByTime(pkgs, timing, 3)
+	expected := []partition{
+		{total: 10 * time.Second, items: []string{"a"}},
+		{total: 9 * time.Second, items: []string{"b", "d", "e"}},
+		// f has no timing, so it is estimated as the median time
+		{total: 10 * time.Second, items: []string{"c", "f"}},
+	}
+	assert.DeepEqual(t, partitions, expected, cmpPartition)
+
+	t.Run("without timing", func(t *testing.T) {
+		partitions := partitionByTime(pkgs, nil, 4)
+		expected := []partition{
+			{items: []string{"a", "e"}},
+			{items: []string{"b", "f"}},
+			{items: []string{"c"}},
+			{items: []string{"d"}},
+		}
+		assert.DeepEqual(t, partitions, expected, cmpPartition)
+	})
+}
+
+var cmpPartition = cmp.AllowUnexported(partition{})
+
+func TestApplyPartition(t *testing.T) {
+	orig := goListPackagesFn
+	goListPackagesFn = func(patterns []string) ([]string, error) {
+		assert.DeepEqual(t, patterns, []string{"./..."})
+		return []string{"example.com/a", "example.com/b", "example.com/c"}, nil
+	}
+	t.Cleanup(func() { goListPackagesFn = orig })
+
+	record := func(pkg, test, elapsed string) string {
+		return `{"run_id":"1","package":"` + pkg + `","test":"` + test + `",` +
+			`"outcome":"pass","elapsed_seconds":` + elapsed + `,"attempt":1}`
+	}
+	dir := fs.NewDir(t, "partition", fs.WithFile("history.jsonl", strings.Join([]string{
+		record("example.com/a", "TestOne", "1"),
+		record("example.com/b", "TestOne", "1"),
+		record("example.com/b", "TestSlow", "4"),
+		record("example.com/c", "TestThree", "3"),
+	}, "\n")))
+
+	t.Run("by package", func(t *testing.T) {
+		opts := &options{historyFile: dir.Join("history.jsonl"), partition: partitionValue{index: 2, total: 2}}
+		ok, err := applyPartition(opts)
+		assert.NilError(t, err)
+		assert.Assert(t, ok)
+		assert.DeepEqual(t, opts.packages, []string{"example.com/a", "example.com/c"})
+	})
+	t.Run("by package without history", func(t *testing.T) {
+		opts := &options{historyFile: dir.Join("missing.jsonl"), partition: partitionValue{index: 1, total: 2}}
+		ok, err := applyPartition(opts)
+		assert.NilError(t, err)
+		assert.Assert(t, ok)
+		assert.DeepEqual(t, opts.packages, []string{"example.com/a", "example.com/c"})
+	})
+	t.Run("by test", func(t *testing.T) {
+		orig := goListTestsFn
+		goListTestsFn = func(pkgs []string, buildFlags []string) (map[string][]string, error) {
+			assert.DeepEqual(t, buildFlags, []string{"-tags", "integration"})
+			return map[string][]string{
+				"example.com/a": {"TestOne", "TestTwo"},
+				"example.com/b": {"TestOne", "TestSlow"},
+				"example.com/c": {"TestThree"},
+			}, nil
+		}
+		t.Cleanup(func() { goListTestsFn = orig })
+
+		opts := &options{
+			historyFile: dir.Join("history.jsonl"),
+			partition:   partitionValue{index: 2, total: 2},
+			partitionBy: "test",
+			packages:    []string{"./..."},
+			args:        []string{"-tags", "integration"},
+		}
+		ok, err := applyPartition(opts)
+		assert.NilError(t, err)
+		assert.Assert(t, ok)
+		// TestTwo has no history, so it is estimated as the median time
+		assert.DeepEqual(t, opts.packages, []string{"example.com/a", "example.com/c"})
+		assert.DeepEqual(t, opts.args, []string{"-tags", "integration", "-run=^(TestThree|TestTwo)$"})
+	})
+}
+
+func TestParseTestList(t *testing.T) {
+	out := `TestA
+BenchmarkA
+FuzzA
+ExampleA
+ok  	example.com/a	0.002s
+?   	example.com/b	[no test files]
+ok  	example.com/c	0.001s
+TestD
+ok  	example.com/d	(cached)
+`
+	expected := map[string][]string{
+		"example.com/a": {"TestA", "FuzzA", "ExampleA"},
+		"example.com/d": {"TestD"},
+	}
+	assert.DeepEqual(t, parseTestList([]byte(out), listedTestPattern), expected)
+}
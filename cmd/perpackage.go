@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"runtime"
+)
+
+// perPackageGroups splits the groups into a testGroup for each package, so that
+// --per-package runs a go test command for each package. When there are no
+// groups the packages are listed with go list.
+func perPackageGroups(opts *options, groups []testGroup) ([]testGroup, error) {
+	if len(groups) == 0 {
+		pkgs, err := goListPackagesFn(cmdArgPackageList(opts, rerunOpts{}, "./..."))
+		if err != nil {
+			return nil, err
+		}
+		groups = []testGroup{{packages: pkgs}}
+	}
+
+	var result []testGroup
+	for _, group := range groups {
+		for _, pkg := range group.packages {
+			result = append(result, testGroup{packages: []string{pkg}, args: group.args})
+		}
+	}
+	return result, nil
+}
+
+// maxProcs returns the number of go test commands which run at the same time.
+func maxProcs(opts *options) int {
+	switch {
+	case !opts.perPackage:
+		return 1
+	case opts.maxProcs > 0:
+		return opts.maxProcs
+	default:
+		return runtime.GOMAXPROCS(0)
+	}
+}
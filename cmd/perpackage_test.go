@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/coverprofile"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestPerPackageGroups(t *testing.T) {
+	orig := goListPackagesFn
+	goListPackagesFn = func(patterns []string) ([]string, error) {
+		assert.DeepEqual(t, patterns, []string{"./pkg/..."})
+		return []string{"example.com/pkg/a", "example.com/pkg/b"}, nil
+	}
+	t.Cleanup(func() { goListPackagesFn = orig })
+
+	t.Run("all packages", func(t *testing.T) {
+		groups, err := perPackageGroups(&options{packages: []string{"./pkg/..."}}, nil)
+		assert.NilError(t, err)
+		expected := []testGroup{
+			{packages: []string{"example.com/pkg/a"}},
+			{packages: []string{"example.com/pkg/b"}},
+		}
+		assert.DeepEqual(t, groups, expected, cmpTestGroup)
+	})
+	t.Run("split groups", func(t *testing.T) {
+		groups, err := perPackageGroups(&options{}, []testGroup{
+			{packages: []string{"example.com/a", "example.com/b"}, args: []string{"-run=^(TestOne)$"}},
+		})
+		assert.NilError(t, err)
+		expected := []testGroup{
+			{packages: []string{"example.com/a"}, args: []string{"-run=^(TestOne)$"}},
+			{packages: []string{"example.com/b"}, args: []string{"-run=^(TestOne)$"}},
+		}
+		assert.DeepEqual(t, groups, expected, cmpTestGroup)
+	})
+}
+
+func TestGoTestGroups_StartParallel(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	release := make(chan struct{})
+	reset := patchStartGoTestFn(func(args []string) *proc {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+
+		pkg := args[len(args)-1]
+		stdout, stdoutW := io.Pipe()
+		go func() {
+			<-release
+			_, _ = io.WriteString(stdoutW, `{"Package": "`+pkg+`", "Action": "run"}`+"\n")
+			_, _ = io.WriteString(stdoutW, `{"Package": "`+pkg+`", "Action": "pass"}`+"\n")
+			mu.Lock()
+			running--
+			mu.Unlock()
+			_ = stdoutW.Close()
+		}()
+		return &proc{cmd: fakeWaiter{}, stdout: stdout, stderr: strings.NewReader("")}
+	})
+	defer reset()
+
+	groups := []testGroup{
+		{packages: []string{"example.com/a"}},
+		{packages: []string{"example.com/b"}},
+		{packages: []string{"example.com/c"}},
+	}
+	goTestProc := (&goTestGroups{groups: groups, parallel: 2}).Start(context.Background(), &options{})
+	go func() { _, _ = io.Copy(io.Discard, goTestProc.stderr) }()
+	close(release)
+
+	out, err := io.ReadAll(goTestProc.stdout)
+	assert.NilError(t, err)
+	assert.NilError(t, goTestProc.cmd.Wait())
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	sort.Strings(lines)
+	assert.DeepEqual(t, lines, []string{
+		`{"Package": "example.com/a", "Action": "pass"}`,
+		`{"Package": "example.com/a", "Action": "run"}`,
+		`{"Package": "example.com/b", "Action": "pass"}`,
+		`{"Package": "example.com/b", "Action": "run"}`,
+		`{"Package": "example.com/c", "Action": "pass"}`,
+		`{"Package": "example.com/c", "Action": "run"}`,
+	})
+	assert.Assert(t, maxRunning <= 2, "max running %d", maxRunning)
+}
+
+func TestRun_PerPackageCoverProfile(t *testing.T) {
+	orig := goListPackagesFn
+	goListPackagesFn = func([]string) ([]string, error) {
+		return []string{"example.com/a", "example.com/b"}, nil
+	}
+	t.Cleanup(func() { goListPackagesFn = orig })
+
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("coverage.out", "mode: set\nexample.com/old/old.go:1.1,2.2 1 1\n"))
+	reset := patchStartGoTestFn(func(args []string) *proc {
+		pkg := args[len(args)-1]
+		profile := coverprofile.ArgValue(args)
+		assert.Assert(t, profile != dir.Join("coverage.out"), "every command must write its own profile")
+		content := fmt.Sprintf("mode: set\n%v/file.go:1.1,2.2 1 1\n", pkg)
+		assert.NilError(t, os.WriteFile(profile, []byte(content), 0o644))
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(`{"Package": "` + pkg + `", "Action": "pass"}` + "\n"),
+			stderr: strings.NewReader(""),
+		}
+	})
+	defer reset()
+
+	opts := &options{
+		perPackage:  true,
+		maxProcs:    2,
+		packages:    []string{"./..."},
+		args:        []string{"-coverprofile=" + dir.Join("coverage.out")},
+		format:      "testname",
+		hideSummary: newHideSummaryValue(),
+		stdout:      new(bytes.Buffer),
+		stderr:      new(bytes.Buffer),
+	}
+	assert.NilError(t, run(opts))
+
+	raw, err := os.ReadFile(dir.Join("coverage.out"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), `mode: set
+example.com/a/file.go:1.1,2.2 1 1
+example.com/b/file.go:1.1,2.2 1 1
+`)
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"gotest.tools/gotestsum/internal/picker"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// pickTestsFn is a shim for testing
+var pickTestsFn = func(items []picker.Item) ([]picker.Item, error) {
+	return picker.Run(os.Stdin, os.Stdout, items)
+}
+
+// applyPick lists the tests in the packages to test, and shows the --pick
+// selector. The packages to test are replaced by the packages of the selected
+// tests, and a -run flag is added to the go test args to run only the selected
+// tests. applyPick returns false when the selection is cancelled.
+func applyPick(opts *options) (bool, error) {
+	pkgs, err := goListPackagesFn(cmdArgPackageList(opts, rerunOpts{}, "./..."))
+	if err != nil {
+		return false, err
+	}
+	tests, err := goListTestsFn(pkgs, listBuildFlags(opts.args))
+	if err != nil {
+		return false, err
+	}
+
+	var items []picker.Item
+	for _, pkg := range pkgs {
+		for _, name := range tests[pkg] {
+			items = append(items, picker.Item{
+				Package: pkg,
+				Test:    name,
+				Label:   testjson.RelativePackagePath(pkg) + "." + name,
+			})
+		}
+	}
+	if len(items) == 0 {
+		return false, fmt.Errorf("no tests found to pick from")
+	}
+
+	selected, err := pickTestsFn(items)
+	switch {
+	case errors.Is(err, picker.ErrCancelled):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to pick tests: %w", err)
+	}
+
+	pkgSet := make(map[string]bool)
+	nameSet := make(map[string]bool)
+	for _, item := range selected {
+		pkgSet[item.Package] = true
+		nameSet[item.Test] = true
+	}
+	opts.packages = sortedKeys(pkgSet)
+	opts.args = append(opts.args, runFlagForTests(sortedKeys(nameSet)))
+	return true, nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
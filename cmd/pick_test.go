@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/gotestsum/internal/picker"
+	"gotest.tools/v3/assert"
+)
+
+func TestApplyPick(t *testing.T) {
+	origPackages, origTests := goListPackagesFn, goListTestsFn
+	goListPackagesFn = func(patterns []string) ([]string, error) {
+		assert.DeepEqual(t, patterns, []string{"./..."})
+		return []string{"example.com/a", "example.com/b"}, nil
+	}
+	goListTestsFn = func(pkgs []string, buildFlags []string) (map[string][]string, error) {
+		return map[string][]string{
+			"example.com/a": {"TestOne", "TestTwo"},
+			"example.com/b": {"TestOne", "TestThree"},
+		}, nil
+	}
+	t.Cleanup(func() { goListPackagesFn, goListTestsFn = origPackages, origTests })
+
+	patchPick := func(t *testing.T, fn func(items []picker.Item) ([]picker.Item, error)) {
+		orig := pickTestsFn
+		pickTestsFn = fn
+		t.Cleanup(func() { pickTestsFn = orig })
+	}
+
+	t.Run("selected tests", func(t *testing.T) {
+		patchPick(t, func(items []picker.Item) ([]picker.Item, error) {
+			assert.Equal(t, len(items), 4)
+			assert.Equal(t, items[0].Label, "example.com/a.TestOne")
+			return []picker.Item{items[1], items[3]}, nil
+		})
+		opts := &options{args: []string{"-v"}, packages: []string{"./..."}}
+		ok, err := applyPick(opts)
+		assert.NilError(t, err)
+		assert.Assert(t, ok)
+		assert.DeepEqual(t, opts.packages, []string{"example.com/a", "example.com/b"})
+		assert.DeepEqual(t, opts.args, []string{"-v", "-run=^(TestThree|TestTwo)$"})
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		patchPick(t, func(items []picker.Item) ([]picker.Item, error) {
+			return nil, picker.ErrCancelled
+		})
+		opts := &options{}
+		ok, err := applyPick(opts)
+		assert.NilError(t, err)
+		assert.Assert(t, !ok)
+		assert.Assert(t, opts.packages == nil)
+	})
+}
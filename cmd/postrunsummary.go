@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/coverprofile"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// runSummary is written as JSON to the stdin of the --post-run-command, so that
+// the command does not need to parse the --jsonfile or --junitfile.
+type runSummary struct {
+	// Status is passed when ExitCode is 0, otherwise failed.
+	Status         string        `json:"status"`
+	ExitCode       int           `json:"exit_code"`
+	ElapsedSeconds float64       `json:"elapsed_seconds"`
+	Totals         summaryTotals `json:"totals"`
+	// Failures are the tests which failed, and did not pass when they were
+	// rerun. Test is empty when the package failed without a failed test.
+	Failures []summaryTest `json:"failures"`
+	// Flaky are the tests which failed, and passed when they were rerun.
+	Flaky    []summaryTest    `json:"flaky"`
+	Errors   []string         `json:"errors"`
+	Packages []summaryPackage `json:"packages"`
+	Reports  summaryReports   `json:"reports"`
+}
+
+type summaryTotals struct {
+	Tests   int `json:"tests"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+	Errors  int `json:"errors"`
+	Flaky   int `json:"flaky"`
+}
+
+type summaryTest struct {
+	Package        string  `json:"package"`
+	Test           string  `json:"test,omitempty"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Quarantined    bool    `json:"quarantined,omitempty"`
+}
+
+type summaryPackage struct {
+	Package        string  `json:"package"`
+	Result         string  `json:"result"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Cached         bool    `json:"cached,omitempty"`
+	// Coverage is the percent of statements covered, for example 91.1%, when
+	// the package was run with -cover.
+	Coverage string `json:"coverage,omitempty"`
+}
+
+// summaryReports are the paths of the files written by the run. A file which
+// was not written is omitted.
+type summaryReports struct {
+	JSONFile             string `json:"jsonfile,omitempty"`
+	JSONFileTimingEvents string `json:"jsonfile_timing_events,omitempty"`
+	JUnitFile            string `json:"junitfile,omitempty"`
+	XUnitFile            string `json:"xunitfile,omitempty"`
+	SonarFile            string `json:"sonarfile,omitempty"`
+	CircleCITimingsFile  string `json:"circleci_timings_file,omitempty"`
+	BESJSONFile          string `json:"bes_json_file,omitempty"`
+	AllureResultsDir     string `json:"allure_results,omitempty"`
+	HistoryFile          string `json:"history_file,omitempty"`
+	CoverProfile         string `json:"coverprofile,omitempty"`
+}
+
+func newRunSummary(opts *options, exec *testjson.Execution, exitErr error) runSummary {
+	summary := runSummary{
+		Status:         "passed",
+		ExitCode:       ExitCodeWithDefault(exitErr),
+		ElapsedSeconds: seconds(exec.Elapsed()),
+		Failures:       []summaryTest{},
+		Flaky:          []summaryTest{},
+		Errors:         exec.Errors(),
+		Packages:       []summaryPackage{},
+		Reports: summaryReports{
+			JSONFile:             opts.jsonFile,
+			JSONFileTimingEvents: opts.jsonFileTimingEvents,
+			JUnitFile:            opts.junitFile,
+			XUnitFile:            opts.xunitFile,
+			SonarFile:            opts.sonarFile,
+			CircleCITimingsFile:  opts.circleCITimingsFile,
+			BESJSONFile:          opts.besJSONFile,
+			AllureResultsDir:     opts.allureResultsDir,
+			HistoryFile:          opts.historyFile,
+			CoverProfile:         coverprofile.ArgValue(opts.args),
+		},
+	}
+	if summary.ExitCode != 0 {
+		summary.Status = "failed"
+	}
+	if summary.Errors == nil {
+		summary.Errors = []string{}
+	}
+
+	for _, tc := range testjson.FilterFailedUnique(exec.Failed()) {
+		test := summaryTest{
+			Package:        tc.Package,
+			Test:           tc.Test.Name(),
+			ElapsedSeconds: seconds(tc.Elapsed),
+			Quarantined:    opts.quarantine.Contains(tc),
+		}
+		if tc.Test != "" && exec.Package(tc.Package).PassedOnRerun(tc) {
+			summary.Flaky = append(summary.Flaky, test)
+			continue
+		}
+		summary.Failures = append(summary.Failures, test)
+	}
+
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		summary.Packages = append(summary.Packages, summaryPackage{
+			Package:        name,
+			Result:         string(pkg.Result()),
+			ElapsedSeconds: seconds(pkg.Elapsed()),
+			Cached:         pkg.Cached(),
+			Coverage:       coveragePercent(pkg.Coverage()),
+		})
+	}
+
+	summary.Totals = summaryTotals{
+		Tests:   exec.Total(),
+		Failed:  len(exec.Failed()),
+		Skipped: len(exec.Skipped()),
+		Errors:  len(summary.Errors),
+		Flaky:   len(summary.Flaky),
+	}
+	return summary
+}
+
+// coveragePercent returns the percent from the coverage output of a package,
+// for example 91.1% from "coverage: 91.1% of statements".
+func coveragePercent(coverage string) string {
+	coverage = strings.TrimPrefix(coverage, "coverage: ")
+	percent, _, _ := strings.Cut(coverage, " ")
+	return percent
+}
+
+func seconds(d time.Duration) float64 {
+	return d.Round(time.Millisecond).Seconds()
+}
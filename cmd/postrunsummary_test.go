@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/quarantine"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestNewRunSummary(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/cart","Test":"TestAdd"}
+{"Action":"pass","Package":"example.com/cart","Test":"TestAdd","Elapsed":0.01}
+{"Action":"run","Package":"example.com/cart","Test":"TestCheckout"}
+{"Action":"fail","Package":"example.com/cart","Test":"TestCheckout","Elapsed":0.25}
+{"Action":"run","Package":"example.com/cart","Test":"TestRefund"}
+{"Action":"fail","Package":"example.com/cart","Test":"TestRefund","Elapsed":1.5}
+{"Action":"run","Package":"example.com/cart","Test":"TestSlow"}
+{"Action":"skip","Package":"example.com/cart","Test":"TestSlow"}
+{"Action":"output","Package":"example.com/cart","Output":"coverage: 81.3% of statements\n"}
+{"Action":"fail","Package":"example.com/cart","Elapsed":1.8}
+{"Action":"output","Package":"example.com/users","Output":"ok  \texample.com/users\t(cached)\tcoverage: 100.0% of statements\n"}
+{"Action":"pass","Package":"example.com/users","Elapsed":0}
+`
+	rerun := `{"Action":"run","Package":"example.com/cart","Test":"TestCheckout"}
+{"Action":"pass","Package":"example.com/cart","Test":"TestCheckout","Elapsed":0.2}
+{"Action":"pass","Package":"example.com/cart","Elapsed":0.3}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(input),
+		Stderr: strings.NewReader("go: warning: example.com/orders matched no packages\n"),
+	})
+	assert.NilError(t, err)
+	exec, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:    strings.NewReader(rerun),
+		Execution: exec,
+		RunID:     1,
+	})
+	assert.NilError(t, err)
+
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("quarantine.txt", "example.com/cart.TestRefund\n"))
+	quarantined, err := quarantine.Load(dir.Join("quarantine.txt"))
+	assert.NilError(t, err)
+	opts := &options{
+		args:       []string{"-coverprofile=cover.out", "./..."},
+		jsonFile:   "events.json",
+		junitFile:  "junit.xml",
+		quarantine: quarantined,
+	}
+	summary := newRunSummary(opts, exec, exitError{num: 1})
+	summary.ElapsedSeconds = 0
+
+	raw, err := json.MarshalIndent(summary, "", "  ")
+	assert.NilError(t, err)
+	golden.Assert(t, string(raw), "post-run-summary.json")
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+func setProcessGroup(*exec.Cmd) {}
+
+func signalProcessGroup(pid int, sig os.Signal) error {
+	return signalProcess(pid, sig)
+}
+
+func quitTestBinary(string, int) error {
+	return errors.New("sending SIGQUIT to a test binary is not supported on this platform")
+}
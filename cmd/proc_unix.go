@@ -0,0 +1,115 @@
+//go:build unix
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a new process group, so that the test
+// binaries started by go test can be stopped along with go test. When the
+// context of cmd is cancelled the entire process group is killed.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// signalProcessGroup sends the signal to every process in the process group
+// started by setProcessGroup.
+func signalProcessGroup(pid int, sig os.Signal) error {
+	return syscall.Kill(-pid, sig.(syscall.Signal))
+}
+
+// quitTestBinary sends SIGQUIT to the processes which run the test binary with
+// this file name, and were started by the process parent, directly or
+// indirectly. When parent is 0 the processes started by gotestsum are searched.
+// The processes are listed with ps.
+func quitTestBinary(name string, parent int) error {
+	out, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "ppid=", "-o", "args=").Output()
+	if err != nil {
+		return fmt.Errorf("failed to list processes: %w", err)
+	}
+	if parent == 0 {
+		parent = os.Getpid()
+	}
+	pids, err := findTestBinary(string(out), name, parent)
+	if err != nil {
+		return err
+	}
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, syscall.SIGQUIT); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findTestBinary returns the processes in the ps output which run the test
+// binary with this file name, and are descendants of parent. The descendants of
+// a matching process which run the same binary are also returned, because a test
+// may run its own binary in a subprocess.
+//
+// Packages with the same last import path element, like a/util and b/util, are
+// built into test binaries with the same file name. When more than one of those
+// binaries is running, an error is returned instead of stopping the wrong
+// package.
+func findTestBinary(ps string, name string, parent int) ([]int, error) {
+	children := make(map[int][]int)
+	binary := make(map[int]string)
+	for _, line := range strings.Split(ps, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		children[ppid] = append(children[ppid], pid)
+		binary[pid] = filepath.Base(fields[2])
+	}
+
+	type process struct {
+		pid int
+		// matched is true when an ancestor of the process runs the binary.
+		matched bool
+	}
+	var found []int
+	var binaries int
+	var pending []process
+	for _, pid := range children[parent] {
+		pending = append(pending, process{pid: pid})
+	}
+	for len(pending) > 0 {
+		proc := pending[0]
+		pending = pending[1:]
+		matched := proc.matched
+		if binary[proc.pid] == name {
+			found = append(found, proc.pid)
+			if !proc.matched {
+				binaries++
+			}
+			matched = true
+		}
+		for _, pid := range children[proc.pid] {
+			pending = append(pending, process{pid: pid, matched: matched})
+		}
+	}
+	switch {
+	case binaries == 0:
+		return nil, fmt.Errorf("no running process for %v", name)
+	case binaries > 1:
+		return nil, fmt.Errorf("%d packages with a test binary named %v are running, "+
+			"use --per-package to run each package in its own go test command", binaries, name)
+	}
+	return found, nil
+}
@@ -0,0 +1,101 @@
+//go:build unix
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestStartGoTest_CancelKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the child process stands in for a test binary started by go test. It
+	// inherits stdout, so stdout is only closed once the child exits.
+	proc, err := startGoTest(withProcessGroup(ctx), "", []string{"sh", "-c", "sleep 30 & echo started; wait"})
+	assert.NilError(t, err)
+
+	stdout := bufio.NewReader(proc.stdout)
+	line, err := stdout.ReadString('\n')
+	assert.NilError(t, err)
+	assert.Equal(t, line, "started\n")
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, stdout)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("child process was not killed")
+	}
+	assert.ErrorContains(t, proc.cmd.Wait(), "killed")
+}
+
+func TestStartGoTest_ProcessGroup(t *testing.T) {
+	run := func(t *testing.T, ctx context.Context) int {
+		t.Helper()
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		proc, err := startGoTest(ctx, "", []string{"sh", "-c", "echo started; exec sleep 30"})
+		assert.NilError(t, err)
+		line, err := bufio.NewReader(proc.stdout).ReadString('\n')
+		assert.NilError(t, err)
+		assert.Equal(t, line, "started\n")
+
+		pgid, err := syscall.Getpgid(proc.pid)
+		assert.NilError(t, err)
+		cancel()
+		_ = proc.cmd.Wait()
+		return pgid
+	}
+
+	t.Run("default", func(t *testing.T) {
+		// go test stays in the process group of the terminal, so that it can
+		// read from the terminal and receive Ctrl-Z.
+		assert.Equal(t, run(t, context.Background()), syscall.Getpgrp())
+	})
+	t.Run("with process group", func(t *testing.T) {
+		assert.Assert(t, run(t, withProcessGroup(context.Background())) != syscall.Getpgrp())
+	})
+}
+
+func TestFindTestBinary(t *testing.T) {
+	ps := `
+  1     0 /sbin/init
+ 10     1 gotestsum --per-package
+ 20    10 go test -json example.com/a/util
+ 21    20 /tmp/go-build1/b001/util.test -test.paniconexit0
+ 22    21 /tmp/go-build1/b001/util.test -test.run=TestHelperProcess
+ 30    10 go test -json example.com/b/util
+ 31    30 /tmp/go-build2/b001/util.test -test.paniconexit0
+ 40     1 /tmp/go-build3/b001/util.test
+`
+	t.Run("descendants of the go test command", func(t *testing.T) {
+		pids, err := findTestBinary(ps, "util.test", 20)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, pids, []int{21, 22})
+
+		pids, err = findTestBinary(ps, "util.test", 30)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, pids, []int{31})
+	})
+	t.Run("packages with the same name", func(t *testing.T) {
+		_, err := findTestBinary(ps, "util.test", 10)
+		assert.ErrorContains(t, err, "2 packages with a test binary named util.test are running")
+	})
+	t.Run("not found", func(t *testing.T) {
+		_, err := findTestBinary(ps, "other.test", 10)
+		assert.ErrorContains(t, err, "no running process for other.test")
+	})
+}
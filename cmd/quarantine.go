@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"gotest.tools/gotestsum/internal/quarantine"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// excludeQuarantined returns the test cases which are not in the quarantine
+// list.
+func excludeQuarantined(list *quarantine.List, tcs []testjson.TestCase) []testjson.TestCase {
+	if list == nil {
+		return tcs
+	}
+	var result []testjson.TestCase
+	for _, tc := range tcs {
+		if !list.Contains(tc) {
+			result = append(result, tc)
+		}
+	}
+	return result
+}
+
+// quarantineExitErr returns nil when the only reason for exitErr is the failure
+// of quarantined tests. Otherwise exitErr is returned unchanged.
+//
+// The exit code of go test is 1 for both test failures and build failures, so
+// the exit error is only ignored when there were no errors, and every package
+// that failed had a test which failed.
+func quarantineExitErr(opts *options, exec *testjson.Execution, exitErr error) error {
+	if opts.quarantine == nil || ExitCodeWithDefault(exitErr) != 1 {
+		return exitErr
+	}
+	if len(exec.Errors()) > 0 || exec.HasPanic() {
+		return exitErr
+	}
+	for _, name := range exec.Packages() {
+		if exec.Package(name).TestMainFailed() {
+			return exitErr
+		}
+	}
+	for _, tc := range testjson.FilterFailedUnique(exec.Failed()) {
+		if !opts.quarantine.Contains(tc) && !exec.Package(tc.Package).PassedOnRerun(tc) {
+			return exitErr
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/quarantine"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func newQuarantineList(t *testing.T, content string) *quarantine.List {
+	t.Helper()
+	file := fs.NewFile(t, t.Name(), fs.WithContent(content))
+	list, err := quarantine.Load(file.Path())
+	assert.NilError(t, err)
+	return list
+}
+
+func TestQuarantineExitErr(t *testing.T) {
+	exitErr := newExitCode("failed", 1)
+	buildErr := newExitCode("build failed", 2)
+	otherErr := errors.New("stopped")
+
+	type testCase struct {
+		quarantine string
+		exec       func(t *testing.T) *testjson.Execution
+		exitErr    error
+		expected   error
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		opts := &options{quarantine: newQuarantineList(t, tc.quarantine)}
+		if tc.exec == nil {
+			tc.exec = newExecutionWithTwoFailures
+		}
+		actual := quarantineExitErr(opts, tc.exec(t), tc.exitErr)
+		assert.Equal(t, actual, tc.expected)
+	}
+
+	testCases := map[string]testCase{
+		"all failures quarantined": {
+			quarantine: "pkg.TestOne\npkg.TestTwo\n",
+			exitErr:    exitErr,
+		},
+		"some failures not quarantined": {
+			quarantine: "pkg.TestOne\n",
+			exitErr:    exitErr,
+			expected:   exitErr,
+		},
+		"failure passed on rerun": {
+			quarantine: "pkg.TestOne\n",
+			exec:       newExecutionWithRerunPass("TestTwo"),
+			exitErr:    exitErr,
+		},
+		"unexpected exit code": {
+			quarantine: "pkg.TestOne\npkg.TestTwo\n",
+			exitErr:    buildErr,
+			expected:   buildErr,
+		},
+		"not an exit code": {
+			quarantine: "pkg.TestOne\npkg.TestTwo\n",
+			exitErr:    otherErr,
+			expected:   otherErr,
+		},
+		"execution has errors": {
+			quarantine: "pkg.TestOne\npkg.TestTwo\n",
+			exec:       newExecutionWithErrors,
+			exitErr:    exitErr,
+			expected:   exitErr,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func newExecutionWithRerunPass(name string) func(t *testing.T) *testjson.Execution {
+	return func(t *testing.T) *testjson.Execution {
+		t.Helper()
+		exec := newExecutionWithTwoFailures(t)
+		out := `{"Package": "pkg", "Action": "run"}
+{"Package": "pkg", "Test": "` + name + `", "Action": "run"}
+{"Package": "pkg", "Test": "` + name + `", "Action": "pass"}
+{"Package": "pkg", "Action": "pass"}
+`
+		_, err := testjson.ScanTestOutput(testjson.ScanConfig{
+			RunID:     1,
+			Stdout:    strings.NewReader(out),
+			Stderr:    strings.NewReader(""),
+			Execution: exec,
+		})
+		assert.NilError(t, err)
+		return exec
+	}
+}
+
+func newExecutionWithErrors(t *testing.T) *testjson.Execution {
+	t.Helper()
+	out := `{"Package": "pkg", "Action": "run"}
+{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+{"Package": "pkg", "Test": "TestOne", "Action": "fail"}
+{"Package": "pkg", "Action": "fail"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(out),
+		Stderr: strings.NewReader("# pkg/other\nother.go:3:1: syntax error\n"),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestQuarantineExitErr_NoQuarantineFile(t *testing.T) {
+	exitErr := newExitCode("failed", 1)
+	actual := quarantineExitErr(&options{}, newExecutionWithTwoFailures(t), exitErr)
+	assert.Equal(t, actual, exitErr)
+}
+
+func TestRerunFailsFilter_ExcludesQuarantined(t *testing.T) {
+	opts := &options{quarantine: newQuarantineList(t, "pkg.TestOne\n")}
+	exec := newExecutionWithTwoFailures(t)
+
+	actual := rerunFailsFilter(opts)(exec.Failed())
+	assert.Equal(t, len(actual), 1)
+	assert.Equal(t, actual[0].Test.Name(), "TestTwo")
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// checkDurationRegressions compares the elapsed time of tests in exec to the
+// baseline jsonfiles, or to the runs in the --history-file when there is no
+// baseline, and prints any tests which were slower than the threshold. An
+// error is returned if any regressions were found and --duration-regression-fail
+// is set.
+func checkDurationRegressions(opts *options, exec *testjson.Execution) error {
+	threshold := opts.durationRegressionThreshold.Value()
+	if threshold == 0 {
+		return nil
+	}
+
+	var regressions []aggregate.Regression
+	switch {
+	case opts.durationRegressionBaseline != "":
+		baseline, err := loadBaselineExecution(opts.durationRegressionBaseline)
+		if err != nil {
+			return fmt.Errorf("failed to load duration regression baseline: %w", err)
+		}
+		regressions = aggregate.DurationRegressions(
+			baseline, exec, threshold, opts.durationRegressionMinElapsed)
+	case opts.historyFile != "":
+		runs, err := history.Read(opts.historyFile)
+		switch {
+		case os.IsNotExist(err):
+			log.Debugf("no duration regression baseline, %v does not exist", opts.historyFile)
+			return nil
+		case err != nil:
+			return fmt.Errorf("failed to load duration regression baseline: %w", err)
+		}
+		log.Debugf("loaded %d baseline runs from %v", len(runs), opts.historyFile)
+		regressions = aggregate.MedianRegressions(
+			history.TestElapsed(runs), exec, threshold, opts.durationRegressionMinElapsed)
+	default:
+		return nil
+	}
+	writeDurationRegressions(opts.stdout, regressions)
+
+	if len(regressions) > 0 && opts.durationRegressionFail {
+		return fmt.Errorf("%d tests exceeded the duration regression threshold of %s",
+			len(regressions), opts.durationRegressionThreshold)
+	}
+	return nil
+}
+
+// loadBaselineExecution scans all the jsonfiles matched by pattern into a
+// single Execution.
+func loadBaselineExecution(pattern string) (*testjson.Execution, error) {
+	fileNames, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(fileNames) == 0 {
+		return nil, fmt.Errorf("no files match %v", pattern)
+	}
+
+	var exec *testjson.Execution
+	for _, fileName := range fileNames {
+		exec, err = jsonfile.Scan(fileName, testjson.ScanConfig{Execution: exec})
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Debugf("loaded %d baseline files from %v", len(fileNames), pattern)
+	return exec, nil
+}
+
+func writeDurationRegressions(out io.Writer, regressions []aggregate.Regression) {
+	if len(regressions) == 0 {
+		return
+	}
+	fmt.Fprintln(out, color.MagentaString("\n=== Duration regressions"))
+	for _, r := range regressions {
+		fmt.Fprintf(out, "=== %s: %s %s %s (median %s, +%.0f%%)\n",
+			color.MagentaString("SLOW"),
+			testjson.RelativePackagePath(r.Package),
+			r.Test,
+			testjson.FormatDurationAsSeconds(r.Elapsed, 2),
+			testjson.FormatDurationAsSeconds(r.Baseline, 2),
+			r.Change()*100)
+	}
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestCheckDurationRegressions(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("run-1.json", `{"Package":"pkg","Test":"TestOne","Action":"run"}
+{"Package":"pkg","Test":"TestOne","Action":"pass","Elapsed":1.0}
+{"Package":"pkg","Test":"TestTwo","Action":"run"}
+{"Package":"pkg","Test":"TestTwo","Action":"pass","Elapsed":1.0}
+`),
+		fs.WithFile("run-2.json", `{"Package":"pkg","Test":"TestOne","Action":"run"}
+{"Package":"pkg","Test":"TestOne","Action":"pass","Elapsed":1.2}
+{"Package":"pkg","Test":"TestTwo","Action":"run"}
+{"Package":"pkg","Test":"TestTwo","Action":"pass","Elapsed":1.0}
+`))
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Package":"pkg","Test":"TestOne","Action":"run"}
+{"Package":"pkg","Test":"TestOne","Action":"pass","Elapsed":3.0}
+{"Package":"pkg","Test":"TestTwo","Action":"run"}
+{"Package":"pkg","Test":"TestTwo","Action":"pass","Elapsed":1.1}
+`),
+	})
+	assert.NilError(t, err)
+
+	newOpts := func(fail bool) (*options, *bytes.Buffer) {
+		threshold := &percentValue{}
+		assert.NilError(t, threshold.Set("50%"))
+		out := new(bytes.Buffer)
+		return &options{
+			durationRegressionThreshold:  threshold,
+			durationRegressionBaseline:   filepath.Join(dir.Path(), "*.json"),
+			durationRegressionMinElapsed: 10 * time.Millisecond,
+			durationRegressionFail:       fail,
+			stdout:                       out,
+		}, out
+	}
+
+	t.Run("warn", func(t *testing.T) {
+		opts, out := newOpts(false)
+		assert.NilError(t, checkDurationRegressions(opts, exec))
+		expected := `
+=== Duration regressions
+=== SLOW: pkg TestOne 3.00s (median 1.20s, +150%)
+`
+		assert.Equal(t, out.String(), expected)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		opts, _ := newOpts(true)
+		err := checkDurationRegressions(opts, exec)
+		assert.Error(t, err, "1 tests exceeded the duration regression threshold of 50%")
+	})
+
+	t.Run("missing baseline", func(t *testing.T) {
+		opts, _ := newOpts(false)
+		opts.durationRegressionBaseline = filepath.Join(dir.Path(), "missing-*.json")
+		err := checkDurationRegressions(opts, exec)
+		assert.ErrorContains(t, err, "no files match")
+	})
+}
+
+func TestCheckDurationRegressions_HistoryFile(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("history.jsonl", `{"run_id":"1","package":"pkg","test":"TestOne","outcome":"pass","elapsed_seconds":1.0,"attempt":1}
+{"run_id":"1","package":"pkg","test":"TestTwo","outcome":"pass","elapsed_seconds":1.0,"attempt":1}
+{"run_id":"2","package":"pkg","test":"TestOne","outcome":"pass","elapsed_seconds":1.2,"attempt":1}
+{"run_id":"2","package":"pkg","test":"TestTwo","outcome":"pass","elapsed_seconds":1.0,"attempt":1}
+`))
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Package":"pkg","Test":"TestOne","Action":"run"}
+{"Package":"pkg","Test":"TestOne","Action":"pass","Elapsed":3.0}
+{"Package":"pkg","Test":"TestTwo","Action":"run"}
+{"Package":"pkg","Test":"TestTwo","Action":"pass","Elapsed":1.1}
+`),
+	})
+	assert.NilError(t, err)
+
+	threshold := &percentValue{}
+	assert.NilError(t, threshold.Set("50%"))
+	out := new(bytes.Buffer)
+	opts := &options{
+		durationRegressionThreshold:  threshold,
+		durationRegressionMinElapsed: 10 * time.Millisecond,
+		historyFile:                  dir.Join("history.jsonl"),
+		stdout:                       out,
+	}
+	assert.NilError(t, checkDurationRegressions(opts, exec))
+	expected := `
+=== Duration regressions
+=== SLOW: pkg TestOne 3.00s (median 1.20s, +150%)
+`
+	assert.Equal(t, out.String(), expected)
+
+	t.Run("first run", func(t *testing.T) {
+		out.Reset()
+		opts.historyFile = dir.Join("missing.jsonl")
+		assert.NilError(t, checkDurationRegressions(opts, exec))
+		assert.Equal(t, out.String(), "")
+	})
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gotest.tools/gotestsum/internal/coverprofile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// attemptArtifacts writes the output of one attempt of a run with
+// --rerun-fails to a directory named attempt-N in --rerun-fails-attempts-dir.
+// The first run of the tests is attempt-1, and each rerun is the next attempt.
+//
+// A nil attemptArtifacts is valid, and does nothing.
+type attemptArtifacts struct {
+	dir    string
+	json   *os.File
+	output *os.File
+}
+
+func newAttemptArtifacts(opts *options, attempt int) (*attemptArtifacts, error) {
+	if opts.rerunFailsAttemptsDir == "" || opts.rerunFailsMaxAttempts == 0 {
+		return nil, nil
+	}
+	dir := filepath.Join(opts.rerunFailsAttemptsDir, fmt.Sprintf("attempt-%d", attempt))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create attempt directory: %w", err)
+	}
+	a := &attemptArtifacts{dir: dir}
+	var err error
+	if a.json, err = os.Create(filepath.Join(dir, "test.json")); err != nil {
+		return nil, fmt.Errorf("failed to create attempt jsonfile: %w", err)
+	}
+	if a.output, err = os.Create(filepath.Join(dir, "output.log")); err != nil {
+		_ = a.json.Close()
+		return nil, fmt.Errorf("failed to create attempt output file: %w", err)
+	}
+	return a, nil
+}
+
+// Handler returns an EventHandler which writes each event to test.json, and
+// the output of tests and the stderr of go test to output.log, before calling
+// handler.
+func (a *attemptArtifacts) Handler(handler testjson.EventHandler) testjson.EventHandler {
+	if a == nil {
+		return handler
+	}
+	return &attemptHandler{EventHandler: handler, artifacts: a}
+}
+
+// MergeCoverProfile merges the cover profile at path into the coverprofile.out
+// of the attempt.
+func (a *attemptArtifacts) MergeCoverProfile(path string) {
+	if a == nil || path == "" {
+		return
+	}
+	if err := coverprofile.MergeRerun(filepath.Join(a.dir, "coverprofile.out"), path); err != nil {
+		log.Warnf("failed to write cover profile of %v: %v", a.dir, err)
+	}
+}
+
+func (a *attemptArtifacts) Close() {
+	if a == nil {
+		return
+	}
+	for _, fh := range []*os.File{a.json, a.output} {
+		if err := fh.Close(); err != nil {
+			log.Errorf("Failed to close %v: %v", fh.Name(), err)
+		}
+	}
+}
+
+type attemptHandler struct {
+	testjson.EventHandler
+	artifacts *attemptArtifacts
+}
+
+func (h *attemptHandler) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	if err := writeWithNewline(h.artifacts.json, event.Bytes()); err != nil {
+		return fmt.Errorf("failed to write attempt jsonfile: %w", err)
+	}
+	if event.Action == testjson.ActionOutput {
+		if _, err := h.artifacts.output.WriteString(event.Output); err != nil {
+			return fmt.Errorf("failed to write attempt output file: %w", err)
+		}
+	}
+	return h.EventHandler.Event(event, execution)
+}
+
+func (h *attemptHandler) Err(text string) error {
+	_, _ = h.artifacts.output.WriteString(text + "\n")
+	return h.EventHandler.Err(text)
+}
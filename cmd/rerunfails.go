@@ -1,12 +1,21 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"gotest.tools/gotestsum/internal/coverprofile"
 	"gotest.tools/gotestsum/internal/log"
@@ -17,6 +26,10 @@ type rerunOpts struct {
 	runFlag         string
 	pkg             string
 	coverProfileArg string
+	race            bool
+	// extraArgs are added to the go test command after the original args, so
+	// that they replace the value of any flags in the original args.
+	extraArgs []string
 }
 
 func (o rerunOpts) Args() []string {
@@ -39,7 +52,48 @@ func newRerunOptsFromTestCase(tc testjson.TestCase) rerunOpts {
 
 type testCaseFilter func([]testjson.TestCase) []testjson.TestCase
 
+// rerunFailsFilter returns a filter for the failures which should be rerun.
+// Quarantined tests are never rerun.
 func rerunFailsFilter(o *options) testCaseFilter {
+	filter := failuresToRerun(o)
+	if o.rerunFailsPkgErrors.Value() != packageErrorsAbort {
+		// package errors are handled by the --rerun-fails-package-errors policy
+		filter = excludePackageErrors(filter)
+	}
+	if o.quarantine == nil {
+		return filter
+	}
+	return func(tcs []testjson.TestCase) []testjson.TestCase {
+		return filter(excludeQuarantined(o.quarantine, tcs))
+	}
+}
+
+func excludePackageErrors(filter testCaseFilter) testCaseFilter {
+	return func(tcs []testjson.TestCase) []testjson.TestCase {
+		var result []testjson.TestCase
+		for _, tc := range tcs {
+			if tc.Test != "" {
+				result = append(result, tc)
+			}
+		}
+		return filter(result)
+	}
+}
+
+func failuresToRerun(o *options) testCaseFilter {
+	if o.rerunFailsRunPackage {
+		return func(tcs []testjson.TestCase) []testjson.TestCase {
+			var result []testjson.TestCase
+			seen := make(map[string]bool)
+			for _, tc := range tcs {
+				if !seen[tc.Package] {
+					seen[tc.Package] = true
+					result = append(result, tc)
+				}
+			}
+			return result
+		}
+	}
 	if o.rerunFailsRunRootCases {
 		return func(tcs []testjson.TestCase) []testjson.TestCase {
 			var result []testjson.TestCase
@@ -54,82 +108,461 @@ func rerunFailsFilter(o *options) testCaseFilter {
 	return testjson.FilterFailedUnique
 }
 
-func rerunFailed(ctx context.Context, opts *options, scanConfig testjson.ScanConfig) error {
+func rerunFailed(ctx context.Context, opts *options, scanConfig testjson.ScanConfig, env ...string) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	tcFilter := rerunFailsFilter(opts)
+	race := rerunWithRace(opts)
 
-	originalCoverProfile := coverprofile.ArgValue(opts.args)
+	coverProfiles, err := newTempCoverProfiles(coverprofile.ArgValue(opts.args), "rerun")
+	if err != nil {
+		return err
+	}
+	defer coverProfiles.Close()
 
-	rec := newFailureRecorderFromExecution(scanConfig.Execution)
-	for attempts := 0; rec.count() > 0 && attempts < opts.rerunFailsMaxAttempts; attempts++ {
-		testjson.PrintSummary(opts.stdout, scanConfig.Execution, testjson.SummarizeNone)
-		opts.stdout.Write([]byte("\n")) //nolint:errcheck
+	var deadline time.Time
+	if opts.rerunFailsMaxTime > 0 {
+		deadline = time.Now().Add(opts.rerunFailsMaxTime)
+	}
+	pkgErrs := packageErrors(scanConfig.Execution)
 
-		nextRec := newFailureRecorder(scanConfig.Handler)
-		for _, tc := range tcFilter(rec.failures) {
-			rerunTC := newRerunOptsFromTestCase(tc)
+	// launch starts the go test command for rerunTC.
+	launch := func(ctx context.Context, rerunTC rerunOpts) (*proc, error) {
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("rerun stopped because reruns exceeded the time (%v) set by --rerun-fails-max-time",
+				opts.rerunFailsMaxTime)
+		}
+		sandboxEnv, err := opts.packageSandboxes.Prepare(rerunTC.pkg)
+		if err != nil {
+			return nil, err
+		}
+		return startGoTestFn(ctx, "", goTestCmdArgs(opts, rerunTC), append(slices.Clip(env), sandboxEnv...)...)
+	}
+	prepare := func(rerunTC *rerunOpts) {
+		rerunTC.coverProfileArg = coverProfiles.Next()
+		rerunTC.extraArgs = append(opts.packageSandboxes.Args(), opts.rerunFailsExtraArgs...)
+	}
+
+	// finish scans the output of goTestProc, and records the failures in rec.
+	finish := func(goTestProc *proc, rerunTC rerunOpts, runID int, rec *failureRecorder, artifacts *attemptArtifacts) error {
+		cfg := testjson.ScanConfig{
+			RunID:                       runID,
+			Stdout:                      goTestStdout(opts, goTestProc.stdout),
+			Stderr:                      goTestProc.stderr,
+			Handler:                     rec,
+			Execution:                   scanConfig.Execution,
+			Stop:                        cancel,
+			IgnoreNonJSONOutputLines:    opts.ignoreNonJSONOutputLines,
+			AttributeNonJSONOutputLines: true,
+		}
+		if _, err := testjson.ScanTestOutput(cfg); err != nil {
+			return err
+		}
+		exitErr := goTestProc.cmd.Wait()
+		if exitErr != nil {
+			rec.lastErr = exitErr
+		}
+
+		artifacts.MergeCoverProfile(rerunTC.coverProfileArg)
+		coverProfiles.Merge(ctx, rerunTC.coverProfileArg)
 
-			var tmpCoverProfile string
-			if originalCoverProfile != "" {
-				tmpFile, err := os.CreateTemp("", "gotestsum-rerun-cover-*.out")
-				if err != nil {
-					return fmt.Errorf("create temp cover profile: %w", err)
+		return hasErrors(exitErr, scanConfig.Execution, opts, pkgErrs)
+	}
+
+	// rerun runs go test for each of rerunTCs, and returns a failureRecorder
+	// with the failures.
+	rerun := func(rerunTCs []rerunOpts, runID int) (*failureRecorder, error) {
+		artifacts, err := newAttemptArtifacts(opts, runID+1)
+		if err != nil {
+			return nil, err
+		}
+		defer artifacts.Close()
+		rec := newFailureRecorder(artifacts.Handler(scanConfig.Handler))
+
+		if opts.rerunFailsParallel > 1 {
+			for i := range rerunTCs {
+				prepare(&rerunTCs[i])
+			}
+			// stop starting commands, and stop the running ones, when a
+			// command fails, and wait for them before returning.
+			bufCtx, stop := context.WithCancel(ctx)
+			results, wait := startBuffered(bufCtx, opts.rerunFailsParallel, rerunTCs, launch)
+			defer wait()
+			defer stop()
+			for i, rerunTC := range rerunTCs {
+				result := <-results[i]
+				if result.err != nil {
+					return nil, result.err
+				}
+				if err := finish(result.proc, rerunTC, runID, rec, artifacts); err != nil {
+					return nil, err
 				}
-				tmpCoverProfile = tmpFile.Name()
-				_ = tmpFile.Close()
-				defer func() { _ = os.Remove(tmpCoverProfile) }() //nolint:gocritic
-				rerunTC.coverProfileArg = tmpCoverProfile
 			}
+			return rec, nil
+		}
 
-			goTestProc, err := startGoTestFn(ctx, "", goTestCmdArgs(opts, rerunTC))
+		for _, rerunTC := range rerunTCs {
+			prepare(&rerunTC)
+			goTestProc, err := launch(ctx, rerunTC)
 			if err != nil {
-				return err
+				return nil, err
+			}
+			if err := finish(goTestProc, rerunTC, runID, rec, artifacts); err != nil {
+				return nil, err
 			}
+		}
+		return rec, nil
+	}
 
-			cfg := testjson.ScanConfig{
-				RunID:     attempts + 1,
-				Stdout:    goTestProc.stdout,
-				Stderr:    goTestProc.stderr,
-				Handler:   nextRec,
-				Execution: scanConfig.Execution,
-				Stop:      cancel,
+	runID := 0
+	if opts.rerunFailsPkgErrors.Value() == packageErrorsRetry {
+		for attempts := 0; attempts < opts.rerunFailsPkgErrors.attempts; attempts++ {
+			pkgs := unresolvedPackageErrors(scanConfig.Execution, pkgErrs)
+			if len(pkgs) == 0 {
+				break
 			}
-			if _, err := testjson.ScanTestOutput(cfg); err != nil {
+			if err := sleepFn(ctx, opts.rerunFailsDelay.Delay(attempts)); err != nil {
 				return err
 			}
-			exitErr := goTestProc.cmd.Wait()
-			if exitErr != nil {
-				nextRec.lastErr = exitErr
+			testjson.PrintSummary(opts.stdout, scanConfig.Execution, testjson.SummarizeNone)
+			opts.stdout.Write([]byte("\n")) //nolint:errcheck
+
+			runID++
+			// failures in a retried package are rerun by the loop below
+			rerunTCs := make([]rerunOpts, 0, len(pkgs))
+			for _, pkg := range pkgs {
+				rerunTCs = append(rerunTCs, rerunOpts{pkg: pkg})
 			}
-
-			if tmpCoverProfile != "" {
-				if mergeErr := coverprofile.MergeRerun(originalCoverProfile, tmpCoverProfile); mergeErr != nil {
-					log.Debugf("failed to merge rerun cover profile: %v", mergeErr)
-				}
-				_ = os.Remove(tmpCoverProfile)
+			if _, err := rerun(rerunTCs, runID); err != nil {
+				return err
 			}
+		}
+	}
 
-			if err := hasErrors(exitErr, scanConfig.Execution, opts); err != nil {
-				return err
+	rec := newFailureRecorderFromExecution(scanConfig.Execution)
+	for attempts := 0; len(tcFilter(rec.failures)) > 0 && attempts < opts.rerunFailsMaxAttempts; attempts++ {
+		failures := excludeQuarantined(opts.quarantine, rec.failures)
+		if err := checkRerunOutputPatterns(opts, scanConfig.Execution, failures); err != nil {
+			return err
+		}
+		if err := sleepFn(ctx, opts.rerunFailsDelay.Delay(attempts)); err != nil {
+			return err
+		}
+		testjson.PrintSummary(opts.stdout, scanConfig.Execution, testjson.SummarizeNone)
+		opts.stdout.Write([]byte("\n")) //nolint:errcheck
+
+		runID++
+		var rerunTCs []rerunOpts
+		for _, tc := range tcFilter(rec.failures) {
+			rerunTC := newRerunOptsFromTestCase(tc)
+			if opts.rerunFailsRunPackage {
+				rerunTC.runFlag = ""
 			}
+			rerunTC.race = race && attempts == 0
+			rerunTCs = append(rerunTCs, rerunTC)
+		}
+		nextRec, err := rerun(rerunTCs, runID)
+		if err != nil {
+			return err
 		}
 		rec = nextRec
 	}
+
+	if opts.rerunFailsPkgErrors.Value() != packageErrorsAbort {
+		if pkgs := unresolvedPackageErrors(scanConfig.Execution, pkgErrs); len(pkgs) > 0 {
+			return fmt.Errorf("tests were not rerun in %d packages which failed to build, or failed in TestMain: %v",
+				len(pkgs), strings.Join(pkgs, ", "))
+		}
+	}
 	return rec.lastErr
 }
 
+type bufferedResult struct {
+	proc *proc
+	err  error
+}
+
+// startBuffered starts a go test command for each of rerunTCs, with at most
+// parallel commands running at the same time. Commands for the same package
+// are run one at a time, because the tests in a package may not be safe to run
+// at the same time. The output of each command is buffered until it exits,
+// because the Execution can only be updated by one scanner at a time.
+//
+// The result of rerunTCs[i] is sent to the i-th channel of the returned slice.
+// No more commands are started once ctx is cancelled. The returned func waits
+// for the goroutines which start the commands to return.
+func startBuffered(
+	ctx context.Context,
+	parallel int,
+	rerunTCs []rerunOpts,
+	launch func(context.Context, rerunOpts) (*proc, error),
+) ([]chan bufferedResult, func()) {
+	results := make([]chan bufferedResult, len(rerunTCs))
+	byPackage := make(map[string][]int)
+	var pkgs []string
+	for i, rerunTC := range rerunTCs {
+		results[i] = make(chan bufferedResult, 1)
+		if _, ok := byPackage[rerunTC.pkg]; !ok {
+			pkgs = append(pkgs, rerunTC.pkg)
+		}
+		byPackage[rerunTC.pkg] = append(byPackage[rerunTC.pkg], i)
+	}
+
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for _, pkg := range pkgs {
+		wg.Add(1)
+		go func(indexes []int) {
+			defer wg.Done()
+			for _, i := range indexes {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					results[i] <- bufferedResult{err: ctx.Err()}
+					continue
+				}
+				if err := ctx.Err(); err != nil {
+					<-sem
+					results[i] <- bufferedResult{err: err}
+					continue
+				}
+				goTestProc, err := launch(ctx, rerunTCs[i])
+				if err == nil {
+					goTestProc = bufferOutput(goTestProc)
+				}
+				<-sem
+				results[i] <- bufferedResult{proc: goTestProc, err: err}
+			}
+		}(byPackage[pkg])
+	}
+	return results, wg.Wait
+}
+
+// bufferOutput reads all the output of goTestProc, and waits for it to exit.
+// The returned proc replays the output and the exit error.
+func bufferOutput(goTestProc *proc) *proc {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(stderr, goTestProc.stderr)
+		close(done)
+	}()
+	_, _ = io.Copy(stdout, goTestProc.stdout)
+	<-done
+	return &proc{
+		cmd:    exitedWaiter{err: goTestProc.cmd.Wait()},
+		stdout: stdout,
+		stderr: stderr,
+	}
+}
+
+// exitedWaiter is the waiter of a command which has already exited.
+type exitedWaiter struct {
+	err error
+}
+
+func (w exitedWaiter) Wait() error {
+	return w.err
+}
+
+// checkRerunOutputPatterns returns an error if the output of any of the
+// failures shows that the failure should not be rerun. Failures are rerun only
+// when the output of every failure matches a --rerun-fails-if-output-matches
+// pattern, and does not match any --rerun-fails-unless-output-matches pattern.
+// When any failure is not rerun the run will fail, so there is no reason to
+// rerun the other failures.
+func checkRerunOutputPatterns(opts *options, exec *testjson.Execution, failures []testjson.TestCase) error {
+	if len(opts.rerunFailsIfOutput) == 0 && len(opts.rerunFailsUnlessOutput) == 0 {
+		return nil
+	}
+	for _, tc := range testjson.FilterFailedUnique(failures) {
+		output := strings.Join(exec.OutputLines(tc), "")
+		name := tc.Package + "." + tc.Test.Name()
+		if len(opts.rerunFailsIfOutput) > 0 && !matchesAny(opts.rerunFailsIfOutput, output) {
+			return fmt.Errorf("rerun skipped because the output of %v does not match --rerun-fails-if-output-matches", name)
+		}
+		if matchesAny(opts.rerunFailsUnlessOutput, output) {
+			return fmt.Errorf("rerun skipped because the output of %v matches --rerun-fails-unless-output-matches", name)
+		}
+	}
+	return nil
+}
+
+func matchesAny(patterns []*regexp.Regexp, output string) bool {
+	for _, re := range patterns {
+		if re.MatchString(output) {
+			return true
+		}
+	}
+	return false
+}
+
+// rerunWithRace returns true if the first rerun should use -race. Reruns of a
+// raw command can not add flags to go test, and there is no reason to add -race
+// when the original run already used it.
+func rerunWithRace(opts *options) bool {
+	return opts.rerunFailsRace && !opts.rawCommand && boolArgIndex("race", opts.args) < 0
+}
+
+// tempCoverProfiles manages the cover profiles written by go test commands
+// which must not overwrite the cover profile set by -coverprofile, like reruns,
+// or the commands run for each group of --per-package. Each command writes to a
+// unique file in a temporary directory, which is merged into the original cover
+// profile after the command exits. The temporary directory is removed by Close,
+// so that no files are left behind on any exit path.
+//
+// A nil tempCoverProfiles is valid, and does nothing.
+type tempCoverProfiles struct {
+	original string
+	// name of the commands, used in file names and warnings.
+	name string
+	dir  string
+
+	mu    sync.Mutex // guards count, and merging into original
+	count int
+}
+
+func newTempCoverProfiles(original, name string) (*tempCoverProfiles, error) {
+	if original == "" {
+		return nil, nil
+	}
+	dir, err := os.MkdirTemp("", "gotestsum-"+name+"-cover-")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir for %v cover profiles: %w", name, err)
+	}
+	return &tempCoverProfiles{original: original, name: name, dir: dir}, nil
+}
+
+// Next returns the path of a new cover profile for the next command.
+func (c *tempCoverProfiles) Next() string {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count++
+	return filepath.Join(c.dir, fmt.Sprintf("%v-%d.out", c.name, c.count))
+}
+
+// Merge the cover profile at path into the original cover profile, and remove
+// the file at path. If ctx was cancelled the command may have been interrupted
+// while writing the profile, so it is not merged.
+func (c *tempCoverProfiles) Merge(ctx context.Context, path string) {
+	if c == nil {
+		return
+	}
+	defer os.Remove(path) //nolint:errcheck
+	if ctx.Err() != nil {
+		log.Warnf("%[1]v was interrupted, cover profile from the %[1]v was not merged", c.name)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := coverprofile.MergeRerun(c.original, path); err != nil {
+		log.Warnf("failed to merge %v cover profile: %v", c.name, err)
+	}
+}
+
+// Close removes the temporary directory, and any cover profiles that were not
+// merged.
+func (c *tempCoverProfiles) Close() {
+	if c == nil {
+		return
+	}
+	if err := os.RemoveAll(c.dir); err != nil {
+		log.Warnf("failed to remove %v cover profiles: %v", c.name, err)
+	}
+}
+
 // startGoTestFn is a shim for testing
 var startGoTestFn = startGoTest
 
-func hasErrors(err error, exec *testjson.Execution, opts *options) error {
+// sleepFn is a shim for testing
+var sleepFn = sleep
+
+// sleep waits for the duration, or until ctx is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	log.Debugf("waiting %v before the next rerun", d)
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// flakyExitCode is the exit code used when --rerun-fails-fail-on-flaky is set,
+// and a test only passed when it was rerun.
+const flakyExitCode = 4
+
+// checkFlakyTests returns an error when --rerun-fails-fail-on-flaky is set and
+// any test failed, but passed when it was rerun.
+func checkFlakyTests(opts *options, exec *testjson.Execution) error {
+	if !opts.rerunFailsFailOnFlaky || opts.rerunFailsMaxAttempts == 0 {
+		return nil
+	}
+	var flaky []string
+	for _, tc := range testjson.FilterFailedUnique(exec.Failed()) {
+		if exec.Package(tc.Package).PassedOnRerun(tc) {
+			flaky = append(flaky, tc.Package+"."+tc.Test.Name())
+		}
+	}
+	if len(flaky) == 0 {
+		return nil
+	}
+	log.Errorf("%d tests only passed when they were rerun, and --rerun-fails-fail-on-flaky is set: %v",
+		len(flaky), strings.Join(flaky, ", "))
+	return exitError{num: flakyExitCode}
+}
+
+// hasPackageErrorPolicy returns true if any package failed to build, or failed
+// in TestMain, and the --rerun-fails-package-errors policy handles them.
+func hasPackageErrorPolicy(opts *options, exec *testjson.Execution) bool {
+	return opts.rerunFailsPkgErrors.Value() != packageErrorsAbort && len(packageErrors(exec)) > 0
+}
+
+// packageErrors returns the packages which failed without any failed tests,
+// because the package failed to build, or TestMain or an init function failed.
+func packageErrors(exec *testjson.Execution) map[string]bool {
+	result := make(map[string]bool)
+	for _, name := range exec.Packages() {
+		if exec.Package(name).TestMainFailed() {
+			result[name] = true
+		}
+	}
+	return result
+}
+
+// unresolvedPackageErrors returns the sorted names of the packages in pkgErrs
+// which still fail without any failed tests.
+func unresolvedPackageErrors(exec *testjson.Execution, pkgErrs map[string]bool) []string {
+	var result []string
+	for name := range pkgErrs {
+		if exec.Package(name).TestMainFailed() {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// hasErrors returns an error if failed tests should not be rerun. Unless the
+// --rerun-fails-package-errors policy is abort, errors and panics from the
+// packages in pkgErrs are handled by the policy, and do not stop the rerun.
+func hasErrors(err error, exec *testjson.Execution, opts *options, pkgErrs map[string]bool) error {
+	handled := opts.rerunFailsPkgErrors.Value() != packageErrorsAbort
 	switch {
-	case len(exec.Errors()) > 0:
+	case len(exec.Errors()) > 0 && !handled:
 		return fmt.Errorf("rerun aborted because previous run had errors")
 	// Exit code 0 and 1 are expected.
 	case ExitCodeWithDefault(err) > 1:
 		return fmt.Errorf("unexpected go test exit code: %v", err)
-	case exec.HasPanic():
+	case hasPanic(exec, handled, pkgErrs):
 		return fmt.Errorf("rerun aborted because previous run had a suspected panic and some test may not have run")
 	case exec.HasDataRace() && opts.rerunFailsMaxAttempts > 0 && opts.rerunFailsAbortOnDataRace:
 		return fmt.Errorf("rerun aborted because previous run had a data race")
@@ -138,6 +571,18 @@ func hasErrors(err error, exec *testjson.Execution, opts *options) error {
 	}
 }
 
+func hasPanic(exec *testjson.Execution, handled bool, pkgErrs map[string]bool) bool {
+	if !handled {
+		return exec.HasPanic()
+	}
+	for _, name := range exec.Packages() {
+		if exec.Package(name).HasPanic() && !pkgErrs[name] {
+			return true
+		}
+	}
+	return false
+}
+
 type failureRecorder struct {
 	testjson.EventHandler
 	failures []testjson.TestCase
@@ -161,10 +606,6 @@ func (r *failureRecorder) Event(event testjson.TestEvent, execution *testjson.Ex
 	return r.EventHandler.Event(event, execution)
 }
 
-func (r *failureRecorder) count() int {
-	return len(r.failures)
-}
-
 func goTestRunFlagForTestCase(test testjson.TestName) string {
 	if test.IsSubTest() {
 		parts := strings.Split(string(test), "/")
@@ -187,52 +628,133 @@ func writeRerunFailsReport(opts *options, exec *testjson.Execution) error {
 	if opts.rerunFailsMaxAttempts == 0 || opts.rerunFailsReportFile == "" {
 		return nil
 	}
+	report := newRerunReport(exec)
+
+	fh, err := os.Create(opts.rerunFailsReportFile)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = fh.Close()
+	}()
+
+	if opts.rerunFailsReportFormat == "json" {
+		enc := json.NewEncoder(fh)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	for _, tc := range report.Tests {
+		fmt.Fprintf(fh, "%s.%s: %d runs, %d failures", tc.Package, tc.Test, tc.Runs, tc.Failures)
+		if tc.DataRace {
+			fmt.Fprint(fh, ", data race detected")
+		}
+		fmt.Fprintln(fh)
+	}
+	return nil
+}
+
+// rerunReport is the JSON document written by --rerun-fails-report when
+// --rerun-fails-report-format=json.
+type rerunReport struct {
+	Tests []rerunReportTest `json:"tests"`
+}
+
+type rerunReportTest struct {
+	Package  string `json:"package"`
+	Test     string `json:"test"`
+	Runs     int    `json:"runs"`
+	Failures int    `json:"failures"`
+	// Result of the last attempt.
+	Result   string               `json:"result"`
+	DataRace bool                 `json:"dataRace"`
+	Attempts []rerunReportAttempt `json:"attempts"`
+}
 
-	type testCaseCounts struct {
-		total  int
-		failed int
+type rerunReportAttempt struct {
+	Result string `json:"result"`
+	// Elapsed time of the attempt in seconds.
+	Elapsed float64 `json:"elapsed"`
+	// OutputHash is the sha256 of the output of a failed attempt. Failures
+	// with the same hash most likely failed for the same reason.
+	OutputHash string `json:"outputHash,omitempty"`
+}
+
+// newRerunReport returns a report of every test which failed at least once,
+// sorted by name.
+func newRerunReport(exec *testjson.Execution) rerunReport {
+	type attempt struct {
+		testjson.TestCase
+		failed bool
 	}
 
-	names := []string{}
-	results := map[string]testCaseCounts{}
+	report := rerunReport{Tests: []rerunReportTest{}}
+	seen := map[string]bool{}
 	for _, failure := range exec.Failed() {
 		name := failure.Package + "." + failure.Test.Name()
-		if _, ok := results[name]; ok {
+		if seen[name] {
 			continue
 		}
-		names = append(names, name)
+		seen[name] = true
 
 		pkg := exec.Package(failure.Package)
-		counts := testCaseCounts{}
-
+		var attempts []attempt
 		for _, tc := range pkg.Failed {
 			if tc.Test == failure.Test {
-				counts.total++
-				counts.failed++
+				attempts = append(attempts, attempt{TestCase: tc, failed: true})
 			}
 		}
 		for _, tc := range pkg.Passed {
 			if tc.Test == failure.Test {
-				counts.total++
+				attempts = append(attempts, attempt{TestCase: tc})
 			}
 		}
 		// Skipped tests are not counted, but presumably skipped tests can not fail
-		results[name] = counts
+		sort.Slice(attempts, func(i, j int) bool {
+			return attempts[i].ID < attempts[j].ID
+		})
+
+		tc := rerunReportTest{
+			Package:  failure.Package,
+			Test:     failure.Test.Name(),
+			Runs:     len(attempts),
+			Attempts: []rerunReportAttempt{},
+		}
+		for _, a := range attempts {
+			ra := rerunReportAttempt{Result: "pass", Elapsed: a.Elapsed.Seconds()}
+			if a.failed {
+				tc.Failures++
+				tc.DataRace = tc.DataRace || hasDataRaceOutput(exec, a.TestCase)
+				ra.Result = "fail"
+				ra.OutputHash = outputHash(exec.OutputLines(a.TestCase))
+			}
+			tc.Result = ra.Result
+			tc.Attempts = append(tc.Attempts, ra)
+		}
+		report.Tests = append(report.Tests, tc)
 	}
 
-	fh, err := os.Create(opts.rerunFailsReportFile)
-	if err != nil {
-		return err
-	}
+	sort.Slice(report.Tests, func(i, j int) bool {
+		a, b := report.Tests[i], report.Tests[j]
+		return a.Package+"."+a.Test < b.Package+"."+b.Test
+	})
+	return report
+}
 
-	defer func() {
-		_ = fh.Close()
-	}()
+func outputHash(lines []string) string {
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line))
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
 
-	sort.Strings(names)
-	for _, name := range names {
-		counts := results[name]
-		fmt.Fprintf(fh, "%s: %d runs, %d failures\n", name, counts.total, counts.failed)
+func hasDataRaceOutput(exec *testjson.Execution, tc testjson.TestCase) bool {
+	for _, line := range exec.OutputLines(tc) {
+		if strings.HasPrefix(line, "WARNING: DATA RACE") {
+			return true
+		}
 	}
-	return nil
+	return false
 }
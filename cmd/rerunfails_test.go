@@ -4,9 +4,14 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"gotest.tools/gotestsum/testjson"
 	"gotest.tools/v3/assert"
@@ -36,6 +41,29 @@ func TestWriteRerunFailsReport(t *testing.T) {
 	golden.Assert(t, string(raw), t.Name()+"-expected")
 }
 
+func TestWriteRerunFailsReport_JSON(t *testing.T) {
+	reportFile := fs.NewFile(t, t.Name())
+	defer reportFile.Remove()
+
+	opts := &options{
+		rerunFailsReportFile:   reportFile.Path(),
+		rerunFailsReportFormat: "json",
+		rerunFailsMaxAttempts:  4,
+	}
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: bytes.NewReader(golden.Get(t, "go-test-json-flaky-rerun.out")),
+	})
+	assert.NilError(t, err)
+
+	err = writeRerunFailsReport(opts, exec)
+	assert.NilError(t, err)
+
+	raw, err := os.ReadFile(reportFile.Path())
+	assert.NilError(t, err)
+	golden.Assert(t, string(raw), t.Name()+"-expected")
+}
+
 func TestWriteRerunFailsReport_HandlesMissingActionRunEvents(t *testing.T) {
 	reportFile := fs.NewFile(t, t.Name())
 	defer reportFile.Remove()
@@ -203,6 +231,540 @@ func TestRerunFailed_AbortOnDataRace(t *testing.T) {
 	}
 }
 
+func TestRerunFailed_StopsWhenMaxTimeIsExceeded(t *testing.T) {
+	jsonFailed := dedentOutput(`
+		{"Package": "pkg", "Action": "run"}
+		{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+		{"Package": "pkg", "Test": "TestOne", "Action": "fail"}
+		{"Package": "pkg", "Action": "fail"}
+	`)
+	var calls int
+	fn := func([]string) *proc {
+		calls++
+		time.Sleep(20 * time.Millisecond)
+		return &proc{
+			cmd:    fakeWaiter{result: newExitCode("run-failed", 1)},
+			stdout: strings.NewReader(jsonFailed),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	opts := &options{
+		rerunFailsMaxInitialFailures: 10,
+		rerunFailsMaxAttempts:        5,
+		rerunFailsMaxTime:            10 * time.Millisecond,
+		stdout:                       new(bytes.Buffer),
+	}
+	cfg := testjson.ScanConfig{
+		Execution: newExecutionWithTwoFailures(t),
+		Handler:   noopHandler{},
+	}
+	err := rerunFailed(context.Background(), opts, cfg)
+	assert.Error(t, err, "rerun stopped because reruns exceeded the time (10ms) set by --rerun-fails-max-time")
+	assert.Equal(t, calls, 1)
+}
+
+func TestRerunFailed_WaitsForDelayBeforeEachAttempt(t *testing.T) {
+	jsonFailed := dedentOutput(`
+		{"Package": "pkg", "Action": "run"}
+		{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+		{"Package": "pkg", "Test": "TestOne", "Action": "fail"}
+		{"Package": "pkg", "Action": "fail"}
+	`)
+	fn := func([]string) *proc {
+		return &proc{
+			cmd:    fakeWaiter{result: newExitCode("run-failed", 1)},
+			stdout: strings.NewReader(jsonFailed),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	var delays []time.Duration
+	origSleep := sleepFn
+	sleepFn = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	defer func() { sleepFn = origSleep }()
+
+	delay := &rerunDelayValue{}
+	assert.NilError(t, delay.Set("3s"))
+	opts := &options{
+		rerunFailsMaxInitialFailures: 10,
+		rerunFailsMaxAttempts:        2,
+		rerunFailsDelay:              delay,
+		stdout:                       new(bytes.Buffer),
+	}
+	cfg := testjson.ScanConfig{
+		Execution: newExecutionWithTwoFailures(t),
+		Handler:   noopHandler{},
+	}
+	err := rerunFailed(context.Background(), opts, cfg)
+	assert.Error(t, err, "run-failed")
+	assert.DeepEqual(t, delays, []time.Duration{3 * time.Second, 3 * time.Second})
+}
+
+func TestSleep_ReturnsWhenContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, sleep(ctx, time.Hour), context.Canceled)
+}
+
+func TestCheckRerunOutputPatterns(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(dedentOutput(`
+			{"Package": "pkg", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "output", "Output": "dial tcp: connection refused\n"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "fail"}
+			{"Package": "pkg", "Test": "TestTwo", "Action": "run"}
+			{"Package": "pkg", "Test": "TestTwo", "Action": "output", "Output": "expected 1, got 2\n"}
+			{"Package": "pkg", "Test": "TestTwo", "Action": "fail"}
+			{"Package": "pkg", "Action": "fail"}
+		`)),
+		Stderr: strings.NewReader(""),
+	})
+	assert.NilError(t, err)
+	one := exec.Package("pkg").LastFailedByName("TestOne")
+
+	type testCase struct {
+		name     string
+		ifMatch  []string
+		unless   []string
+		failures []testjson.TestCase
+		expected string
+	}
+	run := func(t *testing.T, tc testCase) {
+		opts := &options{}
+		for _, p := range tc.ifMatch {
+			assert.NilError(t, opts.rerunFailsIfOutput.Set(p))
+		}
+		for _, p := range tc.unless {
+			assert.NilError(t, opts.rerunFailsUnlessOutput.Set(p))
+		}
+		err := checkRerunOutputPatterns(opts, exec, tc.failures)
+		if tc.expected == "" {
+			assert.NilError(t, err)
+			return
+		}
+		assert.Error(t, err, tc.expected)
+	}
+	testCases := []testCase{
+		{
+			name:     "no patterns",
+			failures: exec.Failed(),
+		},
+		{
+			name:     "all failures match",
+			ifMatch:  []string{"connection refused", "timeout"},
+			failures: []testjson.TestCase{one},
+		},
+		{
+			name:     "a failure does not match",
+			ifMatch:  []string{"connection refused", "timeout"},
+			failures: exec.Failed(),
+			expected: "rerun skipped because the output of pkg.TestTwo does not match --rerun-fails-if-output-matches",
+		},
+		{
+			name:     "a failure matches unless",
+			unless:   []string{"expected .*, got"},
+			failures: exec.Failed(),
+			expected: "rerun skipped because the output of pkg.TestTwo matches --rerun-fails-unless-output-matches",
+		},
+		{
+			name:     "no failure matches unless",
+			unless:   []string{"panic:"},
+			failures: exec.Failed(),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestRerunFailed_SkippedWhenOutputDoesNotMatch(t *testing.T) {
+	fn := func([]string) *proc {
+		t.Fatal("go test should not be run")
+		return nil
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	opts := &options{
+		rerunFailsMaxInitialFailures: 10,
+		rerunFailsMaxAttempts:        2,
+		stdout:                       new(bytes.Buffer),
+	}
+	assert.NilError(t, opts.rerunFailsIfOutput.Set("connection refused"))
+	cfg := testjson.ScanConfig{
+		Execution: newExecutionWithTwoFailures(t),
+		Handler:   noopHandler{},
+	}
+	err := rerunFailed(context.Background(), opts, cfg)
+	assert.Error(t, err, "rerun skipped because the output of pkg.TestOne does not match --rerun-fails-if-output-matches")
+}
+
+func TestRerunFailed_RunPackage(t *testing.T) {
+	var calls [][]string
+	fn := func(args []string) *proc {
+		calls = append(calls, args)
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: strings.NewReader(dedentOutput(`
+				{"Package": "pkg", "Action": "run"}
+				{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+				{"Package": "pkg", "Test": "TestOne", "Action": "pass"}
+				{"Package": "pkg", "Test": "TestTwo", "Action": "run"}
+				{"Package": "pkg", "Test": "TestTwo", "Action": "pass"}
+				{"Package": "pkg", "Action": "pass"}
+			`)),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	opts := &options{
+		rerunFailsMaxInitialFailures: 10,
+		rerunFailsMaxAttempts:        2,
+		rerunFailsRunPackage:         true,
+		stdout:                       new(bytes.Buffer),
+	}
+	cfg := testjson.ScanConfig{
+		Execution: newExecutionWithTwoFailures(t),
+		Handler:   noopHandler{},
+	}
+	err := rerunFailed(context.Background(), opts, cfg)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, calls, [][]string{{"go", "test", "-json", "pkg"}})
+}
+
+func newExecutionWithPackageError(t *testing.T) *testjson.Execution {
+	t.Helper()
+
+	out := dedentOutput(`
+		{"Package": "broken", "Action": "start"}
+		{"Package": "broken", "Action": "output", "Output": "FAIL\tbroken [setup failed]\n"}
+		{"Package": "broken", "Action": "fail"}
+		{"Package": "pkg", "Action": "run"}
+		{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+		{"Package": "pkg", "Test": "TestOne", "Action": "fail"}
+		{"Package": "pkg", "Action": "fail"}
+	`)
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(out),
+		Stderr: strings.NewReader(""),
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestRerunFailed_RetryPackageErrors(t *testing.T) {
+	var calls [][]string
+	fn := func(args []string) *proc {
+		calls = append(calls, args)
+		out := `
+			{"Package": "pkg", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "pass"}
+			{"Package": "pkg", "Action": "pass"}
+		`
+		var exitErr error
+		switch {
+		case len(calls) == 1:
+			out = `
+				{"Package": "broken", "Action": "start"}
+				{"Package": "broken", "Action": "fail"}
+			`
+			exitErr = newExitCode("failed", 1)
+		case len(calls) == 2:
+			out = `
+				{"Package": "broken", "Action": "run"}
+				{"Package": "broken", "Test": "TestBroken", "Action": "run"}
+				{"Package": "broken", "Test": "TestBroken", "Action": "pass"}
+				{"Package": "broken", "Action": "pass"}
+			`
+		}
+		return &proc{
+			cmd:    fakeWaiter{result: exitErr},
+			stdout: strings.NewReader(dedentOutput(out)),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	opts := &options{
+		rerunFailsMaxInitialFailures: 10,
+		rerunFailsMaxAttempts:        2,
+		rerunFailsPkgErrors:          packageErrorsValue{policy: packageErrorsRetry, attempts: 3},
+		stdout:                       new(bytes.Buffer),
+	}
+	cfg := testjson.ScanConfig{
+		Execution: newExecutionWithPackageError(t),
+		Handler:   noopHandler{},
+	}
+	err := rerunFailed(context.Background(), opts, cfg)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, calls, [][]string{
+		{"go", "test", "-json", "broken"},
+		{"go", "test", "-json", "broken"},
+		{"go", "test", "-json", "-test.run=^TestOne$", "pkg"},
+	})
+}
+
+func TestRerunFailed_SkipPackageErrors(t *testing.T) {
+	var calls [][]string
+	fn := func(args []string) *proc {
+		calls = append(calls, args)
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: strings.NewReader(dedentOutput(`
+				{"Package": "pkg", "Action": "run"}
+				{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+				{"Package": "pkg", "Test": "TestOne", "Action": "pass"}
+				{"Package": "pkg", "Action": "pass"}
+			`)),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	opts := &options{
+		rerunFailsMaxInitialFailures: 10,
+		rerunFailsMaxAttempts:        2,
+		rerunFailsPkgErrors:          packageErrorsValue{policy: packageErrorsSkip},
+		stdout:                       new(bytes.Buffer),
+	}
+	cfg := testjson.ScanConfig{
+		Execution: newExecutionWithPackageError(t),
+		Handler:   noopHandler{},
+	}
+	err := rerunFailed(context.Background(), opts, cfg)
+	assert.Error(t, err, "tests were not rerun in 1 packages which failed to build, or failed in TestMain: broken")
+	assert.DeepEqual(t, calls, [][]string{{"go", "test", "-json", "-test.run=^TestOne$", "pkg"}})
+}
+
+// blockingReader blocks the first read until release is closed, and sends
+// name to running when the read starts.
+type blockingReader struct {
+	io.Reader
+	name    string
+	running chan<- string
+	release <-chan struct{}
+	once    sync.Once
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	r.once.Do(func() {
+		r.running <- r.name
+		<-r.release
+	})
+	return r.Reader.Read(p)
+}
+
+func TestRerunFailed_Parallel(t *testing.T) {
+	running := make(chan string, 3)
+	release := make(chan struct{})
+
+	var lock sync.Mutex
+	var calls []string
+	fn := func(args []string) *proc {
+		lock.Lock()
+		defer lock.Unlock()
+		pkg, test := args[len(args)-1], strings.TrimSuffix(strings.TrimPrefix(args[len(args)-2], "-test.run=^"), "$")
+		calls = append(calls, pkg+"."+test)
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: &blockingReader{
+				Reader: strings.NewReader(dedentOutput(fmt.Sprintf(`
+					{"Package": %[1]q, "Action": "run"}
+					{"Package": %[1]q, "Test": %[2]q, "Action": "run"}
+					{"Package": %[1]q, "Test": %[2]q, "Action": "pass"}
+					{"Package": %[1]q, "Action": "pass"}
+				`, pkg, test))),
+				name:    pkg,
+				running: running,
+				release: release,
+			},
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(dedentOutput(`
+			{"Package": "pkg", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "fail"}
+			{"Package": "pkg", "Test": "TestTwo", "Action": "run"}
+			{"Package": "pkg", "Test": "TestTwo", "Action": "fail"}
+			{"Package": "pkg", "Action": "fail"}
+			{"Package": "other", "Action": "run"}
+			{"Package": "other", "Test": "TestThree", "Action": "run"}
+			{"Package": "other", "Test": "TestThree", "Action": "fail"}
+			{"Package": "other", "Action": "fail"}
+		`)),
+	})
+	assert.NilError(t, err)
+
+	opts := &options{
+		rerunFailsMaxInitialFailures: 10,
+		rerunFailsMaxAttempts:        2,
+		rerunFailsParallel:           3,
+		stdout:                       new(bytes.Buffer),
+	}
+	cfg := testjson.ScanConfig{Execution: exec, Handler: noopHandler{}}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- rerunFailed(context.Background(), opts, cfg)
+	}()
+
+	// both packages are rerun at the same time, but the tests in the same
+	// package are rerun one at a time.
+	var started []string
+	for len(started) < 2 {
+		select {
+		case name := <-running:
+			started = append(started, name)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected two packages to be rerun at the same time, started: %v", started)
+		}
+	}
+	sort.Strings(started)
+	assert.DeepEqual(t, started, []string{"other", "pkg"})
+	select {
+	case name := <-running:
+		t.Fatalf("expected only one rerun of each package at a time, started: %v", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	assert.NilError(t, <-done)
+	sort.Strings(calls)
+	assert.DeepEqual(t, calls, []string{"other.TestThree", "pkg.TestOne", "pkg.TestTwo"})
+	assert.Equal(t, len(exec.Package("pkg").Passed), 2)
+	assert.Equal(t, len(exec.Package("other").Passed), 1)
+}
+
+func TestStartBuffered_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var lock sync.Mutex
+	var launched []string
+	launch := func(_ context.Context, rerunTC rerunOpts) (*proc, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		launched = append(launched, rerunTC.runFlag)
+		if rerunTC.runFlag == "TestOne" {
+			cancel() // as rerun does when a command fails
+			return nil, fmt.Errorf("failed to start")
+		}
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(""),
+			stderr: strings.NewReader(""),
+		}, nil
+	}
+	rerunTCs := []rerunOpts{
+		{pkg: "pkg", runFlag: "TestOne"},
+		{pkg: "pkg", runFlag: "TestTwo"},
+		{pkg: "pkg", runFlag: "TestThree"},
+	}
+	results, wait := startBuffered(ctx, 2, rerunTCs, launch)
+	result := <-results[0]
+	assert.ErrorContains(t, result.err, "failed to start")
+	wait()
+
+	assert.DeepEqual(t, launched, []string{"TestOne"})
+	assert.ErrorIs(t, (<-results[1]).err, context.Canceled)
+	assert.ErrorIs(t, (<-results[2]).err, context.Canceled)
+}
+
+func TestRerunFailed_RaceOnFirstRerun(t *testing.T) {
+	reportFile := fs.NewFile(t, t.Name())
+	outputs := []string{
+		dedentOutput(`
+			{"Package": "pkg", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "output", "Output": "WARNING: DATA RACE\n"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "fail"}
+			{"Package": "pkg", "Action": "fail"}
+		`),
+		dedentOutput(`
+			{"Package": "pkg", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "pass"}
+			{"Package": "pkg", "Action": "pass"}
+		`),
+	}
+	var calls [][]string
+	fn := func(args []string) *proc {
+		calls = append(calls, args)
+		out := outputs[0]
+		outputs = outputs[1:]
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(out),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(dedentOutput(`
+			{"Package": "pkg", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "fail"}
+			{"Package": "pkg", "Action": "fail"}
+		`)),
+		Stderr: strings.NewReader(""),
+	})
+	assert.NilError(t, err)
+
+	opts := &options{
+		rerunFailsMaxInitialFailures: 10,
+		rerunFailsMaxAttempts:        2,
+		rerunFailsRace:               true,
+		rerunFailsReportFile:         reportFile.Path(),
+		stdout:                       new(bytes.Buffer),
+	}
+	err = rerunFailed(context.Background(), opts, testjson.ScanConfig{
+		Execution: exec,
+		Handler:   noopHandler{},
+	})
+	assert.NilError(t, err)
+
+	expected := [][]string{
+		{"go", "test", "-json", "-test.run=^TestOne$", "-race", "pkg"},
+		{"go", "test", "-json", "-test.run=^TestOne$", "pkg"},
+	}
+	assert.DeepEqual(t, calls, expected)
+
+	assert.NilError(t, writeRerunFailsReport(opts, exec))
+	raw, err := os.ReadFile(reportFile.Path())
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), "pkg.TestOne: 3 runs, 2 failures, data race detected\n")
+}
+
+func TestRerunWithRace(t *testing.T) {
+	assert.Assert(t, rerunWithRace(&options{rerunFailsRace: true}))
+	assert.Assert(t, !rerunWithRace(&options{}))
+	assert.Assert(t, !rerunWithRace(&options{rerunFailsRace: true, args: []string{"-race"}}))
+	assert.Assert(t, !rerunWithRace(&options{rerunFailsRace: true, rawCommand: true}))
+}
+
 func dedentOutput(s string) string {
 	lines := strings.Split(s, "\n")
 	for i, line := range lines {
@@ -216,7 +778,7 @@ func dedentOutput(s string) string {
 
 func patchStartGoTestFn(f func(args []string) *proc) func() {
 	orig := startGoTestFn
-	startGoTestFn = func(_ context.Context, _ string, args []string) (*proc, error) {
+	startGoTestFn = func(_ context.Context, _ string, args []string, _ ...string) (*proc, error) {
 		return f(args), nil
 	}
 	return func() {
@@ -345,3 +907,141 @@ func TestRerunFailed_MergesCoverProfile(t *testing.T) {
 	assert.Assert(t, strings.Contains(content, "pkg/b.go:1.1,5.2 3 1"),
 		"expected untouched file preserved, got: %s", content)
 }
+
+func TestRerunFailed_CoverProfileNotMergedWhenInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	coverFile := dir + "/cover.out"
+	original := "mode: set\npkg/a.go:1.1,5.2 3 0\n"
+	assert.NilError(t, os.WriteFile(coverFile, []byte(original), 0o644))
+
+	var rerunProfiles []string
+	fn := func(args []string) *proc {
+		for _, arg := range args {
+			if rerunPath, ok := strings.CutPrefix(arg, "-coverprofile="); ok {
+				rerunProfiles = append(rerunProfiles, rerunPath)
+				// simulate a partial write from an interrupted go test
+				_ = os.WriteFile(rerunPath, []byte("mode: set\npkg/a.go:1.1,5.2 3"), 0o644)
+			}
+		}
+		return &proc{
+			cmd:    fakeWaiter{result: nil},
+			stdout: strings.NewReader(""),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts := &options{
+		rerunFailsMaxInitialFailures: 10,
+		rerunFailsMaxAttempts:        1,
+		args:                         []string{"-coverprofile=" + coverFile},
+		packages:                     []string{"./pkg"},
+		stdout:                       new(bytes.Buffer),
+	}
+	cfg := testjson.ScanConfig{
+		Execution: newExecutionWithTwoFailures(t),
+		Handler:   noopHandler{},
+	}
+	err := rerunFailed(ctx, opts, cfg)
+	assert.NilError(t, err)
+
+	raw, err := os.ReadFile(coverFile)
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), original)
+
+	// each rerun used a unique profile, and all of them were removed
+	assert.Equal(t, len(rerunProfiles), 2)
+	assert.Assert(t, rerunProfiles[0] != rerunProfiles[1])
+	for _, path := range rerunProfiles {
+		_, err := os.Stat(filepath.Dir(path))
+		assert.Assert(t, os.IsNotExist(err), "expected %v to be removed", path)
+	}
+}
+
+func TestCheckFlakyTests(t *testing.T) {
+	t.Run("flaky test", func(t *testing.T) {
+		opts := &options{rerunFailsMaxAttempts: 2, rerunFailsFailOnFlaky: true}
+		err := checkFlakyTests(opts, newExecutionWithRerunPass("TestTwo")(t))
+		assert.Equal(t, ExitCodeWithDefault(err), flakyExitCode)
+	})
+	t.Run("no flaky tests", func(t *testing.T) {
+		opts := &options{rerunFailsMaxAttempts: 2, rerunFailsFailOnFlaky: true}
+		err := checkFlakyTests(opts, newExecutionWithTwoFailures(t))
+		assert.NilError(t, err)
+	})
+	t.Run("flag not set", func(t *testing.T) {
+		opts := &options{rerunFailsMaxAttempts: 2}
+		err := checkFlakyTests(opts, newExecutionWithRerunPass("TestTwo")(t))
+		assert.NilError(t, err)
+	})
+}
+
+func TestRerunFailed_WritesAttemptArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	coverFile := filepath.Join(dir, "cover.out")
+
+	var calls int
+	fn := func(args []string) *proc {
+		calls++
+		var test string
+		for _, arg := range args {
+			if rerunPath, ok := strings.CutPrefix(arg, "-coverprofile="); ok {
+				_ = os.WriteFile(rerunPath, []byte(fmt.Sprintf("mode: set\npkg/a.go:%d.1,5.2 3 1\n", calls)), 0o644)
+			}
+			if run, ok := strings.CutPrefix(arg, "-test.run=^"); ok {
+				test = strings.TrimSuffix(run, "$")
+			}
+		}
+		action := "pass"
+		if calls == 2 {
+			action = "fail"
+		}
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: strings.NewReader(dedentOutput(fmt.Sprintf(`
+				{"Package": "pkg", "Action": "run"}
+				{"Package": "pkg", "Test": %[1]q, "Action": "run"}
+				{"Package": "pkg", "Test": %[1]q, "Action": "output", "Output": "call %[2]d\n"}
+				{"Package": "pkg", "Test": %[1]q, "Action": %[3]q}
+				{"Package": "pkg", "Action": %[3]q}
+			`, test, calls, action))),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	attemptsDir := filepath.Join(dir, "attempts")
+	opts := &options{
+		rerunFailsMaxInitialFailures: 10,
+		rerunFailsMaxAttempts:        2,
+		rerunFailsAttemptsDir:        attemptsDir,
+		args:                         []string{"-coverprofile=" + coverFile},
+		packages:                     []string{"./pkg"},
+		stdout:                       new(bytes.Buffer),
+	}
+	cfg := testjson.ScanConfig{
+		Execution: newExecutionWithTwoFailures(t),
+		Handler:   noopHandler{},
+	}
+	err := rerunFailed(context.Background(), opts, cfg)
+	assert.NilError(t, err)
+
+	readFile := func(name string) string {
+		t.Helper()
+		raw, err := os.ReadFile(filepath.Join(attemptsDir, name))
+		assert.NilError(t, err)
+		return string(raw)
+	}
+
+	assert.Equal(t, readFile("attempt-2/output.log"), "call 1\ncall 2\n")
+	assert.Equal(t, readFile("attempt-3/output.log"), "call 3\n")
+	assert.Equal(t, strings.Count(readFile("attempt-2/test.json"), "\n"), 10)
+	assert.Assert(t, strings.Contains(readFile("attempt-3/test.json"), `"Test": "TestTwo", "Action": "pass"`))
+	assert.Equal(t, readFile("attempt-2/coverprofile.out"),
+		"mode: set\npkg/a.go:1.1,5.2 3 1\npkg/a.go:2.1,5.2 3 1\n")
+	assert.Equal(t, readFile("attempt-3/coverprofile.out"), "mode: set\npkg/a.go:3.1,5.2 3 1\n")
+}
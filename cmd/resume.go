@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/coverprofile"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// resume records each package which finishes in a checkpoint file next to the
+// --jsonfile, so that a run which is interrupted can be continued by running
+// the same command again. The packages in the checkpoint are not tested again,
+// their results are read from the --jsonfile of the interrupted run.
+type resume struct {
+	path string
+	file *os.File
+	// finished is the packages which finished before the run was interrupted.
+	finished map[string]bool
+	// exec has the results of the finished packages.
+	exec         *testjson.Execution
+	coverProfile string
+}
+
+func checkpointPath(opts *options) string {
+	return opts.jsonFile + ".checkpoint"
+}
+
+// newResume returns nil when --resume is not set. When the checkpoint has
+// packages which finished, the --jsonfile is rewritten with only the events of
+// those packages, and the run continues to add events to the end of the file.
+func newResume(opts *options) (*resume, error) {
+	if !opts.resume {
+		return nil, nil
+	}
+	r := &resume{path: checkpointPath(opts)}
+	finished, err := readCheckpoint(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	if len(finished) > 0 {
+		r.finished = finished
+		if r.exec, err = rewriteJSONFile(opts, finished); err != nil {
+			return nil, fmt.Errorf("failed to resume from %v: %w", opts.jsonFile, err)
+		}
+		opts.jsonFileAppend = true
+		if err := r.saveCoverProfile(coverprofile.ArgValue(opts.args)); err != nil {
+			return nil, err
+		}
+	}
+
+	r.file, err = os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	return r, nil
+}
+
+// readCheckpoint returns the packages in the checkpoint at path, or nil if
+// the file does not exist.
+func readCheckpoint(path string) (map[string]bool, error) {
+	raw, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	finished := make(map[string]bool)
+	for _, line := range strings.Split(string(raw), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			finished[line] = true
+		}
+	}
+	return finished, nil
+}
+
+// rewriteJSONFile replaces the --jsonfile with a copy which only has the
+// events of the finished packages, and returns an Execution with the results
+// of those packages.
+func rewriteJSONFile(opts *options, finished map[string]bool) (*testjson.Execution, error) {
+	in, err := jsonfile.Open(opts.jsonFile)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close() //nolint:errcheck // file is opened read-only
+
+	// the name of the copy ends with the same extension, so that it is
+	// compressed in the same way
+	tmpPath := filepath.Join(filepath.Dir(opts.jsonFile), ".resume."+filepath.Base(opts.jsonFile))
+	out, err := jsonfile.Create(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpPath) //nolint:errcheck // the file is renamed on success
+
+	events, eventsW := io.Pipe()
+	go func() {
+		_ = eventsW.CloseWithError(copyFinishedEvents(io.MultiWriter(out, eventsW), in, finished))
+	}()
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:                 events,
+		KeepPassedOutput:       keepPassedOutput(opts),
+		MaxTestOutputBytes:     opts.maxTestOutputBytes,
+		CollapseRepeatedOutput: opts.formatOptions.CollapseRepeatedOutput,
+	})
+	_ = events.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		_ = out.Close()
+		return nil, err
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+	return exec, os.Rename(tmpPath, opts.jsonFile)
+}
+
+// copyFinishedEvents copies the events of the finished packages from in to
+// out. The file of a run which was killed may end with an incomplete line, or
+// an incomplete gzip stream, which are ignored.
+func copyFinishedEvents(out io.Writer, in io.Reader, finished map[string]bool) error {
+	reader := bufio.NewReader(in)
+	for {
+		line, err := reader.ReadBytes('\n')
+		var event struct{ Package string }
+		if json.Unmarshal(line, &event) == nil && finished[event.Package] {
+			if !bytes.HasSuffix(line, []byte("\n")) {
+				line = append(line, '\n')
+			}
+			if _, err := out.Write(line); err != nil {
+				return err
+			}
+		}
+		switch {
+		case err == io.EOF, errors.Is(err, io.ErrUnexpectedEOF):
+			return nil
+		case err != nil:
+			return err
+		}
+	}
+}
+
+// saveCoverProfile moves the -coverprofile of the interrupted run aside, so
+// that it is not replaced by go test, and can be merged with the profile of
+// the packages which are tested by this run.
+func (r *resume) saveCoverProfile(path string) error {
+	if path == "" {
+		return nil
+	}
+	r.coverProfile = path
+	// merge with the profile of an earlier attempt, which was also interrupted
+	if err := coverprofile.MergeRerun(path+".resume", path); err != nil {
+		return fmt.Errorf("failed to save the coverprofile of the interrupted run: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SkipFinished removes the finished packages from the packages to test, and
+// returns false when every package has finished.
+func (r *resume) SkipFinished(opts *options, groups []testGroup) ([]testGroup, bool, error) {
+	if r == nil || len(r.finished) == 0 {
+		return groups, true, nil
+	}
+	fmt.Fprintf(opts.stderr, "Resuming the interrupted run, %d packages finished and are not tested again\n",
+		len(r.finished))
+
+	unfinished := func(pkgs []string) []string {
+		var result []string
+		for _, pkg := range pkgs {
+			if !r.finished[pkg] {
+				result = append(result, pkg)
+			}
+		}
+		return result
+	}
+
+	if len(groups) > 0 {
+		var result []testGroup
+		for _, group := range groups {
+			if group.packages = unfinished(group.packages); len(group.packages) > 0 {
+				result = append(result, group)
+			}
+		}
+		return result, len(result) > 0, nil
+	}
+
+	pkgs, err := goListPackagesFn(cmdArgPackageList(opts, rerunOpts{}, "./..."))
+	if err != nil {
+		return nil, false, err
+	}
+	opts.packages = unfinished(pkgs)
+	return nil, len(opts.packages) > 0, nil
+}
+
+// Execution returns the results of the finished packages, or nil when there
+// are no finished packages.
+func (r *resume) Execution() *testjson.Execution {
+	if r == nil {
+		return nil
+	}
+	return r.exec
+}
+
+// Handler returns an EventHandler which adds each package to the checkpoint
+// when it finishes. The events are flushed to the --jsonfile before the
+// package is added. A package which ends after the run was interrupted is not
+// added, because it may have ended because of the interrupt.
+func (r *resume) Handler(
+	handler testjson.EventHandler,
+	flush func(),
+	interrupted func() bool,
+) testjson.EventHandler {
+	if r == nil {
+		return handler
+	}
+	return &resumeHandler{EventHandler: handler, resume: r, flush: flush, interrupted: interrupted}
+}
+
+type resumeHandler struct {
+	testjson.EventHandler
+	resume      *resume
+	flush       func()
+	interrupted func() bool
+}
+
+func (h *resumeHandler) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	if err := h.EventHandler.Event(event, execution); err != nil {
+		return err
+	}
+	if !event.PackageEvent() || !event.Action.IsTerminal() || h.interrupted() {
+		return nil
+	}
+	h.flush()
+	if _, err := fmt.Fprintln(h.resume.file, event.Package); err != nil {
+		log.Warnf("failed to write checkpoint: %v", err)
+	}
+	return nil
+}
+
+// ExitErr returns an error when a package which finished before the run was
+// interrupted failed, and exitErr is nil.
+func (r *resume) ExitErr(exec *testjson.Execution, exitErr error) error {
+	if r == nil || exitErr != nil {
+		return exitErr
+	}
+	for pkg := range r.finished {
+		if p := exec.Package(pkg); p != nil && p.Result() == testjson.ActionFail {
+			return exitError{num: 1}
+		}
+	}
+	return nil
+}
+
+// Done is called when every package has finished. The coverprofile of the
+// interrupted run is merged with the coverprofile of this run, and the
+// checkpoint is removed, so that the next run starts again.
+func (r *resume) Done() {
+	if r == nil {
+		return
+	}
+	if r.coverProfile != "" {
+		saved := r.coverProfile + ".resume"
+		if err := coverprofile.MergeRerun(r.coverProfile, saved); err != nil {
+			log.Warnf("failed to merge the coverprofile of the interrupted run: %v", err)
+		} else {
+			_ = os.Remove(saved)
+		}
+	}
+	r.Close()
+	if err := os.Remove(r.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warnf("failed to remove checkpoint: %v", err)
+	}
+}
+
+func (r *resume) Close() {
+	if r == nil || r.file == nil {
+		return
+	}
+	if err := r.file.Close(); err != nil {
+		log.Warnf("failed to close checkpoint: %v", err)
+	}
+	r.file = nil
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestRun_Resume(t *testing.T) {
+	// b finished with a failure, and c was interrupted
+	interrupted := `{"Package": "example.com/b", "Action": "run"}
+{"Package": "example.com/b", "Test": "TestB", "Action": "run"}
+{"Package": "example.com/c", "Action": "run"}
+{"Package": "example.com/b", "Test": "TestB", "Action": "fail"}
+{"Package": "example.com/c", "Test": "TestC", "Action": "run"}
+{"Package": "example.com/b", "Action": "fail"}
+{"Package": "example.com/c", "Test": "TestC", "Ac`
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("out.json", interrupted),
+		fs.WithFile("out.json.checkpoint", "example.com/b\n"))
+
+	orig := goListPackagesFn
+	goListPackagesFn = func([]string) ([]string, error) {
+		return []string{"example.com/a", "example.com/b", "example.com/c"}, nil
+	}
+	t.Cleanup(func() { goListPackagesFn = orig })
+
+	var goTestArgs []string
+	reset := patchStartGoTestFn(func(args []string) *proc {
+		goTestArgs = args
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: strings.NewReader(`{"Package": "example.com/a", "Action": "run"}
+{"Package": "example.com/a", "Action": "pass"}
+{"Package": "example.com/c", "Action": "run"}
+{"Package": "example.com/c", "Action": "pass"}
+`),
+			stderr: strings.NewReader(""),
+		}
+	})
+	defer reset()
+
+	stderr := new(bytes.Buffer)
+	opts := &options{
+		format:      "testname",
+		resume:      true,
+		jsonFile:    dir.Join("out.json"),
+		packages:    []string{"./..."},
+		stdout:      new(bytes.Buffer),
+		stderr:      stderr,
+		hideSummary: newHideSummaryValue(),
+	}
+	err := run(opts)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.DeepEqual(t, goTestArgs,
+		[]string{"go", "test", "-json", "example.com/a", "example.com/c"})
+	assert.Equal(t, stderr.String(),
+		"Resuming the interrupted run, 1 packages finished and are not tested again\n")
+
+	raw, err := os.ReadFile(dir.Join("out.json"))
+	assert.NilError(t, err)
+	expected := `{"Package": "example.com/b", "Action": "run"}
+{"Package": "example.com/b", "Test": "TestB", "Action": "run"}
+{"Package": "example.com/b", "Test": "TestB", "Action": "fail"}
+{"Package": "example.com/b", "Action": "fail"}
+{"Package": "example.com/a", "Action": "run"}
+{"Package": "example.com/a", "Action": "pass"}
+{"Package": "example.com/c", "Action": "run"}
+{"Package": "example.com/c", "Action": "pass"}
+`
+	assert.Equal(t, string(raw), expected)
+
+	_, err = os.Stat(dir.Join("out.json.checkpoint"))
+	assert.Assert(t, os.IsNotExist(err), "checkpoint should be removed when the run finishes")
+}
+
+func TestResume_RewriteCompressedJSONFile(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	path := dir.Join("out.json.gz")
+	f, err := jsonfile.Create(path)
+	assert.NilError(t, err)
+	_, err = io.WriteString(f, `{"Package": "example.com/a", "Action": "run"}
+{"Package": "example.com/b", "Action": "run"}
+{"Package": "example.com/a", "Action": "pass"}
+`)
+	assert.NilError(t, err)
+	// the file of an interrupted run may not have the end of the gzip stream
+	assert.NilError(t, f.Sync())
+
+	exec, err := rewriteJSONFile(&options{jsonFile: path}, map[string]bool{"example.com/a": true})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, exec.Packages(), []string{"example.com/a"})
+
+	in, err := jsonfile.Open(path)
+	assert.NilError(t, err)
+	defer in.Close() //nolint:errcheck
+	raw, err := io.ReadAll(in)
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), `{"Package": "example.com/a", "Action": "run"}
+{"Package": "example.com/a", "Action": "pass"}
+`)
+}
+
+func TestResumeHandler_Event(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	opts := &options{resume: true, jsonFile: dir.Join("out.json")}
+	r, err := newResume(opts)
+	assert.NilError(t, err)
+
+	var flushed int
+	var stopped bool
+	handler := r.Handler(noopHandler{}, func() { flushed++ }, func() bool { return stopped })
+	for _, event := range []testjson.TestEvent{
+		{Package: "example.com/a", Test: "TestA", Action: testjson.ActionPass},
+		{Package: "example.com/a", Action: testjson.ActionPass},
+		{Package: "example.com/b", Action: testjson.ActionFail},
+	} {
+		assert.NilError(t, handler.Event(event, nil))
+	}
+	stopped = true
+	assert.NilError(t, handler.Event(testjson.TestEvent{Package: "example.com/c", Action: testjson.ActionFail}, nil))
+	r.Close()
+
+	assert.Equal(t, flushed, 2)
+	raw, err := os.ReadFile(checkpointPath(opts))
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), "example.com/a\nexample.com/b\n")
+}
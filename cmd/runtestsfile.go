@@ -0,0 +1,359 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// testGroup is a go test command run by --run-tests-file or
+// --skip-tests-file. The packages in a group run with the same args, which
+// select the tests to run.
+type testGroup struct {
+	packages []string
+	args     []string
+}
+
+// selectTestGroups returns the go test commands used to run the tests in the
+// --run-tests-file, without the tests in the --skip-tests-file. When only
+// --skip-tests-file is set the packages to test are listed with go list, so
+// that the packages without any skipped tests are also tested. Returns nil when
+// neither flag is set.
+func selectTestGroups(opts *options) ([]testGroup, error) {
+	var skip map[string][]string
+	if opts.skipTestsFile != "" {
+		var err error
+		if skip, err = readTestsFile(opts.skipTestsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.runTestsFile != "" {
+		tests, err := readTestsFile(opts.runTestsFile)
+		if err != nil {
+			return nil, err
+		}
+		for pkg, names := range tests {
+			names = slices.DeleteFunc(names, func(name string) bool {
+				return slices.Contains(skip[pkg], name)
+			})
+			if len(names) == 0 {
+				delete(tests, pkg)
+				continue
+			}
+			tests[pkg] = names
+		}
+		if len(tests) == 0 {
+			return nil, fmt.Errorf("no tests to run, every test in %v is skipped", opts.runTestsFile)
+		}
+		return groupTests(tests, func(names []string) []string {
+			return []string{runFlagForTests(names)}
+		}), nil
+	}
+	if opts.skipTestsFile == "" {
+		return nil, nil
+	}
+
+	pkgs, err := goListPackagesFn(cmdArgPackageList(opts, rerunOpts{}, "./..."))
+	if err != nil {
+		return nil, err
+	}
+	tests := make(map[string][]string, len(pkgs))
+	for _, pkg := range pkgs {
+		tests[pkg] = skip[pkg]
+	}
+	return groupTests(tests, func(names []string) []string {
+		if len(names) == 0 {
+			return nil
+		}
+		return []string{"-skip=" + testsPattern(names)}
+	}), nil
+}
+
+// readTestsFile reads the tests in a --run-tests-file or --skip-tests-file.
+func readTestsFile(path string) (map[string][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck
+
+	tests, err := parseTestsFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %v: %w", path, err)
+	}
+	return tests, nil
+}
+
+var testNamePattern = regexp.MustCompile(`^\w+$`)
+
+// parseTestsFile parses a list of tests, one test per line. A test is either a
+// package import path and a test name separated by a space, or the package
+// import path followed by a dot and the test name. Empty lines and lines which
+// start with # are ignored. The result maps each package to its test names.
+func parseTestsFile(r io.Reader) (map[string][]string, error) {
+	tests := make(map[string][]string)
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var pkg, name string
+		if fields := strings.Fields(line); len(fields) == 2 {
+			pkg, name = fields[0], fields[1]
+		} else if i := strings.LastIndex(line, "."); i > strings.LastIndex(line, "/") {
+			pkg, name = line[:i], line[i+1:]
+		}
+		if pkg == "" || !testNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("line %d: %q is not a package and a top level test name", lineNum, line)
+		}
+		if key := pkg + " " + name; !seen[key] {
+			seen[key] = true
+			tests[pkg] = append(tests[pkg], name)
+		}
+	}
+	return tests, scanner.Err()
+}
+
+// groupTests returns a testGroup for each distinct set of test names, sorted by
+// the first package in the group. args returns the go test args used to select
+// the tests of the group.
+func groupTests(tests map[string][]string, args func(names []string) []string) []testGroup {
+	byNames := make(map[string]*testGroup)
+	var groups []*testGroup
+	for pkg, names := range tests {
+		names = append([]string{}, names...)
+		sort.Strings(names)
+		key := strings.Join(names, " ")
+		group, ok := byNames[key]
+		if !ok {
+			group = &testGroup{args: args(names)}
+			byNames[key] = group
+			groups = append(groups, group)
+		}
+		group.packages = append(group.packages, pkg)
+	}
+
+	result := make([]testGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Strings(group.packages)
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].packages[0] < result[j].packages[0]
+	})
+	return result
+}
+
+// goTestGroups runs a go test command for each of the groups.
+type goTestGroups struct {
+	groups []testGroup
+	// parallel is the number of commands which run at the same time.
+	parallel int
+	// deadline is the time after which no more commands are started. The zero
+	// value means there is no deadline.
+	deadline time.Time
+	// coverProfiles is used so that each command writes its own -coverprofile,
+	// which is merged into the -coverprofile when the command exits. Without it
+	// the commands would overwrite each others profile.
+	coverProfiles *tempCoverProfiles
+
+	mu         sync.Mutex
+	notStarted []string
+	// pids are the go test commands of the groups with a single package.
+	pids map[string]int
+}
+
+// Pid returns the pid of the go test command which tests only pkg, or 0 if
+// there is no such command.
+func (g *goTestGroups) Pid(pkg string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.pids[pkg]
+}
+
+// NotStarted returns the packages which were not tested because the deadline
+// was reached before their command was started.
+func (g *goTestGroups) NotStarted() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string{}, g.notStarted...)
+}
+
+// Start the go test commands, with at most g.parallel commands running at the
+// same time. The output of the commands is read from the returned proc as if it
+// were the output of a single command. The output is copied one line at a
+// time, so that the lines of commands which run at the same time are not mixed.
+// Every group is run even when one fails, and the exit error is the error of
+// the first group which failed.
+func (g *goTestGroups) Start(ctx context.Context, opts *options, env ...string) *proc {
+	groups := g.groups
+	if g.coverProfiles != nil {
+		// the profile of a previous run is replaced, as it would be by go test
+		if err := os.Remove(g.coverProfiles.original); err != nil && !os.IsNotExist(err) {
+			log.Warnf("failed to remove the previous cover profile: %v", err)
+		}
+	}
+	stdout, stdoutW := io.Pipe()
+	stderr, stderrW := io.Pipe()
+	result := &proc{stdout: stdout, stderr: stderr}
+	go func() {
+		// the output is no longer read once the run is stopped
+		<-ctx.Done()
+		_ = stdoutW.Close()
+		_ = stderrW.Close()
+	}()
+
+	var mu sync.Mutex // guards writes to stdoutW and stderrW
+	copyLines := func(dst io.Writer, src io.Reader) {
+		reader := bufio.NewReader(src)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				mu.Lock()
+				_, _ = dst.Write(line)
+				mu.Unlock()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	// opts is copied before any command starts, because opts is still being
+	// updated by the caller.
+	baseOpts := *opts
+	run := func(group testGroup) error {
+		groupOpts := baseOpts
+		groupOpts.packages = group.packages
+		coverProfile := g.coverProfiles.Next()
+		extraArgs := group.args
+		groupEnv := env
+		if groupOpts.packageSandboxes != nil {
+			sandboxEnv, err := groupOpts.packageSandboxes.Prepare(group.packages[0])
+			if err != nil {
+				return err
+			}
+			extraArgs = append(groupOpts.packageSandboxes.Args(), extraArgs...)
+			groupEnv = append(slices.Clip(env), sandboxEnv...)
+		}
+		args := goTestCmdArgs(&groupOpts, rerunOpts{extraArgs: extraArgs, coverProfileArg: coverProfile})
+		goTestProc, err := startGoTestFn(ctx, "", args, groupEnv...)
+		if err != nil {
+			return err
+		}
+		defer g.coverProfiles.Merge(ctx, coverProfile)
+		if len(group.packages) == 1 && goTestProc.pid != 0 {
+			g.mu.Lock()
+			if g.pids == nil {
+				g.pids = make(map[string]int)
+			}
+			g.pids[group.packages[0]] = goTestProc.pid
+			g.mu.Unlock()
+		}
+		copied := make(chan struct{})
+		go func() {
+			copyLines(stderrW, goTestProc.stderr)
+			close(copied)
+		}()
+		copyLines(stdoutW, goTestProc.stdout)
+		<-copied
+		err = goTestProc.cmd.Wait()
+		if signum := atomic.LoadInt32(&goTestProc.signal); signum != 0 {
+			atomic.StoreInt32(&result.signal, signum)
+		}
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		errs := make([]error, len(groups))
+		sem := make(chan struct{}, max(g.parallel, 1))
+		var wg sync.WaitGroup
+		for i, group := range groups {
+			sem <- struct{}{}
+			if !g.deadline.IsZero() && !time.Now().Before(g.deadline) {
+				g.mu.Lock()
+				for _, group := range groups[i:] {
+					g.notStarted = append(g.notStarted, group.packages...)
+				}
+				g.mu.Unlock()
+				break
+			}
+			if ctx.Err() != nil || atomic.LoadInt32(&result.signal) != 0 {
+				break
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = run(group)
+				<-sem
+			}()
+		}
+		wg.Wait()
+		_ = stdoutW.Close()
+		_ = stderrW.Close()
+		done <- firstError(errs)
+	}()
+	result.cmd = groupWaiter(done)
+	return result
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupWaiter waits for the commands started by goTestGroups.
+type groupWaiter chan error
+
+func (w groupWaiter) Wait() error {
+	return <-w
+}
+
+func validateRunTestsFile(o options) error {
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{name: "--raw-command", set: o.rawCommand},
+		{name: "--watch", set: o.watch},
+		{name: "--packages", set: len(o.packages) > 0},
+		{name: "--only-affected", set: o.onlyAffected != ""},
+		{name: "--partition", set: o.partition.total > 0},
+		{name: "--pick", set: o.pick},
+	} {
+		if flag.set {
+			return fmt.Errorf("--run-tests-file can not be used with %v", flag.name)
+		}
+	}
+	if start, _ := argIndex("run", o.args); start >= 0 {
+		return fmt.Errorf("-run can not be used with --run-tests-file")
+	}
+	return nil
+}
+
+func validateSkipTestsFile(o options) error {
+	if start, _ := argIndex("skip", o.args); start >= 0 {
+		return fmt.Errorf("-skip can not be used with --skip-tests-file")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestParseTestsFile(t *testing.T) {
+	in := `# tests selected for this change
+example.com/a TestOne
+example.com/a.TestTwo
+
+gopkg.in/yaml.v3.TestDecode
+example.com/a TestOne
+`
+	tests, err := parseTestsFile(strings.NewReader(in))
+	assert.NilError(t, err)
+	expected := map[string][]string{
+		"example.com/a":    {"TestOne", "TestTwo"},
+		"gopkg.in/yaml.v3": {"TestDecode"},
+	}
+	assert.DeepEqual(t, tests, expected)
+
+	t.Run("invalid line", func(t *testing.T) {
+		_, err := parseTestsFile(strings.NewReader("example.com/a TestOne\nexample.com/a.TestTwo/sub\n"))
+		assert.Error(t, err, `line 2: "example.com/a.TestTwo/sub" is not a package and a top level test name`)
+	})
+}
+
+func TestGroupTests(t *testing.T) {
+	groups := groupTests(map[string][]string{
+		"example.com/c": {"TestOne"},
+		"example.com/a": {"TestTwo", "TestOne"},
+		"example.com/b": {"TestOne", "TestTwo"},
+	}, func(names []string) []string {
+		return []string{runFlagForTests(names)}
+	})
+	expected := []testGroup{
+		{packages: []string{"example.com/a", "example.com/b"}, args: []string{"-run=^(TestOne|TestTwo)$"}},
+		{packages: []string{"example.com/c"}, args: []string{"-run=^(TestOne)$"}},
+	}
+	assert.DeepEqual(t, groups, expected, cmpTestGroup)
+}
+
+var cmpTestGroup = cmp.AllowUnexported(testGroup{})
+
+func TestSelectTestGroups(t *testing.T) {
+	orig := goListPackagesFn
+	goListPackagesFn = func(patterns []string) ([]string, error) {
+		assert.DeepEqual(t, patterns, []string{"./..."})
+		return []string{"example.com/a", "example.com/b", "example.com/c"}, nil
+	}
+	t.Cleanup(func() { goListPackagesFn = orig })
+
+	dir := fs.NewDir(t, "tests-file",
+		fs.WithFile("run.txt", "example.com/a TestOne\nexample.com/a TestTwo\nexample.com/b TestOne\n"),
+		fs.WithFile("skip.txt", "example.com/a TestTwo\nexample.com/b TestOne\nexample.com/c TestThree\n"))
+
+	t.Run("skip tests", func(t *testing.T) {
+		opts := &options{skipTestsFile: dir.Join("skip.txt")}
+		groups, err := selectTestGroups(opts)
+		assert.NilError(t, err)
+		expected := []testGroup{
+			{packages: []string{"example.com/a"}, args: []string{"-skip=^(TestTwo)$"}},
+			{packages: []string{"example.com/b"}, args: []string{"-skip=^(TestOne)$"}},
+			{packages: []string{"example.com/c"}, args: []string{"-skip=^(TestThree)$"}},
+		}
+		assert.DeepEqual(t, groups, expected, cmpTestGroup)
+	})
+	t.Run("run and skip tests", func(t *testing.T) {
+		opts := &options{runTestsFile: dir.Join("run.txt"), skipTestsFile: dir.Join("skip.txt")}
+		groups, err := selectTestGroups(opts)
+		assert.NilError(t, err)
+		expected := []testGroup{
+			{packages: []string{"example.com/a"}, args: []string{"-run=^(TestOne)$"}},
+		}
+		assert.DeepEqual(t, groups, expected, cmpTestGroup)
+	})
+	t.Run("neither", func(t *testing.T) {
+		groups, err := selectTestGroups(&options{})
+		assert.NilError(t, err)
+		assert.Assert(t, groups == nil)
+	})
+}
+
+func TestGoTestGroups_Start(t *testing.T) {
+	var commands [][]string
+	reset := patchStartGoTestFn(func(args []string) *proc {
+		commands = append(commands, args)
+		pkg := args[len(args)-1]
+		result := error(nil)
+		if pkg == "example.com/a" {
+			result = newExitCode("failed", 1)
+		}
+		return &proc{
+			cmd:    fakeWaiter{result: result},
+			stdout: strings.NewReader(`{"Package": "` + pkg + `", "Action": "pass"}` + "\n"),
+			stderr: strings.NewReader("stderr of " + pkg + "\n"),
+		}
+	})
+	defer reset()
+
+	groups := []testGroup{
+		{packages: []string{"example.com/a"}, args: []string{"-run=^(TestOne)$"}},
+		{packages: []string{"example.com/b"}, args: []string{"-skip=^(TestTwo)$"}},
+	}
+	opts := &options{args: []string{"-race"}}
+	goTestProc := (&goTestGroups{groups: groups, parallel: 1}).Start(context.Background(), opts)
+
+	stderr := new(bytes.Buffer)
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(stderr, goTestProc.stderr)
+		close(done)
+	}()
+	stdout, err := io.ReadAll(goTestProc.stdout)
+	assert.NilError(t, err)
+	<-done
+
+	assert.Equal(t, string(stdout), `{"Package": "example.com/a", "Action": "pass"}
+{"Package": "example.com/b", "Action": "pass"}
+`)
+	assert.Equal(t, stderr.String(), "stderr of example.com/a\nstderr of example.com/b\n")
+	assert.ErrorContains(t, goTestProc.cmd.Wait(), "failed")
+	assert.DeepEqual(t, commands, [][]string{
+		{"go", "test", "-json", "-race", "-run=^(TestOne)$", "example.com/a"},
+		{"go", "test", "-json", "-race", "-skip=^(TestTwo)$", "example.com/b"},
+	})
+}
@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// tmpDirSandbox is a temporary directory used as the TMPDIR of 'go test'. Any
+// files left in the directory after the run are reported as warnings, because
+// tests should clean up after themselves (ex: by using t.TempDir).
+//
+// A nil tmpDirSandbox is valid, and does nothing.
+type tmpDirSandbox struct {
+	dir string
+}
+
+func newTmpDirSandbox(opts *options) (*tmpDirSandbox, error) {
+	if !opts.sandboxTmpDir {
+		return nil, nil
+	}
+	dir, err := os.MkdirTemp("", "gotestsum-sandbox-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox TMPDIR: %w", err)
+	}
+	log.Debugf("using sandbox TMPDIR %v", dir)
+	return &tmpDirSandbox{dir: dir}, nil
+}
+
+// Env returns the environment variables that should be set on the 'go test'
+// process.
+func (s *tmpDirSandbox) Env() []string {
+	if s == nil {
+		return nil
+	}
+	env := []string{"TMPDIR=" + s.dir, "TMP=" + s.dir, "TEMP=" + s.dir}
+	// Keep the build work directory of 'go test' out of the sandbox.
+	if _, ok := os.LookupEnv("GOTMPDIR"); !ok {
+		env = append(env, "GOTMPDIR="+os.TempDir())
+	}
+	return env
+}
+
+// Close warns about any files left in the sandbox, and removes the directory.
+func (s *tmpDirSandbox) Close() {
+	if s == nil {
+		return
+	}
+	leftover, err := leftoverFiles(s.dir)
+	if err != nil {
+		log.Warnf("failed to check sandbox TMPDIR for leftover files: %v", err)
+	}
+	if len(leftover) > 0 {
+		log.Warnf("tests left %d files in TMPDIR, use t.TempDir() to have them removed: %v",
+			len(leftover), formatLeftoverFiles(leftover))
+	}
+	if err := os.RemoveAll(s.dir); err != nil {
+		log.Warnf("failed to remove sandbox TMPDIR %v: %v", s.dir, err)
+	}
+}
+
+// leftoverFiles returns the paths, relative to dir, of all the files in the
+// sandbox dir.
+func leftoverFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+const maxLeftoverFilesShown = 10
+
+func formatLeftoverFiles(files []string) string {
+	if len(files) <= maxLeftoverFilesShown {
+		return strings.Join(files, ", ")
+	}
+	return fmt.Sprintf("%s, and %d more",
+		strings.Join(files[:maxLeftoverFilesShown], ", "),
+		len(files)-maxLeftoverFilesShown)
+}
+
+// sandboxWorkDirEnv is the environment variable which sets the working
+// directory of the test binary run by RunSandboxExec.
+const sandboxWorkDirEnv = "GOTESTSUM_SANDBOX_WORKDIR"
+
+// packageSandboxes is used by --sandbox-packages to give every go test command
+// of a package its own sandbox. A sandbox is a new working directory with a
+// copy of the testdata directory of the package, and a new TMPDIR, so that
+// packages which run at the same time can not write to the same relative path
+// or temporary file. The test binary is run in the working directory by
+// passing gotestsum sandbox-exec as the -exec of go test.
+//
+// A nil packageSandboxes is valid, and does nothing.
+type packageSandboxes struct {
+	dir     string
+	execArg string
+
+	mu        sync.Mutex
+	sandboxes []packageSandbox
+}
+
+type packageSandbox struct {
+	pkg string
+	dir string
+}
+
+func newPackageSandboxes(opts *options) (*packageSandboxes, error) {
+	if !opts.sandboxPackages {
+		return nil, nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the gotestsum executable for --sandbox-packages: %w", err)
+	}
+	execArg, err := quoteExecArg(exe)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "gotestsum-sandbox-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create package sandboxes: %w", err)
+	}
+	log.Debugf("using package sandboxes in %v", dir)
+	return &packageSandboxes{dir: dir, execArg: "-exec=" + execArg + " sandbox-exec"}, nil
+}
+
+// quoteExecArg quotes path in the way go test splits the value of -exec.
+func quoteExecArg(path string) (string, error) {
+	switch {
+	case !strings.ContainsAny(path, " \t\n'\""):
+		return path, nil
+	case !strings.Contains(path, "'"):
+		return "'" + path + "'", nil
+	case !strings.Contains(path, `"`):
+		return `"` + path + `"`, nil
+	}
+	return "", fmt.Errorf("the path of the gotestsum executable %q can not be used as the -exec of go test", path)
+}
+
+// Args returns the go test args which run the test binary in the sandbox.
+func (s *packageSandboxes) Args() []string {
+	if s == nil {
+		return nil
+	}
+	return []string{s.execArg}
+}
+
+// Prepare a new sandbox for a go test command which tests pkg, and return the
+// environment variables of the command. Every call returns a new sandbox, so
+// that a rerun starts with a new copy of the testdata directory.
+func (s *packageSandboxes) Prepare(pkg string) ([]string, error) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.Lock()
+	dir := filepath.Join(s.dir, fmt.Sprintf("%d", len(s.sandboxes)+1))
+	s.sandboxes = append(s.sandboxes, packageSandbox{pkg: pkg, dir: dir})
+	s.mu.Unlock()
+
+	workDir, tmpDir := filepath.Join(dir, "work"), filepath.Join(dir, "tmp")
+	for _, d := range []string{workDir, tmpDir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create the sandbox of %v: %w", pkg, err)
+		}
+	}
+	pkgDir, err := goListDirFn(pkg)
+	if err != nil {
+		return nil, err
+	}
+	testdata := filepath.Join(pkgDir, "testdata")
+	if err := copyDir(testdata, filepath.Join(workDir, "testdata")); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to copy the testdata of %v to its sandbox: %w", pkg, err)
+	}
+	log.Debugf("sandbox of %v: %v", pkg, dir)
+	env := []string{
+		sandboxWorkDirEnv + "=" + workDir,
+		"TMPDIR=" + tmpDir, "TMP=" + tmpDir, "TEMP=" + tmpDir,
+	}
+	// Keep the build work directory of 'go test' out of the sandbox.
+	if _, ok := os.LookupEnv("GOTMPDIR"); !ok {
+		env = append(env, "GOTMPDIR="+os.TempDir())
+	}
+	return env, nil
+}
+
+// Close warns about any files left in the sandboxes, and removes them. Files
+// in the copy of the testdata directory are not reported.
+func (s *packageSandboxes) Close() {
+	if s == nil {
+		return
+	}
+	for _, sandbox := range s.sandboxes {
+		files, err := leftoverFiles(sandbox.dir)
+		if err != nil {
+			log.Warnf("failed to check the sandbox of %v for leftover files: %v", sandbox.pkg, err)
+		}
+		files = slices.DeleteFunc(files, func(name string) bool {
+			return strings.HasPrefix(name, "work/testdata/")
+		})
+		if len(files) > 0 {
+			log.Warnf("tests in %v left %d files in their sandbox, use t.TempDir() to have them removed: %v",
+				sandbox.pkg, len(files), formatLeftoverFiles(files))
+		}
+	}
+	if err := os.RemoveAll(s.dir); err != nil {
+		log.Warnf("failed to remove package sandboxes %v: %v", s.dir, err)
+	}
+}
+
+// copyDir copies the files in src to dst, which must not exist.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0o700)
+		case d.Type()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, raw, info.Mode().Perm())
+	})
+}
+
+// goListDirFn is a shim for testing
+var goListDirFn = goListDir
+
+// goListDir returns the source directory of the package.
+func goListDir(pkg string) (string, error) {
+	cmd := exec.Command("go", "list", "-f", "{{.Dir}}", pkg)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go list failed: %w\n%s", err, stderr)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// RunSandboxExec runs the test binary in args in the working directory set by
+// GOTESTSUM_SANDBOX_WORKDIR. It is the -exec of go test used by
+// --sandbox-packages.
+func RunSandboxExec(args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing test binary to run")
+	}
+	dir := os.Getenv(sandboxWorkDirEnv)
+	if dir == "" {
+		return fmt.Errorf("%v is not set, sandbox-exec is only used by --sandbox-packages", sandboxWorkDirEnv)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
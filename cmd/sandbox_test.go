@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/skip"
+)
+
+func TestTmpDirSandbox(t *testing.T) {
+	sandbox, err := newTmpDirSandbox(&options{sandboxTmpDir: true})
+	assert.NilError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(sandbox.dir) })
+
+	env := sandbox.Env()
+	assert.Assert(t, len(env) >= 3)
+	assert.Equal(t, env[0], "TMPDIR="+sandbox.dir)
+
+	assert.NilError(t, os.WriteFile(filepath.Join(sandbox.dir, "one"), nil, 0o600))
+	assert.NilError(t, os.MkdirAll(filepath.Join(sandbox.dir, "a", "b"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(sandbox.dir, "a", "b", "two"), nil, 0o600))
+	assert.NilError(t, os.MkdirAll(filepath.Join(sandbox.dir, "empty"), 0o755))
+
+	files, err := leftoverFiles(sandbox.dir)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, files, []string{"a/b/two", "one"})
+
+	sandbox.Close()
+	_, err = os.Stat(sandbox.dir)
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestTmpDirSandbox_Disabled(t *testing.T) {
+	sandbox, err := newTmpDirSandbox(&options{})
+	assert.NilError(t, err)
+	assert.Assert(t, sandbox == nil)
+	assert.Assert(t, sandbox.Env() == nil)
+	sandbox.Close()
+}
+
+func TestFormatLeftoverFiles(t *testing.T) {
+	assert.Equal(t, formatLeftoverFiles([]string{"a", "b"}), "a, b")
+
+	var files []string
+	for i := 0; i < 12; i++ {
+		files = append(files, string(rune('a'+i)))
+	}
+	assert.Equal(t, formatLeftoverFiles(files), "a, b, c, d, e, f, g, h, i, j, and 2 more")
+}
+
+func TestRun_WithSandboxTmpDir(t *testing.T) {
+	var tmpDir string
+	orig := startGoTestFn
+	startGoTestFn = func(_ context.Context, _ string, _ []string, env ...string) (*proc, error) {
+		for _, v := range env {
+			if dir, ok := strings.CutPrefix(v, "TMPDIR="); ok {
+				tmpDir = dir
+			}
+		}
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(`{"Package":"pkg","Action":"pass"}` + "\n"),
+			stderr: strings.NewReader(""),
+		}, nil
+	}
+	t.Cleanup(func() { startGoTestFn = orig })
+
+	out := new(bytes.Buffer)
+	err := run(&options{
+		rawCommand:    true,
+		args:          []string{"./test.test"},
+		format:        "none",
+		sandboxTmpDir: true,
+		hideSummary:   newHideSummaryValue(),
+		stdout:        out,
+		stderr:        out,
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, tmpDir != "")
+	_, err = os.Stat(tmpDir)
+	assert.Assert(t, os.IsNotExist(err), "expected sandbox to be removed")
+}
+
+func TestPackageSandboxes(t *testing.T) {
+	pkgDir := fs.NewDir(t, t.Name(),
+		fs.WithDir("testdata",
+			fs.WithFile("input.txt", "input"),
+			fs.WithDir("nested", fs.WithFile("more.txt", "more"))))
+	orig := goListDirFn
+	goListDirFn = func(pkg string) (string, error) {
+		assert.Equal(t, pkg, "example.com/pkg")
+		return pkgDir.Path(), nil
+	}
+	t.Cleanup(func() { goListDirFn = orig })
+
+	logs := new(bytes.Buffer)
+	prev := log.SetOutput(logs)
+	t.Cleanup(func() { log.SetOutput(prev) })
+
+	sandboxes, err := newPackageSandboxes(&options{sandboxPackages: true})
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(sandboxes.Args()[0], "-exec="))
+	assert.Assert(t, strings.HasSuffix(sandboxes.Args()[0], " sandbox-exec"))
+
+	first, err := sandboxes.Prepare("example.com/pkg")
+	assert.NilError(t, err)
+	second, err := sandboxes.Prepare("example.com/pkg")
+	assert.NilError(t, err)
+	assert.Assert(t, first[0] != second[0], "every command has its own sandbox")
+
+	env := envMap(first)
+	expected := fs.Expected(t, fs.MatchAnyFileMode,
+		fs.WithDir("testdata",
+			fs.WithFile("input.txt", "input"),
+			fs.WithDir("nested", fs.WithFile("more.txt", "more"))))
+	assert.Assert(t, fs.Equal(env[sandboxWorkDirEnv], expected))
+	assert.Equal(t, env["TMPDIR"], filepath.Join(filepath.Dir(env[sandboxWorkDirEnv]), "tmp"))
+
+	assert.NilError(t, os.WriteFile(filepath.Join(env["TMPDIR"], "one"), nil, 0o600))
+	assert.NilError(t, os.WriteFile(filepath.Join(env[sandboxWorkDirEnv], "two"), nil, 0o600))
+	assert.NilError(t, os.WriteFile(filepath.Join(env[sandboxWorkDirEnv], "testdata", "golden"), nil, 0o600))
+
+	sandboxes.Close()
+	assert.Equal(t, logs.String(), "WARN tests in example.com/pkg left 2 files in their sandbox, "+
+		"use t.TempDir() to have them removed: tmp/one, work/two\n")
+	_, err = os.Stat(sandboxes.dir)
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestPackageSandboxes_Disabled(t *testing.T) {
+	sandboxes, err := newPackageSandboxes(&options{})
+	assert.NilError(t, err)
+	assert.Assert(t, sandboxes == nil)
+	assert.Assert(t, sandboxes.Args() == nil)
+	env, err := sandboxes.Prepare("example.com/pkg")
+	assert.NilError(t, err)
+	assert.Assert(t, env == nil)
+	sandboxes.Close()
+}
+
+func envMap(env []string) map[string]string {
+	result := make(map[string]string, len(env))
+	for _, v := range env {
+		key, value, _ := strings.Cut(v, "=")
+		result[key] = value
+	}
+	return result
+}
+
+func TestQuoteExecArg(t *testing.T) {
+	for path, expected := range map[string]string{
+		"/usr/bin/gotestsum":                  "/usr/bin/gotestsum",
+		"/home/a user/gotestsum":              "'/home/a user/gotestsum'",
+		"/home/it's/gotestsum":                `"/home/it's/gotestsum"`,
+		`C:\Program Files\gotestsum.exe`:      `'C:\Program Files\gotestsum.exe'`,
+		"/home/it's a \"path\"/gotestsum.exe": "",
+	} {
+		actual, err := quoteExecArg(path)
+		if expected == "" {
+			assert.ErrorContains(t, err, "can not be used as the -exec of go test")
+			continue
+		}
+		assert.NilError(t, err)
+		assert.Equal(t, actual, expected)
+	}
+}
+
+func TestRunSandboxExec(t *testing.T) {
+	skip.If(t, runtime.GOOS == "windows", "sh is not available")
+	dir := fs.NewDir(t, t.Name())
+	t.Setenv(sandboxWorkDirEnv, dir.Path())
+
+	assert.NilError(t, RunSandboxExec([]string{"sh", "-c", "touch created"}))
+	_, err := os.Stat(dir.Join("created"))
+	assert.NilError(t, err)
+
+	err = RunSandboxExec([]string{"sh", "-c", "exit 3"})
+	assert.Equal(t, ExitCodeWithDefault(err), 3)
+}
+
+func TestRun_WithSandboxPackages(t *testing.T) {
+	origList := goListPackagesFn
+	goListPackagesFn = func([]string) ([]string, error) {
+		return []string{"example.com/a", "example.com/b"}, nil
+	}
+	origDir := goListDirFn
+	goListDirFn = func(string) (string, error) {
+		return t.TempDir(), nil
+	}
+	t.Cleanup(func() {
+		goListPackagesFn = origList
+		goListDirFn = origDir
+	})
+
+	var mu sync.Mutex
+	workDirs := make(map[string]string)
+	orig := startGoTestFn
+	startGoTestFn = func(_ context.Context, _ string, args []string, env ...string) (*proc, error) {
+		pkg := args[len(args)-1]
+		assert.Check(t, strings.HasPrefix(args[3], "-exec="), args)
+		mu.Lock()
+		workDirs[pkg] = envMap(env)[sandboxWorkDirEnv]
+		mu.Unlock()
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(`{"Package":"` + pkg + `","Action":"pass"}` + "\n"),
+			stderr: strings.NewReader(""),
+		}, nil
+	}
+	t.Cleanup(func() { startGoTestFn = orig })
+
+	out := new(bytes.Buffer)
+	err := run(&options{
+		perPackage:      true,
+		sandboxPackages: true,
+		format:          "none",
+		hideSummary:     newHideSummaryValue(),
+		stdout:          out,
+		stderr:          out,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(workDirs), 2)
+	assert.Assert(t, workDirs["example.com/a"] != "")
+	assert.Assert(t, workDirs["example.com/a"] != workDirs["example.com/b"])
+}
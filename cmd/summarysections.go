@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/formatexec"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// summarySections are the sections which can be added to the summary with
+// --summary-section, by name.
+var summarySections = map[string]testjson.SummarySection{}
+
+// registerSummarySection adds a section which can be selected by name with
+// --summary-section. It panics if the name is already registered.
+func registerSummarySection(name string, section testjson.SummarySection) {
+	if _, exists := summarySections[name]; exists {
+		panic(fmt.Sprintf("summary section %q is already registered", name))
+	}
+	summarySections[name] = section
+}
+
+func init() {
+	registerSummarySection("coverage", coverageSection{})
+	registerSummarySection("flaky", flakySection{})
+}
+
+func summarySectionNames() string {
+	names := make([]string, 0, len(summarySections))
+	for name := range summarySections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// summarySectionValue is a flag.Value which accepts the name of a registered
+// section, or NAME=exec:COMMAND to render a section with a command. The flag
+// may be repeated to add more than one section.
+type summarySectionValue struct {
+	specs []summarySectionSpec
+}
+
+type summarySectionSpec struct {
+	name    string
+	command []string
+}
+
+func (v *summarySectionValue) Set(raw string) error {
+	name, format, isExec := strings.Cut(raw, "=")
+	if !isExec {
+		if _, ok := summarySections[name]; !ok {
+			return fmt.Errorf("unknown summary section %q, must be one of: %v, or NAME=exec:COMMAND",
+				name, summarySectionNames())
+		}
+		v.specs = append(v.specs, summarySectionSpec{name: name})
+		return nil
+	}
+	command, ok, err := formatexec.Command(format)
+	switch {
+	case err != nil:
+		return err
+	case !ok:
+		return fmt.Errorf("summary section %q must use the format NAME=exec:COMMAND", raw)
+	case name == "":
+		return fmt.Errorf("summary section %q requires a name", raw)
+	}
+	v.specs = append(v.specs, summarySectionSpec{name: name, command: command})
+	return nil
+}
+
+func (v *summarySectionValue) Type() string {
+	return "section"
+}
+
+func (v *summarySectionValue) String() string {
+	names := make([]string, 0, len(v.specs))
+	for _, spec := range v.specs {
+		names = append(names, spec.name)
+	}
+	return strings.Join(names, ",")
+}
+
+// sections returns the sections to add to the summary. The sections which run
+// a command receive the summary of the run, with exitErr, on stdin.
+func (v *summarySectionValue) sections(opts *options, exitErr error) []testjson.SummarySection {
+	var sections []testjson.SummarySection
+	for _, spec := range v.specs {
+		if len(spec.command) == 0 {
+			sections = append(sections, summarySections[spec.name])
+			continue
+		}
+		sections = append(sections, &execSection{
+			name:    spec.name,
+			command: spec.command,
+			opts:    opts,
+			exitErr: exitErr,
+		})
+	}
+	return sections
+}
+
+// coverageSection prints the coverage of each package which was run with
+// -cover.
+type coverageSection struct{}
+
+func (coverageSection) Name() string {
+	return "Coverage"
+}
+
+func (coverageSection) Render(exec *testjson.Execution) string {
+	buf := new(strings.Builder)
+	for _, name := range exec.Packages() {
+		percent := coveragePercent(exec.Package(name).Coverage())
+		if percent == "" {
+			continue
+		}
+		fmt.Fprintf(buf, "%7s %s\n", percent, testjson.RelativePackagePath(name))
+	}
+	return buf.String()
+}
+
+// flakySection prints the tests which failed, and passed when they were rerun.
+type flakySection struct{}
+
+func (flakySection) Name() string {
+	return "Flaky"
+}
+
+func (flakySection) Render(exec *testjson.Execution) string {
+	buf := new(strings.Builder)
+	for _, tc := range testjson.FilterFailedUnique(exec.Failed()) {
+		if tc.Test == "" || !exec.Package(tc.Package).PassedOnRerun(tc) {
+			continue
+		}
+		fmt.Fprintf(buf, "=== FLAKY: %s %s\n", testjson.RelativePackagePath(tc.Package), tc.Test)
+	}
+	return buf.String()
+}
+
+// execSection runs a command to render a section. The summary of the run is
+// written as JSON to the stdin of the command, in the same format as the stdin
+// of --post-run-command, and the stdout of the command is the text of the
+// section.
+type execSection struct {
+	name    string
+	command []string
+	opts    *options
+	exitErr error
+}
+
+func (s *execSection) Name() string {
+	return s.name
+}
+
+func (s *execSection) Render(execution *testjson.Execution) string {
+	raw, err := json.Marshal(newRunSummary(s.opts, execution, s.exitErr))
+	if err != nil {
+		log.Warnf("summary section %v: %v", s.name, err)
+		return ""
+	}
+	log.Debugf("exec: %s", s.command)
+	stdout := new(bytes.Buffer)
+	cmd := exec.Command(s.command[0], s.command[1:]...)
+	cmd.Stdin = bytes.NewReader(append(raw, '\n'))
+	cmd.Stdout = stdout
+	cmd.Stderr = s.opts.stderr
+	if err := cmd.Run(); err != nil {
+		log.Warnf("summary section %v: command failed: %v", s.name, err)
+		return ""
+	}
+	return stdout.String()
+}
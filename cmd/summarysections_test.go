@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/skip"
+)
+
+func TestSummarySectionValue_Set(t *testing.T) {
+	value := &summarySectionValue{}
+	assert.NilError(t, value.Set("coverage"))
+	assert.NilError(t, value.Set("owners=exec:./owners --format 'short text'"))
+	assert.DeepEqual(t, value.specs, []summarySectionSpec{
+		{name: "coverage"},
+		{name: "owners", command: []string{"./owners", "--format", "short text"}},
+	}, cmpSummarySectionSpec)
+	assert.Equal(t, value.String(), "coverage,owners")
+
+	assert.ErrorContains(t, value.Set("unknown"),
+		`unknown summary section "unknown", must be one of: coverage, flaky, or NAME=exec:COMMAND`)
+	assert.ErrorContains(t, value.Set("owners=./owners"), "must use the format NAME=exec:COMMAND")
+	assert.ErrorContains(t, value.Set("=exec:./owners"), "requires a name")
+	assert.ErrorContains(t, value.Set("owners=exec:"), "a command is required after exec:")
+}
+
+var cmpSummarySectionSpec = cmp.AllowUnexported(summarySectionSpec{})
+
+func newExecWithRerun(t *testing.T) *testjson.Execution {
+	t.Helper()
+	input := `{"Action":"run","Package":"example.com/cart","Test":"TestAdd"}
+{"Action":"pass","Package":"example.com/cart","Test":"TestAdd","Elapsed":0.01}
+{"Action":"run","Package":"example.com/cart","Test":"TestCheckout"}
+{"Action":"fail","Package":"example.com/cart","Test":"TestCheckout","Elapsed":0.25}
+{"Action":"run","Package":"example.com/cart","Test":"TestRefund"}
+{"Action":"fail","Package":"example.com/cart","Test":"TestRefund","Elapsed":1.5}
+{"Action":"output","Package":"example.com/cart","Output":"coverage: 81.3% of statements\n"}
+{"Action":"fail","Package":"example.com/cart","Elapsed":1.8}
+{"Action":"output","Package":"example.com/users","Output":"ok  \texample.com/users\t(cached)\tcoverage: 100.0% of statements\n"}
+{"Action":"pass","Package":"example.com/users","Elapsed":0}
+{"Action":"pass","Package":"example.com/orders","Elapsed":0}
+`
+	rerun := `{"Action":"run","Package":"example.com/cart","Test":"TestCheckout"}
+{"Action":"pass","Package":"example.com/cart","Test":"TestCheckout","Elapsed":0.2}
+{"Action":"run","Package":"example.com/cart","Test":"TestRefund"}
+{"Action":"fail","Package":"example.com/cart","Test":"TestRefund","Elapsed":1.5}
+{"Action":"fail","Package":"example.com/cart","Elapsed":0.3}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(input),
+	})
+	assert.NilError(t, err)
+	exec, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:    strings.NewReader(rerun),
+		Execution: exec,
+		RunID:     1,
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+func TestCoverageSection(t *testing.T) {
+	exec := newExecWithRerun(t)
+	expected := `  81.3% example.com/cart
+ 100.0% example.com/users
+`
+	assert.Equal(t, coverageSection{}.Render(exec), expected)
+}
+
+func TestFlakySection(t *testing.T) {
+	exec := newExecWithRerun(t)
+	expected := "=== FLAKY: example.com/cart TestCheckout\n"
+	assert.Equal(t, flakySection{}.Render(exec), expected)
+}
+
+func TestExecSection(t *testing.T) {
+	skip.If(t, runtime.GOOS == "windows", "cat is not available")
+	exec := newExecWithRerun(t)
+
+	stderr := new(bytes.Buffer)
+	opts := &options{stderr: stderr}
+	value := &summarySectionValue{}
+	assert.NilError(t, value.Set("copy=exec:cat"))
+	sections := value.sections(opts, exitError{num: 1})
+	assert.Equal(t, len(sections), 1)
+	assert.Equal(t, sections[0].Name(), "copy")
+
+	out := sections[0].Render(exec)
+	assert.Assert(t, strings.HasPrefix(out, `{"status":"failed","exit_code":1,`), out)
+	assert.Assert(t, strings.Contains(out, `"flaky":[{"package":"example.com/cart","test":"TestCheckout"`), out)
+	assert.Equal(t, stderr.String(), "")
+}
+
+func TestExecSection_CommandFails(t *testing.T) {
+	skip.If(t, runtime.GOOS == "windows", "false is not available")
+	value := &summarySectionValue{}
+	assert.NilError(t, value.Set("broken=exec:false"))
+	sections := value.sections(&options{}, nil)
+	assert.Equal(t, sections[0].Render(newExecWithRerun(t)), "")
+}
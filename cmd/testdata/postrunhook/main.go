@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -26,6 +27,16 @@ func run() error {
 		}
 	}
 
+	var summary struct {
+		Status string
+		Totals struct{ Tests, Failed int }
+	}
+	if err := json.NewDecoder(os.Stdin).Decode(&summary); err != nil {
+		return fmt.Errorf("failed to read summary from stdin: %w", err)
+	}
+	fmt.Printf("stdin: status=%v tests=%d failed=%d\n",
+		summary.Status, summary.Totals.Tests, summary.Totals.Failed)
+
 	err := os.Getenv("TEST_STUB_ERROR")
 	if err != "" {
 		return errors.New(err)
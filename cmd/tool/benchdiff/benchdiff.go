@@ -0,0 +1,220 @@
+package benchdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	if flags.NArg() != 2 {
+		usage(os.Stderr, name, flags)
+		return fmt.Errorf("expected 2 arguments, OLD and NEW, got %d", flags.NArg())
+	}
+	opts.old, opts.new = flags.Arg(0), flags.Arg(1)
+	return run(opts)
+}
+
+type options struct {
+	old                 string
+	new                 string
+	format              string
+	regressionThreshold float64
+	failOnRegression    bool
+	debug               bool
+	stdout              io.Writer
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{stdout: os.Stdout}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.format, "format", "text",
+		"format of the report, one of: text, json")
+	flags.Float64Var(&opts.regressionThreshold, "regression-threshold", 10,
+		"report benchmarks which are worse than OLD by more than this percent")
+	flags.BoolVar(&opts.failOnRegression, "fail-on-regression", false,
+		"exit with a non-zero exit code when there are regressions")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] OLD NEW
+
+Compare the results of the benchmarks in two runs, and print the change of
+every metric, like ns/op, B/op, and allocs/op. OLD and NEW are json files
+created with 'gotestsum --jsonfile' or 'go test -json', or glob patterns which
+match many json files.
+
+When a benchmark has more than one result, from -count or from many files, the
+median of the results is compared. A metric is a regression when it is worse
+in NEW by more than --regression-threshold percent. A higher value is worse,
+except for metrics with a unit per second, like MB/s, where a lower value is
+worse.
+
+Example:
+
+    go test -json -run=^$ -bench=. -count=5 ./... > new.json
+    %[1]s --fail-on-regression main.json new.json
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	write, err := reportWriter(opts.format)
+	if err != nil {
+		return err
+	}
+	oldResults, err := loadResults(opts.old)
+	if err != nil {
+		return err
+	}
+	newResults, err := loadResults(opts.new)
+	if err != nil {
+		return err
+	}
+
+	result := compare(oldResults, newResults, opts.regressionThreshold/100)
+	if err := write(opts.stdout, result); err != nil {
+		return err
+	}
+	if opts.failOnRegression && result.regressions() > 0 {
+		return fmt.Errorf("%d benchmark metrics are worse than %v by more than %v%%",
+			result.regressions(), opts.old, opts.regressionThreshold)
+	}
+	return nil
+}
+
+// loadResults returns the results of the benchmarks in every json file
+// matched by pattern.
+func loadResults(pattern string) (map[benchmarkKey]*benchmarkResults, error) {
+	fileNames, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %v: %w", pattern, err)
+	}
+	if len(fileNames) == 0 {
+		return nil, fmt.Errorf("no files match %v", pattern)
+	}
+
+	results := make(map[benchmarkKey]*benchmarkResults)
+	for _, fileName := range fileNames {
+		exec, err := jsonfile.Scan(fileName, testjson.ScanConfig{})
+		if err != nil {
+			return nil, err
+		}
+		benchmarks := exec.Benchmarks()
+		log.Debugf("read %d benchmark results from %v", len(benchmarks), fileName)
+		addResults(results, benchmarks)
+	}
+	return results, nil
+}
+
+func reportWriter(format string) (func(io.Writer, result) error, error) {
+	switch format {
+	case "text":
+		return writeText, nil
+	case "json":
+		return writeJSON, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q, must be one of: text, json", format)
+	}
+}
+
+func writeText(out io.Writer, r result) error {
+	b := new(strings.Builder)
+	if len(r.Metrics) > 0 {
+		rows := [][]string{{"BENCHMARK", "UNIT", "OLD", "NEW", "DELTA", ""}}
+		for _, m := range r.Metrics {
+			var status string
+			if m.Regression {
+				status = "regression"
+			}
+			rows = append(rows, []string{
+				testjson.RelativePackagePath(m.Package) + " " + m.Benchmark,
+				m.Unit,
+				formatValue(m.Old),
+				formatValue(m.New),
+				fmt.Sprintf("%+.2f%%", m.ChangePercent),
+				status,
+			})
+		}
+		writeTable(b, rows)
+	}
+	section := func(title string, benchmarks []benchmark) {
+		if len(benchmarks) == 0 {
+			return
+		}
+		fmt.Fprintf(b, "=== %s (%d)\n", title, len(benchmarks))
+		for _, bench := range benchmarks {
+			fmt.Fprintf(b, "%s %s\n", testjson.RelativePackagePath(bench.Package), bench.Benchmark)
+		}
+	}
+	section("Added", r.Added)
+	section("Removed", r.Removed)
+	if b.Len() == 0 {
+		b.WriteString("No benchmarks\n")
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// writeTable writes rows with the columns aligned. The first column is
+// aligned to the left, and the values are aligned to the right.
+func writeTable(out io.Writer, rows [][]string) {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	for _, row := range rows {
+		line := row[0] + strings.Repeat(" ", widths[0]-len(row[0]))
+		for i, cell := range row[1 : len(row)-1] {
+			line += strings.Repeat(" ", widths[i+1]-len(cell)+2) + cell
+		}
+		if last := row[len(row)-1]; last != "" {
+			line += "  " + last
+		}
+		fmt.Fprintln(out, line)
+	}
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func writeJSON(out io.Writer, r result) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
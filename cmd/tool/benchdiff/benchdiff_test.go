@@ -0,0 +1,56 @@
+package benchdiff
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestRun(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		format string
+		golden string
+	}{
+		{name: "text", format: "text", golden: "benchdiff.out"},
+		{name: "json", format: "json", golden: "benchdiff.json"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			opts := &options{
+				old:                 "testdata/old.json",
+				new:                 "testdata/new.json",
+				format:              tc.format,
+				regressionThreshold: 10,
+				stdout:              out,
+			}
+			assert.NilError(t, run(opts))
+			golden.Assert(t, out.String(), tc.golden)
+		})
+	}
+}
+
+func TestRun_FailOnRegression(t *testing.T) {
+	opts := &options{
+		old:                 "testdata/old.json",
+		new:                 "testdata/new.json",
+		format:              "text",
+		regressionThreshold: 10,
+		failOnRegression:    true,
+		stdout:              new(bytes.Buffer),
+	}
+	err := run(opts)
+	assert.Error(t, err, "4 benchmark metrics are worse than testdata/old.json by more than 10%")
+
+	opts.old, opts.new = "testdata/new.json", "testdata/new.json"
+	assert.NilError(t, run(opts))
+}
+
+func TestMedian(t *testing.T) {
+	assert.Equal(t, median([]float64{3, 1, 2}), 2.0)
+	assert.Equal(t, median([]float64{4, 1, 3, 2}), 2.5)
+	assert.Equal(t, median([]float64{7}), 7.0)
+}
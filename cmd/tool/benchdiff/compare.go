@@ -0,0 +1,144 @@
+package benchdiff
+
+import (
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+type benchmarkKey struct {
+	pkg string
+	// name of the benchmark with the GOMAXPROCS suffix, because the results
+	// with different values of GOMAXPROCS are not comparable.
+	name string
+}
+
+// benchmarkResults are the values of each metric of a benchmark, from every
+// result in OLD or NEW.
+type benchmarkResults struct {
+	values map[string][]float64
+	// units in the order they were first printed.
+	units []string
+}
+
+// addResults adds the results of benchmarks to results.
+func addResults(results map[benchmarkKey]*benchmarkResults, benchmarks []testjson.Benchmark) {
+	for _, b := range benchmarks {
+		key := benchmarkKey{pkg: b.Package, name: b.NameWithProcs()}
+		r, ok := results[key]
+		if !ok {
+			r = &benchmarkResults{values: make(map[string][]float64)}
+			results[key] = r
+		}
+		for _, m := range b.Metrics {
+			if _, ok := r.values[m.Unit]; !ok {
+				r.units = append(r.units, m.Unit)
+			}
+			r.values[m.Unit] = append(r.values[m.Unit], m.Value)
+		}
+	}
+}
+
+type benchmark struct {
+	Package   string `json:"package"`
+	Benchmark string `json:"benchmark"`
+}
+
+type metric struct {
+	benchmark
+	Unit          string  `json:"unit"`
+	Old           float64 `json:"old"`
+	New           float64 `json:"new"`
+	ChangePercent float64 `json:"change_percent"`
+	Regression    bool    `json:"regression"`
+}
+
+type result struct {
+	Metrics []metric    `json:"metrics"`
+	Added   []benchmark `json:"added"`
+	Removed []benchmark `json:"removed"`
+}
+
+func (r result) regressions() int {
+	var count int
+	for _, m := range r.Metrics {
+		if m.Regression {
+			count++
+		}
+	}
+	return count
+}
+
+// compare the median of each metric of the benchmarks in both oldResults and
+// newResults. threshold is the fraction that a metric must be worse by to be
+// a regression, 0.1 is 10%.
+func compare(oldResults, newResults map[benchmarkKey]*benchmarkResults, threshold float64) result {
+	r := result{Metrics: []metric{}, Added: []benchmark{}, Removed: []benchmark{}}
+	for key, n := range newResults {
+		b := benchmark{Package: key.pkg, Benchmark: key.name}
+		o, ok := oldResults[key]
+		if !ok {
+			r.Added = append(r.Added, b)
+			continue
+		}
+		for _, unit := range n.units {
+			oldValues, ok := o.values[unit]
+			if !ok {
+				continue
+			}
+			r.Metrics = append(r.Metrics, compareMetric(b, unit, oldValues, n.values[unit], threshold))
+		}
+	}
+	for key := range oldResults {
+		if _, ok := newResults[key]; !ok {
+			r.Removed = append(r.Removed, benchmark{Package: key.pkg, Benchmark: key.name})
+		}
+	}
+
+	sort.SliceStable(r.Metrics, func(i, j int) bool {
+		return lessBenchmark(r.Metrics[i].benchmark, r.Metrics[j].benchmark)
+	})
+	for _, benchmarks := range [][]benchmark{r.Added, r.Removed} {
+		sort.Slice(benchmarks, func(i, j int) bool {
+			return lessBenchmark(benchmarks[i], benchmarks[j])
+		})
+	}
+	return r
+}
+
+func compareMetric(b benchmark, unit string, oldValues, newValues []float64, threshold float64) metric {
+	m := metric{benchmark: b, Unit: unit, Old: median(oldValues), New: median(newValues)}
+	if m.Old == 0 {
+		return m
+	}
+	change := (m.New - m.Old) / m.Old
+	m.ChangePercent = change * 100
+	if higherIsBetter(unit) {
+		change = -change
+	}
+	m.Regression = threshold > 0 && change > threshold
+	return m
+}
+
+// higherIsBetter returns true for a metric which is a rate, like MB/s.
+func higherIsBetter(unit string) bool {
+	return strings.HasSuffix(unit, "/s")
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func lessBenchmark(a, b benchmark) bool {
+	if a.Package != b.Package {
+		return a.Package < b.Package
+	}
+	return a.Benchmark < b.Benchmark
+}
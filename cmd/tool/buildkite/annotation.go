@@ -0,0 +1,124 @@
+package buildkite
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/failurereport"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type annotationConfig struct {
+	// MaxFailures is the number of failed tests to include with their output.
+	MaxFailures int
+}
+
+// maxOutputLines is the number of lines of output that are included for each
+// failed test.
+const maxOutputLines = 50
+
+// Values of the --style flag of buildkite-agent annotate.
+const (
+	styleSuccess = "success"
+	styleWarning = "warning"
+	styleError   = "error"
+)
+
+type annotation struct {
+	style string
+	body  string
+}
+
+// packageFailures are the failures of a package. A failure in TestMain is
+// included as a failure named TestMain.
+type packageFailures struct {
+	name     string
+	failures []failurereport.Failure
+}
+
+func newAnnotation(exec *testjson.Execution, cfg annotationConfig) annotation {
+	var failed []packageFailures
+	var flaky []string
+	var numFailed int
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		group := packageFailures{name: name}
+		if pkg.TestMainFailed() {
+			group.failures = append(group.failures,
+				failurereport.Failure{Name: "TestMain", Output: pkg.Output(0)})
+		}
+		for _, tc := range testjson.FilterFailedUnique(pkg.Failed) {
+			if tc.Test == "" {
+				// a failure in TestMain was added above
+				continue
+			}
+			if pkg.PassedOnRerun(tc) {
+				flaky = append(flaky, name+"."+tc.Test.Name())
+				continue
+			}
+			group.failures = append(group.failures, failurereport.Failure{
+				Name:   tc.Test.Name(),
+				Output: failurereport.LastLines(pkg.OutputLines(tc), maxOutputLines),
+			})
+		}
+		if len(group.failures) > 0 {
+			numFailed += len(group.failures)
+			failed = append(failed, group)
+		}
+	}
+
+	style := styleSuccess
+	b := new(strings.Builder)
+	switch {
+	case numFailed > 0 || len(exec.Errors()) > 0:
+		style = styleError
+		b.WriteString("### :x: Tests failed\n\n")
+	case len(flaky) > 0:
+		style = styleWarning
+		b.WriteString("### :warning: Tests passed with flaky tests\n\n")
+	default:
+		b.WriteString("### :white_check_mark: Tests passed\n\n")
+	}
+
+	fmt.Fprintf(b, "**%d tests** in %v", exec.Total(), exec.Elapsed().Round(time.Millisecond))
+	counts := []struct {
+		num  int
+		name string
+	}{
+		{numFailed, "failed"},
+		{len(flaky), "flaky"},
+		{len(exec.Skipped()), "skipped"},
+		{len(exec.Errors()), "errors"},
+	}
+	for _, c := range counts {
+		if c.num > 0 {
+			fmt.Fprintf(b, ", %d %v", c.num, c.name)
+		}
+	}
+	b.WriteString("\n")
+
+	if len(exec.Errors()) > 0 {
+		b.WriteString("\n#### Errors\n\n")
+		failurereport.WriteCodeBlock(b, strings.Join(exec.Errors(), "\n"))
+	}
+
+	var count int
+	for _, group := range failed {
+		if cfg.MaxFailures > 0 && count >= cfg.MaxFailures {
+			fmt.Fprintf(b, "\nand %d more failed tests\n", numFailed-count)
+			break
+		}
+		fmt.Fprintf(b, "\n#### `%v`\n\n", group.name)
+		for _, f := range group.failures {
+			if cfg.MaxFailures > 0 && count >= cfg.MaxFailures {
+				break
+			}
+			count++
+			failurereport.WriteDetails(b, f)
+		}
+	}
+
+	failurereport.WriteFlaky(b, flaky)
+	return annotation{style: style, body: b.String()}
+}
@@ -0,0 +1,124 @@
+package buildkite
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	return run(opts)
+}
+
+type options struct {
+	jsonfile    string
+	output      string
+	context     string
+	maxFailures int
+	debug       bool
+
+	// shims for testing
+	annotate func(context, style, body string) error
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{annotate: agentAnnotate}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.jsonfile, "jsonfile", os.Getenv("GOTESTSUM_JSONFILE"),
+		"path to test2json output, defaults to stdin")
+	flags.StringVar(&opts.output, "output", "",
+		"write the annotation to this file instead of running buildkite-agent annotate")
+	flags.StringVar(&opts.context, "context", "gotestsum",
+		"context of the annotation, an annotation with the same context is replaced")
+	flags.IntVar(&opts.maxFailures, "max-failures", 20,
+		"maximum number of failed tests to include with their output")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Read a json file and add an annotation to a Buildkite build with a summary of
+the test results. The annotation includes the failed tests, grouped by package,
+with their output in a collapsible section, and the tests which only passed
+when they were rerun (flaky tests). The json file may be created with
+'gotestsum --jsonfile' or 'go test -json'.
+
+The annotation is added by running 'buildkite-agent annotate', which replaces
+any previous annotation with the same --context. The style of the annotation
+is error when any test failed, warning when tests were flaky, and success
+otherwise. Use --output to write the Markdown to a file instead, for example
+to add the annotation from a later step.
+
+Example:
+
+    gotestsum --jsonfile test.json --rerun-fails ./...
+    %[1]s --jsonfile test.json
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	in, err := jsonfile.OpenOrStdin(opts.jsonfile)
+	if err != nil {
+		return fmt.Errorf("failed to read jsonfile: %v", err)
+	}
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Errorf("Failed to close file %v: %v", opts.jsonfile, err)
+		}
+	}()
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: in})
+	if err != nil {
+		return fmt.Errorf("failed to scan testjson: %w", err)
+	}
+
+	a := newAnnotation(exec, annotationConfig{MaxFailures: opts.maxFailures})
+	if opts.output != "" {
+		return os.WriteFile(opts.output, []byte(a.body), 0o644)
+	}
+	return opts.annotate(opts.context, a.style, a.body)
+}
+
+// agentAnnotate runs buildkite-agent annotate with body as stdin.
+func agentAnnotate(context, style, body string) error {
+	cmd := exec.Command("buildkite-agent", "annotate", "--context", context, "--style", style)
+	cmd.Stdin = strings.NewReader(body)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	log.Debugf("exec: %s", cmd.Args)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run buildkite-agent annotate: %w", err)
+	}
+	return nil
+}
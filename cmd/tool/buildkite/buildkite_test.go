@@ -0,0 +1,45 @@
+package buildkite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestRun_Annotate(t *testing.T) {
+	var calls []string
+	opts := &options{
+		jsonfile:    "testdata/input.json",
+		context:     "unit-tests",
+		maxFailures: 20,
+		annotate: func(context, style, body string) error {
+			calls = append(calls, context, style)
+			golden.Assert(t, body, "expected-annotation.md")
+			return nil
+		},
+	}
+	assert.NilError(t, run(opts))
+	assert.DeepEqual(t, calls, []string{"unit-tests", "error"})
+}
+
+func TestRun_Output(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	opts := &options{
+		jsonfile:    "testdata/input.json",
+		output:      filepath.Join(dir.Path(), "annotation.md"),
+		maxFailures: 20,
+		annotate: func(string, string, string) error {
+			t.Fatal("buildkite-agent should not run when --output is set")
+			return nil
+		},
+	}
+	assert.NilError(t, run(opts))
+
+	raw, err := os.ReadFile(opts.output)
+	assert.NilError(t, err)
+	golden.Assert(t, string(raw), "expected-annotation.md")
+}
@@ -0,0 +1,184 @@
+package diff
+
+import (
+	"sort"
+	"time"
+
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type testKey struct {
+	pkg  string
+	test testjson.TestName
+}
+
+// testRuns are the results of a test in every run of OLD or NEW.
+type testRuns struct {
+	// failed is true if the test failed in any run, and did not pass when it
+	// was rerun.
+	failed bool
+	passed bool
+	// elapsed is the elapsed time of every passing run of the test.
+	elapsed []time.Duration
+}
+
+// addRun adds the results of the tests in exec to runs.
+func addRun(runs map[testKey]*testRuns, exec *testjson.Execution) {
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		get := func(tc testjson.TestCase) *testRuns {
+			key := testKey{pkg: name, test: tc.Test}
+			r, ok := runs[key]
+			if !ok {
+				r = &testRuns{}
+				runs[key] = r
+			}
+			return r
+		}
+
+		passed := make(map[testjson.TestName]bool)
+		for _, tc := range pkg.Passed {
+			r := get(tc)
+			r.passed = true
+			r.elapsed = append(r.elapsed, tc.Elapsed)
+			passed[tc.Test] = true
+		}
+		for _, tc := range pkg.Failed {
+			if tc.Test == "" {
+				// a failure in TestMain is not a test
+				continue
+			}
+			if r := get(tc); !passed[tc.Test] {
+				r.failed = true
+			}
+		}
+		for _, tc := range pkg.Skipped {
+			get(tc)
+		}
+	}
+}
+
+type compareConfig struct {
+	// threshold is the fraction that a test must be slower by to be a
+	// regression, 0.2 is 20%.
+	threshold  float64
+	minElapsed time.Duration
+}
+
+type test struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+}
+
+type regression struct {
+	test
+	OldElapsedSeconds float64 `json:"old_elapsed_seconds"`
+	NewElapsedSeconds float64 `json:"new_elapsed_seconds"`
+	ChangePercent     float64 `json:"change_percent"`
+	old               time.Duration
+	new               time.Duration
+}
+
+type result struct {
+	NewFailures []test       `json:"new_failures"`
+	Fixed       []test       `json:"fixed"`
+	Added       []test       `json:"added"`
+	Removed     []test       `json:"removed"`
+	Regressions []regression `json:"regressions"`
+}
+
+func compare(oldRuns, newRuns map[testKey]*testRuns, cfg compareConfig) result {
+	r := result{
+		NewFailures: []test{},
+		Fixed:       []test{},
+		Added:       []test{},
+		Removed:     []test{},
+		Regressions: []regression{},
+	}
+	for key, n := range newRuns {
+		t := test{Package: key.pkg, Test: key.test.Name()}
+		o, ok := oldRuns[key]
+		switch {
+		case !ok:
+			r.Added = append(r.Added, t)
+			continue
+		case n.failed && !o.failed && o.passed:
+			r.NewFailures = append(r.NewFailures, t)
+		case o.failed && !n.failed && n.passed:
+			r.Fixed = append(r.Fixed, t)
+		}
+		if reg, ok := durationRegression(o, n, cfg); ok {
+			reg.test = t
+			r.Regressions = append(r.Regressions, reg)
+		}
+	}
+	for key := range oldRuns {
+		if _, ok := newRuns[key]; !ok {
+			r.Removed = append(r.Removed, test{Package: key.pkg, Test: key.test.Name()})
+		}
+	}
+
+	for _, tests := range [][]test{r.NewFailures, r.Fixed, r.Added, r.Removed} {
+		sortTests(tests)
+	}
+	sort.Slice(r.Regressions, func(i, j int) bool {
+		a, b := r.Regressions[i], r.Regressions[j]
+		if a.ChangePercent != b.ChangePercent {
+			return a.ChangePercent > b.ChangePercent
+		}
+		return lessTest(a.test, b.test)
+	})
+	return r
+}
+
+// durationRegression returns a regression when the median elapsed time of n is
+// slower than o by more than the threshold. When both have more than one run,
+// the fastest run of n must also be slower than the slowest run of o.
+func durationRegression(o, n *testRuns, cfg compareConfig) (regression, bool) {
+	if len(o.elapsed) == 0 || len(n.elapsed) == 0 || cfg.threshold <= 0 {
+		return regression{}, false
+	}
+	oldTimes := sortedCopy(o.elapsed)
+	newTimes := sortedCopy(n.elapsed)
+	oldMedian := aggregate.Median(oldTimes)
+	newMedian := aggregate.Median(newTimes)
+	if oldMedian <= 0 || oldMedian < cfg.minElapsed {
+		return regression{}, false
+	}
+	change := float64(newMedian-oldMedian) / float64(oldMedian)
+	if change <= cfg.threshold {
+		return regression{}, false
+	}
+	if len(oldTimes) > 1 && len(newTimes) > 1 && newTimes[0] <= oldTimes[len(oldTimes)-1] {
+		return regression{}, false
+	}
+	return regression{
+		OldElapsedSeconds: oldMedian.Seconds(),
+		NewElapsedSeconds: newMedian.Seconds(),
+		ChangePercent:     change * 100,
+		old:               oldMedian,
+		new:               newMedian,
+	}, true
+}
+
+func sortedCopy(times []time.Duration) []time.Duration {
+	result := append([]time.Duration{}, times...)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result
+}
+
+func sortTests(tests []test) {
+	sort.Slice(tests, func(i, j int) bool {
+		return lessTest(tests[i], tests[j])
+	})
+}
+
+func lessTest(a, b test) bool {
+	if a.Package != b.Package {
+		return a.Package < b.Package
+	}
+	return a.Test < b.Test
+}
@@ -0,0 +1,192 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	if flags.NArg() != 2 {
+		usage(os.Stderr, name, flags)
+		return fmt.Errorf("expected 2 arguments, OLD and NEW, got %d", flags.NArg())
+	}
+	opts.old, opts.new = flags.Arg(0), flags.Arg(1)
+	return run(opts)
+}
+
+type options struct {
+	old                 string
+	new                 string
+	format              string
+	regressionThreshold float64
+	minElapsed          time.Duration
+	failOnRegression    bool
+	debug               bool
+	stdout              io.Writer
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{stdout: os.Stdout}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.format, "format", "text",
+		"format of the report, one of: text, json")
+	flags.Float64Var(&opts.regressionThreshold, "regression-threshold", 20,
+		"report tests which are slower than OLD by more than this percent")
+	flags.DurationVar(&opts.minElapsed, "min-elapsed", 100*time.Millisecond,
+		"ignore duration regressions in tests which took less than this in OLD")
+	flags.BoolVar(&opts.failOnRegression, "fail-on-regression", false,
+		"exit with a non-zero exit code when there are duration regressions")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] OLD NEW
+
+Compare the results of two runs, and print the tests which are newly failing,
+newly fixed, added, or removed, and the tests which are slower in NEW than in OLD.
+OLD and NEW are json files created with 'gotestsum --jsonfile' or 'go test -json',
+or glob patterns which match many json files.
+
+A test is slower when the median of its elapsed time in NEW is more than
+--regression-threshold percent slower than the median in OLD. When OLD and NEW
+both have more than one passing run of a test, the fastest run in NEW must also
+be slower than the slowest run in OLD, so that noise from a single slow run is
+not reported as a regression.
+
+Example:
+
+    %[1]s --fail-on-regression main.json 'branch/*.json'
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	write, err := reportWriter(opts.format)
+	if err != nil {
+		return err
+	}
+	oldRuns, err := loadRuns(opts.old)
+	if err != nil {
+		return err
+	}
+	newRuns, err := loadRuns(opts.new)
+	if err != nil {
+		return err
+	}
+
+	result := compare(oldRuns, newRuns, compareConfig{
+		threshold:  opts.regressionThreshold / 100,
+		minElapsed: opts.minElapsed,
+	})
+	if err := write(opts.stdout, result); err != nil {
+		return err
+	}
+	if opts.failOnRegression && len(result.Regressions) > 0 {
+		return fmt.Errorf("%d tests are slower than %v by more than %v%%",
+			len(result.Regressions), opts.old, opts.regressionThreshold)
+	}
+	return nil
+}
+
+// loadRuns returns the results of the tests in every json file matched by
+// pattern.
+func loadRuns(pattern string) (map[testKey]*testRuns, error) {
+	fileNames, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %v: %w", pattern, err)
+	}
+	if len(fileNames) == 0 {
+		return nil, fmt.Errorf("no files match %v", pattern)
+	}
+
+	runs := make(map[testKey]*testRuns)
+	for _, fileName := range fileNames {
+		exec, err := jsonfile.Scan(fileName, testjson.ScanConfig{})
+		if err != nil {
+			return nil, err
+		}
+		addRun(runs, exec)
+	}
+	return runs, nil
+}
+
+func reportWriter(format string) (func(io.Writer, result) error, error) {
+	switch format {
+	case "text":
+		return writeText, nil
+	case "json":
+		return writeJSON, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q, must be one of: text, json", format)
+	}
+}
+
+func writeText(out io.Writer, r result) error {
+	b := new(strings.Builder)
+	section := func(title string, tests []test) {
+		if len(tests) == 0 {
+			return
+		}
+		fmt.Fprintf(b, "=== %s (%d)\n", title, len(tests))
+		for _, t := range tests {
+			fmt.Fprintf(b, "%s %s\n", testjson.RelativePackagePath(t.Package), t.Test)
+		}
+	}
+	section("New failures", r.NewFailures)
+	section("Fixed", r.Fixed)
+	section("Added", r.Added)
+	section("Removed", r.Removed)
+	if len(r.Regressions) > 0 {
+		fmt.Fprintf(b, "=== Duration regressions (%d)\n", len(r.Regressions))
+		for _, reg := range r.Regressions {
+			fmt.Fprintf(b, "%s %s %s (was %s, +%.0f%%)\n",
+				testjson.RelativePackagePath(reg.Package), reg.Test,
+				testjson.FormatDurationAsSeconds(reg.new, 2),
+				testjson.FormatDurationAsSeconds(reg.old, 2),
+				reg.ChangePercent)
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteString("No differences\n")
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func writeJSON(out io.Writer, r result) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
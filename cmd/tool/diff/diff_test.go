@@ -0,0 +1,85 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestRun(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		format string
+		golden string
+	}{
+		{name: "text", format: "text", golden: "diff.out"},
+		{name: "json", format: "json", golden: "diff.json"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			opts := &options{
+				old:                 "testdata/old.json",
+				new:                 "testdata/new.json",
+				format:              tc.format,
+				regressionThreshold: 20,
+				minElapsed:          100 * time.Millisecond,
+				stdout:              out,
+			}
+			assert.NilError(t, run(opts))
+			golden.Assert(t, out.String(), tc.golden)
+		})
+	}
+}
+
+func TestRun_FailOnRegression(t *testing.T) {
+	opts := &options{
+		old:                 "testdata/old.json",
+		new:                 "testdata/new.json",
+		format:              "text",
+		regressionThreshold: 20,
+		minElapsed:          100 * time.Millisecond,
+		failOnRegression:    true,
+		stdout:              new(bytes.Buffer),
+	}
+	err := run(opts)
+	assert.Error(t, err, "1 tests are slower than testdata/old.json by more than 20%")
+
+	opts.old, opts.new = "testdata/new.json", "testdata/new.json"
+	assert.NilError(t, run(opts))
+}
+
+func TestDurationRegression(t *testing.T) {
+	cfg := compareConfig{threshold: 0.2, minElapsed: 100 * time.Millisecond}
+	runs := func(ms ...int) *testRuns {
+		r := &testRuns{passed: true}
+		for _, v := range ms {
+			r.elapsed = append(r.elapsed, time.Duration(v)*time.Millisecond)
+		}
+		return r
+	}
+
+	reg, ok := durationRegression(runs(200, 210, 220), runs(300, 310, 320), cfg)
+	assert.Assert(t, ok)
+	assert.Equal(t, reg.old, 210*time.Millisecond)
+	assert.Equal(t, reg.new, 310*time.Millisecond)
+
+	// the slowest old run overlaps with the fastest new run
+	_, ok = durationRegression(runs(200, 210, 400), runs(300, 310, 320), cfg)
+	assert.Assert(t, !ok)
+
+	// one run on each side is compared by the median alone
+	_, ok = durationRegression(runs(200), runs(300), cfg)
+	assert.Assert(t, ok)
+
+	// below the threshold
+	_, ok = durationRegression(runs(200), runs(230), cfg)
+	assert.Assert(t, !ok)
+
+	// faster than minElapsed
+	_, ok = durationRegression(runs(50), runs(90), cfg)
+	assert.Assert(t, !ok)
+}
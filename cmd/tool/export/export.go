@@ -0,0 +1,347 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/internal/parquet"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	opts.jsonfiles = flags.Args()
+	return run(opts)
+}
+
+type options struct {
+	format    string
+	outputDir string
+	jsonfiles []string
+	debug     bool
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.format, "format", "csv",
+		"format of the exported tables, one of: csv, parquet")
+	flags.StringVar(&opts.outputDir, "output-dir", ".",
+		"directory where the tables are written")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] JSONFILE...
+
+Read one or more json files and export the test results as tables that can be
+loaded into a database or data warehouse. The json files may be created with
+'gotestsum --jsonfile' or 'go test -json'. Each json file is one run.
+
+Three tables are written to --output-dir:
+
+    runs       one row for each json file
+    tests      one row for each test in each run, with the final result
+    attempts   one row for each time a test was run, including reruns
+
+Example:
+
+    %[1]s --format parquet --output-dir ./export ./logs/*.log
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	write, ext, err := tableWriter(opts.format)
+	if err != nil {
+		return err
+	}
+	if len(opts.jsonfiles) == 0 {
+		return fmt.Errorf("at least one json file is required")
+	}
+
+	var runs []runRecord
+	for _, fileName := range opts.jsonfiles {
+		exec, err := jsonfile.Scan(fileName, testjson.ScanConfig{})
+		if err != nil {
+			return err
+		}
+		runs = append(runs, runRecord{name: filepath.Base(fileName), exec: exec})
+	}
+
+	if err := os.MkdirAll(opts.outputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	tables := map[string]parquet.Table{
+		"runs":     runsTable(runs),
+		"tests":    testsTable(runs),
+		"attempts": attemptsTable(runs),
+	}
+	for _, name := range []string{"runs", "tests", "attempts"} {
+		path := filepath.Join(opts.outputDir, name+ext)
+		if err := writeFile(path, tables[name], write); err != nil {
+			return fmt.Errorf("failed to write %v: %w", path, err)
+		}
+		log.Debugf("wrote %d rows to %v", len(tables[name].Rows), path)
+	}
+	return nil
+}
+
+func tableWriter(format string) (func(io.Writer, parquet.Table) error, string, error) {
+	switch format {
+	case "csv":
+		return writeCSV, ".csv", nil
+	case "parquet":
+		return parquet.Write, ".parquet", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported format %q, must be one of: csv, parquet", format)
+	}
+}
+
+func writeFile(path string, table parquet.Table, write func(io.Writer, parquet.Table) error) error {
+	fh, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(fh, table); err != nil {
+		_ = fh.Close()
+		return err
+	}
+	return fh.Close()
+}
+
+func writeCSV(out io.Writer, table parquet.Table) error {
+	w := csv.NewWriter(out)
+	header := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		header[i] = col.Name
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(table.Columns))
+	for _, row := range table.Rows {
+		for i, value := range row {
+			record[i] = formatCSVValue(value)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type runRecord struct {
+	name string
+	exec *testjson.Execution
+}
+
+func runsTable(runs []runRecord) parquet.Table {
+	table := parquet.Table{
+		Columns: []parquet.Column{
+			{Name: "run", Type: parquet.String},
+			{Name: "started", Type: parquet.String},
+			{Name: "elapsed_seconds", Type: parquet.Double},
+			{Name: "packages", Type: parquet.Int64},
+			{Name: "tests", Type: parquet.Int64},
+			{Name: "failed", Type: parquet.Int64},
+			{Name: "skipped", Type: parquet.Int64},
+			{Name: "errors", Type: parquet.Int64},
+		},
+	}
+	for _, run := range runs {
+		exec := run.exec
+		table.Rows = append(table.Rows, []interface{}{
+			run.name,
+			formatTime(exec.Started()),
+			exec.Elapsed().Seconds(),
+			int64(len(exec.Packages())),
+			int64(exec.Total()),
+			int64(len(exec.Failed())),
+			int64(len(exec.Skipped())),
+			int64(len(exec.Errors())),
+		})
+	}
+	return table
+}
+
+func testsTable(runs []runRecord) parquet.Table {
+	table := parquet.Table{
+		Columns: []parquet.Column{
+			{Name: "run", Type: parquet.String},
+			{Name: "package", Type: parquet.String},
+			{Name: "test", Type: parquet.String},
+			{Name: "result", Type: parquet.String},
+			{Name: "attempts", Type: parquet.Int64},
+			{Name: "flaky", Type: parquet.Bool},
+			{Name: "elapsed_seconds", Type: parquet.Double},
+		},
+	}
+	for _, run := range runs {
+		for _, tc := range testRecords(run.exec) {
+			table.Rows = append(table.Rows, []interface{}{
+				run.name,
+				tc.pkg,
+				tc.test,
+				tc.result,
+				int64(tc.attempts),
+				tc.flaky,
+				tc.elapsed.Seconds(),
+			})
+		}
+	}
+	return table
+}
+
+func attemptsTable(runs []runRecord) parquet.Table {
+	table := parquet.Table{
+		Columns: []parquet.Column{
+			{Name: "run", Type: parquet.String},
+			{Name: "package", Type: parquet.String},
+			{Name: "test", Type: parquet.String},
+			{Name: "attempt", Type: parquet.Int64},
+			{Name: "result", Type: parquet.String},
+			{Name: "started", Type: parquet.String},
+			{Name: "elapsed_seconds", Type: parquet.Double},
+		},
+	}
+	for _, run := range runs {
+		for _, a := range attemptRecords(run.exec) {
+			table.Rows = append(table.Rows, []interface{}{
+				run.name,
+				a.tc.Package,
+				string(a.tc.Test),
+				int64(a.attempt),
+				a.result,
+				formatTime(a.tc.Time),
+				a.tc.Elapsed.Seconds(),
+			})
+		}
+	}
+	return table
+}
+
+type attemptRecord struct {
+	tc      testjson.TestCase
+	result  string
+	attempt int
+}
+
+// attemptRecords returns all the test cases in exec, in the order they
+// started. When a test is run more than once, for example by --rerun-fails,
+// attempt is incremented for each run.
+func attemptRecords(exec *testjson.Execution) []attemptRecord {
+	var records []attemptRecord
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		add := func(tcs []testjson.TestCase, result string) {
+			for _, tc := range tcs {
+				records = append(records, attemptRecord{tc: tc, result: result})
+			}
+		}
+		add(pkg.Passed, "pass")
+		add(pkg.Failed, "fail")
+		add(pkg.Skipped, "skip")
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i].tc, records[j].tc
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.ID < b.ID
+	})
+
+	count := make(map[testKey]int)
+	for i, r := range records {
+		key := testKey{pkg: r.tc.Package, test: string(r.tc.Test)}
+		count[key]++
+		records[i].attempt = count[key]
+	}
+	return records
+}
+
+type testKey struct {
+	pkg  string
+	test string
+}
+
+type testRecord struct {
+	pkg      string
+	test     string
+	result   string
+	attempts int
+	flaky    bool
+	elapsed  time.Duration
+}
+
+// testRecords returns one record for each test in exec. The result is the
+// result of the last attempt, and a test is flaky if it failed and then
+// passed on a later attempt.
+func testRecords(exec *testjson.Execution) []testRecord {
+	var records []testRecord
+	index := make(map[testKey]int)
+	for _, a := range attemptRecords(exec) {
+		key := testKey{pkg: a.tc.Package, test: string(a.tc.Test)}
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(records)
+			records = append(records, testRecord{pkg: key.pkg, test: key.test})
+			i = len(records) - 1
+		}
+		r := &records[i]
+		if r.result == "fail" && a.result == "pass" {
+			r.flaky = true
+		}
+		r.result = a.result
+		r.attempts = a.attempt
+		r.elapsed += a.tc.Elapsed
+	}
+	return records
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
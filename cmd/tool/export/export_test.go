@@ -0,0 +1,51 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestRun_CSV(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	opts := &options{
+		format:    "csv",
+		outputDir: dir.Path(),
+		jsonfiles: []string{"testdata/rerun.json"},
+	}
+	assert.NilError(t, run(opts))
+
+	for _, name := range []string{"runs", "tests", "attempts"} {
+		raw, err := os.ReadFile(filepath.Join(dir.Path(), name+".csv"))
+		assert.NilError(t, err)
+		golden.Assert(t, string(raw), name+".csv")
+	}
+}
+
+func TestRun_Parquet(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	opts := &options{
+		format:    "parquet",
+		outputDir: filepath.Join(dir.Path(), "out"),
+		jsonfiles: []string{"testdata/rerun.json"},
+	}
+	assert.NilError(t, run(opts))
+
+	for _, name := range []string{"runs", "tests", "attempts"} {
+		raw, err := os.ReadFile(filepath.Join(opts.outputDir, name+".parquet"))
+		assert.NilError(t, err)
+		assert.Equal(t, string(raw[:4]), "PAR1")
+	}
+}
+
+func TestRun_InvalidOptions(t *testing.T) {
+	err := run(&options{format: "xml", jsonfiles: []string{"testdata/rerun.json"}})
+	assert.Error(t, err, `unsupported format "xml", must be one of: csv, parquet`)
+
+	err = run(&options{format: "csv"})
+	assert.Error(t, err, "at least one json file is required")
+}
@@ -0,0 +1,239 @@
+package flaky
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	return run(opts)
+}
+
+type options struct {
+	jsonfiles []string
+	format    string
+	num       int
+	debug     bool
+	stdout    io.Writer
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{stdout: os.Stdout}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringArrayVar(&opts.jsonfiles, "jsonfile", nil,
+		"path or glob pattern of test2json output, may be repeated")
+	flags.StringVar(&opts.format, "format", "text",
+		"format of the report, one of: text, json")
+	flags.IntVar(&opts.num, "num", 0,
+		"print at most num flaky tests, instead of all flaky tests")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Read many json files and print the tests which are flaky. The json files may be
+created with 'gotestsum --jsonfile' or 'go test -json'. Each json file is one run.
+
+A test is flaky if it both passed and failed, either in different runs, or in the
+same run when it failed and then passed when it was rerun by --rerun-fails. The
+flaky tests are sorted by flake rate, highest first.
+
+The columns of the report are:
+
+    runs           number of runs which included the test
+    failed_runs    number of runs where the test failed at least once
+    rerun_passed   number of runs where the test failed, and then passed when
+                   it was rerun
+    flake_rate     percentage of runs where the test failed at least once
+
+Example:
+
+    %[1]s --jsonfile './runs/*.json' --num 10
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	write, err := reportWriter(opts.format)
+	if err != nil {
+		return err
+	}
+	fileNames, err := jsonfile.Expand(opts.jsonfiles)
+	if err != nil {
+		return err
+	}
+	if len(fileNames) == 0 {
+		return fmt.Errorf("at least one --jsonfile is required")
+	}
+
+	stats := make(map[testKey]*testStats)
+	for _, fileName := range fileNames {
+		exec, err := jsonfile.Scan(fileName, testjson.ScanConfig{})
+		if err != nil {
+			return err
+		}
+		addRun(stats, exec)
+	}
+
+	tests := flakyTests(stats)
+	if opts.num > 0 && opts.num < len(tests) {
+		tests = tests[:opts.num]
+	}
+	return write(opts.stdout, tests)
+}
+
+type testKey struct {
+	pkg  string
+	test testjson.TestName
+}
+
+type testStats struct {
+	Package     string  `json:"package"`
+	Test        string  `json:"test"`
+	Runs        int     `json:"runs"`
+	FailedRuns  int     `json:"failed_runs"`
+	RerunPassed int     `json:"rerun_passed"`
+	FlakeRate   float64 `json:"flake_rate"`
+	// passedRuns is the number of runs where the test passed at least once.
+	passedRuns int
+}
+
+// addRun adds the results of one run to stats. A test which was skipped in a
+// run is not counted as part of that run.
+func addRun(stats map[testKey]*testStats, exec *testjson.Execution) {
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+
+		passed := make(map[testjson.TestName]bool)
+		for _, tc := range pkg.Passed {
+			passed[tc.Test] = true
+		}
+		failed := make(map[testjson.TestName]bool)
+		for _, tc := range pkg.Failed {
+			if tc.Test != "" {
+				failed[tc.Test] = true
+			}
+		}
+
+		get := func(test testjson.TestName) *testStats {
+			key := testKey{pkg: name, test: test}
+			s, ok := stats[key]
+			if !ok {
+				s = &testStats{Package: name, Test: test.Name()}
+				stats[key] = s
+			}
+			s.Runs++
+			return s
+		}
+		for test := range failed {
+			s := get(test)
+			s.FailedRuns++
+			if passed[test] {
+				s.RerunPassed++
+				s.passedRuns++
+			}
+		}
+		for test := range passed {
+			if !failed[test] {
+				get(test).passedRuns++
+			}
+		}
+	}
+}
+
+// flakyTests returns the tests which both passed and failed, sorted by flake
+// rate, highest first.
+func flakyTests(stats map[testKey]*testStats) []testStats {
+	var result []testStats
+	for _, s := range stats {
+		if s.FailedRuns == 0 || s.passedRuns == 0 {
+			continue
+		}
+		s.FlakeRate = float64(s.FailedRuns) / float64(s.Runs) * 100
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		switch {
+		case a.FlakeRate != b.FlakeRate:
+			return a.FlakeRate > b.FlakeRate
+		case a.FailedRuns != b.FailedRuns:
+			return a.FailedRuns > b.FailedRuns
+		case a.Package != b.Package:
+			return a.Package < b.Package
+		default:
+			return a.Test < b.Test
+		}
+	})
+	return result
+}
+
+func reportWriter(format string) (func(io.Writer, []testStats) error, error) {
+	switch format {
+	case "text":
+		return writeText, nil
+	case "json":
+		return writeJSON, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q, must be one of: text, json", format)
+	}
+}
+
+var reportColumns = []string{"package", "test", "runs", "failed_runs", "rerun_passed", "flake_rate"}
+
+func writeText(out io.Writer, tests []testStats) error {
+	if len(tests) == 0 {
+		_, err := fmt.Fprintln(out, "No flaky tests")
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(reportColumns, "\t")))
+	for _, s := range tests {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.1f%%\n",
+			testjson.RelativePackagePath(s.Package), s.Test,
+			s.Runs, s.FailedRuns, s.RerunPassed, s.FlakeRate)
+	}
+	return w.Flush()
+}
+
+func writeJSON(out io.Writer, tests []testStats) error {
+	if tests == nil {
+		tests = []testStats{}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(tests)
+}
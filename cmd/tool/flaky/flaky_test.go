@@ -0,0 +1,43 @@
+package flaky
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestRun(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		format string
+		golden string
+	}{
+		{name: "text", format: "text", golden: "flaky.out"},
+		{name: "json", format: "json", golden: "flaky.json"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			opts := &options{
+				jsonfiles: []string{"testdata/run*.json"},
+				format:    tc.format,
+				stdout:    out,
+			}
+			assert.NilError(t, run(opts))
+			golden.Assert(t, out.String(), tc.golden)
+		})
+	}
+}
+
+func TestRun_InvalidOptions(t *testing.T) {
+	err := run(&options{format: "xml", jsonfiles: []string{"testdata/run1.json"}})
+	assert.Error(t, err, `unsupported format "xml", must be one of: text, json`)
+
+	err = run(&options{format: "text"})
+	assert.Error(t, err, "at least one --jsonfile is required")
+
+	err = run(&options{format: "text", jsonfiles: []string{"testdata/missing-*.json"}})
+	assert.Error(t, err, "no files match testdata/missing-*.json")
+}
@@ -0,0 +1,111 @@
+package githubcomment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// client of the GitHub REST API.
+type client struct {
+	http   *http.Client
+	apiURL string
+	token  string
+	repo   string
+}
+
+type issueComment struct {
+	ID      int64  `json:"id,omitempty"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url,omitempty"`
+}
+
+// upsertComment updates the comment on the pull request that contains marker,
+// or creates a new comment if there is no comment with the marker. It returns
+// the URL of the comment.
+func (c *client) upsertComment(ctx context.Context, pr int, marker, body string) (string, error) {
+	existing, err := c.findComment(ctx, pr, marker)
+	if err != nil {
+		return "", err
+	}
+
+	method := http.MethodPost
+	path := fmt.Sprintf("/repos/%v/issues/%d/comments", c.repo, pr)
+	if existing != nil {
+		method = http.MethodPatch
+		path = fmt.Sprintf("/repos/%v/issues/comments/%d", c.repo, existing.ID)
+	}
+	var result issueComment
+	if err := c.do(ctx, method, path, issueComment{Body: body}, &result); err != nil {
+		return "", err
+	}
+	return result.HTMLURL, nil
+}
+
+const commentsPerPage = 100
+
+func (c *client) findComment(ctx context.Context, pr int, marker string) (*issueComment, error) {
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("/repos/%v/issues/%d/comments?per_page=%d&page=%d",
+			c.repo, pr, commentsPerPage, page)
+		var comments []issueComment
+		if err := c.do(ctx, http.MethodGet, path, nil, &comments); err != nil {
+			return nil, err
+		}
+		for _, comment := range comments {
+			if strings.HasPrefix(comment.Body, marker) {
+				return &comment, nil
+			}
+		}
+		if len(comments) < commentsPerPage {
+			return nil, nil
+		}
+	}
+}
+
+func (c *client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+	url := strings.TrimSuffix(c.apiURL, "/") + path
+	log.Debugf("GitHub API request: %v %v", method, url)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("GitHub API request failed: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read GitHub API response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("GitHub API request %v %v failed: %v: %s", method, path, resp.Status, raw)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to parse GitHub API response: %w", err)
+	}
+	return nil
+}
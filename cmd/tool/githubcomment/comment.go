@@ -0,0 +1,117 @@
+package githubcomment
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/internal/failurereport"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type commentConfig struct {
+	// ID is added to the marker, to find the comment when it is updated.
+	ID string
+	// Slowest is the number of the slowest tests to include.
+	Slowest int
+	// MaxFailures is the number of failed tests to include with their output.
+	MaxFailures int
+}
+
+// maxOutputLines is the number of lines of output that are included for each
+// failed test.
+const maxOutputLines = 30
+
+// commentMarker is a hidden HTML comment used to find a comment that was
+// posted by a previous run.
+func commentMarker(id string) string {
+	if id == "" {
+		return "<!-- gotestsum-github-comment -->"
+	}
+	return fmt.Sprintf("<!-- gotestsum-github-comment: %v -->", id)
+}
+
+func newComment(exec *testjson.Execution, cfg commentConfig) string {
+	var failed []failurereport.Failure
+	var flaky []string
+	for _, tc := range testjson.FilterFailedUnique(exec.Failed()) {
+		if tc.Test == "" {
+			// a failure in TestMain is included in the package errors
+			continue
+		}
+		name := tc.Package + "." + tc.Test.Name()
+		if exec.Package(tc.Package).PassedOnRerun(tc) {
+			flaky = append(flaky, name)
+			continue
+		}
+		failed = append(failed, failurereport.Failure{
+			Name:   name,
+			Output: failurereport.LastLines(exec.OutputLines(tc), maxOutputLines),
+		})
+	}
+
+	var pkgErrors []failurereport.Failure
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		if pkg.TestMainFailed() {
+			pkgErrors = append(pkgErrors, failurereport.Failure{Name: name, Output: pkg.Output(0)})
+		}
+	}
+
+	b := new(strings.Builder)
+	b.WriteString(commentMarker(cfg.ID) + "\n")
+	passed := len(failed) == 0 && len(pkgErrors) == 0 && len(exec.Errors()) == 0
+	if passed {
+		b.WriteString("### :white_check_mark: Tests passed\n\n")
+	} else {
+		b.WriteString("### :x: Tests failed\n\n")
+	}
+
+	fmt.Fprintf(b, "**%d tests** in %v", exec.Total(), exec.Elapsed().Round(time.Millisecond))
+	counts := []struct {
+		num  int
+		name string
+	}{
+		{len(failed), "failed"},
+		{len(flaky), "flaky"},
+		{len(exec.Skipped()), "skipped"},
+		{len(pkgErrors), "package errors"},
+		{len(exec.Errors()), "errors"},
+	}
+	for _, c := range counts {
+		if c.num > 0 {
+			fmt.Fprintf(b, ", %d %v", c.num, c.name)
+		}
+	}
+	b.WriteString("\n")
+
+	if len(exec.Errors()) > 0 {
+		b.WriteString("\n#### Errors\n\n")
+		failurereport.WriteCodeBlock(b, strings.Join(exec.Errors(), "\n"))
+	}
+	if len(pkgErrors) > 0 {
+		b.WriteString("\n#### Package errors\n\n")
+		for _, f := range pkgErrors {
+			failurereport.WriteDetails(b, f)
+		}
+	}
+	if len(failed) > 0 {
+		b.WriteString("\n#### Failed tests\n\n")
+		for i, f := range failed {
+			if cfg.MaxFailures > 0 && i >= cfg.MaxFailures {
+				fmt.Fprintf(b, "and %d more failed tests\n", len(failed)-i)
+				break
+			}
+			failurereport.WriteDetails(b, f)
+		}
+	}
+	failurereport.WriteFlaky(b, flaky)
+	if slowest := aggregate.Slowest(exec, 0, cfg.Slowest); len(slowest) > 0 {
+		b.WriteString("\n#### Slowest tests\n\n| Test | Elapsed |\n| --- | ---: |\n")
+		for _, tc := range slowest {
+			fmt.Fprintf(b, "| `%v.%v` | %.3fs |\n", tc.Package, tc.Test.Name(), tc.Elapsed.Seconds())
+		}
+	}
+	return b.String()
+}
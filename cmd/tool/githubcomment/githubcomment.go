@@ -0,0 +1,195 @@
+package githubcomment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	return run(opts)
+}
+
+type options struct {
+	jsonfile    string
+	repo        string
+	pr          int
+	apiURL      string
+	id          string
+	slowest     int
+	maxFailures int
+	dryRun      bool
+	debug       bool
+
+	// shims for testing
+	stdout io.Writer
+	getenv func(string) string
+	client *http.Client
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{stdout: os.Stdout, getenv: os.Getenv, client: http.DefaultClient}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.jsonfile, "jsonfile", os.Getenv("GOTESTSUM_JSONFILE"),
+		"path to test2json output, defaults to stdin")
+	flags.StringVar(&opts.repo, "repo", os.Getenv("GITHUB_REPOSITORY"),
+		"owner/name of the GitHub repository")
+	flags.IntVar(&opts.pr, "pr", 0,
+		"number of the pull request, defaults to the pull request of the GitHub Actions event")
+	flags.StringVar(&opts.apiURL, "api-url", defaultString(os.Getenv("GITHUB_API_URL"), "https://api.github.com"),
+		"URL of the GitHub API")
+	flags.StringVar(&opts.id, "id", "",
+		"identifies the comment to update, when more than one comment is posted to a pull request")
+	flags.IntVar(&opts.slowest, "slowest", 5,
+		"number of the slowest tests to include in the comment")
+	flags.IntVar(&opts.maxFailures, "max-failures", 10,
+		"maximum number of failed tests to include with their output")
+	flags.BoolVar(&opts.dryRun, "dry-run", false,
+		"print the comment to stdout instead of posting it")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func defaultString(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Read a json file and post a comment to a GitHub pull request with a summary of
+the test results. The summary includes the failed tests with their output, the
+tests which only passed when they were rerun (flaky tests), and the slowest
+tests. The json file may be created with 'gotestsum --jsonfile' or
+'go test -json'.
+
+A previous comment posted by this command is updated, so that a pull request
+has a single comment with the results of the latest run.
+
+The GITHUB_TOKEN environment variable must be set to a token which can write
+pull request comments. In GitHub Actions the repository and pull request number
+are read from the GITHUB_REPOSITORY and GITHUB_EVENT_PATH environment
+variables.
+
+Example:
+
+    gotestsum --jsonfile test.json --rerun-fails ./...
+    %[1]s --jsonfile test.json
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	in, err := jsonfile.OpenOrStdin(opts.jsonfile)
+	if err != nil {
+		return fmt.Errorf("failed to read jsonfile: %v", err)
+	}
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Errorf("Failed to close file %v: %v", opts.jsonfile, err)
+		}
+	}()
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: in})
+	if err != nil {
+		return fmt.Errorf("failed to scan testjson: %w", err)
+	}
+
+	body := newComment(exec, commentConfig{
+		ID:          opts.id,
+		Slowest:     opts.slowest,
+		MaxFailures: opts.maxFailures,
+	})
+	if opts.dryRun {
+		_, err := io.WriteString(opts.stdout, body)
+		return err
+	}
+
+	token := opts.getenv("GITHUB_TOKEN")
+	if token == "" {
+		return fmt.Errorf("GITHUB_TOKEN must be set to post a comment")
+	}
+	if opts.repo == "" {
+		return fmt.Errorf("--repo or GITHUB_REPOSITORY must be set to post a comment")
+	}
+	pr := opts.pr
+	if pr == 0 {
+		if pr, err = pullRequestFromEnv(opts.getenv); err != nil {
+			return err
+		}
+	}
+
+	c := &client{http: opts.client, apiURL: opts.apiURL, token: token, repo: opts.repo}
+	url, err := c.upsertComment(context.Background(), pr, commentMarker(opts.id), body)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(opts.stdout, "Posted test results to %v\n", url)
+	return nil
+}
+
+var pullRefPattern = regexp.MustCompile(`^refs/pull/(\d+)/`)
+
+// pullRequestFromEnv returns the number of the pull request from the event
+// payload of a GitHub Actions workflow, or from GITHUB_REF.
+func pullRequestFromEnv(getenv func(string) string) (int, error) {
+	if path := getenv("GITHUB_EVENT_PATH"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read the GitHub event: %w", err)
+		}
+		var event struct {
+			Number      int `json:"number"`
+			PullRequest struct {
+				Number int `json:"number"`
+			} `json:"pull_request"`
+		}
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return 0, fmt.Errorf("failed to parse the GitHub event: %w", err)
+		}
+		switch {
+		case event.PullRequest.Number != 0:
+			return event.PullRequest.Number, nil
+		case event.Number != 0:
+			return event.Number, nil
+		}
+	}
+	if m := pullRefPattern.FindStringSubmatch(getenv("GITHUB_REF")); m != nil {
+		return strconv.Atoi(m[1])
+	}
+	return 0, fmt.Errorf("the pull request number was not found in the GitHub Actions environment, use --pr")
+}
@@ -0,0 +1,146 @@
+package githubcomment
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestRun_DryRun(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfile:    "testdata/input.json",
+		slowest:     3,
+		maxFailures: 10,
+		dryRun:      true,
+		stdout:      out,
+	}
+	assert.NilError(t, run(opts))
+	golden.Assert(t, out.String(), "expected-comment.md")
+}
+
+type fakeGitHub struct {
+	comments []issueComment
+	requests []string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests = append(f.requests, r.Method+" "+r.URL.String())
+	if r.Header.Get("Authorization") != "Bearer the-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(f.comments)
+	case http.MethodPost, http.MethodPatch:
+		var comment issueComment
+		if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		comment.HTMLURL = "https://github.com/owner/repo/pull/12#issuecomment-7"
+		_ = json.NewEncoder(w).Encode(comment)
+	}
+}
+
+func TestRun_PostComment(t *testing.T) {
+	type testCase struct {
+		name     string
+		comments []issueComment
+		expected []string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		gh := &fakeGitHub{comments: tc.comments}
+		srv := httptest.NewServer(gh)
+		t.Cleanup(srv.Close)
+
+		out := new(bytes.Buffer)
+		opts := &options{
+			jsonfile: "testdata/input.json",
+			repo:     "owner/repo",
+			pr:       12,
+			apiURL:   srv.URL,
+			stdout:   out,
+			getenv:   fakeEnv(map[string]string{"GITHUB_TOKEN": "the-token"}),
+			client:   srv.Client(),
+		}
+		assert.NilError(t, run(opts))
+		assert.DeepEqual(t, gh.requests, tc.expected)
+		assert.Equal(t, out.String(), "Posted test results to https://github.com/owner/repo/pull/12#issuecomment-7\n")
+	}
+
+	testCases := []testCase{
+		{
+			name:     "new comment",
+			comments: []issueComment{{ID: 3, Body: "looks good"}},
+			expected: []string{
+				"GET /repos/owner/repo/issues/12/comments?per_page=100&page=1",
+				"POST /repos/owner/repo/issues/12/comments",
+			},
+		},
+		{
+			name: "update comment",
+			comments: []issueComment{
+				{ID: 3, Body: "looks good"},
+				{ID: 7, Body: "<!-- gotestsum-github-comment -->\n### Tests passed"},
+			},
+			expected: []string{
+				"GET /repos/owner/repo/issues/12/comments?per_page=100&page=1",
+				"PATCH /repos/owner/repo/issues/comments/7",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestRun_MissingToken(t *testing.T) {
+	opts := &options{
+		jsonfile: "testdata/input.json",
+		repo:     "owner/repo",
+		getenv:   fakeEnv(nil),
+		stdout:   new(bytes.Buffer),
+	}
+	assert.ErrorContains(t, run(opts), "GITHUB_TOKEN must be set")
+}
+
+func TestPullRequestFromEnv(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("pull_request.json", `{"action": "opened", "number": 4, "pull_request": {"number": 4}}`),
+		fs.WithFile("push.json", `{"ref": "refs/heads/main"}`))
+
+	pr, err := pullRequestFromEnv(fakeEnv(map[string]string{
+		"GITHUB_EVENT_PATH": dir.Join("pull_request.json"),
+	}))
+	assert.NilError(t, err)
+	assert.Equal(t, pr, 4)
+
+	pr, err = pullRequestFromEnv(fakeEnv(map[string]string{
+		"GITHUB_EVENT_PATH": dir.Join("push.json"),
+		"GITHUB_REF":        "refs/pull/31/merge",
+	}))
+	assert.NilError(t, err)
+	assert.Equal(t, pr, 31)
+
+	_, err = pullRequestFromEnv(fakeEnv(map[string]string{
+		"GITHUB_EVENT_PATH": dir.Join("push.json"),
+	}))
+	assert.ErrorContains(t, err, "use --pr")
+}
+
+func fakeEnv(env map[string]string) func(string) string {
+	return func(key string) string {
+		return env[key]
+	}
+}
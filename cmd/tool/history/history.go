@@ -0,0 +1,48 @@
+package history
+
+import (
+	"fmt"
+	"os"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	next, rest := nextArg(args)
+	switch next {
+	case "", "help", "?", "-h", "--help":
+		fmt.Println(usage(name))
+		return nil
+	case "report":
+		return runReport(name+" "+next, rest)
+	case "slowest", "flaky", "newly-slow":
+		return runQuery(name+" "+next, next, rest)
+	default:
+		fmt.Fprintln(os.Stderr, usage(name))
+		return fmt.Errorf("invalid command: %v %v", name, next)
+	}
+}
+
+func usage(name string) string {
+	return fmt.Sprintf(`Usage: %[1]s COMMAND [flags]
+
+Commands:
+    %[1]s report       summarize test time, failures, and flakes from previous runs
+    %[1]s slowest      print the slowest tests in the --history-file
+    %[1]s flaky        print the flakiest tests in the --history-file
+    %[1]s newly-slow   print the tests which are slower in the most recent runs
+
+Use '%[1]s COMMAND --help' for command specific help.
+`, name)
+}
+
+// nextArg splits args into the next positional argument and any remaining args.
+func nextArg(args []string) (string, []string) {
+	switch len(args) {
+	case 0:
+		return "", nil
+	case 1:
+		return args[0], nil
+	default:
+		return args[0], args[1:]
+	}
+}
@@ -0,0 +1,325 @@
+package history
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type queryOptions struct {
+	file       string
+	runs       int
+	num        int
+	recent     int
+	threshold  float64
+	minElapsed time.Duration
+	debug      bool
+	stdout     io.Writer
+}
+
+// queries are the commands which read the --history-file, by name.
+var queries = map[string]struct {
+	description string
+	run         func(opts *queryOptions, runs []history.Run) error
+}{
+	"slowest": {
+		description: `Print the tests with the slowest median elapsed time across the runs in the
+history file. Only passing runs of a test are used.`,
+		run: querySlowest,
+	},
+	"flaky": {
+		description: `Print the tests which both passed and failed across the runs in the history
+file, or which failed and then passed when they were rerun in the same run.
+The tests are sorted by flake rate, the percentage of runs where the test
+failed, highest first.`,
+		run: queryFlaky,
+	},
+	"newly-slow": {
+		description: `Print the tests which are slower in the most recent runs than in the runs
+before them. The median elapsed time of the last --recent runs is compared to
+the median elapsed time of the earlier runs.`,
+		run: queryNewlySlow,
+	},
+}
+
+const defaultMinElapsed = 100 * time.Millisecond
+
+func runQuery(name string, queryName string, args []string) error {
+	flags, opts := setupQueryFlags(name, queryName)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		queryUsage(os.Stderr, name, queryName, flags)
+		return err
+	}
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	return query(opts, queryName)
+}
+
+// query runs the query with the runs read from the history file.
+func query(opts *queryOptions, name string) error {
+	if opts.file == "" {
+		return fmt.Errorf("--history-file is required")
+	}
+	runs, err := history.Read(opts.file)
+	if err != nil {
+		return fmt.Errorf("failed to read history file: %w", err)
+	}
+	if opts.runs > 0 && len(runs) > opts.runs {
+		runs = runs[len(runs)-opts.runs:]
+	}
+	log.Debugf("read %d runs from %v", len(runs), opts.file)
+	return queries[name].run(opts, runs)
+}
+
+func setupQueryFlags(name string, queryName string) (*pflag.FlagSet, *queryOptions) {
+	opts := &queryOptions{stdout: os.Stdout}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		queryUsage(os.Stdout, name, queryName, flags)
+	}
+	flags.StringVar(&opts.file, "history-file", os.Getenv("GOTESTSUM_HISTORY_FILE"),
+		"path to the file written by 'gotestsum --history-file'")
+	flags.IntVar(&opts.runs, "runs", 0,
+		"only use the most recent runs, instead of all the runs in the file")
+	flags.IntVar(&opts.num, "num", 10,
+		"print at most num tests, or all tests when 0")
+	if queryName == "newly-slow" {
+		flags.IntVar(&opts.recent, "recent", 5,
+			"number of recent runs to compare to the runs before them")
+		flags.Float64Var(&opts.threshold, "threshold", 50,
+			"print tests which are slower in the recent runs by more than this percent")
+		flags.DurationVar(&opts.minElapsed, "min-elapsed", defaultMinElapsed,
+			"ignore tests which took less than this before the recent runs")
+	}
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func queryUsage(out io.Writer, name string, queryName string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+%[2]s
+
+The history file is written by 'gotestsum --history-file', which appends the
+result of every test to the file at the end of each run.
+
+Flags:
+`, name, queries[queryName].description)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+type testKey struct {
+	pkg  string
+	test string
+}
+
+func limit[T any](items []T, num int) []T {
+	if num > 0 && len(items) > num {
+		return items[:num]
+	}
+	return items
+}
+
+// passedElapsed returns the elapsed time of every passing attempt of every
+// test in runs.
+func passedElapsed(runs []history.Run) map[testKey][]time.Duration {
+	result := make(map[testKey][]time.Duration)
+	for _, run := range runs {
+		for _, r := range run.Records {
+			if r.Outcome != string(testjson.ActionPass) {
+				continue
+			}
+			key := testKey{pkg: r.Package, test: r.Test}
+			result[key] = append(result[key], r.Elapsed())
+		}
+	}
+	return result
+}
+
+func sortedKeys[V any](m map[testKey]V) []testKey {
+	keys := make([]testKey, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pkg != keys[j].pkg {
+			return keys[i].pkg < keys[j].pkg
+		}
+		return keys[i].test < keys[j].test
+	})
+	return keys
+}
+
+func querySlowest(opts *queryOptions, runs []history.Run) error {
+	type row struct {
+		testKey
+		runs   int
+		median time.Duration
+		max    time.Duration
+	}
+	elapsed := passedElapsed(runs)
+	rows := make([]row, 0, len(elapsed))
+	for _, key := range sortedKeys(elapsed) {
+		times := elapsed[key]
+		median := aggregate.Median(times) // sorts times
+		rows = append(rows, row{testKey: key, runs: len(times), median: median, max: times[len(times)-1]})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].median > rows[j].median
+	})
+
+	w := tabwriter.NewWriter(opts.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PACKAGE\tTEST\tRUNS\tMEDIAN\tMAX")
+	for _, r := range limit(rows, opts.num) {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n",
+			testjson.RelativePackagePath(r.pkg), r.test, r.runs,
+			testjson.FormatDurationAsSeconds(r.median, 3),
+			testjson.FormatDurationAsSeconds(r.max, 3))
+	}
+	return w.Flush()
+}
+
+func queryFlaky(opts *queryOptions, runs []history.Run) error {
+	type row struct {
+		testKey
+		runs        int
+		failedRuns  int
+		rerunPassed int
+		passedRuns  int
+	}
+	rows := make(map[testKey]*row)
+	for _, run := range runs {
+		passed := make(map[testKey]bool)
+		failed := make(map[testKey]bool)
+		for _, r := range run.Records {
+			key := testKey{pkg: r.Package, test: r.Test}
+			switch r.Outcome {
+			case string(testjson.ActionPass):
+				passed[key] = true
+			case string(testjson.ActionFail):
+				failed[key] = true
+			}
+		}
+		get := func(key testKey) *row {
+			r, ok := rows[key]
+			if !ok {
+				r = &row{testKey: key}
+				rows[key] = r
+			}
+			r.runs++
+			return r
+		}
+		for key := range failed {
+			r := get(key)
+			r.failedRuns++
+			if passed[key] {
+				r.rerunPassed++
+				r.passedRuns++
+			}
+		}
+		for key := range passed {
+			if !failed[key] {
+				get(key).passedRuns++
+			}
+		}
+	}
+
+	var flaky []row
+	for _, key := range sortedKeys(rows) {
+		if r := rows[key]; r.failedRuns > 0 && r.passedRuns > 0 {
+			flaky = append(flaky, *r)
+		}
+	}
+	rate := func(r row) float64 {
+		return percent(r.failedRuns, r.runs)
+	}
+	sort.SliceStable(flaky, func(i, j int) bool {
+		return rate(flaky[i]) > rate(flaky[j])
+	})
+
+	if len(flaky) == 0 {
+		_, err := fmt.Fprintln(opts.stdout, "No flaky tests")
+		return err
+	}
+	w := tabwriter.NewWriter(opts.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PACKAGE\tTEST\tRUNS\tFAILED_RUNS\tRERUN_PASSED\tFLAKE_RATE")
+	for _, r := range limit(flaky, opts.num) {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.1f%%\n",
+			testjson.RelativePackagePath(r.pkg), r.test,
+			r.runs, r.failedRuns, r.rerunPassed, rate(r))
+	}
+	return w.Flush()
+}
+
+func queryNewlySlow(opts *queryOptions, runs []history.Run) error {
+	if opts.recent < 1 {
+		return fmt.Errorf("--recent must be at least 1")
+	}
+	if len(runs) <= opts.recent {
+		return fmt.Errorf("the history file has %d runs, more than --recent=%d are required",
+			len(runs), opts.recent)
+	}
+	before := passedElapsed(runs[:len(runs)-opts.recent])
+	recent := passedElapsed(runs[len(runs)-opts.recent:])
+
+	type row struct {
+		testKey
+		before time.Duration
+		recent time.Duration
+		change float64
+	}
+	var rows []row
+	for _, key := range sortedKeys(recent) {
+		if len(before[key]) == 0 {
+			continue
+		}
+		r := row{
+			testKey: key,
+			before:  aggregate.Median(before[key]),
+			recent:  aggregate.Median(recent[key]),
+		}
+		if r.before <= 0 || r.before < opts.minElapsed {
+			continue
+		}
+		r.change = float64(r.recent-r.before) / float64(r.before) * 100
+		if r.change > opts.threshold {
+			rows = append(rows, r)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].change > rows[j].change
+	})
+
+	if len(rows) == 0 {
+		_, err := fmt.Fprintln(opts.stdout, "No newly slow tests")
+		return err
+	}
+	w := tabwriter.NewWriter(opts.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join([]string{"PACKAGE", "TEST", "BEFORE", "RECENT", "CHANGE"}, "\t"))
+	for _, r := range limit(rows, opts.num) {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t+%.0f%%\n",
+			testjson.RelativePackagePath(r.pkg), r.test,
+			testjson.FormatDurationAsSeconds(r.before, 3),
+			testjson.FormatDurationAsSeconds(r.recent, 3),
+			r.change)
+	}
+	return w.Flush()
+}
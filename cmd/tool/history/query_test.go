@@ -0,0 +1,55 @@
+package history
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestQueries(t *testing.T) {
+	runs, err := history.Read("testdata/history.jsonl")
+	assert.NilError(t, err)
+
+	var testCases = []struct {
+		query  string
+		opts   queryOptions
+		golden string
+	}{
+		{query: "slowest", opts: queryOptions{num: 10}, golden: "query-slowest.out"},
+		{query: "flaky", opts: queryOptions{num: 10}, golden: "query-flaky.out"},
+		{
+			query:  "newly-slow",
+			opts:   queryOptions{num: 10, recent: 3, threshold: 50, minElapsed: defaultMinElapsed},
+			golden: "query-newly-slow.out",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			opts := tc.opts
+			opts.stdout = out
+			assert.NilError(t, queries[tc.query].run(&opts, runs))
+			golden.Assert(t, out.String(), tc.golden)
+		})
+	}
+}
+
+func TestQuery_Runs(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := &queryOptions{file: "testdata/history.jsonl", runs: 2, num: 10, stdout: out}
+	assert.NilError(t, query(opts, "flaky"))
+	assert.Equal(t, out.String(), "No flaky tests\n")
+
+	err := query(&queryOptions{}, "flaky")
+	assert.Error(t, err, "--history-file is required")
+}
+
+func TestQueryNewlySlow_NotEnoughRuns(t *testing.T) {
+	runs, err := history.Read("testdata/history.jsonl")
+	assert.NilError(t, err)
+	err = queryNewlySlow(&queryOptions{recent: 8}, runs)
+	assert.Error(t, err, "the history file has 8 runs, more than --recent=8 are required")
+}
@@ -0,0 +1,343 @@
+package history
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/history"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type reportOptions struct {
+	groupBy     string
+	format      string
+	jsonfiles   []string
+	historyFile string
+	runs        int
+	debug       bool
+	stdout      io.Writer
+}
+
+func runReport(name string, args []string) error {
+	flags, opts := setupReportFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		reportUsage(os.Stderr, name, flags)
+		return err
+	}
+	opts.jsonfiles = flags.Args()
+	return report(opts)
+}
+
+func setupReportFlags(name string) (*pflag.FlagSet, *reportOptions) {
+	opts := &reportOptions{stdout: os.Stdout}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		reportUsage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.groupBy, "group-by", "package",
+		"group results by 'package', or by the first N path segments with 'dir:N'")
+	flags.StringVar(&opts.format, "format", "text",
+		"format of the report, one of: text, csv")
+	flags.StringVar(&opts.historyFile, "history-file", os.Getenv("GOTESTSUM_HISTORY_FILE"),
+		"path to the file written by 'gotestsum --history-file', used when no json files are given")
+	flags.IntVar(&opts.runs, "runs", 0,
+		"only use the most recent runs in the --history-file, instead of all the runs in the file")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func reportUsage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] [JSONFILE...]
+
+Read one or more json files, or the --history-file, and print the total test
+time, failure rate, and flake rate of each group of packages. The json files
+may be created with 'gotestsum --jsonfile' or 'go test -json'. Each json file
+is one run. The history file is used when no json files are given. It does not
+have the elapsed time of packages, so the test time of a package is the sum of
+the elapsed time of its top level tests, including reruns.
+
+Package paths are relative to the Go module in the working directory. With
+--group-by=dir:N packages are grouped by the first N segments of that path,
+which can be used to report the share of CI time used by each team in a
+repository where each team owns a directory.
+
+The columns of the report are:
+
+    runs         number of runs which included a package from the group
+    tests        number of test results, one for each test in each run
+    test_time    total elapsed time of all packages in the group
+    fail_rate    percentage of test results which failed
+    flake_rate   percentage of test results which failed and then passed
+                 when the test was rerun
+
+Example:
+
+    %[1]s --group-by=dir:2 ./logs/*.json
+    %[1]s --group-by=dir:2 --history-file=test-history.jsonl
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func report(opts *reportOptions) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	groupKey, err := parseGroupBy(opts.groupBy)
+	if err != nil {
+		return err
+	}
+	write, err := reportWriter(opts.format)
+	if err != nil {
+		return err
+	}
+	groups := make(map[string]*groupStats)
+	if len(opts.jsonfiles) == 0 {
+		if opts.historyFile == "" {
+			return fmt.Errorf("a --history-file or at least one json file is required")
+		}
+		runs, err := history.Read(opts.historyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read history file: %w", err)
+		}
+		if opts.runs > 0 && len(runs) > opts.runs {
+			runs = runs[len(runs)-opts.runs:]
+		}
+		log.Debugf("read %d runs from %v", len(runs), opts.historyFile)
+		for _, run := range runs {
+			addHistoryRun(groups, run, groupKey)
+		}
+		return write(opts.stdout, sortGroups(groups))
+	}
+
+	for _, fileName := range opts.jsonfiles {
+		exec, err := jsonfile.Scan(fileName, testjson.ScanConfig{})
+		if err != nil {
+			return err
+		}
+		addRun(groups, exec, groupKey)
+	}
+	return write(opts.stdout, sortGroups(groups))
+}
+
+// parseGroupBy returns a function which returns the name of the group for a
+// package path.
+func parseGroupBy(value string) (func(pkg string) string, error) {
+	switch {
+	case value == "package":
+		return testjson.RelativePackagePath, nil
+	case strings.HasPrefix(value, "dir:"):
+		depth, err := strconv.Atoi(strings.TrimPrefix(value, "dir:"))
+		if err != nil || depth < 1 {
+			return nil, fmt.Errorf("invalid --group-by %q, dir depth must be a positive number", value)
+		}
+		return func(pkg string) string {
+			parts := strings.Split(testjson.RelativePackagePath(pkg), "/")
+			if len(parts) > depth {
+				parts = parts[:depth]
+			}
+			return strings.Join(parts, "/")
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid --group-by %q, must be one of: package, dir:N", value)
+	}
+}
+
+type groupStats struct {
+	name     string
+	runs     int
+	tests    int
+	failed   int
+	flaky    int
+	testTime time.Duration
+}
+
+func (g groupStats) failRate() float64 {
+	return percent(g.failed, g.tests)
+}
+
+func (g groupStats) flakeRate() float64 {
+	return percent(g.flaky, g.tests)
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
+// addRun adds the results of one run to groups. A test which failed and then
+// passed on a later attempt is counted as flaky, not as failed.
+func addRun(groups map[string]*groupStats, exec *testjson.Execution, groupKey func(string) string) {
+	seen := make(map[string]bool)
+	for _, name := range exec.Packages() {
+		key := groupKey(name)
+		g, ok := groups[key]
+		if !ok {
+			g = &groupStats{name: key}
+			groups[key] = g
+		}
+		if !seen[key] {
+			seen[key] = true
+			g.runs++
+		}
+
+		pkg := exec.Package(name)
+		g.testTime += pkg.Elapsed()
+
+		passed := make(map[testjson.TestName]bool)
+		for _, tc := range pkg.Passed {
+			passed[tc.Test] = true
+		}
+		failed := make(map[testjson.TestName]bool)
+		for _, tc := range pkg.Failed {
+			if failed[tc.Test] {
+				continue
+			}
+			failed[tc.Test] = true
+			if passed[tc.Test] {
+				g.flaky++
+			} else {
+				g.failed++
+			}
+		}
+		g.tests += len(failed) + len(pkg.Skipped)
+		for name := range passed {
+			if !failed[name] {
+				g.tests++
+			}
+		}
+	}
+}
+
+// addHistoryRun adds the records of one run from the history file to groups,
+// in the same way as addRun. The test time is the sum of the elapsed time of
+// the top level tests, because the history file does not have the elapsed time
+// of packages.
+func addHistoryRun(groups map[string]*groupStats, run history.Run, groupKey func(string) string) {
+	type testKey struct {
+		pkg  string
+		test string
+	}
+	var keys []testKey
+	outcomes := make(map[testKey]map[string]int)
+	for _, r := range run.Records {
+		key := testKey{pkg: r.Package, test: r.Test}
+		if outcomes[key] == nil {
+			outcomes[key] = make(map[string]int)
+			keys = append(keys, key)
+		}
+		outcomes[key][r.Outcome]++
+
+		g, ok := groups[groupKey(r.Package)]
+		if !ok {
+			g = &groupStats{name: groupKey(r.Package)}
+			groups[g.name] = g
+		}
+		if !testjson.TestName(r.Test).IsSubTest() {
+			g.testTime += r.Elapsed()
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		g := groups[groupKey(key.pkg)]
+		if !seen[g.name] {
+			seen[g.name] = true
+			g.runs++
+		}
+		outcome := outcomes[key]
+		failed, passed := outcome[string(testjson.ActionFail)] > 0, outcome[string(testjson.ActionPass)] > 0
+		switch {
+		case failed && passed:
+			g.flaky++
+			g.tests++
+		case failed:
+			g.failed++
+			g.tests++
+		case passed:
+			g.tests++
+		}
+		g.tests += outcome[string(testjson.ActionSkip)]
+	}
+}
+
+// sortGroups returns the groups sorted by test time, highest first.
+func sortGroups(groups map[string]*groupStats) []groupStats {
+	result := make([]groupStats, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].testTime != result[j].testTime {
+			return result[i].testTime > result[j].testTime
+		}
+		return result[i].name < result[j].name
+	})
+	return result
+}
+
+var reportColumns = []string{"group", "runs", "tests", "test_time", "fail_rate", "flake_rate"}
+
+func reportWriter(format string) (func(io.Writer, []groupStats) error, error) {
+	switch format {
+	case "text":
+		return writeText, nil
+	case "csv":
+		return writeCSV, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q, must be one of: text, csv", format)
+	}
+}
+
+func writeText(out io.Writer, groups []groupStats) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(reportColumns, "\t")))
+	for _, g := range groups {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%.1f%%\t%.1f%%\n",
+			g.name, g.runs, g.tests, g.testTime.Round(time.Millisecond),
+			g.failRate(), g.flakeRate())
+	}
+	return w.Flush()
+}
+
+func writeCSV(out io.Writer, groups []groupStats) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(reportColumns); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		record := []string{
+			g.name,
+			strconv.Itoa(g.runs),
+			strconv.Itoa(g.tests),
+			strconv.FormatFloat(g.testTime.Seconds(), 'f', -1, 64),
+			strconv.FormatFloat(g.failRate(), 'f', 2, 64),
+			strconv.FormatFloat(g.flakeRate(), 'f', 2, 64),
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,76 @@
+package history
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestReport(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		groupBy string
+		format  string
+		golden  string
+	}{
+		{name: "package", groupBy: "package", format: "text", golden: "report-package.out"},
+		{name: "dir", groupBy: "dir:2", format: "text", golden: "report-dir.out"},
+		{name: "dir csv", groupBy: "dir:2", format: "csv", golden: "report-dir.csv"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			opts := &reportOptions{
+				groupBy:   tc.groupBy,
+				format:    tc.format,
+				jsonfiles: []string{"testdata/run1.json", "testdata/run2.json"},
+				stdout:    out,
+			}
+			assert.NilError(t, report(opts))
+			golden.Assert(t, out.String(), tc.golden)
+		})
+	}
+}
+
+func TestReport_InvalidOptions(t *testing.T) {
+	jsonfiles := []string{"testdata/run1.json"}
+
+	err := report(&reportOptions{groupBy: "team", format: "text", jsonfiles: jsonfiles})
+	assert.Error(t, err, `invalid --group-by "team", must be one of: package, dir:N`)
+
+	err = report(&reportOptions{groupBy: "dir:0", format: "text", jsonfiles: jsonfiles})
+	assert.Error(t, err, `invalid --group-by "dir:0", dir depth must be a positive number`)
+
+	err = report(&reportOptions{groupBy: "package", format: "xml", jsonfiles: jsonfiles})
+	assert.Error(t, err, `unsupported format "xml", must be one of: text, csv`)
+
+	err = report(&reportOptions{groupBy: "package", format: "text"})
+	assert.Error(t, err, "a --history-file or at least one json file is required")
+}
+
+func TestReport_HistoryFile(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		runs   int
+		golden string
+	}{
+		{name: "all runs", golden: "report-history.out"},
+		{name: "recent runs", runs: 2, golden: "report-history-runs.out"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			opts := &reportOptions{
+				groupBy:     "package",
+				format:      "text",
+				historyFile: "testdata/history.jsonl",
+				runs:        tc.runs,
+				stdout:      out,
+			}
+			assert.NilError(t, report(opts))
+			golden.Assert(t, out.String(), tc.golden)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package html
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	return run(opts)
+}
+
+type options struct {
+	jsonfile string
+	output   string
+	title    string
+	debug    bool
+	stdout   io.Writer
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{stdout: os.Stdout}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.jsonfile, "jsonfile", os.Getenv("GOTESTSUM_JSONFILE"),
+		"path to test2json output, defaults to stdin")
+	flags.StringVar(&opts.output, "output", "",
+		"path of the HTML report, defaults to stdout")
+	flags.StringVar(&opts.title, "title", "Test report",
+		"title of the HTML report")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Read a json file and write a standalone HTML report of the test results. The
+json file may be created with 'gotestsum --jsonfile' or 'go test -json'.
+
+The report includes the result and elapsed time of every package and test, the
+coverage of each package, and the output of failed tests. Tests which were run
+more than once, for example by 'gotestsum --rerun-fails', show the result of
+every attempt. The report has no external dependencies, so it can be attached
+to a CI build as an artifact.
+
+Example:
+
+    gotestsum --jsonfile test.json --rerun-fails -- -cover ./...
+    %[1]s --jsonfile test.json --output report.html
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	in, err := jsonfile.OpenOrStdin(opts.jsonfile)
+	if err != nil {
+		return fmt.Errorf("failed to read jsonfile: %v", err)
+	}
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Errorf("Failed to close file %v: %v", opts.jsonfile, err)
+		}
+	}()
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: in})
+	if err != nil {
+		return fmt.Errorf("failed to scan testjson: %w", err)
+	}
+
+	if opts.output == "" {
+		return Write(opts.stdout, exec, Config{Title: opts.title})
+	}
+	fh, err := os.Create(opts.output)
+	if err != nil {
+		return err
+	}
+	if err := Write(fh, exec, Config{Title: opts.title}); err != nil {
+		_ = fh.Close()
+		return err
+	}
+	return fh.Close()
+}
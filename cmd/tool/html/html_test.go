@@ -0,0 +1,36 @@
+package html
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestRun(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfile: "testdata/input.json",
+		title:    "Test report",
+		stdout:   out,
+	}
+	assert.NilError(t, run(opts))
+	golden.Assert(t, out.String(), "expected-report.html")
+}
+
+func TestRun_WithOutput(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	opts := &options{
+		jsonfile: "testdata/input.json",
+		output:   filepath.Join(dir.Path(), "report.html"),
+		title:    "Test report",
+	}
+	assert.NilError(t, run(opts))
+
+	expected := fs.Expected(t, fs.WithFile("report.html", "",
+		fs.MatchContentIgnoreCarriageReturn, fs.WithBytes(golden.Get(t, "expected-report.html"))))
+	assert.Assert(t, fs.Equal(dir.Path(), expected))
+}
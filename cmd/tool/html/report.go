@@ -0,0 +1,187 @@
+package html
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+//go:embed report.html
+var reportTemplate string
+
+var tmpl = template.Must(template.New("report").Parse(reportTemplate))
+
+// Config used to write a report.
+type Config struct {
+	Title string
+}
+
+// Write a standalone HTML report of exec to out.
+func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
+	if err := tmpl.Execute(out, newReport(exec, cfg)); err != nil {
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+	return nil
+}
+
+type report struct {
+	Title    string
+	Started  string
+	Elapsed  string
+	Total    int
+	Passed   int
+	Failed   int
+	Skipped  int
+	Flaky    int
+	Errors   []string
+	Packages []packageReport
+}
+
+type packageReport struct {
+	// ID of the HTML element, used to link to the package.
+	ID       string
+	Name     string
+	Result   string
+	Elapsed  string
+	Coverage string
+	// Output of the package when TestMain failed.
+	Output string
+	Tests  []testReport
+}
+
+type testReport struct {
+	Name    string
+	Result  string
+	Elapsed string
+	Flaky   bool
+	// Attempts of the test, in the order they were run. A test which was only
+	// run once has a single attempt.
+	Attempts []attempt
+}
+
+type attempt struct {
+	Number  int
+	Result  string
+	Elapsed string
+	Output  string
+}
+
+// Values of the result of a package, test, or attempt. The values are used as
+// CSS classes, and by the filter.
+const (
+	resultPass = "pass"
+	resultFail = "fail"
+	resultSkip = "skip"
+)
+
+func newReport(exec *testjson.Execution, cfg Config) report {
+	r := report{
+		Title:   cfg.Title,
+		Started: exec.Started().UTC().Format(time.RFC3339),
+		Elapsed: formatDuration(exec.Elapsed()),
+		Errors:  exec.Errors(),
+	}
+	for i, name := range exec.Packages() {
+		pkg := newPackageReport(name, exec.Package(name))
+		pkg.ID = fmt.Sprintf("pkg-%d", i)
+		for _, tc := range pkg.Tests {
+			r.Total++
+			switch {
+			case tc.Flaky:
+				r.Flaky++
+			case tc.Result == resultFail:
+				r.Failed++
+			case tc.Result == resultSkip:
+				r.Skipped++
+			default:
+				r.Passed++
+			}
+		}
+		r.Packages = append(r.Packages, pkg)
+	}
+	return r
+}
+
+func newPackageReport(name string, pkg *testjson.Package) packageReport {
+	r := packageReport{
+		Name:     name,
+		Result:   packageResult(pkg),
+		Elapsed:  formatDuration(pkg.Elapsed()),
+		Coverage: strings.TrimPrefix(pkg.Coverage(), "coverage: "),
+	}
+	if pkg.TestMainFailed() {
+		r.Output = pkg.Output(0)
+	}
+
+	type testCase struct {
+		testjson.TestCase
+		result string
+	}
+	var tcs []testCase
+	for _, group := range []struct {
+		tcs    []testjson.TestCase
+		result string
+	}{
+		{tcs: pkg.Failed, result: resultFail},
+		{tcs: pkg.Skipped, result: resultSkip},
+		{tcs: pkg.Passed, result: resultPass},
+	} {
+		for _, tc := range group.tcs {
+			tcs = append(tcs, testCase{TestCase: tc, result: group.result})
+		}
+	}
+	sort.Slice(tcs, func(i, j int) bool { return tcs[i].ID < tcs[j].ID })
+
+	index := make(map[testjson.TestName]int)
+	for _, tc := range tcs {
+		i, ok := index[tc.Test]
+		if !ok {
+			i = len(r.Tests)
+			index[tc.Test] = i
+			r.Tests = append(r.Tests, testReport{Name: tc.Test.Name()})
+		}
+		test := &r.Tests[i]
+		if test.Result == resultFail && tc.result == resultPass {
+			test.Flaky = true
+		}
+		test.Result = tc.result
+		test.Elapsed = formatDuration(tc.Elapsed)
+		a := attempt{
+			Number:  len(test.Attempts) + 1,
+			Result:  tc.result,
+			Elapsed: formatDuration(tc.Elapsed),
+		}
+		if tc.result != resultPass {
+			a.Output = strings.Join(pkg.OutputLines(tc.TestCase), "")
+		}
+		test.Attempts = append(test.Attempts, a)
+	}
+	return r
+}
+
+func packageResult(pkg *testjson.Package) string {
+	switch pkg.Result() {
+	case testjson.ActionFail:
+		return resultFail
+	case testjson.ActionSkip:
+		return resultSkip
+	default:
+		if pkg.IsEmpty() {
+			return resultSkip
+		}
+		return resultPass
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
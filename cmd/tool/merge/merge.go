@@ -0,0 +1,199 @@
+package merge
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	opts.jsonfiles = flags.Args()
+	return run(opts)
+}
+
+type options struct {
+	jsonfiles        []string
+	out              string
+	junitFile        string
+	junitProjectName string
+	debug            bool
+	stdout           io.Writer
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{stdout: os.Stdout}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringVar(&opts.out, "out", "",
+		"write the merged test2json output to this file")
+	flags.StringVar(&opts.junitFile, "junitfile", "",
+		"write a JUnit XML file of the merged results")
+	flags.StringVar(&opts.junitProjectName, "junitfile-project-name", "",
+		"name of the project used in the junit.xml file")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags] JSONFILE...
+
+Merge the json files from the shards of a test run into a single run, and print
+the summary of the merged run. The json files may be created with
+'gotestsum --jsonfile' or 'go test -json'. Each argument may be a path, or a
+glob pattern which matches many files.
+
+Use --junitfile to write a single JUnit XML file for all the shards, and --out to
+write the merged json file, which can be used as the input of other tools.
+
+The exit code is 1 if any test failed, or any package failed to build, the same
+as the exit code of the test run.
+
+Example:
+
+    %[1]s 'shard-*.json' --junitfile junit.xml --out merged.json
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	fileNames, err := jsonfile.Expand(opts.jsonfiles)
+	if err != nil {
+		return err
+	}
+	if len(fileNames) == 0 {
+		return fmt.Errorf("at least one json file is required")
+	}
+
+	var out io.Writer = io.Discard
+	if opts.out != "" {
+		fh, err := jsonfile.Create(opts.out)
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+		defer func() {
+			if err := fh.Close(); err != nil {
+				log.Errorf("Failed to close file %v: %v", opts.out, err)
+			}
+		}()
+		out = fh
+	}
+
+	exec, err := mergeFiles(fileNames, out)
+	if err != nil {
+		return err
+	}
+	testjson.PrintSummary(opts.stdout, exec, testjson.SummarizeAll)
+
+	if err := writeJUnitFile(opts, exec); err != nil {
+		return fmt.Errorf("failed to write junit file: %w", err)
+	}
+	if len(exec.Failed()) > 0 || len(exec.Errors()) > 0 {
+		return exitError{num: 1}
+	}
+	return nil
+}
+
+// mergeFiles scans every file into a single Execution, and copies the events
+// from every file to out.
+func mergeFiles(fileNames []string, out io.Writer) (*testjson.Execution, error) {
+	w := &lineWriter{out: out}
+	var exec *testjson.Execution
+	for _, fileName := range fileNames {
+		log.Debugf("merging %v", fileName)
+		fh, err := jsonfile.Open(fileName)
+		if err != nil {
+			return nil, err
+		}
+		exec, err = testjson.ScanTestOutput(testjson.ScanConfig{
+			Stdout:    io.TeeReader(fh, w),
+			Execution: exec,
+		})
+		_ = fh.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan testjson from %v: %w", fileName, err)
+		}
+		if err := w.endLine(); err != nil {
+			return nil, fmt.Errorf("failed to write merged file: %w", err)
+		}
+	}
+	return exec, nil
+}
+
+// lineWriter is an io.Writer which can end the last line written, so that the
+// first event of the next file is on a new line.
+type lineWriter struct {
+	out  io.Writer
+	last byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		w.last = p[len(p)-1]
+	}
+	return w.out.Write(p)
+}
+
+func (w *lineWriter) endLine() error {
+	if w.last == 0 || w.last == '\n' {
+		return nil
+	}
+	_, err := w.Write([]byte{'\n'})
+	return err
+}
+
+func writeJUnitFile(opts *options, exec *testjson.Execution) error {
+	if opts.junitFile == "" {
+		return nil
+	}
+	_ = os.MkdirAll(filepath.Dir(opts.junitFile), 0o755)
+	junitFile, err := os.Create(opts.junitFile)
+	if err != nil {
+		return fmt.Errorf("failed to open JUnit file: %v", err)
+	}
+	defer func() {
+		if err := junitFile.Close(); err != nil {
+			log.Errorf("Failed to close JUnit file: %v", err)
+		}
+	}()
+	return junitxml.Write(junitFile, exec, junitxml.Config{
+		ProjectName: opts.junitProjectName,
+	})
+}
+
+type exitError struct {
+	num int
+}
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit code %d", e.num)
+}
+
+func (e exitError) ExitCode() int {
+	return e.num
+}
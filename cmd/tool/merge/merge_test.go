@@ -0,0 +1,53 @@
+package merge
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestRun(t *testing.T) {
+	dir := fs.NewDir(t, "merge")
+	out := new(bytes.Buffer)
+	opts := &options{
+		jsonfiles: []string{"testdata/shard-*.json"},
+		out:       dir.Join("merged.json"),
+		junitFile: dir.Join("junit.xml"),
+		stdout:    out,
+	}
+	err := run(opts)
+	assert.Error(t, err, "exit code 1")
+	golden.Assert(t, out.String(), "summary.out")
+
+	merged, err := os.ReadFile(opts.out)
+	assert.NilError(t, err)
+	var expected []byte
+	for _, name := range []string{"shard-1.json", "shard-2.json", "shard-3.json"} {
+		raw, err := os.ReadFile("testdata/" + name)
+		assert.NilError(t, err)
+		expected = append(expected, raw...)
+		if !bytes.HasSuffix(expected, []byte("\n")) {
+			expected = append(expected, '\n')
+		}
+	}
+	assert.Equal(t, string(merged), string(expected))
+
+	junit, err := os.ReadFile(opts.junitFile)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(string(junit), `<testsuites tests="6" failures="2" errors="0"`))
+	assert.Equal(t, strings.Count(string(junit), "<testsuite "), 3)
+}
+
+func TestRun_NoFiles(t *testing.T) {
+	err := run(&options{})
+	assert.Error(t, err, "at least one json file is required")
+
+	err = run(&options{jsonfiles: []string{"testdata/missing-*.json"}})
+	assert.Error(t, err, "no files match testdata/missing-*.json")
+}
@@ -0,0 +1,390 @@
+package retrystats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	return run(opts)
+}
+
+type options struct {
+	jsonfiles    []string
+	rerunReports []string
+	format       string
+	num          int
+	debug        bool
+	stdout       io.Writer
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{stdout: os.Stdout}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringArrayVar(&opts.jsonfiles, "jsonfile", nil,
+		"path or glob pattern of test2json output from 'gotestsum --rerun-fails', may be repeated")
+	flags.StringArrayVar(&opts.rerunReports, "rerun-report", nil,
+		"path or glob pattern of a json --rerun-fails-report, may be repeated")
+	flags.StringVar(&opts.format, "format", "text",
+		"format of the report, one of: text, json")
+	flags.IntVar(&opts.num, "num", 0,
+		"print at most num tests, instead of all tests which were rerun")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Read the results of many runs of 'gotestsum --rerun-fails' and report how
+effective the reruns were. Each file is one run. A run may be a json file created
+with 'gotestsum --jsonfile', or a json file created with --rerun-fails-report
+and --rerun-fails-report-format=json.
+
+The report includes the number of runs which were rescued by reruns, which
+means every failed test passed when it was rerun, the number of runs which
+failed even after the reruns, and the time spent running tests again. The time
+is an estimate of the CI minutes spent on retries, it does not include the
+time to build the test binaries.
+
+The tests which were rerun are sorted by the time spent on their retries,
+highest first. The columns of the report are:
+
+    retries        number of times the test was rerun
+    rescued_runs   number of runs where the test failed, and then passed
+                   when it was rerun
+    failed_runs    number of runs where the last attempt of the test failed
+    retry_time     total elapsed time of the reruns of the test
+
+Example:
+
+    %[1]s --jsonfile './runs/*.json' --num 10
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	write, err := reportWriter(opts.format)
+	if err != nil {
+		return err
+	}
+	jsonfiles, err := jsonfile.Expand(opts.jsonfiles)
+	if err != nil {
+		return err
+	}
+	rerunReports, err := jsonfile.Expand(opts.rerunReports)
+	if err != nil {
+		return err
+	}
+	if len(jsonfiles)+len(rerunReports) == 0 {
+		return fmt.Errorf("at least one --jsonfile or --rerun-report is required")
+	}
+
+	var runs [][]testAttempts
+	for _, fileName := range jsonfiles {
+		exec, err := jsonfile.Scan(fileName, testjson.ScanConfig{})
+		if err != nil {
+			return err
+		}
+		runs = append(runs, attemptsFromExecution(exec))
+	}
+	for _, fileName := range rerunReports {
+		tests, err := readRerunReport(fileName)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, tests)
+	}
+	log.Debugf("read %d runs", len(runs))
+
+	r := newReport(runs)
+	if opts.num > 0 && opts.num < len(r.Tests) {
+		r.Tests = r.Tests[:opts.num]
+	}
+	return write(opts.stdout, r)
+}
+
+// testAttempts are the attempts of a test which failed at least once in a run,
+// in the order they were run.
+type testAttempts struct {
+	pkg      string
+	test     testjson.TestName
+	attempts []attempt
+}
+
+type attempt struct {
+	failed  bool
+	elapsed time.Duration
+}
+
+func (t testAttempts) lastFailed() bool {
+	return t.attempts[len(t.attempts)-1].failed
+}
+
+// attemptsFromExecution returns the attempts of every test which failed in
+// exec. When the tests were rerun by --rerun-fails every attempt of the test
+// is in the same execution.
+func attemptsFromExecution(exec *testjson.Execution) []testAttempts {
+	var tests []testAttempts
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		seen := make(map[testjson.TestName]bool)
+		for _, failure := range pkg.Failed {
+			if failure.Test == "" || seen[failure.Test] {
+				continue
+			}
+			seen[failure.Test] = true
+
+			type result struct {
+				testjson.TestCase
+				failed bool
+			}
+			var tcs []result
+			for _, tc := range pkg.Failed {
+				if tc.Test == failure.Test {
+					tcs = append(tcs, result{TestCase: tc, failed: true})
+				}
+			}
+			for _, tc := range pkg.Passed {
+				if tc.Test == failure.Test {
+					tcs = append(tcs, result{TestCase: tc})
+				}
+			}
+			sort.Slice(tcs, func(i, j int) bool {
+				return tcs[i].ID < tcs[j].ID
+			})
+
+			t := testAttempts{pkg: name, test: failure.Test}
+			for _, tc := range tcs {
+				t.attempts = append(t.attempts, attempt{failed: tc.failed, elapsed: tc.Elapsed})
+			}
+			tests = append(tests, t)
+		}
+	}
+	return tests
+}
+
+// rerunReport is the subset of the json --rerun-fails-report used by the
+// command.
+type rerunReport struct {
+	Tests []struct {
+		Package  string `json:"package"`
+		Test     string `json:"test"`
+		Attempts []struct {
+			Result  string  `json:"result"`
+			Elapsed float64 `json:"elapsed"`
+		} `json:"attempts"`
+	} `json:"tests"`
+}
+
+func readRerunReport(fileName string) ([]testAttempts, error) {
+	raw, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var report rerunReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return nil, fmt.Errorf("failed to read rerun report from %v: %w", fileName, err)
+	}
+	var result []testAttempts
+	for _, tc := range report.Tests {
+		if len(tc.Attempts) == 0 {
+			continue
+		}
+		t := testAttempts{pkg: tc.Package, test: testjson.TestName(tc.Test)}
+		for _, a := range tc.Attempts {
+			t.attempts = append(t.attempts, attempt{
+				failed:  a.Result == string(testjson.ActionFail),
+				elapsed: time.Duration(a.Elapsed * float64(time.Second)),
+			})
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+type report struct {
+	Runs           int         `json:"runs"`
+	RunsWithReruns int         `json:"runs_with_reruns"`
+	RescuedRuns    int         `json:"rescued_runs"`
+	FailedRuns     int         `json:"failed_runs"`
+	RetrySeconds   float64     `json:"retry_seconds"`
+	Tests          []testStats `json:"tests"`
+}
+
+type testStats struct {
+	Package      string  `json:"package"`
+	Test         string  `json:"test"`
+	Retries      int     `json:"retries"`
+	RescuedRuns  int     `json:"rescued_runs"`
+	FailedRuns   int     `json:"failed_runs"`
+	RetrySeconds float64 `json:"retry_seconds"`
+	retryTime    time.Duration
+}
+
+// newReport returns the report of runs. The retry time of the run only
+// includes top level tests, because the elapsed time of a subtest is part of
+// the elapsed time of its parent.
+func newReport(runs [][]testAttempts) report {
+	r := report{Runs: len(runs), Tests: []testStats{}}
+	type testKey struct {
+		pkg  string
+		test testjson.TestName
+	}
+	stats := make(map[testKey]*testStats)
+	var retryTime time.Duration
+
+	for _, tests := range runs {
+		var rerun, failed bool
+		for _, t := range tests {
+			if len(t.attempts) < 2 {
+				if t.lastFailed() {
+					failed = true
+				}
+				continue
+			}
+			rerun = true
+
+			key := testKey{pkg: t.pkg, test: t.test}
+			s, ok := stats[key]
+			if !ok {
+				s = &testStats{Package: t.pkg, Test: t.test.Name()}
+				stats[key] = s
+			}
+			s.Retries += len(t.attempts) - 1
+			var elapsed time.Duration
+			for _, a := range t.attempts[1:] {
+				elapsed += a.elapsed
+			}
+			s.retryTime += elapsed
+			if !t.test.IsSubTest() {
+				retryTime += elapsed
+			}
+
+			if t.lastFailed() {
+				s.FailedRuns++
+				failed = true
+			} else {
+				s.RescuedRuns++
+			}
+		}
+
+		if !rerun {
+			continue
+		}
+		r.RunsWithReruns++
+		if failed {
+			r.FailedRuns++
+		} else {
+			r.RescuedRuns++
+		}
+	}
+
+	r.RetrySeconds = retryTime.Seconds()
+	for _, s := range stats {
+		s.RetrySeconds = s.retryTime.Seconds()
+		r.Tests = append(r.Tests, *s)
+	}
+	sort.Slice(r.Tests, func(i, j int) bool {
+		a, b := r.Tests[i], r.Tests[j]
+		switch {
+		case a.RetrySeconds != b.RetrySeconds:
+			return a.RetrySeconds > b.RetrySeconds
+		case a.Retries != b.Retries:
+			return a.Retries > b.Retries
+		case a.Package != b.Package:
+			return a.Package < b.Package
+		default:
+			return a.Test < b.Test
+		}
+	})
+	return r
+}
+
+func reportWriter(format string) (func(io.Writer, report) error, error) {
+	switch format {
+	case "text":
+		return writeText, nil
+	case "json":
+		return writeJSON, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q, must be one of: text, json", format)
+	}
+}
+
+var reportColumns = []string{"package", "test", "retries", "rescued_runs", "failed_runs", "retry_time"}
+
+func writeText(out io.Writer, r report) error {
+	retryTime := time.Duration(r.RetrySeconds * float64(time.Second))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Runs:\t%d\n", r.Runs)
+	fmt.Fprintf(w, "Runs with reruns:\t%d\n", r.RunsWithReruns)
+	fmt.Fprintf(w, "Rescued by reruns:\t%d (%s of runs with reruns)\n",
+		r.RescuedRuns, percent(r.RescuedRuns, r.RunsWithReruns))
+	fmt.Fprintf(w, "Failed after reruns:\t%d\n", r.FailedRuns)
+	fmt.Fprintf(w, "Time spent on retries:\t%v (%.1f CI minutes)\n",
+		retryTime.Round(time.Millisecond), retryTime.Minutes())
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.Tests) == 0 {
+		_, err := fmt.Fprintln(out, "\nNo tests were rerun")
+		return err
+	}
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(reportColumns, "\t")))
+	for _, s := range r.Tests {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\n",
+			testjson.RelativePackagePath(s.Package), s.Test,
+			s.Retries, s.RescuedRuns, s.FailedRuns,
+			testjson.FormatDurationAsSeconds(s.retryTime, 3))
+	}
+	return w.Flush()
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(n)/float64(total)*100)
+}
+
+func writeJSON(out io.Writer, r report) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
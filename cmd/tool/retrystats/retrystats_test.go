@@ -0,0 +1,51 @@
+package retrystats
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestRun(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		format string
+		golden string
+	}{
+		{name: "text", format: "text", golden: "retry-stats.out"},
+		{name: "json", format: "json", golden: "retry-stats.json"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			opts := &options{
+				jsonfiles:    []string{"testdata/run*.json"},
+				rerunReports: []string{"testdata/rerun-report.json"},
+				format:       tc.format,
+				stdout:       out,
+			}
+			assert.NilError(t, run(opts))
+			golden.Assert(t, out.String(), tc.golden)
+		})
+	}
+}
+
+func TestRun_NoReruns(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := &options{jsonfiles: []string{"testdata/run3.json"}, format: "text", stdout: out}
+	assert.NilError(t, run(opts))
+	golden.Assert(t, out.String(), "retry-stats-no-reruns.out")
+}
+
+func TestRun_InvalidOptions(t *testing.T) {
+	err := run(&options{format: "xml", jsonfiles: []string{"testdata/run1.json"}})
+	assert.Error(t, err, `unsupported format "xml", must be one of: text, json`)
+
+	err = run(&options{format: "text"})
+	assert.Error(t, err, "at least one --jsonfile or --rerun-report is required")
+
+	err = run(&options{format: "text", rerunReports: []string{"testdata/missing-*.json"}})
+	assert.Error(t, err, "no files match testdata/missing-*.json")
+}
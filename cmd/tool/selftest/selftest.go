@@ -0,0 +1,223 @@
+package selftest
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dnephin/pflag"
+	"github.com/fatih/color"
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// testdata contains the input replayed through each format, and the expected
+// output of each format. The expected output is updated by running
+// 'go test ./cmd/tool/selftest -update'.
+//
+//go:embed testdata
+var testdata embed.FS
+
+const inputFile = "testdata/input.json"
+
+// formats that are replayed by the selftest. Formats which are aliases of
+// another format are not included.
+var formats = []string{
+	"dots-v1",
+	"dots-v2",
+	"grid",
+	"pkgname",
+	"pkgname-and-test-fails",
+	"testname",
+	"failures-only",
+	"stream",
+	"testdox",
+	"github-actions",
+	"standard-quiet",
+	"standard-verbose",
+	"standard-json",
+	"tap",
+	"teamcity",
+	"azure-pipelines",
+	"gitlab",
+}
+
+// Run the command
+func Run(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	switch err := flags.Parse(args); {
+	case err == pflag.ErrHelp:
+		return nil
+	case err != nil:
+		usage(os.Stderr, name, flags)
+		return err
+	}
+	return run(opts)
+}
+
+type options struct {
+	formats []string
+	verbose bool
+	debug   bool
+	stdout  io.Writer
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *options) {
+	opts := &options{stdout: os.Stdout}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		usage(os.Stdout, name, flags)
+	}
+	flags.StringSliceVar(&opts.formats, "format", nil,
+		"only test these formats, defaults to all formats")
+	flags.BoolVar(&opts.verbose, "verbose", false,
+		"print the output of each format")
+	flags.BoolVar(&opts.debug, "debug", false,
+		"enable debug logging")
+	return flags, opts
+}
+
+func usage(out io.Writer, name string, flags *pflag.FlagSet) {
+	fmt.Fprintf(out, `Usage:
+    %[1]s [flags]
+
+Replay a bundled json file through every --format, and compare the output of
+each format, followed by the summary, to the expected output. Use this command
+to verify that a build of gotestsum renders the output correctly on a
+platform.
+
+The output is rendered without color, and as if stdout is not a terminal, so
+that the result does not depend on the terminal size. Prints the difference
+between the expected and actual output of any format which does not match, and
+exits with a non-zero status.
+
+Flags:
+`, name)
+	flags.SetOutput(out)
+	flags.PrintDefaults()
+}
+
+func run(opts *options) error {
+	if opts.debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	selected := formats
+	if len(opts.formats) > 0 {
+		selected = opts.formats
+	}
+
+	var failed []string
+	for _, format := range selected {
+		expected, err := testdata.ReadFile("testdata/" + format + ".out")
+		if err != nil {
+			return fmt.Errorf("unknown format %v", format)
+		}
+		actual, err := render(format)
+		if err != nil {
+			return fmt.Errorf("failed to render %v: %w", format, err)
+		}
+
+		if opts.verbose {
+			fmt.Fprintf(opts.stdout, "=== %v\n%s\n", format, actual)
+		}
+		if diff := cmp.Diff(lines(string(expected)), lines(actual)); diff != "" {
+			failed = append(failed, format)
+			fmt.Fprintf(opts.stdout, "FAIL %v (-expected +actual):\n%s\n", format, diff)
+			continue
+		}
+		fmt.Fprintf(opts.stdout, "ok   %v\n", format)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("output of %d %s did not match: %v",
+			len(failed), pluralize(len(failed), "format", "formats"), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// render replays the input through format, and returns the output of the
+// format followed by the summary, or the plan of the tap format.
+func render(format string) (string, error) {
+	input, err := testdata.ReadFile(inputFile)
+	if err != nil {
+		return "", err
+	}
+
+	// Some formats print a different output when stdout is a terminal, or
+	// when running in github actions.
+	defer patchEnvironment()()
+
+	out := new(bytes.Buffer)
+	formatter := testjson.NewEventFormatter(out, format, testjson.FormatOptions{})
+	if formatter == nil {
+		return "", fmt.Errorf("unknown format %v", format)
+	}
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  bytes.NewReader(input),
+		Handler: &handler{formatter: formatter},
+	})
+	if err != nil {
+		return "", err
+	}
+	if format == "tap" {
+		err = testjson.PrintTAPPlan(out, exec)
+		return out.String(), err
+	}
+	testjson.PrintSummary(out, exec, testjson.SummarizeAll)
+	return out.String(), nil
+}
+
+type handler struct {
+	formatter testjson.EventFormatter
+}
+
+func (h *handler) Event(event testjson.TestEvent, exec *testjson.Execution) error {
+	return h.formatter.Format(event, exec)
+}
+
+func (h *handler) Err(text string) error {
+	return fmt.Errorf("unexpected stderr: %v", text)
+}
+
+// patchEnvironment disables color, replaces os.Stdout with a file that is not
+// a terminal, and unsets GITHUB_ACTIONS. It returns a function which restores
+// the original values.
+func patchEnvironment() func() {
+	noColor := color.NoColor
+	color.NoColor = true
+
+	stdout := os.Stdout
+	if devNull, err := os.Open(os.DevNull); err == nil {
+		os.Stdout = devNull
+	}
+
+	githubActions, ok := os.LookupEnv("GITHUB_ACTIONS")
+	_ = os.Unsetenv("GITHUB_ACTIONS")
+
+	return func() {
+		color.NoColor = noColor
+		if os.Stdout != stdout {
+			_ = os.Stdout.Close()
+			os.Stdout = stdout
+		}
+		if ok {
+			_ = os.Setenv("GITHUB_ACTIONS", githubActions)
+		}
+	}
+}
+
+func lines(s string) []string {
+	return strings.SplitAfter(s, "\n")
+}
+
+func pluralize(count int, singular, plural string) string {
+	if count == 1 {
+		return singular
+	}
+	return plural
+}
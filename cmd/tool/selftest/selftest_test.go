@@ -0,0 +1,31 @@
+package selftest
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestRender(t *testing.T) {
+	for _, format := range formats {
+		t.Run(format, func(t *testing.T) {
+			out, err := render(format)
+			assert.NilError(t, err)
+			golden.Assert(t, out, format+".out")
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := run(&options{formats: []string{"pkgname", "testname"}, stdout: out})
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), "ok   pkgname\nok   testname\n")
+}
+
+func TestRun_UnknownFormat(t *testing.T) {
+	err := run(&options{formats: []string{"fancy"}, stdout: new(bytes.Buffer)})
+	assert.Error(t, err, "unknown format fancy")
+}
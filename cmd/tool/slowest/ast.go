@@ -6,8 +6,12 @@ import (
 	"go/format"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"golang.org/x/tools/go/packages"
 	"gotest.tools/gotestsum/internal/log"
@@ -101,39 +105,183 @@ func rewriteAST(file *ast.File, testNames set, skipStmt ast.Stmt) bool {
 			continue
 		}
 		name := fd.Name.Name // TODO: can this be nil?
-		if _, ok := testNames[name]; !ok {
+		names := testNamesForFunc(testNames, name)
+		if len(names) == 0 {
 			continue
 		}
 
-		fd.Body.List = append([]ast.Stmt{skipStmt}, fd.Body.List...)
+		skipParent := false
+		for _, testName := range names {
+			subtest := strings.Split(testName, "/")[1:]
+			if len(subtest) == 0 || !insertSubtestSkip(file, fd.Body, subtest, skipStmt) {
+				log.Debugf("skipping all of %v for %v", name, testName)
+				skipParent = true
+			}
+			delete(testNames, testName)
+		}
+		if skipParent {
+			fd.Body.List = append([]ast.Stmt{skipStmt}, fd.Body.List...)
+		}
 		modified = true
-		delete(testNames, name)
 	}
 	return modified
 }
 
-type set map[string]struct{}
+// testNamesForFunc returns the names in testNames of the test function, and of
+// its subtests. When a test and its subtests are all slow only the subtests are
+// returned, because the test is slow from the time of its subtests.
+func testNamesForFunc(testNames set, funcName string) []string {
+	var names []string
+	for testName := range testNames {
+		if testName == funcName || strings.HasPrefix(testName, funcName+"/") {
+			names = append(names, testName)
+		}
+	}
+	sort.Strings(names)
 
-// testNamesByPkgName strips subtest names from test names, then builds
-// and returns a slice of all the packages names, and a mapping of package name
-// to set of failed tests in that package.
+	result := names[:0]
+	for i, testName := range names {
+		// names are sorted, so any subtest of testName is after it
+		if i+1 < len(names) && strings.HasPrefix(names[i+1], testName+"/") {
+			delete(testNames, testName)
+			continue
+		}
+		result = append(result, testName)
+	}
+	return result
+}
+
+// insertSubtestSkip adds skipStmt to the start of the function literal passed
+// to the t.Run call of the subtest. The subtest is a path of names, one for
+// each level of t.Run calls. Returns false if the subtest was not found.
 //
-// subtests are removed because the AST lookup currently only works for top-level
-// functions, not t.Run subtests.
+// A t.Run call with a string literal matches the name of the subtest. A t.Run
+// call with any other expression, like tc.name in a table test, matches when
+// the file has a string literal with the name of the subtest, and the skip is
+// guarded by a comparison of the expression to that literal.
+func insertSubtestSkip(file *ast.File, body *ast.BlockStmt, subtest []string, skipStmt ast.Stmt) bool {
+	var conds []ast.Expr
+	for i, name := range subtest {
+		fn, cond := findSubtestFunc(file, body, name)
+		if fn == nil {
+			return false
+		}
+		if cond != nil {
+			conds = append(conds, cond)
+		}
+		if i < len(subtest)-1 {
+			body = fn.Body
+			continue
+		}
+
+		stmt := skipStmt
+		if len(conds) > 0 {
+			cond := conds[0]
+			for _, c := range conds[1:] {
+				cond = &ast.BinaryExpr{X: cond, Op: token.LAND, Y: c}
+			}
+			stmt = &ast.IfStmt{Cond: cond, Body: &ast.BlockStmt{List: []ast.Stmt{skipStmt}}}
+		}
+		fn.Body.List = append([]ast.Stmt{stmt}, fn.Body.List...)
+	}
+	return true
+}
+
+// findSubtestFunc returns the function literal of the first t.Run call in body
+// which matches name, and the condition which guards the skip, if one is
+// required.
+func findSubtestFunc(file *ast.File, body *ast.BlockStmt, name string) (*ast.FuncLit, ast.Expr) {
+	var fn *ast.FuncLit
+	var cond ast.Expr
+	ast.Inspect(body, func(node ast.Node) bool {
+		if fn != nil {
+			return false
+		}
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Run" || len(call.Args) != 2 {
+			return true
+		}
+		lit, ok := call.Args[1].(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+
+		if nameLit, ok := call.Args[0].(*ast.BasicLit); ok {
+			if subtestName(nameLit) == name {
+				fn = lit
+			}
+			// do not look for subtests of other subtests
+			return false
+		}
+		if tableLit := findStringLit(file, name); tableLit != nil {
+			expr, err := parser.ParseExpr(types.ExprString(call.Args[0]))
+			if err != nil {
+				return true
+			}
+			fn = lit
+			cond = &ast.BinaryExpr{
+				X:  expr,
+				Op: token.EQL,
+				Y:  &ast.BasicLit{Kind: token.STRING, Value: tableLit.Value},
+			}
+		}
+		return false
+	})
+	return fn, cond
+}
+
+// findStringLit returns the first string literal in file which is name, after
+// the name is rewritten in the same way as the name of a subtest.
+func findStringLit(file *ast.File, name string) *ast.BasicLit {
+	var result *ast.BasicLit
+	ast.Inspect(file, func(node ast.Node) bool {
+		if result != nil {
+			return false
+		}
+		if lit, ok := node.(*ast.BasicLit); ok && subtestName(lit) == name {
+			result = lit
+		}
+		return true
+	})
+	return result
+}
+
+// subtestName returns the name of a subtest created by t.Run with the string
+// literal. Returns an empty string if lit is not a string.
+func subtestName(lit *ast.BasicLit) string {
+	if lit.Kind != token.STRING {
+		return ""
+	}
+	value, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return ""
+	}
+	// the testing package replaces spaces in the name of a subtest with _
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+type set map[string]struct{}
+
+// testNamesByPkgName builds and returns a slice of all the packages names, and
+// a mapping of package name to set of slow tests in that package.
 func testNamesByPkgName(tcs []testjson.TestCase) ([]string, map[string]set) {
 	var pkgs []string
 	index := make(map[string]set)
 	for _, tc := range tcs {
-		testName := tc.Test.Name()
-		if tc.Test.IsSubTest() {
-			root, _ := tc.Test.Split()
-			testName = root
-		}
 		if len(index[tc.Package]) == 0 {
 			pkgs = append(pkgs, tc.Package)
 			index[tc.Package] = make(map[string]struct{})
 		}
-		index[tc.Package][testName] = struct{}{}
+		index[tc.Package][tc.Test.Name()] = struct{}{}
 	}
 	return pkgs, index
 }
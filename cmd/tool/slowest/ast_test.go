@@ -3,10 +3,13 @@ package slowest
 import (
 	"bytes"
 	"go/format"
+	"go/parser"
 	"go/token"
 	"testing"
 
+	"gotest.tools/gotestsum/testjson"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
 )
 
 func TestParseSkipStatement_Preset_testingShort(t *testing.T) {
@@ -20,3 +23,30 @@ func TestParseSkipStatement_Preset_testingShort(t *testing.T) {
 	assert.NilError(t, err)
 	assert.DeepEqual(t, buf.String(), expected)
 }
+
+func TestRewriteAST_Subtests(t *testing.T) {
+	fset := token.NewFileSet()
+	// packages.Load parses other files first, so the positions of skipStmt are
+	// never in the file that is rewritten.
+	fset.AddFile("other.go", -1, 10000)
+	file, err := parser.ParseFile(fset, "testdata/subtests_test.go.in", nil, parser.ParseComments)
+	assert.NilError(t, err)
+	skipStmt, err := parseSkipStatement("testing.Short")
+	assert.NilError(t, err)
+
+	_, index := testNamesByPkgName([]testjson.TestCase{
+		{Package: "example", Test: "TestTable"},
+		{Package: "example", Test: "TestTable/slow_case"},
+		{Package: "example", Test: "TestLiteral"},
+		{Package: "example", Test: "TestLiteral/slow"},
+		{Package: "example", Test: "TestLiteral/slow/nested_slow"},
+		{Package: "example", Test: "TestWhole"},
+		{Package: "example", Test: "TestHelperRun/by_helper"},
+	})
+	assert.Assert(t, rewriteAST(file, index["example"], skipStmt))
+	assert.NilError(t, errTestCasesNotFound(index))
+
+	buf := new(bytes.Buffer)
+	assert.NilError(t, format.Node(buf, fset, file))
+	golden.Assert(t, buf.String(), "subtests_test.go.golden")
+}
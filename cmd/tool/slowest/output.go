@@ -0,0 +1,82 @@
+package slowest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// outputFormats are the values of the --output flag.
+var outputFormats = []string{"text", "json", "csv", "markdown"}
+
+type slowTest struct {
+	Package        string  `json:"package"`
+	Test           string  `json:"test"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+}
+
+// writeSlowest writes the list of slow tests to out in the format of the
+// --output flag.
+func writeSlowest(out io.Writer, format string, tcs []testjson.TestCase) error {
+	switch format {
+	case "text":
+		for _, tc := range tcs {
+			if _, err := fmt.Fprintf(out, "%s %s %v\n", tc.Package, tc.Test, tc.Elapsed); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		tests := make([]slowTest, 0, len(tcs))
+		for _, tc := range tcs {
+			tests = append(tests, slowTest{
+				Package:        tc.Package,
+				Test:           tc.Test.Name(),
+				ElapsedSeconds: tc.Elapsed.Seconds(),
+			})
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(tests)
+	case "csv":
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"package", "test", "elapsed_seconds"})
+		for _, tc := range tcs {
+			elapsed := strconv.FormatFloat(tc.Elapsed.Seconds(), 'f', -1, 64)
+			_ = w.Write([]string{tc.Package, tc.Test.Name(), elapsed})
+		}
+		w.Flush()
+		return w.Error()
+	case "markdown":
+		var b strings.Builder
+		b.WriteString("| Package | Test | Elapsed |\n")
+		b.WriteString("| --- | --- | ---: |\n")
+		for _, tc := range tcs {
+			fmt.Fprintf(&b, "| `%s` | `%s` | %v |\n",
+				markdownEscape(tc.Package), markdownEscape(tc.Test.Name()), tc.Elapsed)
+		}
+		_, err := io.WriteString(out, b.String())
+		return err
+	default:
+		return validateOutput(format)
+	}
+}
+
+func validateOutput(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("--output must be one of: %v", strings.Join(outputFormats, ", "))
+}
+
+// markdownEscape escapes the characters which would end a table cell.
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
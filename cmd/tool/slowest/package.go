@@ -0,0 +1,153 @@
+package slowest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// groupByValues are the values of the --group-by flag.
+var groupByValues = []string{"test", "package"}
+
+func validateGroupBy(groupBy string) error {
+	for _, v := range groupByValues {
+		if v == groupBy {
+			return nil
+		}
+	}
+	return fmt.Errorf("--group-by must be one of: %v", strings.Join(groupByValues, ", "))
+}
+
+// packageTime is the test time of a package.
+type packageTime struct {
+	Package string
+	// Tests is the number of top level tests in the package.
+	Tests int
+	// Total is the sum of the elapsed time of the top level tests. The time of
+	// a subtest is included in the time of its parent.
+	Total time.Duration
+}
+
+// Average returns the average elapsed time of a test in the package.
+func (p packageTime) Average() time.Duration {
+	if p.Tests == 0 {
+		return 0
+	}
+	return p.Total / time.Duration(p.Tests)
+}
+
+// packageTimes returns the test time of every package in exec, sorted by total
+// time, slowest first. fn is used to select the elapsed time of a test that ran
+// more than once.
+func packageTimes(exec *testjson.Execution, fn func([]time.Duration) time.Duration) []packageTime {
+	var result []packageTime
+	for _, name := range exec.Packages() {
+		pt := packageTime{Package: name}
+		for _, tc := range aggregate.ByElapsed(exec.Package(name).TestCases(), fn) {
+			if tc.Test == "" || tc.Test.IsSubTest() {
+				continue
+			}
+			pt.Tests++
+			pt.Total += tc.Elapsed
+		}
+		if pt.Tests > 0 {
+			result = append(result, pt)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Total > result[j].Total
+	})
+	return result
+}
+
+// slowestPackages returns the packages with a total time greater than
+// threshold, or the num slowest packages when num is greater than 0.
+func slowestPackages(pkgs []packageTime, threshold time.Duration, num int) []packageTime {
+	if num > 0 {
+		if num < len(pkgs) {
+			return pkgs[:num]
+		}
+		return pkgs
+	}
+	end := sort.Search(len(pkgs), func(i int) bool {
+		return pkgs[i].Total < threshold
+	})
+	return pkgs[:end]
+}
+
+// percentileOfPackages returns the p-th percentile of the total time of pkgs.
+func percentileOfPackages(pkgs []packageTime, p int) time.Duration {
+	times := make([]time.Duration, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		times = append(times, pkg.Total)
+	}
+	return aggregate.Percentile(times, p)
+}
+
+type slowPackage struct {
+	Package        string  `json:"package"`
+	Tests          int     `json:"tests"`
+	TotalSeconds   float64 `json:"total_seconds"`
+	AverageSeconds float64 `json:"average_seconds"`
+}
+
+// writeSlowestPackages writes the list of slow packages to out in the format
+// of the --output flag.
+func writeSlowestPackages(out io.Writer, format string, pkgs []packageTime) error {
+	switch format {
+	case "text":
+		for _, p := range pkgs {
+			if _, err := fmt.Fprintf(out, "%s %v (%d tests, %v average)\n",
+				p.Package, p.Total, p.Tests, p.Average()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		result := make([]slowPackage, 0, len(pkgs))
+		for _, p := range pkgs {
+			result = append(result, slowPackage{
+				Package:        p.Package,
+				Tests:          p.Tests,
+				TotalSeconds:   p.Total.Seconds(),
+				AverageSeconds: p.Average().Seconds(),
+			})
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	case "csv":
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"package", "tests", "total_seconds", "average_seconds"})
+		for _, p := range pkgs {
+			_ = w.Write([]string{
+				p.Package,
+				strconv.Itoa(p.Tests),
+				strconv.FormatFloat(p.Total.Seconds(), 'f', -1, 64),
+				strconv.FormatFloat(p.Average().Seconds(), 'f', -1, 64),
+			})
+		}
+		w.Flush()
+		return w.Error()
+	case "markdown":
+		var b strings.Builder
+		b.WriteString("| Package | Tests | Total | Average |\n")
+		b.WriteString("| --- | ---: | ---: | ---: |\n")
+		for _, p := range pkgs {
+			fmt.Fprintf(&b, "| `%s` | %d | %v | %v |\n",
+				markdownEscape(p.Package), p.Tests, p.Total, p.Average())
+		}
+		_, err := io.WriteString(out, b.String())
+		return err
+	default:
+		return validateOutput(format)
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/dnephin/pflag"
 	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/internal/jsonfile"
 	"gotest.tools/gotestsum/internal/log"
 	"gotest.tools/gotestsum/testjson"
 )
@@ -26,18 +28,26 @@ func Run(name string, args []string) error {
 }
 
 func setupFlags(name string) (*pflag.FlagSet, *options) {
-	opts := &options{}
+	opts := &options{threshold: thresholdValue{duration: 100 * time.Millisecond}}
 	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
 	flags.SetInterspersed(false)
 	flags.Usage = func() {
 		usage(os.Stdout, name, flags)
 	}
-	flags.StringVar(&opts.jsonfile, "jsonfile", os.Getenv("GOTESTSUM_JSONFILE"),
-		"path to test2json output, defaults to stdin")
-	flags.DurationVar(&opts.threshold, "threshold", 100*time.Millisecond,
-		"test cases with elapsed time greater than threshold are slow tests")
+	flags.StringArrayVar(&opts.jsonfiles, "jsonfile", defaultJSONFiles(),
+		"path or glob pattern of test2json output, may be repeated, defaults to stdin")
+	flags.Var(&opts.threshold, "threshold",
+		"test cases with elapsed time greater than threshold are slow tests, "+
+			"a duration, or a percentile of all the tests (ex: p95)")
+	flags.StringVar(&opts.aggregate, "aggregate", "median",
+		"elapsed time to use for tests that ran more than once, one of: median, p95")
 	flags.IntVar(&opts.topN, "num", 0,
 		"print at most num slowest tests, instead of all tests above the threshold")
+	flags.StringVar(&opts.groupBy, "group-by", "test",
+		"print the slowest tests, or the total test time of each package, one of: "+
+			strings.Join(groupByValues, ", "))
+	flags.StringVar(&opts.output, "output", "text",
+		"format of the list of slow tests, one of: "+strings.Join(outputFormats, ", "))
 	flags.StringVar(&opts.skipStatement, "skip-stmt", "",
 		"add this go statement to slow tests, instead of printing the list of slow tests")
 	flags.BoolVar(&opts.debug, "debug", false,
@@ -45,6 +55,13 @@ func setupFlags(name string) (*pflag.FlagSet, *options) {
 	return flags, opts
 }
 
+func defaultJSONFiles() []string {
+	if v := os.Getenv("GOTESTSUM_JSONFILE"); v != "" {
+		return []string{v}
+	}
+	return nil
+}
+
 func usage(out io.Writer, name string, flags *pflag.FlagSet) {
 	fmt.Fprintf(out, `Usage:
     %[1]s [flags]
@@ -52,16 +69,37 @@ func usage(out io.Writer, name string, flags *pflag.FlagSet) {
 Read a json file and print or update tests which are slower than threshold.
 The json file may be created with 'gotestsum --jsonfile' or 'go test -json'.
 If a TestCase appears more than once in the json file, it will only appear once
-in the output, and the median value of all the elapsed times will be used.
+in the output, and the median value of all the elapsed times will be used. Use
+--aggregate=p95 to use the 95th percentile instead of the median.
+
+The --jsonfile flag may be repeated, or set to a glob pattern, to read the output
+of many runs. The elapsed times of a TestCase are aggregated across all the files,
+so that one unusually slow run does not make a test appear slow.
+
+The --threshold flag may be a duration, or a percentile of the elapsed time of all
+the tests in the run. For example --threshold=p95 prints the slowest 5%% of tests,
+without having to choose a duration that suits the size of the test suite.
 
 By default this command will print the list of tests slower than threshold to stdout.
-The list will be sorted from slowest to fastest.
+The list will be sorted from slowest to fastest. Use --output to print the list
+as json, csv, or a markdown table, instead of lines of text.
+
+With --group-by=package the total and average test time of each package is
+printed instead of the time of each test, to find the packages which use the
+most time. The total is the sum of the time of the top level tests in the
+package, and --threshold and --num apply to the total time of the package.
 
 If --skip-stmt is set, instead of printing the list to stdout, the AST for the
 Go source code in the working directory tree will be modified. The value of
 --skip-stmt will be added to Go test files as the first statement in all the test
 functions which are slower than threshold.
 
+When a slow test is a subtest, the statement is added to the function literal
+passed to the t.Run call of the subtest, instead of to the test function. When
+the name of the subtest is not a string literal, for example tc.name in a table
+test, the statement is guarded by a comparison of the name to the matching string
+from the table. If the subtest can not be found the whole test function is skipped.
+
 The --skip-stmt flag may be set to the name of a predefined statement, or to
 Go source code which will be parsed as a go/ast.Stmt. Currently there is only one
 predefined statement, --skip-stmt=testing.Short, which uses this Go statement:
@@ -94,10 +132,13 @@ Flags:
 }
 
 type options struct {
-	threshold     time.Duration
+	threshold     thresholdValue
 	topN          int
-	jsonfile      string
+	jsonfiles     []string
+	aggregate     string
 	skipStatement string
+	output        string
+	groupBy       string
 	debug         bool
 }
 
@@ -105,22 +146,34 @@ func run(opts *options) error {
 	if opts.debug {
 		log.SetLevel(log.DebugLevel)
 	}
-	in, err := jsonfileReader(opts.jsonfile)
+	if err := validateOutput(opts.output); err != nil {
+		return err
+	}
+	if err := validateGroupBy(opts.groupBy); err != nil {
+		return err
+	}
+	if opts.groupBy == "package" && opts.skipStatement != "" {
+		return fmt.Errorf("--skip-stmt can not be used with --group-by=package")
+	}
+	aggregateFn, err := aggregateFunc(opts.aggregate)
 	if err != nil {
-		return fmt.Errorf("failed to read jsonfile: %v", err)
+		return err
 	}
-	defer func() {
-		if err := in.Close(); err != nil {
-			log.Errorf("Failed to close file %v: %v", opts.jsonfile, err)
-		}
-	}()
-
-	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{Stdout: in})
+	exec, err := scanJSONFiles(opts.jsonfiles)
 	if err != nil {
-		return fmt.Errorf("failed to scan testjson: %v", err)
+		return err
+	}
+
+	if opts.groupBy == "package" {
+		return runGroupByPackage(opts, exec, aggregateFn)
 	}
 
-	tcs := aggregate.Slowest(exec, opts.threshold, opts.topN)
+	threshold := opts.threshold.duration
+	if opts.threshold.percentile > 0 {
+		threshold = aggregate.PercentileOfRun(exec, opts.threshold.percentile, aggregateFn)
+		log.Debugf("threshold %v is %v", opts.threshold.String(), threshold)
+	}
+	tcs := aggregate.SlowestBy(exec, threshold, opts.topN, aggregateFn)
 	if opts.skipStatement != "" {
 		skipStmt, err := parseSkipStatement(opts.skipStatement)
 		if err != nil {
@@ -128,18 +181,80 @@ func run(opts *options) error {
 		}
 		return writeTestSkip(tcs, skipStmt)
 	}
-	for _, tc := range tcs {
-		fmt.Printf("%s %s %v\n", tc.Package, tc.Test, tc.Elapsed)
-	}
+	return writeSlowest(os.Stdout, opts.output, tcs)
+}
 
-	return nil
+func runGroupByPackage(
+	opts *options,
+	exec *testjson.Execution,
+	aggregateFn func([]time.Duration) time.Duration,
+) error {
+	pkgs := packageTimes(exec, aggregateFn)
+	threshold := opts.threshold.duration
+	if opts.threshold.percentile > 0 {
+		threshold = percentileOfPackages(pkgs, opts.threshold.percentile)
+		log.Debugf("threshold %v is %v", opts.threshold.String(), threshold)
+	}
+	return writeSlowestPackages(os.Stdout, opts.output, slowestPackages(pkgs, threshold, opts.topN))
 }
 
-func jsonfileReader(v string) (io.ReadCloser, error) {
-	switch v {
-	case "", "-":
-		return io.NopCloser(os.Stdin), nil
+func aggregateFunc(name string) (func([]time.Duration) time.Duration, error) {
+	switch name {
+	case "median":
+		return aggregate.Median, nil
+	case "p95":
+		return aggregate.P95, nil
 	default:
-		return os.Open(v)
+		return nil, fmt.Errorf("--aggregate must be one of: median, p95")
+	}
+}
+
+// scanJSONFiles reads the test2json output from every file into a single
+// Execution. Each file is scanned with a different RunID, so that a test from
+// every file is a separate TestCase. When there are no files the output is read
+// from stdin.
+func scanJSONFiles(patterns []string) (*testjson.Execution, error) {
+	var fileNames []string
+	for _, pattern := range patterns {
+		if pattern == "-" {
+			fileNames = append(fileNames, pattern)
+			continue
+		}
+		matches, err := jsonfile.Expand([]string{pattern})
+		if err != nil {
+			return nil, err
+		}
+		fileNames = append(fileNames, matches...)
+	}
+	if len(fileNames) == 0 {
+		fileNames = []string{"-"}
+	}
+
+	var exec *testjson.Execution
+	for i, fileName := range fileNames {
+		var err error
+		cfg := testjson.ScanConfig{Execution: exec, RunID: i}
+		if fileName == "-" {
+			exec, err = scanStdin(cfg)
+		} else {
+			exec, err = jsonfile.Scan(fileName, cfg)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return exec, nil
+}
+
+func scanStdin(cfg testjson.ScanConfig) (*testjson.Execution, error) {
+	in, err := jsonfile.NewReader(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jsonfile: %v", err)
+	}
+	cfg.Stdout = in
+	exec, err := testjson.ScanTestOutput(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan testjson: %v", err)
 	}
+	return exec, nil
 }
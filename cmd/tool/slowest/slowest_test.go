@@ -3,8 +3,13 @@ package slowest
 import (
 	"bytes"
 	"testing"
+	"time"
 
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
 	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
 	"gotest.tools/v3/golden"
 )
 
@@ -18,3 +23,88 @@ func TestUsage_WithFlagsFromSetupFlags(t *testing.T) {
 
 	golden.Assert(t, buf.String(), "cmd-flags-help-text")
 }
+
+func TestWriteSlowest(t *testing.T) {
+	tcs := []testjson.TestCase{
+		{Package: "example.com/app/api", Test: "TestHandler/a|b", Elapsed: 2500 * time.Millisecond},
+		{Package: "example.com/app/db", Test: "TestMigrate", Elapsed: 300 * time.Millisecond},
+	}
+	for _, format := range outputFormats {
+		t.Run(format, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			assert.NilError(t, writeSlowest(buf, format, tcs))
+			golden.Assert(t, buf.String(), "expected-slowest."+format)
+		})
+	}
+
+	err := writeSlowest(new(bytes.Buffer), "yaml", tcs)
+	assert.Error(t, err, "--output must be one of: text, json, csv, markdown")
+}
+
+func TestScanJSONFiles(t *testing.T) {
+	event := func(elapsed string) string {
+		return `{"Action":"pass","Package":"pkg","Test":"TestSlow","Elapsed":` + elapsed + "}\n"
+	}
+	dir := fs.NewDir(t, "slowest",
+		fs.WithFile("run-1.json", event("0.1")),
+		fs.WithFile("run-2.json", event("0.2")),
+		fs.WithFile("run-3.json", event("9")),
+		fs.WithFile("other.json", event("0.3")))
+
+	exec, err := scanJSONFiles([]string{dir.Join("run-*.json"), dir.Join("other.json")})
+	assert.NilError(t, err)
+
+	tcs := aggregate.SlowestBy(exec, time.Millisecond, 0, aggregate.Median)
+	assert.Equal(t, len(tcs), 1)
+	assert.Equal(t, tcs[0].Elapsed, 300*time.Millisecond)
+
+	_, err = scanJSONFiles([]string{dir.Join("missing-*.json")})
+	assert.ErrorContains(t, err, "no files match")
+}
+
+func TestThresholdValue(t *testing.T) {
+	var v thresholdValue
+	assert.NilError(t, v.Set("p95"))
+	assert.Equal(t, v, thresholdValue{percentile: 95})
+	assert.Equal(t, v.String(), "p95")
+
+	assert.NilError(t, v.Set("250ms"))
+	assert.Equal(t, v, thresholdValue{duration: 250 * time.Millisecond})
+	assert.Equal(t, v.String(), "250ms")
+
+	assert.Error(t, v.Set("p0"), "percentile must be between p1 and p100")
+	assert.Error(t, v.Set("pfast"), "percentile must be between p1 and p100")
+	assert.ErrorContains(t, v.Set("fast"), "invalid duration")
+}
+
+func TestPackageTimes(t *testing.T) {
+	event := func(pkg, test, elapsed string) string {
+		return `{"Action":"pass","Package":"` + pkg + `","Test":"` + test + `","Elapsed":` + elapsed + "}\n"
+	}
+	dir := fs.NewDir(t, "slowest",
+		fs.WithFile("run-1.json",
+			event("example.com/app/api", "TestHandler/a", "1.5")+
+				event("example.com/app/api", "TestHandler", "2")+
+				event("example.com/app/api", "TestRoutes", "1")+
+				event("example.com/app/db", "TestMigrate", "0.3")+
+				event("example.com/app/util", "TestTrim", "0.01")),
+		fs.WithFile("run-2.json",
+			event("example.com/app/api", "TestHandler", "4")+
+				event("example.com/app/api", "TestRoutes", "3")+
+				event("example.com/app/db", "TestMigrate", "0.2")))
+
+	exec, err := scanJSONFiles([]string{dir.Join("run-*.json")})
+	assert.NilError(t, err)
+	pkgs := packageTimes(exec, aggregate.Median)
+
+	for _, format := range outputFormats {
+		t.Run(format, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			assert.NilError(t, writeSlowestPackages(buf, format, slowestPackages(pkgs, 100*time.Millisecond, 0)))
+			golden.Assert(t, buf.String(), "expected-slowest-packages."+format)
+		})
+	}
+
+	assert.Equal(t, len(slowestPackages(pkgs, 0, 1)), 1)
+	assert.Equal(t, percentileOfPackages(pkgs, 50), 300*time.Millisecond)
+}
@@ -0,0 +1,44 @@
+package slowest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// thresholdValue is the value of the --threshold flag. The value is either a
+// duration, or a percentile of the elapsed time of all the tests in the run,
+// for example p95.
+type thresholdValue struct {
+	duration   time.Duration
+	percentile int
+}
+
+func (t *thresholdValue) String() string {
+	if t.percentile > 0 {
+		return "p" + strconv.Itoa(t.percentile)
+	}
+	return t.duration.String()
+}
+
+func (t *thresholdValue) Set(raw string) error {
+	if v, ok := strings.CutPrefix(raw, "p"); ok {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 1 || p > 100 {
+			return fmt.Errorf("percentile must be between p1 and p100")
+		}
+		*t = thresholdValue{percentile: p}
+		return nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+	*t = thresholdValue{duration: d}
+	return nil
+}
+
+func (t *thresholdValue) Type() string {
+	return "threshold"
+}
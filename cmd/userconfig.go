@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dnephin/pflag"
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// userConfigFlags are the flags which may be set from the user config file,
+// and the environment variable which takes precedence over the config file for
+// each flag. Only flags which change how the output is displayed, or how the
+// user is notified, are allowed, so that the personal preferences of one user
+// can not change the result of a test run, or the files written by a test run.
+var userConfigFlags = map[string]string{
+	"accessible":            "GOTESTSUM_ACCESSIBLE",
+	"format":                "GOTESTSUM_FORMAT",
+	"format-hide-empty-pkg": "",
+	"format-icons":          "GOTESTSUM_FORMAT_ICONS",
+	"hide-summary":          "",
+	"max-line-length":       "",
+	"no-color":              "NO_COLOR",
+	"notify":                "",
+}
+
+// userConfigPath returns the path to the user config file. XDG_CONFIG_HOME is
+// used on all platforms when it is set.
+func userConfigPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		var err error
+		if dir, err = os.UserConfigDir(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, "gotestsum", "config.yaml"), nil
+}
+
+// applyUserConfig sets the value of flags from the user config file. Flags set
+// on the command line, or by an environment variable, are not changed. Errors
+// in the config file are logged as a warning, so that a broken config file does
+// not prevent tests from running.
+func applyUserConfig(flags *pflag.FlagSet) {
+	path, err := userConfigPath()
+	if err != nil {
+		log.Debugf("failed to find user config directory: %v", err)
+		return
+	}
+	fh, err := os.Open(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return
+	case err != nil:
+		log.Warnf("failed to read user config: %v", err)
+		return
+	}
+	defer fh.Close() //nolint:errcheck
+
+	values, err := parseUserConfig(fh)
+	if err != nil {
+		log.Warnf("failed to read user config %v: %v", path, err)
+		return
+	}
+	log.Debugf("using user config %v", path)
+	for _, err := range setFlagsFromUserConfig(flags, values) {
+		log.Warnf("user config %v: %v", path, err)
+	}
+}
+
+func setFlagsFromUserConfig(flags *pflag.FlagSet, values map[string]string) []error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		envVar, ok := userConfigFlags[key]
+		flag := flags.Lookup(key)
+		if !ok || flag == nil {
+			errs = append(errs, fmt.Errorf("%v can not be set from the user config", key))
+			continue
+		}
+		if flag.Changed {
+			continue
+		}
+		if _, isSet := os.LookupEnv(envVar); envVar != "" && isSet {
+			continue
+		}
+		if err := flags.Set(key, values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for %v: %w", key, err))
+		}
+	}
+	return errs
+}
+
+// parseUserConfig reads the user config file. The file uses a subset of YAML:
+// a mapping of flag names to scalar values, with comments and blank lines.
+func parseUserConfig(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" || line == "---" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected 'name: value'", lineNum)
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil, fmt.Errorf("line %d: %v must have a value", lineNum, key)
+		}
+		values[key] = unquote(value)
+	}
+	return values, scanner.Err()
+}
+
+// stripComment removes a comment from the end of line. A # only starts a
+// comment at the start of the line, or after a space.
+func stripComment(line string) string {
+	for i, r := range line {
+		if r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquote(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	switch first, last := value[0], value[len(value)-1]; {
+	case first == '"' && last == '"', first == '\'' && last == '\'':
+		return value[1 : len(value)-1]
+	}
+	return value
+}
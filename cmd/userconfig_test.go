@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func TestParseUserConfig(t *testing.T) {
+	source := `---
+# personal defaults
+format: testname
+format-icons: "hivis"   # quoted value
+hide-summary: 'skipped'
+
+no-color: true
+`
+	values, err := parseUserConfig(strings.NewReader(source))
+	assert.NilError(t, err)
+	expected := map[string]string{
+		"format":       "testname",
+		"format-icons": "hivis",
+		"hide-summary": "skipped",
+		"no-color":     "true",
+	}
+	assert.DeepEqual(t, values, expected)
+}
+
+func TestParseUserConfig_Invalid(t *testing.T) {
+	_, err := parseUserConfig(strings.NewReader("format: dots\nformat-icons\n"))
+	assert.Error(t, err, "line 2: expected 'name: value'")
+
+	_, err = parseUserConfig(strings.NewReader("format:\n  value: nested\n"))
+	assert.Error(t, err, "line 1: format must have a value")
+}
+
+func TestApplyUserConfig(t *testing.T) {
+	env.PatchAll(t, nil)
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithDir("gotestsum",
+			fs.WithFile("config.yaml", `
+format: testname
+format-icons: hivis
+max-line-length: 80
+notify: true
+junitfile: report.xml
+`)))
+	env.Patch(t, "XDG_CONFIG_HOME", dir.Path())
+
+	t.Run("values from config", func(t *testing.T) {
+		flags, opts := setupFlags("gotestsum")
+		assert.NilError(t, flags.Parse(nil))
+		applyUserConfig(flags)
+
+		assert.Equal(t, opts.format, "testname")
+		assert.Equal(t, opts.formatOptions.Icons, "hivis")
+		assert.Equal(t, opts.formatOptions.MaxLineLength, 80)
+		assert.Assert(t, opts.notify)
+		assert.Equal(t, opts.junitFile, "", "junitfile is not allowed in the user config")
+	})
+
+	t.Run("flags take precedence", func(t *testing.T) {
+		flags, opts := setupFlags("gotestsum")
+		assert.NilError(t, flags.Parse([]string{"--format=dots", "--max-line-length=0"}))
+		applyUserConfig(flags)
+
+		assert.Equal(t, opts.format, "dots")
+		assert.Equal(t, opts.formatOptions.MaxLineLength, 0)
+		assert.Equal(t, opts.formatOptions.Icons, "hivis")
+	})
+
+	t.Run("environment takes precedence", func(t *testing.T) {
+		env.Patch(t, "GOTESTSUM_FORMAT", "pkgname-and-test-fails")
+		flags, opts := setupFlags("gotestsum")
+		assert.NilError(t, flags.Parse(nil))
+		applyUserConfig(flags)
+
+		assert.Equal(t, opts.format, "pkgname-and-test-fails")
+		assert.Equal(t, opts.formatOptions.Icons, "hivis")
+	})
+}
+
+func TestApplyUserConfig_NoFile(t *testing.T) {
+	env.PatchAll(t, nil)
+	dir := fs.NewDir(t, t.Name())
+	env.Patch(t, "XDG_CONFIG_HOME", dir.Path())
+
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse(nil))
+	applyUserConfig(flags)
+	assert.Equal(t, opts.format, "pkgname")
+}
@@ -4,10 +4,20 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/fatih/color"
+	fswatch "gotest.tools/gotestsum/filewatcher"
 	"gotest.tools/gotestsum/internal/filewatcher"
+	"gotest.tools/gotestsum/internal/log"
 	"gotest.tools/gotestsum/testjson"
 )
 
@@ -16,7 +26,96 @@ func runWatcher(opts *options) error {
 	defer cancel()
 
 	w := &watchRuns{opts: *opts}
-	return filewatcher.Watch(ctx, opts.packages, opts.watchClear, w.run)
+	cfg := watchConfig(opts)
+	if len(cfg.Dirs) == 0 {
+		dirs, err := workspaceWatchDirs()
+		if err != nil {
+			log.Warnf("failed to read the modules in the go.work workspace: %v", err)
+		}
+		cfg.Dirs = dirs
+	}
+	watchOpts := filewatcher.Options{ClearScreen: opts.watchClear}
+	if opts.watchStatusLine {
+		watchOpts.Status = w.status
+	}
+	return filewatcher.Watch(ctx, cfg, watchOpts, w.run)
+}
+
+// watchConfig returns the config of the file watcher from the --watch flags.
+// When --watch-include is used testdata directories are watched, because they
+// commonly contain the included files.
+func watchConfig(opts *options) fswatch.Config {
+	cfg := fswatch.Config{
+		Dirs:         opts.packages,
+		BatchWindow:  opts.watchDebounce,
+		PollInterval: opts.watchPoll,
+	}
+	cfg.Ignore = func(path string) bool {
+		switch {
+		case matchGlobs(opts.watchIgnore, path):
+			return true
+		case len(opts.watchInclude) > 0 && filepath.Base(path) == "testdata":
+			return false
+		}
+		return fswatch.DefaultIgnore(path)
+	}
+	if len(opts.watchInclude) > 0 {
+		cfg.Include = func(path string) bool {
+			return matchGlobs(opts.watchInclude, path)
+		}
+	}
+	return cfg
+}
+
+// matchGlobs returns true if any of the patterns matches the base name of
+// path, or path relative to the working directory.
+func matchGlobs(patterns []string, path string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	if filepath.IsAbs(path) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, path); err == nil {
+				path = rel
+			}
+		}
+	}
+	path = filepath.Clean(path)
+	for _, pattern := range patterns {
+		pattern = filepath.Clean(pattern)
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func validateGlobs(flag string, patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%v %q is not a valid glob pattern: %w", flag, pattern, err)
+		}
+	}
+	return nil
+}
+
+// watchPreRunHook runs the --watch-pre-run-command, with the package that
+// triggered the run in the environment of the command.
+func watchPreRunHook(opts *options, pkgPath string) error {
+	command := opts.watchPreRunCmd.Value()
+	if len(command) == 0 {
+		return nil
+	}
+	log.Debugf("exec: %s", command)
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = opts.stdout
+	cmd.Stderr = opts.stderr
+	cmd.Env = append(os.Environ(), "GOTESTSUM_WATCH_PACKAGE="+pkgPath)
+	return cmd.Run()
 }
 
 type watchRuns struct {
@@ -24,7 +123,32 @@ type watchRuns struct {
 	prevExec *testjson.Execution
 }
 
+// status returns the result of the previous run, for the status line.
+func (w *watchRuns) status() string {
+	if w.prevExec == nil {
+		return "waiting for a file to change"
+	}
+	exec := w.prevExec
+	result := "PASS"
+	if len(exec.Failed()) > 0 || len(exec.Errors()) > 0 {
+		result = "FAIL"
+	}
+	text := fmt.Sprintf("%v %d tests", result, exec.Total())
+	if failed := len(exec.Failed()); failed > 0 {
+		text += fmt.Sprintf(", %d failed", failed)
+	}
+	if errs := len(exec.Errors()); errs > 0 {
+		text += fmt.Sprintf(", %d errors", errs)
+	}
+	return text + fmt.Sprintf(" in %v", exec.Elapsed().Round(time.Millisecond))
+}
+
 func (w *watchRuns) run(event filewatcher.Event) error {
+	if err := watchPreRunHook(&w.opts, event.PkgPath); err != nil {
+		log.Errorf("Not running tests because the pre-run command failed: %v", err)
+		return nil
+	}
+
 	if event.Debug {
 		path, cleanup, err := delveInitFile(w.prevExec)
 		if err != nil {
@@ -32,37 +156,190 @@ func (w *watchRuns) run(event filewatcher.Event) error {
 		}
 		defer cleanup()
 		o := delveOpts{
+			command:      w.opts.watchDebugCmd.Value(),
 			pkgPath:      event.PkgPath,
 			args:         w.opts.args,
 			initFilePath: path,
 		}
+		if w.prevExec != nil {
+			if failed := testjson.FilterFailedUnique(w.prevExec.Failed()); len(failed) > 0 {
+				tests := make([]testjson.TestName, 0, len(failed))
+				for _, tc := range failed {
+					tests = append(tests, tc.Test)
+				}
+				o.runFlag = goTestRunFlagForTestNames(tests)
+			}
+		}
 		if err := runDelve(o); !IsExitCoder(err) {
 			return fmt.Errorf("delve failed: %w", err)
 		}
 		return nil
 	}
 
+	if event.RerunFailed {
+		return w.rerunFailed()
+	}
+
 	var dir string
 	if w.opts.watchChdir {
 		dir, event.PkgPath = event.PkgPath, "./"
 	}
 
+	pkgs := []string{event.PkgPath}
+	if w.opts.watchAffected && event.PkgPath != "" {
+		affected, err := affectedPackages(event.PkgPath)
+		if err != nil {
+			log.Warnf("failed to find the packages affected by the change: %v", err)
+		} else {
+			pkgs = affected
+			if len(affected) > 1 {
+				fmt.Fprintf(w.opts.stdout, "Also running tests in %d packages which import %v\n",
+					len(affected)-1, event.PkgPath)
+			}
+		}
+	}
+
 	opts := w.opts // shallow copy opts
 	opts.packages = append([]string{}, opts.packages...)
-	opts.packages = append(opts.packages, event.PkgPath)
+	opts.packages = append(opts.packages, pkgs...)
 	opts.packages = append(opts.packages, event.Args...)
 
-	var err error
-	if w.prevExec, err = runSingle(&opts, dir); !IsExitCoder(err) {
+	return w.runSingle(&opts, dir, rerunOpts{})
+}
+
+// runSingle runs the tests, and prints the difference in failures from the
+// previous run when --watch-failure-diff is set.
+func (w *watchRuns) runSingle(opts *options, dir string, rerunTC rerunOpts) error {
+	prev := w.prevExec
+	exec, err := runSingle(opts, dir, rerunTC)
+	if !IsExitCoder(err) {
 		return err
 	}
+	if exec != nil {
+		w.prevExec = exec
+	}
+	if opts.watchFailureDiff && prev != nil && exec != nil {
+		printFailureDiff(opts.stdout, prev, exec)
+	}
 	return nil
 }
 
+// rerunFailed runs only the tests which failed in the previous run. The tests
+// are run by a single go test command, so a test in one package may also match
+// a test with the same name in another one of the packages.
+func (w *watchRuns) rerunFailed() error {
+	if w.prevExec == nil {
+		fmt.Fprintln(w.opts.stdout, "No previous run to rerun")
+		return nil
+	}
+	failed := testjson.FilterFailedUnique(w.prevExec.Failed())
+	if len(failed) == 0 {
+		fmt.Fprintln(w.opts.stdout, "No tests failed in the previous run")
+		return nil
+	}
+
+	var pkgs []string
+	tests := make([]testjson.TestName, 0, len(failed))
+	for _, tc := range failed {
+		if !slices.Contains(pkgs, tc.Package) {
+			pkgs = append(pkgs, tc.Package)
+		}
+		tests = append(tests, tc.Test)
+	}
+	fmt.Fprintf(w.opts.stdout, "Running %d tests which failed in the previous run\n", len(tests))
+
+	opts := w.opts // shallow copy opts
+	opts.packages = append([]string{}, opts.packages...)
+	opts.packages = append(opts.packages, pkgs...)
+
+	rerunTC := rerunOpts{runFlag: goTestRunFlagForTestNames(tests)}
+	return w.runSingle(&opts, "", rerunTC)
+}
+
+// printFailureDiff prints the tests which failed in exec but not in prev, the
+// tests which failed in both, and the tests which failed in prev but passed
+// in exec. Only the packages which were run by exec are compared, so that
+// the failures of a package which was not run are not reported as fixed.
+func printFailureDiff(out io.Writer, prev, exec *testjson.Execution) {
+	ran := make(map[string]bool)
+	for _, name := range exec.Packages() {
+		ran[name] = true
+	}
+	prevFailed := make(map[string]bool)
+	for _, tc := range testjson.FilterFailedUnique(prev.Failed()) {
+		if ran[tc.Package] {
+			prevFailed[tc.Package+"."+tc.Test.Name()] = true
+		}
+	}
+
+	var newFailures, persisting []string
+	for _, tc := range testjson.FilterFailedUnique(exec.Failed()) {
+		name := tc.Package + "." + tc.Test.Name()
+		if prevFailed[name] {
+			persisting = append(persisting, name)
+			delete(prevFailed, name)
+			continue
+		}
+		newFailures = append(newFailures, name)
+	}
+	passed := make(map[string]bool)
+	for _, name := range exec.Packages() {
+		for _, tc := range exec.Package(name).Passed {
+			passed[name+"."+tc.Test.Name()] = true
+		}
+	}
+	fixed := make([]string, 0, len(prevFailed))
+	for name := range prevFailed {
+		// a test which did not run, because of a -run flag or a build
+		// failure, is not fixed
+		if passed[name] {
+			fixed = append(fixed, name)
+		}
+	}
+	sort.Strings(fixed)
+
+	if len(newFailures)+len(persisting)+len(fixed) == 0 {
+		return
+	}
+	fmt.Fprintln(out)
+	printFailureDiffSection(out, "New failures", newFailures, color.RedString)
+	printFailureDiffSection(out, "Still failing", persisting, color.YellowString)
+	printFailureDiffSection(out, "Fixed", fixed, color.GreenString)
+}
+
+func printFailureDiffSection(out io.Writer, title string, names []string, fmtFn func(string, ...interface{}) string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Fprintln(out, fmtFn("%v (%d):", title, len(names)))
+	for _, name := range names {
+		fmt.Fprintln(out, fmtFn("  %v", name))
+	}
+}
+
+// goTestRunFlagForTestNames returns a -test.run flag which matches all of the
+// tests. When there is more than one test the flag matches the root test of
+// each, because go test can not match a different subtest for each root test.
+func goTestRunFlagForTestNames(tests []testjson.TestName) string {
+	if len(tests) == 1 {
+		return goTestRunFlagForTestCase(tests[0])
+	}
+	var roots []string
+	seen := make(map[string]bool)
+	for _, test := range tests {
+		root, _ := test.Split()
+		if !seen[root] {
+			seen[root] = true
+			roots = append(roots, regexp.QuoteMeta(root))
+		}
+	}
+	return "-test.run=^(" + strings.Join(roots, "|") + ")$"
+}
+
 // runSingle is similar to run. It doesn't support rerun-fails. It may be
 // possible to share runSingle with run, but the defer close on the handler
 // would require at least 3 return values, so for now it is a copy.
-func runSingle(opts *options, dir string) (*testjson.Execution, error) {
+func runSingle(opts *options, dir string, rerunTC rerunOpts) (*testjson.Execution, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -70,7 +347,13 @@ func runSingle(opts *options, dir string) (*testjson.Execution, error) {
 		return nil, err
 	}
 
-	goTestProc, err := startGoTestFn(ctx, dir, goTestCmdArgs(opts, rerunOpts{}))
+	sandbox, err := newTmpDirSandbox(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer sandbox.Close()
+
+	goTestProc, err := startGoTestFn(ctx, dir, goTestCmdArgs(opts, rerunTC), sandbox.Env()...)
 	if err != nil {
 		return nil, err
 	}
@@ -81,13 +364,16 @@ func runSingle(opts *options, dir string) (*testjson.Execution, error) {
 	}
 	defer handler.Close() //nolint:errcheck
 	cfg := testjson.ScanConfig{
-		Stdout:  goTestProc.stdout,
-		Stderr:  goTestProc.stderr,
-		Handler: handler,
-		Stop:    cancel,
+		Stdout:                 goTestProc.stdout,
+		Stderr:                 goTestProc.stderr,
+		Handler:                handler,
+		Stop:                   cancel,
+		MaxTestOutputBytes:     opts.maxTestOutputBytes,
+		CollapseRepeatedOutput: opts.formatOptions.CollapseRepeatedOutput,
 	}
 	exec, err := testjson.ScanTestOutput(cfg)
 	handler.Flush()
+	handler.closeFormatter()
 	if err != nil {
 		return exec, finishRun(opts, exec, err)
 	}
@@ -105,7 +391,11 @@ func delveInitFile(exec *testjson.Execution) (string, func(), error) {
 	}
 
 	buf := bufio.NewWriter(fh)
-	for _, tc := range exec.Failed() {
+	var failed []testjson.TestCase
+	if exec != nil {
+		failed = exec.Failed()
+	}
+	for _, tc := range failed {
 		fmt.Fprintf(buf, "break %s\n", tc.Test.Name())
 	}
 	buf.WriteString("continue\n")
@@ -117,19 +407,17 @@ func delveInitFile(exec *testjson.Execution) (string, func(), error) {
 }
 
 type delveOpts struct {
+	// command used to run the debugger, defaults to 'dlv test'.
+	command      []string
 	pkgPath      string
 	args         []string
 	initFilePath string
+	// runFlag selects the tests which failed in the previous run.
+	runFlag string
 }
 
 func runDelve(opts delveOpts) error {
-	pkg := opts.pkgPath
-	args := []string{"dlv", "test", "--wd", pkg}
-	args = append(args, "--output", "gotestsum-watch-debug.test")
-	args = append(args, "--init", opts.initFilePath)
-	args = append(args, pkg, "--")
-	args = append(args, opts.args...)
-
+	args := delveCmdArgs(opts)
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
@@ -137,3 +425,20 @@ func runDelve(opts delveOpts) error {
 
 	return cmd.Run()
 }
+
+func delveCmdArgs(opts delveOpts) []string {
+	pkg := opts.pkgPath
+	args := []string{"dlv", "test"}
+	if len(opts.command) > 0 {
+		args = append([]string{}, opts.command...)
+	}
+	args = append(args, "--wd", pkg)
+	args = append(args, "--output", "gotestsum-watch-debug.test")
+	args = append(args, "--init", opts.initFilePath)
+	args = append(args, pkg, "--")
+	args = append(args, opts.args...)
+	if opts.runFlag != "" {
+		args = append(args, opts.runFlag)
+	}
+	return args
+}
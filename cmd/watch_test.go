@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/internal/filewatcher"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestWatchRuns_RerunFailed(t *testing.T) {
+	var args [][]string
+	fn := func(a []string) *proc {
+		args = append(args, a)
+		return &proc{
+			cmd: fakeWaiter{result: newExitCode("test-failed", 1)},
+			stdout: strings.NewReader(dedentOutput(`
+				{"Package": "pkg", "Action": "run"}
+				{"Package": "pkg", "Test": "TestTwo", "Action": "run"}
+				{"Package": "pkg", "Test": "TestTwo", "Action": "fail"}
+				{"Package": "pkg", "Action": "fail"}
+			`)),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	stdout := new(bytes.Buffer)
+	w := &watchRuns{
+		opts: options{
+			format:      "testname",
+			hideSummary: newHideSummaryValue(),
+			stdout:      stdout,
+			stderr:      new(bytes.Buffer),
+		},
+		prevExec: newExecutionWithTwoFailures(t),
+	}
+	assert.NilError(t, w.run(filewatcher.Event{RerunFailed: true}))
+	assert.DeepEqual(t, args, [][]string{
+		{"go", "test", "-json", "-test.run=^(TestOne|TestTwo)$", "pkg"},
+	})
+	assert.Assert(t, strings.HasPrefix(stdout.String(),
+		"Running 2 tests which failed in the previous run\n"), stdout.String())
+
+	assert.NilError(t, w.run(filewatcher.Event{RerunFailed: true}))
+	assert.DeepEqual(t, args[1], []string{"go", "test", "-json", "-test.run=^TestTwo$", "pkg"})
+}
+
+func TestWatchRuns_RerunFailed_NoFailures(t *testing.T) {
+	fn := func([]string) *proc {
+		t.Fatal("go test should not be run")
+		return nil
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	stdout := new(bytes.Buffer)
+	w := &watchRuns{opts: options{stdout: stdout}}
+	assert.NilError(t, w.run(filewatcher.Event{RerunFailed: true}))
+	assert.Equal(t, stdout.String(), "No previous run to rerun\n")
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(dedentOutput(`
+			{"Package": "pkg", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "pass"}
+			{"Package": "pkg", "Action": "pass"}
+		`)),
+		Stderr: strings.NewReader(""),
+	})
+	assert.NilError(t, err)
+	w.prevExec = exec
+	stdout.Reset()
+	assert.NilError(t, w.run(filewatcher.Event{RerunFailed: true}))
+	assert.Equal(t, stdout.String(), "No tests failed in the previous run\n")
+}
+
+func TestGoTestRunFlagForTestNames(t *testing.T) {
+	tests := []testjson.TestName{"TestOne/sub", "TestTwo", "TestOne/other", "Test[3]"}
+	actual := goTestRunFlagForTestNames(tests)
+	assert.Equal(t, actual, `-test.run=^(TestOne|TestTwo|Test\[3\])$`)
+
+	actual = goTestRunFlagForTestNames(tests[:1])
+	assert.Assert(t, cmp.Contains(actual, "TestOne$/^sub$"))
+}
+
+func TestWatchConfig(t *testing.T) {
+	opts := &options{
+		watchIgnore:  []string{"gen", "*_mock.go", "internal/legacy"},
+		watchInclude: []string{"*.sql"},
+	}
+	cfg := watchConfig(opts)
+
+	assert.Assert(t, cfg.Ignore("pkg/gen"))
+	assert.Assert(t, cfg.Ignore("pkg/store_mock.go"))
+	assert.Assert(t, cfg.Ignore("./internal/legacy"))
+	assert.Assert(t, cfg.Ignore("vendor"))
+	assert.Assert(t, !cfg.Ignore("pkg/testdata"))
+	assert.Assert(t, !cfg.Ignore("pkg/legacy"))
+
+	assert.Assert(t, cfg.Include("pkg/testdata/query.sql"))
+	assert.Assert(t, !cfg.Include("pkg/readme.md"))
+
+	cfg = watchConfig(&options{})
+	assert.Assert(t, cfg.Ignore("pkg/testdata"))
+	assert.Assert(t, cfg.Include == nil)
+
+	cfg = watchConfig(&options{watchPoll: time.Second, watchDebounce: 200 * time.Millisecond})
+	assert.Equal(t, cfg.PollInterval, time.Second)
+	assert.Equal(t, cfg.BatchWindow, 200*time.Millisecond)
+}
+
+func TestOptionsValidate_WatchGlobs(t *testing.T) {
+	opts := options{watchInclude: []string{"*.sql", "[a-"}}
+	err := opts.Validate()
+	assert.ErrorContains(t, err, `--watch-include "[a-" is not a valid glob pattern`)
+}
+
+func TestWatchRuns_PreRunCommand(t *testing.T) {
+	var runs int
+	fn := func([]string) *proc {
+		runs++
+		return &proc{
+			cmd: fakeWaiter{},
+			stdout: strings.NewReader(dedentOutput(`
+				{"Package": "pkg", "Action": "run"}
+				{"Package": "pkg", "Action": "pass"}
+			`)),
+			stderr: bytes.NewReader(nil),
+		}
+	}
+	reset := patchStartGoTestFn(fn)
+	defer reset()
+
+	newWatchRuns := func(command string) *watchRuns {
+		cmd := &commandValue{}
+		assert.NilError(t, cmd.Set(command))
+		return &watchRuns{opts: options{
+			format:         "testname",
+			hideSummary:    newHideSummaryValue(),
+			watchPreRunCmd: cmd,
+			stdout:         new(bytes.Buffer),
+			stderr:         new(bytes.Buffer),
+		}}
+	}
+
+	t.Run("command succeeds", func(t *testing.T) {
+		runs = 0
+		w := newWatchRuns("go env GOOS")
+		assert.NilError(t, w.run(filewatcher.Event{PkgPath: "./pkg"}))
+		assert.Equal(t, runs, 1)
+		assert.Assert(t, cmp.Contains(w.opts.stdout.(*bytes.Buffer).String(), runtime.GOOS+"\n"))
+	})
+
+	t.Run("command fails", func(t *testing.T) {
+		runs = 0
+		w := newWatchRuns("go not-a-command")
+		assert.NilError(t, w.run(filewatcher.Event{PkgPath: "./pkg"}))
+		assert.Equal(t, runs, 0)
+	})
+}
+
+func TestDelveCmdArgs(t *testing.T) {
+	opts := delveOpts{
+		pkgPath:      "./pkg",
+		args:         []string{"-count=1"},
+		initFilePath: "init",
+		runFlag:      "-test.run=^(TestOne|TestTwo)$",
+	}
+	expected := []string{
+		"dlv", "test", "--wd", "./pkg", "--output", "gotestsum-watch-debug.test",
+		"--init", "init", "./pkg", "--", "-count=1", "-test.run=^(TestOne|TestTwo)$",
+	}
+	assert.DeepEqual(t, delveCmdArgs(opts), expected)
+
+	opts.command = []string{"dlv", "test", "--headless", "--listen=:2345"}
+	opts.runFlag = ""
+	expected = []string{
+		"dlv", "test", "--headless", "--listen=:2345", "--wd", "./pkg",
+		"--output", "gotestsum-watch-debug.test", "--init", "init", "./pkg", "--", "-count=1",
+	}
+	assert.DeepEqual(t, delveCmdArgs(opts), expected)
+}
+
+func TestWatchRuns_Status(t *testing.T) {
+	w := &watchRuns{}
+	assert.Equal(t, w.status(), "waiting for a file to change")
+
+	w.prevExec = newExecutionWithTwoFailures(t)
+	assert.Assert(t, cmp.Regexp(`^FAIL 2 tests, 2 failed in \S+$`, w.status()))
+}
+
+func TestPrintFailureDiff(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(dedentOutput(`
+			{"Package": "pkg", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "run"}
+			{"Package": "pkg", "Test": "TestOne", "Action": "pass"}
+			{"Package": "pkg", "Test": "TestTwo", "Action": "run"}
+			{"Package": "pkg", "Test": "TestTwo", "Action": "fail"}
+			{"Package": "pkg", "Test": "TestThree", "Action": "run"}
+			{"Package": "pkg", "Test": "TestThree", "Action": "fail"}
+			{"Package": "pkg", "Action": "fail"}
+		`)),
+		Stderr: strings.NewReader(""),
+	})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	printFailureDiff(out, newExecutionWithTwoFailures(t), exec)
+	expected := `
+New failures (1):
+  pkg.TestThree
+Still failing (1):
+  pkg.TestTwo
+Fixed (1):
+  pkg.TestOne
+`
+	assert.Equal(t, out.String(), expected)
+
+	out.Reset()
+	printFailureDiff(out, exec, exec)
+	assert.Equal(t, out.String(), "\nStill failing (2):\n  pkg.TestTwo\n  pkg.TestThree\n")
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+// listedPackage is the subset of the fields printed by 'go list -json' that is
+// used to find the packages affected by a change.
+type listedPackage struct {
+	Dir          string
+	ImportPath   string
+	Deps         []string
+	TestImports  []string
+	XTestImports []string
+}
+
+// goListFn is a shim for testing
+var goListFn = goList
+
+func goList(dir string) ([]listedPackage, error) {
+	cmd := exec.Command("go", "list", "-e",
+		"-json=Dir,ImportPath,Deps,TestImports,XTestImports", "./...")
+	cmd.Dir = dir
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list failed: %w\n%s", err, stderr)
+	}
+
+	var pkgs []listedPackage
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var pkg listedPackage
+		switch err := dec.Decode(&pkg); {
+		case errors.Is(err, io.EOF):
+			return pkgs, nil
+		case err != nil:
+			return nil, fmt.Errorf("failed to decode go list output: %w", err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+}
+
+// affectedPackages returns the package in pkgDir, followed by every package in
+// the module in the working directory which imports it, directly or
+// transitively, from either the package or its tests. Packages are returned
+// as a 'go test' argument, a path relative to the working directory with a
+// ./ prefix.
+func affectedPackages(pkgDir string) ([]string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := goListFn(wd)
+	if err != nil {
+		return nil, err
+	}
+	changedDir, err := filepath.Abs(pkgDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed string
+	for _, pkg := range pkgs {
+		if pkg.Dir == changedDir {
+			changed = pkg.ImportPath
+		}
+	}
+	if changed == "" {
+		return []string{pkgDir}, nil
+	}
+
+	var affected []string
+	for _, pkg := range dependents(pkgs, map[string]bool{changed: true}) {
+		if pkg.ImportPath == changed {
+			continue
+		}
+		affected = append(affected, relativePackageDir(wd, pkg.Dir))
+	}
+	sort.Strings(affected)
+	return append([]string{pkgDir}, affected...), nil
+}
+
+// dependents returns the packages which are in changed, or which import a
+// package in changed, directly or transitively, from either the package or its
+// tests. changed is a set of import paths.
+func dependents(pkgs []listedPackage, changed map[string]bool) []listedPackage {
+	byImportPath := make(map[string]listedPackage, len(pkgs))
+	for _, pkg := range pkgs {
+		byImportPath[pkg.ImportPath] = pkg
+	}
+	dependsOnChanged := func(importPath string) bool {
+		if changed[importPath] {
+			return true
+		}
+		return slices.ContainsFunc(byImportPath[importPath].Deps, func(dep string) bool {
+			return changed[dep]
+		})
+	}
+
+	var result []listedPackage
+	for _, pkg := range pkgs {
+		if dependsOnChanged(pkg.ImportPath) ||
+			slices.ContainsFunc(pkg.TestImports, dependsOnChanged) ||
+			slices.ContainsFunc(pkg.XTestImports, dependsOnChanged) {
+			result = append(result, pkg)
+		}
+	}
+	return result
+}
+
+func relativePackageDir(wd, dir string) string {
+	rel, err := filepath.Rel(wd, dir)
+	if err != nil {
+		return dir
+	}
+	return "./" + filepath.ToSlash(rel)
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/filewatcher"
+	"gotest.tools/v3/assert"
+)
+
+func TestAffectedPackages(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NilError(t, err)
+
+	pkgs := []listedPackage{
+		{Dir: filepath.Join(wd, "store"), ImportPath: "example.com/app/store"},
+		{
+			Dir:        filepath.Join(wd, "api"),
+			ImportPath: "example.com/app/api",
+			Deps:       []string{"example.com/app/store", "fmt"},
+		},
+		{
+			Dir:        filepath.Join(wd, "cli"),
+			ImportPath: "example.com/app/cli",
+			Deps:       []string{"example.com/app/api", "example.com/app/store"},
+		},
+		{
+			Dir:         filepath.Join(wd, "fixtures"),
+			ImportPath:  "example.com/app/fixtures",
+			TestImports: []string{"example.com/app/api"},
+		},
+		{
+			Dir:          filepath.Join(wd, "docs"),
+			ImportPath:   "example.com/app/docs",
+			XTestImports: []string{"example.com/app/store"},
+		},
+		{
+			Dir:        filepath.Join(wd, "unrelated"),
+			ImportPath: "example.com/app/unrelated",
+			Deps:       []string{"fmt"},
+		},
+	}
+	patchGoListFn(t, pkgs)
+
+	t.Run("package with dependents", func(t *testing.T) {
+		actual, err := affectedPackages("./store")
+		assert.NilError(t, err)
+		expected := []string{"./store", "./api", "./cli", "./docs", "./fixtures"}
+		assert.DeepEqual(t, actual, expected)
+	})
+	t.Run("package with no dependents", func(t *testing.T) {
+		actual, err := affectedPackages("./unrelated")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, actual, []string{"./unrelated"})
+	})
+	t.Run("package not in the list", func(t *testing.T) {
+		actual, err := affectedPackages("./new")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, actual, []string{"./new"})
+	})
+}
+
+func patchGoListFn(t *testing.T, pkgs []listedPackage) {
+	orig := goListFn
+	goListFn = func(string) ([]listedPackage, error) {
+		return pkgs, nil
+	}
+	t.Cleanup(func() {
+		goListFn = orig
+	})
+}
+
+func TestGoList(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for short run")
+	}
+	pkgs, err := goList("../internal/coverprofile")
+	assert.NilError(t, err)
+	assert.Equal(t, len(pkgs), 1)
+	assert.Equal(t, pkgs[0].ImportPath, "gotest.tools/gotestsum/internal/coverprofile")
+	assert.Assert(t, len(pkgs[0].Deps) > 0)
+}
+
+func TestWatchRuns_Affected(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NilError(t, err)
+	patchGoListFn(t, []listedPackage{
+		{Dir: filepath.Join(wd, "store"), ImportPath: "example.com/app/store"},
+		{
+			Dir:        filepath.Join(wd, "api"),
+			ImportPath: "example.com/app/api",
+			Deps:       []string{"example.com/app/store"},
+		},
+	})
+
+	var args [][]string
+	reset := patchStartGoTestFn(func(a []string) *proc {
+		args = append(args, a)
+		return &proc{
+			cmd:    fakeWaiter{},
+			stdout: strings.NewReader(`{"Package": "example.com/app/store", "Action": "pass"}` + "\n"),
+			stderr: bytes.NewReader(nil),
+		}
+	})
+	defer reset()
+
+	stdout := new(bytes.Buffer)
+	w := &watchRuns{
+		opts: options{
+			format:        "testname",
+			hideSummary:   newHideSummaryValue(),
+			watchAffected: true,
+			stdout:        stdout,
+			stderr:        new(bytes.Buffer),
+		},
+	}
+	assert.NilError(t, w.run(filewatcher.Event{PkgPath: "./store"}))
+	assert.DeepEqual(t, args, [][]string{{"go", "test", "-json", "./store", "./api"}})
+	assert.Assert(t, strings.HasPrefix(stdout.String(),
+		"Also running tests in 1 packages which import ./store\n"), stdout.String())
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// goWorkFileFn is a shim for testing
+var goWorkFileFn = goWorkFile
+
+// goWorkFile returns the path of the go.work file used by the go command in
+// the working directory, or an empty string when workspace mode is off.
+func goWorkFile() (string, error) {
+	cmd := exec.Command("go", "env", "GOWORK")
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go env failed: %w\n%s", err, stderr)
+	}
+	path := strings.TrimSpace(string(out))
+	if path == "off" {
+		return "", nil
+	}
+	return path, nil
+}
+
+// workspaceWatchDirs returns the directories to watch when the working
+// directory is in a go.work workspace. The working directory is always
+// watched, and every module in the workspace that is not in the working
+// directory is also watched. When there is no workspace workspaceWatchDirs
+// returns nil.
+func workspaceWatchDirs() ([]string, error) {
+	path, err := goWorkFileFn()
+	if err != nil || path == "" {
+		return nil, err
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	work, err := modfile.ParseWork(path, raw, nil)
+	if err != nil {
+		return nil, err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{"./..."}
+	for _, use := range work.Use {
+		dir := filepath.FromSlash(use.Path)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(path), dir)
+		}
+		rel, err := filepath.Rel(wd, dir)
+		if err != nil {
+			return nil, err
+		}
+		if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			// the module is in the working directory, so it is already watched
+			continue
+		}
+		dirs = append(dirs, rel+"/...")
+	}
+	return dirs, nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+)
+
+func TestWorkspaceWatchDirs(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithDir("work",
+			fs.WithFile("go.work", "go 1.21\n\nuse (\n\t.\n\t./nested\n\t../other\n)\n\nuse ../third\n"),
+			fs.WithDir("nested")),
+		fs.WithDir("other"),
+		fs.WithDir("third"))
+
+	patchGoWorkFile(t, dir.Join("work", "go.work"))
+
+	t.Run("in the workspace root", func(t *testing.T) {
+		defer env.ChangeWorkingDir(t, dir.Join("work"))()
+		dirs, err := workspaceWatchDirs()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, dirs, []string{"./...", "../other/...", "../third/..."})
+	})
+
+	t.Run("in a module in the workspace", func(t *testing.T) {
+		defer env.ChangeWorkingDir(t, dir.Join("work", "nested"))()
+		dirs, err := workspaceWatchDirs()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, dirs, []string{"./...", "../...", "../../other/...", "../../third/..."})
+	})
+
+	t.Run("no workspace", func(t *testing.T) {
+		patchGoWorkFile(t, "")
+		dirs, err := workspaceWatchDirs()
+		assert.NilError(t, err)
+		assert.Assert(t, dirs == nil)
+	})
+}
+
+func patchGoWorkFile(t *testing.T, path string) {
+	t.Helper()
+	orig := goWorkFileFn
+	goWorkFileFn = func() (string, error) {
+		return path, nil
+	}
+	t.Cleanup(func() {
+		goWorkFileFn = orig
+	})
+}
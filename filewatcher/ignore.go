@@ -0,0 +1,19 @@
+package filewatcher
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// DefaultIgnore returns true if the base name of path is vendor or testdata,
+// or starts with a dot.
+func DefaultIgnore(path string) bool {
+	base := filepath.Base(path)
+	switch {
+	case strings.HasPrefix(base, ".") && len(base) > 1:
+		return true
+	case base == "vendor" || base == "testdata":
+		return true
+	}
+	return false
+}
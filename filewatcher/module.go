@@ -0,0 +1,61 @@
+package filewatcher
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+)
+
+// moduleCache finds the import path of a directory from the go.mod file of the
+// module that contains the directory. The module root of each directory is
+// cached, so go.mod files are only read once.
+type moduleCache struct {
+	// roots maps an absolute directory to the directory and module path of
+	// the module that contains it.
+	roots map[string]module
+}
+
+type module struct {
+	dir  string
+	path string
+}
+
+func newModuleCache() *moduleCache {
+	return &moduleCache{roots: make(map[string]module)}
+}
+
+// importPath returns the import path of the package in dir, or an empty string
+// if dir is not in a Go module.
+func (c *moduleCache) importPath(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	mod := c.find(abs)
+	if mod.path == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(mod.dir, abs)
+	if err != nil {
+		return ""
+	}
+	return path.Join(mod.path, filepath.ToSlash(rel))
+}
+
+func (c *moduleCache) find(dir string) module {
+	if mod, ok := c.roots[dir]; ok {
+		return mod
+	}
+	var mod module
+	raw, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	switch {
+	case err == nil:
+		mod = module{dir: dir, path: modfile.ModulePath(raw)}
+	case filepath.Dir(dir) != dir:
+		mod = c.find(filepath.Dir(dir))
+	}
+	c.roots[dir] = mod
+	return mod
+}
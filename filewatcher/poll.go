@@ -0,0 +1,165 @@
+//go:build !aix
+// +build !aix
+
+package filewatcher
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// poller watches directories by reading the files in each directory at an
+// interval, and sends an event for every file or directory which was created
+// or modified since the previous interval. Removed files do not send an event.
+type poller struct {
+	interval time.Duration
+	events   chan fsnotify.Event
+	errors   chan error
+	done     chan struct{}
+	closed   sync.Once
+
+	mu   sync.Mutex
+	dirs map[string]map[string]fileState
+}
+
+// fileState is used to detect a modified file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	isDir   bool
+}
+
+func newPoller(interval time.Duration) *poller {
+	p := &poller{
+		interval: interval,
+		events:   make(chan fsnotify.Event),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+		dirs:     make(map[string]map[string]fileState),
+	}
+	go p.loop()
+	return p
+}
+
+// Add a directory to the list of watched directories. Adding a directory that
+// is already watched does nothing.
+func (p *poller) Add(dir string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.dirs[dir]; ok {
+		return nil
+	}
+	files, err := readDirState(dir)
+	if err != nil {
+		return err
+	}
+	p.dirs[dir] = files
+	return nil
+}
+
+// WatchList returns the list of watched directories.
+func (p *poller) WatchList() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	dirs := make([]string, 0, len(p.dirs))
+	for dir := range p.dirs {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// Close stops polling.
+func (p *poller) Close() error {
+	p.closed.Do(func() {
+		close(p.done)
+	})
+	return nil
+}
+
+func (p *poller) loop() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+		for _, event := range p.poll() {
+			select {
+			case p.events <- event:
+			case <-p.done:
+				return
+			}
+		}
+	}
+}
+
+// poll reads every watched directory, and returns the events for the files
+// which changed since the last poll. A directory which can no longer be read
+// is no longer watched.
+func (p *poller) poll() []fsnotify.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	dirs := make([]string, 0, len(p.dirs))
+	for dir := range p.dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var events []fsnotify.Event
+	for _, dir := range dirs {
+		files, err := readDirState(dir)
+		if err != nil {
+			delete(p.dirs, dir)
+			continue
+		}
+		prev := p.dirs[dir]
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			state := files[name]
+			path := filepath.Join(dir, name)
+			prevState, ok := prev[name]
+			switch {
+			case !ok:
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+			case !state.isDir && state != prevState:
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+			}
+		}
+		p.dirs[dir] = files
+	}
+	return events
+}
+
+func readDirState(dir string) (map[string]fileState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]fileState, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// the file was removed after the directory was read
+			continue
+		}
+		files[entry.Name()] = fileState{
+			modTime: info.ModTime(),
+			size:    info.Size(),
+			isDir:   entry.IsDir(),
+		}
+	}
+	return files, nil
+}
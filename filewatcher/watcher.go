@@ -0,0 +1,374 @@
+//go:build !aix
+// +build !aix
+
+/*
+Package filewatcher watches the directories of Go packages, and reports changes
+to .go files. It is the file watcher used by 'gotestsum --watch'.
+
+Changes are sent on the channel returned by Watcher.Changes. Changes to files
+which are received within Config.BatchWindow of each other are sent as a
+single Change.
+*/
+package filewatcher
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// DefaultMaxDepth is the default value of Config.MaxDepth.
+const DefaultMaxDepth = 7
+
+// Config used by New to create a Watcher.
+type Config struct {
+	// Dirs to watch. A directory with a /... suffix watches the directory, and
+	// all the sub-directories which contain .go files. Defaults to ./...
+	Dirs []string
+	// MaxDepth is the maximum depth of sub-directories that are watched when
+	// a directory has a /... suffix. Defaults to DefaultMaxDepth.
+	MaxDepth int
+	// Ignore returns true for a file or directory that should not be watched.
+	// Defaults to DefaultIgnore.
+	Ignore func(path string) bool
+	// Include returns true for a file, other than a .go file, that should be
+	// watched. A change to an included file is reported for the package in
+	// the nearest parent directory that contains .go files. Directories that
+	// contain included files are watched even if they have no .go files.
+	Include func(path string) bool
+	// BatchWindow is how long to wait for more changes after the first change
+	// is received, before the changes are sent as a single Change. When
+	// BatchWindow is zero every change is sent as soon as it is received.
+	BatchWindow time.Duration
+	// PollInterval, when it is not zero, watches the directories by checking
+	// the files in each directory for changes at this interval, instead of
+	// using file system notifications. Polling works on file systems which do
+	// not support notifications, like NFS, or some container volume mounts.
+	PollInterval time.Duration
+}
+
+// Change is one or more .go files, or files matched by Config.Include, that
+// were created, written, or renamed.
+type Change struct {
+	// Files that changed, in the order the first event for each was received.
+	Files []string
+	// Packages that contain the files, in the same order as Files.
+	Packages []Package
+}
+
+// Package is a directory that contains Go files.
+type Package struct {
+	// Dir is the path of the package as a 'go test' argument, prefixed with
+	// "./".
+	Dir string
+	// ImportPath of the package, from the go.mod file of the module which
+	// contains Dir. ImportPath is empty if Dir is not in a Go module.
+	ImportPath string
+}
+
+// Watcher sends a Change when .go files in the watched directories are
+// modified. A Watcher must be closed by calling Close.
+type Watcher struct {
+	cfg     Config
+	fsw     backend
+	events  <-chan fsnotify.Event
+	fsErrs  <-chan error
+	changes chan Change
+	errors  chan error
+	done    chan struct{}
+	closed  sync.Once
+	modules *moduleCache
+}
+
+// New creates a Watcher which watches cfg.Dirs.
+func New(cfg Config) (*Watcher, error) {
+	if len(cfg.Dirs) == 0 {
+		cfg.Dirs = []string{"./..."}
+	}
+	if cfg.MaxDepth == 0 {
+		cfg.MaxDepth = DefaultMaxDepth
+	}
+	if cfg.Ignore == nil {
+		cfg.Ignore = DefaultIgnore
+	}
+	w := &Watcher{
+		cfg:     cfg,
+		changes: make(chan Change),
+		errors:  make(chan error),
+		done:    make(chan struct{}),
+		modules: newModuleCache(),
+	}
+	if cfg.PollInterval > 0 {
+		p := newPoller(cfg.PollInterval)
+		w.fsw, w.events, w.fsErrs = p, p.events, p.errors
+	} else {
+		fsw, err := fsnotify.NewWatcher()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create file watcher: %w", err)
+		}
+		w.fsw, w.events, w.fsErrs = fsw, fsw.Events, fsw.Errors
+	}
+	if err := w.Reload(); err != nil {
+		_ = w.fsw.Close()
+		return nil, err
+	}
+	go w.loop()
+	return w, nil
+}
+
+// backend is the source of file system events. It is implemented by
+// fsnotify.Watcher, and by poller.
+type backend interface {
+	Add(dir string) error
+	WatchList() []string
+	Close() error
+}
+
+// Changes returns the channel which receives changes to .go files.
+func (w *Watcher) Changes() <-chan Change {
+	return w.changes
+}
+
+// Errors returns the channel which receives errors from the file system
+// watcher.
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Reload finds all the directories that match Config.Dirs and watches any
+// that are not already watched. Use Reload to watch new directories that were
+// created by something other than a file system event, for example a checkout
+// of a different branch.
+func (w *Watcher) Reload() error {
+	for _, dir := range findAllDirs(w.cfg.Dirs, w.cfg.MaxDepth, w.cfg.Ignore, w.cfg.Include) {
+		if err := w.fsw.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch directory %v: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// WatchedDirs returns the sorted list of directories that are watched.
+func (w *Watcher) WatchedDirs() []string {
+	dirs := w.fsw.WatchList()
+	sort.Strings(dirs)
+	return dirs
+}
+
+// Close stops watching all directories. The Changes and Errors channels are
+// not closed, so Close may be called while another goroutine is receiving
+// from them.
+func (w *Watcher) Close() error {
+	var err error
+	w.closed.Do(func() {
+		close(w.done)
+		err = w.fsw.Close()
+	})
+	return err
+}
+
+func (w *Watcher) loop() {
+	var pending Change
+	var out chan Change // nil until pending is ready to send
+	var timer <-chan time.Time
+
+	for {
+		select {
+		case <-w.done:
+			return
+
+		case event, ok := <-w.events:
+			if !ok {
+				return
+			}
+			log.Debugf("handling event %v", event)
+			if w.handleDirCreated(event) || !w.isWatchedFileChange(event) {
+				continue
+			}
+			pending.add(event.Name, w.modules)
+			switch {
+			case w.cfg.BatchWindow <= 0:
+				out = w.changes
+			case timer == nil && out == nil:
+				timer = time.After(w.cfg.BatchWindow)
+			}
+
+		case <-timer:
+			timer = nil
+			out = w.changes
+
+		case out <- pending:
+			pending = Change{}
+			out = nil
+
+		case err, ok := <-w.fsErrs:
+			if !ok {
+				return
+			}
+			select {
+			case w.errors <- err:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// isWatchedFileChange returns true if the event is a write, create, or rename
+// of a .go file, or an included file, that is not ignored.
+func (w *Watcher) isWatchedFileChange(event fsnotify.Event) bool {
+	if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+		return false
+	}
+	if !isWatchedFile(event.Name, w.cfg.Include) {
+		return false
+	}
+	return !w.cfg.Ignore(event.Name)
+}
+
+func isWatchedFile(path string, include func(string) bool) bool {
+	return strings.HasSuffix(path, ".go") || include != nil && include(path)
+}
+
+// handleDirCreated watches a new directory, and returns true if the event was
+// for a new directory.
+func (w *Watcher) handleDirCreated(event fsnotify.Event) bool {
+	if event.Op&fsnotify.Create != fsnotify.Create {
+		return false
+	}
+
+	fileInfo, err := os.Stat(event.Name)
+	if err != nil {
+		log.Debugf("failed to stat %s: %s", event.Name, err)
+		return false
+	}
+
+	if !fileInfo.IsDir() {
+		return false
+	}
+
+	if w.cfg.Ignore(event.Name) {
+		return true
+	}
+	if err := w.fsw.Add(event.Name); err != nil {
+		log.Warnf("failed to watch new directory %v: %v", event.Name, err)
+	}
+	return true
+}
+
+func (c *Change) add(file string, modules *moduleCache) {
+	for _, f := range c.Files {
+		if f == file {
+			return
+		}
+	}
+	c.Files = append(c.Files, file)
+
+	dir := filepath.Dir(file)
+	if !strings.HasSuffix(file, ".go") {
+		dir = packageDir(dir)
+	}
+	pkg := Package{Dir: "./" + dir, ImportPath: modules.importPath(dir)}
+	for _, p := range c.Packages {
+		if p == pkg {
+			return
+		}
+	}
+	c.Packages = append(c.Packages, pkg)
+}
+
+// packageDir returns the nearest directory, starting at dir, that contains .go
+// files. If no parent directory contains .go files, dir is returned.
+func packageDir(dir string) string {
+	for current := dir; ; current = filepath.Dir(current) {
+		if hasGoFiles(current) {
+			return current
+		}
+		if filepath.Dir(current) == current {
+			return dir
+		}
+	}
+}
+
+func findAllDirs(dirs []string, maxDepth int, ignore, include func(string) bool) []string {
+	var output []string //nolint:prealloc
+	for _, dir := range dirs {
+		const recur = "/..."
+		if strings.HasSuffix(dir, recur) {
+			dir = strings.TrimSuffix(dir, recur)
+			output = append(output, findSubDirs(dir, maxDepth, ignore, include)...)
+			continue
+		}
+		output = append(output, dir)
+	}
+	return output
+}
+
+func findSubDirs(rootDir string, maxDepth int, ignore, include func(string) bool) []string {
+	var output []string
+	// add root dir depth so that maxDepth is relative to the root dir
+	maxDepth += pathDepth(rootDir)
+	walker := func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			log.Warnf("failed to watch %v: %v", path, err)
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if pathDepth(path) > maxDepth || ignore(path) {
+			log.Debugf("Ignoring %v because of max depth or ignore rules", path)
+			return filepath.SkipDir
+		}
+		if !hasWatchedFiles(path, include) {
+			log.Debugf("Ignoring %v because it has no .go files or included files", path)
+			return nil
+		}
+		output = append(output, path)
+		return nil
+	}
+	//nolint:errcheck // error is handled by walker func
+	filepath.Walk(rootDir, walker)
+	return output
+}
+
+func pathDepth(path string) int {
+	return strings.Count(filepath.Clean(path), string(filepath.Separator))
+}
+
+func hasGoFiles(path string) bool {
+	return hasWatchedFiles(path, nil)
+}
+
+func hasWatchedFiles(path string, include func(string) bool) bool {
+	fh, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer fh.Close() //nolint:errcheck // fh is opened read-only
+
+	for {
+		names, err := fh.Readdirnames(20)
+		switch {
+		case err == io.EOF:
+			return false
+		case err != nil:
+			log.Warnf("failed to read directory %v: %v", path, err)
+			return false
+		}
+
+		for _, name := range names {
+			if isWatchedFile(filepath.Join(path, name), include) {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,291 @@
+//go:build !aix
+// +build !aix
+
+package filewatcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/env"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/poll"
+)
+
+func TestWatcher_IsWatchedFileChange(t *testing.T) {
+	type testCase struct {
+		name     string
+		event    fsnotify.Event
+		expected bool
+	}
+
+	w := &Watcher{cfg: Config{Ignore: DefaultIgnore}}
+	var testCases = []testCase{
+		{
+			name:     "Op is rename",
+			event:    fsnotify.Event{Op: fsnotify.Rename, Name: "file_test.go"},
+			expected: true,
+		},
+		{
+			name:  "Op is remove",
+			event: fsnotify.Event{Op: fsnotify.Remove, Name: "file_test.go"},
+		},
+		{
+			name:  "Op is chmod",
+			event: fsnotify.Event{Op: fsnotify.Chmod, Name: "file_test.go"},
+		},
+		{
+			name:     "Op is write+chmod",
+			event:    fsnotify.Event{Op: fsnotify.Write | fsnotify.Chmod, Name: "file_test.go"},
+			expected: true,
+		},
+		{
+			name:     "Op is write",
+			event:    fsnotify.Event{Op: fsnotify.Write, Name: "file_test.go"},
+			expected: true,
+		},
+		{
+			name:     "Op is create",
+			event:    fsnotify.Event{Op: fsnotify.Create, Name: "file_test.go"},
+			expected: true,
+		},
+		{
+			name:  "file is not a go file",
+			event: fsnotify.Event{Op: fsnotify.Write, Name: "readme.md"},
+		},
+		{
+			name:  "file is ignored",
+			event: fsnotify.Event{Op: fsnotify.Write, Name: ".#file.go"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, w.isWatchedFileChange(tc.event), tc.expected)
+		})
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("go.mod", "module example.com/mod\n"),
+		fs.WithFile("main.go", "package main\n"),
+		fs.WithDir("pkg", fs.WithFile("pkg.go", "package pkg\n")),
+		fs.WithDir("testdata", fs.WithFile("data.go", "package data\n")))
+
+	w, err := New(Config{Dirs: []string{dir.Path() + "/..."}, BatchWindow: 100 * time.Millisecond})
+	assert.NilError(t, err)
+	t.Cleanup(func() { assert.Check(t, w.Close()) })
+
+	assert.DeepEqual(t, w.WatchedDirs(), []string{dir.Path(), dir.Join("pkg")})
+
+	fs.Apply(t, dir,
+		fs.WithDir("pkg", fs.WithFile("pkg.go", "package pkg\n\nvar x = 1\n")),
+		fs.WithFile("main.go", "package main\n\nfunc main() {}\n"),
+		fs.WithDir("testdata", fs.WithFile("data.go", "package data\n\nvar x = 1\n")))
+
+	select {
+	case change := <-w.Changes():
+		assert.DeepEqual(t, change.Files, []string{dir.Join("pkg", "pkg.go"), dir.Join("main.go")})
+		expected := []Package{
+			{Dir: "./" + dir.Join("pkg"), ImportPath: "example.com/mod/pkg"},
+			{Dir: "./" + dir.Path(), ImportPath: "example.com/mod"},
+		}
+		assert.DeepEqual(t, change.Packages, expected)
+	case err := <-w.Errors():
+		t.Fatalf("unexpected error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for change")
+	}
+}
+
+func TestModuleCache_ImportPath(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("go.mod", "module example.com/mod // comment\n\ngo 1.20\n"),
+		fs.WithDir("a", fs.WithDir("b")),
+		fs.WithDir("nested",
+			fs.WithFile("go.mod", "module \"example.com/nested\"\n"),
+			fs.WithDir("c")))
+
+	cache := newModuleCache()
+	assert.Equal(t, cache.importPath(dir.Path()), "example.com/mod")
+	assert.Equal(t, cache.importPath(dir.Join("a", "b")), "example.com/mod/a/b")
+	assert.Equal(t, cache.importPath(dir.Join("nested", "c")), "example.com/nested/c")
+
+	defer env.ChangeWorkingDir(t, dir.Path())()
+	assert.Equal(t, cache.importPath("a"), "example.com/mod/a")
+}
+
+func TestHasGoFiles(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		tmpDir := fs.NewDir(t, t.Name(), fs.WithFile("readme.md", ""))
+		defer tmpDir.Remove()
+		assert.Assert(t, !hasGoFiles(tmpDir.Path()))
+	})
+	t.Run("empty", func(t *testing.T) {
+		tmpDir := fs.NewDir(t, t.Name())
+		defer tmpDir.Remove()
+		assert.Assert(t, !hasGoFiles(tmpDir.Path()))
+	})
+	t.Run("some go files", func(t *testing.T) {
+		tmpDir := fs.NewDir(t, t.Name(), fs.WithFile("main.go", ""))
+		defer tmpDir.Remove()
+		assert.Assert(t, hasGoFiles(tmpDir.Path()))
+	})
+	t.Run("many go files", func(t *testing.T) {
+		tmpDir := fs.NewDir(t, t.Name())
+		for i := 0; i < 47; i++ {
+			fs.Apply(t, tmpDir, fs.WithFile(fmt.Sprintf("file%d.go", i), ""))
+		}
+		defer tmpDir.Remove()
+		assert.Assert(t, hasGoFiles(tmpDir.Path()))
+	})
+}
+
+func TestFindAllDirs(t *testing.T) {
+	goFile := fs.WithFile("file.go", "")
+	dirOne := fs.NewDir(t, t.Name(),
+		goFile,
+		fs.WithFile("not-a-dir", ""),
+		fs.WithDir("no-go-files"),
+		fs.WithDir(".starts-with-dot", goFile))
+	defer dirOne.Remove()
+	var path string
+	for i := 1; i <= 10; i++ {
+		path = filepath.Join(path, fmt.Sprintf("%d", i))
+		var ops []fs.PathOp
+		if i != 4 && i != 5 {
+			ops = []fs.PathOp{goFile}
+		}
+		fs.Apply(t, dirOne, fs.WithDir(path, ops...))
+	}
+
+	dirTwo := fs.NewDir(t, t.Name(),
+		goFile,
+		// subdir should be ignored, dirTwo is used without /... suffix
+		fs.WithDir("subdir", goFile))
+	defer dirTwo.Remove()
+
+	dirs := findAllDirs([]string{dirOne.Path() + "/...", dirTwo.Path()}, DefaultMaxDepth, DefaultIgnore, nil)
+	expected := []string{
+		dirOne.Path(),
+		dirOne.Join("1"),
+		dirOne.Join("1/2"),
+		dirOne.Join("1/2/3"),
+		dirOne.Join("1/2/3/4/5/6"),
+		dirOne.Join("1/2/3/4/5/6/7"),
+		dirTwo.Path(),
+	}
+	assert.DeepEqual(t, dirs, expected)
+}
+
+func TestFindAllDirs_RelativePath(t *testing.T) {
+	goFile := fs.WithFile("file.go", "")
+	dirOne := fs.NewDir(t, t.Name(),
+		goFile,
+		fs.WithDir("a", goFile),
+		fs.WithDir("b", goFile))
+	defer dirOne.Remove()
+
+	defer env.ChangeWorkingDir(t, dirOne.Path())()
+	dirs := findAllDirs([]string{"./..."}, DefaultMaxDepth, DefaultIgnore, nil)
+	expected := []string{".", "a", "b"}
+	assert.DeepEqual(t, dirs, expected)
+}
+
+func TestWatcher_Include(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("go.mod", "module example.com/mod\n"),
+		fs.WithDir("pkg",
+			fs.WithFile("pkg.go", "package pkg\n"),
+			fs.WithFile("query.sql", "select 1;\n"),
+			fs.WithDir("testdata", fs.WithFile("input.golden", "one\n"))))
+
+	include := func(path string) bool {
+		return filepath.Ext(path) == ".sql" || filepath.Ext(path) == ".golden"
+	}
+	ignore := func(path string) bool {
+		return filepath.Base(path) != "testdata" && DefaultIgnore(path)
+	}
+	cfg := Config{
+		Dirs:        []string{dir.Path() + "/..."},
+		Ignore:      ignore,
+		Include:     include,
+		BatchWindow: 100 * time.Millisecond,
+	}
+	w, err := New(cfg)
+	assert.NilError(t, err)
+	t.Cleanup(func() { assert.Check(t, w.Close()) })
+
+	assert.DeepEqual(t, w.WatchedDirs(), []string{dir.Join("pkg"), dir.Join("pkg", "testdata")})
+
+	fs.Apply(t, dir, fs.WithDir("pkg",
+		fs.WithFile("readme.md", "not included\n"),
+		fs.WithDir("testdata", fs.WithFile("input.golden", "two\n"))))
+
+	select {
+	case change := <-w.Changes():
+		assert.DeepEqual(t, change.Files, []string{dir.Join("pkg", "testdata", "input.golden")})
+		expected := []Package{
+			{Dir: "./" + dir.Join("pkg"), ImportPath: "example.com/mod/pkg"},
+		}
+		assert.DeepEqual(t, change.Packages, expected)
+	case err := <-w.Errors():
+		t.Fatalf("unexpected error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for change")
+	}
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("go.mod", "module example.com/mod\n"),
+		fs.WithFile("main.go", "package main\n"),
+		fs.WithDir("pkg", fs.WithFile("pkg.go", "package pkg\n")))
+
+	cfg := Config{
+		Dirs:         []string{dir.Path() + "/..."},
+		BatchWindow:  100 * time.Millisecond,
+		PollInterval: 10 * time.Millisecond,
+	}
+	w, err := New(cfg)
+	assert.NilError(t, err)
+	t.Cleanup(func() { assert.Check(t, w.Close()) })
+
+	assert.DeepEqual(t, w.WatchedDirs(), []string{dir.Path(), dir.Join("pkg")})
+
+	fs.Apply(t, dir,
+		fs.WithDir("pkg", fs.WithFile("pkg.go", "package pkg\n\nvar x = 1\n")),
+		fs.WithDir("other", fs.WithFile("other.go", "package other\n")))
+
+	select {
+	case change := <-w.Changes():
+		assert.DeepEqual(t, change.Files, []string{dir.Join("pkg", "pkg.go")})
+	case err := <-w.Errors():
+		t.Fatalf("unexpected error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for change")
+	}
+
+	// the new directory is watched after it is found by a poll
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		if len(w.WatchedDirs()) == 3 {
+			return poll.Success()
+		}
+		return poll.Continue("waiting for the new directory to be watched")
+	}, poll.WithTimeout(5*time.Second))
+
+	fs.Apply(t, dir, fs.WithDir("other", fs.WithFile("other.go", "package other\n\nvar x = 1\n")))
+
+	select {
+	case change := <-w.Changes():
+		assert.DeepEqual(t, change.Files, []string{dir.Join("other", "other.go")})
+	case err := <-w.Errors():
+		t.Fatalf("unexpected error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for change")
+	}
+}
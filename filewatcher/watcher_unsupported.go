@@ -0,0 +1,41 @@
+//go:build aix
+// +build aix
+
+package filewatcher
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// Config used by New to create a Watcher.
+type Config struct {
+	Dirs         []string
+	MaxDepth     int
+	Ignore       func(path string) bool
+	Include      func(path string) bool
+	BatchWindow  time.Duration
+	PollInterval time.Duration
+}
+
+// Change is one or more .go files that were created, written, or renamed.
+type Change struct {
+	Files    []string
+	Packages []Package
+}
+
+// Package is a directory that contains Go files.
+type Package struct {
+	Dir        string
+	ImportPath string
+}
+
+// Watcher is not supported on this platform.
+type Watcher struct{}
+
+// New returns an error, because file watching is not supported on this
+// platform.
+func New(Config) (*Watcher, error) {
+	return nil, fmt.Errorf("file watching is not supported on %v/%v", runtime.GOOS, runtime.GOARCH)
+}
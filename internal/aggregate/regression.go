@@ -0,0 +1,88 @@
+package aggregate
+
+import (
+	"sort"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Regression is a test case which was slower than its historical median by
+// more than the allowed threshold.
+type Regression struct {
+	testjson.TestCase
+	// Baseline is the median elapsed time of the test case in the baseline
+	// execution.
+	Baseline time.Duration
+}
+
+// Change returns the relative change in elapsed time from the Baseline, as a
+// fraction. A value of 0.5 means the test was 50% slower than the baseline.
+func (r Regression) Change() float64 {
+	if r.Baseline <= 0 {
+		return 0
+	}
+	return float64(r.Elapsed-r.Baseline) / float64(r.Baseline)
+}
+
+// DurationRegressions compares the elapsed time of every passing test in
+// current against the median elapsed time of the same test in baseline. Tests
+// that are slower than the baseline by more than threshold (a fraction, 0.5
+// is 50%) are returned, sorted by the largest change first.
+//
+// Tests with a baseline median less than minElapsed are ignored, because
+// small differences in very fast tests are mostly noise. Tests which do not
+// appear in baseline are also ignored.
+func DurationRegressions(
+	baseline, current *testjson.Execution,
+	threshold float64,
+	minElapsed time.Duration,
+) []Regression {
+	if baseline == nil {
+		return nil
+	}
+	medians := make(map[string]map[string]time.Duration)
+	for _, name := range baseline.Packages() {
+		medians[name] = make(map[string]time.Duration)
+		for _, tc := range ByElapsed(baseline.Package(name).TestCases(), Median) {
+			medians[name][tc.Test.Name()] = tc.Elapsed
+		}
+	}
+	return MedianRegressions(medians, current, threshold, minElapsed)
+}
+
+// MedianRegressions is the same as DurationRegressions, with the median elapsed
+// time of each test in the baseline, by package and then by test name.
+func MedianRegressions(
+	medians map[string]map[string]time.Duration,
+	current *testjson.Execution,
+	threshold float64,
+	minElapsed time.Duration,
+) []Regression {
+	if current == nil || threshold <= 0 {
+		return nil
+	}
+
+	var result []Regression
+	for _, name := range current.Packages() {
+		pkgMedians, ok := medians[name]
+		if !ok {
+			continue
+		}
+		for _, tc := range ByElapsed(current.Package(name).Passed, Median) {
+			base, ok := pkgMedians[tc.Test.Name()]
+			if !ok || base < minElapsed || base <= 0 {
+				continue
+			}
+			r := Regression{TestCase: tc, Baseline: base}
+			if r.Change() > threshold {
+				result = append(result, r)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Change() > result[j].Change()
+	})
+	return result
+}
@@ -0,0 +1,65 @@
+package aggregate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+func TestDurationRegressions(t *testing.T) {
+	newEvent := func(pkg, test string, elapsed float64) testjson.TestEvent {
+		return testjson.TestEvent{
+			Package: pkg,
+			Test:    test,
+			Action:  testjson.ActionPass,
+			Elapsed: elapsed,
+		}
+	}
+
+	baseline := newExecutionFromEvents(t,
+		newEvent("one", "TestOmega", 1.0),
+		newEvent("one", "TestOmega", 1.2),
+		newEvent("one", "TestOmega", 9.0),
+		newEvent("one", "TestOnion", 0.5),
+		newEvent("one", "TestTiny", 0.001),
+		newEvent("two", "TestTents", 2.0))
+
+	current := newExecutionFromEvents(t,
+		newEvent("one", "TestOmega", 2.4),
+		newEvent("one", "TestOnion", 0.6),
+		newEvent("one", "TestTiny", 0.5),
+		newEvent("one", "TestNew", 30),
+		newEvent("two", "TestTents", 5.0),
+		newEvent("three", "TestThree", 5.0))
+
+	cmpRegression := cmp.Comparer(func(x, y Regression) bool {
+		return x.Package == y.Package &&
+			x.Test == y.Test &&
+			x.Elapsed == y.Elapsed &&
+			x.Baseline == y.Baseline
+	})
+
+	actual := DurationRegressions(baseline, current, 0.5, 10*time.Millisecond)
+	expected := []Regression{
+		{
+			TestCase: testjson.TestCase{Package: "two", Test: "TestTents", Elapsed: 5 * time.Second},
+			Baseline: 2 * time.Second,
+		},
+		{
+			TestCase: testjson.TestCase{Package: "one", Test: "TestOmega", Elapsed: 2400 * time.Millisecond},
+			Baseline: 1200 * time.Millisecond,
+		},
+	}
+	assert.DeepEqual(t, actual, expected, cmpRegression)
+	assert.Equal(t, actual[0].Change(), 1.5)
+}
+
+func TestDurationRegressions_ThresholdNotSet(t *testing.T) {
+	exec := newExecutionFromEvents(t, testjson.TestEvent{
+		Package: "one", Test: "TestOne", Action: testjson.ActionPass, Elapsed: 1,
+	})
+	assert.Assert(t, DurationRegressions(exec, exec, 0, 0) == nil)
+}
@@ -14,13 +14,24 @@ import (
 // If there are multiple runs of a TestCase, all of them will be represented
 // by a single TestCase with the median elapsed time in the returned slice.
 func Slowest(exec *testjson.Execution, threshold time.Duration, num int) []testjson.TestCase {
+	return SlowestBy(exec, threshold, num, Median)
+}
+
+// SlowestBy is like Slowest, but uses fn to select the elapsed time of a
+// TestCase with multiple runs.
+func SlowestBy(
+	exec *testjson.Execution,
+	threshold time.Duration,
+	num int,
+	fn func(times []time.Duration) time.Duration,
+) []testjson.TestCase {
 	if threshold == 0 && num == 0 {
 		return nil
 	}
 	pkgs := exec.Packages()
 	tests := make([]testjson.TestCase, 0, len(pkgs))
 	for _, pkg := range pkgs {
-		pkgTests := ByElapsed(exec.Package(pkg).TestCases(), median)
+		pkgTests := ByElapsed(exec.Package(pkg).TestCases(), fn)
 		tests = append(tests, pkgTests...)
 	}
 	sort.Slice(tests, func(i, j int) bool {
@@ -63,7 +74,9 @@ func ByElapsed(cases []testjson.TestCase, fn func(times []time.Duration) time.Du
 	return result
 }
 
-func median(times []time.Duration) time.Duration {
+// Median returns the median of times. When there is an even number of times
+// the larger of the two middle values is used. times is sorted in place.
+func Median(times []time.Duration) time.Duration {
 	switch len(times) {
 	case 0:
 		return 0
@@ -75,3 +88,41 @@ func median(times []time.Duration) time.Duration {
 	})
 	return times[len(times)/2]
 }
+
+// P95 returns the 95th percentile of times. times is sorted in place.
+func P95(times []time.Duration) time.Duration {
+	return Percentile(times, 95)
+}
+
+// Percentile returns the p-th percentile of times, using the nearest-rank
+// method. times is sorted in place.
+func Percentile(times []time.Duration, p int) time.Duration {
+	if len(times) == 0 {
+		return 0
+	}
+	sort.Slice(times, func(i, j int) bool {
+		return times[i] < times[j]
+	})
+	rank := (len(times)*p + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return times[rank-1]
+}
+
+// PercentileOfRun returns the p-th percentile of the elapsed time of all the
+// tests in exec. When a test ran more than once fn is used to select its
+// elapsed time, the same as SlowestBy.
+func PercentileOfRun(
+	exec *testjson.Execution,
+	p int,
+	fn func(times []time.Duration) time.Duration,
+) time.Duration {
+	var times []time.Duration
+	for _, pkg := range exec.Packages() {
+		for _, tc := range ByElapsed(exec.Package(pkg).TestCases(), fn) {
+			times = append(times, tc.Elapsed)
+		}
+	}
+	return Percentile(times, p)
+}
@@ -119,7 +119,7 @@ func TestByElapsed_WithMedian(t *testing.T) {
 		{Test: "TestOne", Package: "pkg", Elapsed: 5 * time.Second},
 		{Test: "TestTwo", Package: "pkg", Elapsed: 6 * time.Second},
 	}
-	actual := ByElapsed(cases, median)
+	actual := ByElapsed(cases, Median)
 	expected := []testjson.TestCase{
 		{Test: "TestOne", Package: "pkg", Elapsed: 3 * time.Second},
 		{Test: "TestTwo", Package: "pkg", Elapsed: 4 * time.Second},
@@ -156,8 +156,42 @@ func TestMedian(t *testing.T) {
 
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			actual := median(tc.times)
+			actual := Median(tc.times)
 			assert.Equal(t, actual, tc.expected)
 		})
 	}
 }
+
+func TestP95(t *testing.T) {
+	times := make([]time.Duration, 0, 20)
+	for i := 20; i > 0; i-- {
+		times = append(times, time.Duration(i)*time.Second)
+	}
+	assert.Equal(t, P95(times), 19*time.Second)
+	assert.Equal(t, P95([]time.Duration{time.Second}), time.Second)
+	assert.Equal(t, P95([]time.Duration{time.Second, time.Hour}), time.Hour)
+	assert.Equal(t, P95(nil), time.Duration(0))
+}
+
+func TestPercentileOfRun(t *testing.T) {
+	newEvent := func(test string, elapsed float64) testjson.TestEvent {
+		return testjson.TestEvent{
+			Package: "pkg",
+			Test:    test,
+			Action:  testjson.ActionPass,
+			Elapsed: elapsed,
+		}
+	}
+	exec := newExecutionFromEvents(t,
+		newEvent("TestOne", 0.1),
+		newEvent("TestTwo", 0.2),
+		newEvent("TestThree", 0.3),
+		newEvent("TestThree", 0.9),
+		newEvent("TestThree", 0.5),
+		newEvent("TestFour", 0.4))
+
+	assert.Equal(t, PercentileOfRun(exec, 50, Median), 200*time.Millisecond)
+	assert.Equal(t, PercentileOfRun(exec, 75, Median), 400*time.Millisecond)
+	assert.Equal(t, PercentileOfRun(exec, 100, Median), 500*time.Millisecond)
+	assert.Equal(t, PercentileOfRun(exec, 100, P95), 900*time.Millisecond)
+}
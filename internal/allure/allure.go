@@ -0,0 +1,353 @@
+/*
+Package allure writes test results as Allure result files, which are rendered
+by Allure Report.
+
+Each attempt of a top-level test is written as a result file. Subtests are
+steps of the result, and the output of the test is an attachment. When a test
+is run more than once, for example by --rerun-fails, every attempt has the same
+historyId, so that Allure Report shows the earlier attempts as retries.
+
+See https://allurereport.org/docs/how-it-works-test-result-file/ for the
+format.
+*/
+package allure
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Status of a result or step.
+type Status string
+
+// Values of Status.
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	StatusBroken  Status = "broken"
+	StatusSkipped Status = "skipped"
+)
+
+// Result is the result of one attempt of a test.
+type Result struct {
+	UUID          string         `json:"uuid"`
+	HistoryID     string         `json:"historyId"`
+	TestCaseID    string         `json:"testCaseId"`
+	FullName      string         `json:"fullName"`
+	Name          string         `json:"name"`
+	Status        Status         `json:"status"`
+	StatusDetails *StatusDetails `json:"statusDetails,omitempty"`
+	Stage         string         `json:"stage"`
+	Start         int64          `json:"start"`
+	Stop          int64          `json:"stop"`
+	Labels        []Label        `json:"labels"`
+	Steps         []*Step        `json:"steps,omitempty"`
+	Attachments   []Attachment   `json:"attachments,omitempty"`
+}
+
+// Step is a subtest of a test.
+type Step struct {
+	Name          string         `json:"name"`
+	Status        Status         `json:"status"`
+	StatusDetails *StatusDetails `json:"statusDetails,omitempty"`
+	Stage         string         `json:"stage"`
+	Start         int64          `json:"start"`
+	Stop          int64          `json:"stop"`
+	Steps         []*Step        `json:"steps,omitempty"`
+}
+
+// StatusDetails explains why a test failed or was skipped.
+type StatusDetails struct {
+	Message string `json:"message,omitempty"`
+	Trace   string `json:"trace,omitempty"`
+	Flaky   bool   `json:"flaky,omitempty"`
+}
+
+// Label is a name and value used by Allure Report to group results.
+type Label struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Attachment is a file, in the results directory, attached to a result.
+type Attachment struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Type   string `json:"type"`
+}
+
+const stageFinished = "finished"
+
+// Write a result file for each attempt of each test in exec to the dir.
+func Write(dir string, exec *testjson.Execution) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create allure results directory: %w", err)
+	}
+	w := writer{dir: dir}
+	for _, pkgname := range exec.Packages() {
+		for _, result := range packageResults(exec.Package(pkgname), pkgname) {
+			if err := w.write(result); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newUUID is a shim for testing.
+var newUUID = func() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// result is a Result which has not been written, and the output to attach.
+type result struct {
+	Result
+	output string
+}
+
+type writer struct {
+	dir string
+}
+
+func (w writer) write(r result) error {
+	r.UUID = newUUID()
+	if r.output != "" {
+		source := newUUID() + "-attachment.txt"
+		path := filepath.Join(w.dir, source)
+		if err := os.WriteFile(path, []byte(r.output), 0o644); err != nil {
+			return fmt.Errorf("failed to write allure attachment: %w", err)
+		}
+		r.Attachments = append(r.Attachments, Attachment{
+			Name:   "output",
+			Source: source,
+			Type:   "text/plain",
+		})
+	}
+
+	raw, err := json.MarshalIndent(r.Result, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(w.dir, r.UUID+"-result.json")
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write allure result: %w", err)
+	}
+	return nil
+}
+
+type testCase struct {
+	testjson.TestCase
+	status Status
+}
+
+func packageResults(pkg *testjson.Package, pkgname string) []result {
+	var results []result
+	if pkg.TestMainFailed() {
+		r := newResult(pkgname, testjson.TestCase{Test: "TestMain"}, StatusBroken)
+		r.output = pkg.Output(0)
+		r.StatusDetails = &StatusDetails{Message: "TestMain failed", Trace: r.output}
+		results = append(results, r)
+	}
+
+	var roots, subs []testCase
+	for _, group := range []struct {
+		tcs    []testjson.TestCase
+		status Status
+	}{
+		{tcs: pkg.Failed, status: StatusFailed},
+		{tcs: pkg.Skipped, status: StatusSkipped},
+		{tcs: pkg.Passed, status: StatusPassed},
+	} {
+		for _, tc := range group.tcs {
+			if tc.Test.IsSubTest() {
+				subs = append(subs, testCase{TestCase: tc, status: group.status})
+				continue
+			}
+			roots = append(roots, testCase{TestCase: tc, status: group.status})
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].ID < roots[j].ID })
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+
+	flaky := flakyTests(roots)
+	attempts := groupAttempts(roots, subs, pkg)
+	for _, root := range roots {
+		r := newResult(pkgname, root.TestCase, root.status)
+		r.Steps = attempts[root.ID].steps
+		r.output = attempts[root.ID].output.String()
+		r.StatusDetails = statusDetails(pkg, root)
+		if root.status == StatusFailed {
+			r.StatusDetails.Trace = r.output
+			if message := failedStepMessage(r.Steps); message != "" {
+				r.StatusDetails.Message = message
+			}
+		}
+		if flaky[root.Test] {
+			if r.StatusDetails == nil {
+				r.StatusDetails = &StatusDetails{}
+			}
+			r.StatusDetails.Flaky = true
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+func newResult(pkgname string, tc testjson.TestCase, status Status) result {
+	fullName := pkgname + "." + tc.Test.Name()
+	start, stop := timeRange(tc)
+	return result{Result: Result{
+		HistoryID:  hash(fullName),
+		TestCaseID: hash(fullName),
+		FullName:   fullName,
+		Name:       tc.Test.Name(),
+		Status:     status,
+		Stage:      stageFinished,
+		Start:      start,
+		Stop:       stop,
+		Labels: []Label{
+			{Name: "framework", Value: "gotestsum"},
+			{Name: "language", Value: "go"},
+			{Name: "package", Value: pkgname},
+			{Name: "suite", Value: pkgname},
+		},
+	}}
+}
+
+func hash(value string) string {
+	sum := md5.Sum([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// timeRange returns the start and stop time of the test case in milliseconds.
+func timeRange(tc testjson.TestCase) (int64, int64) {
+	if tc.Time.IsZero() {
+		return 0, 0
+	}
+	elapsed := tc.Elapsed
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return tc.Time.UnixMilli(), tc.Time.Add(elapsed).Round(time.Millisecond).UnixMilli()
+}
+
+// flakyTests returns the names of tests which both failed and passed.
+func flakyTests(roots []testCase) map[testjson.TestName]bool {
+	statuses := make(map[testjson.TestName]map[Status]bool)
+	for _, tc := range roots {
+		if statuses[tc.Test] == nil {
+			statuses[tc.Test] = make(map[Status]bool)
+		}
+		statuses[tc.Test][tc.status] = true
+	}
+	flaky := make(map[testjson.TestName]bool)
+	for name, s := range statuses {
+		if s[StatusFailed] && s[StatusPassed] {
+			flaky[name] = true
+		}
+	}
+	return flaky
+}
+
+// attempt is a single run of a top-level test.
+type attempt struct {
+	steps []*Step
+	// output of the top-level test followed by the output of each subtest.
+	output strings.Builder
+	// steps by the name of the subtest, used to find the parent of a step.
+	named map[string]*Step
+}
+
+// groupAttempts returns each attempt of a top-level test, keyed by the ID of
+// the test case. A subtest belongs to the most recent attempt of its top-level
+// test, in the same run, which started before the subtest.
+func groupAttempts(roots, subs []testCase, pkg *testjson.Package) map[int]*attempt {
+	result := make(map[int]*attempt, len(roots))
+	for _, root := range roots {
+		a := &attempt{named: make(map[string]*Step)}
+		a.output.WriteString(pkg.Output(root.ID))
+		result[root.ID] = a
+	}
+
+	for _, sub := range subs {
+		rootName, _ := sub.Test.Split()
+		owner := -1
+		for _, root := range roots {
+			if root.ID > sub.ID {
+				break
+			}
+			if root.Test.Name() == rootName && root.RunID == sub.RunID {
+				owner = root.ID
+			}
+		}
+		if owner < 0 {
+			continue
+		}
+
+		start, stop := timeRange(sub.TestCase)
+		parts := strings.Split(sub.Test.Name(), "/")
+		step := &Step{
+			Name:          parts[len(parts)-1],
+			Status:        sub.status,
+			StatusDetails: statusDetails(pkg, sub),
+			Stage:         stageFinished,
+			Start:         start,
+			Stop:          stop,
+		}
+		a := result[owner]
+		a.output.WriteString(pkg.Output(sub.ID))
+		a.named[sub.Test.Name()] = step
+
+		if parent, ok := a.named[sub.Test.Parent()]; ok {
+			parent.Steps = append(parent.Steps, step)
+			continue
+		}
+		a.steps = append(a.steps, step)
+	}
+	return result
+}
+
+func statusDetails(pkg *testjson.Package, tc testCase) *StatusDetails {
+	switch tc.status {
+	case StatusFailed:
+		message := pkg.SkipReason(tc.TestCase)
+		if message == "" {
+			message = "Failed"
+		}
+		return &StatusDetails{
+			Message: message,
+			Trace:   pkg.Output(tc.ID),
+		}
+	case StatusSkipped:
+		return &StatusDetails{Message: pkg.SkipReason(tc.TestCase)}
+	}
+	return nil
+}
+
+// failedStepMessage returns the message of the first failed step in steps.
+func failedStepMessage(steps []*Step) string {
+	for _, step := range steps {
+		if step.Status != StatusFailed {
+			continue
+		}
+		if message := failedStepMessage(step.Steps); message != "" {
+			return message
+		}
+		return step.StatusDetails.Message
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package allure
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/jsonfile/jsonfiletest"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestWrite(t *testing.T) {
+	patchUUID(t)
+	exec := jsonfiletest.ScanFile(t, "testdata/run.json", testjson.ScanConfig{KeepPassedOutput: true})
+	jsonfiletest.ScanFile(t, "testdata/rerun.json", testjson.ScanConfig{
+		Execution:        exec,
+		RunID:            1,
+		KeepPassedOutput: true,
+	})
+
+	dir := fs.NewDir(t, t.Name())
+	err := Write(dir.Join("allure-results"), exec)
+	assert.NilError(t, err)
+	golden.Assert(t, readDir(t, dir.Join("allure-results")), "expected-results")
+}
+
+func patchUUID(t *testing.T) {
+	t.Helper()
+	orig := newUUID
+	var count int
+	newUUID = func() string {
+		count++
+		return fmt.Sprintf("00000000-0000-4000-8000-%012d", count)
+	}
+	t.Cleanup(func() {
+		newUUID = orig
+	})
+}
+
+// readDir returns the name and contents of every file in dir, sorted by name.
+func readDir(t *testing.T, dir string) string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	var out strings.Builder
+	for _, entry := range entries {
+		raw, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		assert.NilError(t, err)
+		fmt.Fprintf(&out, "=== %v\n%s\n", entry.Name(), raw)
+	}
+	return out.String()
+}
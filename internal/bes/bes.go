@@ -0,0 +1,320 @@
+/*
+Package bes writes the results of a test run as a stream of Bazel Build Event
+Protocol (BEP) events, in the JSON format written by bazel --build_event_json_file.
+
+Each package is a test target, labelled with its import path. A package has a
+TestResult event for every attempt, where an attempt after the first is a rerun
+of the failed tests in the package, for example by --rerun-fails, and a
+TestSummary event with the overall status of the target. See
+https://bazel.build/remote/bep for the protocol.
+*/
+package bes
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// BuildEvent is a single event of the stream. The ID of the event is a
+// BuildEventID, and exactly one of the payload fields is set.
+type BuildEvent struct {
+	ID          BuildEventID   `json:"id"`
+	Children    []BuildEventID `json:"children,omitempty"`
+	LastMessage bool           `json:"lastMessage,omitempty"`
+	Started     *BuildStarted  `json:"started,omitempty"`
+	TestResult  *TestResult    `json:"testResult,omitempty"`
+	TestSummary *TestSummary   `json:"testSummary,omitempty"`
+	Finished    *BuildFinished `json:"finished,omitempty"`
+}
+
+// BuildEventID identifies an event. Exactly one of the fields is set.
+type BuildEventID struct {
+	Started       *struct{}      `json:"started,omitempty"`
+	TestResult    *TestResultID  `json:"testResult,omitempty"`
+	TestSummary   *TestSummaryID `json:"testSummary,omitempty"`
+	BuildFinished *struct{}      `json:"buildFinished,omitempty"`
+}
+
+// TestResultID identifies an attempt of a test target.
+type TestResultID struct {
+	Label   string `json:"label"`
+	Run     int    `json:"run"`
+	Shard   int    `json:"shard"`
+	Attempt int    `json:"attempt"`
+}
+
+// TestSummaryID identifies the summary of a test target.
+type TestSummaryID struct {
+	Label string `json:"label"`
+}
+
+// BuildStarted is the payload of the first event.
+type BuildStarted struct {
+	UUID               string `json:"uuid"`
+	StartTime          string `json:"startTime"`
+	BuildToolVersion   string `json:"buildToolVersion"`
+	OptionsDescription string `json:"optionsDescription,omitempty"`
+	Command            string `json:"command"`
+}
+
+// TestResult is the payload of an attempt of a test target.
+type TestResult struct {
+	Status              string `json:"status"`
+	CachedLocally       bool   `json:"cachedLocally,omitempty"`
+	TestAttemptStart    string `json:"testAttemptStart"`
+	TestAttemptDuration string `json:"testAttemptDuration"`
+	TestActionOutput    []File `json:"testActionOutput,omitempty"`
+}
+
+// TestSummary is the payload of the summary of a test target.
+type TestSummary struct {
+	OverallStatus    string `json:"overallStatus"`
+	TotalRunCount    int    `json:"totalRunCount"`
+	RunCount         int    `json:"runCount"`
+	AttemptCount     int    `json:"attemptCount"`
+	ShardCount       int    `json:"shardCount"`
+	TotalNumCached   int    `json:"totalNumCached"`
+	FirstStartTime   string `json:"firstStartTime"`
+	LastStopTime     string `json:"lastStopTime"`
+	TotalRunDuration string `json:"totalRunDuration"`
+}
+
+// BuildFinished is the payload of the last event.
+type BuildFinished struct {
+	ExitCode   ExitCode `json:"exitCode"`
+	FinishTime string   `json:"finishTime"`
+}
+
+// ExitCode of the build, using the names and codes of the bazel exit codes.
+type ExitCode struct {
+	Name string `json:"name"`
+	Code int    `json:"code"`
+}
+
+// File is an output of a test action.
+type File struct {
+	Name string `json:"name"`
+	URI  string `json:"uri"`
+}
+
+// Values of TestResult.Status and TestSummary.OverallStatus.
+const (
+	statusPassed = "PASSED"
+	statusFlaky  = "FLAKY"
+	statusFailed = "FAILED"
+)
+
+// Config used to create the events.
+type Config struct {
+	// Version of gotestsum, used as the version of the build tool.
+	Version string
+	// Command is the go test command which ran the tests.
+	Command []string
+	// JUnitFile is the path to the JUnit XML file of the run. When it is set
+	// the file is the test.xml output of every TestResult.
+	JUnitFile string
+	// ExitCode of gotestsum.
+	ExitCode int
+}
+
+// newUUID is a shim for testing.
+var newUUID = func() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Write the events of exec to out, as newline delimited JSON.
+func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
+	enc := json.NewEncoder(out)
+	for _, event := range NewEvents(exec, cfg) {
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewEvents returns the events of the run. The first event is BuildStarted, and
+// the last event is BuildFinished.
+func NewEvents(exec *testjson.Execution, cfg Config) []BuildEvent {
+	var outputs []File
+	if cfg.JUnitFile != "" {
+		if path, err := filepath.Abs(cfg.JUnitFile); err == nil {
+			outputs = []File{{Name: "test.xml", URI: "file://" + filepath.ToSlash(path)}}
+		}
+	}
+
+	started := BuildEvent{
+		ID: BuildEventID{Started: &struct{}{}},
+		Started: &BuildStarted{
+			UUID:               newUUID(),
+			StartTime:          timestamp(exec.Started()),
+			BuildToolVersion:   "gotestsum " + cfg.Version,
+			OptionsDescription: strings.Join(cfg.Command, " "),
+			Command:            "test",
+		},
+	}
+	events := []BuildEvent{started}
+
+	end := exec.Started().Add(exec.Elapsed())
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		if pkg.IsEmpty() {
+			// a package with no tests is not a test target
+			continue
+		}
+		label := "//" + name
+		attempts := packageAttempts(pkg)
+		summary := &TestSummary{
+			OverallStatus:  overallStatus(attempts),
+			TotalRunCount:  len(attempts),
+			RunCount:       1,
+			AttemptCount:   len(attempts),
+			ShardCount:     1,
+			FirstStartTime: timestamp(attempts[0].start),
+		}
+		for i, a := range attempts {
+			id := BuildEventID{TestResult: &TestResultID{Label: label, Run: 1, Shard: 1, Attempt: i + 1}}
+			started.Children = append(started.Children, id)
+			events = append(events, BuildEvent{
+				ID: id,
+				TestResult: &TestResult{
+					Status:              a.status,
+					CachedLocally:       pkg.Cached(),
+					TestAttemptStart:    timestamp(a.start),
+					TestAttemptDuration: duration(a.end.Sub(a.start)),
+					TestActionOutput:    outputs,
+				},
+			})
+		}
+		last := attempts[len(attempts)-1].end
+		summary.LastStopTime = timestamp(last)
+		summary.TotalRunDuration = duration(last.Sub(attempts[0].start))
+		if pkg.Cached() {
+			summary.TotalNumCached = 1
+		}
+		if last.After(end) {
+			end = last
+		}
+
+		id := BuildEventID{TestSummary: &TestSummaryID{Label: label}}
+		started.Children = append(started.Children, id)
+		events = append(events, BuildEvent{ID: id, TestSummary: summary})
+	}
+
+	finished := BuildEventID{BuildFinished: &struct{}{}}
+	started.Children = append(started.Children, finished)
+	events[0] = started
+	events = append(events, BuildEvent{
+		ID:          finished,
+		LastMessage: true,
+		Finished: &BuildFinished{
+			ExitCode:   exitCode(exec, cfg.ExitCode),
+			FinishTime: timestamp(end),
+		},
+	})
+	return events
+}
+
+type attempt struct {
+	status string
+	start  time.Time
+	end    time.Time
+}
+
+// packageAttempts returns the attempts of a package, in the order they were
+// run. There is always at least one attempt.
+func packageAttempts(pkg *testjson.Package) []attempt {
+	byRunID := map[int]*attempt{
+		0: {status: statusPassed, start: pkg.Start, end: pkg.Start},
+	}
+	add := func(tc testjson.TestCase, failed bool) {
+		a, ok := byRunID[tc.RunID]
+		if !ok {
+			a = &attempt{status: statusPassed, start: tc.Time, end: tc.Time}
+			byRunID[tc.RunID] = a
+		}
+		if tc.Time.Before(a.start) {
+			a.start = tc.Time
+		}
+		if tcEnd := tc.Time.Add(tc.Elapsed); tcEnd.After(a.end) {
+			a.end = tcEnd
+		}
+		if failed {
+			a.status = statusFailed
+		}
+	}
+	for _, tc := range pkg.Failed {
+		add(tc, true)
+	}
+	for _, tc := range pkg.Skipped {
+		add(tc, false)
+	}
+	for _, tc := range pkg.Passed {
+		add(tc, false)
+	}
+
+	runIDs := make([]int, 0, len(byRunID))
+	for runID := range byRunID {
+		runIDs = append(runIDs, runID)
+	}
+	sort.Ints(runIDs)
+	attempts := make([]attempt, 0, len(runIDs))
+	for _, runID := range runIDs {
+		attempts = append(attempts, *byRunID[runID])
+	}
+
+	if len(attempts) == 1 {
+		if pkgEnd := pkg.Start.Add(pkg.Elapsed()); pkgEnd.After(attempts[0].end) {
+			attempts[0].end = pkgEnd
+		}
+	}
+	// a package can fail without a failed test, when TestMain or init fails
+	if pkg.Result() == testjson.ActionFail {
+		attempts[len(attempts)-1].status = statusFailed
+	}
+	return attempts
+}
+
+func overallStatus(attempts []attempt) string {
+	last := attempts[len(attempts)-1].status
+	switch {
+	case last == statusFailed:
+		return statusFailed
+	case len(attempts) > 1:
+		return statusFlaky
+	default:
+		return statusPassed
+	}
+}
+
+func exitCode(exec *testjson.Execution, code int) ExitCode {
+	switch {
+	case code == 0:
+		return ExitCode{Name: "SUCCESS", Code: 0}
+	case len(exec.Errors()) > 0:
+		return ExitCode{Name: "BUILD_FAILURE", Code: 1}
+	default:
+		return ExitCode{Name: "TESTS_FAILED", Code: 3}
+	}
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// duration formats d as a google.protobuf.Duration in JSON.
+func duration(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
@@ -0,0 +1,30 @@
+package bes
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/jsonfile/jsonfiletest"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestWrite(t *testing.T) {
+	orig := newUUID
+	newUUID = func() string { return "11111111-2222-4333-8444-555555555555" }
+	t.Cleanup(func() { newUUID = orig })
+
+	exec := jsonfiletest.ScanFile(t, jsonfiletest.InputFile, testjson.ScanConfig{})
+	jsonfiletest.ScanFile(t, jsonfiletest.RerunFile, testjson.ScanConfig{Execution: exec, RunID: 1})
+
+	buf := new(bytes.Buffer)
+	err := Write(buf, exec, Config{
+		Version:   "v1.2.3",
+		Command:   []string{"go", "test", "-json", "./..."},
+		JUnitFile: "/work/junit.xml",
+		ExitCode:  0,
+	})
+	assert.NilError(t, err)
+	golden.Assert(t, buf.String(), "expected-events.json")
+}
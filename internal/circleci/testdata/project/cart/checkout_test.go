@@ -0,0 +1,11 @@
+package cart
+
+import "testing"
+
+func TestCheckout(t *testing.T) {
+	t.Fatal("payment declined")
+}
+
+func TestMain(m *testing.M) {
+	m.Run()
+}
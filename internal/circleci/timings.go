@@ -0,0 +1,136 @@
+/*
+Package circleci creates a timings file from a testjson.Execution, which is used
+by CircleCI to split tests between parallel containers.
+
+The timings file is a JUnit XML document which is stored with the
+store_test_results step. 'circleci tests split --split-by=timings' reads the
+file, classname, and time attributes of each testcase from the stored results
+of previous builds. Only top-level tests are included, so that the time of a
+subtest is not counted twice. See
+https://circleci.com/docs/parallelism-faster-jobs/ for how the timings are used.
+*/
+package circleci
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/internal/sonar"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// TestSuites is the root element of the timings file.
+type TestSuites struct {
+	XMLName xml.Name    `xml:"testsuites"`
+	Suites  []TestSuite `xml:"testsuite"`
+}
+
+// TestSuite is the timing of a package, and the tests in the package.
+type TestSuite struct {
+	Name      string     `xml:"name,attr"`
+	Tests     int        `xml:"tests,attr"`
+	Time      string     `xml:"time,attr"`
+	TestCases []TestCase `xml:"testcase"`
+}
+
+// TestCase is the timing of a top-level test. File is the path of the test
+// file which declares the test, and is empty when the file was not found.
+type TestCase struct {
+	Classname string `xml:"classname,attr"`
+	Name      string `xml:"name,attr"`
+	File      string `xml:"file,attr,omitempty"`
+	Time      string `xml:"time,attr"`
+}
+
+// Config used to write a timings file.
+type Config struct {
+	// PackageDir returns the directory of a package from the import path of the
+	// package. The path is used to find test files, and is written to the
+	// file attribute, so it should be relative to the root of the project.
+	// Defaults to testjson.RelativePackagePath.
+	PackageDir func(pkgpath string) string
+}
+
+// Write creates an XML document and writes it to out.
+func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
+	if cfg.PackageDir == nil {
+		cfg.PackageDir = testjson.RelativePackagePath
+	}
+	raw, err := xml.MarshalIndent(generate(exec, cfg), "", "\t")
+	if err != nil {
+		return fmt.Errorf("failed to write CircleCI timings: %w", err)
+	}
+	if _, err := out.Write(raw); err != nil {
+		return fmt.Errorf("failed to write CircleCI timings: %w", err)
+	}
+	_, err = out.Write([]byte("\n"))
+	return err
+}
+
+func generate(exec *testjson.Execution, cfg Config) TestSuites {
+	var doc TestSuites
+	for _, pkgname := range exec.Packages() {
+		pkg := exec.Package(pkgname)
+		if pkg.IsEmpty() {
+			continue
+		}
+		dir := cfg.PackageDir(pkgname)
+		testFiles, err := sonar.IndexTestFiles(dir)
+		if err != nil {
+			log.Debugf("failed to find test files for package %v: %v", pkgname, err)
+		}
+
+		suite := TestSuite{Name: pkgname, Time: formatDuration(pkg.Elapsed())}
+		for _, tc := range latestRootTests(pkg) {
+			testCase := TestCase{
+				Classname: pkgname,
+				Name:      tc.Test.Name(),
+				Time:      formatDuration(tc.Elapsed),
+			}
+			if file, ok := testFiles[tc.Test.Name()]; ok {
+				testCase.File = path.Join(filepath.ToSlash(dir), file)
+			}
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+		suite.Tests = len(suite.TestCases)
+		doc.Suites = append(doc.Suites, suite)
+	}
+	return doc
+}
+
+// latestRootTests returns the top-level tests of the package, in the order
+// they were run. When a test was run more than once, for example by
+// --rerun-fails, only the last attempt is included.
+func latestRootTests(pkg *testjson.Package) []testjson.TestCase {
+	latest := make(map[testjson.TestName]testjson.TestCase)
+	for _, group := range [][]testjson.TestCase{pkg.Failed, pkg.Skipped, pkg.Passed} {
+		for _, tc := range group {
+			if tc.Test == "" || tc.Test.IsSubTest() {
+				continue
+			}
+			if prev, ok := latest[tc.Test]; ok && prev.ID > tc.ID {
+				continue
+			}
+			latest[tc.Test] = tc
+		}
+	}
+
+	tcs := make([]testjson.TestCase, 0, len(latest))
+	for _, tc := range latest {
+		tcs = append(tcs, tc)
+	}
+	sort.Slice(tcs, func(i, j int) bool {
+		return tcs[i].ID < tcs[j].ID
+	})
+	return tcs
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,26 @@
+package circleci
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/jsonfile/jsonfiletest"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestWrite(t *testing.T) {
+	exec := jsonfiletest.ScanFile(t, jsonfiletest.InputFile, testjson.ScanConfig{})
+
+	out := new(bytes.Buffer)
+	err := Write(out, exec, Config{PackageDir: packageDir})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "expected-timings.xml")
+}
+
+func packageDir(pkgpath string) string {
+	return filepath.Join("testdata/project", strings.TrimPrefix(pkgpath, "example.com/project/"))
+}
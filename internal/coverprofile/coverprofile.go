@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
@@ -138,22 +139,46 @@ func mergeCounts(a, b int, mode string) int {
 	return b
 }
 
+// writeProfilesFile writes profiles to filename. The profiles are written to a
+// temporary file in the same directory, which is synced and then renamed to
+// filename, so that an interrupted write never leaves a partial cover profile.
 func writeProfilesFile(filename string, profiles []*cover.Profile) (retErr error) {
 	if len(profiles) == 0 {
 		return nil
 	}
 
-	f, err := os.Create(filename)
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-*")
 	if err != nil {
 		return fmt.Errorf("create cover profile: %w", err)
 	}
 	defer func() {
-		if err := f.Close(); retErr == nil {
-			retErr = err
+		if retErr != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
 		}
 	}()
 
-	return writeProfiles(f, profiles)
+	if err := writeProfiles(f, profiles); err != nil {
+		return fmt.Errorf("write cover profile: %w", err)
+	}
+	if err := f.Chmod(mode); err != nil {
+		return fmt.Errorf("write cover profile: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync cover profile: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close cover profile: %w", err)
+	}
+	if err := os.Rename(f.Name(), filename); err != nil {
+		return fmt.Errorf("rename cover profile: %w", err)
+	}
+	return nil
 }
 
 func writeProfiles(w io.Writer, profiles []*cover.Profile) error {
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"golang.org/x/tools/cover"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/skip"
 )
 
 func TestArgValue(t *testing.T) {
@@ -227,6 +229,37 @@ func TestMergeRerun_ModeMismatch(t *testing.T) {
 	assert.ErrorContains(t, err, "mode mismatch")
 }
 
+func TestMergeRerun_AtomicWrite(t *testing.T) {
+	skip.If(t, runtime.GOOS == "windows", "file mode is not supported")
+	dir := t.TempDir()
+	original := filepath.Join(dir, "original.out")
+	rerun := filepath.Join(dir, "rerun.out")
+
+	writeTestProfile(t, original, "set", []profileEntry{
+		{file: "pkg/a.go", startLine: 1, startCol: 1, endLine: 5, endCol: 2, numStmt: 3, count: 0},
+	})
+	assert.NilError(t, os.Chmod(original, 0o600))
+	writeTestProfile(t, rerun, "set", []profileEntry{
+		{file: "pkg/a.go", startLine: 1, startCol: 1, endLine: 5, endCol: 2, numStmt: 3, count: 1},
+	})
+
+	err := MergeRerun(original, rerun)
+	assert.NilError(t, err)
+
+	info, err := os.Stat(original)
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0o600))
+
+	// no temporary files are left in the directory
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.DeepEqual(t, names, []string{"original.out", "rerun.out"})
+}
+
 // Test helpers
 
 type profileEntry struct {
@@ -0,0 +1,276 @@
+/*
+Package datadog sends the results of a test run to the Datadog CI Visibility
+intake, without the Datadog tracer or agent.
+
+The run is a test session, with a single test module. Each package is a test
+suite, and each test is a test event in the suite of its package. When a test is
+run more than once, for example by --rerun-fails, every attempt is a test event,
+and the attempts after the first are retries. Tests which failed, and then passed
+when they were run again, are flaky.
+*/
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Payload is the body of a request to the CI Visibility intake.
+type Payload struct {
+	Version  int                          `json:"version"`
+	Metadata map[string]map[string]string `json:"metadata"`
+	Events   []Event                      `json:"events"`
+}
+
+// Event is a test, test suite, test module, or test session.
+type Event struct {
+	Type    string  `json:"type"`
+	Version int     `json:"version"`
+	Content Content `json:"content"`
+}
+
+// Content of an event. Start is the start time in nanoseconds since the Unix
+// epoch, and Duration is in nanoseconds.
+type Content struct {
+	TraceID       uint64            `json:"trace_id,omitempty"`
+	SpanID        uint64            `json:"span_id,omitempty"`
+	ParentID      uint64            `json:"parent_id"`
+	Name          string            `json:"name"`
+	Resource      string            `json:"resource"`
+	Service       string            `json:"service"`
+	Type          string            `json:"type"`
+	Start         int64             `json:"start"`
+	Duration      int64             `json:"duration"`
+	Error         int               `json:"error"`
+	Meta          map[string]string `json:"meta"`
+	TestSessionID uint64            `json:"test_session_id,omitempty"`
+	TestModuleID  uint64            `json:"test_module_id,omitempty"`
+	TestSuiteID   uint64            `json:"test_suite_id,omitempty"`
+}
+
+// Values of the test.status tag.
+const (
+	statusPass = "pass"
+	statusFail = "fail"
+	statusSkip = "skip"
+)
+
+const testFramework = "golang.org/pkg/testing"
+
+// Config of the events.
+type Config struct {
+	Service string
+	Env     string
+	// Tags are added to every event. Tags include the git and CI metadata.
+	Tags map[string]string
+}
+
+// newID returns a random ID. It is replaced in tests.
+var newID = func() uint64 {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return binary.BigEndian.Uint64(b[:]) >> 1
+}
+
+// NewPayload returns the events of the session, module, suites, and tests of
+// exec.
+func NewPayload(exec *testjson.Execution, cfg Config) Payload {
+	b := &payloadBuilder{cfg: cfg, sessionID: newID(), moduleID: newID()}
+
+	start := exec.Started()
+	end := start.Add(exec.Elapsed())
+	for _, name := range exec.Packages() {
+		if suiteEnd := b.addSuite(name, exec.Package(name)); suiteEnd.After(end) {
+			end = suiteEnd
+		}
+	}
+
+	status := statusPass
+	if len(exec.Failed()) > 0 || len(exec.Errors()) > 0 {
+		status = statusFail
+	}
+	module := b.content("go.test_module", cfg.Service, "test_module_end", start, end, status)
+	module.TestSessionID = b.sessionID
+	module.TestModuleID = b.moduleID
+	module.Meta["test.module"] = cfg.Service
+
+	session := b.content("go.test_session", "go test", "test_session_end", start, end, status)
+	session.TestSessionID = b.sessionID
+	session.Meta["test.command"] = "go test"
+
+	b.events = append(b.events,
+		Event{Type: "test_module_end", Version: 1, Content: module},
+		Event{Type: "test_session_end", Version: 1, Content: session})
+
+	return Payload{
+		Version: 1,
+		Metadata: map[string]map[string]string{
+			"*": {
+				"language":        "go",
+				"env":             cfg.Env,
+				"runtime.name":    "go",
+				"runtime.version": runtime.Version(),
+				"os.platform":     runtime.GOOS,
+				"os.architecture": runtime.GOARCH,
+			},
+		},
+		Events: b.events,
+	}
+}
+
+type payloadBuilder struct {
+	cfg       Config
+	sessionID uint64
+	moduleID  uint64
+	events    []Event
+}
+
+func (b *payloadBuilder) content(name, resource, typ string, start, end time.Time, status string) Content {
+	meta := map[string]string{
+		"span.kind":      "test",
+		"test.framework": testFramework,
+		"test.status":    status,
+	}
+	for k, v := range b.cfg.Tags {
+		meta[k] = v
+	}
+	c := Content{
+		Name:     name,
+		Resource: resource,
+		Service:  b.cfg.Service,
+		Type:     typ,
+		Start:    start.UnixNano(),
+		Duration: end.Sub(start).Nanoseconds(),
+		Meta:     meta,
+	}
+	if status == statusFail {
+		c.Error = 1
+	}
+	return c
+}
+
+// addSuite adds the events of a package and its tests, and returns the end
+// time of the suite.
+func (b *payloadBuilder) addSuite(name string, pkg *testjson.Package) time.Time {
+	suiteID := newID()
+	tcs := testCases(pkg)
+
+	end := pkg.Start.Add(pkg.Elapsed())
+	for _, tc := range tcs {
+		if tcEnd := tc.Time.Add(tc.Elapsed); tcEnd.After(end) {
+			end = tcEnd
+		}
+	}
+
+	failed := make(map[testjson.TestName]bool)
+	for _, tc := range tcs {
+		test := b.content("go.test", name+"."+tc.Test.Name(), "test", tc.Time, tc.Time.Add(tc.Elapsed), tc.status)
+		test.TraceID = newID()
+		test.SpanID = newID()
+		test.TestSessionID = b.sessionID
+		test.TestModuleID = b.moduleID
+		test.TestSuiteID = suiteID
+		test.Meta["test.name"] = tc.Test.Name()
+		test.Meta["test.suite"] = name
+		test.Meta["test.module"] = b.cfg.Service
+		test.Meta["test.type"] = "test"
+		if tc.RunID > 0 {
+			test.Meta["test.is_retry"] = "true"
+		}
+		if tc.status == statusPass && failed[tc.Test] {
+			test.Meta["test.is_flaky"] = "true"
+		}
+		if tc.status == statusFail {
+			failed[tc.Test] = true
+			test.Meta["error.message"] = "test failed"
+		}
+		if tc.status == statusSkip {
+			if reason := pkg.SkipReason(tc.TestCase); reason != "" {
+				test.Meta["test.skip_reason"] = reason
+			}
+		}
+		b.events = append(b.events, Event{Type: "test", Version: 2, Content: test})
+	}
+
+	status := statusPass
+	switch {
+	case pkg.Result() == testjson.ActionFail:
+		status = statusFail
+	case pkg.Result() == testjson.ActionSkip || pkg.IsEmpty():
+		status = statusSkip
+	}
+	suite := b.content("go.test_suite", name, "test_suite_end", pkg.Start, end, status)
+	suite.TestSessionID = b.sessionID
+	suite.TestModuleID = b.moduleID
+	suite.TestSuiteID = suiteID
+	suite.Meta["test.suite"] = name
+	suite.Meta["test.module"] = b.cfg.Service
+	b.events = append(b.events, Event{Type: "test_suite_end", Version: 1, Content: suite})
+	return end
+}
+
+type testCase struct {
+	testjson.TestCase
+	status string
+}
+
+// testCases returns every test case of the package, in the order they were
+// run.
+func testCases(pkg *testjson.Package) []testCase {
+	var tcs []testCase
+	for _, group := range []struct {
+		tcs    []testjson.TestCase
+		status string
+	}{
+		{tcs: pkg.Failed, status: statusFail},
+		{tcs: pkg.Skipped, status: statusSkip},
+		{tcs: pkg.Passed, status: statusPass},
+	} {
+		for _, tc := range group.tcs {
+			if tc.Test == "" {
+				// a failure in TestMain is reported by the status of the suite
+				continue
+			}
+			tcs = append(tcs, testCase{TestCase: tc, status: group.status})
+		}
+	}
+	sort.Slice(tcs, func(i, j int) bool {
+		return tcs[i].ID < tcs[j].ID
+	})
+	return tcs
+}
+
+// Send the payload to the CI Visibility intake at url.
+func Send(ctx context.Context, client *http.Client, url, apiKey string, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("dd-api-key", apiKey)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("datadog intake returned %v: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package datadog
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/webhook"
+)
+
+// Intake is the configuration of the CI Visibility intake.
+type Intake struct {
+	URL    string
+	APIKey string
+	Config Config
+}
+
+// IntakeFromEnv returns the configuration of the intake from the environment.
+// The API key is read from DD_API_KEY, the site from DD_SITE, and the service
+// and environment of the events from DD_SERVICE and DD_ENV. The git metadata is
+// read from the DD_GIT_* variables, the variables of the CI system, or the git
+// repository in the current directory.
+func IntakeFromEnv(getenv func(string) string) (Intake, error) {
+	apiKey := getenv("DD_API_KEY")
+	if apiKey == "" {
+		return Intake{}, fmt.Errorf("DD_API_KEY must be set to send test results to Datadog")
+	}
+
+	intakeURL := getenv("DD_CIVISIBILITY_AGENTLESS_URL")
+	if intakeURL == "" {
+		intakeURL = "https://citestcycle-intake." + firstNonEmpty(getenv("DD_SITE"), "datadoghq.com")
+	}
+
+	tags := gitTags(getenv)
+	if jobURL := webhook.JobURL(getenv); jobURL != "" {
+		tags["ci.job.url"] = jobURL
+	}
+	if provider := ciProvider(getenv); provider != "" {
+		tags["ci.provider.name"] = provider
+	}
+
+	service := getenv("DD_SERVICE")
+	if service == "" {
+		service = repositoryName(tags["git.repository_url"])
+	}
+	return Intake{
+		URL:    strings.TrimSuffix(intakeURL, "/") + "/api/v2/citestcycle",
+		APIKey: apiKey,
+		Config: Config{
+			Service: firstNonEmpty(service, "gotestsum"),
+			Env:     firstNonEmpty(getenv("DD_ENV"), "ci"),
+			Tags:    tags,
+		},
+	}, nil
+}
+
+// gitCommandFn runs a git command, and returns the trimmed output. It is
+// replaced in tests.
+var gitCommandFn = func(args ...string) string {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func gitTags(getenv func(string) string) map[string]string {
+	firstEnv := func(names ...string) string {
+		for _, name := range names {
+			if v := getenv(name); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	repo := firstEnv("DD_GIT_REPOSITORY_URL", "CI_REPOSITORY_URL", "CIRCLE_REPOSITORY_URL",
+		"BUILDKITE_REPO", "BUILD_REPOSITORY_URI", "GIT_URL")
+	if repo == "" && getenv("GITHUB_REPOSITORY") != "" {
+		repo = firstNonEmpty(getenv("GITHUB_SERVER_URL"), "https://github.com") + "/" + getenv("GITHUB_REPOSITORY") + ".git"
+	}
+	if repo == "" {
+		repo = gitCommandFn("config", "--get", "remote.origin.url")
+	}
+
+	sha := firstEnv("DD_GIT_COMMIT_SHA", "GITHUB_SHA", "CI_COMMIT_SHA", "CIRCLE_SHA1",
+		"BUILDKITE_COMMIT", "BUILD_SOURCEVERSION", "GIT_COMMIT")
+	if sha == "" {
+		sha = gitCommandFn("rev-parse", "HEAD")
+	}
+
+	branch := firstEnv("DD_GIT_BRANCH", "GITHUB_HEAD_REF", "GITHUB_REF_NAME", "CI_COMMIT_REF_NAME",
+		"CIRCLE_BRANCH", "BUILDKITE_BRANCH", "BUILD_SOURCEBRANCHNAME", "GIT_BRANCH")
+	if branch == "" {
+		branch = gitCommandFn("rev-parse", "--abbrev-ref", "HEAD")
+	}
+
+	tags := make(map[string]string)
+	for key, value := range map[string]string{
+		"git.repository_url": removeCredentials(repo),
+		"git.commit.sha":     sha,
+		"git.branch":         strings.TrimPrefix(branch, "refs/heads/"),
+		"git.tag":            firstEnv("DD_GIT_TAG", "CI_COMMIT_TAG", "CIRCLE_TAG", "BUILDKITE_TAG"),
+	} {
+		if value != "" {
+			tags[key] = value
+		}
+	}
+	return tags
+}
+
+// removeCredentials removes the user and password from a repository URL, for
+// example the job token in CI_REPOSITORY_URL of GitLab CI.
+func removeCredentials(repo string) string {
+	u, err := url.Parse(repo)
+	if err != nil || u.User == nil || u.Scheme == "" {
+		return repo
+	}
+	u.User = nil
+	return u.String()
+}
+
+// repositoryName returns the name of the repository from its URL.
+func repositoryName(repo string) string {
+	if repo == "" {
+		return ""
+	}
+	repo = strings.TrimSuffix(strings.TrimSuffix(repo, "/"), ".git")
+	if i := strings.LastIndexAny(repo, "/:"); i >= 0 {
+		return repo[i+1:]
+	}
+	return path.Base(repo)
+}
+
+func ciProvider(getenv func(string) string) string {
+	switch {
+	case getenv("GITHUB_ACTIONS") != "":
+		return "github"
+	case getenv("GITLAB_CI") != "":
+		return "gitlab"
+	case getenv("CIRCLECI") != "":
+		return "circleci"
+	case getenv("BUILDKITE") != "":
+		return "buildkite"
+	case getenv("TF_BUILD") != "":
+		return "azurepipelines"
+	case getenv("JENKINS_URL") != "":
+		return "jenkins"
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
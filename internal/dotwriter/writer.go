@@ -30,3 +30,12 @@ func New(out io.Writer) *Writer {
 func (w *Writer) Write(buf []byte) (int, error) {
 	return w.buf.Write(buf)
 }
+
+// Persist keeps the first n lines written by the last Flush. The next Flush
+// will only replace the lines written after the first n lines.
+func (w *Writer) Persist(n int) {
+	w.lineCount -= n
+	if w.lineCount < 0 {
+		w.lineCount = 0
+	}
+}
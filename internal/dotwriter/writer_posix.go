@@ -14,9 +14,10 @@ var hide = fmt.Sprintf("%c[?25l", ESC)
 // show cursor
 var show = fmt.Sprintf("%c[?25h", ESC)
 
-// Flush the buffer, writing all buffered lines to out
+// Flush the buffer, writing all buffered lines to out. Flushing an empty buffer
+// clears the lines written by the previous Flush.
 func (w *Writer) Flush() error {
-	if w.buf.Len() == 0 {
+	if w.buf.Len() == 0 && w.lineCount == 0 {
 		return nil
 	}
 	// Hide cursor during write to avoid it moving around the screen
@@ -24,6 +25,11 @@ func (w *Writer) Flush() error {
 
 	// Move up to the top of our last output.
 	w.up(w.lineCount)
+	if w.buf.Len() == 0 {
+		w.clearLines(w.lineCount)
+		w.lineCount = 0
+		return nil
+	}
 	lines := bytes.Split(w.buf.Bytes(), []byte{'\n'})
 	w.lineCount = len(lines) - 1 // Record how many lines we will write for the next Flush()
 	for i, line := range lines {
@@ -63,3 +69,13 @@ func (w *Writer) hideCursor() func() {
 		_, _ = fmt.Fprint(w.out, show)
 	}
 }
+
+// clearLines clears count lines, starting at the current line, and returns the
+// cursor to the current line.
+func (w *Writer) clearLines(count int) {
+	for i := 0; i < count; i++ {
+		w.clearRest()
+		_, _ = w.out.Write([]byte{'\n'})
+	}
+	w.up(count)
+}
@@ -39,7 +39,7 @@ type fdWriter interface {
 // Flush implementation on windows is not ideal; we clear the entire screen before writing, which can result in flashing output
 // Windows likely can adopt the same approach as posix if someone invests some effort
 func (w *Writer) Flush() error {
-	if w.buf.Len() == 0 {
+	if w.buf.Len() == 0 && w.lineCount == 0 {
 		return nil
 	}
 	w.clearLines(w.lineCount)
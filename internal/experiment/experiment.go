@@ -0,0 +1,97 @@
+/*
+Package experiment provides feature flags for experimental features.
+
+Experimental features are disabled by default, and are enabled by setting the
+GOTESTSUM_EXPERIMENT environment variable to a comma separated list of names.
+Experimental features may change, or be removed, in any release.
+
+When an experiment is finished it is retired. The name of a retired experiment
+is still accepted, and prints a warning which explains what happened to the
+feature.
+*/
+package experiment
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/log"
+)
+
+// EnvVar is the name of the environment variable used to enable experiments.
+const EnvVar = "GOTESTSUM_EXPERIMENT"
+
+// Experiment is a feature which is disabled unless it is enabled by
+// GOTESTSUM_EXPERIMENT.
+type Experiment struct {
+	Name        string
+	Description string
+	enabled     bool
+}
+
+// Enabled returns true if the experiment was enabled by Load.
+func (e *Experiment) Enabled() bool {
+	return e.enabled
+}
+
+var (
+	experiments = map[string]*Experiment{}
+	// retired experiments, and a message which explains what happened to the
+	// feature.
+	retired = map[string]string{}
+)
+
+// register a new experiment. register must only be called during package
+// initialization, as part of a var declaration.
+func register(name, description string) *Experiment {
+	if _, exists := experiments[name]; exists {
+		panic(fmt.Sprintf("experiment %v is already registered", name))
+	}
+	e := &Experiment{Name: name, Description: description}
+	experiments[name] = e
+	return e
+}
+
+// retire an experiment. The feature should either be enabled by default, or
+// removed, and the message should say which.
+func retire(name, message string) {
+	delete(experiments, name)
+	retired[name] = message
+}
+
+// Load enables the experiments in value, a comma separated list of names.
+// A warning is printed for each experiment that is enabled, and for any name
+// which is not a known experiment.
+func Load(value string) {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if e, ok := experiments[name]; ok {
+			if !e.enabled {
+				log.Warnf("experiment %v is enabled, it may change or be removed in any release", name)
+			}
+			e.enabled = true
+			continue
+		}
+		if msg, ok := retired[name]; ok {
+			log.Warnf("experiment %v has ended: %v. Remove it from %v", name, msg, EnvVar)
+			continue
+		}
+		log.Warnf("unknown experiment %v in %v", name, EnvVar)
+	}
+}
+
+// All returns all the experiments which are not retired, sorted by name.
+func All() []*Experiment {
+	result := make([]*Experiment, 0, len(experiments))
+	for _, e := range experiments {
+		result = append(result, e)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
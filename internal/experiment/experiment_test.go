@@ -0,0 +1,61 @@
+package experiment
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func patchExperiments(t *testing.T) {
+	t.Helper()
+	origExperiments, origRetired := experiments, retired
+	experiments = map[string]*Experiment{}
+	retired = map[string]string{}
+	t.Cleanup(func() {
+		experiments, retired = origExperiments, origRetired
+	})
+}
+
+func TestLoad(t *testing.T) {
+	patchExperiments(t)
+	board := register("board-format", "a format which shows a board of packages")
+	orchestrator := register("orchestrator", "run packages with a scheduler")
+	other := register("other", "not enabled")
+	retire("old", "the feature is enabled by default")
+
+	Load(" board-format,,orchestrator,old,unknown, board-format")
+	assert.Assert(t, board.Enabled())
+	assert.Assert(t, orchestrator.Enabled())
+	assert.Assert(t, !other.Enabled())
+}
+
+func TestLoad_Empty(t *testing.T) {
+	patchExperiments(t)
+	e := register("example", "an example")
+
+	Load("")
+	assert.Assert(t, !e.Enabled())
+}
+
+func TestAll(t *testing.T) {
+	patchExperiments(t)
+	register("zeta", "last")
+	register("alpha", "first")
+	register("old", "retired")
+	retire("old", "the feature was removed")
+
+	var names []string
+	for _, e := range All() {
+		names = append(names, e.Name)
+	}
+	assert.DeepEqual(t, names, []string{"alpha", "zeta"})
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	patchExperiments(t)
+	register("example", "an example")
+	defer func() {
+		assert.Equal(t, recover(), "experiment example is already registered")
+	}()
+	register("example", "again")
+}
@@ -0,0 +1,56 @@
+/*
+Package failurereport writes the failed tests of a run as markdown, for the
+reports posted by gotestsum tool github-comment and gotestsum tool buildkite.
+*/
+package failurereport
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Failure is a failed test, or a package which failed to run its tests.
+type Failure struct {
+	Name   string
+	Output string
+}
+
+// WriteDetails writes the output of the failure in a collapsed section, with
+// the name of the failure as the summary.
+func WriteDetails(b *strings.Builder, f Failure) {
+	fmt.Fprintf(b, "<details><summary><code>%v</code></summary>\n\n", f.Name)
+	WriteCodeBlock(b, f.Output)
+	b.WriteString("</details>\n")
+}
+
+// WriteCodeBlock writes text in a fenced code block.
+func WriteCodeBlock(b *strings.Builder, text string) {
+	// a fence longer than any run of backticks in the text, so that the
+	// text can not end the code block
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%v\n%v\n%v\n\n", fence, strings.TrimRight(text, "\n"), fence)
+}
+
+// WriteFlaky writes the list of tests which failed, and passed when they were
+// rerun.
+func WriteFlaky(b *strings.Builder, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	b.WriteString("\n#### Flaky tests\n\nThese tests failed, and passed when they were rerun.\n\n")
+	for _, name := range names {
+		fmt.Fprintf(b, "- `%v`\n", name)
+	}
+}
+
+// LastLines joins the last max lines. The last lines are used, because the
+// end of the output of a failed test usually has the reason for the failure.
+func LastLines(lines []string, max int) string {
+	if len(lines) > max {
+		lines = append([]string{"...\n"}, lines[len(lines)-max:]...)
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,33 @@
+package failurereport
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWriteDetails(t *testing.T) {
+	b := new(strings.Builder)
+	WriteDetails(b, Failure{Name: "TestOne", Output: "a ``` fence\n"})
+	expected := "<details><summary><code>TestOne</code></summary>\n\n" +
+		"````\na ``` fence\n````\n\n</details>\n"
+	assert.Equal(t, b.String(), expected)
+}
+
+func TestWriteFlaky(t *testing.T) {
+	b := new(strings.Builder)
+	WriteFlaky(b, nil)
+	assert.Equal(t, b.String(), "")
+
+	WriteFlaky(b, []string{"pkg.TestOne", "pkg.TestTwo"})
+	expected := "\n#### Flaky tests\n\nThese tests failed, and passed when they were rerun.\n\n" +
+		"- `pkg.TestOne`\n- `pkg.TestTwo`\n"
+	assert.Equal(t, b.String(), expected)
+}
+
+func TestLastLines(t *testing.T) {
+	lines := []string{"one\n", "two\n", "three\n"}
+	assert.Equal(t, LastLines(lines, 3), "one\ntwo\nthree\n")
+	assert.Equal(t, LastLines(lines, 2), "...\ntwo\nthree\n")
+}
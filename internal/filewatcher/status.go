@@ -0,0 +1,75 @@
+//go:build !aix
+// +build !aix
+
+package filewatcher
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// statusLine is drawn on the last line of the terminal. The rest of the
+// terminal is set as the scrolling region, so that the output of the tests
+// does not scroll the status line.
+type statusLine struct {
+	out    io.Writer
+	fd     int
+	text   func() string
+	height int
+}
+
+// newStatusLine returns nil if text is nil, or if stdout is not a terminal.
+// All the methods of statusLine do nothing when it is nil.
+func newStatusLine(text func() string) *statusLine {
+	fd := int(os.Stdout.Fd())
+	if text == nil || !term.IsTerminal(fd) {
+		return nil
+	}
+	return &statusLine{out: os.Stdout, fd: fd, text: text}
+}
+
+// Draw the status line, with the number of directories that are watched.
+func (s *statusLine) Draw(watchedDirs int) {
+	if s == nil {
+		return
+	}
+	width, height, err := term.GetSize(s.fd)
+	if err != nil || height < 2 {
+		return
+	}
+	if s.height == 0 {
+		// move the cursor up from the last line, so that the cursor is in the
+		// scrolling region.
+		fmt.Fprint(s.out, "\n\033[1A")
+	}
+	s.height = height
+
+	text := fmt.Sprintf(" %v | watching %d directories", s.text(), watchedDirs)
+	if keyHelp != "" {
+		text += " | keys: " + keyHelp
+	}
+	fmt.Fprint(s.out, renderStatusLine(text, width, height))
+}
+
+// Close removes the status line, and resets the scrolling region.
+func (s *statusLine) Close() {
+	if s == nil || s.height == 0 {
+		return
+	}
+	fmt.Fprintf(s.out, "\0337\033[r\033[%d;1H\033[2K\0338", s.height)
+}
+
+func renderStatusLine(text string, width, height int) string {
+	if runes := []rune(text); len(runes) > width {
+		text = string(runes[:width])
+	}
+	text += strings.Repeat(" ", max(0, width-len([]rune(text))))
+	// save the cursor, set the scrolling region, draw the status line in
+	// reverse video, and restore the cursor.
+	return fmt.Sprintf("\0337\033[1;%dr\033[%d;1H\033[2K\033[7m%s\033[0m\0338",
+		height-1, height, text)
+}
@@ -0,0 +1,25 @@
+//go:build !aix
+// +build !aix
+
+package filewatcher
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRenderStatusLine(t *testing.T) {
+	actual := renderStatusLine("PASS 3 tests", 16, 24)
+	assert.Equal(t, actual, "\0337\033[1;23r\033[24;1H\033[2K\033[7mPASS 3 tests    \033[0m\0338")
+
+	actual = renderStatusLine("FAIL 3 tests, 1 failed", 10, 5)
+	assert.Equal(t, actual, "\0337\033[1;4r\033[5;1H\033[2K\033[7mFAIL 3 tes\033[0m\0338")
+}
+
+func TestStatusLine_Nil(t *testing.T) {
+	var s *statusLine
+	s.Draw(3)
+	s.Close()
+	assert.Assert(t, newStatusLine(nil) == nil)
+}
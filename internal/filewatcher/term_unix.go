@@ -66,6 +66,9 @@ func enableNonBlockingRead(fd int) (func(), error) {
 
 var stdin io.Reader = os.Stdin
 
+// keyHelp lists the keys handled by Monitor, and is shown in the status line.
+const keyHelp = "r rerun, f failed, d debug, u update, a all, l reload"
+
 // Monitor the terminal for key presses. If the key press is associated with an
 // action, an event will be sent to channel returned by Events.
 func (r *terminal) Monitor(ctx context.Context) {
@@ -87,6 +90,8 @@ func (r *terminal) Monitor(ctx context.Context) {
 			r.ch <- Event{resume: chResume, useLastPath: true}
 		case 'd':
 			r.ch <- Event{resume: chResume, useLastPath: true, Debug: true}
+		case 'f':
+			r.ch <- Event{resume: chResume, useLastPath: true, RerunFailed: true}
 		case 'a':
 			r.ch <- Event{resume: chResume, PkgPath: "./..."}
 		case 'l':
@@ -2,6 +2,9 @@ package filewatcher
 
 import "context"
 
+// keyHelp is empty, because keys are not handled on windows.
+const keyHelp = ""
+
 type terminal struct{}
 
 func newTerminal() *terminal {
@@ -11,6 +11,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"gotest.tools/gotestsum/filewatcher"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/fs"
 )
@@ -31,7 +32,7 @@ func TestWatch(t *testing.T) {
 	}
 
 	go func() {
-		err := Watch(ctx, []string{dir.Path()}, false, capture)
+		err := Watch(ctx, filewatcher.Config{Dirs: []string{dir.Path()}}, Options{}, capture)
 		assert.Check(t, err)
 	}()
 
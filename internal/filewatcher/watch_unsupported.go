@@ -7,13 +7,22 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+
+	"gotest.tools/gotestsum/filewatcher"
 )
 
 type Event struct {
-	PkgPath string
-	Debug   bool
+	PkgPath     string
+	Args        []string
+	Debug       bool
+	RerunFailed bool
+}
+
+type Options struct {
+	ClearScreen bool
+	Status      func() string
 }
 
-func Watch(ctx context.Context, dirs []string, clearScreen bool, run func(Event) error) error {
+func Watch(ctx context.Context, cfg filewatcher.Config, opts Options, run func(Event) error) error {
 	return fmt.Errorf("file watching is not supported on %v/%v", runtime.GOOS, runtime.GOARCH)
 }
@@ -0,0 +1,204 @@
+/*
+Package formatexec sends the events of a test run to a command which prints
+them, so that a team can write a format for gotestsum in any language. The
+command is set with --format=exec:command.
+
+Each message is written to the stdin of the command as a JSON object on a
+single line. The type field of the message is one of:
+
+	run_start   sent when the command starts, before any event
+	event       sent for every test2json event, with the results of the run so far
+	stderr      sent for every line that go test wrote to stderr
+	run_end     sent when every test has finished, the last message
+
+The run_start message includes the version of the protocol. Fields may be added
+to the messages without a change to the version, so a command should ignore the
+fields and message types it does not recognize.
+*/
+package formatexec
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// ProtocolVersion is the version of the messages written by Writer.
+const ProtocolVersion = 1
+
+// Values of the type field of a message.
+const (
+	TypeRunStart = "run_start"
+	TypeEvent    = "event"
+	TypeStderr   = "stderr"
+	TypeRunEnd   = "run_end"
+)
+
+// RunStart is the first message of a run.
+type RunStart struct {
+	Type    string    `json:"type"`
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	// Command is the go test command which runs the tests.
+	Command []string `json:"command,omitempty"`
+}
+
+// Event is a test2json event, with the results of the test or package when the
+// event is the end of a test or package.
+type Event struct {
+	Type           string    `json:"type"`
+	Time           time.Time `json:"time"`
+	Action         string    `json:"action"`
+	Package        string    `json:"package"`
+	Test           string    `json:"test,omitempty"`
+	ElapsedSeconds float64   `json:"elapsed_seconds,omitempty"`
+	Output         string    `json:"output,omitempty"`
+	// Attempt is 1 for the first run of the test, and is incremented for
+	// every rerun.
+	Attempt int `json:"attempt"`
+	// TestOutput is all the output of a test which failed, sent with the
+	// fail event of the test, so that the command does not need to keep the
+	// output of every test.
+	TestOutput []string `json:"test_output,omitempty"`
+	// Coverage and Cached are sent with the last event of a package.
+	Coverage string `json:"coverage,omitempty"`
+	Cached   bool   `json:"cached,omitempty"`
+	// Counts of the tests which have finished so far.
+	Counts Counts `json:"counts"`
+}
+
+// Counts of the tests in a run.
+type Counts struct {
+	Total   int `json:"total"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// Stderr is a line of text that go test wrote to stderr.
+type Stderr struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// RunEnd is the last message of a run.
+type RunEnd struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// Status is passed when ExitCode is 0, otherwise failed.
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit_code"`
+	Counts
+	Errors         []string `json:"errors,omitempty"`
+	ElapsedSeconds float64  `json:"elapsed_seconds"`
+}
+
+// timeNow returns the current time. It is replaced in tests.
+var timeNow = time.Now
+
+// Writer writes the messages of a run to an io.Writer. After a write fails,
+// every later write is ignored, and Err returns the error. Stderr may be called
+// at the same time as Format, because stdout and stderr of go test are read
+// in separate goroutines.
+type Writer struct {
+	mu     sync.Mutex // guards enc and err
+	enc    *json.Encoder
+	err    error
+	counts Counts
+}
+
+// NewWriter returns a Writer which writes messages to out.
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{enc: json.NewEncoder(out)}
+}
+
+// Err returns the first error returned by a write.
+func (w *Writer) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *Writer) write(msg interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.enc.Encode(msg)
+	return w.err
+}
+
+// RunStart writes the run_start message.
+func (w *Writer) RunStart(command []string) error {
+	return w.write(RunStart{
+		Type:    TypeRunStart,
+		Version: ProtocolVersion,
+		Time:    timeNow(),
+		Command: command,
+	})
+}
+
+// Format writes an event message. Writer implements testjson.EventFormatter.
+func (w *Writer) Format(event testjson.TestEvent, exec *testjson.Execution) error {
+	msg := Event{
+		Type:           TypeEvent,
+		Time:           event.Time,
+		Action:         string(event.Action),
+		Package:        event.Package,
+		Test:           event.Test,
+		ElapsedSeconds: event.Elapsed,
+		Output:         event.Output,
+		Attempt:        event.RunID + 1,
+	}
+	pkg := exec.Package(event.Package)
+	switch {
+	case !event.Action.IsTerminal() || pkg == nil:
+	case event.PackageEvent():
+		msg.Coverage = pkg.Coverage()
+		msg.Cached = pkg.Cached()
+	default:
+		w.counts.Total++
+		switch event.Action {
+		case testjson.ActionFail:
+			w.counts.Failed++
+			if tc := pkg.LastFailedByName(event.Test); tc.Test != "" {
+				msg.TestOutput = exec.OutputLines(tc)
+			}
+		case testjson.ActionSkip:
+			w.counts.Skipped++
+		}
+	}
+	msg.Counts = w.counts
+	return w.write(msg)
+}
+
+// Stderr writes a stderr message.
+func (w *Writer) Stderr(text string) error {
+	return w.write(Stderr{Type: TypeStderr, Text: text})
+}
+
+// RunEnd writes the run_end message, with the exit code of the tests.
+func (w *Writer) RunEnd(exec *testjson.Execution, exitCode int) error {
+	status := "passed"
+	if exitCode != 0 {
+		status = "failed"
+	}
+	return w.write(RunEnd{
+		Type:     TypeRunEnd,
+		Time:     timeNow(),
+		Status:   status,
+		ExitCode: exitCode,
+		Counts: Counts{
+			Total:   exec.Total(),
+			Failed:  len(exec.Failed()),
+			Skipped: len(exec.Skipped()),
+		},
+		Errors:         exec.Errors(),
+		ElapsedSeconds: exec.Elapsed().Seconds(),
+	})
+}
+
+var _ testjson.EventFormatter = (*Writer)(nil)
@@ -0,0 +1,115 @@
+package formatexec
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestWriter(t *testing.T) {
+	patchTimeNow(t)
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+	assert.NilError(t, w.RunStart([]string{"go", "test", "-json", "./..."}))
+
+	fh, err := os.Open("testdata/input.json")
+	assert.NilError(t, err)
+	defer fh.Close() //nolint:errcheck
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  fh,
+		Handler: handler{w: w},
+	})
+	assert.NilError(t, err)
+	// stderr is scanned separately so that the order of the messages is
+	// always the same
+	exec, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:    strings.NewReader(""),
+		Stderr:    strings.NewReader("# example.com/project/broken\nbroken.go:3:1: syntax error\n"),
+		Execution: exec,
+		Handler:   handler{w: w},
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, w.RunEnd(exec, 1))
+
+	golden.Assert(t, buf.String(), "expected-messages.jsonl")
+}
+
+func TestProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+	patchTimeNow(t)
+	out := filepath.Join(t.TempDir(), "messages.jsonl")
+	stdout := new(bytes.Buffer)
+	proc, err := Start(
+		[]string{"sh", "-c", "tee " + out + " | wc -l | tr -d ' '"},
+		[]string{"go", "test"},
+		Config{Stdout: stdout})
+	assert.NilError(t, err)
+
+	assert.NilError(t, proc.Stderr("build failed"))
+	assert.NilError(t, proc.Close())
+	assert.NilError(t, proc.Close())
+	assert.Equal(t, stdout.String(), "2\n")
+
+	raw, err := os.ReadFile(out)
+	assert.NilError(t, err)
+	expected := `{"type":"run_start","version":1,"time":"2024-01-02T03:04:05Z","command":["go","test"]}
+{"type":"stderr","text":"build failed"}
+`
+	assert.Equal(t, string(raw), expected)
+}
+
+func TestProcess_CommandFails(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+	proc, err := Start([]string{"sh", "-c", "exit 3"}, nil, Config{})
+	assert.NilError(t, err)
+	assert.ErrorContains(t, proc.Close(), "exit status 3")
+}
+
+func TestCommand(t *testing.T) {
+	command, ok, err := Command("pkgname")
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+	assert.Assert(t, command == nil)
+
+	command, ok, err = Command("exec:./render --color 'a b'")
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, command, []string{"./render", "--color", "a b"})
+
+	_, ok, err = Command("exec: ")
+	assert.Assert(t, ok)
+	assert.Error(t, err, "a command is required after exec:")
+}
+
+type handler struct {
+	w *Writer
+}
+
+func (h handler) Event(event testjson.TestEvent, exec *testjson.Execution) error {
+	return h.w.Format(event, exec)
+}
+
+func (h handler) Err(text string) error {
+	return h.w.Stderr(text)
+}
+
+func patchTimeNow(t *testing.T) {
+	orig := timeNow
+	timeNow = func() time.Time {
+		return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+	t.Cleanup(func() { timeNow = orig })
+}
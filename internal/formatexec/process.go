@@ -0,0 +1,84 @@
+package formatexec
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/google/shlex"
+)
+
+// Prefix of the --format value which runs a command.
+const Prefix = "exec:"
+
+// Command returns the command of a --format value, and true when the format
+// runs a command.
+func Command(format string) ([]string, bool, error) {
+	raw, ok := strings.CutPrefix(format, Prefix)
+	if !ok {
+		return nil, false, nil
+	}
+	command, err := shlex.Split(raw)
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid format command %q: %w", raw, err)
+	}
+	if len(command) == 0 {
+		return nil, true, fmt.Errorf("a command is required after %v", Prefix)
+	}
+	return command, true, nil
+}
+
+// Process is a command which receives the messages of a run on its stdin.
+type Process struct {
+	*Writer
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	done  bool
+}
+
+// Config used to start a Process.
+type Config struct {
+	// Stdout and Stderr of the command.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Start the command, and write the run_start message with goTestCommand.
+func Start(command []string, goTestCommand []string, cfg Config) (*Process, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("a command is required")
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = cfg.Stdout
+	cmd.Stderr = cfg.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &Process{Writer: NewWriter(stdin), cmd: cmd, stdin: stdin}
+	_ = p.RunStart(goTestCommand)
+	return p, nil
+}
+
+// Close stdin of the command, and wait for it to exit, so that everything it
+// prints is written before the summary. Close returns an error if a message
+// could not be written, or if the command failed. Calling Close more than once
+// is a no-op.
+func (p *Process) Close() error {
+	if p == nil || p.done {
+		return nil
+	}
+	p.done = true
+	_ = p.stdin.Close()
+	if err := p.cmd.Wait(); err != nil {
+		return err
+	}
+	if err := p.Err(); err != nil {
+		return fmt.Errorf("failed to write to stdin: %w", err)
+	}
+	return nil
+}
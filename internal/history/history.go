@@ -0,0 +1,248 @@
+/*
+Package history stores the results of test runs in a file, so that the results
+of many runs can be queried.
+
+The file is JSON lines, with one Record for every attempt of every test. Each
+run appends its records to the end of the file, so the records are in the order
+the runs finished.
+*/
+package history
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gotest.tools/gotestsum/internal/aggregate"
+	"gotest.tools/gotestsum/internal/webhook"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Record is the result of one attempt of a test in a run.
+type Record struct {
+	RunID    string    `json:"run_id"`
+	RunStart time.Time `json:"run_start"`
+	Package  string    `json:"package"`
+	Test     string    `json:"test"`
+	// Outcome is one of pass, fail, or skip.
+	Outcome        string  `json:"outcome"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	// Attempt is 1 for the first run of the test, and is incremented for every
+	// rerun.
+	Attempt int    `json:"attempt"`
+	Commit  string `json:"commit,omitempty"`
+	Branch  string `json:"branch,omitempty"`
+	JobURL  string `json:"job_url,omitempty"`
+}
+
+// Elapsed returns the elapsed time of the test.
+func (r Record) Elapsed() time.Duration {
+	return time.Duration(r.ElapsedSeconds * float64(time.Second))
+}
+
+// RunInfo is the metadata of a run, which is added to every Record.
+type RunInfo struct {
+	Commit string
+	Branch string
+	JobURL string
+}
+
+// RunInfoFromEnv returns the metadata of the run from the environment variables
+// of the CI system.
+func RunInfoFromEnv(getenv func(string) string) RunInfo {
+	firstEnv := func(names ...string) string {
+		for _, name := range names {
+			if v := getenv(name); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	return RunInfo{
+		Commit: firstEnv("GITHUB_SHA", "CI_COMMIT_SHA", "CIRCLE_SHA1",
+			"BUILDKITE_COMMIT", "BUILD_SOURCEVERSION", "GIT_COMMIT"),
+		Branch: firstEnv("GITHUB_HEAD_REF", "GITHUB_REF_NAME", "CI_COMMIT_REF_NAME",
+			"CIRCLE_BRANCH", "BUILDKITE_BRANCH", "BUILD_SOURCEBRANCHNAME", "GIT_BRANCH"),
+		JobURL: webhook.JobURL(getenv),
+	}
+}
+
+// newRunID returns a random ID for a run. It is replaced in tests.
+var newRunID = func() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// NewRecords returns a Record for every attempt of every test in exec, in the
+// order the tests were run. A failure in TestMain is not a test, and is not
+// included.
+func NewRecords(exec *testjson.Execution, info RunInfo) []Record {
+	runID := newRunID()
+	type result struct {
+		testjson.TestCase
+		outcome string
+	}
+	var results []result
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		var pkgResults []result
+		for _, group := range []struct {
+			tcs     []testjson.TestCase
+			outcome testjson.Action
+		}{
+			{tcs: pkg.Failed, outcome: testjson.ActionFail},
+			{tcs: pkg.Skipped, outcome: testjson.ActionSkip},
+			{tcs: pkg.Passed, outcome: testjson.ActionPass},
+		} {
+			for _, tc := range group.tcs {
+				if tc.Test == "" {
+					continue
+				}
+				pkgResults = append(pkgResults, result{TestCase: tc, outcome: string(group.outcome)})
+			}
+		}
+		sort.Slice(pkgResults, func(i, j int) bool {
+			return pkgResults[i].ID < pkgResults[j].ID
+		})
+		results = append(results, pkgResults...)
+	}
+
+	records := make([]Record, 0, len(results))
+	for _, tc := range results {
+		records = append(records, Record{
+			RunID:          runID,
+			RunStart:       exec.Started().UTC(),
+			Package:        tc.Package,
+			Test:           tc.Test.Name(),
+			Outcome:        tc.outcome,
+			ElapsedSeconds: tc.Elapsed.Seconds(),
+			Attempt:        tc.RunID + 1,
+			Commit:         info.Commit,
+			Branch:         info.Branch,
+			JobURL:         info.JobURL,
+		})
+	}
+	return records
+}
+
+// Append the records to the end of the file at path. The file is created if
+// it does not exist.
+func Append(path string, records []Record) error {
+	_ = os.MkdirAll(filepath.Dir(path), 0o755)
+	fh, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(fh)
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			_ = fh.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = fh.Close()
+		return err
+	}
+	return fh.Close()
+}
+
+// Run is the records of a single run.
+type Run struct {
+	ID      string
+	Start   time.Time
+	Records []Record
+}
+
+// Read the runs from the file at path, in the order they were appended.
+func Read(path string) ([]Run, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close() //nolint:errcheck // file is opened read-only
+	return readRuns(fh)
+}
+
+func readRuns(in io.Reader) ([]Run, error) {
+	var runs []Run
+	index := make(map[string]int)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d: %w", line, err)
+		}
+		i, ok := index[r.RunID]
+		if !ok {
+			i = len(runs)
+			index[r.RunID] = i
+			runs = append(runs, Run{ID: r.RunID, Start: r.RunStart})
+		}
+		runs[i].Records = append(runs[i].Records, r)
+	}
+	return runs, scanner.Err()
+}
+
+// PackageElapsed returns the median elapsed time of each package across runs.
+// The elapsed time of a package in a run is the sum of the elapsed time of the
+// first attempt of its top level tests.
+func PackageElapsed(runs []Run) map[string]time.Duration {
+	times := make(map[string][]time.Duration)
+	for _, run := range runs {
+		byPkg := make(map[string]time.Duration)
+		for _, r := range run.Records {
+			if r.Attempt > 1 || testjson.TestName(r.Test).IsSubTest() {
+				continue
+			}
+			byPkg[r.Package] += r.Elapsed()
+		}
+		for pkg, elapsed := range byPkg {
+			times[pkg] = append(times[pkg], elapsed)
+		}
+	}
+	result := make(map[string]time.Duration, len(times))
+	for pkg, t := range times {
+		result[pkg] = aggregate.Median(t)
+	}
+	return result
+}
+
+// TestElapsed returns the median elapsed time of each top level test across
+// runs, by package and then by test name. Only the first attempt of a test in
+// a run is used.
+func TestElapsed(runs []Run) map[string]map[string]time.Duration {
+	times := make(map[string]map[string][]time.Duration)
+	for _, run := range runs {
+		for _, r := range run.Records {
+			if r.Attempt > 1 || testjson.TestName(r.Test).IsSubTest() {
+				continue
+			}
+			if times[r.Package] == nil {
+				times[r.Package] = make(map[string][]time.Duration)
+			}
+			times[r.Package][r.Test] = append(times[r.Package][r.Test], r.Elapsed())
+		}
+	}
+	result := make(map[string]map[string]time.Duration, len(times))
+	for pkg, tests := range times {
+		result[pkg] = make(map[string]time.Duration, len(tests))
+		for test, t := range tests {
+			result[pkg][test] = aggregate.Median(t)
+		}
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/internal/jsonfile/jsonfiletest"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/golden"
+)
+
+func TestNewRecords(t *testing.T) {
+	patchRunID(t)
+	exec := jsonfiletest.ScanFile(t, jsonfiletest.InputFile, testjson.ScanConfig{})
+	jsonfiletest.ScanFile(t, jsonfiletest.RerunFile, testjson.ScanConfig{Execution: exec, RunID: 1})
+
+	records := NewRecords(exec, RunInfo{Commit: "abc123", Branch: "main"})
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	for _, r := range records {
+		assert.NilError(t, enc.Encode(r))
+	}
+	golden.Assert(t, buf.String(), "expected-records.jsonl")
+}
+
+func TestAppendAndRead(t *testing.T) {
+	patchRunID(t)
+	dir := fs.NewDir(t, "history")
+	path := dir.Join("runs", "history.jsonl")
+
+	exec := jsonfiletest.ScanFile(t, jsonfiletest.InputFile, testjson.ScanConfig{})
+	assert.NilError(t, Append(path, NewRecords(exec, RunInfo{})))
+	assert.NilError(t, Append(path, NewRecords(exec, RunInfo{})))
+
+	runs, err := Read(path)
+	assert.NilError(t, err)
+	assert.Equal(t, len(runs), 2)
+	assert.Equal(t, runs[0].ID, "run-1")
+	assert.Equal(t, runs[1].ID, "run-2")
+	assert.Equal(t, len(runs[1].Records), 5)
+}
+
+func TestPackageElapsedAndTestElapsed(t *testing.T) {
+	record := func(pkg, test string, elapsed float64, attempt int) Record {
+		return Record{Package: pkg, Test: test, ElapsedSeconds: elapsed, Attempt: attempt}
+	}
+	runs := []Run{
+		{Records: []Record{
+			record("one", "TestA", 1, 1),
+			record("one", "TestA/sub", 0.5, 1),
+			record("one", "TestB", 2, 1),
+			record("one", "TestB", 9, 2),
+			record("two", "TestC", 0.1, 1),
+		}},
+		{Records: []Record{
+			record("one", "TestA", 2, 1),
+			record("one", "TestB", 2, 1),
+		}},
+		{Records: []Record{
+			record("one", "TestA", 5, 1),
+			record("one", "TestB", 5, 1),
+		}},
+	}
+	expected := map[string]time.Duration{
+		"one": 4 * time.Second,
+		"two": 100 * time.Millisecond,
+	}
+	assert.DeepEqual(t, PackageElapsed(runs), expected)
+
+	expectedTests := map[string]map[string]time.Duration{
+		"one": {"TestA": 2 * time.Second, "TestB": 2 * time.Second},
+		"two": {"TestC": 100 * time.Millisecond},
+	}
+	assert.DeepEqual(t, TestElapsed(runs), expectedTests)
+}
+
+func TestRunInfoFromEnv(t *testing.T) {
+	env := map[string]string{
+		"GITHUB_SHA":        "abc123",
+		"GITHUB_REF_NAME":   "main",
+		"GITHUB_SERVER_URL": "https://github.com",
+		"GITHUB_REPOSITORY": "owner/repo",
+		"GITHUB_RUN_ID":     "7",
+	}
+	info := RunInfoFromEnv(func(key string) string { return env[key] })
+	expected := RunInfo{
+		Commit: "abc123",
+		Branch: "main",
+		JobURL: "https://github.com/owner/repo/actions/runs/7",
+	}
+	assert.Equal(t, info, expected)
+}
+
+func patchRunID(t *testing.T) {
+	orig := newRunID
+	var count int
+	newRunID = func() string {
+		count++
+		return fmt.Sprintf("run-%d", count)
+	}
+	t.Cleanup(func() { newRunID = orig })
+}
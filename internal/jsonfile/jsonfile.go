@@ -0,0 +1,135 @@
+/*
+Package jsonfile creates and opens the files of test2json events written by
+--jsonfile. A file with a .gz extension is compressed with gzip, and a
+compressed file is decompressed when it is read, whatever its extension.
+*/
+package jsonfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsCompressed returns true if the file at path is written with gzip.
+func IsCompressed(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+// File is a file of events which is being written.
+type File struct {
+	file *os.File
+	gz   *gzip.Writer
+}
+
+// Create the file at path, and any missing parent directories. The file is
+// compressed with gzip when path ends with .gz.
+func Create(path string) (*File, error) {
+	return openFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+}
+
+// Append opens the file at path to add more events to the end of the file.
+// The file is created if it does not exist. A compressed file is continued with
+// a new gzip stream, which is read as if it were part of the first one.
+func Append(path string) (*File, error) {
+	return openFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+}
+
+func openFile(path string, flag int) (*File, error) {
+	_ = os.MkdirAll(filepath.Dir(path), 0o755)
+	f, err := os.OpenFile(path, flag, 0o666)
+	if err != nil {
+		return nil, err
+	}
+	file := &File{file: f}
+	if IsCompressed(path) {
+		file.gz = gzip.NewWriter(f)
+	}
+	return file, nil
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	if f.gz != nil {
+		return f.gz.Write(p)
+	}
+	return f.file.Write(p)
+}
+
+// Sync writes the compressed data buffered by gzip to the file, and commits
+// the file to disk.
+func (f *File) Sync() error {
+	if f.gz != nil {
+		if err := f.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	return f.file.Sync()
+}
+
+// Close the file, and write the end of the gzip stream.
+func (f *File) Close() error {
+	if f.gz != nil {
+		if err := f.gz.Close(); err != nil {
+			_ = f.file.Close()
+			return err
+		}
+	}
+	return f.file.Close()
+}
+
+// Open the file at path for reading. The file is decompressed when it is
+// compressed with gzip.
+func Open(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return readCloser{Reader: r, close: f.Close}, nil
+}
+
+// OpenOrStdin opens the file at path for reading like Open, or reads from
+// os.Stdin when path is empty or "-". Closing the reader does not close
+// os.Stdin.
+func OpenOrStdin(path string) (io.ReadCloser, error) {
+	switch path {
+	case "", "-":
+		in, err := NewReader(os.Stdin)
+		return io.NopCloser(in), err
+	default:
+		return Open(path)
+	}
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// NewReader returns a reader which decompresses r when it starts with the
+// gzip header, or reads r unchanged when it does not.
+func NewReader(r io.Reader) (io.Reader, error) {
+	buf := bufio.NewReader(r)
+	header, err := buf.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(header, gzipMagic) {
+		return buf, nil
+	}
+	return gzip.NewReader(buf)
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error {
+	return r.close()
+}
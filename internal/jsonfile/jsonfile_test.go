@@ -0,0 +1,153 @@
+package jsonfile
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+const events = `{"Action":"run","Package":"example.com/a","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/a","Test":"TestOne"}
+`
+
+func TestCreateAndOpen(t *testing.T) {
+	for _, name := range []string{"out.json", "out.json.gz"} {
+		t.Run(name, func(t *testing.T) {
+			dir := fs.NewDir(t, t.Name())
+			path := filepath.Join(dir.Path(), "new-dir", name)
+
+			f, err := Create(path)
+			assert.NilError(t, err)
+			_, err = io.WriteString(f, events)
+			assert.NilError(t, err)
+			assert.NilError(t, f.Sync())
+			assert.NilError(t, f.Close())
+
+			raw, err := os.ReadFile(path)
+			assert.NilError(t, err)
+			assert.Equal(t, bytes.HasPrefix(raw, gzipMagic), IsCompressed(path))
+
+			in, err := Open(path)
+			assert.NilError(t, err)
+			defer in.Close() //nolint:errcheck
+			out, err := io.ReadAll(in)
+			assert.NilError(t, err)
+			assert.Equal(t, string(out), events)
+		})
+	}
+}
+
+func TestOpen_CompressedWithoutExtension(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	f, err := Create(dir.Join("out.json.gz"))
+	assert.NilError(t, err)
+	_, err = io.WriteString(f, events)
+	assert.NilError(t, err)
+	assert.NilError(t, f.Close())
+	assert.NilError(t, os.Rename(dir.Join("out.json.gz"), dir.Join("out.json")))
+
+	in, err := Open(dir.Join("out.json"))
+	assert.NilError(t, err)
+	defer in.Close() //nolint:errcheck
+	out, err := io.ReadAll(in)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), events)
+}
+
+func TestNewReader_ShortInput(t *testing.T) {
+	for _, input := range []string{"", "{"} {
+		r, err := NewReader(strings.NewReader(input))
+		assert.NilError(t, err)
+		out, err := io.ReadAll(r)
+		assert.NilError(t, err)
+		assert.Equal(t, string(out), input)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	for _, name := range []string{"out.json", "out.json.gz"} {
+		t.Run(name, func(t *testing.T) {
+			dir := fs.NewDir(t, t.Name())
+			path := dir.Join(name)
+
+			for _, line := range strings.SplitAfter(events, "\n")[:2] {
+				f, err := Append(path)
+				assert.NilError(t, err)
+				_, err = io.WriteString(f, line)
+				assert.NilError(t, err)
+				assert.NilError(t, f.Close())
+			}
+
+			in, err := Open(path)
+			assert.NilError(t, err)
+			defer in.Close() //nolint:errcheck
+			out, err := io.ReadAll(in)
+			assert.NilError(t, err)
+			assert.Equal(t, string(out), events)
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	f, err := Create(dir.Join("out.json.gz"))
+	assert.NilError(t, err)
+	_, err = io.WriteString(f, events)
+	assert.NilError(t, err)
+	assert.NilError(t, f.Close())
+
+	exec, err := Scan(dir.Join("out.json.gz"), testjson.ScanConfig{})
+	assert.NilError(t, err)
+	exec, err = Scan(dir.Join("out.json.gz"), testjson.ScanConfig{Execution: exec, RunID: 1})
+	assert.NilError(t, err)
+	assert.Equal(t, len(exec.Package("example.com/a").Passed), 2)
+
+	_, err = Scan(dir.Join("missing.json"), testjson.ScanConfig{})
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestOpenOrStdin(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("stdin.json", events))
+	stdin, err := os.Open(dir.Join("stdin.json"))
+	assert.NilError(t, err)
+	defer stdin.Close() //nolint:errcheck
+
+	orig := os.Stdin
+	os.Stdin = stdin
+	t.Cleanup(func() { os.Stdin = orig })
+
+	in, err := OpenOrStdin("-")
+	assert.NilError(t, err)
+	out, err := io.ReadAll(in)
+	assert.NilError(t, err)
+	assert.NilError(t, in.Close())
+	assert.Equal(t, string(out), events)
+
+	// closing the reader does not close stdin
+	_, err = stdin.Stat()
+	assert.NilError(t, err)
+}
+
+func TestExpand(t *testing.T) {
+	dir := fs.NewDir(t, t.Name(),
+		fs.WithFile("a.json", ""),
+		fs.WithFile("b.json", ""),
+		fs.WithFile("c.log", ""))
+
+	fileNames, err := Expand([]string{dir.Join("c.log"), dir.Join("*.json")})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, fileNames, []string{dir.Join("c.log"), dir.Join("a.json"), dir.Join("b.json")})
+
+	_, err = Expand([]string{dir.Join("*.json"), dir.Join("*.xml")})
+	assert.Error(t, err, "no files match "+dir.Join("*.xml"))
+
+	_, err = Expand([]string{"["})
+	assert.ErrorContains(t, err, "invalid pattern [")
+}
@@ -0,0 +1,34 @@
+/*
+Package jsonfiletest provides the files of test2json events, and the helpers to
+scan them, used by the tests of the report packages. It must only be imported
+by tests.
+*/
+package jsonfiletest
+
+import (
+	"gotest.tools/gotestsum/internal/jsonfile"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+)
+
+// InputFile and RerunFile are a run with some failed tests, and a rerun of
+// the failed tests. The paths are relative to the directory of a package in
+// internal/.
+const (
+	InputFile = "../jsonfile/jsonfiletest/testdata/input.json"
+	RerunFile = "../jsonfile/jsonfiletest/testdata/rerun.json"
+)
+
+type TestingT interface {
+	Helper()
+	assert.TestingT
+}
+
+// ScanFile is jsonfile.Scan for tests. The test fails if the file can not be
+// scanned.
+func ScanFile(t TestingT, path string, config testjson.ScanConfig) *testjson.Execution {
+	t.Helper()
+	exec, err := jsonfile.Scan(path, config)
+	assert.NilError(t, err)
+	return exec
+}
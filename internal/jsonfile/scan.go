@@ -0,0 +1,43 @@
+package jsonfile
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Scan the events in the file at path, using config for everything except the
+// Stdout of the scan. The file is decompressed when it is compressed with gzip.
+func Scan(path string, config testjson.ScanConfig) (*testjson.Execution, error) {
+	fh, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close() //nolint:errcheck // file is opened read-only
+
+	config.Stdout = fh
+	exec, err := testjson.ScanTestOutput(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan testjson from %v: %w", path, err)
+	}
+	return exec, nil
+}
+
+// Expand the glob patterns to the names of the files which match them, in the
+// order of the patterns. An error is returned if a pattern does not match any
+// files.
+func Expand(patterns []string) ([]string, error) {
+	var fileNames []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %v: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %v", pattern)
+		}
+		fileNames = append(fileNames, matches...)
+	}
+	return fileNames, nil
+}
@@ -0,0 +1,108 @@
+package junitxml
+
+import (
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// SuiteGranularity sets what each testsuite in the report contains.
+type SuiteGranularity string
+
+const (
+	// SuitePerPackage creates a testsuite for each Go package.
+	SuitePerPackage SuiteGranularity = "package"
+	// SuitePerTest creates a testsuite for each top-level test, which contains
+	// the test and all of its subtests.
+	SuitePerTest SuiteGranularity = "test"
+	// SingleSuite creates a single testsuite which contains every test.
+	SingleSuite SuiteGranularity = "single"
+)
+
+// SubtestNaming sets how the name and classname of a subtest are formatted.
+type SubtestNaming string
+
+const (
+	// SubtestSlash uses the name of the subtest as reported by go test, for
+	// example TestParent/child.
+	SubtestSlash SubtestNaming = "slash"
+	// SubtestDot replaces each slash in the name of a subtest with a dot, for
+	// example TestParent.child.
+	SubtestDot SubtestNaming = "dot"
+	// SubtestClassname appends the name of the parent test to the classname,
+	// and uses only the last part of the name of the subtest as the name. For
+	// example classname="example.com/pkg.TestParent" name="child".
+	SubtestClassname SubtestNaming = "classname"
+)
+
+func (n SubtestNaming) apply(tc *JUnitTestCase) {
+	switch n {
+	case SubtestDot:
+		tc.Name = strings.ReplaceAll(tc.Name, "/", ".")
+	case SubtestClassname:
+		i := strings.LastIndex(tc.Name, "/")
+		if i < 0 {
+			return
+		}
+		tc.Classname += "." + strings.ReplaceAll(tc.Name[:i], "/", ".")
+		tc.Name = tc.Name[i+1:]
+	}
+}
+
+// testSuites returns a testsuite for each top-level test in the package. The
+// time of each suite is the elapsed time of the top-level test.
+func testSuites(
+	pkgname string,
+	pkg *testjson.Package,
+	cases []JUnitTestCase,
+	cfg Config,
+	version string,
+) []JUnitTestSuite {
+	var order []string
+	byRoot := make(map[string][]JUnitTestCase)
+	for _, tc := range cases {
+		root, _, _ := strings.Cut(tc.Name, "/")
+		if _, ok := byRoot[root]; !ok {
+			order = append(order, root)
+		}
+		byRoot[root] = append(byRoot[root], tc)
+	}
+
+	suites := make([]JUnitTestSuite, 0, len(order))
+	for _, root := range order {
+		suite := newSuite(cfg.FormatTestSuiteName(pkgname)+"."+root, byRoot[root], cfg, version)
+		for _, tc := range byRoot[root] {
+			if tc.Name == root {
+				suite.Time = tc.Time
+			}
+		}
+		suite.Timestamp = cfg.suiteTimestamp(pkg.Start)
+		suites = append(suites, suite)
+	}
+	return suites
+}
+
+// newSuite returns a testsuite with the counts of tests, failures, and skipped
+// tests from cases.
+func newSuite(name string, cases []JUnitTestCase, cfg Config, version string) JUnitTestSuite {
+	if name == "" {
+		name = "gotestsum"
+	}
+	suite := JUnitTestSuite{
+		Name:       name,
+		Tests:      len(cases),
+		Time:       formatDurationAsSeconds(0),
+		Hostname:   cfg.Hostname,
+		Properties: packageProperties(version, cfg.Properties),
+		TestCases:  cases,
+	}
+	for _, tc := range cases {
+		switch {
+		case tc.Failure != nil:
+			suite.Failures++
+		case tc.SkipMessage != nil:
+			suite.Skipped++
+		}
+	}
+	return suite
+}
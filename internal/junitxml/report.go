@@ -10,8 +10,10 @@ import (
 	"os"
 	"os/exec"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"gotest.tools/gotestsum/internal/log"
 	"gotest.tools/gotestsum/testjson"
@@ -32,6 +34,7 @@ type JUnitTestSuites struct {
 // testcases.
 type JUnitTestSuite struct {
 	XMLName    xml.Name        `xml:"testsuite"`
+	Hostname   string          `xml:"hostname,attr,omitempty"`
 	Tests      int             `xml:"tests,attr"`
 	Failures   int             `xml:"failures,attr"`
 	Skipped    int             `xml:"skipped,attr,omitempty"`
@@ -51,11 +54,16 @@ type JUnitTestCase struct {
 	Properties  *JUnitProperties  `xml:"properties,omitempty"`
 	SkipMessage *JUnitSkipMessage `xml:"skipped,omitempty"`
 	Failure     *JUnitFailure     `xml:"failure,omitempty"`
+	// FlakyFailures are the failed attempts of a test which passed when it was
+	// run again.
+	FlakyFailures []JUnitFlakyFailure `xml:"flakyFailure,omitempty"`
+	SystemOut     string              `xml:"system-out,omitempty"`
 }
 
 // JUnitSkipMessage contains the reason why a testcase was skipped.
 type JUnitSkipMessage struct {
-	Message string `xml:"message,attr"`
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
 }
 
 // JUnitProperties is a wrapper for the <properties> tag as
@@ -77,6 +85,14 @@ type JUnitFailure struct {
 	Contents string `xml:",chardata"`
 }
 
+// JUnitFlakyFailure is a failed attempt of a test which passed when it was run
+// again. This element is an extension used by the Maven Surefire plugin.
+type JUnitFlakyFailure struct {
+	Message    string `xml:"message,attr"`
+	Type       string `xml:"type,attr"`
+	StackTrace string `xml:"stackTrace"`
+}
+
 // Config used to write a junit XML document.
 type Config struct {
 	ProjectName             string
@@ -84,6 +100,45 @@ type Config struct {
 	FormatTestCaseClassname FormatFunc
 	HideEmptyPackages       bool
 	HideSkippedTests        bool
+	// IncludeOutput adds the output of passed tests to the report as a
+	// <system-out> element. The output of failed and skipped tests is always
+	// included in the <failure> and <skipped> elements.
+	IncludeOutput bool
+	// MaxSystemOutBytes is the maximum size of each <system-out> element.
+	// Output beyond this size is truncated. Zero means no limit.
+	MaxSystemOutBytes int
+	// MaxOutputBytes is the maximum size of the output of each test, in the
+	// <failure>, <skipped>, <flakyFailure>, and <system-out> elements. The
+	// middle of the output is removed, so that both the start and the end of the
+	// output are kept. Zero means no limit.
+	MaxOutputBytes int
+	// MaxTotalOutputBytes is the maximum size of the output of all the tests in
+	// the document. Once the limit is reached the output of any remaining tests
+	// is replaced by a message with the number of bytes that were removed. Zero
+	// means no limit.
+	MaxTotalOutputBytes int
+	// Properties are added to the <properties> of every testsuite, after the
+	// go.version property.
+	Properties []JUnitProperty
+	// Hostname is set as the hostname attribute of every testsuite.
+	Hostname string
+	// Timestamp is used as the timestamp of every testsuite, in place of the
+	// start time of the package.
+	Timestamp time.Time
+	// FlakyFailures reports the failed attempts of a test which passed when it
+	// was run again (for example by --rerun-fails) as <flakyFailure> elements
+	// of the passed testcase, instead of as separate failed testcases.
+	FlakyFailures bool
+	// SuiteGranularity sets what each testsuite contains. Defaults to
+	// SuitePerPackage.
+	SuiteGranularity SuiteGranularity
+	// SubtestNaming sets how the name and classname of subtests are
+	// formatted. Defaults to SubtestSlash.
+	SubtestNaming SubtestNaming
+	// Quarantined returns true if the test is quarantined. A quarantined
+	// testcase which failed has a quarantined property, so that the failure
+	// can be ignored by tools which read the report.
+	Quarantined func(testjson.TestCase) bool
 	// This is used for tests to have a consistent timestamp
 	customTimestamp string
 	customElapsed   string
@@ -121,8 +176,13 @@ func generate(exec *testjson.Execution, cfg Config) JUnitTestSuites {
 		suites.Time = cfg.customElapsed
 	}
 
+	var allCases []JUnitTestCase
+	limit := &outputLimit{perTest: cfg.MaxOutputBytes, remaining: cfg.MaxTotalOutputBytes}
 	for _, pkgname := range exec.Packages() {
-		pkg := exec.Package(pkgname)
+		// copy the package, so that hiding tests does not change the Execution,
+		// which may be used by other reports, or to write the report again.
+		pkgCopy := *exec.Package(pkgname)
+		pkg := &pkgCopy
 		if cfg.HideEmptyPackages && pkg.IsEmpty() {
 			continue
 		}
@@ -132,24 +192,66 @@ func generate(exec *testjson.Execution, cfg Config) JUnitTestSuites {
 			pkg.Skipped = nil
 		}
 
+		var flaky map[testjson.TestName][]testjson.TestCase
+		var flakyCount int
+		if cfg.FlakyFailures {
+			flaky = flakyAttempts(pkg)
+			flakyCount = countAttempts(flaky)
+			pkg.Total -= flakyCount
+			suites.Tests -= flakyCount
+			suites.Failures -= flakyCount
+		}
+
+		cases := packageTestCases(pkg, cfg, flaky, limit)
+		switch cfg.SuiteGranularity {
+		case SuitePerTest:
+			suites.Suites = append(suites.Suites, testSuites(pkgname, pkg, cases, cfg, version)...)
+			continue
+		case SingleSuite:
+			allCases = append(allCases, cases...)
+			continue
+		}
+
 		junitpkg := JUnitTestSuite{
 			Name:       cfg.FormatTestSuiteName(pkgname),
 			Tests:      pkg.Total,
 			Time:       formatDurationAsSeconds(pkg.Elapsed()),
-			Properties: packageProperties(version),
-			TestCases:  packageTestCases(pkg, cfg.FormatTestCaseClassname),
-			Failures:   len(pkg.Failed),
+			Hostname:   cfg.Hostname,
+			Properties: packageProperties(version, cfg.Properties),
+			TestCases:  cases,
+			Failures:   len(pkg.Failed) - flakyCount,
 			Skipped:    len(pkg.Skipped),
-			Timestamp:  cfg.customTimestamp,
-		}
-		if cfg.customTimestamp == "" {
-			junitpkg.Timestamp = pkg.Start.Format(time.RFC3339)
+			Timestamp:  cfg.suiteTimestamp(pkg.Start),
 		}
 		suites.Suites = append(suites.Suites, junitpkg)
 	}
+	if cfg.SuiteGranularity == SingleSuite {
+		suite := newSuite(cfg.ProjectName, allCases, cfg, version)
+		suite.Time = suites.Time
+		suite.Timestamp = cfg.suiteTimestamp(exec.Started())
+		suites.Suites = []JUnitTestSuite{suite}
+	}
+
+	for i := range suites.Suites {
+		for j := range suites.Suites[i].TestCases {
+			cfg.SubtestNaming.apply(&suites.Suites[i].TestCases[j])
+		}
+	}
 	return suites
 }
 
+// suiteTimestamp returns the timestamp of a testsuite which started at start.
+func (cfg Config) suiteTimestamp(start time.Time) string {
+	switch {
+	case cfg.customTimestamp != "":
+		return cfg.customTimestamp
+	case !cfg.Timestamp.IsZero():
+		return cfg.Timestamp.Format(time.RFC3339)
+	default:
+		return start.Format(time.RFC3339)
+	}
+}
+
 func configWithDefaults(cfg Config) Config {
 	noop := func(v string) string {
 		return v
@@ -167,10 +269,11 @@ func formatDurationAsSeconds(d time.Duration) string {
 	return fmt.Sprintf("%f", d.Seconds())
 }
 
-func packageProperties(goVersion string) []JUnitProperty {
-	return []JUnitProperty{
+func packageProperties(goVersion string, extra []JUnitProperty) []JUnitProperty {
+	properties := []JUnitProperty{
 		{Name: "go.version", Value: goVersion},
 	}
+	return append(properties, extra...)
 }
 
 // goVersion returns the version as reported by the go binary in PATH. This
@@ -193,8 +296,41 @@ func goVersion() string {
 	return strings.TrimPrefix(strings.TrimSpace(string(out)), "go version ")
 }
 
-func packageTestCases(pkg *testjson.Package, formatClassname FormatFunc) []JUnitTestCase {
+// flakyAttempts returns the failed attempts of each test in the package which
+// also passed.
+func flakyAttempts(pkg *testjson.Package) map[testjson.TestName][]testjson.TestCase {
+	passed := make(map[testjson.TestName]bool, len(pkg.Passed))
+	for _, tc := range pkg.Passed {
+		passed[tc.Test] = true
+	}
+	flaky := make(map[testjson.TestName][]testjson.TestCase)
+	for _, tc := range pkg.Failed {
+		if passed[tc.Test] {
+			flaky[tc.Test] = append(flaky[tc.Test], tc)
+		}
+	}
+	return flaky
+}
+
+func countAttempts(attempts map[testjson.TestName][]testjson.TestCase) int {
+	var count int
+	for _, tcs := range attempts {
+		count += len(tcs)
+	}
+	return count
+}
+
+// packageTestCases returns a testcase for each test in the package. Failed
+// attempts of a test in flaky are added to the testcase of the passed test,
+// instead of as failed testcases.
+func packageTestCases(
+	pkg *testjson.Package,
+	cfg Config,
+	flaky map[testjson.TestName][]testjson.TestCase,
+	limit *outputLimit,
+) []JUnitTestCase {
 	cases := []JUnitTestCase{}
+	formatClassname := cfg.FormatTestCaseClassname
 
 	if pkg.TestMainFailed() {
 		var buf bytes.Buffer
@@ -202,16 +338,25 @@ func packageTestCases(pkg *testjson.Package, formatClassname FormatFunc) []JUnit
 		jtc := newJUnitTestCase(testjson.TestCase{Test: "TestMain"}, formatClassname)
 		jtc.Failure = &JUnitFailure{
 			Message:  "Failed",
-			Contents: buf.String(),
+			Contents: limit.apply(buf.String()),
 		}
 		cases = append(cases, jtc)
 	}
 
 	for _, tc := range pkg.Failed {
+		if _, ok := flaky[tc.Test]; ok {
+			continue
+		}
 		jtc := newJUnitTestCase(tc, formatClassname)
 		jtc.Failure = &JUnitFailure{
 			Message:  "Failed",
-			Contents: strings.Join(pkg.OutputLines(tc), ""),
+			Contents: limit.apply(strings.Join(pkg.OutputLines(tc), "")),
+		}
+		if cfg.Quarantined != nil && cfg.Quarantined(tc) {
+			jtc.Properties = addProperty(jtc.Properties, JUnitProperty{Name: "quarantined", Value: "true"})
+		}
+		if fuzz, ok := pkg.TestCaseFuzz(tc); ok {
+			jtc.Properties = addFuzzProperties(jtc.Properties, fuzz)
 		}
 		cases = append(cases, jtc)
 	}
@@ -219,18 +364,123 @@ func packageTestCases(pkg *testjson.Package, formatClassname FormatFunc) []JUnit
 	for _, tc := range pkg.Skipped {
 		jtc := newJUnitTestCase(tc, formatClassname)
 		jtc.SkipMessage = &JUnitSkipMessage{
-			Message: strings.Join(pkg.OutputLines(tc), ""),
+			Message:  pkg.SkipReason(tc),
+			Contents: limit.apply(strings.Join(pkg.OutputLines(tc), "")),
 		}
 		cases = append(cases, jtc)
 	}
 
 	for _, tc := range pkg.Passed {
 		jtc := newJUnitTestCase(tc, formatClassname)
+		for _, attempt := range flaky[tc.Test] {
+			jtc.FlakyFailures = append(jtc.FlakyFailures, JUnitFlakyFailure{
+				Message:    "Failed",
+				StackTrace: limit.apply(strings.Join(pkg.OutputLines(attempt), "")),
+			})
+		}
+		// with -count a test may pass more than once, only report the failed
+		// attempts on the first testcase.
+		delete(flaky, tc.Test)
+		for _, b := range pkg.TestCaseBenchmarks(tc) {
+			jtc.Properties = addBenchmarkProperties(jtc.Properties, b)
+		}
+		if fuzz, ok := pkg.TestCaseFuzz(tc); ok {
+			jtc.Properties = addFuzzProperties(jtc.Properties, fuzz)
+		}
+		if cfg.IncludeOutput {
+			output := strings.Join(pkg.OutputLines(tc), "")
+			jtc.SystemOut = limit.apply(truncateOutput(output, cfg.MaxSystemOutBytes))
+		}
 		cases = append(cases, jtc)
 	}
 	return cases
 }
 
+// truncateOutput returns the first max bytes of output, followed by a line
+// with the number of bytes that were removed. If max is 0 output is returned
+// unmodified.
+func truncateOutput(output string, max int) string {
+	if max <= 0 || len(output) <= max {
+		return output
+	}
+	end := max
+	// do not split a multi-byte character
+	for end > 0 && !utf8.RuneStart(output[end]) {
+		end--
+	}
+	head := output[:end]
+	if !strings.HasSuffix(head, "\n") {
+		head += "\n"
+	}
+	return fmt.Sprintf("%s... %d bytes truncated\n", head, len(output)-end)
+}
+
+// outputLimit limits the size of the output of each test, and the total size
+// of the output of all tests.
+type outputLimit struct {
+	// perTest is the maximum size of the output of each test. Zero means no
+	// limit.
+	perTest int
+	// remaining is the number of bytes of output that may still be added to
+	// the document. Zero means no limit, and once the limit is reached
+	// remaining is negative.
+	remaining int
+}
+
+// apply returns output truncated to the limits, and subtracts the size of the
+// result from the remaining total.
+func (l *outputLimit) apply(output string) string {
+	if output == "" {
+		return output
+	}
+	max := l.perTest
+	if l.remaining != 0 {
+		if l.remaining < 0 {
+			return fmt.Sprintf("... %d bytes truncated, the output limit of the file was reached\n", len(output))
+		}
+		if max <= 0 || l.remaining < max {
+			max = l.remaining
+		}
+	}
+	result := truncateMiddle(output, max)
+	if l.remaining != 0 {
+		l.remaining -= len(result)
+		if l.remaining == 0 {
+			l.remaining = -1
+		}
+	}
+	return result
+}
+
+// truncateMiddle returns at most the first and last max/2 bytes of output,
+// separated by a line with the number of bytes that were removed. Whole lines
+// are kept when possible. If max is 0 output is returned unmodified.
+func truncateMiddle(output string, max int) string {
+	if max <= 0 || len(output) <= max {
+		return output
+	}
+	end := max / 2
+	if i := strings.LastIndex(output[:end], "\n"); i >= 0 {
+		end = i + 1
+	}
+	// do not split a multi-byte character
+	for end > 0 && !utf8.RuneStart(output[end]) {
+		end--
+	}
+	start := len(output) - (max - max/2)
+	if i := strings.Index(output[start:], "\n"); output[start-1] != '\n' && i >= 0 && start+i+1 < len(output) {
+		start += i + 1
+	}
+	for start < len(output) && !utf8.RuneStart(output[start]) {
+		start++
+	}
+	head := output[:end]
+	if head != "" && !strings.HasSuffix(head, "\n") {
+		head += "\n"
+	}
+	return fmt.Sprintf("%s... %d bytes truncated ...\n%s", head, start-end, output[start:])
+}
+
 func newJUnitTestCase(tc testjson.TestCase, formatClassname FormatFunc) JUnitTestCase {
 	return JUnitTestCase{
 		Classname:  formatClassname(tc.Package),
@@ -259,6 +509,52 @@ func encodeAttributes(attributes map[string]string) *JUnitProperties {
 	return &JUnitProperties{Properties: properties}
 }
 
+func addProperty(properties *JUnitProperties, property JUnitProperty) *JUnitProperties {
+	if properties == nil {
+		properties = &JUnitProperties{}
+	}
+	properties.Properties = append(properties.Properties, property)
+	return properties
+}
+
+// addBenchmarkProperties adds a property for the number of iterations, and
+// each metric, of a benchmark result. The name of each property starts with
+// the name of the benchmark printed by go test, because a benchmark run with
+// -cpu has a result for each value of GOMAXPROCS.
+func addBenchmarkProperties(properties *JUnitProperties, b testjson.Benchmark) *JUnitProperties {
+	name := b.NameWithProcs()
+	properties = addProperty(properties, JUnitProperty{
+		Name:  name + " iterations",
+		Value: strconv.FormatInt(b.Iterations, 10),
+	})
+	for _, m := range b.Metrics {
+		properties = addProperty(properties, JUnitProperty{
+			Name:  name + " " + m.Unit,
+			Value: strconv.FormatFloat(m.Value, 'f', -1, 64),
+		})
+	}
+	return properties
+}
+
+// addFuzzProperties adds the progress of a fuzz test run with go test -fuzz,
+// and the path of the failing input when the fuzz test found one.
+func addFuzzProperties(properties *JUnitProperties, fuzz testjson.Fuzz) *JUnitProperties {
+	values := []JUnitProperty{
+		{Name: "fuzz elapsed", Value: fuzz.Elapsed.String()},
+		{Name: "fuzz execs", Value: strconv.FormatInt(fuzz.Execs, 10)},
+		{Name: "fuzz execs/sec", Value: strconv.FormatFloat(fuzz.AverageExecsPerSec(), 'f', 0, 64)},
+		{Name: "fuzz new interesting", Value: strconv.FormatInt(fuzz.NewInteresting, 10)},
+		{Name: "fuzz corpus", Value: strconv.FormatInt(fuzz.Corpus, 10)},
+	}
+	if fuzz.FailingInput != "" {
+		values = append(values, JUnitProperty{Name: "fuzz failing input", Value: fuzz.FailingInput})
+	}
+	for _, property := range values {
+		properties = addProperty(properties, property)
+	}
+	return properties
+}
+
 func write(out io.Writer, suites JUnitTestSuites) error {
 	doc, err := xml.MarshalIndent(suites, "", "\t")
 	if err != nil {
@@ -58,14 +58,196 @@ func TestWrite_HideSkippedTests(t *testing.T) {
 	})
 
 	t.Setenv("GOVERSION", "go7.7.7")
-	err := Write(out, exec, Config{
+	cfg := Config{
 		ProjectName:      "test",
 		HideSkippedTests: true,
 		customTimestamp:  new(time.Time).Format(time.RFC3339),
 		customElapsed:    "2.1",
-	})
+	}
+	err := Write(out, exec, cfg)
 	assert.NilError(t, err)
 	golden.Assert(t, out.String(), "junitxml-report-hide-skipped-tests.golden")
+
+	// the Execution is not changed, so the report can be written again
+	assert.Assert(t, len(exec.Skipped()) > 0)
+	again := new(bytes.Buffer)
+	assert.NilError(t, Write(again, exec, cfg))
+	assert.Equal(t, again.String(), out.String())
+}
+
+func TestWrite_IncludeOutput(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t, testjson.ScanConfig{
+		Stdout:           readTestData(t, "go-test-json.out"),
+		Stderr:           readTestData(t, "go-test-json.err"),
+		KeepPassedOutput: true,
+	})
+
+	t.Setenv("GOVERSION", "go7.7.7")
+	err := Write(out, exec, Config{
+		ProjectName:       "test",
+		IncludeOutput:     true,
+		MaxSystemOutBytes: 100,
+		customTimestamp:   new(time.Time).Format(time.RFC3339),
+		customElapsed:     "2.1",
+	})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "junitxml-report-system-out.golden")
+}
+
+func TestWrite_PropertiesHostnameAndTimestamp(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t, testjson.ScanConfig{
+		Stdout: readTestData(t, "go-test-json.out"),
+		Stderr: readTestData(t, "go-test-json.err"),
+	})
+
+	t.Setenv("GOVERSION", "go7.7.7")
+	err := Write(out, exec, Config{
+		ProjectName: "test",
+		Properties: []JUnitProperty{
+			{Name: "git.sha", Value: "abc123"},
+			{Name: "ci.job", Value: "https://ci.example.com/job/1"},
+		},
+		Hostname:      "build-host-1",
+		Timestamp:     time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC),
+		customElapsed: "2.1",
+	})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "junitxml-report-properties.golden")
+}
+
+func TestWrite_SuiteGranularity(t *testing.T) {
+	testCases := []struct {
+		name        string
+		granularity SuiteGranularity
+		naming      SubtestNaming
+		expected    string
+	}{
+		{
+			name:        "suite per test",
+			granularity: SuitePerTest,
+			expected:    "junitxml-report-suite-per-test.golden",
+		},
+		{
+			name:        "single suite with dot subtests",
+			granularity: SingleSuite,
+			naming:      SubtestDot,
+			expected:    "junitxml-report-single-suite.golden",
+		},
+		{
+			name:     "subtest classname",
+			naming:   SubtestClassname,
+			expected: "junitxml-report-subtest-classname.golden",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			exec := createExecution(t, testjson.ScanConfig{
+				Stdout: readTestData(t, "go-test-json.out"),
+				Stderr: readTestData(t, "go-test-json.err"),
+			})
+
+			t.Setenv("GOVERSION", "go7.7.7")
+			err := Write(out, exec, Config{
+				ProjectName:      "test",
+				SuiteGranularity: tc.granularity,
+				SubtestNaming:    tc.naming,
+				customTimestamp:  new(time.Time).Format(time.RFC3339),
+				customElapsed:    "2.1",
+			})
+			assert.NilError(t, err)
+			golden.Assert(t, out.String(), tc.expected)
+		})
+	}
+}
+
+func TestWrite_FlakyFailures(t *testing.T) {
+	exec := createExecution(t, testjson.ScanConfig{
+		Stdout: readTestData(t, "go-test-json-rerun.out"),
+	})
+	_, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		RunID:     1,
+		Stdout:    readTestData(t, "go-test-json-rerun-2.out"),
+		Execution: exec,
+	})
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	t.Setenv("GOVERSION", "go7.7.7")
+	err = Write(out, exec, Config{
+		ProjectName:     "test",
+		FlakyFailures:   true,
+		customTimestamp: new(time.Time).Format(time.RFC3339),
+		customElapsed:   "2.1",
+	})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "junitxml-report-flaky-failures.golden")
+}
+
+func TestTruncateOutput(t *testing.T) {
+	assert.Equal(t, truncateOutput("short\n", 0), "short\n")
+	assert.Equal(t, truncateOutput("short\n", 6), "short\n")
+	assert.Equal(t, truncateOutput("line one\nline two\n", 9), "line one\n... 9 bytes truncated\n")
+	assert.Equal(t, truncateOutput("line one\nline two\n", 12), "line one\nlin\n... 6 bytes truncated\n")
+	assert.Equal(t, truncateOutput("ééé", 3), "é\n... 4 bytes truncated\n")
+}
+
+func TestWrite_Quarantined(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t, testjson.ScanConfig{
+		Stdout: readTestData(t, "go-test-json.out"),
+		Stderr: readTestData(t, "go-test-json.err"),
+	})
+
+	t.Setenv("GOVERSION", "go7.7.7")
+	err := Write(out, exec, Config{
+		ProjectName:       "test",
+		HideEmptyPackages: true,
+		Quarantined: func(tc testjson.TestCase) bool {
+			return tc.Test.Name() == "TestParallelTheFirst"
+		},
+		customTimestamp: new(time.Time).Format(time.RFC3339),
+		customElapsed:   "2.1",
+	})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "junitxml-report-quarantined.golden")
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	assert.Equal(t, truncateMiddle("short\n", 0), "short\n")
+	assert.Equal(t, truncateMiddle("short\n", 6), "short\n")
+	assert.Equal(t, truncateMiddle("line one\nline two\nline three\n", 22),
+		"line one\n... 9 bytes truncated ...\nline three\n")
+	assert.Equal(t, truncateMiddle("ééé", 4), "é\n... 2 bytes truncated ...\né")
+}
+
+func TestOutputLimit(t *testing.T) {
+	limit := &outputLimit{perTest: 10, remaining: 25}
+	assert.Equal(t, limit.apply("0123456789"), "0123456789")
+	assert.Equal(t, limit.apply(""), "")
+	assert.Equal(t, limit.apply("0123456789abcdef\n"), "01234\n... 7 bytes truncated ...\ncdef\n")
+	assert.Equal(t, limit.apply("0123456789"), "... 10 bytes truncated, the output limit of the file was reached\n")
+}
+
+func TestWrite_MaxOutputBytes(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t, testjson.ScanConfig{
+		Stdout: readTestData(t, "go-test-json.out"),
+		Stderr: readTestData(t, "go-test-json.err"),
+	})
+
+	t.Setenv("GOVERSION", "go7.7.7")
+	err := Write(out, exec, Config{
+		ProjectName:         "test",
+		MaxOutputBytes:      80,
+		MaxTotalOutputBytes: 600,
+		customTimestamp:     new(time.Time).Format(time.RFC3339),
+		customElapsed:       "2.1",
+	})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "junitxml-report-max-output-bytes.golden")
 }
 
 func TestWrite_WithAttributes(t *testing.T) {
@@ -153,3 +335,39 @@ func Test_encodeAttributes(t *testing.T) {
 		})
 	}
 }
+
+func TestWrite_WithBenchmarks(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t, testjson.ScanConfig{
+		Stdout: readTestData(t, "go-test-json-with-benchmarks.out"),
+	})
+
+	t.Setenv("GOVERSION", "go7.7.7")
+	err := Write(out, exec, Config{
+		ProjectName:     "test",
+		customTimestamp: new(time.Time).Format(time.RFC3339),
+		customElapsed:   "2.1",
+	})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "junitxml-report-with-benchmarks.golden")
+}
+
+func TestWrite_WithFuzz(t *testing.T) {
+	for _, name := range []string{"with-fuzz", "with-fuzz-crash"} {
+		t.Run(name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			exec := createExecution(t, testjson.ScanConfig{
+				Stdout: readTestData(t, "go-test-json-"+name+".out"),
+			})
+
+			t.Setenv("GOVERSION", "go7.7.7")
+			err := Write(out, exec, Config{
+				ProjectName:     "test",
+				customTimestamp: new(time.Time).Format(time.RFC3339),
+				customElapsed:   "2.1",
+			})
+			assert.NilError(t, err)
+			golden.Assert(t, out.String(), "junitxml-report-"+name+".golden")
+		})
+	}
+}
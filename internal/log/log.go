@@ -2,6 +2,7 @@ package log
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/fatih/color"
 )
@@ -25,6 +26,19 @@ func SetLevel(l Level) {
 	level = l
 }
 
+// GetLevel returns the level of the global logger.
+func GetLevel() Level {
+	return level
+}
+
+// SetOutput sets the writer of the global logger, and returns the previous
+// writer. The default is stderr.
+func SetOutput(w io.Writer) io.Writer {
+	prev := out
+	out = w
+	return prev
+}
+
 // Warnf prints the message to stderr, with a yellow WARN prefix.
 func Warnf(format string, args ...interface{}) {
 	if level < WarnLevel {
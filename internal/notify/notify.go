@@ -0,0 +1,31 @@
+/*
+Package notify sends a desktop notification using the notification command of
+the operating system. On macOS the notification is sent with osascript, on
+Windows with a PowerShell toast notification, and on other platforms with
+notify-send.
+*/
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Notification to send.
+type Notification struct {
+	Title   string
+	Message string
+}
+
+// Send the notification. Send returns an error if the notification command
+// is not installed, or if it fails.
+func Send(n Notification) error {
+	cmd := command(n)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v failed: %w: %s", cmd.Args[0], err, out)
+	}
+	return nil
+}
+
+// commandFn is used to replace the notification command in tests.
+var commandFn = exec.Command
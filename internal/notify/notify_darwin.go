@@ -0,0 +1,13 @@
+package notify
+
+import "os/exec"
+
+// the title and message are passed as arguments, so that they do not need to
+// be quoted in the script
+const appleScript = `on run argv
+display notification (item 2 of argv) with title (item 1 of argv)
+end run`
+
+func command(n Notification) *exec.Cmd {
+	return commandFn("osascript", "-e", appleScript, n.Title, n.Message)
+}
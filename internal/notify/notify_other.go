@@ -0,0 +1,9 @@
+//go:build !darwin && !windows
+
+package notify
+
+import "os/exec"
+
+func command(n Notification) *exec.Cmd {
+	return commandFn("notify-send", "--app-name", "gotestsum", n.Title, n.Message)
+}
@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestSend(t *testing.T) {
+	var args []string
+	commandFn = func(name string, arg ...string) *exec.Cmd {
+		args = append([]string{name}, arg...)
+		return exec.Command("go", "version")
+	}
+	t.Cleanup(func() { commandFn = exec.Command })
+
+	n := Notification{Title: "Passed", Message: "3 tests run"}
+	assert.NilError(t, Send(n))
+	assert.Assert(t, len(args) > 0)
+
+	cmd := command(n)
+	all := strings.Join(append(args, cmd.Env...), "\n")
+	assert.Assert(t, strings.Contains(all, n.Title), all)
+	assert.Assert(t, strings.Contains(all, n.Message), all)
+}
+
+func TestSend_Fails(t *testing.T) {
+	commandFn = func(string, ...string) *exec.Cmd {
+		return exec.Command("go", "not-a-command")
+	}
+	t.Cleanup(func() { commandFn = exec.Command })
+
+	err := Send(Notification{Title: "Failed"})
+	assert.ErrorContains(t, err, "go failed: exit status 2")
+}
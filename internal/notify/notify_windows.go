@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"os"
+	"os/exec"
+)
+
+// the title and message are read from the environment, so that they do not
+// need to be quoted in the script
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName("text")
+$text.Item(0).AppendChild($template.CreateTextNode($env:GOTESTSUM_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode($env:GOTESTSUM_NOTIFY_MESSAGE)) > $null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier("gotestsum").Show($toast)
+`
+
+func command(n Notification) *exec.Cmd {
+	cmd := commandFn("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(),
+		"GOTESTSUM_NOTIFY_TITLE="+n.Title,
+		"GOTESTSUM_NOTIFY_MESSAGE="+n.Message)
+	return cmd
+}
@@ -0,0 +1,162 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// ExporterConfig is the configuration of the exporter, read from the standard
+// OTEL_* environment variables by ConfigFromEnv.
+type ExporterConfig struct {
+	// Endpoint is the URL that traces are posted to.
+	Endpoint string
+	// Headers are added to the export request.
+	Headers map[string]string
+	Timeout time.Duration
+	Trace   TraceConfig
+}
+
+const defaultEndpoint = "http://localhost:4318"
+
+// ConfigFromEnv returns the configuration of the exporter from the
+// environment. The endpoint, headers, timeout, and protocol are read from the
+// OTEL_EXPORTER_OTLP_* variables, the resource from OTEL_SERVICE_NAME and
+// OTEL_RESOURCE_ATTRIBUTES, and the parent span from TRACEPARENT. Only the
+// http/json protocol is supported.
+func ConfigFromEnv(getenv func(string) string) (ExporterConfig, error) {
+	cfg := ExporterConfig{Timeout: 10 * time.Second}
+
+	protocol := firstNonEmpty(getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"), getenv("OTEL_EXPORTER_OTLP_PROTOCOL"))
+	switch protocol {
+	case "", "http/json":
+	default:
+		return cfg, fmt.Errorf("OTLP protocol %v is not supported, use http/json", protocol)
+	}
+
+	cfg.Endpoint = getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if cfg.Endpoint == "" {
+		base := firstNonEmpty(getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), defaultEndpoint)
+		cfg.Endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+
+	cfg.Headers = make(map[string]string)
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS"} {
+		headers, err := parseKeyValues(getenv(name))
+		if err != nil {
+			return cfg, fmt.Errorf("invalid %v: %w", name, err)
+		}
+		for _, kv := range headers {
+			cfg.Headers[kv[0]] = kv[1]
+		}
+	}
+
+	timeout := firstNonEmpty(getenv("OTEL_EXPORTER_OTLP_TRACES_TIMEOUT"), getenv("OTEL_EXPORTER_OTLP_TIMEOUT"))
+	if timeout != "" {
+		ms, err := strconv.Atoi(timeout)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid OTLP timeout %v: %w", timeout, err)
+		}
+		cfg.Timeout = time.Duration(ms) * time.Millisecond
+	}
+
+	attrs, err := parseKeyValues(getenv("OTEL_RESOURCE_ATTRIBUTES"))
+	if err != nil {
+		return cfg, fmt.Errorf("invalid OTEL_RESOURCE_ATTRIBUTES: %w", err)
+	}
+	serviceName := firstNonEmpty(getenv("OTEL_SERVICE_NAME"), "gotestsum")
+	cfg.Trace.Resource = []KeyValue{stringAttr("service.name", serviceName)}
+	for _, kv := range attrs {
+		if kv[0] == "service.name" {
+			if getenv("OTEL_SERVICE_NAME") == "" {
+				cfg.Trace.Resource[0] = stringAttr("service.name", kv[1])
+			}
+			continue
+		}
+		cfg.Trace.Resource = append(cfg.Trace.Resource, stringAttr(kv[0], kv[1]))
+	}
+
+	cfg.Trace.Parent = parseTraceParent(getenv("TRACEPARENT"))
+	return cfg, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// parseKeyValues parses a list of comma separated key=value pairs, with
+// URL encoded values, as used by the OTEL_* environment variables.
+func parseKeyValues(raw string) ([][2]string, error) {
+	var result [][2]string
+	for _, item := range strings.Split(raw, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not a key=value pair", item)
+		}
+		value, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, [2]string{strings.TrimSpace(key), value})
+	}
+	return result, nil
+}
+
+var traceParentPattern = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+// parseTraceParent returns the span of a W3C traceparent header, or an empty
+// SpanContext if the value is not valid.
+func parseTraceParent(raw string) SpanContext {
+	m := traceParentPattern.FindStringSubmatch(strings.TrimSpace(raw))
+	if m == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: m[1], SpanID: m[2]}
+}
+
+// Export the trace of exec to the endpoint of cfg.
+func Export(ctx context.Context, client *http.Client, exec *testjson.Execution, cfg ExporterConfig) error {
+	body, err := json.Marshal(NewTrace(exec, cfg.Trace))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("OTLP endpoint returned %v: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
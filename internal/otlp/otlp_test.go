@@ -0,0 +1,96 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/internal/jsonfile/jsonfiletest"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestNewTrace(t *testing.T) {
+	patchNewID(t)
+	exec := jsonfiletest.ScanFile(t, jsonfiletest.InputFile, testjson.ScanConfig{})
+	jsonfiletest.ScanFile(t, jsonfiletest.RerunFile, testjson.ScanConfig{Execution: exec, RunID: 1})
+
+	trace := NewTrace(exec, TraceConfig{
+		Resource: []KeyValue{stringAttr("service.name", "gotestsum")},
+		Parent:   SpanContext{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"},
+		Version:  "v1.2.3",
+	})
+	raw, err := json.MarshalIndent(trace, "", "  ")
+	assert.NilError(t, err)
+	golden.Assert(t, string(raw)+"\n", "expected-trace.json")
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	env := map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT":       "https://otlp.example.com/",
+		"OTEL_EXPORTER_OTLP_HEADERS":        "x-api-key=abc%3D,x-team=dev",
+		"OTEL_EXPORTER_OTLP_TRACES_HEADERS": "x-team=ci",
+		"OTEL_EXPORTER_OTLP_TIMEOUT":        "2500",
+		"OTEL_RESOURCE_ATTRIBUTES":          "service.name=other,deployment.environment=ci",
+		"OTEL_SERVICE_NAME":                 "unit-tests",
+		"TRACEPARENT":                       "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	}
+	cfg, err := ConfigFromEnv(func(key string) string { return env[key] })
+	assert.NilError(t, err)
+
+	expected := ExporterConfig{
+		Endpoint: "https://otlp.example.com/v1/traces",
+		Headers:  map[string]string{"x-api-key": "abc=", "x-team": "ci"},
+		Timeout:  2500 * time.Millisecond,
+		Trace: TraceConfig{
+			Resource: []KeyValue{
+				stringAttr("service.name", "unit-tests"),
+				stringAttr("deployment.environment", "ci"),
+			},
+			Parent: SpanContext{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"},
+		},
+	}
+	assert.DeepEqual(t, cfg, expected)
+
+	env = map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "grpc"}
+	_, err = ConfigFromEnv(func(key string) string { return env[key] })
+	assert.Error(t, err, "OTLP protocol grpc is not supported, use http/json")
+}
+
+func TestExport(t *testing.T) {
+	var body TracesData
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Check(t, r.URL.Path == "/v1/traces")
+		assert.Check(t, r.Header.Get("Content-Type") == "application/json")
+		assert.Check(t, r.Header.Get("x-api-key") == "abc")
+		raw, err := io.ReadAll(r.Body)
+		assert.Check(t, err)
+		assert.Check(t, json.Unmarshal(raw, &body))
+	}))
+	t.Cleanup(srv.Close)
+
+	exec := jsonfiletest.ScanFile(t, jsonfiletest.InputFile, testjson.ScanConfig{})
+	cfg := ExporterConfig{
+		Endpoint: srv.URL + "/v1/traces",
+		Headers:  map[string]string{"x-api-key": "abc"},
+		Timeout:  time.Second,
+	}
+	assert.NilError(t, Export(context.Background(), srv.Client(), exec, cfg))
+	assert.Equal(t, len(body.ResourceSpans[0].ScopeSpans[0].Spans), 8)
+}
+
+func patchNewID(t *testing.T) {
+	orig := newID
+	var count int
+	newID = func(n int) string {
+		count++
+		return fmt.Sprintf("%0*x", n*2, count)
+	}
+	t.Cleanup(func() { newID = orig })
+}
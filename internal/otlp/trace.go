@@ -0,0 +1,306 @@
+/*
+Package otlp exports the results of a test run as an OpenTelemetry trace, using
+the OTLP/HTTP protocol with JSON encoding.
+
+The run is the root span of the trace. Each package is a child span of the run,
+and each test is a child span of its package, or of its parent test when it is
+a subtest. When a test is run more than once, for example by --rerun-fails,
+every attempt is a separate span. See
+https://opentelemetry.io/docs/specs/otlp/#otlphttp for the protocol.
+*/
+package otlp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// TracesData is the body of a request to export traces.
+type TracesData struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+// ResourceSpans are the spans of a resource.
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+// Resource is the entity which produced the spans.
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+// ScopeSpans are the spans produced by an instrumentation scope.
+type ScopeSpans struct {
+	Scope Scope  `json:"scope"`
+	Spans []Span `json:"spans"`
+}
+
+// Scope is the instrumentation scope which produced the spans.
+type Scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// Span is a single operation in a trace. TraceID, SpanID, and ParentSpanID
+// are hex encoded.
+type Span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	Status            Status     `json:"status"`
+}
+
+// Status of a span.
+type Status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Values of Status.Code.
+const (
+	StatusUnset = 0
+	StatusOK    = 1
+	StatusError = 2
+)
+
+// spanKindInternal is the kind of every span.
+const spanKindInternal = 1
+
+// KeyValue is an attribute of a span or resource.
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue is the value of an attribute. Only one of the fields is set.
+type AnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func stringAttr(key, value string) KeyValue {
+	return KeyValue{Key: key, Value: AnyValue{StringValue: &value}}
+}
+
+func intAttr(key string, value int) KeyValue {
+	v := strconv.Itoa(value)
+	return KeyValue{Key: key, Value: AnyValue{IntValue: &v}}
+}
+
+// TraceConfig used to create a trace.
+type TraceConfig struct {
+	// Resource attributes, which include the service.name.
+	Resource []KeyValue
+	// Parent is the span of the W3C traceparent of the run. When Parent is
+	// set the trace of the run is part of the parent trace.
+	Parent SpanContext
+	// Version of gotestsum, used as the version of the instrumentation scope.
+	Version string
+}
+
+// SpanContext identifies a span in a trace.
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// newID returns a random hex encoded ID of n bytes. It is replaced in tests.
+var newID = func(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NewTrace returns the spans of the run, its packages, and its tests.
+func NewTrace(exec *testjson.Execution, cfg TraceConfig) TracesData {
+	traceID := cfg.Parent.TraceID
+	if traceID == "" {
+		traceID = newID(16)
+	}
+	b := &traceBuilder{traceID: traceID}
+
+	end := exec.Started().Add(exec.Elapsed())
+	root := b.add(cfg.Parent.SpanID, "go test", exec.Started(), end)
+	root.Attributes = []KeyValue{
+		intAttr("gotestsum.tests.total", exec.Total()),
+		intAttr("gotestsum.tests.failed", len(exec.Failed())),
+		intAttr("gotestsum.tests.skipped", len(exec.Skipped())),
+		intAttr("gotestsum.errors", len(exec.Errors())),
+	}
+	root.Status = runStatus(exec)
+	rootID := root.SpanID
+
+	for _, name := range exec.Packages() {
+		if pkgEnd := b.addPackage(rootID, name, exec.Package(name)); pkgEnd.After(end) {
+			end = pkgEnd
+		}
+	}
+	// the end of the run is extended to the end of the last package, because
+	// the time of the events does not include the elapsed time of the last test.
+	b.spans[0].EndTimeUnixNano = unixNano(end)
+
+	return TracesData{ResourceSpans: []ResourceSpans{{
+		Resource: Resource{Attributes: cfg.Resource},
+		ScopeSpans: []ScopeSpans{{
+			Scope: Scope{Name: "gotest.tools/gotestsum", Version: cfg.Version},
+			Spans: b.spans,
+		}},
+	}}}
+}
+
+type traceBuilder struct {
+	traceID string
+	spans   []Span
+}
+
+// add a span, and return a pointer to the span so that it can be modified
+// before the next span is added.
+func (b *traceBuilder) add(parent, name string, start, end time.Time) *Span {
+	b.spans = append(b.spans, Span{
+		TraceID:           b.traceID,
+		SpanID:            newID(8),
+		ParentSpanID:      parent,
+		Name:              name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: unixNano(start),
+		EndTimeUnixNano:   unixNano(end),
+	})
+	return &b.spans[len(b.spans)-1]
+}
+
+// addPackage adds the spans of a package and its tests, and returns the end
+// time of the package span.
+func (b *traceBuilder) addPackage(parent, name string, pkg *testjson.Package) time.Time {
+	tcs := testCases(pkg)
+
+	// the elapsed time of the package is the time of the last run, so the end
+	// of the span is extended to the end of any earlier test.
+	end := pkg.Start.Add(pkg.Elapsed())
+	for _, tc := range tcs {
+		if tcEnd := tc.Time.Add(tc.Elapsed); tcEnd.After(end) {
+			end = tcEnd
+		}
+	}
+	span := b.add(parent, name, pkg.Start, end)
+	span.Attributes = []KeyValue{
+		stringAttr("test.suite.name", name),
+		stringAttr("test.suite.run.status", packageStatus(pkg)),
+	}
+	if pkg.Result() == testjson.ActionFail {
+		span.Status = Status{Code: StatusError, Message: "package failed"}
+	}
+	pkgSpanID := span.SpanID
+
+	type attempt struct {
+		runID int
+		name  testjson.TestName
+	}
+	spanIDs := make(map[attempt]string)
+	for _, tc := range tcs {
+		parentID := pkgSpanID
+		if tc.Test.IsSubTest() {
+			if id, ok := spanIDs[attempt{runID: tc.RunID, name: testjson.TestName(tc.Test.Parent())}]; ok {
+				parentID = id
+			}
+		}
+		span := b.add(parentID, tc.Test.Name(), tc.Time, tc.Time.Add(tc.Elapsed))
+		span.Attributes = testAttributes(pkg, tc)
+		if tc.result == testjson.ActionFail {
+			span.Status = Status{Code: StatusError, Message: "test failed"}
+		}
+		spanIDs[attempt{runID: tc.RunID, name: tc.Test}] = span.SpanID
+	}
+	return end
+}
+
+type testCase struct {
+	testjson.TestCase
+	result testjson.Action
+}
+
+// testCases returns every test case of the package, in the order they were
+// run, so that a parent test is added before its subtests.
+func testCases(pkg *testjson.Package) []testCase {
+	var tcs []testCase
+	for _, group := range []struct {
+		tcs    []testjson.TestCase
+		result testjson.Action
+	}{
+		{tcs: pkg.Failed, result: testjson.ActionFail},
+		{tcs: pkg.Skipped, result: testjson.ActionSkip},
+		{tcs: pkg.Passed, result: testjson.ActionPass},
+	} {
+		for _, tc := range group.tcs {
+			if tc.Test == "" {
+				// a failure in TestMain is reported by the status of the package
+				continue
+			}
+			tcs = append(tcs, testCase{TestCase: tc, result: group.result})
+		}
+	}
+	sort.Slice(tcs, func(i, j int) bool {
+		return tcs[i].ID < tcs[j].ID
+	})
+	return tcs
+}
+
+func testAttributes(pkg *testjson.Package, tc testCase) []KeyValue {
+	attrs := []KeyValue{
+		stringAttr("test.suite.name", tc.Package),
+		stringAttr("test.case.name", tc.Test.Name()),
+		stringAttr("test.case.result.status", string(tc.result)),
+		intAttr("gotestsum.attempt", tc.RunID+1),
+	}
+	if tc.result == testjson.ActionSkip {
+		if reason := pkg.SkipReason(tc.TestCase); reason != "" {
+			attrs = append(attrs, stringAttr("test.case.skip_reason", reason))
+		}
+	}
+	keys := make([]string, 0, len(tc.Attributes))
+	for key := range tc.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		attrs = append(attrs, stringAttr("test.case.attr."+key, tc.Attributes[key]))
+	}
+	return attrs
+}
+
+func runStatus(exec *testjson.Execution) Status {
+	if len(exec.Failed()) > 0 || len(exec.Errors()) > 0 {
+		return Status{Code: StatusError, Message: "tests failed"}
+	}
+	return Status{Code: StatusOK}
+}
+
+func packageStatus(pkg *testjson.Package) string {
+	switch {
+	case pkg.Result() == testjson.ActionFail:
+		return "fail"
+	case pkg.Result() == testjson.ActionSkip || pkg.IsEmpty():
+		return "skip"
+	default:
+		return "pass"
+	}
+}
+
+func unixNano(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return fmt.Sprintf("%d", t.UnixNano())
+}
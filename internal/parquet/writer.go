@@ -0,0 +1,241 @@
+/*
+Package parquet implements a minimal writer for the Apache Parquet file format.
+
+Only the features required to export test results are supported: a flat
+schema of required columns, a single row group, PLAIN encoding, and no
+compression. The files can be read by any parquet reader.
+*/
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ColumnType is the type of the values in a Column.
+type ColumnType int
+
+const (
+	// String values are stored as a UTF8 BYTE_ARRAY.
+	String ColumnType = iota
+	// Int64 values are stored as INT64.
+	Int64
+	// Double values are stored as DOUBLE.
+	Double
+	// Bool values are stored as BOOLEAN.
+	Bool
+)
+
+// physical types, from the parquet thrift definition
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+)
+
+func (t ColumnType) physicalType() int32 {
+	switch t {
+	case Int64:
+		return typeInt64
+	case Double:
+		return typeDouble
+	case Bool:
+		return typeBoolean
+	default:
+		return typeByteArray
+	}
+}
+
+// Column is a column in the schema of a Table.
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Table is a list of rows. Each row must have one value for each column, and
+// the type of the value must match the type of the column: string, int64,
+// float64, or bool.
+type Table struct {
+	Columns []Column
+	Rows    [][]interface{}
+}
+
+const magic = "PAR1"
+
+// CreatedBy is written to the file metadata.
+var CreatedBy = "gotestsum"
+
+// Write the table to out in the parquet format.
+func Write(out io.Writer, table Table) error {
+	file := new(bytes.Buffer)
+	file.WriteString(magic)
+
+	chunks := make([]columnChunk, 0, len(table.Columns))
+	for i, col := range table.Columns {
+		data, err := encodeColumn(table, i)
+		if err != nil {
+			return err
+		}
+		header := pageHeader(len(table.Rows), len(data))
+		chunk := columnChunk{
+			column: col,
+			offset: int64(file.Len()),
+			size:   int64(len(header) + len(data)),
+		}
+		file.Write(header)
+		file.Write(data)
+		chunks = append(chunks, chunk)
+	}
+
+	meta := fileMetaData(table, chunks)
+	file.Write(meta)
+	if err := binary.Write(file, binary.LittleEndian, uint32(len(meta))); err != nil {
+		return err
+	}
+	file.WriteString(magic)
+
+	_, err := out.Write(file.Bytes())
+	return err
+}
+
+type columnChunk struct {
+	column Column
+	offset int64
+	size   int64
+}
+
+// encodeColumn returns the PLAIN encoded values of the column at index. The
+// columns are all required, so there are no repetition or definition levels.
+func encodeColumn(table Table, index int) ([]byte, error) {
+	col := table.Columns[index]
+	buf := new(bytes.Buffer)
+	var bits byte
+	for rowNum, row := range table.Rows {
+		if len(row) != len(table.Columns) {
+			return nil, fmt.Errorf("row %d has %d values, expected %d", rowNum, len(row), len(table.Columns))
+		}
+		value := row[index]
+		var ok bool
+		switch col.Type {
+		case String:
+			var v string
+			if v, ok = value.(string); ok {
+				_ = binary.Write(buf, binary.LittleEndian, uint32(len(v)))
+				buf.WriteString(v)
+			}
+		case Int64:
+			var v int64
+			if v, ok = value.(int64); ok {
+				_ = binary.Write(buf, binary.LittleEndian, v)
+			}
+		case Double:
+			var v float64
+			if v, ok = value.(float64); ok {
+				_ = binary.Write(buf, binary.LittleEndian, math.Float64bits(v))
+			}
+		case Bool:
+			var v bool
+			if v, ok = value.(bool); ok {
+				if v {
+					bits |= 1 << (rowNum % 8)
+				}
+				if rowNum%8 == 7 {
+					buf.WriteByte(bits)
+					bits = 0
+				}
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("row %d column %v: unexpected value %v (%T)", rowNum, col.Name, value, value)
+		}
+	}
+	if col.Type == Bool && len(table.Rows)%8 != 0 {
+		buf.WriteByte(bits)
+	}
+	return buf.Bytes(), nil
+}
+
+// encodings, from the parquet thrift definition
+const (
+	encodingPlain = 0
+	encodingRLE   = 3
+)
+
+func pageHeader(numValues int, size int) []byte {
+	w := new(compactWriter)
+	w.structBegin()
+	w.i32Field(1, 0) // type: DATA_PAGE
+	w.i32Field(2, int32(size))
+	w.i32Field(3, int32(size))
+	w.structField(5) // data_page_header
+	w.i32Field(1, int32(numValues))
+	w.i32Field(2, encodingPlain)
+	w.i32Field(3, encodingRLE)
+	w.i32Field(4, encodingRLE)
+	w.structEnd()
+	w.structEnd()
+	return w.buf.Bytes()
+}
+
+func fileMetaData(table Table, chunks []columnChunk) []byte {
+	numRows := int64(len(table.Rows))
+
+	w := new(compactWriter)
+	w.structBegin()
+	w.i32Field(1, 1) // version
+
+	w.listField(2, thriftStruct, len(table.Columns)+1) // schema
+	w.structBegin()
+	w.stringField(4, "schema")
+	w.i32Field(5, int32(len(table.Columns)))
+	w.structEnd()
+	for _, col := range table.Columns {
+		w.structBegin()
+		w.i32Field(1, col.Type.physicalType())
+		w.i32Field(3, 0) // repetition_type: REQUIRED
+		w.stringField(4, col.Name)
+		if col.Type == String {
+			w.i32Field(6, 0) // converted_type: UTF8
+		}
+		w.structEnd()
+	}
+
+	w.i64Field(3, numRows)
+
+	var totalSize int64
+	for _, chunk := range chunks {
+		totalSize += chunk.size
+	}
+	w.listField(4, thriftStruct, 1) // row_groups
+	w.structBegin()
+	w.listField(1, thriftStruct, len(chunks)) // columns
+	for _, chunk := range chunks {
+		w.structBegin()
+		w.i64Field(2, chunk.offset) // file_offset
+		w.structField(3)            // meta_data
+		w.i32Field(1, chunk.column.Type.physicalType())
+		w.listField(2, thriftI32, 2)
+		w.varint(encodingPlain)
+		w.varint(encodingRLE)
+		w.listField(3, thriftBinary, 1)
+		w.binary(chunk.column.Name)
+		w.i32Field(4, 0) // codec: UNCOMPRESSED
+		w.i64Field(5, numRows)
+		w.i64Field(6, chunk.size)
+		w.i64Field(7, chunk.size)
+		w.i64Field(9, chunk.offset) // data_page_offset
+		w.structEnd()
+		w.structEnd()
+	}
+	w.i64Field(2, totalSize)
+	w.i64Field(3, numRows)
+	w.structEnd()
+
+	w.stringField(6, CreatedBy)
+	w.structEnd()
+	return w.buf.Bytes()
+}
@@ -0,0 +1,133 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestWrite(t *testing.T) {
+	table := Table{
+		Columns: []Column{
+			{Name: "package", Type: String},
+			{Name: "count", Type: Int64},
+			{Name: "elapsed", Type: Double},
+			{Name: "failed", Type: Bool},
+		},
+		Rows: [][]interface{}{
+			{"example.com/one", int64(3), 0.25, false},
+			{"example.com/two", int64(1), 1.5, true},
+		},
+	}
+	buf := new(bytes.Buffer)
+	assert.NilError(t, Write(buf, table))
+	golden.AssertBytes(t, buf.Bytes(), "table.parquet")
+
+	raw := buf.Bytes()
+	assert.Equal(t, string(raw[:4]), magic)
+	assert.Equal(t, string(raw[len(raw)-4:]), magic)
+
+	metaLen := int(binary.LittleEndian.Uint32(raw[len(raw)-8:]))
+	meta := decodeStruct(t, bytes.NewReader(raw[len(raw)-8-metaLen:len(raw)-8]))
+	assert.Equal(t, meta[1], int64(1))
+	assert.Equal(t, meta[3], int64(2))
+	assert.Equal(t, meta[6], "gotestsum")
+
+	schema := meta[2].([]interface{})
+	assert.Equal(t, len(schema), 5)
+	var names []string
+	for _, elem := range schema {
+		names = append(names, elem.(map[int16]interface{})[4].(string))
+	}
+	assert.DeepEqual(t, names, []string{"schema", "package", "count", "elapsed", "failed"})
+
+	rowGroup := meta[4].([]interface{})[0].(map[int16]interface{})
+	assert.Equal(t, rowGroup[3], int64(2))
+	columns := rowGroup[1].([]interface{})
+	assert.Equal(t, len(columns), 4)
+
+	// the data page of the count column is the PLAIN encoded values
+	colMeta := columns[1].(map[int16]interface{})[3].(map[int16]interface{})
+	offset := colMeta[9].(int64)
+	page := bytes.NewReader(raw[offset:])
+	header := decodeStruct(t, page)
+	assert.Equal(t, header[2], int64(16))
+	values := make([]int64, 2)
+	assert.NilError(t, binary.Read(page, binary.LittleEndian, values))
+	assert.DeepEqual(t, values, []int64{3, 1})
+}
+
+func TestWrite_WrongType(t *testing.T) {
+	table := Table{
+		Columns: []Column{{Name: "count", Type: Int64}},
+		Rows:    [][]interface{}{{"three"}},
+	}
+	err := Write(new(bytes.Buffer), table)
+	assert.Error(t, err, "row 0 column count: unexpected value three (string)")
+}
+
+// decodeStruct decodes a thrift compact struct into a map of field ID to
+// value. It supports only the types written by compactWriter.
+func decodeStruct(t *testing.T, r *bytes.Reader) map[int16]interface{} {
+	t.Helper()
+	result := map[int16]interface{}{}
+	var last int16
+	for {
+		b, err := r.ReadByte()
+		assert.NilError(t, err)
+		if b == 0 {
+			return result
+		}
+		typ := b & 0x0f
+		if delta := int16(b >> 4); delta != 0 {
+			last += delta
+		} else {
+			last = int16(readVarint(t, r))
+		}
+		result[last] = decodeValue(t, r, typ)
+	}
+}
+
+func decodeValue(t *testing.T, r *bytes.Reader, typ byte) interface{} {
+	t.Helper()
+	switch typ {
+	case thriftI32, thriftI64:
+		return readVarint(t, r)
+	case thriftBinary:
+		n, err := binary.ReadUvarint(r)
+		assert.NilError(t, err)
+		buf := make([]byte, n)
+		_, err = r.Read(buf)
+		assert.NilError(t, err)
+		return string(buf)
+	case thriftList:
+		b, err := r.ReadByte()
+		assert.NilError(t, err)
+		size := int(b >> 4)
+		if size == 15 {
+			n, err := binary.ReadUvarint(r)
+			assert.NilError(t, err)
+			size = int(n)
+		}
+		var list []interface{}
+		for i := 0; i < size; i++ {
+			list = append(list, decodeValue(t, r, b&0x0f))
+		}
+		return list
+	case thriftStruct:
+		return decodeStruct(t, r)
+	default:
+		t.Fatalf("unsupported type %d", typ)
+		return nil
+	}
+}
+
+func readVarint(t *testing.T, r *bytes.Reader) int64 {
+	t.Helper()
+	v, err := binary.ReadUvarint(r)
+	assert.NilError(t, err)
+	return int64(v>>1) ^ -int64(v&1)
+}
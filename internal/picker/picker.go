@@ -0,0 +1,303 @@
+/*
+Package picker is an interactive selector for a list of tests. The list is
+filtered by a fuzzy search of the text typed by the user, and one or more tests
+are selected from the filtered list.
+*/
+package picker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// Item is a test which can be picked.
+type Item struct {
+	Package string
+	Test    string
+	// Label is the text shown for the item, and matched by the search.
+	Label string
+}
+
+// ErrCancelled is returned by Run when the user cancels the selection.
+var ErrCancelled = errors.New("cancelled")
+
+// Run the picker in the terminal of in and out, and return the selected items.
+// in must be a terminal.
+func Run(in *os.File, out io.Writer, items []Item) ([]Item, error) {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("stdin is not a terminal")
+	}
+	height := 20
+	if _, h, err := term.GetSize(fd); err == nil && h > 4 {
+		height = h
+	}
+	prev, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	// use the alternate screen, so that the picker is removed when it exits
+	fmt.Fprint(out, "\033[?1049h")
+	defer func() {
+		fmt.Fprint(out, "\033[?1049l")
+		_ = term.Restore(fd, prev)
+	}()
+
+	s := newState(items, height-2)
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Fprint(out, "\033[H\033[2J"+strings.ReplaceAll(s.render(), "\n", "\r\n"))
+		key, err := readKey(reader)
+		if err != nil {
+			return nil, err
+		}
+		switch s.handleKey(key) {
+		case actionDone:
+			return s.result(), nil
+		case actionCancel:
+			return nil, ErrCancelled
+		}
+	}
+}
+
+// Keys which are not a printable character.
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyEnter     = "enter"
+	keyTab       = "tab"
+	keyBackspace = "backspace"
+	keyCancel    = "cancel"
+	keySelectAll = "select-all"
+)
+
+// readKey reads a key press, which is either a printable character, or one of
+// the key constants.
+func readKey(in *bufio.Reader) (string, error) {
+	r, _, err := in.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	switch r {
+	case '\r', '\n':
+		return keyEnter, nil
+	case '\t', ' ':
+		return keyTab, nil
+	case 127, '\b':
+		return keyBackspace, nil
+	case 3, 4: // ctrl-c, ctrl-d
+		return keyCancel, nil
+	case 1: // ctrl-a
+		return keySelectAll, nil
+	case 14: // ctrl-n
+		return keyDown, nil
+	case 16: // ctrl-p
+		return keyUp, nil
+	case 27: // escape
+		if in.Buffered() == 0 {
+			return keyCancel, nil
+		}
+		seq := make([]byte, 2)
+		if _, err := io.ReadFull(in, seq); err != nil {
+			return "", err
+		}
+		switch string(seq) {
+		case "[A", "OA":
+			return keyUp, nil
+		case "[B", "OB":
+			return keyDown, nil
+		}
+		return "", nil
+	}
+	if !unicode.IsPrint(r) {
+		return "", nil
+	}
+	return string(r), nil
+}
+
+type action int
+
+const (
+	actionNone action = iota
+	actionDone
+	actionCancel
+)
+
+// state of the picker.
+type state struct {
+	items    []Item
+	query    string
+	matches  []int
+	cursor   int
+	selected map[int]bool
+	// height is the number of items shown.
+	height int
+}
+
+func newState(items []Item, height int) *state {
+	s := &state{items: items, selected: make(map[int]bool), height: max(height, 1)}
+	s.filter()
+	return s
+}
+
+func (s *state) filter() {
+	type match struct {
+		index int
+		score int
+	}
+	var matches []match
+	for i, item := range s.items {
+		if score, ok := Match(s.query, item.Label); ok {
+			matches = append(matches, match{index: i, score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	s.matches = s.matches[:0]
+	for _, m := range matches {
+		s.matches = append(s.matches, m.index)
+	}
+	s.cursor = 0
+}
+
+func (s *state) handleKey(key string) action {
+	switch key {
+	case "":
+	case keyCancel:
+		return actionCancel
+	case keyEnter:
+		if len(s.selected) == 0 && len(s.matches) > 0 {
+			s.selected[s.matches[s.cursor]] = true
+		}
+		if len(s.selected) > 0 {
+			return actionDone
+		}
+	case keyUp:
+		if s.cursor > 0 {
+			s.cursor--
+		}
+	case keyDown:
+		if s.cursor < len(s.matches)-1 {
+			s.cursor++
+		}
+	case keyTab:
+		if len(s.matches) > 0 {
+			i := s.matches[s.cursor]
+			if s.selected[i] {
+				delete(s.selected, i)
+			} else {
+				s.selected[i] = true
+			}
+			if s.cursor < len(s.matches)-1 {
+				s.cursor++
+			}
+		}
+	case keySelectAll:
+		for _, i := range s.matches {
+			s.selected[i] = true
+		}
+	case keyBackspace:
+		if runes := []rune(s.query); len(runes) > 0 {
+			s.query = string(runes[:len(runes)-1])
+			s.filter()
+		}
+	default:
+		s.query += key
+		s.filter()
+	}
+	return actionNone
+}
+
+// result returns the selected items, in the order of the items.
+func (s *state) result() []Item {
+	var result []Item
+	for i, item := range s.items {
+		if s.selected[i] {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+const keyHelp = "type to search, up/down to move, space to select, ctrl-a select all, enter to run, esc to cancel"
+
+func (s *state) render() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "> %s\n", s.query)
+	fmt.Fprintf(&b, "  %d/%d tests, %d selected | %s\n",
+		len(s.matches), len(s.items), len(s.selected), keyHelp)
+
+	// scroll the list so that the cursor is always visible
+	start := 0
+	if s.cursor >= s.height {
+		start = s.cursor - s.height + 1
+	}
+	end := min(len(s.matches), start+s.height)
+	for pos := start; pos < end; pos++ {
+		i := s.matches[pos]
+		cursor, mark := " ", " "
+		if pos == s.cursor {
+			cursor = ">"
+		}
+		if s.selected[i] {
+			mark = "*"
+		}
+		fmt.Fprintf(&b, "%s%s %s\n", cursor, mark, s.items[i].Label)
+	}
+	return b.String()
+}
+
+// Match returns true if every character of query is in text, in the same
+// order, ignoring case. The score is higher when the characters are closer
+// together, at the start of a word, and when the text is shorter.
+func Match(query, text string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	q := []rune(strings.ToLower(query))
+	t := []rune(text)
+
+	best, found := 0, false
+	for start := range t {
+		if unicode.ToLower(t[start]) != q[0] {
+			continue
+		}
+		if score, ok := matchFrom(q, t, start); ok && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	if !found {
+		return 0, false
+	}
+	return best*100 - len(t), true
+}
+
+// matchFrom matches the characters of q in t, starting at index start of t.
+func matchFrom(q, t []rune, start int) (int, bool) {
+	score, qi, last := 0, 0, -1
+	for ti := start; ti < len(t) && qi < len(q); ti++ {
+		if unicode.ToLower(t[ti]) != q[qi] {
+			continue
+		}
+		switch {
+		case last >= 0 && last == ti-1:
+			score += 3 // consecutive
+		case ti == 0 || !unicode.IsLetter(t[ti-1]) || unicode.IsUpper(t[ti]):
+			score += 2 // start of a word
+		default:
+			score++
+		}
+		last = ti
+		qi++
+	}
+	return score, qi == len(q)
+}
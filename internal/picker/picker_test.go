@@ -0,0 +1,74 @@
+package picker
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestMatch(t *testing.T) {
+	_, ok := Match("tsave", "store.TestSave")
+	assert.Assert(t, ok)
+	_, ok = Match("TSAVE", "store.TestSave")
+	assert.Assert(t, ok)
+	_, ok = Match("saveTest", "store.TestSave")
+	assert.Assert(t, !ok)
+
+	consecutive, _ := Match("save", "store.TestSave")
+	scattered, _ := Match("save", "store.TestStandaloneValue")
+	assert.Assert(t, consecutive > scattered, "%d <= %d", consecutive, scattered)
+}
+
+func TestState(t *testing.T) {
+	items := []Item{
+		{Package: "example.com/api", Test: "TestHandler", Label: "api.TestHandler"},
+		{Package: "example.com/api", Test: "TestRoutes", Label: "api.TestRoutes"},
+		{Package: "example.com/store", Test: "TestSave", Label: "store.TestSave"},
+		{Package: "example.com/store", Test: "TestSaveAll", Label: "store.TestSaveAll"},
+		{Package: "example.com/store", Test: "TestLoad", Label: "store.TestLoad"},
+	}
+	s := newState(items, 3)
+	for _, key := range []string{"s", "a", "v", keyTab, keyBackspace} {
+		assert.Equal(t, s.handleKey(key), actionNone)
+	}
+	golden.Assert(t, s.render(), "state.golden")
+
+	assert.Equal(t, s.handleKey(keyEnter), actionDone)
+	assert.DeepEqual(t, s.result(), []Item{items[2]})
+
+	t.Run("enter selects the item at the cursor", func(t *testing.T) {
+		s := newState(items, 3)
+		s.handleKey(keyDown)
+		assert.Equal(t, s.handleKey(keyEnter), actionDone)
+		assert.DeepEqual(t, s.result(), []Item{items[1]})
+	})
+	t.Run("select all matches", func(t *testing.T) {
+		s := newState(items, 3)
+		for _, key := range []string{"s", "t", "o", "r", "e", ".", keySelectAll} {
+			s.handleKey(key)
+		}
+		assert.Equal(t, s.handleKey(keyEnter), actionDone)
+		assert.DeepEqual(t, s.result(), items[2:])
+	})
+	t.Run("enter with no matches", func(t *testing.T) {
+		s := newState(items, 3)
+		s.handleKey("z")
+		assert.Equal(t, s.handleKey(keyEnter), actionNone)
+		assert.Equal(t, s.handleKey(keyCancel), actionCancel)
+	})
+}
+
+func TestReadKey(t *testing.T) {
+	in := bufio.NewReader(strings.NewReader("a\x1b[A\x1b[B\r \x7f\x03"))
+	var keys []string
+	for range 7 {
+		key, err := readKey(in)
+		assert.NilError(t, err)
+		keys = append(keys, key)
+	}
+	expected := []string{"a", keyUp, keyDown, keyEnter, keyTab, keyBackspace, keyCancel}
+	assert.DeepEqual(t, keys, expected)
+}
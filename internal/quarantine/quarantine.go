@@ -0,0 +1,70 @@
+/*
+Package quarantine reads a list of tests which are known to be flaky. The tests
+are still run, but a failure of a quarantined test does not fail the run.
+
+Each line of the file is the name of a test, prefixed by the import path of the
+package and a dot. The import path may be relative to the Go module in the
+working directory. A quarantined test also quarantines all of its subtests.
+Blank lines, and lines which start with #, are ignored.
+
+	# flaky since the database upgrade
+	example.com/app/store.TestSave
+	api.TestHandler/slow_client
+*/
+package quarantine
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// List of quarantined tests.
+type List struct {
+	tests map[string]bool
+}
+
+// Load the list of quarantined tests from the file at path. If path is empty
+// Load returns a nil List, which contains no tests.
+func Load(path string) (*List, error) {
+	if path == "" {
+		return nil, nil
+	}
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantine file: %w", err)
+	}
+	defer fh.Close() //nolint:errcheck // file is opened read-only
+
+	list := &List{tests: make(map[string]bool)}
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list.tests[strings.TrimPrefix(line, "./")] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read quarantine file: %w", err)
+	}
+	return list, nil
+}
+
+// Contains returns true if the test, or the parent of a subtest, is in the
+// list.
+func (l *List) Contains(tc testjson.TestCase) bool {
+	if l == nil || len(l.tests) == 0 {
+		return false
+	}
+	relPkg := testjson.RelativePackagePath(tc.Package)
+	for name := tc.Test.Name(); name != ""; name = testjson.TestName(name).Parent() {
+		if l.tests[tc.Package+"."+name] || l.tests[relPkg+"."+name] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package quarantine
+
+import (
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestLoad(t *testing.T) {
+	file := fs.NewFile(t, t.Name(), fs.WithContent(`
+# flaky since the database upgrade
+example.com/app/store.TestSave
+
+./api.TestHandler/slow_client
+`))
+	list, err := Load(file.Path())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, list.tests, map[string]bool{
+		"example.com/app/store.TestSave": true,
+		"api.TestHandler/slow_client":    true,
+	})
+}
+
+func TestLoad_NoPath(t *testing.T) {
+	list, err := Load("")
+	assert.NilError(t, err)
+	assert.Assert(t, !list.Contains(testjson.TestCase{Package: "pkg", Test: "TestOne"}))
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	_, err := Load("./does-not-exist")
+	assert.ErrorContains(t, err, "failed to read quarantine file")
+}
+
+func TestList_Contains(t *testing.T) {
+	list := &List{tests: map[string]bool{
+		"example.com/app/store.TestSave": true,
+		"api.TestHandler/slow_client":    true,
+	}}
+
+	type testCase struct {
+		pkg      string
+		test     testjson.TestName
+		expected bool
+	}
+	for _, tc := range []testCase{
+		{pkg: "example.com/app/store", test: "TestSave", expected: true},
+		{pkg: "example.com/app/store", test: "TestSave/with_cache", expected: true},
+		{pkg: "example.com/app/store", test: "TestSaveAll", expected: false},
+		{pkg: "example.com/app/store", test: "TestLoad", expected: false},
+		{pkg: "example.com/other/store", test: "TestSave", expected: false},
+		{pkg: "api", test: "TestHandler", expected: false},
+		{pkg: "api", test: "TestHandler/slow_client", expected: true},
+		{pkg: "api", test: "TestHandler/slow_client/retry", expected: true},
+		{pkg: "api", test: "TestHandler/fast_client", expected: false},
+	} {
+		t.Run(tc.pkg+"."+string(tc.test), func(t *testing.T) {
+			actual := list.Contains(testjson.TestCase{Package: tc.pkg, Test: tc.test})
+			assert.Equal(t, actual, tc.expected)
+		})
+	}
+}
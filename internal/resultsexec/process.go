@@ -0,0 +1,64 @@
+package resultsexec
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// Process is a command which receives the messages of a run on its stdin.
+type Process struct {
+	*Writer
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	done  bool
+}
+
+// Config used to start a Process.
+type Config struct {
+	// Stdout and Stderr of the command.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Env is added to the environment of the command.
+	Env []string
+}
+
+// Start the command, and write the run_start message with goTestCommand.
+func Start(command []string, goTestCommand []string, cfg Config) (*Process, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("a command is required")
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = cfg.Stdout
+	cmd.Stderr = cfg.Stderr
+	cmd.Env = append(os.Environ(), cfg.Env...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &Process{Writer: NewWriter(stdin), cmd: cmd, stdin: stdin}
+	p.RunStart(goTestCommand)
+	return p, nil
+}
+
+// Close stdin of the command, and wait for it to exit. Close returns an error
+// if a message could not be written, or if the command failed. Calling Close
+// more than once is a no-op.
+func (p *Process) Close() error {
+	if p == nil || p.done {
+		return nil
+	}
+	p.done = true
+	_ = p.stdin.Close()
+	if err := p.cmd.Wait(); err != nil {
+		return err
+	}
+	if err := p.Err(); err != nil {
+		return fmt.Errorf("failed to write to stdin: %w", err)
+	}
+	return nil
+}
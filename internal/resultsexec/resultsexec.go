@@ -0,0 +1,147 @@
+/*
+Package resultsexec streams the results of a test run to the stdin of a
+command, so that the results can be sent to systems which gotestsum does not
+have a client for.
+
+Each message is a JSON object on a single line. The type field of the message
+is one of:
+
+	run_start   sent when the command starts, before any test has run
+	test_done   sent when a test passes, fails, or is skipped
+	run_end     sent when the run has completed, after every test_done
+
+The run_start message includes the version of the protocol. Fields may be added
+to the messages without a change to the version, so a command should ignore the
+fields and message types it does not recognize.
+*/
+package resultsexec
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// ProtocolVersion is the version of the messages written by Writer.
+const ProtocolVersion = 1
+
+// Values of the type field of a message.
+const (
+	TypeRunStart = "run_start"
+	TypeTestDone = "test_done"
+	TypeRunEnd   = "run_end"
+)
+
+// RunStart is the first message of a run.
+type RunStart struct {
+	Type    string    `json:"type"`
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	// Command is the go test command which runs the tests.
+	Command []string `json:"command,omitempty"`
+}
+
+// TestDone is sent when a test passes, fails, or is skipped. When a test is run
+// more than once, for example by --rerun-fails, a message is sent for every
+// attempt.
+type TestDone struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Package string    `json:"package"`
+	Test    string    `json:"test"`
+	// Outcome is one of pass, fail, or skip.
+	Outcome        string  `json:"outcome"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	// Attempt is 1 for the first run of the test, and is incremented for
+	// every rerun.
+	Attempt int `json:"attempt"`
+}
+
+// RunEnd is the last message of a run.
+type RunEnd struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// Status is passed when ExitCode is 0, otherwise failed.
+	Status         string  `json:"status"`
+	ExitCode       int     `json:"exit_code"`
+	Total          int     `json:"total"`
+	Failed         int     `json:"failed"`
+	Skipped        int     `json:"skipped"`
+	Errors         int     `json:"errors"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+}
+
+// timeNow returns the current time. It is replaced in tests.
+var timeNow = time.Now
+
+// Writer writes the messages of a run to an io.Writer. After a write fails,
+// every later write is ignored, and Err returns the error.
+type Writer struct {
+	enc *json.Encoder
+	err error
+}
+
+// NewWriter returns a Writer which writes messages to out.
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{enc: json.NewEncoder(out)}
+}
+
+// Err returns the first error returned by a write.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+func (w *Writer) write(msg interface{}) {
+	if w.err != nil {
+		return
+	}
+	w.err = w.enc.Encode(msg)
+}
+
+// RunStart writes the run_start message.
+func (w *Writer) RunStart(command []string) {
+	w.write(RunStart{
+		Type:    TypeRunStart,
+		Version: ProtocolVersion,
+		Time:    timeNow(),
+		Command: command,
+	})
+}
+
+// Event writes a test_done message when the event is the end of a test. All
+// other events are ignored.
+func (w *Writer) Event(event testjson.TestEvent) {
+	if event.PackageEvent() || !event.Action.IsTerminal() {
+		return
+	}
+	w.write(TestDone{
+		Type:           TypeTestDone,
+		Time:           event.Time,
+		Package:        event.Package,
+		Test:           event.Test,
+		Outcome:        string(event.Action),
+		ElapsedSeconds: event.Elapsed,
+		Attempt:        event.RunID + 1,
+	})
+}
+
+// RunEnd writes the run_end message, with the exit code of gotestsum.
+func (w *Writer) RunEnd(exec *testjson.Execution, exitCode int) {
+	status := "passed"
+	if exitCode != 0 {
+		status = "failed"
+	}
+	w.write(RunEnd{
+		Type:           TypeRunEnd,
+		Time:           timeNow(),
+		Status:         status,
+		ExitCode:       exitCode,
+		Total:          exec.Total(),
+		Failed:         len(exec.Failed()),
+		Skipped:        len(exec.Skipped()),
+		Errors:         len(exec.Errors()),
+		ElapsedSeconds: exec.Elapsed().Seconds(),
+	})
+}
@@ -0,0 +1,87 @@
+package resultsexec
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"gotest.tools/gotestsum/internal/jsonfile/jsonfiletest"
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestWriter(t *testing.T) {
+	patchTimeNow(t)
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+	w.RunStart([]string{"go", "test", "-json", "./..."})
+
+	exec := jsonfiletest.ScanFile(t, jsonfiletest.InputFile, testjson.ScanConfig{Handler: handler{w: w}})
+	jsonfiletest.ScanFile(t, jsonfiletest.RerunFile, testjson.ScanConfig{
+		Execution: exec,
+		RunID:     1,
+		Handler:   handler{w: w},
+	})
+	w.RunEnd(exec, 1)
+
+	assert.NilError(t, w.Err())
+	golden.Assert(t, buf.String(), "expected-messages.jsonl")
+}
+
+func TestProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+	patchTimeNow(t)
+	out := filepath.Join(t.TempDir(), "messages.jsonl")
+	proc, err := Start([]string{"sh", "-c", "cat > " + out}, []string{"go", "test"}, Config{})
+	assert.NilError(t, err)
+
+	exec := jsonfiletest.ScanFile(t, jsonfiletest.RerunFile, testjson.ScanConfig{Handler: handler{w: proc.Writer}})
+	proc.RunEnd(exec, 0)
+	assert.NilError(t, proc.Close())
+	assert.NilError(t, proc.Close())
+
+	raw, err := os.ReadFile(out)
+	assert.NilError(t, err)
+	expected := `{"type":"run_start","version":1,"time":"2024-01-02T03:04:05Z","command":["go","test"]}
+{"type":"test_done","time":"2024-01-02T03:04:06Z","package":"example.com/project/cart","test":"TestCheckout","outcome":"pass","elapsed_seconds":0.2,"attempt":1}
+{"type":"run_end","time":"2024-01-02T03:04:05Z","status":"passed","exit_code":0,"total":1,"failed":0,"skipped":0,"errors":0,"elapsed_seconds":0}
+`
+
+	assert.Equal(t, string(raw), expected)
+}
+
+func TestProcess_CommandFails(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+	proc, err := Start([]string{"sh", "-c", "exit 3"}, nil, Config{})
+	assert.NilError(t, err)
+	assert.ErrorContains(t, proc.Close(), "exit status 3")
+}
+
+type handler struct {
+	w *Writer
+}
+
+func (h handler) Event(event testjson.TestEvent, _ *testjson.Execution) error {
+	h.w.Event(event)
+	return nil
+}
+
+func (h handler) Err(string) error {
+	return nil
+}
+
+func patchTimeNow(t *testing.T) {
+	orig := timeNow
+	timeNow = func() time.Time {
+		return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+	t.Cleanup(func() { timeNow = orig })
+}
@@ -0,0 +1,222 @@
+/*
+Package sonar creates a SonarQube Generic Test Execution report from a
+testjson.Execution.
+
+SonarQube requires each test to belong to a test file. The file of each test is
+found by searching the _test.go files in the directory of the package for the
+function which declares the top-level test. See
+https://docs.sonarsource.com/sonarqube/latest/analyzing-source-code/test-coverage/generic-test-data/
+for the format.
+*/
+package sonar
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// TestExecutions is the root element of the report.
+type TestExecutions struct {
+	XMLName xml.Name `xml:"testExecutions"`
+	Version int      `xml:"version,attr"`
+	Files   []File   `xml:"file"`
+}
+
+// File is a test file, and the tests declared in the file.
+type File struct {
+	Path      string     `xml:"path,attr"`
+	TestCases []TestCase `xml:"testCase"`
+}
+
+// TestCase is the result of a single test. Duration is in milliseconds.
+type TestCase struct {
+	Name     string   `xml:"name,attr"`
+	Duration int64    `xml:"duration,attr"`
+	Skipped  *Message `xml:"skipped,omitempty"`
+	Failure  *Message `xml:"failure,omitempty"`
+	Error    *Message `xml:"error,omitempty"`
+}
+
+// Message is the reason a test was skipped or failed, and the output of the
+// test.
+type Message struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// Config used to write a report.
+type Config struct {
+	// PackageDir returns the directory of a package from the import path of the
+	// package. The path is used to find test files, and is written to the
+	// report, so it should be relative to the root of the SonarQube project.
+	// Defaults to testjson.RelativePackagePath.
+	PackageDir func(pkgpath string) string
+}
+
+// Write creates an XML document and writes it to out.
+func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
+	if cfg.PackageDir == nil {
+		cfg.PackageDir = testjson.RelativePackagePath
+	}
+	doc := generate(exec, cfg)
+	raw, err := xml.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return fmt.Errorf("failed to write SonarQube XML: %w", err)
+	}
+	if _, err := out.Write(raw); err != nil {
+		return fmt.Errorf("failed to write SonarQube XML: %w", err)
+	}
+	_, err = out.Write([]byte("\n"))
+	return err
+}
+
+func generate(exec *testjson.Execution, cfg Config) TestExecutions {
+	files := make(map[string]*File)
+	for _, pkgname := range exec.Packages() {
+		pkg := exec.Package(pkgname)
+		if pkg.IsEmpty() {
+			continue
+		}
+		dir := cfg.PackageDir(pkgname)
+		testFiles, err := IndexTestFiles(dir)
+		if err != nil {
+			log.Warnf("failed to find test files for package %v: %v", pkgname, err)
+			continue
+		}
+
+		for _, tc := range packageTestCases(pkg) {
+			root, _ := testjson.TestName(tc.Name).Split()
+			file, ok := testFiles[root]
+			if !ok {
+				log.Debugf("no test file found for %v.%v", pkgname, tc.Name)
+				continue
+			}
+			addTestCase(files, path.Join(filepath.ToSlash(dir), file), tc)
+		}
+	}
+
+	doc := TestExecutions{Version: 1}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		doc.Files = append(doc.Files, *files[p])
+	}
+	return doc
+}
+
+func addTestCase(files map[string]*File, filePath string, tc TestCase) {
+	file, ok := files[filePath]
+	if !ok {
+		file = &File{Path: filePath}
+		files[filePath] = file
+	}
+	file.TestCases = append(file.TestCases, tc)
+}
+
+// packageTestCases returns a test case for each test in the package. When a
+// test was run more than once, for example by --rerun-fails, only the result
+// of the last attempt is included.
+func packageTestCases(pkg *testjson.Package) []TestCase {
+	type result struct {
+		id int
+		tc TestCase
+	}
+	latest := make(map[testjson.TestName]result)
+	add := func(tc testjson.TestCase, testCase TestCase) {
+		if prev, ok := latest[tc.Test]; ok && prev.id > tc.ID {
+			return
+		}
+		latest[tc.Test] = result{id: tc.ID, tc: testCase}
+	}
+
+	for _, tc := range pkg.Failed {
+		testCase := newTestCase(tc)
+		testCase.Failure = &Message{
+			Message:  "Failed",
+			Contents: strings.Join(pkg.OutputLines(tc), ""),
+		}
+		add(tc, testCase)
+	}
+	for _, tc := range pkg.Skipped {
+		testCase := newTestCase(tc)
+		testCase.Skipped = &Message{
+			Message:  pkg.SkipReason(tc),
+			Contents: strings.Join(pkg.OutputLines(tc), ""),
+		}
+		add(tc, testCase)
+	}
+	for _, tc := range pkg.Passed {
+		add(tc, newTestCase(tc))
+	}
+
+	results := make([]result, 0, len(latest)+1)
+	for _, r := range latest {
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].id < results[j].id
+	})
+
+	cases := make([]TestCase, 0, len(results)+1)
+	if pkg.TestMainFailed() {
+		cases = append(cases, TestCase{
+			Name:  "TestMain",
+			Error: &Message{Message: "TestMain failed", Contents: pkg.Output(0)},
+		})
+	}
+	for _, r := range results {
+		cases = append(cases, r.tc)
+	}
+	return cases
+}
+
+func newTestCase(tc testjson.TestCase) TestCase {
+	return TestCase{
+		Name:     tc.Test.Name(),
+		Duration: tc.Elapsed.Milliseconds(),
+	}
+}
+
+var testFuncPattern = regexp.MustCompile(`(?m)^func\s+((?:Test|Example|Fuzz)\w*)\s*\(`)
+
+// IndexTestFiles returns the name of the file which declares each test function
+// in the _test.go files in dir. TestMain is mapped to the first file when no
+// file declares it, so that a failure in TestMain can be reported.
+func IndexTestFiles(dir string) (map[string]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no _test.go files in %v", dir)
+	}
+	sort.Strings(matches)
+
+	files := make(map[string]string)
+	for _, match := range matches {
+		raw, err := os.ReadFile(match)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range testFuncPattern.FindAllSubmatch(raw, -1) {
+			files[string(m[1])] = filepath.Base(match)
+		}
+	}
+	if _, ok := files["TestMain"]; !ok {
+		files["TestMain"] = filepath.Base(matches[0])
+	}
+	return files, nil
+}
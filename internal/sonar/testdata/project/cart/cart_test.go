@@ -0,0 +1,11 @@
+package cart
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	t.Run("empty cart", func(t *testing.T) {})
+}
+
+func TestRemove(t *testing.T) {
+	t.Skip("not implemented")
+}
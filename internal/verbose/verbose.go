@@ -0,0 +1,218 @@
+/*
+Package verbose converts the text printed by go test -v into test2json events,
+for the tools which run go test without -json. The name of a package is only
+printed on the last line of its output, so the events of a package are written
+when that line is read.
+
+The conversion is heuristic. A line which looks like the framing of a test,
+for example "--- PASS: TestName (0.00s)", is treated as one, even when it was
+printed by the test itself.
+*/
+package verbose
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// timeNow returns the current time. It is replaced in tests.
+var timeNow = time.Now
+
+// event is a test2json event.
+type event struct {
+	Time    time.Time
+	Action  testjson.Action
+	Package string  `json:",omitempty"`
+	Test    string  `json:",omitempty"`
+	Elapsed float64 `json:",omitempty"`
+	Output  string  `json:",omitempty"`
+}
+
+var (
+	// testResult matches the line printed when a test ends, which is indented
+	// for a subtest.
+	testResult = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): (\S+) \((\d+\.\d+)s\)`)
+	// packageResult matches the line printed when a package ends.
+	packageResult = regexp.MustCompile(`^(ok  |FAIL|\?   )\t(\S+)(?:\t| |$)`)
+	elapsed       = regexp.MustCompile(`\t(\d+\.\d+)s`)
+)
+
+// Convert reads the output of go test -v from in, and writes the test2json
+// events to out. The events of a package which did not print a result line,
+// for example the output of a test binary, are written at the end with an
+// empty package name. Output at the end which is not part of a test is
+// ignored.
+func Convert(out io.Writer, in io.Reader) error {
+	c := &converter{enc: json.NewEncoder(out)}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if err := c.line(scanner.Text() + "\n"); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	c.endTests("")
+	for _, e := range c.pending {
+		if e.Test != "" {
+			return c.flush("")
+		}
+	}
+	// the FAIL line printed by go test at the end of a run is not the output
+	// of a package
+	return nil
+}
+
+// NewReader returns a reader of the test2json events converted from the
+// output of go test -v read from in.
+func NewReader(in io.Reader) io.Reader {
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(Convert(w, in))
+	}()
+	return r
+}
+
+type converter struct {
+	enc *json.Encoder
+	// pending are the events of the package which has not ended.
+	pending []event
+	// test is the name of the test which receives the lines of output.
+	test string
+	// ending are the result lines of a test, and its subtests which have
+	// been read so far. go test -v prints the result of a test before the
+	// results of its subtests, but a test must end after its subtests.
+	ending []testEnd
+}
+
+type testEnd struct {
+	name   string
+	events []event
+}
+
+func (c *converter) add(e event) {
+	e.Time = timeNow()
+	c.pending = append(c.pending, e)
+}
+
+func (c *converter) output(test, line string) {
+	c.add(event{Action: testjson.ActionOutput, Test: test, Output: line})
+}
+
+func (c *converter) line(line string) error {
+	if m := testResult.FindStringSubmatch(line); m != nil {
+		name := m[2]
+		seconds, _ := strconv.ParseFloat(m[3], 64)
+		c.endTests(name)
+		c.test = name
+		c.ending = append(c.ending, testEnd{name: name, events: []event{
+			{Time: timeNow(), Action: testjson.ActionOutput, Test: name, Output: line},
+			{Time: timeNow(), Action: resultAction(m[1]), Test: name, Elapsed: seconds},
+		}})
+		return nil
+	}
+	c.endTests("")
+
+	if name, ok := framing(line, "=== RUN"); ok {
+		c.test = name
+		c.add(event{Action: testjson.ActionRun, Test: name})
+		c.output(name, line)
+		return nil
+	}
+	if name, ok := framing(line, "=== PAUSE"); ok {
+		c.output(name, line)
+		c.add(event{Action: testjson.ActionPause, Test: name})
+		return nil
+	}
+	if name, ok := framing(line, "=== CONT"); ok {
+		c.test = name
+		c.add(event{Action: testjson.ActionCont, Test: name})
+		c.output(name, line)
+		return nil
+	}
+	if name, ok := framing(line, "=== NAME"); ok {
+		c.test = name
+		return nil
+	}
+	if m := packageResult.FindStringSubmatch(line); m != nil {
+		c.output("", line)
+		action := testjson.ActionPass
+		switch strings.TrimSpace(m[1]) {
+		case "FAIL":
+			action = testjson.ActionFail
+		case "?":
+			action = testjson.ActionSkip
+		}
+		var seconds float64
+		if e := elapsed.FindStringSubmatch(line); e != nil {
+			seconds, _ = strconv.ParseFloat(e[1], 64)
+		}
+		c.add(event{Action: action, Elapsed: seconds})
+		return c.flush(m[2])
+	}
+	switch strings.TrimSpace(line) {
+	case "PASS", "FAIL":
+		c.test = ""
+	}
+	c.output(c.test, line)
+	return nil
+}
+
+// endTests adds the events of the tests in ending which are not a parent of
+// the test name, starting with the last one.
+func (c *converter) endTests(name string) {
+	for i := len(c.ending) - 1; i >= 0; i-- {
+		end := c.ending[i]
+		if name != "" && strings.HasPrefix(name, end.name+"/") {
+			break
+		}
+		c.pending = append(c.pending, end.events...)
+		c.ending = c.ending[:i]
+	}
+}
+
+// flush writes the pending events with the name of their package.
+func (c *converter) flush(pkg string) error {
+	for _, e := range c.pending {
+		e.Package = pkg
+		if err := c.enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	c.pending = c.pending[:0]
+	c.test = ""
+	return nil
+}
+
+// framing returns the name of the test from a line like "=== RUN   TestName".
+func framing(line, prefix string) (string, bool) {
+	rest, ok := strings.CutPrefix(line, prefix+" ")
+	if !ok {
+		return "", false
+	}
+	name := strings.TrimSpace(rest)
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return "", false
+	}
+	return name, true
+}
+
+func resultAction(result string) testjson.Action {
+	switch result {
+	case "FAIL":
+		return testjson.ActionFail
+	case "SKIP":
+		return testjson.ActionSkip
+	default:
+		return testjson.ActionPass
+	}
+}
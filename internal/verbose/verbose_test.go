@@ -0,0 +1,91 @@
+package verbose
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func patchTimeNow(t *testing.T) {
+	now := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = time.Now })
+}
+
+func TestConvert(t *testing.T) {
+	patchTimeNow(t)
+	in, err := os.Open("testdata/go-test-v.out")
+	assert.NilError(t, err)
+	defer in.Close() //nolint:errcheck
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, Convert(out, in))
+	golden.Assert(t, out.String(), "expected-events.jsonl")
+}
+
+func TestNewReader_ScanTestOutput(t *testing.T) {
+	in, err := os.Open("testdata/go-test-v.out")
+	assert.NilError(t, err)
+	defer in.Close() //nolint:errcheck
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: NewReader(in),
+		Stderr: strings.NewReader(""),
+	})
+	assert.NilError(t, err)
+
+	var failed []string
+	for _, tc := range exec.Failed() {
+		failed = append(failed, testjson.RelativePackagePath(tc.Package)+"."+tc.Test.Name())
+	}
+	assert.DeepEqual(t, failed, []string{
+		"testjson/internal/broken.",
+		"testjson/internal/parallelfails.TestNestedParallelFailures/a",
+		"testjson/internal/parallelfails.TestNestedParallelFailures/d",
+		"testjson/internal/parallelfails.TestNestedParallelFailures/c",
+		"testjson/internal/parallelfails.TestNestedParallelFailures/b",
+		"testjson/internal/parallelfails.TestNestedParallelFailures",
+		"testjson/internal/parallelfails.TestParallelTheFirst",
+		"testjson/internal/parallelfails.TestParallelTheThird",
+		"testjson/internal/parallelfails.TestParallelTheSecond",
+		"testjson/internal/withfails.TestFailed",
+		"testjson/internal/withfails.TestFailedWithStderr",
+		"testjson/internal/withfails.TestNestedWithFailure/c",
+		"testjson/internal/withfails.TestNestedWithFailure",
+	})
+	assert.Equal(t, exec.Total(), 59)
+	assert.Equal(t, len(exec.Skipped()), 5)
+	assert.Equal(t, len(exec.Packages()), 5)
+}
+
+func TestConvert_WithoutPackageResult(t *testing.T) {
+	patchTimeNow(t)
+	in := `=== RUN   TestOne
+--- PASS: TestOne (0.25s)
+PASS
+`
+	out := new(bytes.Buffer)
+	assert.NilError(t, Convert(out, strings.NewReader(in)))
+	expected := `{"Time":"2022-01-02T03:04:05Z","Action":"run","Test":"TestOne"}
+{"Time":"2022-01-02T03:04:05Z","Action":"output","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Time":"2022-01-02T03:04:05Z","Action":"output","Test":"TestOne","Output":"--- PASS: TestOne (0.25s)\n"}
+{"Time":"2022-01-02T03:04:05Z","Action":"pass","Test":"TestOne","Elapsed":0.25}
+{"Time":"2022-01-02T03:04:05Z","Action":"output","Output":"PASS\n"}
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestNewReader_Error(t *testing.T) {
+	r := NewReader(iotest.ErrReader(errors.New("read failed")))
+	_, err := io.ReadAll(r)
+	assert.Error(t, err, "read failed")
+}
@@ -0,0 +1,177 @@
+/*
+Package webhook posts a summary of the test results of a run to a webhook URL.
+The summary is posted either as JSON, or as the payload of a Slack incoming
+webhook.
+*/
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Format of the payload posted to the webhook.
+type Format string
+
+const (
+	// FormatJSON posts the Summary as JSON.
+	FormatJSON Format = "json"
+	// FormatSlack posts a message using the payload of a Slack incoming
+	// webhook.
+	FormatSlack Format = "slack"
+)
+
+// maxFailures is the number of failed tests included in the summary.
+const maxFailures = 20
+
+// Summary of the results of a run.
+type Summary struct {
+	// Status is one of passed, failed, or errored.
+	Status         string    `json:"status"`
+	Total          int       `json:"total"`
+	Failed         int       `json:"failed"`
+	Skipped        int       `json:"skipped"`
+	Errors         int       `json:"errors"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+	Failures       []Failure `json:"failures,omitempty"`
+	// JobURL is the URL of the CI job, when the run is in a known CI system.
+	JobURL string `json:"job_url,omitempty"`
+}
+
+// Failure is a failed test. Test is empty when the package failed to build,
+// or when TestMain failed.
+type Failure struct {
+	Package string `json:"package"`
+	Test    string `json:"test,omitempty"`
+}
+
+// NewSummary returns the summary of exec. At most 20 failed tests are
+// included in Failures.
+func NewSummary(exec *testjson.Execution, jobURL string) Summary {
+	s := Summary{
+		Status:         "passed",
+		Total:          exec.Total(),
+		Failed:         len(exec.Failed()),
+		Skipped:        len(exec.Skipped()),
+		Errors:         len(exec.Errors()),
+		ElapsedSeconds: exec.Elapsed().Round(time.Millisecond).Seconds(),
+		JobURL:         jobURL,
+	}
+	switch {
+	case s.Errors > 0:
+		s.Status = "errored"
+	case s.Failed > 0:
+		s.Status = "failed"
+	}
+	for _, tc := range testjson.FilterFailedUnique(exec.Failed()) {
+		if len(s.Failures) == maxFailures {
+			break
+		}
+		s.Failures = append(s.Failures, Failure{Package: tc.Package, Test: tc.Test.Name()})
+	}
+	return s
+}
+
+// Passed returns true if no tests failed, and there were no errors.
+func (s Summary) Passed() bool {
+	return s.Status == "passed"
+}
+
+// Post the summary to url, using the payload of format.
+func Post(ctx context.Context, client *http.Client, url string, format Format, s Summary) error {
+	var payload interface{} = s
+	if format == FormatSlack {
+		payload = slackPayload{Text: slackText(s)}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned %v: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// slackText returns the summary as a message using Slack mrkdwn.
+func slackText(s Summary) string {
+	b := new(strings.Builder)
+	switch s.Status {
+	case "errored":
+		b.WriteString(":x: *Tests errored*")
+	case "failed":
+		b.WriteString(":x: *Tests failed*")
+	default:
+		b.WriteString(":white_check_mark: *Tests passed*")
+	}
+
+	elapsed := time.Duration(s.ElapsedSeconds * float64(time.Second)).Round(time.Millisecond)
+	fmt.Fprintf(b, "\n%d tests run in %v", s.Total, elapsed)
+	if s.Errors > 0 {
+		fmt.Fprintf(b, ", %d errors", s.Errors)
+	}
+	if s.Failed > 0 {
+		fmt.Fprintf(b, ", %d failed", s.Failed)
+	}
+	if s.Skipped > 0 {
+		fmt.Fprintf(b, ", %d skipped", s.Skipped)
+	}
+	for _, f := range s.Failures {
+		name := f.Package
+		if f.Test != "" {
+			name += "." + f.Test
+		}
+		fmt.Fprintf(b, "\n• `%v`", name)
+	}
+	if s.JobURL != "" {
+		fmt.Fprintf(b, "\n<%v|View the CI job>", s.JobURL)
+	}
+	return b.String()
+}
+
+// JobURL returns the URL of the CI job from the environment variables of
+// GitHub Actions, GitLab CI, CircleCI, Buildkite, Azure Pipelines, or Jenkins.
+// JobURL returns an empty string when the CI system is not known.
+func JobURL(getenv func(string) string) string {
+	switch {
+	case getenv("GITHUB_RUN_ID") != "":
+		return fmt.Sprintf("%v/%v/actions/runs/%v",
+			getenv("GITHUB_SERVER_URL"), getenv("GITHUB_REPOSITORY"), getenv("GITHUB_RUN_ID"))
+	case getenv("CI_JOB_URL") != "":
+		return getenv("CI_JOB_URL")
+	case getenv("CIRCLE_BUILD_URL") != "":
+		return getenv("CIRCLE_BUILD_URL")
+	case getenv("BUILDKITE_BUILD_URL") != "":
+		return getenv("BUILDKITE_BUILD_URL")
+	case getenv("BUILD_BUILDID") != "" && getenv("SYSTEM_COLLECTIONURI") != "":
+		return fmt.Sprintf("%v%v/_build/results?buildId=%v",
+			getenv("SYSTEM_COLLECTIONURI"), getenv("SYSTEM_TEAMPROJECT"), getenv("BUILD_BUILDID"))
+	case getenv("BUILD_URL") != "":
+		return getenv("BUILD_URL")
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestPost(t *testing.T) {
+	summary := Summary{
+		Status:         "failed",
+		Total:          12,
+		Failed:         2,
+		Skipped:        1,
+		ElapsedSeconds: 2.5,
+		Failures: []Failure{
+			{Package: "example.com/app/api", Test: "TestHandler/not_found"},
+			{Package: "example.com/app/worker"},
+		},
+		JobURL: "https://ci.example.com/job/12",
+	}
+
+	type testCase struct {
+		name     string
+		format   Format
+		expected string
+	}
+	run := func(t *testing.T, tc testCase) {
+		var body string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, err := io.ReadAll(r.Body)
+			assert.Check(t, err)
+			assert.Check(t, r.Method == http.MethodPost)
+			assert.Check(t, r.Header.Get("Content-Type") == "application/json")
+			body = string(raw)
+		}))
+		t.Cleanup(srv.Close)
+
+		err := Post(context.Background(), srv.Client(), srv.URL, tc.format, summary)
+		assert.NilError(t, err)
+		assert.Equal(t, body, tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name:   "json",
+			format: FormatJSON,
+			expected: `{"status":"failed","total":12,"failed":2,"skipped":1,"errors":0,` +
+				`"elapsed_seconds":2.5,"failures":[` +
+				`{"package":"example.com/app/api","test":"TestHandler/not_found"},` +
+				`{"package":"example.com/app/worker"}],` +
+				`"job_url":"https://ci.example.com/job/12"}`,
+		},
+		{
+			name:   "slack",
+			format: FormatSlack,
+			expected: mustJSON(t, slackPayload{Text: ":x: *Tests failed*\n" +
+				"12 tests run in 2.5s, 2 failed, 1 skipped\n" +
+				"• `example.com/app/api.TestHandler/not_found`\n" +
+				"• `example.com/app/worker`\n" +
+				"<https://ci.example.com/job/12|View the CI job>"}),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestPost_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("no_service\n"))
+	}))
+	t.Cleanup(srv.Close)
+
+	err := Post(context.Background(), srv.Client(), srv.URL, FormatSlack, Summary{})
+	assert.Error(t, err, "webhook returned 404 Not Found: no_service")
+}
+
+func TestJobURL(t *testing.T) {
+	env := map[string]string{
+		"GITHUB_SERVER_URL": "https://github.com",
+		"GITHUB_REPOSITORY": "owner/repo",
+		"GITHUB_RUN_ID":     "1234",
+	}
+	getenv := func(key string) string { return env[key] }
+	assert.Equal(t, JobURL(getenv), "https://github.com/owner/repo/actions/runs/1234")
+
+	env = map[string]string{"CI_JOB_URL": "https://gitlab.com/owner/repo/-/jobs/5"}
+	assert.Equal(t, JobURL(getenv), "https://gitlab.com/owner/repo/-/jobs/5")
+
+	env = map[string]string{}
+	assert.Equal(t, JobURL(getenv), "")
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	assert.NilError(t, err)
+	return string(raw)
+}
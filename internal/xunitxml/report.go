@@ -0,0 +1,244 @@
+/*
+Package xunitxml creates an xUnit.net v2 XML report from a testjson.Execution.
+
+Each Go package is an assembly, with a single collection which contains every
+test in the package. See https://xunit.net/docs/format-xml-v2 for the format.
+*/
+package xunitxml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Assemblies is the root element of the report.
+type Assemblies struct {
+	XMLName    xml.Name   `xml:"assemblies"`
+	Timestamp  string     `xml:"timestamp,attr,omitempty"`
+	Assemblies []Assembly `xml:"assembly"`
+}
+
+// Assembly contains the results of a single Go package.
+type Assembly struct {
+	Name          string       `xml:"name,attr"`
+	TestFramework string       `xml:"test-framework,attr"`
+	RunDate       string       `xml:"run-date,attr"`
+	RunTime       string       `xml:"run-time,attr"`
+	Time          string       `xml:"time,attr"`
+	Total         int          `xml:"total,attr"`
+	Passed        int          `xml:"passed,attr"`
+	Failed        int          `xml:"failed,attr"`
+	Skipped       int          `xml:"skipped,attr"`
+	ErrorCount    int          `xml:"errors,attr"`
+	Errors        []Error      `xml:"errors>error"`
+	Collections   []Collection `xml:"collection"`
+}
+
+// Error is a failure which is not the result of a test, for example a failure
+// in TestMain.
+type Error struct {
+	Type    string  `xml:"type,attr"`
+	Name    string  `xml:"name,attr"`
+	Failure Failure `xml:"failure"`
+}
+
+// Collection is a group of tests.
+type Collection struct {
+	Name    string `xml:"name,attr"`
+	Time    string `xml:"time,attr"`
+	Total   int    `xml:"total,attr"`
+	Passed  int    `xml:"passed,attr"`
+	Failed  int    `xml:"failed,attr"`
+	Skipped int    `xml:"skipped,attr"`
+	Tests   []Test `xml:"test"`
+}
+
+// Test is the result of a single test.
+type Test struct {
+	Name    string   `xml:"name,attr"`
+	Type    string   `xml:"type,attr"`
+	Method  string   `xml:"method,attr"`
+	Time    string   `xml:"time,attr"`
+	Result  string   `xml:"result,attr"`
+	Failure *Failure `xml:"failure,omitempty"`
+	Reason  *CDATA   `xml:"reason,omitempty"`
+	Output  *CDATA   `xml:"output,omitempty"`
+	Traits  *Traits  `xml:"traits,omitempty"`
+}
+
+// Failure contains the output of a failed test.
+type Failure struct {
+	ExceptionType string `xml:"exception-type,attr"`
+	Message       CDATA  `xml:"message"`
+}
+
+// Traits is a wrapper for the <traits> tag as encoding/xml would otherwise
+// always create an empty one.
+type Traits struct {
+	Traits []Trait `xml:"trait"`
+}
+
+// Trait is a name and value associated with a test. Traits are created from
+// the attributes of a test.
+type Trait struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// CDATA is text which is written as a CDATA section.
+type CDATA struct {
+	Text string `xml:",cdata"`
+}
+
+// Values of Test.Result
+const (
+	resultPass = "Pass"
+	resultFail = "Fail"
+	resultSkip = "Skip"
+)
+
+// Config used to write an xUnit XML document.
+type Config struct {
+	HideEmptyPackages bool
+	// IncludeOutput adds the output of passed tests to the report. The output of
+	// failed and skipped tests is always included.
+	IncludeOutput bool
+}
+
+// Write creates an XML document and writes it to out.
+func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
+	if err := write(out, generate(exec, cfg)); err != nil {
+		return fmt.Errorf("failed to write xUnit XML: %v", err)
+	}
+	return nil
+}
+
+func generate(exec *testjson.Execution, cfg Config) Assemblies {
+	doc := Assemblies{Timestamp: formatTimestamp(exec.Started())}
+	for _, pkgname := range exec.Packages() {
+		pkg := exec.Package(pkgname)
+		if cfg.HideEmptyPackages && pkg.IsEmpty() {
+			continue
+		}
+		doc.Assemblies = append(doc.Assemblies, newAssembly(pkgname, pkg, cfg))
+	}
+	return doc
+}
+
+func newAssembly(pkgname string, pkg *testjson.Package, cfg Config) Assembly {
+	elapsed := formatDurationAsSeconds(pkg.Elapsed())
+	collection := Collection{
+		Name:    pkgname,
+		Time:    elapsed,
+		Total:   pkg.Total,
+		Passed:  len(pkg.Passed),
+		Failed:  len(pkg.Failed),
+		Skipped: len(pkg.Skipped),
+		Tests:   packageTests(pkg, cfg),
+	}
+	assembly := Assembly{
+		Name:          pkgname,
+		TestFramework: "gotestsum",
+		RunDate:       pkg.Start.Format("2006-01-02"),
+		RunTime:       pkg.Start.Format("15:04:05"),
+		Time:          elapsed,
+		Total:         collection.Total,
+		Passed:        collection.Passed,
+		Failed:        collection.Failed,
+		Skipped:       collection.Skipped,
+		Collections:   []Collection{collection},
+	}
+	if pkg.TestMainFailed() {
+		assembly.Errors = append(assembly.Errors, Error{
+			Type: "fatal",
+			Name: "TestMain",
+			Failure: Failure{
+				ExceptionType: "TestMain",
+				Message:       CDATA{Text: pkg.Output(0)},
+			},
+		})
+		assembly.ErrorCount = len(assembly.Errors)
+	}
+	return assembly
+}
+
+func packageTests(pkg *testjson.Package, cfg Config) []Test {
+	tests := []Test{}
+	for _, tc := range pkg.Failed {
+		test := newTest(tc, resultFail)
+		test.Failure = &Failure{
+			Message: CDATA{Text: strings.Join(pkg.OutputLines(tc), "")},
+		}
+		tests = append(tests, test)
+	}
+
+	for _, tc := range pkg.Skipped {
+		test := newTest(tc, resultSkip)
+		test.Reason = &CDATA{Text: pkg.SkipReason(tc)}
+		test.Output = &CDATA{Text: strings.Join(pkg.OutputLines(tc), "")}
+		tests = append(tests, test)
+	}
+
+	for _, tc := range pkg.Passed {
+		test := newTest(tc, resultPass)
+		if output := strings.Join(pkg.OutputLines(tc), ""); cfg.IncludeOutput && output != "" {
+			test.Output = &CDATA{Text: output}
+		}
+		tests = append(tests, test)
+	}
+	return tests
+}
+
+func newTest(tc testjson.TestCase, result string) Test {
+	return Test{
+		Name:   tc.Package + "." + tc.Test.Name(),
+		Type:   tc.Package,
+		Method: tc.Test.Name(),
+		Time:   formatDurationAsSeconds(tc.Elapsed),
+		Result: result,
+		Traits: traits(tc.Attributes),
+	}
+}
+
+func traits(attributes map[string]string) *Traits {
+	if len(attributes) == 0 {
+		return nil
+	}
+	result := make([]Trait, 0, len(attributes))
+	for k, v := range attributes {
+		result = append(result, Trait{Name: k, Value: v})
+	}
+	slices.SortFunc(result, func(a, b Trait) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return &Traits{Traits: result}
+}
+
+func formatDurationAsSeconds(d time.Duration) string {
+	return fmt.Sprintf("%f", d.Seconds())
+}
+
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("01/02/2006 15:04:05")
+}
+
+func write(out io.Writer, doc Assemblies) error {
+	raw, err := xml.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	_, err = out.Write(raw)
+	return err
+}
@@ -0,0 +1,51 @@
+package xunitxml
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestWrite(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t, "go-test-json.out")
+
+	err := Write(out, exec, Config{})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "xunitxml-report.golden")
+}
+
+func TestWrite_HideEmptyPackagesAndIncludeOutput(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t, "go-test-json.out")
+
+	err := Write(out, exec, Config{HideEmptyPackages: true, IncludeOutput: true})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "xunitxml-report-output.golden")
+}
+
+func TestWrite_WithAttributes(t *testing.T) {
+	out := new(bytes.Buffer)
+	exec := createExecution(t, "go-test-json-with-attributes.out")
+
+	err := Write(out, exec, Config{})
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "xunitxml-report-traits.golden")
+}
+
+func createExecution(t *testing.T, inputFile string) *testjson.Execution {
+	t.Helper()
+	raw, err := os.ReadFile(path.Join("../../testjson/testdata/input/", inputFile))
+	assert.NilError(t, err)
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:           bytes.NewReader(raw),
+		KeepPassedOutput: true,
+	})
+	assert.NilError(t, err)
+	return exec
+}
@@ -5,8 +5,20 @@ import (
 	"os"
 
 	"gotest.tools/gotestsum/cmd"
+	"gotest.tools/gotestsum/cmd/tool/benchdiff"
+	"gotest.tools/gotestsum/cmd/tool/buildkite"
+	"gotest.tools/gotestsum/cmd/tool/diff"
+	"gotest.tools/gotestsum/cmd/tool/export"
+	"gotest.tools/gotestsum/cmd/tool/flaky"
+	"gotest.tools/gotestsum/cmd/tool/githubcomment"
+	"gotest.tools/gotestsum/cmd/tool/history"
+	"gotest.tools/gotestsum/cmd/tool/html"
 	"gotest.tools/gotestsum/cmd/tool/matrix"
+	"gotest.tools/gotestsum/cmd/tool/merge"
+	"gotest.tools/gotestsum/cmd/tool/retrystats"
+	"gotest.tools/gotestsum/cmd/tool/selftest"
 	"gotest.tools/gotestsum/cmd/tool/slowest"
+	"gotest.tools/gotestsum/internal/experiment"
 	"gotest.tools/gotestsum/internal/log"
 )
 
@@ -26,11 +38,20 @@ func main() {
 }
 
 func route(args []string) error {
+	experiment.Load(os.Getenv(experiment.EnvVar))
+
 	name := args[0]
 	next, rest := nextArg(args[1:])
 	switch next {
 	case "help", "?":
 		return cmd.Run(name, []string{"--help"})
+	case "list":
+		return cmd.RunList(name+" "+next, rest)
+	case "exec-binary":
+		return cmd.RunExecBinary(name+" "+next, rest)
+	case "sandbox-exec":
+		// the -exec of go test used by --sandbox-packages
+		return cmd.RunSandboxExec(rest)
 	case "tool":
 		return toolRun(name+" "+next, rest)
 	default:
@@ -55,8 +76,19 @@ func toolRun(name string, args []string) error {
 		return fmt.Sprintf(`Usage: %[1]s COMMAND [flags]
 
 Commands:
-    %[1]s slowest      find or skip the slowest tests
-    %[1]s ci-matrix    use previous test runtime to place packages into optimal buckets
+    %[1]s slowest             find or skip the slowest tests
+    %[1]s ci-matrix           use previous test runtime to place packages into optimal buckets
+    %[1]s buildkite-annotate  add a summary of test results to a Buildkite build
+    %[1]s benchdiff           compare the benchmark results of two runs
+    %[1]s diff                compare the results and test time of two runs
+    %[1]s export              export test results from json files as csv or parquet tables
+    %[1]s flaky               find tests which both passed and failed across many runs
+    %[1]s github-comment      post a summary of test results to a GitHub pull request
+    %[1]s history             report test time, failures, and flakes from previous runs
+    %[1]s html                write an HTML report of test results from a json file
+    %[1]s merge               merge the json files from CI shards into one run
+    %[1]s retry-stats         report how often reruns rescue a run, and the time spent on them
+    %[1]s selftest            verify the output of every format on this platform
 
 Use '%[1]s COMMAND --help' for command specific help.
 `, name)
@@ -71,6 +103,28 @@ Use '%[1]s COMMAND --help' for command specific help.
 		return slowest.Run(name+" "+next, rest)
 	case "ci-matrix":
 		return matrix.Run(name+" "+next, rest)
+	case "buildkite-annotate":
+		return buildkite.Run(name+" "+next, rest)
+	case "benchdiff":
+		return benchdiff.Run(name+" "+next, rest)
+	case "diff":
+		return diff.Run(name+" "+next, rest)
+	case "export":
+		return export.Run(name+" "+next, rest)
+	case "flaky":
+		return flaky.Run(name+" "+next, rest)
+	case "github-comment":
+		return githubcomment.Run(name+" "+next, rest)
+	case "history":
+		return history.Run(name+" "+next, rest)
+	case "html":
+		return html.Run(name+" "+next, rest)
+	case "merge":
+		return merge.Run(name+" "+next, rest)
+	case "retry-stats":
+		return retrystats.Run(name+" "+next, rest)
+	case "selftest":
+		return selftest.Run(name+" "+next, rest)
 	default:
 		fmt.Fprintln(os.Stderr, usage(name))
 		return fmt.Errorf("invalid command: %v %v", name, next)
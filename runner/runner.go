@@ -0,0 +1,122 @@
+/*
+Package runner runs gotestsum from a Go program, for example a mage target or
+a build tool, and returns the results of the run as structs, so that the
+program does not need to run the gotestsum binary and parse its output.
+
+	result, err := runner.Run(ctx, runner.Options{
+	    Args: []string{"--format=testname", "--rerun-fails", "--packages=./...", "--", "-cover"},
+	})
+	if err != nil {
+	    return err
+	}
+	for _, tc := range result.Flaky {
+	    fmt.Printf("flaky: %v.%v\n", tc.Package, tc.Test)
+	}
+	if result.ExitCode != 0 {
+	    return fmt.Errorf("tests failed")
+	}
+
+The Args are the same flags and go test args that are accepted by the
+gotestsum command, so every feature of the command can be used. The user
+config of the gotestsum command is not applied.
+
+The API of this package follows the semantic versioning of the
+gotest.tools/gotestsum module, in the same way as the testjson package.
+*/
+package runner
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"gotest.tools/gotestsum/cmd"
+	"gotest.tools/gotestsum/internal/coverprofile"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Options used to Run the tests.
+type Options struct {
+	// Args are the flags of the gotestsum command, followed by the go test
+	// args, without the name of the command.
+	Args []string
+	// Stdout and Stderr receive the output of the run. The defaults are
+	// os.Stdout and os.Stderr. Use io.Discard to hide the output. The live
+	// status line of the pkgname and standard-verbose formats is only printed
+	// when Stdout is a terminal.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Result of a run.
+type Result struct {
+	// Execution has the result, elapsed time, and output of every test and
+	// package, including the tests run again by --rerun-fails.
+	Execution *testjson.Execution
+	// ExitCode is the exit code of the gotestsum command for the same run.
+	ExitCode int
+	// Failed are the tests which failed, and did not pass when they were
+	// rerun. The Test is empty when a package failed without a failed test,
+	// for example because the package did not build.
+	Failed []testjson.TestCase
+	// Flaky are the tests which failed, and passed when they were rerun.
+	Flaky []testjson.TestCase
+	// Reruns is the number of times failed tests were run again by
+	// --rerun-fails.
+	Reruns int
+	// CoverProfile is the path of the -coverprofile, which includes the
+	// coverage of the reruns. It is empty when -coverprofile is not set.
+	CoverProfile string
+}
+
+// Run the tests, and return the Result. The run stops when ctx is cancelled.
+// An error is returned when the tests could not be run, for example because
+// an option is not valid. A test which fails is not an error, it sets the
+// ExitCode of the Result.
+//
+// Run does not handle os.Interrupt, cancel ctx to stop the run. The log of
+// gotestsum is written to Stderr. The log level and the color setting of
+// github.com/fatih/color are global, so they are set only for the duration of
+// the run, and only one Run runs at a time.
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+	exec, err := cmd.RunContext(ctx, "gotestsum", opts.Args, opts.Stdout, opts.Stderr)
+	if err != nil && !cmd.IsExitCoder(err) {
+		return nil, err
+	}
+	if exec == nil {
+		// no packages were selected to test
+		var scanErr error
+		exec, scanErr = testjson.ScanTestOutput(testjson.ScanConfig{Stdout: strings.NewReader("")})
+		if scanErr != nil {
+			return nil, scanErr
+		}
+	}
+	result := &Result{
+		Execution:    exec,
+		ExitCode:     cmd.ExitCodeWithDefault(err),
+		CoverProfile: coverprofile.ArgValue(opts.Args),
+	}
+	for _, tc := range testjson.FilterFailedUnique(exec.Failed()) {
+		if tc.Test != "" && exec.Package(tc.Package).PassedOnRerun(tc) {
+			result.Flaky = append(result.Flaky, tc)
+			continue
+		}
+		result.Failed = append(result.Failed, tc)
+	}
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		for _, tcs := range [][]testjson.TestCase{pkg.Passed, pkg.Failed, pkg.Skipped} {
+			for _, tc := range tcs {
+				result.Reruns = max(result.Reruns, tc.RunID)
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/fatih/color"
+	"gotest.tools/gotestsum/internal/log"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/skip"
+)
+
+func TestRun_RawCommand(t *testing.T) {
+	skip.If(t, runtime.GOOS == "windows", "cat is not available")
+	input := filepath.FromSlash("../testjson/testdata/input/go-test-json.out")
+
+	stdout := new(bytes.Buffer)
+	result, err := Run(context.Background(), Options{
+		Args:   []string{"--format=pkgname", "--raw-command", "--", "cat", input},
+		Stdout: stdout,
+		Stderr: io.Discard,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, result.ExitCode, 0)
+	assert.Equal(t, result.Execution.Total(), 59)
+	assert.Equal(t, len(result.Failed), 11)
+	assert.Equal(t, len(result.Flaky), 0)
+	assert.Equal(t, result.Reruns, 0)
+	assert.Equal(t, result.CoverProfile, "")
+	assert.Assert(t, stdout.Len() > 0)
+}
+
+func TestRun_RestoresGlobals(t *testing.T) {
+	skip.If(t, runtime.GOOS == "windows", "cat is not available")
+	input := filepath.FromSlash("../testjson/testdata/input/go-test-json.out")
+
+	noColor := color.NoColor
+	t.Cleanup(func() { color.NoColor = noColor })
+	color.NoColor = false
+
+	stderr := new(bytes.Buffer)
+	_, err := Run(context.Background(), Options{
+		Args:   []string{"--debug", "--no-color", "--format=none", "--raw-command", "--", "cat", input},
+		Stdout: io.Discard,
+		Stderr: stderr,
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(stderr.String(), "exec: [cat"))
+	assert.Equal(t, log.GetLevel(), log.WarnLevel)
+	assert.Equal(t, color.NoColor, false)
+}
+
+func TestRun_InvalidOptions(t *testing.T) {
+	_, err := Run(context.Background(), Options{
+		Args:   []string{"--not-a-flag"},
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	})
+	assert.ErrorContains(t, err, "unknown flag: --not-a-flag")
+}
+
+func TestRun_RerunFails(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for short run")
+	}
+	seedFile := fs.NewFile(t, t.Name()+"-seedfile", fs.WithContent("0"))
+	t.Setenv("TEST_SEEDFILE", seedFile.Path())
+	coverProfile := filepath.Join(t.TempDir(), "cover.out")
+
+	result, err := Run(context.Background(), Options{
+		Args: []string{
+			"--format=none",
+			"--rerun-fails=4",
+			"--packages=../cmd/testdata/e2e/flaky/",
+			"--", "-count=1", "-tags=testdata", "-coverprofile=" + coverProfile,
+		},
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, result.ExitCode, 0)
+	assert.Equal(t, len(result.Failed), 0)
+	assert.Assert(t, len(result.Flaky) > 0)
+	assert.Assert(t, result.Reruns > 0)
+	assert.Equal(t, result.CoverProfile, coverProfile)
+}
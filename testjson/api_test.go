@@ -0,0 +1,80 @@
+package testjson
+
+import (
+	"bytes"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+// TestExportedAPI fails when the exported API of the package changes. The
+// API follows semantic versioning, so an identifier must not be removed, and
+// a signature must not change in an incompatible way. When the change is
+// compatible, update the golden file with -update.
+func TestExportedAPI(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(info fs.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, parser.ParseComments)
+	assert.NilError(t, err)
+
+	files := make([]*ast.File, 0, len(pkgs["testjson"].Files))
+	for _, file := range pkgs["testjson"].Files {
+		files = append(files, file)
+	}
+	pkg, err := doc.NewFromFiles(fset, files, "gotest.tools/gotestsum/testjson")
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	config := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	write := func(decl ast.Decl) {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			decl.Doc, decl.Body = nil, nil
+		case *ast.GenDecl:
+			decl.Doc = nil
+		}
+		// the docs may change without changing the API
+		ast.Inspect(decl, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.Field:
+				node.Doc, node.Comment = nil, nil
+			case *ast.ValueSpec:
+				node.Doc, node.Comment = nil, nil
+			}
+			return true
+		})
+		assert.NilError(t, config.Fprint(buf, fset, decl))
+		buf.WriteString("\n\n")
+	}
+	writeFuncs := func(funcs []*doc.Func) {
+		for _, fn := range funcs {
+			write(fn.Decl)
+		}
+	}
+	writeValues := func(values []*doc.Value) {
+		for _, value := range values {
+			write(value.Decl)
+		}
+	}
+
+	writeValues(pkg.Consts)
+	writeValues(pkg.Vars)
+	writeFuncs(pkg.Funcs)
+	for _, typ := range pkg.Types {
+		write(typ.Decl)
+		writeValues(typ.Consts)
+		writeValues(typ.Vars)
+		writeFuncs(typ.Funcs)
+		writeFuncs(typ.Methods)
+	}
+	golden.Assert(t, buf.String(), "exported-api.golden")
+}
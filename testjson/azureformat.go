@@ -0,0 +1,175 @@
+package testjson
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// azurePipelinesFormatter prints the testname format with the logging commands
+// used by Azure Pipelines. The output of each test is printed in a collapsible
+// group, every failure is reported as an error with task.logissue, and the
+// progress of the task is updated as packages finish.
+type azurePipelinesFormatter struct {
+	out    *bufio.Writer
+	output map[azureKey][]string
+	// started and finished are the number of packages, used to report the
+	// progress of the task.
+	started  map[string]bool
+	finished int
+	progress int
+}
+
+type azureKey struct {
+	pkg  string
+	test string
+}
+
+func azurePipelinesFormat(out io.Writer) EventFormatter {
+	return &azurePipelinesFormatter{
+		out:     bufio.NewWriter(out),
+		output:  make(map[azureKey][]string),
+		started: make(map[string]bool),
+	}
+}
+
+func (f *azurePipelinesFormatter) Format(event TestEvent, exec *Execution) error {
+	f.started[event.Package] = true
+	key := azureKey{pkg: event.Package, test: event.Test}
+
+	// test case output
+	if event.Test != "" && event.Action == ActionOutput {
+		if !isFramingLine(event.Output, event.Test) {
+			f.output[key] = append(f.output[key], event.Output)
+		}
+		return nil
+	}
+
+	// test case end event
+	if event.Test != "" && event.Action.IsTerminal() {
+		output := f.output[key]
+		delete(f.output, key)
+		if len(output) > 0 {
+			f.out.WriteString("##[group]")
+		} else {
+			f.out.WriteString("  ")
+		}
+		testNameFormatTestEvent(f.out, event, exec, FormatOptions{})
+		for _, item := range output {
+			f.out.WriteString(item)
+		}
+		if len(output) > 0 {
+			f.out.WriteString("\n##[endgroup]\n")
+		}
+		if event.Action == ActionFail {
+			f.logIssue(event.Package, event.Test, output)
+		}
+		return f.out.Flush()
+	}
+
+	// package event
+	if !event.Action.IsTerminal() {
+		return nil
+	}
+
+	pkg := exec.Package(event.Package)
+	result := colorEvent(event)(strings.ToUpper(string(event.Action)))
+	if event.Action == ActionSkip || (event.Action == ActionPass && pkg.Total == 0) {
+		event.Action = ActionSkip // always color these as skip actions
+		result = colorEvent(event)("EMPTY")
+	}
+	f.out.WriteString("  ")
+	f.out.WriteString(result)
+	f.out.WriteString(" Package ")
+	f.out.WriteString(packageLine(event, pkg))
+	f.out.WriteString("\n")
+
+	// a package which failed without a failed test did not build, or TestMain failed
+	if event.Action == ActionFail && len(pkg.Failed) == 0 {
+		f.logIssue(event.Package, "", pkg.OutputLines(TestCase{}))
+	}
+
+	f.finished++
+	f.setProgress()
+	return f.out.Flush()
+}
+
+// logIssue prints a task.logissue command for a failed test, or for a failed
+// package when test is empty. The first line of output which includes the
+// location of the failure is used as the source of the error.
+func (f *azurePipelinesFormatter) logIssue(pkg, test string, output []string) {
+	name := RelativePackagePath(pkg)
+	if test != "" {
+		name = joinPkgToTestName(name, test)
+	}
+	message := name + " failed"
+
+	props := []string{"type", "error"}
+	for _, line := range output {
+		match := azureSourceLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if pkgPath := RelativePackagePath(pkg); pkgPath != pkg {
+			props = append(props, "sourcepath", path.Join(pkgPath, match[1]), "linenumber", match[2])
+		}
+		message += ": " + strings.TrimSpace(line)
+		break
+	}
+
+	f.out.WriteString("##vso[task.logissue ")
+	for i := 0; i+1 < len(props); i += 2 {
+		fmt.Fprintf(f.out, "%s=%s;", props[i], azureEscapeProperty(props[i+1]))
+	}
+	f.out.WriteString("]" + azureEscapeData(message) + "\n")
+}
+
+var azureSourceLine = regexp.MustCompile(`^\s*([\w.-]+\.go):(\d+): `)
+
+// setProgress prints a task.setprogress command with the percent of started
+// packages which have finished. Packages are only known once they start, so
+// the progress is only printed when it increases.
+func (f *azurePipelinesFormatter) setProgress() {
+	progress := f.finished * 100 / len(f.started)
+	if progress <= f.progress {
+		return
+	}
+	f.progress = progress
+	fmt.Fprintf(f.out, "##vso[task.setprogress value=%d;]Running tests\n", progress)
+}
+
+var (
+	azureDataReplacer = strings.NewReplacer(
+		"%", "%AZP25",
+		"\r", "%0D",
+		"\n", "%0A",
+	)
+	azurePropertyReplacer = strings.NewReplacer(
+		"%", "%AZP25",
+		"\r", "%0D",
+		"\n", "%0A",
+		";", "%3B",
+		"]", "%5D",
+	)
+)
+
+// azureEscapeData escapes the message of a logging command.
+func azureEscapeData(value string) string {
+	return azureDataReplacer.Replace(value)
+}
+
+// azureEscapeProperty escapes the value of a property of a logging command.
+func azureEscapeProperty(value string) string {
+	return azurePropertyReplacer.Replace(value)
+}
+
+// PrintAzurePipelinesPublish prints the logging command which publishes the
+// JUnit XML file at filename as the test results of an Azure Pipelines job.
+func PrintAzurePipelinesPublish(out io.Writer, filename string) error {
+	_, err := fmt.Fprintf(out, "##vso[results.publish type=JUnit;resultFiles=%s;]\n",
+		azureEscapeProperty(filename))
+	return err
+}
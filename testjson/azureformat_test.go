@@ -0,0 +1,13 @@
+package testjson
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestAzureEscape(t *testing.T) {
+	value := "100% done; [ok]\r\n"
+	assert.Equal(t, azureEscapeData(value), "100%AZP25 done; [ok]%0D%0A")
+	assert.Equal(t, azureEscapeProperty(value), "100%AZP25 done%3B [ok%5D%0D%0A")
+}
@@ -0,0 +1,217 @@
+package testjson
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Benchmark is the result of a benchmark, parsed from the line printed by
+// go test when the benchmark ends, for example:
+//
+//	BenchmarkJoin-8   	 1000000	      1051 ns/op	      16 B/op	       1 allocs/op
+type Benchmark struct {
+	Package string
+	// Name of the benchmark, without the GOMAXPROCS suffix.
+	Name string
+	// Procs is the GOMAXPROCS suffix of the name, or 1 when the name has no
+	// suffix.
+	Procs int
+	// Iterations is the number of times the benchmark ran, b.N.
+	Iterations int64
+	// Metrics are the values printed after the iterations, in the order they
+	// were printed.
+	Metrics []BenchmarkMetric
+}
+
+// BenchmarkMetric is a value reported by a benchmark, for example 1051 ns/op.
+type BenchmarkMetric struct {
+	Value float64
+	Unit  string
+}
+
+// Metric returns the value of the metric with unit, for example ns/op, and
+// false when the benchmark did not report the metric.
+func (b Benchmark) Metric(unit string) (float64, bool) {
+	for _, m := range b.Metrics {
+		if m.Unit == unit {
+			return m.Value, true
+		}
+	}
+	return 0, false
+}
+
+// NameWithProcs returns the name of the benchmark with the GOMAXPROCS
+// suffix, the same as the name printed by go test.
+func (b Benchmark) NameWithProcs() string {
+	if b.Procs == 1 {
+		return b.Name
+	}
+	return b.Name + "-" + strconv.Itoa(b.Procs)
+}
+
+var benchmarkProcs = regexp.MustCompile(`-(\d+)$`)
+
+// ParseBenchmarkLine parses the result line printed by go test when a
+// benchmark ends. Returns false if line is not the result of a benchmark.
+func ParseBenchmarkLine(pkg, line string) (Benchmark, bool) {
+	fields := strings.Split(strings.TrimRight(line, "\n"), "\t")
+	if len(fields) < 3 {
+		return Benchmark{}, false
+	}
+	name := strings.TrimSpace(fields[0])
+	if !strings.HasPrefix(name, "Benchmark") || strings.ContainsAny(name, " ") {
+		return Benchmark{}, false
+	}
+	iterations, err := strconv.ParseInt(strings.TrimSpace(fields[1]), 10, 64)
+	if err != nil {
+		return Benchmark{}, false
+	}
+
+	b := Benchmark{Package: pkg, Name: name, Procs: 1, Iterations: iterations}
+	if m := benchmarkProcs.FindStringSubmatch(name); m != nil {
+		b.Name = strings.TrimSuffix(name, m[0])
+		b.Procs, _ = strconv.Atoi(m[1])
+	}
+	for _, field := range fields[2:] {
+		parts := strings.Fields(field)
+		if len(parts) != 2 {
+			return Benchmark{}, false
+		}
+		value, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return Benchmark{}, false
+		}
+		b.Metrics = append(b.Metrics, BenchmarkMetric{Value: value, Unit: parts[1]})
+	}
+	return b, true
+}
+
+func isBenchmark(name string) bool {
+	return strings.HasPrefix(name, "Benchmark")
+}
+
+// addBenchmarkOutput adds the result of a benchmark from a line of output.
+// test2json may split the result line into two events, the name of the
+// benchmark, and the values printed when it ends, so a line which starts with
+// the name of a benchmark is kept until the rest of the line is received.
+func (p *Package) addBenchmarkOutput(event TestEvent) {
+	output := event.Output
+	if p.benchmarkLine != "" {
+		output = p.benchmarkLine + output
+		p.benchmarkLine = ""
+	}
+	if !strings.HasSuffix(output, "\n") {
+		if isBenchmark(output) {
+			p.benchmarkLine = output
+		}
+		return
+	}
+	b, ok := ParseBenchmarkLine(event.Package, output)
+	if !ok {
+		return
+	}
+	p.benchmarks = append(p.benchmarks, b)
+	// the result of a benchmark run with -cpu may be printed as the output of
+	// the package, but the benchmark is still running
+	if tc, ok := p.running[b.Name]; ok {
+		if p.testBenchmarks == nil {
+			p.testBenchmarks = make(map[int][]Benchmark)
+		}
+		p.testBenchmarks[tc.ID] = append(p.testBenchmarks[tc.ID], b)
+	}
+}
+
+// Benchmarks returns the results of the benchmarks in the package, in the
+// order they were printed. A benchmark run with -count has a result for
+// each run.
+func (p *Package) Benchmarks() []Benchmark {
+	return p.benchmarks
+}
+
+// TestCaseBenchmarks returns the results of the benchmark tc. A benchmark run
+// with -cpu has a result for each value of GOMAXPROCS.
+func (p *Package) TestCaseBenchmarks(tc TestCase) []Benchmark {
+	return p.testBenchmarks[tc.ID]
+}
+
+// Benchmarks returns the results of the benchmarks in every package, sorted
+// by package.
+func (e *Execution) Benchmarks() []Benchmark {
+	var result []Benchmark
+	for _, name := range sortedKeys(e.packages) {
+		result = append(result, e.packages[name].benchmarks...)
+	}
+	return result
+}
+
+// endBenchmarks returns a pass event for each benchmark which ended before
+// event. go test does not print an event when a benchmark passes, so a
+// benchmark ends when the next benchmark starts, or when its package ends.
+// A benchmark passed when it printed a result, or when all of its
+// sub-benchmarks passed. Any other benchmark which is still running is
+// failed when the execution ends.
+func (e *Execution) endBenchmarks(event TestEvent) []TestEvent {
+	pkg := e.packages[event.Package]
+	switch {
+	case pkg == nil:
+		return nil
+	case event.Action == ActionRun && isBenchmark(event.Test):
+	case event.PackageEvent() && event.Action.IsTerminal():
+	default:
+		return nil
+	}
+
+	var ended []TestCase
+	for name, tc := range pkg.running {
+		if !isBenchmark(name) || strings.HasPrefix(event.Test, name+"/") {
+			continue
+		}
+		if len(pkg.testBenchmarks[tc.ID]) == 0 && (len(pkg.subTests[tc.ID]) == 0 || tc.hasSubTestFailed) {
+			continue
+		}
+		ended = append(ended, tc)
+	}
+	// sub-benchmarks end before their parent
+	sort.Slice(ended, func(i, j int) bool {
+		return ended[i].ID > ended[j].ID
+	})
+
+	result := make([]TestEvent, 0, len(ended))
+	for _, tc := range ended {
+		var elapsed float64
+		if !tc.Time.IsZero() && !event.Time.IsZero() {
+			elapsed = event.Time.Sub(tc.Time).Seconds()
+		}
+		result = append(result, newBenchmarkEndEvent(event, tc.Test.Name(), elapsed))
+	}
+	return result
+}
+
+// newBenchmarkEndEvent returns a pass event for the benchmark name. The raw
+// bytes of the event are set, so that it is written to the --jsonfile like
+// any other event.
+func newBenchmarkEndEvent(next TestEvent, name string, elapsed float64) TestEvent {
+	event := TestEvent{
+		Time:    next.Time,
+		Action:  ActionPass,
+		Package: next.Package,
+		Test:    name,
+		Elapsed: elapsed,
+	}
+	raw := struct {
+		Time    *time.Time `json:",omitempty"`
+		Action  Action
+		Package string
+		Test    string
+		Elapsed float64
+	}{Action: event.Action, Package: event.Package, Test: name, Elapsed: elapsed}
+	if !next.Time.IsZero() {
+		raw.Time = &event.Time
+	}
+	event.raw, _ = json.Marshal(raw)
+	return event
+}
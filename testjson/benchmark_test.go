@@ -0,0 +1,123 @@
+package testjson
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestParseBenchmarkLine(t *testing.T) {
+	type testCase struct {
+		name     string
+		line     string
+		expected Benchmark
+		ok       bool
+	}
+	testCases := []testCase{
+		{
+			name: "with procs",
+			line: "BenchmarkJoin-8   \t 1000000\t      1051 ns/op\t      16 B/op\t       1 allocs/op\n",
+			expected: Benchmark{
+				Package:    "pkg",
+				Name:       "BenchmarkJoin",
+				Procs:      8,
+				Iterations: 1000000,
+				Metrics: []BenchmarkMetric{
+					{Value: 1051, Unit: "ns/op"},
+					{Value: 16, Unit: "B/op"},
+					{Value: 1, Unit: "allocs/op"},
+				},
+			},
+			ok: true,
+		},
+		{
+			name: "sub-benchmark without procs",
+			line: "BenchmarkSizes/n=64 \t     100\t        24.5 ns/op\t 2611.43 MB/s\n",
+			expected: Benchmark{
+				Package:    "pkg",
+				Name:       "BenchmarkSizes/n=64",
+				Procs:      1,
+				Iterations: 100,
+				Metrics: []BenchmarkMetric{
+					{Value: 24.5, Unit: "ns/op"},
+					{Value: 2611.43, Unit: "MB/s"},
+				},
+			},
+			ok: true,
+		},
+		{name: "name only", line: "BenchmarkJoin\n"},
+		{name: "log output", line: "    bench_test.go:33: BenchmarkJoin\t100\tthings\n"},
+		{name: "not a number", line: "BenchmarkJoin \t many\t 1051 ns/op\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, ok := ParseBenchmarkLine("pkg", tc.line)
+			assert.Equal(t, ok, tc.ok)
+			assert.DeepEqual(t, actual, tc.expected)
+		})
+	}
+}
+
+func TestScanTestOutput_WithBenchmarks(t *testing.T) {
+	handler := &rawHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  bytes.NewReader(golden.Get(t, "input/go-test-json-with-benchmarks.out")),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+
+	var failed []string
+	for _, tc := range exec.Failed() {
+		failed = append(failed, tc.Test.Name())
+	}
+	assert.DeepEqual(t, failed, []string{"BenchmarkFailed"})
+
+	pkg := exec.Package("gotest.tools/gotestsum/testjson/internal/withbenchmarks")
+	var passed []string
+	for _, tc := range pkg.Passed {
+		passed = append(passed, tc.Test.Name())
+	}
+	assert.DeepEqual(t, passed, []string{
+		"TestPassed",
+		"TestPassed",
+		"BenchmarkJoin",
+		"BenchmarkSizes/n=1",
+		"BenchmarkSizes/n=64",
+		"BenchmarkSizes",
+		"BenchmarkWithLog",
+	})
+	assert.Equal(t, len(pkg.TestCaseBenchmarks(pkg.Passed[2])), 2)
+	assert.Equal(t, len(pkg.TestCaseBenchmarks(pkg.Passed[5])), 0)
+
+	var names []string
+	for _, b := range exec.Benchmarks() {
+		names = append(names, fmt.Sprintf("%s-%d", b.Name, b.Procs))
+	}
+	assert.DeepEqual(t, names, []string{
+		"BenchmarkJoin-1",
+		"BenchmarkJoin-2",
+		"BenchmarkSizes/n=1-1",
+		"BenchmarkSizes/n=1-2",
+		"BenchmarkSizes/n=64-1",
+		"BenchmarkSizes/n=64-2",
+		"BenchmarkWithLog-1",
+		"BenchmarkWithLog-2",
+	})
+	nsPerOp, ok := exec.Benchmarks()[1].Metric("ns/op")
+	assert.Assert(t, ok)
+	assert.Equal(t, nsPerOp, 128.9)
+
+	// the pass events of the benchmarks are sent to the handler
+	var ends []string
+	for _, line := range handler.lines {
+		if strings.Contains(line, `"Action":"pass","Package":"gotest.tools/gotestsum/testjson/internal/withbenchmarks","Test":"Benchmark`) {
+			ends = append(ends, line)
+		}
+	}
+	assert.Equal(t, len(ends), 5)
+	assert.Assert(t, strings.HasPrefix(ends[0], `{"Time":"`), ends[0])
+}
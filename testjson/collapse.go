@@ -0,0 +1,77 @@
+package testjson
+
+import "fmt"
+
+// repeatedLineFormat is the line which replaces the consecutive copies of a
+// line of output.
+const repeatedLineFormat = "... last line repeated %d times ...\n"
+
+// repeatedLine is the last line of output of a test, when repeated output is
+// collapsed by ScanConfig.CollapseRepeatedOutput.
+type repeatedLine struct {
+	line  string
+	count int
+}
+
+// collapseOutput returns true if output is the same as the previous line of
+// output of the test with id. The copy is not added to the output, instead the
+// line which follows the first copy is updated with the number of times the
+// line was repeated.
+func (p *Package) collapseOutput(id int, output string) bool {
+	if p.repeated == nil {
+		p.repeated = make(map[int]*repeatedLine)
+	}
+	last, ok := p.repeated[id]
+	if !ok || last.line != output {
+		p.repeated[id] = &repeatedLine{line: output}
+		return false
+	}
+
+	last.count++
+	marker := fmt.Sprintf(repeatedLineFormat, last.count)
+	if last.count == 1 {
+		p.output[id] = append(p.output[id], marker)
+		if p.maxOutputBytes > 0 {
+			p.limitOutput(id, len(marker))
+		}
+		return true
+	}
+	lines := p.output[id]
+	lines[len(lines)-1] = marker
+	return true
+}
+
+// collapseFormatter collapses consecutive copies of a line of output into a
+// single line, before the output is printed by the base formatter. The number
+// of copies is printed before the next event.
+type collapseFormatter struct {
+	base EventFormatter
+	// last is the last event printed, and repeated is the number of copies of
+	// the event which were not printed.
+	last     TestEvent
+	repeated int
+}
+
+func (f *collapseFormatter) Format(event TestEvent, exec *Execution) error {
+	if event.Action == ActionOutput && f.last.Action == ActionOutput &&
+		event.Package == f.last.Package && event.Test == f.last.Test &&
+		event.Output == f.last.Output {
+		f.repeated++
+		return nil
+	}
+	if f.repeated > 0 {
+		repeated := f.last
+		repeated.Output = fmt.Sprintf(repeatedLineFormat, f.repeated)
+		repeated.raw = nil
+		f.repeated = 0
+		if err := f.base.Format(repeated, exec); err != nil {
+			return err
+		}
+	}
+	f.last = event
+	return f.base.Format(event, exec)
+}
+
+func (f *collapseFormatter) Close() error {
+	return closeFormatter(f.base)
+}
@@ -0,0 +1,104 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+const repeatedOutputInput = `{"Action":"run","Package":"pkg","Test":"TestOne"}
+{"Action":"output","Package":"pkg","Test":"TestOne","Output":"=== RUN   TestOne\n"}
+{"Action":"output","Package":"pkg","Test":"TestOne","Output":"    one_test.go:10: retrying\n"}
+{"Action":"output","Package":"pkg","Test":"TestOne","Output":"    one_test.go:10: retrying\n"}
+{"Action":"run","Package":"pkg","Test":"TestTwo"}
+{"Action":"output","Package":"pkg","Test":"TestTwo","Output":"    two_test.go:10: retrying\n"}
+{"Action":"output","Package":"pkg","Test":"TestOne","Output":"    one_test.go:10: retrying\n"}
+{"Action":"output","Package":"pkg","Test":"TestOne","Output":"    one_test.go:12: gave up\n"}
+{"Action":"output","Package":"pkg","Test":"TestOne","Output":"    one_test.go:10: retrying\n"}
+{"Action":"output","Package":"pkg","Test":"TestOne","Output":"    one_test.go:10: retrying\n"}
+{"Action":"output","Package":"pkg","Test":"TestOne","Output":"--- FAIL: TestOne (0.00s)\n"}
+{"Action":"fail","Package":"pkg","Test":"TestOne"}
+{"Action":"output","Package":"pkg","Test":"TestTwo","Output":"    two_test.go:10: retrying\n"}
+{"Action":"output","Package":"pkg","Test":"TestTwo","Output":"    two_test.go:10: retrying\n"}
+{"Action":"output","Package":"pkg","Output":"FAIL\n"}
+{"Action":"fail","Package":"pkg"}
+`
+
+func TestScanTestOutput_CollapseRepeatedOutput(t *testing.T) {
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:                 strings.NewReader(repeatedOutputInput),
+		CollapseRepeatedOutput: true,
+	})
+	assert.NilError(t, err)
+
+	failed := exec.Failed()
+	assert.Equal(t, failed[0].Test.Name(), "TestOne")
+	assert.DeepEqual(t, exec.OutputLines(failed[0]), []string{
+		"=== RUN   TestOne\n",
+		"    one_test.go:10: retrying\n",
+		"... last line repeated 2 times ...\n",
+		"    one_test.go:12: gave up\n",
+		"    one_test.go:10: retrying\n",
+		"... last line repeated 1 times ...\n",
+		"--- FAIL: TestOne (0.00s)\n",
+	})
+}
+
+func TestScanTestOutput_CollapseRepeatedOutput_WithMaxTestOutputBytes(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(`{"Action":"run","Package":"pkg","Test":"TestOne"}` + "\n")
+	for range 1000 {
+		b.WriteString(`{"Action":"output","Package":"pkg","Test":"TestOne","Output":"    retrying\n"}` + "\n")
+	}
+	b.WriteString(`{"Action":"fail","Package":"pkg","Test":"TestOne"}` + "\n")
+
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:                 strings.NewReader(b.String()),
+		CollapseRepeatedOutput: true,
+		MaxTestOutputBytes:     100,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, exec.OutputLines(exec.Failed()[0]), []string{
+		"    retrying\n",
+		"... last line repeated 999 times ...\n",
+	})
+}
+
+func TestNewEventFormatter_CollapseRepeatedOutput(t *testing.T) {
+	buf := new(bytes.Buffer)
+	formatter := NewEventFormatter(buf, "standard-verbose", FormatOptions{CollapseRepeatedOutput: true})
+	_, err := ScanTestOutput(ScanConfig{
+		Stdout:  strings.NewReader(repeatedOutputInput),
+		Handler: &formatHandler{formatter: formatter},
+	})
+	assert.NilError(t, err)
+
+	expected := `=== RUN   TestOne
+    one_test.go:10: retrying
+... last line repeated 1 times ...
+    two_test.go:10: retrying
+    one_test.go:10: retrying
+    one_test.go:12: gave up
+    one_test.go:10: retrying
+... last line repeated 1 times ...
+--- FAIL: TestOne (0.00s)
+    two_test.go:10: retrying
+... last line repeated 1 times ...
+FAIL
+`
+	assert.Equal(t, buf.String(), expected)
+}
+
+type formatHandler struct {
+	formatter EventFormatter
+}
+
+func (h *formatHandler) Event(event TestEvent, exec *Execution) error {
+	return h.formatter.Format(event, exec)
+}
+
+func (h *formatHandler) Err(string) error {
+	return nil
+}
@@ -17,6 +17,40 @@ prints the number of tests run.
 	if err != nil {
 	    return fmt.Errorf("failed to scan testjson: %w", err)
 	}
-	fmt.Println("Ran %d tests", exec.Total())
+	fmt.Printf("Ran %d tests\n", exec.Total())
+
+# Embedding gotestsum
+
+A program which runs go test can print the same output as the gotestsum
+command by using NewFormatterHandler with a formatter from
+NewEventFormatter, and PrintSummaryWithConfig once the output has been
+scanned.
+
+	formatter := testjson.NewEventFormatter(os.Stdout, "pkgname", testjson.FormatOptions{})
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+	    Stdout:  stdout,
+	    Stderr:  stderr,
+	    Handler: testjson.NewFormatterHandler(formatter, os.Stderr),
+	})
+	if err != nil {
+	    return err
+	}
+	testjson.PrintSummaryWithConfig(os.Stdout, exec, testjson.SummaryConfig{
+	    Sections: testjson.SummarizeAll,
+	})
+
+# Compatibility
+
+The exported API of this package follows the semantic versioning of the
+gotest.tools/gotestsum module. Exported identifiers are not removed, and
+their behaviour does not change in an incompatible way, within a major
+version. New fields may be added to the config structs, so they should be
+created with named fields. The text printed by each format, and the
+summary, may change in any release.
+
+The runner package, which runs gotestsum from a Go program, follows the same
+rules. Every other package in the module, including everything under cmd and
+internal, is an implementation detail of the gotestsum command, and has no
+compatibility guarantees.
 */
 package testjson // import "gotest.tools/gotestsum/testjson"
@@ -8,13 +8,14 @@ import (
 	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/term"
 	"gotest.tools/gotestsum/internal/dotwriter"
 	"gotest.tools/gotestsum/internal/log"
 )
 
-func dotsFormatV1(out io.Writer) EventFormatter {
+func dotsFormatV1(out io.Writer, opts FormatOptions) EventFormatter {
 	buf := bufio.NewWriter(out)
 	return eventFormatterFunc(func(event TestEvent, exec *Execution) error {
 		pkg := exec.Package(event.Package)
@@ -25,22 +26,33 @@ func dotsFormatV1(out io.Writer) EventFormatter {
 			buf.WriteString("[" + RelativePackagePath(event.Package) + "]")
 			return buf.Flush()
 		}
-		buf.WriteString(fmtDot(event))
+		dot, _ := fmtDot(event, opts)
+		buf.WriteString(dot)
 		return buf.Flush()
 	})
 }
 
-func fmtDot(event TestEvent) string {
+// fmtDot returns the dot for the test event, and the width of the dot.
+func fmtDot(event TestEvent, opts FormatOptions) (string, int) {
+	if hasIconOption(opts) {
+		switch event.Action {
+		case ActionPass, ActionFail, ActionSkip:
+			icons := iconSetFromOptions(opts)
+			return icons.forAction(event.Action), utf8.RuneCountInString(icons.plain(event.Action))
+		}
+		return "", 0
+	}
+
 	withColor := colorEvent(event)
 	switch event.Action {
 	case ActionPass:
-		return withColor("·")
+		return withColor("·"), 1
 	case ActionFail:
-		return withColor("✖")
+		return withColor("✖"), 1
 	case ActionSkip:
-		return withColor("↷")
+		return withColor("↷"), 1
 	}
-	return ""
+	return "", 0
 }
 
 type dotFormatter struct {
@@ -57,12 +69,12 @@ type dotLine struct {
 	lastUpdate time.Time
 }
 
-func (l *dotLine) update(dot string) {
+func (l *dotLine) update(dot string, width int) {
 	if dot == "" {
 		return
 	}
 	l.builder.WriteString(dot)
-	l.runes++
+	l.runes += width
 }
 
 // checkWidth marks the line as full when the width of the line hits the
@@ -78,7 +90,7 @@ func newDotFormatter(out io.Writer, opts FormatOptions) EventFormatter {
 	w, _, err := term.GetSize(int(os.Stdout.Fd()))
 	if err != nil || w == 0 {
 		log.Warnf("Failed to detect terminal width for dots format, error: %v", err)
-		return dotsFormatV1(out)
+		return dotsFormatV1(out, opts)
 	}
 	return &dotFormatter{
 		pkgs:      make(map[string]*dotLine),
@@ -97,7 +109,7 @@ func (d *dotFormatter) Format(event TestEvent, exec *Execution) error {
 	line.lastUpdate = event.Time
 
 	if !event.PackageEvent() {
-		line.update(fmtDot(event))
+		line.update(fmtDot(event, d.opts))
 	}
 	switch event.Action {
 	case ActionOutput, ActionBench:
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -30,6 +31,8 @@ const (
 	ActionSkip   Action = "skip"
 	ActionBuild  Action = "build-output"
 	ActionAttr   Action = "attr"
+	// ActionStart is sent when a package starts, by go1.20 and later.
+	ActionStart Action = "start"
 )
 
 // IsTerminal returns true if the Action is one of: pass, fail, skip.
@@ -70,6 +73,8 @@ func (e TestEvent) PackageEvent() bool {
 }
 
 // Bytes returns the serialized JSON bytes that were parsed to create the event.
+// The bytes are only valid until EventHandler.Event returns, they must be
+// copied to be used after that.
 func (e TestEvent) Bytes() []byte {
 	return e.raw
 }
@@ -121,6 +126,30 @@ type Package struct {
 	// output caused by a test timeout. This is necessary to work around a race
 	// condition in test2json. See https://github.com/golang/go/issues/57305.
 	testTimeoutPanicInTest string
+
+	// keepPassedOutput is true when the output of passed tests should not be
+	// removed. See ScanConfig.KeepPassedOutput.
+	keepPassedOutput bool
+	// maxOutputBytes limits the output kept for each test. See
+	// ScanConfig.MaxTestOutputBytes.
+	maxOutputBytes int
+	outputSize     map[int]*outputSize
+	// collapseRepeatedOutput replaces consecutive copies of a line of output
+	// with a line that says how many times it was repeated. See
+	// ScanConfig.CollapseRepeatedOutput.
+	collapseRepeatedOutput bool
+	repeated               map[int]*repeatedLine
+
+	// benchmarks are the results of the benchmarks in the package.
+	benchmarks []Benchmark
+	// testBenchmarks are the results of each benchmark, indexed by
+	// TestCase.ID.
+	testBenchmarks map[int][]Benchmark
+	// benchmarkLine is the start of a benchmark result line, which was split
+	// into more than one output event.
+	benchmarkLine string
+	// fuzz is the progress of the fuzz tests run with go test -fuzz.
+	fuzz []*Fuzz
 }
 
 // Result returns if the package passed, failed, or was skipped because there
@@ -135,6 +164,18 @@ func (p *Package) Elapsed() time.Duration {
 	return p.elapsed
 }
 
+// Coverage returns the code coverage output of the package, for example
+// "coverage: 91.1% of statements", or an empty string if the package was not
+// run with -cover.
+func (p *Package) Coverage() string {
+	return p.coverage
+}
+
+// Cached returns true if the results of the package were cached by go test.
+func (p *Package) Cached() bool {
+	return p.cached
+}
+
 // TestCases returns all the test cases.
 func (p *Package) TestCases() []TestCase {
 	tc := append([]TestCase{}, p.Passed...)
@@ -158,6 +199,17 @@ func (p *Package) LastFailedByName(name string) TestCase {
 	return TestCase{}
 }
 
+// PassedOnRerun returns true if the test tc failed, and then passed on a later
+// run of the same test, for example when it was rerun by --rerun-fails.
+func (p *Package) PassedOnRerun(tc TestCase) bool {
+	for _, passed := range p.Passed {
+		if passed.Test == tc.Test && passed.ID > tc.ID {
+			return true
+		}
+	}
+	return false
+}
+
 // Output returns the full test output for a test. Unlike OutputLines() it does
 // not return lines from subtests in some cases.
 //
@@ -168,8 +220,16 @@ func (p *Package) Output(id int) string {
 
 // WriteOutputTo writes the output for TestCase with id to out.
 func (p *Package) WriteOutputTo(out io.StringWriter, id int) error {
+	return p.writeOutputTo(out, id, 0)
+}
+
+// writeOutputTo writes the output for TestCase with id to out, the same as
+// WriteOutputTo, with lines longer than maxLineLength truncated. Formats use
+// it to print the output of failed tests, so that FormatOptions.MaxLineLength
+// applies to output which was not printed as the events were received.
+func (p *Package) writeOutputTo(out io.StringWriter, id int, maxLineLength int) error {
 	for _, v := range p.output[id] {
-		if _, err := out.WriteString(v); err != nil {
+		if _, err := out.WriteString(truncateLine(v, maxLineLength)); err != nil {
 			return err
 		}
 	}
@@ -209,9 +269,42 @@ func (p *Package) addOutput(id int, output string) {
 	if strings.HasPrefix(output, "WARNING: DATA RACE") {
 		p.hasDataRace = true
 	}
+	if p.collapseRepeatedOutput && p.collapseOutput(id, output) {
+		return
+	}
 	p.output[id] = append(p.output[id], output)
+	if p.maxOutputBytes > 0 {
+		p.limitOutput(id, len(output))
+	}
+}
+
+// SkipReason returns the message logged by t.Skip for a skipped test case. If
+// the test did not log a message before it was skipped, or if the output is not
+// available, an empty string is returned.
+func (p *Package) SkipReason(tc TestCase) string {
+	return logMessage(p.output[tc.ID], tc.Test.Name())
+}
+
+// logMessage returns the message from the last line of lines which was written
+// by t.Log, t.Skip, or t.Error.
+func logMessage(lines []string, testName string) string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimRight(lines[i], "\n")
+		if isFramingLine(line, testName) {
+			continue
+		}
+		if match := testLogLinePattern.FindStringSubmatch(line); match != nil {
+			return strings.TrimSpace(match[1])
+		}
+	}
+	return ""
 }
 
+// testLogLinePattern matches a line of output from t.Log, which is prefixed by
+// the file name and line number. Older versions of Go also prefix the line with
+// the test name.
+var testLogLinePattern = regexp.MustCompile(`^\s+(?:[^\s:]+: )?[^\s:]+\.go:\d+: ?(.*)$`)
+
 type TestName string
 
 func (n TestName) Split() (root string, sub string) {
@@ -242,11 +335,18 @@ func (n TestName) Parent() string {
 
 func (p *Package) removeOutput(id int) {
 	delete(p.output, id)
+	delete(p.outputSize, id)
+	delete(p.repeated, id)
+	if len(p.subTests[id]) == 0 {
+		return
+	}
 
 	skipped := tcIDSet(p.Skipped)
 	for _, sub := range p.subTests[id] {
 		if _, isSkipped := skipped[sub]; !isSkipped {
 			delete(p.output, sub)
+			delete(p.outputSize, sub)
+			delete(p.repeated, sub)
 		}
 	}
 }
@@ -270,6 +370,12 @@ func (p *Package) TestMainFailed() bool {
 	return p.action == ActionFail && len(p.Failed) == 0
 }
 
+// HasPanic returns true if the package, or one of the tests in the package, had
+// output that looked like a panic.
+func (p *Package) HasPanic() bool {
+	return p.panicked
+}
+
 // IsEmpty returns true if this package contains no tests.
 func (p *Package) IsEmpty() bool {
 	return p.Total == 0 && !p.TestMainFailed()
@@ -364,9 +470,10 @@ func (c TestCase) addAttribute(key string, value string) TestCase {
 
 func newPackage() *Package {
 	return &Package{
-		output:   make(map[int][]string),
-		running:  make(map[string]TestCase),
-		subTests: make(map[int][]int),
+		output:     make(map[int][]string),
+		outputSize: make(map[int]*outputSize),
+		running:    make(map[string]TestCase),
+		subTests:   make(map[int][]int),
 	}
 }
 
@@ -380,12 +487,23 @@ type Execution struct {
 	errors     []string
 	done       bool
 	lastRunID  int
+	// keepPassedOutput is copied to each new Package.
+	keepPassedOutput bool
+	// maxOutputBytes is copied to each new Package.
+	maxOutputBytes int
+	// collapseRepeatedOutput is copied to each new Package.
+	collapseRepeatedOutput bool
+	// nonJSON attributes the non-JSON lines of stdout during a scan.
+	nonJSON nonJSONLines
 }
 
 func (e *Execution) add(event TestEvent) {
 	pkg, ok := e.packages[event.Package]
 	if !ok {
 		pkg = newPackage()
+		pkg.keepPassedOutput = e.keepPassedOutput
+		pkg.maxOutputBytes = e.maxOutputBytes
+		pkg.collapseRepeatedOutput = e.collapseRepeatedOutput
 		e.packages[event.Package] = pkg
 	}
 
@@ -428,6 +546,7 @@ func (p *Package) addEvent(event TestEvent) {
 		if isShuffleSeedOutput(event.Output) {
 			p.shuffleSeed = strings.TrimRight(event.Output, "\n")
 		}
+		p.addBenchmarkOutput(event)
 		p.addOutput(0, event.Output)
 	}
 }
@@ -477,6 +596,8 @@ func (p *Package) addTestEvent(event TestEvent) {
 		}
 
 		tc := p.running[event.Test]
+		p.addBenchmarkOutput(event)
+		p.addFuzzOutput(tc, event.Output)
 		p.addOutput(tc.ID, event.Output)
 		return
 	case ActionAttr:
@@ -515,7 +636,9 @@ func (p *Package) addTestEvent(event TestEvent) {
 		}
 
 		// Remove test output once a test passes, it wont be used.
-		p.removeOutput(tc.ID)
+		if !p.keepPassedOutput {
+			p.removeOutput(tc.ID)
+		}
 	}
 }
 
@@ -558,6 +681,16 @@ func (e *Execution) OutputLines(tc TestCase) []string {
 	return e.packages[tc.Package].OutputLines(tc)
 }
 
+// SkipReason returns the message logged by t.Skip for a skipped test case.
+// See Package.SkipReason for more details.
+func (e *Execution) SkipReason(tc TestCase) string {
+	pkg := e.packages[tc.Package]
+	if pkg == nil {
+		return ""
+	}
+	return pkg.SkipReason(tc)
+}
+
 // Package returns the Package by name.
 func (e *Execution) Package(name string) *Package {
 	return e.packages[name]
@@ -746,6 +879,30 @@ type ScanConfig struct {
 	// IgnoreNonJSONOutputLines causes ScanTestOutput to ignore non-JSON lines received from
 	// the Stdout reader. Instead of causing an error, the lines will be sent to Handler.Err.
 	IgnoreNonJSONOutputLines bool
+	// AttributeNonJSONOutputLines adds the non-JSON lines received from the
+	// Stdout reader to the output of the test which received the last event,
+	// or its package when the test has ended, instead of causing an error. The
+	// lines received before the first event are added to the package of the
+	// first event. The lines are sent to Handler.Event as output events.
+	// IgnoreNonJSONOutputLines takes precedence.
+	AttributeNonJSONOutputLines bool
+	// KeepPassedOutput keeps the output of tests that passed in the Execution.
+	// By default the output of a test is removed when it passes, because it
+	// is not printed in the summary.
+	KeepPassedOutput bool
+	// MaxTestOutputBytes limits the output kept in the Execution for each test,
+	// and for the package output. When the output of a test is larger, lines
+	// are removed from the middle of the output. Zero means no limit.
+	MaxTestOutputBytes int
+	// ParseWorkers is the number of goroutines used to parse the events from
+	// Stdout. When it is greater than zero, reading, parsing, and handling the
+	// events happen at the same time, which is faster for a large stream of
+	// events. The events are always handled in the order they were read.
+	ParseWorkers int
+	// CollapseRepeatedOutput replaces consecutive copies of a line of output of
+	// a test, in the Execution, with a single copy followed by a line that says
+	// how many times the line was repeated.
+	CollapseRepeatedOutput bool
 }
 
 // EventHandler is called by ScanTestOutput for each event and write to stderr.
@@ -781,9 +938,19 @@ func ScanTestOutput(config ScanConfig) (*Execution, error) {
 	}
 	execution.done = false
 	execution.lastRunID = config.RunID
+	if config.KeepPassedOutput {
+		execution.keepPassedOutput = true
+	}
+	if config.MaxTestOutputBytes > 0 {
+		execution.maxOutputBytes = config.MaxTestOutputBytes
+	}
+	if config.CollapseRepeatedOutput {
+		execution.collapseRepeatedOutput = true
+	}
 
 	var group errgroup.Group
 	group.Go(func() error {
+		defer endNonJSONLines(config, execution)
 		return stopOnError(config.Stop, readStdout(config, execution))
 	})
 	group.Go(func() error {
@@ -808,27 +975,14 @@ func stopOnError(stop func(), err error) error {
 }
 
 func readStdout(config ScanConfig, execution *Execution) error {
-	scanner := bufio.NewScanner(config.Stdout)
+	if config.ParseWorkers > 0 {
+		return readStdoutParallel(config, execution)
+	}
+	scanner := newLineScanner(config.Stdout)
 	for scanner.Scan() {
 		raw := scanner.Bytes()
 		event, err := parseEvent(raw)
-		switch {
-		case err == errBadEvent:
-			//nolint:errcheck
-			config.Handler.Err(errBadEvent.Error() + ": " + scanner.Text())
-			continue
-		case err != nil:
-			if config.IgnoreNonJSONOutputLines {
-				//nolint:errcheck
-				config.Handler.Err(string(raw))
-				continue
-			}
-			return fmt.Errorf("failed to parse test output: %s: %w", string(raw), err)
-		}
-
-		event.RunID = config.RunID
-		execution.add(event)
-		if err := config.Handler.Event(event, execution); err != nil {
+		if err := handleEvent(config, execution, raw, event, err); err != nil {
 			return err
 		}
 	}
@@ -838,6 +992,40 @@ func readStdout(config ScanConfig, execution *Execution) error {
 	return nil
 }
 
+// handleEvent adds the event parsed from the line raw to the execution, and
+// calls the handler. parseErr is the error from parseEvent.
+func handleEvent(config ScanConfig, execution *Execution, raw []byte, event TestEvent, parseErr error) error {
+	switch {
+	case parseErr == errBadEvent:
+		//nolint:errcheck
+		config.Handler.Err(errBadEvent.Error() + ": " + string(raw))
+		return nil
+	case parseErr != nil:
+		if config.IgnoreNonJSONOutputLines {
+			//nolint:errcheck
+			config.Handler.Err(string(raw))
+			return nil
+		}
+		if config.AttributeNonJSONOutputLines {
+			return handleNonJSONLine(config, execution, string(raw))
+		}
+		return fmt.Errorf("failed to parse test output: %s: %w", string(raw), parseErr)
+	}
+
+	if err := handlePendingNonJSONLines(config, execution, event); err != nil {
+		return err
+	}
+	for _, end := range execution.endBenchmarks(event) {
+		if err := handleEvent(config, execution, nil, end, nil); err != nil {
+			return err
+		}
+	}
+	event.RunID = config.RunID
+	execution.add(event)
+	execution.nonJSON.track(event)
+	return config.Handler.Event(event, execution)
+}
+
 func readStderr(config ScanConfig, execution *Execution) error {
 	scanner := bufio.NewScanner(config.Stderr)
 	for scanner.Scan() {
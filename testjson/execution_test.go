@@ -315,3 +315,81 @@ func TestFilterFailedUnique_NestedWithGaps(t *testing.T) {
 	cmpTestCase := cmp.AllowUnexported(TestCase{})
 	assert.DeepEqual(t, expected, actual, cmpTestCase)
 }
+
+func TestPackage_PassedOnRerun(t *testing.T) {
+	pkg := newPackage()
+	pkg.Failed = []TestCase{
+		{ID: 1, Test: "TestFlaky"},
+		{ID: 2, Test: "TestBroken"},
+		{ID: 4, Test: "TestBroken"},
+	}
+	pkg.Passed = []TestCase{
+		{ID: 3, Test: "TestFlaky"},
+		{ID: 0, Test: "TestBroken"},
+	}
+	assert.Assert(t, pkg.PassedOnRerun(pkg.Failed[0]))
+	assert.Assert(t, !pkg.PassedOnRerun(pkg.Failed[1]))
+	assert.Assert(t, !pkg.PassedOnRerun(pkg.Failed[2]))
+}
+
+func TestPackage_SkipReason(t *testing.T) {
+	type testCase struct {
+		name     string
+		output   []string
+		expected string
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		pkg := newPackage()
+		skipped := TestCase{ID: 1, Test: "TestSkipped"}
+		pkg.output[skipped.ID] = tc.output
+		assert.Equal(t, pkg.SkipReason(skipped), tc.expected)
+	}
+
+	testCases := []testCase{
+		{
+			name: "with message",
+			output: []string{
+				"=== RUN   TestSkipped\n",
+				"    some_test.go:12: not on this platform\n",
+				"--- SKIP: TestSkipped (0.00s)\n",
+			},
+			expected: "not on this platform",
+		},
+		{
+			name: "empty message",
+			output: []string{
+				"=== RUN   TestSkipped\n",
+				"    some_test.go:12: \n",
+				"--- SKIP: TestSkipped (0.00s)\n",
+			},
+		},
+		{
+			name: "last log line is the message",
+			output: []string{
+				"=== RUN   TestSkipped\n",
+				"    some_test.go:10: a log line\n",
+				"    some_test.go:12: the reason\n",
+				"--- SKIP: TestSkipped (0.00s)\n",
+			},
+			expected: "the reason",
+		},
+		{
+			name: "prefixed with test name",
+			output: []string{
+				"    TestSkipped: some_test.go:12: the reason\n",
+			},
+			expected: "the reason",
+		},
+		{
+			name:   "no output",
+			output: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package testjson
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FoldConfig configures how the output of a failed test is folded into a
+// short headline. The full output is still available in the jsonfile and
+// junit reports.
+type FoldConfig struct {
+	// MinLines is the number of lines of output a failed test must have
+	// before the output is folded.
+	MinLines int
+	// Patterns match the lines which are included in the headline, for
+	// example assertion messages.
+	Patterns []*regexp.Regexp
+	// TailLines is the number of lines from the end of the output which are
+	// included in the headline.
+	TailLines int
+}
+
+// DefaultFoldPatterns match the lines printed by common assertion libraries,
+// and by tests that compare a got and want value.
+var DefaultFoldPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`assertion failed`),
+	regexp.MustCompile(`^\s+Error( Trace)?:`),
+	regexp.MustCompile(`(?i)\b(got|want|expected|actual)\b`),
+}
+
+var (
+	panicLinePattern      = regexp.MustCompile(`^panic: `)
+	stackFrameFilePattern = regexp.MustCompile(`^\t\S+\.go:\d+`)
+	stdlibFramePattern    = regexp.MustCompile(`/src/(runtime|testing|reflect)/`)
+)
+
+// Headline returns the most relevant lines of output from a failed test. The
+// headline includes lines matched by Patterns, the panic message and the
+// first stack frame in user code, and the last TailLines lines. The lines are
+// returned in their original order, followed by a line which reports the
+// number of lines that were folded.
+//
+// If the output has fewer than MinLines lines, or MinLines is 0, lines is
+// returned unmodified.
+func (c FoldConfig) Headline(lines []string) []string {
+	if c.MinLines <= 0 || len(lines) < c.MinLines {
+		return lines
+	}
+
+	keep := make([]bool, len(lines))
+	inPanic := false
+	for i, line := range lines {
+		switch {
+		case panicLinePattern.MatchString(line):
+			keep[i] = true
+			inPanic = true
+		case inPanic && stackFrameFilePattern.MatchString(line) && !stdlibFramePattern.MatchString(line):
+			// include the function name, which is on the line before the file
+			keep[i] = true
+			keep[i-1] = true
+			inPanic = false
+		case matchesAny(c.Patterns, line):
+			keep[i] = true
+		}
+	}
+	for i := len(lines) - c.TailLines; i < len(lines); i++ {
+		if i >= 0 {
+			keep[i] = true
+		}
+	}
+
+	var result []string
+	var folded int
+	for i, line := range lines {
+		if !keep[i] {
+			folded++
+			continue
+		}
+		result = append(result, line)
+	}
+	if folded == 0 {
+		return lines
+	}
+	unit := "lines"
+	if folded == 1 {
+		unit = "line"
+	}
+	return append(result, fmt.Sprintf(
+		"    ... %d %s folded, see the jsonfile or junitfile for full output\n", folded, unit))
+}
+
+func matchesAny(patterns []*regexp.Regexp, line string) bool {
+	line = strings.TrimSuffix(line, "\n")
+	for _, p := range patterns {
+		if p.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
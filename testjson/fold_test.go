@@ -0,0 +1,101 @@
+package testjson
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestFoldConfig_Headline(t *testing.T) {
+	lines := multiLine(`    main_test.go:10: setting up
+    main_test.go:11: still setting up
+    main_test.go:20: assertion failed: 1 (got int) != 2 (want int)
+    main_test.go:21: one
+    main_test.go:22: two
+    main_test.go:23: three
+`)
+	lines = lines[:len(lines)-1] // remove the empty line after the last newline
+
+	t.Run("too short to fold", func(t *testing.T) {
+		conf := FoldConfig{MinLines: 10, Patterns: DefaultFoldPatterns, TailLines: 1}
+		assert.DeepEqual(t, conf.Headline(lines), lines)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		conf := FoldConfig{Patterns: DefaultFoldPatterns, TailLines: 1}
+		assert.DeepEqual(t, conf.Headline(lines), lines)
+	})
+
+	t.Run("assertion and tail", func(t *testing.T) {
+		conf := FoldConfig{MinLines: 3, Patterns: DefaultFoldPatterns, TailLines: 2}
+		expected := []string{
+			"    main_test.go:20: assertion failed: 1 (got int) != 2 (want int)\n",
+			"    main_test.go:22: two\n",
+			"    main_test.go:23: three\n",
+			"    ... 3 lines folded, see the jsonfile or junitfile for full output\n",
+		}
+		assert.DeepEqual(t, conf.Headline(lines), expected)
+	})
+
+	t.Run("custom pattern", func(t *testing.T) {
+		conf := FoldConfig{
+			MinLines: 3,
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`setting up$`)},
+		}
+		expected := []string{
+			"    main_test.go:10: setting up\n",
+			"    main_test.go:11: still setting up\n",
+			"    ... 4 lines folded, see the jsonfile or junitfile for full output\n",
+		}
+		assert.DeepEqual(t, conf.Headline(lines), expected)
+	})
+
+	t.Run("everything matches", func(t *testing.T) {
+		conf := FoldConfig{MinLines: 3, TailLines: 10}
+		assert.DeepEqual(t, conf.Headline(lines), lines)
+	})
+}
+
+func TestFoldConfig_Headline_Panic(t *testing.T) {
+	lines := multiLine(`panic: runtime error: index out of range [recovered]
+	panic: runtime error: index out of range
+
+goroutine 7 [running]:
+testing.tRunner.func1.2({0x5e5f40, 0xc000016108})
+	/usr/local/go/src/testing/testing.go:1545 +0x238
+panic({0x5e5f40?, 0xc000016108?})
+	/usr/local/go/src/runtime/panic.go:914 +0x21f
+example.com/pkg.TestPanics(0x0?)
+	/home/user/pkg/pkg_test.go:12 +0x1d
+testing.tRunner(0xc0000a6820, 0x615c78)
+	/usr/local/go/src/testing/testing.go:1595 +0xff
+`)
+	lines = lines[:len(lines)-1]
+
+	conf := FoldConfig{MinLines: 3, Patterns: DefaultFoldPatterns}
+	expected := []string{
+		"panic: runtime error: index out of range [recovered]\n",
+		"example.com/pkg.TestPanics(0x0?)\n",
+		"\t/home/user/pkg/pkg_test.go:12 +0x1d\n",
+		"    ... 9 lines folded, see the jsonfile or junitfile for full output\n",
+	}
+	assert.DeepEqual(t, conf.Headline(lines), expected)
+}
+
+func TestPrintSummaryWithConfig_FoldFailures(t *testing.T) {
+	exec, err := ScanTestOutput(scanConfigFromGolden("input/go-test-json.out")(t))
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	PrintSummaryWithConfig(buf, exec, SummaryConfig{
+		Sections: SummarizeFailed | SummarizeOutput,
+		FoldFailures: FoldConfig{
+			MinLines: 2,
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`(failed sub [ab]|this is stderr)$`)},
+		},
+	})
+	golden.Assert(t, buf.String(), "summary/fold-failures")
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/bitfield/gotestdox"
 	"github.com/fatih/color"
+	"golang.org/x/term"
 )
 
 func debugFormat(out io.Writer) eventFormatterFunc {
@@ -76,14 +77,45 @@ func standardJSONFormat(out io.Writer) EventFormatter {
 	})
 }
 
-func testNameFormatTestEvent(out io.Writer, event TestEvent) {
+func testNameFormatTestEvent(out io.Writer, event TestEvent, exec *Execution, opts FormatOptions) {
 	pkgPath := RelativePackagePath(event.Package)
 
-	fmt.Fprintf(out, "%s %s%s (%.2fs)\n",
-		colorEvent(event)(strings.ToUpper(string(event.Action))),
+	result := colorEvent(event)(strings.ToUpper(string(event.Action)))
+	if hasIconOption(opts) {
+		result = getIconFunc(opts)(event.Action)
+	}
+	fmt.Fprintf(out, "%s %s%s (%.2fs)%s\n",
+		result,
 		joinPkgToTestName(pkgPath, event.Test),
 		formatRunID(event.RunID),
-		event.Elapsed)
+		event.Elapsed,
+		formatSkipReason(skipReasonForEvent(event, exec)))
+}
+
+// skipReasonForEvent returns the skip reason of the test case which produced
+// the skip event.
+func skipReasonForEvent(event TestEvent, exec *Execution) string {
+	if event.Action != ActionSkip || exec == nil {
+		return ""
+	}
+	pkg := exec.Package(event.Package)
+	if pkg == nil {
+		return ""
+	}
+	for i := len(pkg.Skipped) - 1; i >= 0; i-- {
+		if tc := pkg.Skipped[i]; tc.Test.Name() == event.Test {
+			return pkg.SkipReason(tc)
+		}
+	}
+	return ""
+}
+
+// formatSkipReason returns a formatted string of the skip reason.
+func formatSkipReason(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return ": " + reason
 }
 
 func testDoxFormat(out io.Writer, opts FormatOptions) EventFormatter {
@@ -138,12 +170,12 @@ func isFuzzCase(event TestEvent) bool {
 		TestName(event.Test).IsSubTest()
 }
 
-func testNameFormat(out io.Writer) EventFormatter {
+func testNameFormat(out io.Writer, opts FormatOptions) EventFormatter {
 	buf := bufio.NewWriter(out)
 	//nolint:errcheck
 	return eventFormatterFunc(func(event TestEvent, exec *Execution) error {
 		formatTest := func() error {
-			testNameFormatTestEvent(buf, event)
+			testNameFormatTestEvent(buf, event, exec, opts)
 			return buf.Flush()
 		}
 
@@ -159,9 +191,12 @@ func testNameFormat(out io.Writer) EventFormatter {
 
 			result := colorEvent(event)(strings.ToUpper(string(event.Action)))
 			pkg := exec.Package(event.Package)
-			if event.Action == ActionSkip || (event.Action == ActionPass && pkg.Total == 0) {
+			switch {
+			case event.Action == ActionSkip || (event.Action == ActionPass && pkg.Total == 0):
 				event.Action = ActionSkip // always color these as skip actions
 				result = colorEvent(event)("EMPTY")
+			case hasIconOption(opts):
+				result = getIconFunc(opts)(event.Action)
 			}
 
 			event.Elapsed = 0 // hide elapsed for now, for backwards compat
@@ -173,7 +208,7 @@ func testNameFormat(out io.Writer) EventFormatter {
 		case event.Action == ActionFail:
 			pkg := exec.Package(event.Package)
 			tc := pkg.LastFailedByName(event.Test)
-			pkg.WriteOutputTo(buf, tc.ID)
+			pkg.writeOutputTo(buf, tc.ID, opts.MaxLineLength)
 			return formatTest()
 
 		case event.Action == ActionPass || event.Action == ActionSkip:
@@ -183,6 +218,62 @@ func testNameFormat(out io.Writer) EventFormatter {
 	})
 }
 
+// streamFormat prints the output of each test as soon as it is received,
+// prefixed with the name of the test. Unlike the testname format, which prints
+// the output of a test when it fails, streamFormat is useful for debugging tests
+// that hang, because the output is visible before the test ends.
+func streamFormat(out io.Writer, opts FormatOptions) EventFormatter {
+	buf := bufio.NewWriter(out)
+	testname := testNameFormat(out, opts)
+	//nolint:errcheck
+	return eventFormatterFunc(func(event TestEvent, exec *Execution) error {
+		switch {
+		case event.Test == "":
+			return testname.Format(event, exec)
+
+		case event.Action == ActionRun:
+			fmt.Fprintf(buf, "%s %s%s\n",
+				colorEvent(event)("=== RUN"),
+				joinPkgToTestName(RelativePackagePath(event.Package), event.Test),
+				formatRunID(event.RunID))
+			return buf.Flush()
+
+		case event.Action == ActionOutput:
+			if isStreamFramingLine(event.Output) {
+				return nil
+			}
+			buf.WriteString(joinPkgToTestName(RelativePackagePath(event.Package), event.Test))
+			buf.WriteString(": ")
+			buf.WriteString(strings.TrimPrefix(event.Output, "    "))
+			if !strings.HasSuffix(event.Output, "\n") {
+				buf.WriteString("\n")
+			}
+			return buf.Flush()
+
+		case event.Action.IsTerminal():
+			testNameFormatTestEvent(buf, event, exec, opts)
+			return buf.Flush()
+		}
+		return nil
+	})
+}
+
+// isStreamFramingLine returns true for the lines printed by 'go test -v' at
+// the start and end of every test and subtest. streamFormat prints these
+// events as its own lines.
+func isStreamFramingLine(line string) bool {
+	line = strings.TrimLeft(line, " ")
+	for _, prefix := range []string{
+		"=== RUN ", "=== PAUSE ", "=== CONT ", "=== NAME ",
+		"--- PASS: ", "--- FAIL: ", "--- SKIP: ",
+	} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // joinPkgToTestName for formatting.
 // If the package path isn't the current directory, we add a period to separate
 // the test name and the package path. If it is the current directory, we don't
@@ -242,84 +333,132 @@ func pkgNameFormat(out io.Writer, opts FormatOptions) eventFormatterFunc {
 	}
 }
 
-type icons struct {
-	pass  string
-	fail  string
-	skip  string
-	color bool
+// IconSet is the set of icons used by formats to show the result of a test or
+// package.
+type IconSet struct {
+	Pass string `json:"pass"`
+	Skip string `json:"skip"`
+	Fail string `json:"fail"`
+	// Color the icons green, yellow, or red.
+	Color bool `json:"color"`
 }
 
-func (i icons) forAction(action Action) string {
-	if i.color {
+func (i IconSet) forAction(action Action) string {
+	if i.Color {
 		switch action {
 		case ActionPass:
-			return color.GreenString(i.pass)
+			return color.GreenString(i.Pass)
 		case ActionSkip:
-			return color.YellowString(i.skip)
+			return color.YellowString(i.Skip)
 		case ActionFail:
-			return color.RedString(i.fail)
+			return color.RedString(i.Fail)
 		default:
 			return " "
 		}
 	} else {
 		switch action {
 		case ActionPass:
-			return i.pass
+			return i.Pass
 		case ActionSkip:
-			return i.skip
+			return i.Skip
 		case ActionFail:
-			return i.fail
+			return i.Fail
 		default:
 			return " "
 		}
 	}
 }
 
-func getIconFunc(opts FormatOptions) func(Action) string {
+// plain returns the icon for action without color.
+func (i IconSet) plain(action Action) string {
+	i.Color = false
+	return i.forAction(action)
+}
+
+var defaultIconSet = IconSet{
+	Pass:  "✓", // CHECK MARK
+	Skip:  "∅", // EMPTY SET
+	Fail:  "✖", // HEAVY MULTIPLICATION X
+	Color: true,
+}
+
+var hivisIconSet = IconSet{
+	Pass:  "✅", // WHITE HEAVY CHECK MARK
+	Skip:  "➖", // HEAVY MINUS SIGN
+	Fail:  "❌", // CROSS MARK
+	Color: false,
+}
+
+var codiconsIconSet = IconSet{
+	Pass:  "\ueba4", // cod-pass
+	Skip:  "\ueabd", // cod-circle_slash
+	Fail:  "\uea87", // cod-error
+	Color: true,
+}
+
+// iconSets are the built-in icon sets, by the name used for --format-icons.
+var iconSets = map[string]IconSet{
+	"default": defaultIconSet,
+	"unicode": defaultIconSet,
+	"hivis":   hivisIconSet,
+	"emoji":   hivisIconSet,
+	"text": {
+		Pass:  "PASS",
+		Skip:  "SKIP",
+		Fail:  "FAIL",
+		Color: true,
+	},
+	"ascii": {
+		Pass:  "+",
+		Skip:  "-",
+		Fail:  "x",
+		Color: true,
+	},
+	"codicons":  codiconsIconSet,
+	"nerdfonts": codiconsIconSet,
+	"octicons": {
+		Pass:  "\uf49e", // oct-check_circle
+		Skip:  "\uf517", // oct-skip
+		Fail:  "\uf52f", // oct-x_circle
+		Color: true,
+	},
+	"emoticons": {
+		Pass:  "\U000f01f5", // md-emoticon_happy_outline
+		Skip:  "\U000f01f6", // md-emoticon_neutral_outline
+		Fail:  "\U000f01f8", // md-emoticon_sad_outline
+		Color: true,
+	},
+}
+
+// IsBuiltinIconSet returns true if name is the name of one of the built-in
+// icon sets.
+func IsBuiltinIconSet(name string) bool {
+	_, ok := iconSets[name]
+	return ok
+}
+
+func iconSetFromOptions(opts FormatOptions) IconSet {
 	switch {
-	case opts.UseHiVisibilityIcons || opts.Icons == "hivis":
-		return icons{
-			pass:  "✅", // WHITE HEAVY CHECK MARK
-			skip:  "➖", // HEAVY MINUS SIGN
-			fail:  "❌", // CROSS MARK
-			color: false,
-		}.forAction
-	case opts.Icons == "text":
-		return icons{
-			pass:  "PASS",
-			skip:  "SKIP",
-			fail:  "FAIL",
-			color: true,
-		}.forAction
-	case opts.Icons == "codicons":
-		return icons{
-			pass:  "\ueba4", // cod-pass
-			skip:  "\ueabd", // cod-circle_slash
-			fail:  "\uea87", // cod-error
-			color: true,
-		}.forAction
-	case opts.Icons == "octicons":
-		return icons{
-			pass:  "\uf49e", // oct-check_circle
-			skip:  "\uf517", // oct-skip
-			fail:  "\uf52f", // oct-x_circle
-			color: true,
-		}.forAction
-	case opts.Icons == "emoticons":
-		return icons{
-			pass:  "\U000f01f5", // md-emoticon_happy_outline
-			skip:  "\U000f01f6", // md-emoticon_neutral_outline
-			fail:  "\U000f01f8", // md-emoticon_sad_outline
-			color: true,
-		}.forAction
-	default:
-		return icons{
-			pass:  "✓", // CHECK MARK
-			skip:  "∅", // EMPTY SET
-			fail:  "✖", // HEAVY MULTIPLICATION X
-			color: true,
-		}.forAction
+	case opts.CustomIcons != nil:
+		return *opts.CustomIcons
+	case opts.UseHiVisibilityIcons:
+		return hivisIconSet
+	}
+	if set, ok := iconSets[opts.Icons]; ok {
+		return set
 	}
+	return defaultIconSet
+}
+
+// hasIconOption returns true if the icons were set by an option. Formats which
+// print words or symbols other than the default icons only use the icon set
+// when it was set by an option.
+func hasIconOption(opts FormatOptions) bool {
+	return opts.CustomIcons != nil || opts.UseHiVisibilityIcons || opts.Icons != ""
+}
+
+func getIconFunc(opts FormatOptions) func(Action) string {
+	return iconSetFromOptions(opts).forAction
 }
 
 func shortFormatPackageEvent(opts FormatOptions, event TestEvent, exec *Execution) string {
@@ -379,7 +518,7 @@ func pkgNameWithFailuresFormat(out io.Writer, opts FormatOptions) eventFormatter
 			if event.Action == ActionFail {
 				pkg := exec.Package(event.Package)
 				tc := pkg.LastFailedByName(event.Test)
-				pkg.WriteOutputTo(buf, tc.ID) //nolint:errcheck
+				pkg.writeOutputTo(buf, tc.ID, opts.MaxLineLength) //nolint:errcheck
 				return buf.Flush()
 			}
 			return nil
@@ -389,6 +528,28 @@ func pkgNameWithFailuresFormat(out io.Writer, opts FormatOptions) eventFormatter
 	}
 }
 
+// failuresOnlyFormat prints the output of failed tests and packages as soon as
+// they fail, and nothing else. A run where all the tests pass prints nothing.
+func failuresOnlyFormat(out io.Writer, opts FormatOptions) EventFormatter {
+	buf := bufio.NewWriter(out)
+	return eventFormatterFunc(func(event TestEvent, exec *Execution) error {
+		switch {
+		case isPkgFailureOutput(event):
+			buf.WriteString(event.Output)
+		case event.PackageEvent() && event.Action == ActionFail:
+			buf.WriteString(shortFormatPackageEvent(opts, event, exec))
+		case event.Action == ActionFail:
+			pkg := exec.Package(event.Package)
+			tc := pkg.LastFailedByName(event.Test)
+			pkg.writeOutputTo(buf, tc.ID, opts.MaxLineLength) //nolint:errcheck
+			testNameFormatTestEvent(buf, event, exec, opts)
+		default:
+			return nil
+		}
+		return buf.Flush()
+	})
+}
+
 func colorEvent(event TestEvent) func(format string, a ...interface{}) string {
 	switch event.Action {
 	case ActionPass:
@@ -401,8 +562,9 @@ func colorEvent(event TestEvent) func(format string, a ...interface{}) string {
 	return color.WhiteString
 }
 
-// EventFormatter is a function which handles an event and returns a string to
-// output for the event.
+// EventFormatter formats an event, and writes the result to the output of the
+// formatter. A program may implement its own format by implementing this
+// interface.
 type EventFormatter interface {
 	Format(event TestEvent, output *Execution) error
 }
@@ -417,10 +579,46 @@ type FormatOptions struct {
 	HideEmptyPackages    bool
 	UseHiVisibilityIcons bool // Deprecated
 	Icons                string
+	// CustomIcons are used in place of the icon set named by Icons.
+	CustomIcons *IconSet
+	// Accessible output does not rewrite lines, and uses words instead of
+	// color or icons to show the status of tests and packages.
+	Accessible bool
+	// MaxLineLength is the maximum number of characters printed from each
+	// line of test output. Longer lines are truncated. Zero means no limit.
+	MaxLineLength int
+	// CollapseRepeatedOutput prints consecutive copies of a line of output of a
+	// test once, followed by a line that says how many times it was repeated.
+	CollapseRepeatedOutput bool
 }
 
-// NewEventFormatter returns a formatter for printing events.
+// NewEventFormatter returns a formatter for printing events in one of the
+// formats of the --format flag. Returns nil if the format is not known.
+//
+// Some formatters print a status line which is updated while packages are
+// running. These formatters implement io.Closer. Close should be called after
+// the last event, before anything else is printed, to remove the status line.
 func NewEventFormatter(out io.Writer, format string, formatOpts FormatOptions) EventFormatter {
+	if formatOpts.Accessible {
+		format = accessibleFormat(format)
+		formatOpts.UseHiVisibilityIcons = false
+		formatOpts.CustomIcons = nil
+		formatOpts.Icons = "text"
+	}
+	formatter := newEventFormatter(out, format, formatOpts)
+	if formatter == nil {
+		return nil
+	}
+	if formatOpts.MaxLineLength > 0 {
+		formatter = &truncateFormatter{base: formatter, maxLineLength: formatOpts.MaxLineLength}
+	}
+	if formatOpts.CollapseRepeatedOutput {
+		formatter = &collapseFormatter{base: formatter}
+	}
+	return formatter
+}
+
+func newEventFormatter(out io.Writer, format string, formatOpts FormatOptions) EventFormatter {
 	switch format {
 	case "none":
 		return eventFormatterFunc(func(TestEvent, *Execution) error { return nil })
@@ -429,31 +627,128 @@ func NewEventFormatter(out io.Writer, format string, formatOpts FormatOptions) E
 	case "standard-json":
 		return standardJSONFormat(out)
 	case "standard-verbose":
-		return standardVerboseFormat(out)
+		return withLiveTimersOnTerminal(out, formatOpts, standardVerboseFormat)
 	case "standard-quiet":
 		return standardQuietFormat(out)
 	case "dots", "dots-v1":
-		return dotsFormatV1(out)
+		return dotsFormatV1(out, formatOpts)
 	case "dots-v2":
 		return newDotFormatter(out, formatOpts)
+	case "grid", "matrix":
+		return newGridFormatter(out, formatOpts)
 	case "gotestdox", "testdox":
 		return testDoxFormat(out, formatOpts)
 	case "testname", "short-verbose":
 		if os.Getenv("GITHUB_ACTIONS") == "true" {
 			return githubActionsFormat(out)
 		}
-		return testNameFormat(out)
+		return testNameFormat(out, formatOpts)
+	case "stream":
+		return streamFormat(out, formatOpts)
 	case "pkgname", "short":
-		return pkgNameFormat(out, formatOpts)
+		return withLiveTimersOnTerminal(out, formatOpts, func(out io.Writer) EventFormatter {
+			return pkgNameFormat(out, formatOpts)
+		})
 	case "pkgname-and-test-fails", "short-with-failures":
 		return pkgNameWithFailuresFormat(out, formatOpts)
+	case "failures-only", "quiet":
+		return failuresOnlyFormat(out, formatOpts)
 	case "github-actions", "github-action":
 		return githubActionsFormat(out)
+	case "tap":
+		return tapFormat(out)
+	case "teamcity":
+		return teamCityFormat(out)
+	case "azure-pipelines", "azure-devops":
+		return azurePipelinesFormat(out)
+	case "gitlab":
+		return gitLabFormat(out)
 	default:
 		return nil
 	}
 }
 
+// closeFormatter closes f if it implements io.Closer.
+func closeFormatter(f EventFormatter) error {
+	if c, ok := f.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// NewFormatterHandler returns an EventHandler which sends every event to
+// formatter, and writes every line of stderr to errOut. It can be used as the
+// ScanConfig.Handler to print the output of go test the same way as the
+// gotestsum command.
+func NewFormatterHandler(formatter EventFormatter, errOut io.Writer) EventHandler {
+	return &formatterHandler{formatter: formatter, errOut: errOut}
+}
+
+type formatterHandler struct {
+	formatter EventFormatter
+	errOut    io.Writer
+}
+
+func (h *formatterHandler) Event(event TestEvent, execution *Execution) error {
+	if err := h.formatter.Format(event, execution); err != nil {
+		return fmt.Errorf("failed to format event: %w", err)
+	}
+	return nil
+}
+
+func (h *formatterHandler) Err(text string) error {
+	// always return nil, no need to stop scanning if the write fails
+	_, _ = fmt.Fprintln(h.errOut, text)
+	return nil
+}
+
+// accessibleFormat returns the format to use in place of format when
+// FormatOptions.Accessible is set. Formats which rewrite lines, or which only
+// use symbols to show the status of tests, are replaced by pkgname.
+func accessibleFormat(format string) string {
+	switch format {
+	case "dots", "dots-v1", "dots-v2", "grid", "matrix":
+		return "pkgname"
+	}
+	return format
+}
+
+// withLiveTimersOnTerminal adds a status line with the elapsed time of running
+// packages when out is a terminal. When FormatOptions.Accessible is set the
+// status is printed as a new line at a regular interval instead.
+func withLiveTimersOnTerminal(
+	out io.Writer,
+	opts FormatOptions,
+	newBase func(io.Writer) EventFormatter,
+) EventFormatter {
+	if opts.Accessible {
+		return withProgressLines(out, newBase)
+	}
+	width := terminalWidth(out)
+	if width == 0 {
+		return newBase(out)
+	}
+	return withLiveTimers(out, width, newBase)
+}
+
+// terminalWidth returns the width of the terminal that out writes to, or 0
+// when out is not a terminal. color.Output writes to os.Stdout, but on Windows
+// it is a wrapper which does not have the file descriptor.
+func terminalWidth(out io.Writer) int {
+	if out == color.Output {
+		out = os.Stdout
+	}
+	file, ok := out.(interface{ Fd() uintptr })
+	if !ok {
+		return 0
+	}
+	width, _, err := term.GetSize(int(file.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
 func githubActionsFormat(out io.Writer) EventFormatter {
 	buf := bufio.NewWriter(out)
 
@@ -481,7 +776,7 @@ func githubActionsFormat(out io.Writer) EventFormatter {
 			} else {
 				buf.WriteString("  ")
 			}
-			testNameFormatTestEvent(buf, event)
+			testNameFormatTestEvent(buf, event, exec, FormatOptions{})
 
 			for _, item := range output[key] {
 				buf.WriteString(item)
@@ -94,13 +94,24 @@ func TestFormats_DefaultGoTestJson(t *testing.T) {
 			expectedOut: "format/testdox.out",
 		},
 		{
-			name:        "testname",
-			format:      testNameFormat,
+			name: "testname",
+			format: func(out io.Writer) EventFormatter {
+				return testNameFormat(out, FormatOptions{})
+			},
 			expectedOut: "format/testname.out",
 		},
 		{
-			name:        "dots-v1",
-			format:      dotsFormatV1,
+			name: "stream",
+			format: func(out io.Writer) EventFormatter {
+				return streamFormat(out, FormatOptions{})
+			},
+			expectedOut: "format/stream.out",
+		},
+		{
+			name: "dots-v1",
+			format: func(out io.Writer) EventFormatter {
+				return dotsFormatV1(out, FormatOptions{})
+			},
 			expectedOut: "format/dots-v1.out",
 		},
 		{
@@ -145,6 +156,35 @@ func TestFormats_DefaultGoTestJson(t *testing.T) {
 			},
 			expectedOut: "format/pkgname-emoticons.out",
 		},
+		{
+			name: "pkgname with ascii",
+			format: func(out io.Writer) EventFormatter {
+				return pkgNameFormat(out, FormatOptions{Icons: "ascii"})
+			},
+			expectedOut: "format/pkgname-ascii.out",
+		},
+		{
+			name: "testname with emoji",
+			format: func(out io.Writer) EventFormatter {
+				return testNameFormat(out, FormatOptions{Icons: "emoji"})
+			},
+			expectedOut: "format/testname-emoji.out",
+		},
+		{
+			name: "dots-v1 with custom icons",
+			format: func(out io.Writer) EventFormatter {
+				icons := &IconSet{Pass: "p", Skip: "s", Fail: "F"}
+				return dotsFormatV1(out, FormatOptions{CustomIcons: icons})
+			},
+			expectedOut: "format/dots-v1-custom-icons.out",
+		},
+		{
+			name: "failures-only",
+			format: func(out io.Writer) EventFormatter {
+				return failuresOnlyFormat(out, FormatOptions{})
+			},
+			expectedOut: "format/failures-only.out",
+		},
 		{
 			name: "pkgname with hide-empty",
 			format: func(out io.Writer) EventFormatter {
@@ -172,6 +212,32 @@ func TestFormats_DefaultGoTestJson(t *testing.T) {
 			format:      githubActionsFormat,
 			expectedOut: "format/github-actions.out",
 		},
+		{
+			name:        "teamcity",
+			format:      teamCityFormat,
+			expectedOut: "format/teamcity.out",
+		},
+		{
+			name:        "azure-pipelines",
+			format:      azurePipelinesFormat,
+			expectedOut: "format/azure-pipelines.out",
+		},
+		{
+			name:        "gitlab",
+			format:      gitLabFormat,
+			expectedOut: "format/gitlab.out",
+		},
+		{
+			name:        "tap",
+			format:      tapFormat,
+			expectedOut: "format/tap.out",
+			expected: func(t *testing.T, exec *Execution) {
+				t.Helper()
+				out := new(bytes.Buffer)
+				assert.NilError(t, PrintTAPPlan(out, exec))
+				golden.Assert(t, out.String(), "format/tap-plan.out")
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -219,8 +285,10 @@ func TestFormats_Coverage(t *testing.T) {
 			expectedOut: "format/testdox-coverage.out",
 		},
 		{
-			name:        "testname",
-			format:      testNameFormat,
+			name: "testname",
+			format: func(out io.Writer) EventFormatter {
+				return testNameFormat(out, FormatOptions{})
+			},
 			expectedOut: "format/testname-coverage.out",
 		},
 		{
@@ -294,8 +362,10 @@ func TestFormats_Shuffle(t *testing.T) {
 			expectedOut: "format/testdox-shuffle.out",
 		},
 		{
-			name:        "testname",
-			format:      testNameFormat,
+			name: "testname",
+			format: func(out io.Writer) EventFormatter {
+				return testNameFormat(out, FormatOptions{})
+			},
 			expectedOut: "format/testname-shuffle.out",
 		},
 		{
@@ -323,3 +393,60 @@ func TestFormats_Shuffle(t *testing.T) {
 		})
 	}
 }
+
+func TestNewEventFormatter_Accessible(t *testing.T) {
+	run := func(t *testing.T, format string, opts FormatOptions) string {
+		t.Helper()
+		out := new(bytes.Buffer)
+		formatter := NewEventFormatter(out, format, opts)
+		assert.Assert(t, formatter != nil)
+		shim := newFakeHandler(formatter, "input/go-test-json")
+		_, err := ScanTestOutput(shim.Config(t))
+		assert.NilError(t, err)
+		return out.String()
+	}
+
+	expected := run(t, "pkgname", FormatOptions{Icons: "text"})
+	for _, format := range []string{"dots", "dots-v2", "grid", "pkgname"} {
+		t.Run(format, func(t *testing.T) {
+			actual := run(t, format, FormatOptions{Accessible: true, Icons: "hivis"})
+			assert.Equal(t, actual, expected)
+		})
+	}
+}
+
+func TestNewEventFormatter_AccessibleTestnameEmptyPackage(t *testing.T) {
+	out := new(bytes.Buffer)
+	formatter := NewEventFormatter(out, "testname", FormatOptions{Accessible: true})
+	exec := newExecution()
+	for _, event := range []TestEvent{
+		{Action: ActionStart, Package: "example.com/empty"},
+		{Action: ActionPass, Package: "example.com/empty"},
+		{Action: ActionStart, Package: "example.com/one"},
+		{Action: ActionRun, Package: "example.com/one", Test: "TestOne"},
+		{Action: ActionPass, Package: "example.com/one", Test: "TestOne"},
+		{Action: ActionPass, Package: "example.com/one"},
+	} {
+		exec.add(event)
+		assert.NilError(t, formatter.Format(event, exec))
+	}
+	assert.Equal(t, out.String(), `EMPTY example.com/empty
+PASS example.com/one.TestOne (0.00s)
+PASS example.com/one
+`)
+}
+
+func TestNewFormatterHandler(t *testing.T) {
+	out := new(bytes.Buffer)
+	errOut := new(bytes.Buffer)
+	formatter := NewEventFormatter(out, "standard-quiet", FormatOptions{})
+	_, err := ScanTestOutput(ScanConfig{
+		Stdout:  bytes.NewReader(golden.Get(t, "input/go-test-json.out")),
+		Stderr:  bytes.NewReader(golden.Get(t, "input/go-test-json.err")),
+		Handler: NewFormatterHandler(formatter, errOut),
+	})
+	assert.NilError(t, err)
+
+	golden.Assert(t, out.String(), "format/standard-quiet.out")
+	golden.Assert(t, errOut.String(), "input/go-test-json.err")
+}
@@ -0,0 +1,153 @@
+package testjson
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fuzz is the progress and result of a fuzz test run with go test -fuzz,
+// parsed from the lines printed by the fuzzing engine, for example:
+//
+//	fuzz: elapsed: 3s, execs: 44229 (14743/sec), new interesting: 22 (total: 23)
+type Fuzz struct {
+	Package string
+	// Test is the name of the fuzz test.
+	Test string
+	// Elapsed is the time spent fuzzing, from the last line printed by the
+	// fuzzing engine.
+	Elapsed time.Duration
+	// Execs is the number of times the fuzz target was called.
+	Execs int64
+	// ExecsPerSec is the rate printed on the last progress line. It is the
+	// rate since the previous progress line, not the average rate.
+	ExecsPerSec int64
+	// NewInteresting is the number of inputs added to the corpus by fuzzing.
+	NewInteresting int64
+	// Corpus is the total number of inputs in the corpus, including the seed
+	// corpus and the inputs in the cache.
+	Corpus int64
+	// FailingInput is the path of the input which caused the fuzz test to
+	// fail, relative to the directory of the package. It is empty unless
+	// the fuzzing engine found a failing input.
+	FailingInput string
+	// Rerun is the command printed by go test to run the fuzz test with the
+	// FailingInput, from the directory of the package.
+	Rerun string
+
+	// id is the TestCase.ID of the fuzz test.
+	id int
+}
+
+// AverageExecsPerSec returns the average number of times the fuzz target was
+// called per second.
+func (f Fuzz) AverageExecsPerSec() float64 {
+	if f.Elapsed <= 0 {
+		return 0
+	}
+	return float64(f.Execs) / f.Elapsed.Seconds()
+}
+
+var (
+	fuzzElapsed      = regexp.MustCompile(`^fuzz: elapsed: (\S+?),`)
+	fuzzProgress     = regexp.MustCompile(`, execs: (\d+) \((\d+)/sec\), new interesting: (\d+) \(total: (\d+)\)`)
+	fuzzBaseline     = regexp.MustCompile(`, gathering baseline coverage: \d+/(\d+) completed`)
+	fuzzFailingInput = regexp.MustCompile(`^\s+Failing input written to (\S+)`)
+	fuzzRerun        = regexp.MustCompile(`^\s+(go test -run=\S+)`)
+)
+
+// addFuzzOutput updates the progress of the fuzz test tc from a line of its
+// output. The Fuzz is created from the first line printed by the fuzzing
+// engine, so fuzz tests which only run the seed corpus have no Fuzz.
+func (p *Package) addFuzzOutput(tc TestCase, output string) {
+	if !isFuzz(tc.Test.Name()) {
+		return
+	}
+	f := p.fuzzByID(tc.ID)
+	line := strings.TrimRight(output, "\n")
+
+	if m := fuzzElapsed.FindStringSubmatch(line); m != nil {
+		if f == nil {
+			f = &Fuzz{Package: tc.Package, Test: tc.Test.Name(), id: tc.ID}
+			p.fuzz = append(p.fuzz, f)
+		}
+		if d, err := time.ParseDuration(m[1]); err == nil {
+			f.Elapsed = d
+		}
+		if m := fuzzBaseline.FindStringSubmatch(line); m != nil {
+			f.Corpus, _ = strconv.ParseInt(m[1], 10, 64)
+		}
+		if m := fuzzProgress.FindStringSubmatch(line); m != nil {
+			f.Execs, _ = strconv.ParseInt(m[1], 10, 64)
+			f.ExecsPerSec, _ = strconv.ParseInt(m[2], 10, 64)
+			f.NewInteresting, _ = strconv.ParseInt(m[3], 10, 64)
+			f.Corpus, _ = strconv.ParseInt(m[4], 10, 64)
+		}
+		return
+	}
+	if f == nil {
+		return
+	}
+	if m := fuzzFailingInput.FindStringSubmatch(line); m != nil {
+		f.FailingInput = m[1]
+		return
+	}
+	if m := fuzzRerun.FindStringSubmatch(line); m != nil && f.FailingInput != "" {
+		f.Rerun = m[1]
+	}
+}
+
+func isFuzz(name string) bool {
+	return strings.HasPrefix(name, "Fuzz") && !TestName(name).IsSubTest()
+}
+
+func (p *Package) fuzzByID(id int) *Fuzz {
+	for _, f := range p.fuzz {
+		if f.id == id {
+			return f
+		}
+	}
+	return nil
+}
+
+// runningFuzz returns the progress of the fuzz test which is running in the
+// package, or nil if no fuzz test is running. go test runs only one fuzz test
+// at a time.
+func (p *Package) runningFuzz() *Fuzz {
+	for i := len(p.fuzz) - 1; i >= 0; i-- {
+		if tc, ok := p.running[p.fuzz[i].Test]; ok && tc.ID == p.fuzz[i].id {
+			return p.fuzz[i]
+		}
+	}
+	return nil
+}
+
+// Fuzz returns the progress and results of the fuzz tests run with
+// go test -fuzz in the package, in the order they started.
+func (p *Package) Fuzz() []Fuzz {
+	result := make([]Fuzz, 0, len(p.fuzz))
+	for _, f := range p.fuzz {
+		result = append(result, *f)
+	}
+	return result
+}
+
+// TestCaseFuzz returns the progress and result of the fuzz test tc, and false
+// if tc was not run with go test -fuzz.
+func (p *Package) TestCaseFuzz(tc TestCase) (Fuzz, bool) {
+	if f := p.fuzzByID(tc.ID); f != nil {
+		return *f, true
+	}
+	return Fuzz{}, false
+}
+
+// Fuzz returns the progress and results of the fuzz tests in every package,
+// sorted by package.
+func (e *Execution) Fuzz() []Fuzz {
+	var result []Fuzz
+	for _, name := range sortedKeys(e.packages) {
+		result = append(result, e.packages[name].Fuzz()...)
+	}
+	return result
+}
@@ -0,0 +1,119 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+)
+
+func TestScanTestOutput_WithFuzz(t *testing.T) {
+	exec, err := ScanTestOutput(scanConfigFromGolden("input/go-test-json-with-fuzz.out")(t))
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, exec.Fuzz(), []Fuzz{
+		{
+			Package:        "gotest.tools/gotestsum/testjson/internal/withfuzz",
+			Test:           "FuzzReverse",
+			Elapsed:        8 * time.Second,
+			Execs:          86146,
+			ExecsPerSec:    0,
+			NewInteresting: 24,
+			Corpus:         25,
+			id:             1,
+		},
+	}, cmpFuzz)
+
+	pkg := exec.Package("gotest.tools/gotestsum/testjson/internal/withfuzz")
+	fuzz, ok := pkg.TestCaseFuzz(pkg.Passed[0])
+	assert.Assert(t, ok)
+	assert.Equal(t, int(fuzz.AverageExecsPerSec()), 10768)
+}
+
+func TestScanTestOutput_WithFuzzFailingInput(t *testing.T) {
+	exec, err := ScanTestOutput(scanConfigFromGolden("input/go-test-json-with-fuzz-crash.out")(t))
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, exec.Fuzz(), []Fuzz{
+		{
+			Package:      "gotest.tools/gotestsum/testjson/internal/withfuzz",
+			Test:         "FuzzPrefix",
+			Corpus:       1,
+			FailingInput: "testdata/fuzz/FuzzPrefix/2d61a7517c6945b1",
+			Rerun:        "go test -run=FuzzPrefix/2d61a7517c6945b1",
+			id:           1,
+		},
+	}, cmpFuzz)
+}
+
+func TestScanTestOutput_FuzzSeedCorpusOnly(t *testing.T) {
+	exec, err := ScanTestOutput(scanConfigFromGolden("input/go-test-json.out")(t))
+	assert.NilError(t, err)
+	assert.Equal(t, len(exec.Fuzz()), 0)
+}
+
+var cmpFuzz = cmp.AllowUnexported(Fuzz{})
+
+func TestLiveTimerFormatter_WithFuzz(t *testing.T) {
+	start := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	f := &liveTimerFormatter{
+		base:     eventFormatterFunc(func(TestEvent, *Execution) error { return nil }),
+		running:  map[string]time.Time{},
+		fuzz:     map[string]Fuzz{},
+		progress: true,
+	}
+	now := start
+	f.now = func() time.Time { return now }
+
+	exec := newExecution()
+	send := func(event TestEvent) {
+		t.Helper()
+		event.Package, event.Test = "example.com/one", "FuzzOne"
+		exec.add(event)
+		assert.NilError(t, f.Format(event, exec))
+	}
+	output := func(line string) TestEvent {
+		return TestEvent{Action: ActionOutput, Output: line}
+	}
+
+	send(TestEvent{Action: ActionRun})
+	now = now.Add(4 * time.Second)
+	assert.Equal(t, f.statusLine(), "running 1 package: example.com/one 4s")
+	send(output("fuzz: elapsed: 0s, gathering baseline coverage: 0/3 completed\n"))
+	assert.Equal(t, f.statusLine(), "running 1 package: example.com/one 4s (fuzzing FuzzOne, corpus 3)")
+	send(output("fuzz: elapsed: 3s, execs: 44229 (14743/sec), new interesting: 2 (total: 5)\n"))
+	assert.Equal(t, f.statusLine(),
+		"running 1 package: example.com/one 4s (fuzzing FuzzOne, 14743 execs/sec, corpus 5)")
+
+	send(TestEvent{Action: ActionPass})
+	assert.Equal(t, f.statusLine(), "running 1 package: example.com/one 4s")
+}
+
+// TestLiveTimerFormatter_FuzzWhileTicking is run with -race to show that the
+// ticker does not read the Execution while the scanner is adding events to it.
+func TestLiveTimerFormatter_FuzzWhileTicking(t *testing.T) {
+	f := withLiveTimers(io.Discard, 80, func(out io.Writer) EventFormatter {
+		return pkgNameFormat(out, FormatOptions{})
+	}).(*liveTimerFormatter)
+	f.interval = time.Millisecond
+
+	exec := newExecution()
+	send := func(event TestEvent) {
+		t.Helper()
+		event.Package, event.Test = "example.com/one", "FuzzOne"
+		exec.add(event)
+		assert.NilError(t, f.Format(event, exec))
+	}
+
+	send(TestEvent{Action: ActionRun})
+	deadline := time.Now().Add(50 * time.Millisecond)
+	for i := 0; time.Now().Before(deadline); i++ {
+		send(TestEvent{Action: ActionOutput, Output: fmt.Sprintf(
+			"fuzz: elapsed: %ds, execs: %d (100/sec), new interesting: 2 (total: 5)\n", i, i*100)})
+	}
+	send(TestEvent{Action: ActionPass})
+	assert.NilError(t, f.Close())
+}
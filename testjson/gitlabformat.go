@@ -0,0 +1,118 @@
+package testjson
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// gitLabFormatter prints the testname format with a collapsible section for
+// each package, using the section markers of the GitLab CI job log. The lines of
+// a package are printed when the package ends, so that the lines of packages
+// which run in parallel are not interleaved. The section of a failed package is
+// expanded, all other sections are collapsed.
+type gitLabFormatter struct {
+	out      *bufio.Writer
+	packages map[string]*gitLabSection
+	output   map[gitLabKey][]string
+	// sections is the number of sections printed for each package name, used
+	// to give every section a unique name when a package is run more than once.
+	sections map[string]int
+}
+
+type gitLabSection struct {
+	start time.Time
+	lines strings.Builder
+}
+
+type gitLabKey struct {
+	pkg  string
+	test string
+}
+
+func gitLabFormat(out io.Writer) EventFormatter {
+	return &gitLabFormatter{
+		out:      bufio.NewWriter(out),
+		packages: make(map[string]*gitLabSection),
+		output:   make(map[gitLabKey][]string),
+		sections: make(map[string]int),
+	}
+}
+
+func (f *gitLabFormatter) Format(event TestEvent, exec *Execution) error {
+	section, ok := f.packages[event.Package]
+	if !ok {
+		section = &gitLabSection{start: event.Time}
+		f.packages[event.Package] = section
+	}
+	key := gitLabKey{pkg: event.Package, test: event.Test}
+
+	// test case output
+	if event.Test != "" && event.Action == ActionOutput {
+		if !isFramingLine(strings.TrimLeft(event.Output, " "), event.Test) {
+			f.output[key] = append(f.output[key], event.Output)
+		}
+		return nil
+	}
+
+	// test case end event
+	if event.Test != "" && event.Action.IsTerminal() {
+		output := f.output[key]
+		delete(f.output, key)
+		section.lines.WriteString("  ")
+		testNameFormatTestEvent(&section.lines, event, exec, FormatOptions{})
+		if event.Action == ActionFail {
+			for _, line := range output {
+				section.lines.WriteString(line)
+			}
+		}
+		return nil
+	}
+
+	// package event
+	if !event.Action.IsTerminal() {
+		return nil
+	}
+	delete(f.packages, event.Package)
+
+	pkg := exec.Package(event.Package)
+	result := colorEvent(event)(strings.ToUpper(string(event.Action)))
+	if event.Action == ActionSkip || (event.Action == ActionPass && pkg.Total == 0) {
+		event.Action = ActionSkip // always color these as skip actions
+		result = colorEvent(event)("EMPTY")
+	}
+	header := result + " Package " + packageLine(event, pkg)
+	if pkg.TestMainFailed() {
+		section.lines.WriteString(pkg.Output(0))
+	}
+	if section.lines.Len() == 0 {
+		f.out.WriteString(header)
+		return f.out.Flush()
+	}
+
+	name := gitLabSectionName(event.Package)
+	f.sections[name]++
+	if n := f.sections[name]; n > 1 {
+		name = fmt.Sprintf("%s_%d", name, n)
+	}
+	var options string
+	if event.Action != ActionFail {
+		options = "[collapsed=true]"
+	}
+	fmt.Fprintf(f.out, "\x1b[0Ksection_start:%d:%s%s\r\x1b[0K%s",
+		section.start.Unix(), name, options, header)
+	f.out.WriteString(section.lines.String())
+	fmt.Fprintf(f.out, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", event.Time.Unix(), name)
+	return f.out.Flush()
+}
+
+var gitLabSectionInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// gitLabSectionName returns the name of the section of a package. The name of
+// a section may only use letters, numbers, and the characters '_', '.', and '-'.
+func gitLabSectionName(pkg string) string {
+	return "pkg_" + gitLabSectionInvalidChars.ReplaceAllString(RelativePackagePath(pkg), "_")
+}
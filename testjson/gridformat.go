@@ -0,0 +1,166 @@
+package testjson
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+	"gotest.tools/gotestsum/internal/dotwriter"
+)
+
+// gridCell is the status of a single package in the grid format.
+type gridCell int
+
+const (
+	gridCellRunning gridCell = iota
+	gridCellPass
+	gridCellFail
+	gridCellEmpty
+)
+
+func (c gridCell) String() string {
+	switch c {
+	case gridCellPass:
+		return color.GreenString("■")
+	case gridCellFail:
+		return color.RedString("✖")
+	case gridCellEmpty:
+		return color.YellowString("□")
+	default:
+		return "·"
+	}
+}
+
+func gridLegend() string {
+	return fmt.Sprintf("%s pass  %s fail  %s no tests  %s running",
+		gridCellPass, gridCellFail, gridCellEmpty, gridCellRunning)
+}
+
+// defaultGridWidth is the width used when the terminal width can not be
+// detected.
+const defaultGridWidth = 80
+
+// gridFormatter prints a single cell for each package, wrapping to the
+// terminal width. It is designed for very large runs, where even the dots
+// format prints too many lines.
+//
+// When stdout is a terminal the entire grid is redrawn after every package
+// event, so that running packages are visible. Otherwise a cell is appended
+// after each package completes.
+type gridFormatter struct {
+	order []string
+	cells map[string]gridCell
+	opts  FormatOptions
+	width int
+
+	// live is true when the grid is redrawn using writer.
+	live   bool
+	writer *dotwriter.Writer
+	buf    *bufio.Writer
+	// column is the number of cells written to the current line, when the
+	// grid is not live.
+	column  int
+	started bool
+}
+
+func newGridFormatter(out io.Writer, opts FormatOptions) EventFormatter {
+	f := &gridFormatter{
+		cells: make(map[string]gridCell),
+		opts:  opts,
+		width: defaultGridWidth,
+		buf:   bufio.NewWriter(out),
+	}
+	w, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err == nil && w > 0 {
+		f.width = w
+		f.live = true
+		f.writer = dotwriter.New(out)
+	}
+	return f
+}
+
+func (f *gridFormatter) Format(event TestEvent, exec *Execution) error {
+	if !event.PackageEvent() {
+		return nil
+	}
+	if _, ok := f.cells[event.Package]; !ok {
+		f.cells[event.Package] = gridCellRunning
+		f.order = append(f.order, event.Package)
+	}
+	if !event.Action.IsTerminal() {
+		if f.live && event.Action != ActionOutput {
+			return f.redraw(exec)
+		}
+		return nil
+	}
+
+	cell := gridCellForPackage(event, exec.Package(event.Package))
+	f.cells[event.Package] = cell
+	if f.live {
+		return f.redraw(exec)
+	}
+	return f.append(cell)
+}
+
+func gridCellForPackage(event TestEvent, pkg *Package) gridCell {
+	switch {
+	case event.Action == ActionFail:
+		return gridCellFail
+	case event.Action == ActionSkip || pkg == nil || pkg.Total == 0:
+		return gridCellEmpty
+	default:
+		return gridCellPass
+	}
+}
+
+func (f *gridFormatter) append(cell gridCell) error {
+	if cell == gridCellEmpty && f.opts.HideEmptyPackages {
+		return nil
+	}
+	if !f.started {
+		f.buf.WriteString(gridLegend() + "\n")
+		f.started = true
+	}
+	if f.column >= f.width {
+		f.buf.WriteString("\n")
+		f.column = 0
+	}
+	f.buf.WriteString(cell.String())
+	f.column++
+	return f.buf.Flush()
+}
+
+func (f *gridFormatter) redraw(exec *Execution) error {
+	var done, failed int
+	var line strings.Builder
+	var column int
+
+	fmt.Fprint(f.writer, "\n"+gridLegend()+"\n")
+	for _, pkg := range f.order {
+		cell := f.cells[pkg]
+		if cell != gridCellRunning {
+			done++
+		}
+		if cell == gridCellFail {
+			failed++
+		}
+		if cell == gridCellEmpty && f.opts.HideEmptyPackages {
+			continue
+		}
+		if column >= f.width {
+			line.WriteString("\n")
+			column = 0
+		}
+		line.WriteString(cell.String())
+		column++
+	}
+	fmt.Fprint(f.writer, line.String()+"\n")
+	fmt.Fprintf(f.writer, "%d/%d packages%s\n", done, len(f.order),
+		formatTestCount(failed, "failed", ""))
+	PrintSummary(f.writer, exec, SummarizeNone)
+	return f.writer.Flush()
+}
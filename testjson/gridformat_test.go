@@ -0,0 +1,44 @@
+package testjson
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"gotest.tools/gotestsum/internal/dotwriter"
+	"gotest.tools/gotestsum/internal/text"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestScanTestOutput_WithGridFormatter(t *testing.T) {
+	out := new(bytes.Buffer)
+	gridfmt := &gridFormatter{
+		cells: make(map[string]gridCell),
+		width: 2,
+		buf:   bufio.NewWriter(out),
+	}
+	shim := newFakeHandler(gridfmt, "input/go-test-json")
+	_, err := ScanTestOutput(shim.Config(t))
+	assert.NilError(t, err)
+
+	golden.Assert(t, out.String(), "format/grid.out")
+	golden.Assert(t, shim.err.String(), "input/go-test-json.err")
+}
+
+func TestScanTestOutput_WithGridFormatter_Live(t *testing.T) {
+	out := new(bytes.Buffer)
+	gridfmt := &gridFormatter{
+		cells:  make(map[string]gridCell),
+		opts:   FormatOptions{HideEmptyPackages: true},
+		width:  3,
+		live:   true,
+		writer: dotwriter.New(out),
+	}
+	shim := newFakeHandler(gridfmt, "input/go-test-json")
+	_, err := ScanTestOutput(shim.Config(t))
+	assert.NilError(t, err)
+
+	actual := text.ProcessLines(t, out, text.OpRemoveSummaryLineElapsedTime)
+	golden.Assert(t, actual, "format/grid-live.out")
+}
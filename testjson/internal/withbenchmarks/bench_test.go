@@ -0,0 +1,40 @@
+//go:build stubpkg
+
+/*Package withbenchmarks is used to generate testdata for the testjson package.
+ */
+package withbenchmarks
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPassed(t *testing.T) {}
+
+func BenchmarkJoin(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = strings.Join([]string{"a", "b"}, ",")
+	}
+}
+
+func BenchmarkSizes(b *testing.B) {
+	for _, n := range []int{1, 64} {
+		b.Run("n="+strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = make([]byte, n)
+			}
+		})
+	}
+}
+
+func BenchmarkWithLog(b *testing.B) {
+	b.Log("the log of a benchmark")
+	for i := 0; i < b.N; i++ {
+	}
+}
+
+func BenchmarkFailed(b *testing.B) {
+	b.Fatal("the benchmark failed")
+}
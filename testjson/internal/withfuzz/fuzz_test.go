@@ -0,0 +1,40 @@
+//go:build stubpkg
+
+/*Package withfuzz is used to generate testdata for the testjson package.
+ */
+package withfuzz
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzReverse(f *testing.F) {
+	f.Add("abc")
+	f.Fuzz(func(t *testing.T, s string) {
+		if !utf8.ValidString(s) {
+			t.Skip("not valid utf8")
+		}
+		if reverse(reverse(s)) != s {
+			t.Fatalf("reverse(reverse(%q)) != %q", s, s)
+		}
+	})
+}
+
+func FuzzPrefix(f *testing.F) {
+	f.Add("abc")
+	f.Fuzz(func(t *testing.T, s string) {
+		if strings.HasPrefix(s, "x") {
+			t.Fatalf("found %q", s)
+		}
+	})
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
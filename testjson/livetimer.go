@@ -0,0 +1,283 @@
+package testjson
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"gotest.tools/gotestsum/internal/dotwriter"
+)
+
+const (
+	// liveTimerInterval is how often the status line is updated.
+	liveTimerInterval = time.Second
+	// progressLineInterval is how often a progress line is printed when
+	// lines can not be rewritten.
+	progressLineInterval = 30 * time.Second
+)
+
+// liveTimerFormatter wraps another formatter, and prints a status line below
+// the output of that formatter. The status line shows the elapsed time of each
+// package that is still running, so that a package which is stuck is easy to
+// spot before the test timeout is reached. The status line is updated by a
+// ticker while any packages are running, and is removed once no packages are
+// running.
+//
+// When progress is true the status line is never rewritten. Instead a new
+// progress line is printed to out at every tick.
+type liveTimerFormatter struct {
+	mu       sync.Mutex
+	base     EventFormatter
+	pending  bytes.Buffer
+	writer   *dotwriter.Writer
+	out      io.Writer
+	progress bool
+	interval time.Duration
+	width    int
+	running  map[string]time.Time
+	// fuzz is the progress of the fuzz test running in each package, copied
+	// from the Execution by Format, so that the ticker never reads the
+	// Execution while the scanner is changing it.
+	fuzz   map[string]Fuzz
+	ticker *time.Ticker
+	done   chan struct{}
+
+	// shims for testing
+	now         func() time.Time
+	startTicker bool
+}
+
+// withLiveTimers returns a formatter which prints the output of the formatter
+// created by newBase, followed by a status line with the elapsed time of all
+// the running packages.
+func withLiveTimers(out io.Writer, width int, newBase func(io.Writer) EventFormatter) EventFormatter {
+	f := &liveTimerFormatter{
+		writer:      dotwriter.New(out),
+		interval:    liveTimerInterval,
+		width:       width,
+		running:     make(map[string]time.Time),
+		fuzz:        make(map[string]Fuzz),
+		now:         time.Now,
+		startTicker: true,
+	}
+	f.base = newBase(&f.pending)
+	return f
+}
+
+// withProgressLines returns a formatter which prints the output of the
+// formatter created by newBase, and periodically prints a line with the elapsed
+// time of all the running packages. Unlike withLiveTimers, lines are never
+// rewritten, which works better with screen readers and dumb terminals.
+func withProgressLines(out io.Writer, newBase func(io.Writer) EventFormatter) EventFormatter {
+	return &liveTimerFormatter{
+		base:        newBase(out),
+		out:         out,
+		progress:    true,
+		interval:    progressLineInterval,
+		running:     make(map[string]time.Time),
+		fuzz:        make(map[string]Fuzz),
+		now:         time.Now,
+		startTicker: true,
+	}
+}
+
+func (f *liveTimerFormatter) Format(event TestEvent, exec *Execution) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.base.Format(event, exec); err != nil {
+		return err
+	}
+
+	switch {
+	case event.Package == "":
+	case event.PackageEvent() && event.Action.IsTerminal():
+		delete(f.running, event.Package)
+		delete(f.fuzz, event.Package)
+	default:
+		if _, ok := f.running[event.Package]; !ok {
+			f.running[event.Package] = f.now()
+		}
+		f.updateFuzz(event.Package, exec)
+	}
+	f.updateTicker()
+	if f.progress {
+		return nil
+	}
+	return f.redraw()
+}
+
+// updateTicker starts the ticker when the first package starts running, and
+// stops it when no packages are running.
+func (f *liveTimerFormatter) updateTicker() {
+	if !f.startTicker {
+		return
+	}
+	switch {
+	case len(f.running) > 0 && f.ticker == nil:
+		f.ticker = time.NewTicker(f.interval)
+		f.done = make(chan struct{})
+		go f.tick(f.ticker, f.done)
+	case len(f.running) == 0 && f.ticker != nil:
+		f.ticker.Stop()
+		close(f.done)
+		f.ticker = nil
+	}
+}
+
+func (f *liveTimerFormatter) tick(ticker *time.Ticker, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			f.mu.Lock()
+			select {
+			case <-done: // stopped while waiting for the lock
+			default:
+				_ = f.update()
+			}
+			f.mu.Unlock()
+		}
+	}
+}
+
+// Close stops the ticker and removes the status line. Any partial line of
+// output from the base formatter is ended with a newline and written. A
+// package which did not end, because go test was stopped by --max-fails, a
+// timeout, or a signal, is no longer shown as running.
+func (f *liveTimerFormatter) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	clear(f.running)
+	clear(f.fuzz)
+	if f.ticker != nil {
+		f.ticker.Stop()
+		close(f.done)
+		f.ticker = nil
+	}
+	if f.progress {
+		return nil
+	}
+	if f.pending.Len() > 0 && !bytes.HasSuffix(f.pending.Bytes(), []byte{'\n'}) {
+		f.pending.WriteByte('\n')
+	}
+	return f.redraw()
+}
+
+func (f *liveTimerFormatter) update() error {
+	if !f.progress {
+		return f.redraw()
+	}
+	if len(f.running) == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintln(f.out, "still "+f.statusLine())
+	return err
+}
+
+// redraw writes any complete lines of output from the base formatter, followed
+// by the status line. The lines from the base formatter are persisted, so
+// only the status line is replaced by the next redraw.
+func (f *liveTimerFormatter) redraw() error {
+	var lines int
+	if i := bytes.LastIndexByte(f.pending.Bytes(), '\n'); i >= 0 {
+		complete := f.pending.Next(i + 1)
+		lines = bytes.Count(complete, []byte{'\n'})
+		_, _ = f.writer.Write(complete)
+	}
+	if status := f.statusLine(); status != "" {
+		_, _ = f.writer.Write([]byte(status + "\n"))
+	}
+	if err := f.writer.Flush(); err != nil {
+		return err
+	}
+	f.writer.Persist(lines)
+	return nil
+}
+
+func (f *liveTimerFormatter) statusLine() string {
+	if len(f.running) == 0 {
+		return ""
+	}
+	pkgs := make([]string, 0, len(f.running))
+	for pkg := range f.running {
+		pkgs = append(pkgs, pkg)
+	}
+	// oldest first, because those are most likely to be stuck
+	sort.Slice(pkgs, func(i, j int) bool {
+		a, b := f.running[pkgs[i]], f.running[pkgs[j]]
+		if a.Equal(b) {
+			return pkgs[i] < pkgs[j]
+		}
+		return a.Before(b)
+	})
+
+	now := f.now()
+	prefix := fmt.Sprintf("running %d %s: ", len(pkgs), pluralize(len(pkgs), "package", "packages"))
+	line := prefix
+	for i, pkg := range pkgs {
+		item := fmt.Sprintf("%s %s", RelativePackagePath(pkg),
+			formatLiveElapsed(now.Sub(f.running[pkg])))
+		if fuzz, ok := f.fuzz[pkg]; ok {
+			item += " (" + formatLiveFuzz(fuzz) + ")"
+		}
+		if i > 0 {
+			item = ", " + item
+		}
+		more := fmt.Sprintf(", +%d more", len(pkgs)-i)
+		if f.width > 0 && utf8.RuneCountInString(line+item+more) >= f.width {
+			if i == 0 {
+				break
+			}
+			line += more
+			break
+		}
+		line += item
+	}
+	return color.CyanString(strings.TrimSuffix(line, ": "))
+}
+
+// updateFuzz copies the progress of the fuzz test running in pkg from exec.
+func (f *liveTimerFormatter) updateFuzz(pkg string, exec *Execution) {
+	var fuzz *Fuzz
+	if exec != nil && exec.Package(pkg) != nil {
+		fuzz = exec.Package(pkg).runningFuzz()
+	}
+	if fuzz == nil {
+		delete(f.fuzz, pkg)
+		return
+	}
+	f.fuzz[pkg] = *fuzz
+}
+
+// formatLiveFuzz returns the progress of a running fuzz test. The rate is
+// only known after the first progress line, which is printed after the
+// baseline coverage is gathered.
+func formatLiveFuzz(fuzz Fuzz) string {
+	if fuzz.Execs == 0 {
+		return fmt.Sprintf("fuzzing %s, corpus %d", fuzz.Test, fuzz.Corpus)
+	}
+	return fmt.Sprintf("fuzzing %s, %d execs/sec, corpus %d", fuzz.Test, fuzz.ExecsPerSec, fuzz.Corpus)
+}
+
+func pluralize(count int, singular, plural string) string {
+	if count == 1 {
+		return singular
+	}
+	return plural
+}
+
+func formatLiveElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	return d.Truncate(time.Second).String()
+}
@@ -0,0 +1,168 @@
+package testjson
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+func TestLiveTimerFormatter(t *testing.T) {
+	out := new(bytes.Buffer)
+	f := withLiveTimers(out, 80, func(out io.Writer) EventFormatter {
+		return pkgNameFormat(out, FormatOptions{})
+	}).(*liveTimerFormatter)
+
+	start := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	now := start
+	f.now = func() time.Time { return now }
+	f.startTicker = false
+
+	exec := newExecution()
+	send := func(event TestEvent) {
+		t.Helper()
+		exec.add(event)
+		assert.NilError(t, f.Format(event, exec))
+	}
+
+	send(TestEvent{Action: "start", Package: "example.com/one"})
+	now = now.Add(2 * time.Second)
+	send(TestEvent{Action: "start", Package: "example.com/two"})
+	now = now.Add(3 * time.Second)
+	assert.NilError(t, f.redraw())
+	send(TestEvent{Action: ActionRun, Package: "example.com/one", Test: "TestOne"})
+	send(TestEvent{Action: ActionPass, Package: "example.com/one", Test: "TestOne"})
+	send(TestEvent{Action: ActionPass, Package: "example.com/one", Elapsed: 5})
+	now = now.Add(90 * time.Second)
+	assert.NilError(t, f.redraw())
+	send(TestEvent{Action: ActionFail, Package: "example.com/two", Elapsed: 95})
+
+	golden.Assert(t, out.String(), "format/live-timers.out")
+}
+
+func TestLiveTimerFormatter_StatusLineWidth(t *testing.T) {
+	start := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	f := &liveTimerFormatter{
+		width: 60,
+		now:   func() time.Time { return start.Add(3 * time.Second) },
+		running: map[string]time.Time{
+			"example.com/one":   start,
+			"example.com/two":   start.Add(time.Second),
+			"example.com/three": start.Add(2 * time.Second),
+		},
+	}
+	assert.Equal(t, f.statusLine(), "running 3 packages: example.com/one 3s, +2 more")
+
+	f.width = 0
+	assert.Equal(t, f.statusLine(),
+		"running 3 packages: example.com/one 3s, example.com/two 2s, example.com/three 1s")
+}
+
+func TestFormatLiveElapsed(t *testing.T) {
+	assert.Equal(t, formatLiveElapsed(1500*time.Millisecond), "1s")
+	assert.Equal(t, formatLiveElapsed(59*time.Second), "59s")
+	assert.Equal(t, formatLiveElapsed(3*time.Minute+5500*time.Millisecond), "3m5s")
+}
+
+func TestProgressLines(t *testing.T) {
+	out := new(bytes.Buffer)
+	f := withProgressLines(out, func(out io.Writer) EventFormatter {
+		return pkgNameFormat(out, FormatOptions{Icons: "text"})
+	}).(*liveTimerFormatter)
+
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+	f.startTicker = false
+
+	exec := newExecution()
+	send := func(event TestEvent) {
+		t.Helper()
+		exec.add(event)
+		assert.NilError(t, f.Format(event, exec))
+	}
+
+	send(TestEvent{Action: "start", Package: "example.com/one"})
+	send(TestEvent{Action: "start", Package: "example.com/two"})
+	now = now.Add(30 * time.Second)
+	assert.NilError(t, f.update())
+	send(TestEvent{Action: ActionRun, Package: "example.com/one", Test: "TestOne"})
+	send(TestEvent{Action: ActionPass, Package: "example.com/one", Test: "TestOne"})
+	send(TestEvent{Action: ActionPass, Package: "example.com/one", Elapsed: 31})
+	now = now.Add(30 * time.Second)
+	assert.NilError(t, f.update())
+	send(TestEvent{Action: ActionRun, Package: "example.com/two", Test: "TestTwo"})
+	send(TestEvent{Action: ActionPass, Package: "example.com/two", Test: "TestTwo"})
+	send(TestEvent{Action: ActionPass, Package: "example.com/two", Elapsed: 62})
+	assert.NilError(t, f.update())
+
+	expected := `still running 2 packages: example.com/one 30s, example.com/two 30s
+PASS  example.com/one (31s)
+still running 1 package: example.com/two 1m0s
+PASS  example.com/two (1m2s)
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestLiveTimerFormatter_CloseWithUnfinishedPackage(t *testing.T) {
+	out := new(bytes.Buffer)
+	f := withLiveTimers(out, 80, standardVerboseFormat).(*liveTimerFormatter)
+	f.interval = time.Millisecond
+
+	exec := newExecution()
+	for _, event := range []TestEvent{
+		{Action: "start", Package: "example.com/one"},
+		{Action: ActionRun, Package: "example.com/one", Test: "TestOne"},
+		{Action: ActionOutput, Package: "example.com/one", Test: "TestOne", Output: "partial"},
+	} {
+		exec.add(event)
+		assert.NilError(t, f.Format(event, exec))
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	assert.NilError(t, f.Close())
+	assert.Assert(t, f.ticker == nil)
+	assert.Equal(t, f.statusLine(), "")
+	closed := out.String()
+	// the partial line is written, and is not followed by a status line
+	i := strings.LastIndex(closed, "partial")
+	assert.Assert(t, i >= 0, closed)
+	assert.Assert(t, !strings.Contains(closed[i:], "running"), closed)
+
+	// the status line is not drawn again after Close
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, out.String(), closed)
+}
+
+func TestProgressLines_CloseWithUnfinishedPackage(t *testing.T) {
+	out := new(bytes.Buffer)
+	f := withProgressLines(out, standardVerboseFormat).(*liveTimerFormatter)
+	f.interval = time.Millisecond
+
+	event := TestEvent{Action: "start", Package: "example.com/one"}
+	assert.NilError(t, f.Format(event, newExecution()))
+	time.Sleep(10 * time.Millisecond)
+
+	assert.NilError(t, f.Close())
+	closed := out.String()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, out.String(), closed)
+}
+
+func TestWithLiveTimersOnTerminal_OutIsNotATerminal(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	assert.NilError(t, err)
+	defer file.Close() //nolint:errcheck
+
+	for _, out := range []io.Writer{new(bytes.Buffer), file} {
+		f := withLiveTimersOnTerminal(out, FormatOptions{}, standardVerboseFormat)
+		_, ok := f.(*liveTimerFormatter)
+		assert.Assert(t, !ok, "%T", out)
+		assert.Equal(t, terminalWidth(out), 0)
+	}
+}
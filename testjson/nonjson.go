@@ -0,0 +1,100 @@
+package testjson
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// nonJSONLines attributes the lines of stdout which are not test2json events
+// to the package or test which received the last event, when
+// ScanConfig.AttributeNonJSONOutputLines is set. These lines are printed when
+// a test binary writes directly to stdout, for example from C code, or from
+// TestMain before the tests start.
+type nonJSONLines struct {
+	// received is true once an event was received.
+	received bool
+	pkg      string
+	test     string
+	time     time.Time
+	// pending are the lines received before the first event. They are added
+	// to the package of the first event.
+	pending []string
+}
+
+// track records the package and test of an event, so that the following
+// non-JSON lines are attributed to them. A test which has ended does not
+// receive more output, so the lines after the end of a test are attributed to
+// its package.
+func (n *nonJSONLines) track(event TestEvent) {
+	n.received = true
+	n.pkg = event.Package
+	n.test = event.Test
+	n.time = event.Time
+	if event.Action.IsTerminal() {
+		n.test = ""
+	}
+}
+
+// handleNonJSONLine adds line to the output of the package or test which
+// received the last event.
+func handleNonJSONLine(config ScanConfig, execution *Execution, line string) error {
+	n := &execution.nonJSON
+	if !n.received {
+		n.pending = append(n.pending, line)
+		return nil
+	}
+	return handleEvent(config, execution, nil, newNonJSONEvent(n.pkg, n.test, n.time, line), nil)
+}
+
+// handlePendingNonJSONLines adds the lines received before the first event to
+// the package of event, which is the first event.
+func handlePendingNonJSONLines(config ScanConfig, execution *Execution, event TestEvent) error {
+	n := &execution.nonJSON
+	if n.received || len(n.pending) == 0 {
+		return nil
+	}
+	pending := n.pending
+	n.pending = nil
+	for _, line := range pending {
+		nonJSON := newNonJSONEvent(event.Package, "", event.Time, line)
+		if err := handleEvent(config, execution, nil, nonJSON, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// endNonJSONLines sends the lines which could not be attributed to a package,
+// because no events were received, to Handler.Err.
+func endNonJSONLines(config ScanConfig, execution *Execution) {
+	for _, line := range execution.nonJSON.pending {
+		//nolint:errcheck
+		config.Handler.Err(line)
+	}
+	execution.nonJSON = nonJSONLines{}
+}
+
+// newNonJSONEvent returns an output event for a line which was not a test2json
+// event. The raw bytes of the event are set, so that it is written to the
+// --jsonfile like any other event.
+func newNonJSONEvent(pkg, test string, t time.Time, line string) TestEvent {
+	event := TestEvent{
+		Time:    t,
+		Action:  ActionOutput,
+		Package: pkg,
+		Test:    test,
+		Output:  line + "\n",
+	}
+	raw := struct {
+		Time    *time.Time `json:",omitempty"`
+		Action  Action
+		Package string
+		Test    string `json:",omitempty"`
+		Output  string
+	}{Action: event.Action, Package: pkg, Test: test, Output: event.Output}
+	if !t.IsZero() {
+		raw.Time = &t
+	}
+	event.raw, _ = json.Marshal(raw)
+	return event
+}
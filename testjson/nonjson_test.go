@@ -0,0 +1,59 @@
+package testjson
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestScanTestOutput_AttributeNonJSONOutputLines(t *testing.T) {
+	source := strings.Join([]string{
+		`init: loading native library`,
+		`{"Time":"2022-01-02T03:04:05Z","Action":"start","Package":"pkg"}`,
+		`{"Time":"2022-01-02T03:04:06Z","Action":"run","Package":"pkg","Test":"TestOne"}`,
+		`cgo: connection refused`,
+		`{"Time":"2022-01-02T03:04:07Z","Action":"fail","Package":"pkg","Test":"TestOne","Elapsed":1}`,
+		`cgo: closing connection`,
+		`{"Time":"2022-01-02T03:04:08Z","Action":"fail","Package":"pkg","Elapsed":3}`,
+	}, "\n")
+
+	for _, workers := range []int{0, 2} {
+		handler := &rawHandler{}
+		exec, err := ScanTestOutput(ScanConfig{
+			Stdout:                      strings.NewReader(source),
+			Handler:                     handler,
+			AttributeNonJSONOutputLines: true,
+			ParseWorkers:                workers,
+		})
+		assert.NilError(t, err)
+		assert.Equal(t, len(handler.errs), 0)
+		assert.DeepEqual(t, handler.lines, []string{
+			`{"Time":"2022-01-02T03:04:05Z","Action":"output","Package":"pkg","Output":"init: loading native library\n"}`,
+			`{"Time":"2022-01-02T03:04:05Z","Action":"start","Package":"pkg"}`,
+			`{"Time":"2022-01-02T03:04:06Z","Action":"run","Package":"pkg","Test":"TestOne"}`,
+			`{"Time":"2022-01-02T03:04:06Z","Action":"output","Package":"pkg","Test":"TestOne","Output":"cgo: connection refused\n"}`,
+			`{"Time":"2022-01-02T03:04:07Z","Action":"fail","Package":"pkg","Test":"TestOne","Elapsed":1}`,
+			`{"Time":"2022-01-02T03:04:07Z","Action":"output","Package":"pkg","Output":"cgo: closing connection\n"}`,
+			`{"Time":"2022-01-02T03:04:08Z","Action":"fail","Package":"pkg","Elapsed":3}`,
+		})
+
+		failed := exec.Failed()
+		assert.Equal(t, len(failed), 1)
+		assert.DeepEqual(t, exec.OutputLines(failed[0]), []string{"cgo: connection refused\n"})
+		assert.Equal(t, exec.Package("pkg").Output(0),
+			"init: loading native library\ncgo: closing connection\n")
+	}
+}
+
+func TestScanTestOutput_AttributeNonJSONOutputLines_NoEvents(t *testing.T) {
+	handler := &rawHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:                      strings.NewReader("panic: could not start\n"),
+		Handler:                     handler,
+		AttributeNonJSONOutputLines: true,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, handler.errs, []string{"panic: could not start"})
+	assert.Equal(t, len(exec.Packages()), 0)
+}
@@ -0,0 +1,75 @@
+package testjson
+
+import "fmt"
+
+// outputSize is the size of the output of a test, when the output is limited
+// by ScanConfig.MaxTestOutputBytes.
+type outputSize struct {
+	bytes int
+	// truncated is true once the output is larger than the limit. The lines
+	// before the marker line at index head are kept, and lines are removed from
+	// the start of the tail, which follows the marker.
+	truncated    bool
+	head         int
+	tailBytes    int
+	removedLines int
+	removedBytes int
+}
+
+// limitOutput removes lines from the middle of the output of the test with id,
+// after a line of n bytes was added, so that the output is about the size of
+// p.maxOutputBytes. Half of the limit is used to keep the lines at the start
+// of the output, and up to half to keep the lines at the end. The removed lines
+// are replaced by a single line that says how much output was removed.
+func (p *Package) limitOutput(id int, n int) {
+	if p.outputSize == nil {
+		p.outputSize = make(map[int]*outputSize)
+	}
+	size, ok := p.outputSize[id]
+	if !ok {
+		size = &outputSize{}
+		p.outputSize[id] = size
+	}
+	lines := p.output[id]
+	half := p.maxOutputBytes / 2
+
+	if !size.truncated {
+		size.bytes += n
+		if size.bytes <= p.maxOutputBytes {
+			return
+		}
+		head, headBytes := 0, 0
+		for head < len(lines) && headBytes+len(lines[head]) <= half {
+			headBytes += len(lines[head])
+			head++
+		}
+		tail := lines[head:]
+		lines = append(lines[:head:head], "")
+		lines = append(lines, tail...)
+		size.truncated = true
+		size.head = head
+		size.tailBytes = size.bytes - headBytes
+	} else {
+		size.tailBytes += n
+	}
+
+	if size.tailBytes > half {
+		// remove lines until the tail is half of its limit, so that lines are
+		// removed in batches instead of on every new line. The last line is
+		// always kept.
+		start := size.head + 1
+		i := start
+		for size.tailBytes > half/2 && i < len(lines)-1 {
+			size.tailBytes -= len(lines[i])
+			size.removedBytes += len(lines[i])
+			size.removedLines++
+			i++
+		}
+		end := len(lines)
+		lines = append(lines[:start], lines[i:]...)
+		clear(lines[len(lines):end])
+	}
+	lines[size.head] = fmt.Sprintf("... %d lines (%d bytes) of output were removed by the output limit ...\n",
+		size.removedLines, size.removedBytes)
+	p.output[id] = lines
+}
@@ -0,0 +1,57 @@
+package testjson
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestPackage_LimitOutput(t *testing.T) {
+	pkg := newPackage()
+	pkg.maxOutputBytes = 40
+	for i := 0; i < 20; i++ {
+		pkg.addOutput(1, fmt.Sprintf("line %02d\n", i))
+	}
+	pkg.addOutput(2, "short\n")
+
+	expected := `line 00
+line 01
+... 17 lines (136 bytes) of output were removed by the output limit ...
+line 19
+`
+	assert.Equal(t, pkg.Output(1), expected)
+	assert.Equal(t, pkg.Output(2), "short\n")
+
+	t.Run("no limit", func(t *testing.T) {
+		pkg := newPackage()
+		for i := 0; i < 20; i++ {
+			pkg.addOutput(1, fmt.Sprintf("line %02d\n", i))
+		}
+		assert.Equal(t, strings.Count(pkg.Output(1), "\n"), 20)
+	})
+}
+
+func TestScanTestOutput_MaxTestOutputBytes(t *testing.T) {
+	var in strings.Builder
+	in.WriteString(`{"Package": "pkg", "Test": "TestNoisy", "Action": "run"}` + "\n")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&in, `{"Package": "pkg", "Test": "TestNoisy", "Action": "output", "Output": "log line %04d\n"}`+"\n", i)
+	}
+	in.WriteString(`{"Package": "pkg", "Test": "TestNoisy", "Action": "fail"}` + "\n")
+
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:             strings.NewReader(in.String()),
+		MaxTestOutputBytes: 1000,
+	})
+	assert.NilError(t, err)
+	failed := exec.Failed()
+	assert.Equal(t, len(failed), 1)
+
+	out := exec.Package("pkg").Output(failed[0].ID)
+	assert.Assert(t, len(out) <= 1100, "output is %d bytes", len(out))
+	assert.Assert(t, strings.HasPrefix(out, "log line 0000\n"))
+	assert.Assert(t, strings.HasSuffix(out, "log line 0999\n"))
+	assert.Assert(t, strings.Contains(out, "of output were removed by the output limit"))
+}
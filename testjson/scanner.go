@@ -0,0 +1,189 @@
+package testjson
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// maxLineSize is the size of the longest line read from stdout. A single
+	// event with a lot of output, like the goroutine dump of a test timeout, can
+	// be much larger than the default limit of bufio.Scanner.
+	maxLineSize = 64 << 20
+	// readBufferSize is the size of the buffer used to read stdout when the
+	// events are parsed in parallel.
+	readBufferSize = 1 << 20
+	// maxBatchLines is the number of lines parsed together by a parse worker.
+	maxBatchLines = 256
+)
+
+// newLineScanner returns a scanner which reads lines of up to maxLineSize.
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return scanner
+}
+
+// lineBatch is a group of lines read from stdout, and the events parsed from
+// those lines.
+type lineBatch struct {
+	// buf contains the lines, one after the other, without a newline.
+	buf []byte
+	// ends is the index in buf of the end of each line.
+	ends   []int
+	events []TestEvent
+	errs   []error
+	// parsed is closed once the events are parsed.
+	parsed chan struct{}
+}
+
+var batchPool = sync.Pool{
+	New: func() any {
+		return &lineBatch{}
+	},
+}
+
+func newLineBatch() *lineBatch {
+	batch := batchPool.Get().(*lineBatch)
+	batch.buf = batch.buf[:0]
+	batch.ends = batch.ends[:0]
+	batch.parsed = make(chan struct{})
+	return batch
+}
+
+// release the batch so that its buffers can be reused. The raw bytes of the
+// events in the batch must not be used after the batch is released.
+func (b *lineBatch) release() {
+	clear(b.events)
+	clear(b.errs)
+	batchPool.Put(b)
+}
+
+func (b *lineBatch) line(i int) []byte {
+	start := 0
+	if i > 0 {
+		start = b.ends[i-1]
+	}
+	return b.buf[start:b.ends[i]]
+}
+
+func (b *lineBatch) parse() {
+	n := len(b.ends)
+	b.events = append(b.events[:0], make([]TestEvent, n)...)
+	b.errs = append(b.errs[:0], make([]error, n)...)
+	for i := range n {
+		b.events[i], b.errs[i] = parseEvent(b.line(i))
+	}
+	close(b.parsed)
+}
+
+// readStdoutParallel reads and parses the lines from config.Stdout in
+// separate goroutines, so that reading, parsing, and handling the events
+// happen at the same time. The events are handled in the order they were
+// read.
+func readStdoutParallel(config ScanConfig, execution *Execution) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	toParse := make(chan *lineBatch)
+	ordered := make(chan *lineBatch, 2*config.ParseWorkers)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(toParse)
+		defer close(ordered)
+		readErr <- readLineBatches(config.Stdout, done, func(batch *lineBatch) bool {
+			select {
+			case ordered <- batch:
+			case <-done:
+				return false
+			}
+			select {
+			case toParse <- batch:
+				return true
+			case <-done:
+				return false
+			}
+		})
+	}()
+	for range config.ParseWorkers {
+		go func() {
+			for batch := range toParse {
+				batch.parse()
+			}
+		}()
+	}
+
+	for batch := range ordered {
+		<-batch.parsed
+		for i, event := range batch.events {
+			if err := handleEvent(config, execution, batch.line(i), event, batch.errs[i]); err != nil {
+				return err
+			}
+		}
+		batch.release()
+	}
+	if err := <-readErr; err != nil {
+		return fmt.Errorf("failed to scan test output: %w", err)
+	}
+	return nil
+}
+
+// readLineBatches reads lines from r, and calls send with each batch of
+// lines. A batch is sent when it is full, or when there is no more input
+// ready to read, so that events are not delayed when the input is slow.
+// Reading stops when send returns false.
+func readLineBatches(r io.Reader, done <-chan struct{}, send func(*lineBatch) bool) error {
+	reader := bufio.NewReaderSize(r, readBufferSize)
+	batch := newLineBatch()
+	for {
+		start := len(batch.buf)
+		var err error
+		for {
+			var part []byte
+			part, err = reader.ReadSlice('\n')
+			batch.buf = append(batch.buf, part...)
+			if len(batch.buf)-start > maxLineSize {
+				return bufio.ErrTooLong
+			}
+			if !errors.Is(err, bufio.ErrBufferFull) {
+				break
+			}
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		line := dropNewline(batch.buf[start:])
+		batch.buf = batch.buf[:start+len(line)]
+		if err == nil || len(line) > 0 {
+			batch.ends = append(batch.ends, len(batch.buf))
+		}
+
+		full := len(batch.ends) >= maxBatchLines || len(batch.buf) >= readBufferSize
+		if len(batch.ends) > 0 && (full || err == io.EOF || reader.Buffered() == 0) {
+			if !send(batch) {
+				return nil
+			}
+			batch = newLineBatch()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+	}
+}
+
+// dropNewline removes the trailing newline, and carriage return, in the same
+// way as bufio.ScanLines.
+func dropNewline(line []byte) []byte {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	return bytes.TrimSuffix(line, []byte("\r"))
+}
@@ -0,0 +1,119 @@
+package testjson
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/golden"
+)
+
+// rawHandler records the raw bytes of each event, which are only valid until
+// Event returns.
+type rawHandler struct {
+	lines []string
+	errs  []string
+}
+
+func (h *rawHandler) Event(event TestEvent, _ *Execution) error {
+	h.lines = append(h.lines, string(event.Bytes()))
+	return nil
+}
+
+func (h *rawHandler) Err(text string) error {
+	h.errs = append(h.errs, text)
+	return nil
+}
+
+func TestScanTestOutput_ParseWorkers(t *testing.T) {
+	source := golden.Get(t, "input/go-test-json.out")
+
+	expected := &rawHandler{}
+	expectedExec, err := ScanTestOutput(ScanConfig{
+		Stdout:  bytes.NewReader(source),
+		Handler: expected,
+	})
+	assert.NilError(t, err)
+
+	for _, tc := range []struct {
+		name string
+		wrap func(io.Reader) io.Reader
+	}{
+		{name: "one read", wrap: func(r io.Reader) io.Reader { return r }},
+		{name: "one byte reads", wrap: iotest.OneByteReader},
+		{name: "half reads", wrap: iotest.HalfReader},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := &rawHandler{}
+			exec, err := ScanTestOutput(ScanConfig{
+				Stdout:       tc.wrap(bytes.NewReader(source)),
+				Handler:      handler,
+				ParseWorkers: 4,
+			})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, handler.lines, expected.lines)
+			assert.Equal(t, exec.Total(), expectedExec.Total())
+			assert.Equal(t, len(exec.Failed()), len(expectedExec.Failed()))
+		})
+	}
+}
+
+func TestScanTestOutput_ParseWorkers_LongLinesAndNonJSON(t *testing.T) {
+	longOutput := strings.Repeat("x", 3*readBufferSize)
+	source := strings.Join([]string{
+		`{"Action":"run","Package":"pkg","Test":"TestOne"}`,
+		`not json`,
+		fmt.Sprintf(`{"Action":"output","Package":"pkg","Test":"TestOne","Output":%q}`, longOutput+"\n"),
+		`{"Action":"fail","Package":"pkg","Test":"TestOne"}` + "\r",
+		`{"Action":"fail","Package":"pkg"}`,
+	}, "\n")
+
+	handler := &rawHandler{}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:                   strings.NewReader(source),
+		Handler:                  handler,
+		IgnoreNonJSONOutputLines: true,
+		ParseWorkers:             2,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, handler.errs, []string{"not json"})
+	assert.Equal(t, len(handler.lines), 4)
+	assert.Equal(t, handler.lines[3], `{"Action":"fail","Package":"pkg"}`)
+
+	failed := exec.Failed()
+	assert.Equal(t, len(failed), 1)
+	assert.Equal(t, exec.OutputLines(failed[0])[0], longOutput+"\n")
+}
+
+func TestScanTestOutput_ParseWorkers_CallsStopOnError(t *testing.T) {
+	var called bool
+	cfg := ScanConfig{
+		Stdout:       bytes.NewReader(golden.Get(t, "input/go-test-json.out")),
+		Handler:      &handlerFails{},
+		Stop:         func() { called = true },
+		ParseWorkers: 2,
+	}
+	_, err := ScanTestOutput(cfg)
+	assert.Error(t, err, "something failed")
+	assert.Assert(t, called)
+}
+
+func BenchmarkScanTestOutput(b *testing.B) {
+	source := bytes.Repeat(golden.Get(b, "input/go-test-json.out"), 100)
+	for _, workers := range []int{0, 4} {
+		b.Run(fmt.Sprintf("parse-workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(source)))
+			for range b.N {
+				_, err := ScanTestOutput(ScanConfig{
+					Stdout:       bytes.NewReader(source),
+					ParseWorkers: workers,
+				})
+				assert.NilError(b, err)
+			}
+		})
+	}
+}
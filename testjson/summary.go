@@ -3,6 +3,7 @@ package testjson
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -20,23 +21,30 @@ const (
 	SummarizeFailed
 	SummarizeErrors
 	SummarizeOutput
-	SummarizeAll = SummarizeSkipped | SummarizeFailed | SummarizeErrors | SummarizeOutput
+	SummarizeBenchmarks
+	SummarizeFuzz
+	SummarizeAll = SummarizeSkipped | SummarizeFailed | SummarizeErrors | SummarizeOutput |
+		SummarizeBenchmarks | SummarizeFuzz
 )
 
 var summaryValues = map[Summary]string{
-	SummarizeSkipped: "skipped",
-	SummarizeFailed:  "failed",
-	SummarizeErrors:  "errors",
-	SummarizeOutput:  "output",
+	SummarizeSkipped:    "skipped",
+	SummarizeFailed:     "failed",
+	SummarizeErrors:     "errors",
+	SummarizeOutput:     "output",
+	SummarizeBenchmarks: "benchmarks",
+	SummarizeFuzz:       "fuzz",
 }
 
 var summaryFromValue = map[string]Summary{
-	"none":    SummarizeNone,
-	"skipped": SummarizeSkipped,
-	"failed":  SummarizeFailed,
-	"errors":  SummarizeErrors,
-	"output":  SummarizeOutput,
-	"all":     SummarizeAll,
+	"none":       SummarizeNone,
+	"skipped":    SummarizeSkipped,
+	"failed":     SummarizeFailed,
+	"errors":     SummarizeErrors,
+	"output":     SummarizeOutput,
+	"benchmarks": SummarizeBenchmarks,
+	"fuzz":       SummarizeFuzz,
+	"all":        SummarizeAll,
 }
 
 func (s Summary) String() string {
@@ -67,24 +75,90 @@ func NewSummary(value string) (Summary, bool) {
 // PrintSummary of a test Execution. Prints a section for each summary type
 // followed by a DONE line to out.
 func PrintSummary(out io.Writer, execution *Execution, opts Summary) {
+	PrintSummaryWithConfig(out, execution, SummaryConfig{Sections: opts})
+}
+
+// SummaryConfig is used by PrintSummaryWithConfig to configure the summary.
+type SummaryConfig struct {
+	// Sections of the summary to print.
+	Sections Summary
+	// FoldFailures configures the folding of long failure output into a
+	// short headline.
+	FoldFailures FoldConfig
+	// MaxLineLength is the maximum number of characters printed from each
+	// line of test output. Longer lines are truncated. Zero means no limit.
+	MaxLineLength int
+	// Quarantined returns true if the test is quarantined. The failures of
+	// quarantined tests are printed in a separate section, and are not
+	// counted as failures.
+	Quarantined func(TestCase) bool
+	// Extra sections are printed in order after the errors, and before the
+	// DONE line.
+	Extra []SummarySection
+}
+
+// SummarySection is a section of the summary which is not part of the
+// default summary, for example the coverage of each package.
+type SummarySection interface {
+	// Name is printed as the header of the section.
+	Name() string
+	// Render returns the text of the section. The section is not printed when
+	// the text is empty.
+	Render(execution *Execution) string
+}
+
+// PrintSummaryWithConfig prints a summary of a test Execution, the same as
+// PrintSummary, using the options from config.
+func PrintSummaryWithConfig(out io.Writer, execution *Execution, config SummaryConfig) {
+	opts := config.Sections
 	execSummary := newExecSummary(execution, opts)
+	if opts.Includes(SummarizeBenchmarks) {
+		writeBenchmarkSummary(out, execution.Benchmarks())
+	}
 	if opts.Includes(SummarizeSkipped) {
-		writeTestCaseSummary(out, execSummary, formatSkipped())
+		conf := formatSkipped()
+		conf.maxLineLength = config.MaxLineLength
+		writeTestCaseSummary(out, execSummary, conf)
 	}
 	if opts.Includes(SummarizeFailed) {
-		writeTestCaseSummary(out, execSummary, formatFailed())
+		conf := formatFailed()
+		conf.fold = config.FoldFailures
+		conf.maxLineLength = config.MaxLineLength
+		if config.Quarantined != nil {
+			conf.getter = filterFailed(func(tc TestCase) bool {
+				return !config.Quarantined(tc)
+			})
+		}
+		writeTestCaseSummary(out, execSummary, conf)
+	}
+	var quarantined int
+	if config.Quarantined != nil {
+		conf := formatQuarantined(config.Quarantined)
+		conf.fold = config.FoldFailures
+		conf.maxLineLength = config.MaxLineLength
+		quarantined = len(conf.getter(execSummary))
+		if opts.Includes(SummarizeFailed) {
+			writeTestCaseSummary(out, execSummary, conf)
+		}
 	}
 
 	errors := execution.Errors()
 	if opts.Includes(SummarizeErrors) {
 		writeErrorSummary(out, errors)
 	}
+	if opts.Includes(SummarizeFuzz) {
+		writeFuzzSummary(out, execution.Fuzz())
+	}
+	for _, section := range config.Extra {
+		writeSectionSummary(out, execution, section)
+	}
 
-	fmt.Fprintf(out, "\n%s %d tests%s%s%s in %s\n",
+	fmt.Fprintf(out, "\n%s %d tests%s%s%s%s in %s\n",
 		formatExecStatus(execution),
 		execution.Total(),
 		formatTestCount(len(execution.Skipped()), "skipped", ""),
-		formatTestCount(len(execution.Failed()), "failure", "s"),
+		formatTestCount(len(execution.Failed())-quarantined, "failure", "s"),
+		formatTestCount(quarantined, "quarantined", ""),
 		formatTestCount(countErrors(errors), "error", "s"),
 		FormatDurationAsSeconds(execution.Elapsed(), 3))
 }
@@ -128,6 +202,105 @@ func writeErrorSummary(out io.Writer, errors []string) {
 	}
 }
 
+// writeBenchmarkSummary prints a table of the results of the benchmarks in
+// each package.
+func writeBenchmarkSummary(out io.Writer, benchmarks []Benchmark) {
+	if len(benchmarks) == 0 {
+		return
+	}
+	rows := make([][]string, 0, len(benchmarks))
+	for _, b := range benchmarks {
+		row := []string{b.NameWithProcs(), strconv.FormatInt(b.Iterations, 10)}
+		for _, m := range b.Metrics {
+			row = append(row, strconv.FormatFloat(m.Value, 'f', -1, 64)+" "+m.Unit)
+		}
+		rows = append(rows, row)
+	}
+
+	fmt.Fprintln(out, color.CyanString("\n=== Benchmarks"))
+	var pkg string
+	for i, line := range alignColumns(rows) {
+		if benchmarks[i].Package != pkg {
+			pkg = benchmarks[i].Package
+			fmt.Fprintln(out, RelativePackagePath(pkg))
+		}
+		fmt.Fprintln(out, "  "+line)
+	}
+}
+
+// writeFuzzSummary prints the progress of each fuzz test run with
+// go test -fuzz. The failing input found by a fuzz test is printed below
+// the test, with the command to run the test with that input.
+func writeFuzzSummary(out io.Writer, fuzz []Fuzz) {
+	if len(fuzz) == 0 {
+		return
+	}
+	rows := make([][]string, 0, len(fuzz))
+	for _, f := range fuzz {
+		rows = append(rows, []string{
+			f.Test,
+			strconv.FormatInt(f.Execs, 10) + " execs",
+			fmt.Sprintf("%.0f/sec", f.AverageExecsPerSec()),
+			fmt.Sprintf("corpus %d (%d new)", f.Corpus, f.NewInteresting),
+			f.Elapsed.String(),
+		})
+	}
+
+	fmt.Fprintln(out, color.CyanString("\n=== Fuzz"))
+	var pkg string
+	for i, line := range alignColumns(rows) {
+		f := fuzz[i]
+		if f.Package != pkg {
+			pkg = f.Package
+			fmt.Fprintln(out, RelativePackagePath(pkg))
+		}
+		fmt.Fprintln(out, "  "+line)
+		if f.FailingInput == "" {
+			continue
+		}
+		fmt.Fprintln(out, color.RedString("    Failing input written to %s", f.FailingInput))
+		if f.Rerun != "" {
+			fmt.Fprintf(out, "    To re-run: %s %s\n", f.Rerun, f.Package)
+		}
+	}
+}
+
+// alignColumns returns a line for each row with the columns aligned. The
+// first column is aligned to the left, and the other columns are aligned to
+// the right.
+func alignColumns(rows [][]string) []string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		line := row[0] + strings.Repeat(" ", widths[0]-len(row[0]))
+		for i, cell := range row[1:] {
+			line += strings.Repeat(" ", widths[i+1]-len(cell)+2) + cell
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func writeSectionSummary(out io.Writer, execution *Execution, section SummarySection) {
+	text := section.Render(execution)
+	if text == "" {
+		return
+	}
+	fmt.Fprintln(out, color.BlueString("\n=== "+section.Name()))
+	fmt.Fprint(out, text)
+	if !strings.HasSuffix(text, "\n") {
+		fmt.Fprintln(out)
+	}
+}
+
 // countErrors in stderr lines. Build errors may include multiple lines where
 // subsequent lines are indented.
 // FIXME: Panics will include multiple lines, and are still overcounted.
@@ -146,6 +319,7 @@ type executionSummary interface {
 	Failed() []TestCase
 	Skipped() []TestCase
 	OutputLines(TestCase) []string
+	SkipReason(TestCase) string
 }
 
 type noOutputSummary struct {
@@ -170,17 +344,26 @@ func writeTestCaseSummary(out io.Writer, execution executionSummary, conf testCa
 	}
 	fmt.Fprintln(out, "\n=== "+conf.header)
 	for idx, tc := range testCases {
-		fmt.Fprintf(out, "=== %s: %s %s%s (%s)\n",
+		var reason string
+		if conf.withSkipReason {
+			reason = formatSkipReason(execution.SkipReason(tc))
+		}
+		fmt.Fprintf(out, "=== %s: %s %s%s (%s)%s\n",
 			conf.prefix,
 			RelativePackagePath(tc.Package),
 			tc.Test,
 			formatRunID(tc.RunID),
-			FormatDurationAsSeconds(tc.Elapsed, 2))
+			FormatDurationAsSeconds(tc.Elapsed, 2),
+			reason)
+		var lines []string
 		for _, line := range execution.OutputLines(tc) {
 			if isFramingLine(line, tc.Test.Name()) {
 				continue
 			}
-			fmt.Fprint(out, line)
+			lines = append(lines, line)
+		}
+		for _, line := range conf.fold.Headline(lines) {
+			fmt.Fprint(out, truncateLine(line, conf.maxLineLength))
 		}
 		if _, isNoOutput := execution.(*noOutputSummary); !isNoOutput && idx+1 != len(testCases) {
 			fmt.Fprintln(out)
@@ -192,6 +375,12 @@ type testCaseFormatConfig struct {
 	header string
 	prefix string
 	getter func(executionSummary) []TestCase
+	// withSkipReason adds the reason a test was skipped to the test case line.
+	withSkipReason bool
+	// fold the output of the test case into a headline.
+	fold FoldConfig
+	// maxLineLength truncates lines of output which are longer.
+	maxLineLength int
 }
 
 func formatFailed() testCaseFormatConfig {
@@ -205,6 +394,28 @@ func formatFailed() testCaseFormatConfig {
 	}
 }
 
+func formatQuarantined(quarantined func(TestCase) bool) testCaseFormatConfig {
+	withColor := color.CyanString
+	return testCaseFormatConfig{
+		header: withColor("Quarantined"),
+		prefix: withColor("FAIL"),
+		getter: filterFailed(quarantined),
+	}
+}
+
+// filterFailed returns a getter of the failed tests which match include.
+func filterFailed(include func(TestCase) bool) func(executionSummary) []TestCase {
+	return func(execution executionSummary) []TestCase {
+		var result []TestCase
+		for _, tc := range execution.Failed() {
+			if include(tc) {
+				result = append(result, tc)
+			}
+		}
+		return result
+	}
+}
+
 func formatSkipped() testCaseFormatConfig {
 	withColor := color.YellowString
 	return testCaseFormatConfig{
@@ -213,6 +424,7 @@ func formatSkipped() testCaseFormatConfig {
 		getter: func(execution executionSummary) []TestCase {
 			return execution.Skipped()
 		},
+		withSkipReason: true,
 	}
 }
 
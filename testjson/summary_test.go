@@ -2,6 +2,7 @@ package testjson
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"strings"
 	"testing"
@@ -25,7 +26,7 @@ func TestSummary_String(t *testing.T) {
 		{
 			name:     "all",
 			summary:  SummarizeAll,
-			expected: "skipped,failed,errors,output",
+			expected: "skipped,failed,errors,output,benchmarks,fuzz",
 		},
 		{
 			name:     "one value",
@@ -157,7 +158,7 @@ Some stdout/stderr here
 
 		expected := `
 === Skipped
-=== SKIP: project/pkg/more TestOnlySometimes (0.00s)
+=== SKIP: project/pkg/more TestOnlySometimes (0.00s): the skip message
 	good_test.go:27: the skip message
 
 === Failed
@@ -187,7 +188,7 @@ DONE 13 tests, 1 skipped, 4 failures, 1 error in 34.123s
 
 		expected := `
 === Skipped
-=== SKIP: project/pkg/more TestOnlySometimes (0.00s)
+=== SKIP: project/pkg/more TestOnlySometimes (0.00s): the skip message
 
 === Failed
 === FAIL: project/badmain  (0.00s)
@@ -310,6 +311,21 @@ func TestPrintSummary(t *testing.T) {
 			},
 			expectedOut: "summary/with-run-id",
 		},
+		{
+			name:        "with benchmarks",
+			config:      scanConfigFromGolden("input/go-test-json-with-benchmarks.out"),
+			expectedOut: "summary/with-benchmarks",
+		},
+		{
+			name:        "with fuzz",
+			config:      scanConfigFromGolden("input/go-test-json-with-fuzz.out"),
+			expectedOut: "summary/with-fuzz",
+		},
+		{
+			name:        "with fuzz failing input",
+			config:      scanConfigFromGolden("input/go-test-json-with-fuzz-crash.out"),
+			expectedOut: "summary/with-fuzz-crash",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -319,6 +335,67 @@ func TestPrintSummary(t *testing.T) {
 	}
 }
 
+func TestPrintSummaryWithConfig_Quarantined(t *testing.T) {
+	exec, err := ScanTestOutput(scanConfigFromGolden("input/go-test-json.out")(t))
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	PrintSummaryWithConfig(buf, exec, SummaryConfig{
+		Sections: SummarizeFailed,
+		Quarantined: func(tc TestCase) bool {
+			return strings.HasSuffix(tc.Package, "/parallelfails")
+		},
+	})
+	golden.Assert(t, buf.String(), "summary/quarantined")
+}
+
+type stubSection struct {
+	name string
+	text string
+}
+
+func (s stubSection) Name() string {
+	return s.name
+}
+
+func (s stubSection) Render(exec *Execution) string {
+	if s.text == "" {
+		return ""
+	}
+	return fmt.Sprintf(s.text, exec.Total())
+}
+
+func TestPrintSummaryWithConfig_Extra(t *testing.T) {
+	now := time.Date(2022, 1, 2, 3, 4, 5, 600, time.UTC)
+	patchTimeNow(t, now)
+	exec := &Execution{
+		testStart: now,
+		done:      true,
+		packages:  map[string]*Package{"foo": {Total: 3}},
+	}
+
+	buf := new(bytes.Buffer)
+	PrintSummaryWithConfig(buf, exec, SummaryConfig{
+		Sections: SummarizeAll,
+		Extra: []SummarySection{
+			stubSection{name: "First", text: "total %d"},
+			stubSection{name: "Empty"},
+			stubSection{name: "Second", text: "line one\nline two %d\n"},
+		},
+	})
+	expected := `
+=== First
+total 3
+
+=== Second
+line one
+line two 3
+
+DONE 3 tests in 0.000s
+`
+	assert.Equal(t, buf.String(), expected)
+}
+
 func scanConfigFromGolden(filename string) func(t *testing.T) ScanConfig {
 	return func(t *testing.T) ScanConfig {
 		return ScanConfig{Stdout: bytes.NewReader(golden.Get(t, filename))}
@@ -0,0 +1,174 @@
+package testjson
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// tapFormatter prints the results in TAP version 13 format. A test point is
+// printed for each top-level test when the test ends. Subtests are printed as
+// indented subtests before the test point of their parent, and the output of
+// failed tests is printed in a YAML diagnostics block.
+//
+// The plan is printed at the end of the run by PrintTAPPlan.
+type tapFormatter struct {
+	out     *bufio.Writer
+	started bool
+	count   int
+	// running tests, by package and the name of the top-level test.
+	running map[tapKey]*tapNode
+}
+
+type tapKey struct {
+	pkg  string
+	root string
+}
+
+// tapNode is a test and its subtests.
+type tapNode struct {
+	name     string
+	fullName string
+	action   Action
+	elapsed  float64
+	output   []string
+	children []*tapNode
+	// tests is the top-level test and all of its subtests by the full name of
+	// the test. It is only set on the top-level test.
+	tests map[string]*tapNode
+}
+
+func tapFormat(out io.Writer) EventFormatter {
+	return &tapFormatter{
+		out:     bufio.NewWriter(out),
+		running: make(map[tapKey]*tapNode),
+	}
+}
+
+func (f *tapFormatter) Format(event TestEvent, exec *Execution) error {
+	if !f.started {
+		f.started = true
+		f.out.WriteString("TAP version 13\n") //nolint:errcheck
+	}
+
+	if event.PackageEvent() {
+		if event.Action.IsTerminal() && exec.Package(event.Package).TestMainFailed() {
+			f.writePackageFailure(event, exec)
+		}
+		return f.out.Flush()
+	}
+
+	test := TestName(event.Test)
+	root, _ := test.Split()
+	key := tapKey{pkg: event.Package, root: root}
+	node := f.node(key, event.Test)
+	switch {
+	case event.Action == ActionOutput:
+		node.output = append(node.output, event.Output)
+	case event.Action.IsTerminal():
+		node.action = event.Action
+		node.elapsed = event.Elapsed
+		if !test.IsSubTest() {
+			delete(f.running, key)
+			f.count++
+			f.writeNode("", f.count, RelativePackagePath(event.Package)+"."+root, node)
+		}
+	}
+	return f.out.Flush()
+}
+
+// node returns the node for the test with name, and creates the node, and any
+// missing parents, if it does not exist.
+func (f *tapFormatter) node(key tapKey, name string) *tapNode {
+	root, ok := f.running[key]
+	if !ok {
+		root = &tapNode{name: key.root, fullName: key.root, tests: make(map[string]*tapNode)}
+		root.tests[key.root] = root
+		f.running[key] = root
+	}
+	if node, ok := root.tests[name]; ok {
+		return node
+	}
+
+	parentName := TestName(name).Parent()
+	parent := f.node(key, parentName)
+	node := &tapNode{name: strings.TrimPrefix(name, parentName+"/"), fullName: name}
+	parent.children = append(parent.children, node)
+	root.tests[name] = node
+	return node
+}
+
+//nolint:errcheck // errors are returned by Flush
+func (f *tapFormatter) writeNode(indent string, num int, description string, node *tapNode) {
+	if len(node.children) > 0 {
+		childIndent := indent + "    "
+		fmt.Fprintf(f.out, "%s# Subtest: %s\n", childIndent, description)
+		for i, child := range node.children {
+			f.writeNode(childIndent, i+1, child.name, child)
+		}
+		fmt.Fprintf(f.out, "%s1..%d\n", childIndent, len(node.children))
+	}
+
+	switch node.action {
+	case ActionPass:
+		fmt.Fprintf(f.out, "%sok %d - %s\n", indent, num, description)
+	case ActionSkip:
+		fmt.Fprintf(f.out, "%sok %d - %s # SKIP", indent, num, description)
+		if reason := logMessage(node.output, node.fullName); reason != "" {
+			fmt.Fprintf(f.out, " %s", reason)
+		}
+		f.out.WriteString("\n")
+	default:
+		fmt.Fprintf(f.out, "%snot ok %d - %s\n", indent, num, description)
+		f.writeDiagnostics(indent+"  ", node.elapsed, node.output)
+	}
+}
+
+//nolint:errcheck // errors are returned by Flush
+func (f *tapFormatter) writePackageFailure(event TestEvent, exec *Execution) {
+	f.count++
+	fmt.Fprintf(f.out, "not ok %d - %s\n", f.count, RelativePackagePath(event.Package))
+	pkg := exec.Package(event.Package)
+	f.writeDiagnostics("  ", event.Elapsed, pkg.output[0])
+}
+
+//nolint:errcheck // errors are returned by Flush
+func (f *tapFormatter) writeDiagnostics(indent string, elapsed float64, output []string) {
+	fmt.Fprintf(f.out, "%s---\n", indent)
+	fmt.Fprintf(f.out, "%sduration_ms: %.3f\n", indent, elapsed*1000)
+	if len(output) > 0 {
+		fmt.Fprintf(f.out, "%soutput: |2\n", indent)
+		for _, line := range output {
+			fmt.Fprintf(f.out, "%s  %s\n", indent, strings.TrimRight(line, "\n"))
+		}
+	}
+	fmt.Fprintf(f.out, "%s...\n", indent)
+}
+
+// PrintTAPPlan prints the plan of a TAP version 13 stream printed by the tap
+// format, followed by the totals of the run as TAP comments.
+func PrintTAPPlan(out io.Writer, exec *Execution) error {
+	var count int
+	for _, pkg := range exec.packages {
+		if pkg.TestMainFailed() {
+			count++
+		}
+		for _, group := range [][]TestCase{pkg.Failed, pkg.Skipped, pkg.Passed} {
+			for _, tc := range group {
+				if !tc.Test.IsSubTest() {
+					count++
+				}
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(out, "1..%d\n# tests %d\n# pass %d\n# fail %d\n# skip %d\n# duration_ms %d\n",
+		count,
+		exec.Total(),
+		exec.Total()-len(exec.Failed())-len(exec.Skipped()),
+		len(exec.Failed()),
+		len(exec.Skipped()),
+		exec.Elapsed().Milliseconds())
+	return err
+}
@@ -0,0 +1,108 @@
+package testjson
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// teamCityFormatter prints TeamCity service messages, which are used by
+// TeamCity to report the tests of a build. Each package is a test suite, and
+// uses the package as the flowId.
+//
+// The messages for a test are printed when the test ends, so that the messages
+// of parallel tests in the same package are not interleaved.
+type teamCityFormatter struct {
+	out     *bufio.Writer
+	started map[string]bool
+	output  map[teamCityKey][]string
+}
+
+type teamCityKey struct {
+	pkg  string
+	test string
+}
+
+func teamCityFormat(out io.Writer) EventFormatter {
+	return &teamCityFormatter{
+		out:     bufio.NewWriter(out),
+		started: make(map[string]bool),
+		output:  make(map[teamCityKey][]string),
+	}
+}
+
+func (f *teamCityFormatter) Format(event TestEvent, exec *Execution) error {
+	if !f.started[event.Package] {
+		f.started[event.Package] = true
+		f.message("testSuiteStarted", "name", event.Package, "flowId", event.Package)
+	}
+
+	if event.PackageEvent() {
+		if !event.Action.IsTerminal() {
+			return nil
+		}
+		if pkg := exec.Package(event.Package); pkg.TestMainFailed() {
+			f.writeTest(event.Package, "TestMain", ActionFail, 0, pkg.Output(0))
+		}
+		f.message("testSuiteFinished", "name", event.Package, "flowId", event.Package)
+		delete(f.started, event.Package)
+		return f.out.Flush()
+	}
+
+	key := teamCityKey{pkg: event.Package, test: event.Test}
+	switch {
+	case event.Action == ActionOutput:
+		if !isFramingLine(strings.TrimLeft(event.Output, " "), event.Test) {
+			f.output[key] = append(f.output[key], event.Output)
+		}
+	case event.Action.IsTerminal():
+		output := strings.Join(f.output[key], "")
+		delete(f.output, key)
+		f.writeTest(event.Package, event.Test, event.Action, event.Elapsed, output)
+	}
+	return f.out.Flush()
+}
+
+func (f *teamCityFormatter) writeTest(pkg, test string, action Action, elapsed float64, output string) {
+	f.message("testStarted", "name", test, "flowId", pkg)
+	switch action {
+	case ActionFail:
+		f.message("testFailed", "name", test, "message", "Failed", "details", output, "flowId", pkg)
+	case ActionSkip:
+		f.message("testIgnored", "name", test, "message", strings.TrimSpace(output), "flowId", pkg)
+	default:
+		if output != "" {
+			f.message("testStdOut", "name", test, "out", output, "flowId", pkg)
+		}
+	}
+	f.message("testFinished", "name", test,
+		"duration", fmt.Sprintf("%d", int64(elapsed*1000)), "flowId", pkg)
+}
+
+// message prints a service message with the attributes in attrs, which are
+// pairs of name and value.
+func (f *teamCityFormatter) message(name string, attrs ...string) {
+	f.out.WriteString("##teamcity[" + name) //nolint:errcheck
+	for i := 0; i+1 < len(attrs); i += 2 {
+		fmt.Fprintf(f.out, " %s='%s'", attrs[i], teamCityEscape(attrs[i+1]))
+	}
+	f.out.WriteString("]\n") //nolint:errcheck
+}
+
+var teamCityReplacer = strings.NewReplacer(
+	"|", "||",
+	"'", "|'",
+	"\n", "|n",
+	"\r", "|r",
+	"[", "|[",
+	"]", "|]",
+	"\u0085", "|x",
+	"\u2028", "|l",
+	"\u2029", "|p",
+)
+
+// teamCityEscape escapes the value of an attribute of a service message.
+func teamCityEscape(value string) string {
+	return teamCityReplacer.Replace(value)
+}
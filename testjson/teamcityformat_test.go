@@ -0,0 +1,12 @@
+package testjson
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTeamCityEscape(t *testing.T) {
+	actual := teamCityEscape("it's [a] |test|\r\n\u0085  ")
+	assert.Equal(t, actual, "it|'s |[a|] ||test|||r|n|x|l|p")
+}
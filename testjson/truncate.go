@@ -0,0 +1,53 @@
+package testjson
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// truncateLine returns line with everything after the first max characters
+// replaced by a note with the number of characters that were removed. The
+// trailing newline is preserved. If max is 0 or line is not longer than max,
+// line is returned unmodified.
+func truncateLine(line string, max int) string {
+	if max <= 0 || len(line) <= max {
+		return line
+	}
+	text := strings.TrimSuffix(line, "\n")
+	count := utf8.RuneCountInString(text)
+	if count <= max {
+		return line
+	}
+
+	end, n := 0, 0
+	for i := range text {
+		if n == max {
+			end = i
+			break
+		}
+		n++
+	}
+	note := fmt.Sprintf("… (%d characters truncated, see the jsonfile or junitfile for full output)",
+		utf8.RuneCountInString(text[end:]))
+	return text[:end] + note + line[len(text):]
+}
+
+// truncateFormatter truncates long lines of output before they are printed by
+// the base formatter. The Execution is not modified, so the full output is
+// still used by the jsonfile and junit reports.
+type truncateFormatter struct {
+	base          EventFormatter
+	maxLineLength int
+}
+
+func (f *truncateFormatter) Format(event TestEvent, exec *Execution) error {
+	if event.Action == ActionOutput {
+		event.Output = truncateLine(event.Output, f.maxLineLength)
+	}
+	return f.base.Format(event, exec)
+}
+
+func (f *truncateFormatter) Close() error {
+	return closeFormatter(f.base)
+}
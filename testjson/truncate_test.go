@@ -0,0 +1,124 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/golden"
+)
+
+func TestTruncateLine(t *testing.T) {
+	type testCase struct {
+		line     string
+		max      int
+		expected string
+	}
+	run := func(t *testing.T, tc testCase) {
+		assert.Equal(t, truncateLine(tc.line, tc.max), tc.expected)
+	}
+	testCases := map[string]testCase{
+		"no limit": {
+			line:     "a long line\n",
+			expected: "a long line\n",
+		},
+		"shorter than limit": {
+			line:     "short\n",
+			max:      5,
+			expected: "short\n",
+		},
+		"longer than limit": {
+			line:     "0123456789\n",
+			max:      4,
+			expected: "0123… (6 characters truncated, see the jsonfile or junitfile for full output)\n",
+		},
+		"no trailing newline": {
+			line:     "0123456789",
+			max:      8,
+			expected: "01234567… (2 characters truncated, see the jsonfile or junitfile for full output)",
+		},
+		"multi-byte characters": {
+			line:     "ééééé\n",
+			max:      3,
+			expected: "ééé… (2 characters truncated, see the jsonfile or junitfile for full output)\n",
+		},
+		"multi-byte characters shorter than limit": {
+			line:     "ééééé\n",
+			max:      5,
+			expected: "ééééé\n",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestNewEventFormatter_MaxLineLength(t *testing.T) {
+	exec := newExecution()
+	buf := new(bytes.Buffer)
+	formatter := NewEventFormatter(buf, "standard-verbose", FormatOptions{MaxLineLength: 10})
+
+	output := "    " + strings.Repeat("x", 100) + "\n"
+	event := TestEvent{Action: ActionOutput, Package: "pkg", Test: "TestOne", Output: output}
+	exec.add(event)
+	assert.NilError(t, formatter.Format(event, exec))
+
+	expected := "    xxxxxx… (94 characters truncated, see the jsonfile or junitfile for full output)\n"
+	assert.Equal(t, buf.String(), expected)
+	// the execution has the full output
+	var lines []string
+	for _, out := range exec.Package("pkg").output {
+		lines = append(lines, out...)
+	}
+	assert.DeepEqual(t, lines, []string{output})
+}
+
+func TestNewEventFormatter_MaxLineLengthOfFailedTests(t *testing.T) {
+	line := "    " + strings.Repeat("x", 100) + "\n"
+	events := []TestEvent{
+		{Action: ActionRun, Package: "pkg", Test: "TestOne"},
+		{Action: ActionOutput, Package: "pkg", Test: "TestOne", Output: "=== RUN   TestOne\n"},
+		{Action: ActionOutput, Package: "pkg", Test: "TestOne", Output: line},
+		{Action: ActionOutput, Package: "pkg", Test: "TestOne", Output: "--- FAIL: TestOne (0.00s)\n"},
+		{Action: ActionFail, Package: "pkg", Test: "TestOne"},
+		{Action: ActionFail, Package: "pkg"},
+	}
+	truncated := "    xxxxxx… (94 characters truncated, see the jsonfile or junitfile for full output)\n"
+
+	formats := []string{
+		"standard-verbose",
+		"testname",
+		"failures-only",
+		"pkgname-and-test-fails",
+		"github-actions",
+	}
+	for _, format := range formats {
+		t.Run(format, func(t *testing.T) {
+			exec := newExecution()
+			buf := new(bytes.Buffer)
+			formatter := NewEventFormatter(buf, format, FormatOptions{MaxLineLength: 10})
+			for _, event := range events {
+				exec.add(event)
+				assert.NilError(t, formatter.Format(event, exec))
+			}
+			assert.Assert(t, cmp.Contains(buf.String(), truncated))
+			assert.Assert(t, !strings.Contains(buf.String(), line), buf.String())
+		})
+	}
+}
+
+func TestPrintSummaryWithConfig_MaxLineLength(t *testing.T) {
+	exec, err := ScanTestOutput(scanConfigFromGolden("input/go-test-json.out")(t))
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	PrintSummaryWithConfig(buf, exec, SummaryConfig{
+		Sections:      SummarizeFailed | SummarizeOutput,
+		MaxLineLength: 20,
+	})
+	golden.Assert(t, buf.String(), "summary/max-line-length")
+}